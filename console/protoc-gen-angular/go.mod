@@ -5,4 +5,5 @@ go 1.13
 require (
 	github.com/golang/protobuf v1.4.1
 	github.com/grpc-ecosystem/grpc-gateway v1.12.1
+	google.golang.org/genproto v0.0.0-20190927181202-20e1ac93f88c
 )
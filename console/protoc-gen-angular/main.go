@@ -21,8 +21,10 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/golang/protobuf/proto"
 	pbdescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -30,6 +32,7 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/codegenerator"
 	"github.com/grpc-ecosystem/grpc-gateway/protoc-gen-grpc-gateway/descriptor"
 	swagger_options "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	"google.golang.org/genproto/googleapis/api/annotations"
 )
 
 type EnumDefinitions map[string]*EnumDefinition
@@ -48,20 +51,41 @@ type Definitions struct {
 	EnumDefinitions EnumDefinitions
 	MsgDefinitions  MsgDefinitions
 	RPCDefinitions  RPCDefinitions
+	Output          string   // One of outputAll, outputService or outputModels.
+	ModelsModule    string   // Module a split service file imports its models from.
+	Imports         []string // Names a split service file imports from the models module.
 }
 
+const (
+	outputAll     = "all"
+	outputService = "service"
+	outputModels  = "models"
+)
+
 type Config struct {
 	ClassName   string
 	DefaultHost string
+	TypeGuards  bool
 }
 
 type RPCDefinition struct {
-	EndpointPath string
-	HttpMethod   string
-	InputType    string
-	OutputType   string
-	Arguments    []*Argument
-	Auth         []string
+	EndpointPath    string
+	HttpMethod      string
+	InputType       string
+	OutputType      string
+	Arguments       []*Argument
+	Auth            []string
+	ServerStreaming bool // Responses are a stream of newline delimited JSON objects.
+}
+
+// Reports whether any of the RPCs stream their responses.
+func (d *Definitions) HasServerStreaming() bool {
+	for _, rpcDef := range d.RPCDefinitions {
+		if rpcDef.ServerStreaming {
+			return true
+		}
+	}
+	return false
 }
 
 type EnumField struct {
@@ -75,6 +99,8 @@ type MsgField struct {
 	FieldName string
 	FieldType string
 	Repeated  bool
+	Map       bool // Map fields are described as repeated entry messages, but marshaled as JSON objects.
+	Required  bool // Fields marked with the google.api.field_behavior REQUIRED option.
 }
 
 type Argument struct {
@@ -89,6 +115,10 @@ var (
 	serviceName = flag.String("service_name", "HttpService", "Class name of the angular generated service.")
 	filename    = flag.String("filename", "http.service.ts", "Output filename.")
 	defaultHost = flag.String("default_host", "http://127.0.0.1:7120", "Default host.")
+	typeGuards  = flag.Bool("type_guards", false, "Generate type guard functions to validate parsed messages.")
+
+	splitServices  = flag.Bool("split_services", false, "Generate one file per service, sharing a models file, instead of a single file.")
+	modelsFilename = flag.String("models_filename", "models.ts", "Output filename for the shared models when splitting services.")
 )
 var PackageName string
 
@@ -99,6 +129,11 @@ func main() {
 	}
 	parseReqParameters(parsedReq)
 
+	emitFiles(generate(parsedReq))
+}
+
+// Generates the angular service files for the given request.
+func generate(parsedReq *plugin.CodeGeneratorRequest) []*plugin.CodeGeneratorResponse_File {
 	reg := descriptor.NewRegistry()
 
 	if err := reg.Load(parsedReq); err != nil {
@@ -127,15 +162,28 @@ func main() {
 	PackageName = protoFile.GetPackage()
 
 	spb, err := extractSwaggerOptionFromFileDescriptor(protoFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 	defaultSecDef := getSecurityDefinitions(spb.Security)
 
 	enumDefinitions := make(EnumDefinitions)
 	msgDefinitions := make(MsgDefinitions)
 	rpcDefinitions := make(RPCDefinitions)
+	serviceNames := make([]string, 0)
+	serviceRPCDefinitions := make(map[string]RPCDefinitions)
 
 	for _, target := range targets {
 		for _, service := range target.Services {
+			serviceNames = append(serviceNames, service.GetName())
+			serviceRPCDefinitions[service.GetName()] = make(RPCDefinitions)
 			for _, m := range service.Methods {
+				if m.GetClientStreaming() {
+					// Client streaming cannot be expressed as a single REST request.
+					log.Printf("WARNING: skipping client streaming method %s.%s", service.GetName(), m.GetName())
+					continue
+				}
+
 				inputMsg, err := reg.LookupMsg("", m.GetInputType())
 				if err != nil {
 					log.Fatal(err)
@@ -171,34 +219,86 @@ func main() {
 				}
 
 				arguments := getArgumentsFromBindings(m.Bindings[0], inputType, msgDefinitions)
-				rpcDefinitions[m.GetName()] = &RPCDefinition{
-					EndpointPath: m.Bindings[0].PathTmpl.Template,
-					HttpMethod:   strings.ToLower(m.Bindings[0].HTTPMethod),
-					InputType:    inputType,
-					OutputType:   outputType,
-					Arguments:    arguments,
-					Auth:         authDef,
+				rpcDefinition := &RPCDefinition{
+					EndpointPath:    m.Bindings[0].PathTmpl.Template,
+					HttpMethod:      strings.ToLower(m.Bindings[0].HTTPMethod),
+					InputType:       inputType,
+					OutputType:      outputType,
+					Arguments:       arguments,
+					Auth:            authDef,
+					ServerStreaming: m.GetServerStreaming(),
 				}
+				rpcDefinitions[m.GetName()] = rpcDefinition
+				serviceRPCDefinitions[service.GetName()][m.GetName()] = rpcDefinition
 			}
 		}
 	}
 
 	cleanupUnusedFieldsAndMessages(msgDefinitions, rpcDefinitions)
+	config := &Config{
+		ClassName:   *serviceName,
+		DefaultHost: *defaultHost,
+		TypeGuards:  *typeGuards,
+	}
+
+	var files []*plugin.CodeGeneratorResponse_File
+	if !*splitServices {
+		code := applyTemplate("ts-angular-template", tsAngularTemplate, &Definitions{
+			EnumDefinitions: enumDefinitions,
+			MsgDefinitions:  msgDefinitions,
+			RPCDefinitions:  rpcDefinitions,
+			Config:          config,
+			Output:          outputAll,
+		})
+		files = append(files, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(*filename),
+			Content: proto.String(code),
+		})
+		return files
+	}
+
+	// Shared models, along with the service configuration.
 	code := applyTemplate("ts-angular-template", tsAngularTemplate, &Definitions{
 		EnumDefinitions: enumDefinitions,
 		MsgDefinitions:  msgDefinitions,
-		RPCDefinitions:  rpcDefinitions,
-		Config: &Config{
-			ClassName:   *serviceName,
-			DefaultHost: *defaultHost,
-		},
+		Config:          config,
+		Output:          outputModels,
 	})
-	var files []*plugin.CodeGeneratorResponse_File
 	files = append(files, &plugin.CodeGeneratorResponse_File{
-		Name:    proto.String(*filename),
+		Name:    proto.String(*modelsFilename),
 		Content: proto.String(code),
 	})
-	emitFiles(files)
+
+	imports := make([]string, 0, len(msgDefinitions)+len(enumDefinitions))
+	for namespace := range msgDefinitions {
+		imports = append(imports, getTypeFromNamespace(namespace))
+	}
+	for namespace := range enumDefinitions {
+		imports = append(imports, getTypeFromNamespace(namespace))
+	}
+	sort.Strings(imports)
+
+	// One file per service.
+	for _, name := range serviceNames {
+		code := applyTemplate("ts-angular-template", tsAngularTemplate, &Definitions{
+			EnumDefinitions: enumDefinitions,
+			MsgDefinitions:  msgDefinitions,
+			RPCDefinitions:  serviceRPCDefinitions[name],
+			Config: &Config{
+				ClassName:   name + "Service",
+				DefaultHost: config.DefaultHost,
+				TypeGuards:  config.TypeGuards,
+			},
+			Output:       outputService,
+			ModelsModule: "./" + strings.TrimSuffix(*modelsFilename, ".ts"),
+			Imports:      imports,
+		})
+		files = append(files, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(kebabCase(name) + ".service.ts"),
+			Content: proto.String(code),
+		})
+	}
+	return files
 }
 
 // Parses generator request params from the protoc toolchain
@@ -313,6 +413,7 @@ func findMessagesAndEnumerations(reg *descriptor.Registry, message *descriptor.M
 				FieldName: f.GetName(),
 				FieldType: "enum",
 				Repeated:  f.Label.String() == "LABEL_REPEATED",
+				Required:  isFieldRequired(f.FieldDescriptorProto),
 			})
 		default:
 			fieldType := f.GetType().String()
@@ -328,22 +429,47 @@ func findMessagesAndEnumerations(reg *descriptor.Registry, message *descriptor.M
 			} else {
 				fieldType = primitiveToJson(f.GetType().String())
 			}
-			msgFields = append(msgFields, &MsgField{
+			msgField := &MsgField{
 				Namespace: namespace,
 				FieldName: f.GetName(),
 				FieldType: fieldType,
 				Repeated:  f.Label.String() == "LABEL_REPEATED",
-			})
+				Required:  isFieldRequired(f.FieldDescriptorProto),
+			}
+			msgFields = append(msgFields, msgField)
 			msg, err := reg.LookupMsg("", f.GetTypeName())
 			if err != nil {
 				continue
 			}
+			msgField.Map = msg.GetOptions().GetMapEntry()
 			findMessagesAndEnumerations(reg, msg, f.GetTypeName(), m, e)
 		}
 	}
 	m[namespace] = msgFields
 }
 
+// Reports whether the field is marked as REQUIRED through the google.api.field_behavior option.
+func isFieldRequired(field *pbdescriptor.FieldDescriptorProto) bool {
+	if field.Options == nil || !proto.HasExtension(field.Options, annotations.E_FieldBehavior) {
+		return false
+	}
+	ext, err := proto.GetExtension(field.Options, annotations.E_FieldBehavior)
+	if err != nil {
+		log.Printf("WARNING: failed to read field behavior of %s: %v", field.GetName(), err)
+		return false
+	}
+	behaviors, ok := ext.([]annotations.FieldBehavior)
+	if !ok {
+		return false
+	}
+	for _, behavior := range behaviors {
+		if behavior == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+	return false
+}
+
 //Extract swagger options from file
 func extractSwaggerOptionFromFileDescriptor(file *pbdescriptor.FileDescriptorProto) (*swagger_options.Swagger, error) {
 	if file.Options == nil {
@@ -385,9 +511,11 @@ func extractOperationOptionFromMethodDescriptor(meth *pbdescriptor.MethodDescrip
 func applyTemplate(templateName, templateString string, data interface{}) string {
 	template, err := template.New(templateName).Funcs(template.FuncMap{
 		"title":                strings.Title,
+		"upper":                strings.ToUpper,
 		"convertPathToJs":      convertPathToJs,
 		"decapitalize":         decapitalize,
 		"getTypeFromNamespace": getTypeFromNamespace,
+		"typeGuard":            typeGuard,
 	}).Parse(templateString)
 	if err != nil {
 		log.Fatal(err)
@@ -542,28 +670,39 @@ func primitiveToJson(t string) string {
 
 const tsAngularTemplate string = `// tslint:disable
 /* Code generated automatically DO NOT EDIT. */
+{{- if ne .Output "models" }}
 import { Injectable, Optional } from '@angular/core';
-import { HttpClient, HttpHeaders, HttpParams } from '@angular/common/http';
+import { HttpClient, HttpHeaders, HttpParams{{ if .HasServerStreaming }}, HttpDownloadProgressEvent, HttpEvent, HttpEventType{{ end }} } from '@angular/common/http';
 import { Observable } from 'rxjs';
+import { retry, timeout } from 'rxjs/operators';
+{{- end }}
+{{- if eq .Output "service" }}
+import { ConfigParams, DEFAULT_HOST, DEFAULT_RETRY_COUNT, DEFAULT_TIMEOUT_MS{{ range .Imports }}, {{ . }}{{ end }} } from '{{ .ModelsModule }}';
+{{- else }}
 
-const DEFAULT_HOST = '{{.Config.DefaultHost}}';
-const DEFAULT_TIMEOUT_MS = 5000;
+{{ if eq .Output "models" }}export {{ end }}const DEFAULT_HOST = '{{.Config.DefaultHost}}';
+{{ if eq .Output "models" }}export {{ end }}const DEFAULT_TIMEOUT_MS = 5000;
+{{ if eq .Output "models" }}export {{ end }}const DEFAULT_RETRY_COUNT = 0;
 
 export class ConfigParams {
   host: string
   timeoutMs: number
+  retryCount?: number
 }
+{{- end }}
+{{- if ne .Output "models" }}
 
 @Injectable({providedIn: 'root'})
 export class {{.Config.ClassName}} {
-  private readonly config;
+  private readonly config: ConfigParams;
 
   constructor(private httpClient: HttpClient, @Optional() config: ConfigParams) {
     const defaultConfig: ConfigParams = {
       host: DEFAULT_HOST,
       timeoutMs: DEFAULT_TIMEOUT_MS,
+      retryCount: DEFAULT_RETRY_COUNT,
     };
-    this.config = config || defaultConfig;
+    this.config = Object.assign(defaultConfig, config);
   }
 
   {{- range $methodName, $methodData := .RPCDefinitions }}
@@ -593,7 +732,51 @@ export class {{.Config.ClassName}} {
       {{- end}}
     }{{ end }}
 	{{- end }}
+    {{- if $methodData.ServerStreaming }}
+    return this.streamResults<{{- if ne $output "" }}{{ getTypeFromNamespace $output }}{{- else}}any{{- end}}>(this.httpClient.request('{{ upper $methodData.HttpMethod }}', this.config.host + urlPath, { {{ if eq $body true}}body: body, {{end}}params: params{{- if ne $authFunction "" }}, headers: this.{{$authFunction}}{{- end}}, observe: 'events', reportProgress: true, responseType: 'text' }))
+    {{- else }}
     return this.httpClient.{{ $methodData.HttpMethod }}{{- if ne $output ""}}<{{ getTypeFromNamespace $output }}>{{- end}}(this.config.host + urlPath{{- if eq $body true}}, body{{- end}}, { params: params{{- if ne $authFunction "" }}, headers: this.{{$authFunction}}{{- end}} })
+      .pipe(timeout(this.config.timeoutMs), retry(this.config.retryCount));
+    {{- end }}
+  }
+{{- end }}
+{{- if .HasServerStreaming }}
+
+  // Server streaming responses are delivered as newline delimited JSON objects, each wrapping a message in 'result' or an 'error'.
+  private streamResults<T>(events: Observable<HttpEvent<string>>): Observable<T> {
+    return new Observable<T>(subscriber => {
+      let offset = 0;
+      const emit = (text: string, final: boolean) => {
+        const end = final ? text.length : text.lastIndexOf('\n');
+        if (end <= offset) {
+          return;
+        }
+        for (const line of text.substring(offset, end).split('\n')) {
+          if (line.trim() === '') {
+            continue;
+          }
+          const msg = JSON.parse(line);
+          if (msg.error) {
+            subscriber.error(msg.error);
+            return;
+          }
+          subscriber.next(msg.result as T);
+        }
+        offset = end;
+      };
+      const subscription = events.subscribe({
+        next: event => {
+          if (event.type === HttpEventType.DownloadProgress) {
+            emit((event as HttpDownloadProgressEvent).partialText || '', false);
+          } else if (event.type === HttpEventType.Response) {
+            emit(event.body || '', true);
+          }
+        },
+        error: err => subscriber.error(err),
+        complete: () => subscriber.complete(),
+      });
+      return () => subscription.unsubscribe();
+    });
   }
 {{- end }}
 
@@ -605,6 +788,8 @@ export class {{.Config.ClassName}} {
     return new HttpHeaders().set('Authorization', 'Basic ' + btoa(username + ':' + password));
   }
 }
+{{- end }}
+{{- if ne .Output "service" }}
 
 {{- range $defname, $definitions := .MsgDefinitions }}
 {{- $classname := $defname }}
@@ -612,12 +797,21 @@ export class {{.Config.ClassName}} {
 export interface {{ getTypeFromNamespace $classname }} {
   {{- range $field := $definitions }}
   {{- if eq $field.FieldType "enum" }}
-  {{ $field.FieldName }}?: {{ getTypeFromNamespace $field.Namespace -}}{{- if $field.Repeated }}[]{{end}}
+  {{ $field.FieldName }}{{ if not $field.Required }}?{{ end }}: {{ getTypeFromNamespace $field.Namespace -}}{{- if $field.Repeated }}[]{{end}}
   {{- else }}
-  {{ $field.FieldName }}?: {{ if eq $field.Namespace "" }}{{ $field.FieldType -}}{{else}}{{ getTypeFromNamespace $field.FieldType -}}{{end}}{{- if $field.Repeated }}[]{{end}}
+  {{ $field.FieldName }}{{ if not $field.Required }}?{{ end }}: {{ if eq $field.Namespace "" }}{{ $field.FieldType -}}{{else}}{{ getTypeFromNamespace $field.FieldType -}}{{end}}{{- if $field.Repeated }}[]{{end}}
   {{- end }}
   {{- end }}
 }
+{{- if $.Config.TypeGuards }}
+
+export function is{{ getTypeFromNamespace $classname }}(x: any): x is {{ getTypeFromNamespace $classname }} {
+  return x !== null && typeof x === 'object' && !Array.isArray(x)
+  {{- range $field := $definitions }}
+    && {{ typeGuard $field (printf "x.%s" $field.FieldName) }}
+  {{- end }};
+}
+{{- end }}
 {{- end }}
 {{- range $defname, $definitions := .EnumDefinitions }}
 {{- $classname := $defname }}
@@ -627,9 +821,82 @@ export enum {{ getTypeFromNamespace $classname }} {
   {{ $field.Label }} = {{ $field.Number }},
 {{- end }}
 }
+{{- $enumType := getTypeFromNamespace $classname }}
+
+export const {{ $enumType | decapitalize }}Names: {[value: number]: string} = {
+  {{- range $field := $definitions.Fields }}
+  {{ $field.Number }}: '{{ $field.Label }}',
+{{- end }}
+};
+
+export const {{ $enumType | decapitalize }}Values: {[name: string]: {{ $enumType }}} = {
+  {{- range $field := $definitions.Fields }}
+  '{{ $field.Label }}': {{ $enumType }}.{{ $field.Label }},
+{{- end }}
+};
+
+// Converts a {{ $enumType }} marshaled either as its name or its number, returns undefined if the value is unknown.
+export function {{ $enumType | decapitalize }}FromString(value: string | number): {{ $enumType }} | undefined {
+  if (typeof value === 'number') {
+    return {{ $enumType | decapitalize }}Names.hasOwnProperty(value) ? value as {{ $enumType }} : undefined;
+  }
+  if ({{ $enumType | decapitalize }}Values.hasOwnProperty(value)) {
+    return {{ $enumType | decapitalize }}Values[value];
+  }
+  const num = Number(value);
+  return value.trim() !== '' && {{ $enumType | decapitalize }}Names.hasOwnProperty(num) ? num as {{ $enumType }} : undefined;
+}
+
+// Returns the name of a {{ $enumType }} value, or undefined if the value is unknown.
+export function {{ $enumType | decapitalize }}ToString(value: {{ $enumType }}): string | undefined {
+  return {{ $enumType | decapitalize }}Names[value];
+}
+{{- end }}
 {{- end }}
 `
 
+// Builds a TypeScript expression checking that the given value matches the field's type, or is absent if the field is optional.
+func typeGuard(field *MsgField, value string) string {
+	var check func(v string) string
+	switch {
+	case field.Map:
+		check = func(v string) string { return fmt.Sprintf("typeof %s === 'object' && !Array.isArray(%s)", v, v) }
+	case field.FieldType == "enum":
+		// Enums may be marshaled either as their number or their name.
+		check = func(v string) string { return fmt.Sprintf("(typeof %s === 'number' || typeof %s === 'string')", v, v) }
+	case field.Namespace != "":
+		check = func(v string) string { return fmt.Sprintf("is%s(%s)", getTypeFromNamespace(field.FieldType), v) }
+	case field.FieldType == "string", field.FieldType == "number", field.FieldType == "boolean", field.FieldType == "object":
+		check = func(v string) string { return fmt.Sprintf("typeof %s === '%s'", v, field.FieldType) }
+	default:
+		return "true"
+	}
+
+	expr := check(value)
+	if field.Repeated && !field.Map {
+		expr = fmt.Sprintf("(Array.isArray(%s) && %s.every(e => %s))", value, value, check("e"))
+	}
+	if field.Required {
+		return expr
+	}
+	return fmt.Sprintf("(%s == null || %s)", value, expr)
+}
+
+// Converts a service name to a kebab-case file name, e.g.: UserAccounts becomes user-accounts
+func kebabCase(in string) string {
+	var b strings.Builder
+	for i, r := range in {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 func decapitalize(in string) string {
 	return strings.ToLower(in[:1]) + in[1:]
 }
@@ -0,0 +1,303 @@
+// Copyright 2018 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	pbdescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	swagger_options "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+var update = flag.Bool("update", false, "Update the generated output snapshots in testdata.")
+
+// Builds a generator request for a small service exercising the shapes the generator needs to handle.
+func testRequestFixture(t *testing.T) *plugin.CodeGeneratorRequest {
+	method := func(name, input, output string, rule *annotations.HttpRule, serverStreaming, clientStreaming bool) *pbdescriptor.MethodDescriptorProto {
+		opts := &pbdescriptor.MethodOptions{}
+		if err := proto.SetExtension(opts, annotations.E_Http, rule); err != nil {
+			t.Fatalf("error setting http rule: %v", err)
+		}
+		return &pbdescriptor.MethodDescriptorProto{
+			Name:            proto.String(name),
+			InputType:       proto.String(input),
+			OutputType:      proto.String(output),
+			Options:         opts,
+			ServerStreaming: proto.Bool(serverStreaming),
+			ClientStreaming: proto.Bool(clientStreaming),
+		}
+	}
+	field := func(name string, number int32, fieldType pbdescriptor.FieldDescriptorProto_Type, typeName string) *pbdescriptor.FieldDescriptorProto {
+		f := &pbdescriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    pbdescriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     fieldType.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	required := func(f *pbdescriptor.FieldDescriptorProto) *pbdescriptor.FieldDescriptorProto {
+		f.Options = &pbdescriptor.FieldOptions{}
+		if err := proto.SetExtension(f.Options, annotations.E_FieldBehavior, []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED}); err != nil {
+			t.Fatalf("error setting field behavior: %v", err)
+		}
+		return f
+	}
+	repeated := func(f *pbdescriptor.FieldDescriptorProto) *pbdescriptor.FieldDescriptorProto {
+		f.Label = pbdescriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return f
+	}
+
+	fileOpts := &pbdescriptor.FileOptions{GoPackage: proto.String("test")}
+	if err := proto.SetExtension(fileOpts, swagger_options.E_Openapiv2Swagger, &swagger_options.Swagger{
+		Security: []*swagger_options.SecurityRequirement{{
+			SecurityRequirement: map[string]*swagger_options.SecurityRequirement_SecurityRequirementValue{"BearerJwt": {}},
+		}},
+	}); err != nil {
+		t.Fatalf("error setting swagger options: %v", err)
+	}
+
+	emptyFile := &pbdescriptor.FileDescriptorProto{
+		Name:        proto.String("google/protobuf/empty.proto"),
+		Package:     proto.String("google.protobuf"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*pbdescriptor.DescriptorProto{{Name: proto.String("Empty")}},
+	}
+
+	file := &pbdescriptor.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Options:    fileOpts,
+		MessageType: []*pbdescriptor.DescriptorProto{
+			{
+				Name: proto.String("ItemRequest"),
+				Field: []*pbdescriptor.FieldDescriptorProto{
+					field("topic", 1, pbdescriptor.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+			{
+				Name: proto.String("Item"),
+				Field: []*pbdescriptor.FieldDescriptorProto{
+					required(field("id", 1, pbdescriptor.FieldDescriptorProto_TYPE_STRING, "")),
+					field("payload", 2, pbdescriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					repeated(field("tags", 3, pbdescriptor.FieldDescriptorProto_TYPE_STRING, "")),
+					field("state", 4, pbdescriptor.FieldDescriptorProto_TYPE_ENUM, ".test.State"),
+					field("source", 5, pbdescriptor.FieldDescriptorProto_TYPE_MESSAGE, ".test.ItemRequest"),
+					repeated(field("attributes", 6, pbdescriptor.FieldDescriptorProto_TYPE_MESSAGE, ".test.Item.AttributesEntry")),
+				},
+				NestedType: []*pbdescriptor.DescriptorProto{{
+					Name: proto.String("AttributesEntry"),
+					Field: []*pbdescriptor.FieldDescriptorProto{
+						field("key", 1, pbdescriptor.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, pbdescriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					},
+					Options: &pbdescriptor.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+		},
+		EnumType: []*pbdescriptor.EnumDescriptorProto{{
+			Name: proto.String("State"),
+			Value: []*pbdescriptor.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("ACTIVE"), Number: proto.Int32(1)},
+			},
+		}},
+		Service: []*pbdescriptor.ServiceDescriptorProto{{
+			Name: proto.String("Items"),
+			Method: []*pbdescriptor.MethodDescriptorProto{
+				method("GetItem", ".test.ItemRequest", ".test.Item", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/item/{topic}"}}, false, false),
+				method("WatchItems", ".test.ItemRequest", ".test.Item", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items/{topic}"}}, true, false),
+				method("PublishItems", ".test.Item", ".test.Item", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/items"}, Body: "*"}, false, true),
+			},
+		}, {
+			Name: proto.String("ItemAdmin"),
+			Method: []*pbdescriptor.MethodDescriptorProto{
+				method("DeleteItem", ".test.ItemRequest", ".google.protobuf.Empty", &annotations.HttpRule{Pattern: &annotations.HttpRule_Delete{Delete: "/v1/item/{topic}"}}, false, false),
+			},
+		}},
+	}
+
+	return &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		ProtoFile:      []*pbdescriptor.FileDescriptorProto{emptyFile, file},
+	}
+}
+
+func generateFixture(t *testing.T) string {
+	files := generate(testRequestFixture(t))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
+	return files[0].GetContent()
+}
+
+func TestGenerateServerStreaming(t *testing.T) {
+	code := generateFixture(t)
+
+	if !strings.Contains(code, "public getItem(auth_token: string, topic: string): Observable<Item> {") {
+		t.Fatalf("missing unary method:\n%v", code)
+	}
+	if !strings.Contains(code, "return this.httpClient.get<Item>(this.config.host + urlPath") {
+		t.Fatalf("unary method should use a single request:\n%v", code)
+	}
+
+	if !strings.Contains(code, "public watchItems(auth_token: string, topic: string): Observable<Item> {") {
+		t.Fatalf("missing server streaming method:\n%v", code)
+	}
+	if !strings.Contains(code, "return this.streamResults<Item>(this.httpClient.request('GET', this.config.host + urlPath, { params: params, headers: this.getTokenAuthHeaders(auth_token), observe: 'events', reportProgress: true, responseType: 'text' }))") {
+		t.Fatalf("server streaming method should stream results:\n%v", code)
+	}
+	if !strings.Contains(code, "private streamResults<T>(") {
+		t.Fatalf("missing stream helper:\n%v", code)
+	}
+
+	if strings.Contains(code, "publishItems") {
+		t.Fatalf("client streaming method should be skipped:\n%v", code)
+	}
+}
+
+func TestGenerateTypeGuards(t *testing.T) {
+	if code := generateFixture(t); strings.Contains(code, "export function is") {
+		t.Fatalf("type guards should not be generated by default:\n%v", code)
+	}
+
+	*typeGuards = true
+	defer func() { *typeGuards = false }()
+	code := generateFixture(t)
+
+	expected := `export function isItem(x: any): x is Item {
+  return x !== null && typeof x === 'object' && !Array.isArray(x)
+    && typeof x.id === 'string'
+    && (x.payload == null || typeof x.payload === 'string')
+    && (x.tags == null || (Array.isArray(x.tags) && x.tags.every(e => typeof e === 'string')))
+    && (x.state == null || (typeof x.state === 'number' || typeof x.state === 'string'))
+    && (x.source == null || isItemRequest(x.source))
+    && (x.attributes == null || typeof x.attributes === 'object' && !Array.isArray(x.attributes));
+}`
+	if !strings.Contains(code, expected) {
+		t.Fatalf("missing type guard for Item:\n%v", code)
+	}
+	if !strings.Contains(code, "export function isItemRequest(x: any): x is ItemRequest {") {
+		t.Fatalf("missing type guard for ItemRequest:\n%v", code)
+	}
+}
+
+func TestGenerateRequiredFields(t *testing.T) {
+	code := generateFixture(t)
+
+	expected := `export interface Item {
+  id: string
+  payload?: string
+  tags?: string[]
+  state?: State
+  source?: ItemRequest
+  attributes?: ItemAttributesEntry[]
+}`
+	if !strings.Contains(code, expected) {
+		t.Fatalf("required field should not be optional:\n%v", code)
+	}
+}
+
+func TestGenerateEnumConverters(t *testing.T) {
+	code := generateFixture(t)
+
+	for _, expected := range []string{
+		"export const stateNames: {[value: number]: string} = {\n  0: 'UNKNOWN',\n  1: 'ACTIVE',\n};",
+		"export const stateValues: {[name: string]: State} = {\n  'UNKNOWN': State.UNKNOWN,\n  'ACTIVE': State.ACTIVE,\n};",
+		"export function stateFromString(value: string | number): State | undefined {",
+		"export function stateToString(value: State): string | undefined {",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Fatalf("missing enum converter %q:\n%v", expected, code)
+		}
+	}
+}
+
+func TestGenerateSplitServices(t *testing.T) {
+	*splitServices = true
+	defer func() { *splitServices = false }()
+
+	files := generate(testRequestFixture(t))
+	if len(files) != 3 {
+		t.Fatalf("expected models and 2 service files, got %v", len(files))
+	}
+
+	if files[0].GetName() != "models.ts" {
+		t.Fatalf("unexpected models file name: %v", files[0].GetName())
+	}
+	models := files[0].GetContent()
+	for _, expected := range []string{"export const DEFAULT_HOST = ", "export class ConfigParams {", "export interface Item {", "export enum State {"} {
+		if !strings.Contains(models, expected) {
+			t.Fatalf("models file missing %q:\n%v", expected, models)
+		}
+	}
+	if strings.Contains(models, "@Injectable") {
+		t.Fatalf("models file should not contain services:\n%v", models)
+	}
+
+	if files[1].GetName() != "items.service.ts" || files[2].GetName() != "item-admin.service.ts" {
+		t.Fatalf("unexpected service file names: %v, %v", files[1].GetName(), files[2].GetName())
+	}
+	items := files[1].GetContent()
+	for _, expected := range []string{
+		"import { ConfigParams, DEFAULT_HOST, DEFAULT_RETRY_COUNT, DEFAULT_TIMEOUT_MS, Item, ItemAttributesEntry, ItemRequest, State } from './models';",
+		"export class ItemsService {",
+		"public getItem(",
+	} {
+		if !strings.Contains(items, expected) {
+			t.Fatalf("items service file missing %q:\n%v", expected, items)
+		}
+	}
+	if strings.Contains(items, "deleteItem") || strings.Contains(items, "export interface") {
+		t.Fatalf("items service file should only contain its own service:\n%v", items)
+	}
+	admin := files[2].GetContent()
+	if !strings.Contains(admin, "export class ItemAdminService {") || !strings.Contains(admin, "public deleteItem(") {
+		t.Fatalf("admin service file missing its service:\n%v", admin)
+	}
+}
+
+func TestGenerateSnapshot(t *testing.T) {
+	*typeGuards = true
+	defer func() { *typeGuards = false }()
+	code := generateFixture(t)
+
+	snapshot := filepath.Join("testdata", "http.service.ts")
+	if *update {
+		if err := ioutil.WriteFile(snapshot, []byte(code), 0644); err != nil {
+			t.Fatalf("error updating snapshot: %v", err)
+		}
+	}
+	expected, err := ioutil.ReadFile(snapshot)
+	if err != nil {
+		t.Fatalf("error reading snapshot: %v", err)
+	}
+	if code != string(expected) {
+		t.Fatalf("generated output does not match %v, run the tests with -update to refresh it if the change is intended:\n%v", snapshot, code)
+	}
+}
@@ -206,11 +206,15 @@ func main() {
 	tracker.SetPartyLeaveListener(partyRegistry.Leave)
 
 	storageIndex.RegisterFilters(runtime)
+	tracker.SetStreamPresenceListeners(runtime.StreamPresenceListeners())
 	go func() {
 		if err = storageIndex.Load(ctx); err != nil {
 			logger.Error("Failed to load storage index entries from database", zap.Error(err))
 		}
 	}()
+	server.StartStorageExpirySweeper(ctx, logger, db, storageIndex)
+	server.StartBanExpirySweeper(ctx, logger, db, sessionCache)
+	server.StartEventLogSweeper(ctx, logger, db, config)
 
 	leaderboardScheduler.Start(runtime)
 	googleRefundScheduler.Start(runtime)
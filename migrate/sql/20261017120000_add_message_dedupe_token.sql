@@ -0,0 +1,29 @@
+/*
+ * Copyright 2026 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+ALTER TABLE message
+    ADD COLUMN IF NOT EXISTS dedupe_token VARCHAR(128);
+
+CREATE UNIQUE INDEX IF NOT EXISTS message_sender_id_dedupe_token_stream_idx
+    ON message (sender_id, dedupe_token, stream_mode, stream_subject, stream_descriptor, stream_label)
+    WHERE dedupe_token IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS message_sender_id_dedupe_token_stream_idx;
+
+ALTER TABLE message
+    DROP COLUMN IF EXISTS dedupe_token;
@@ -0,0 +1,22 @@
+/*
+ * Copyright 2026 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE INDEX IF NOT EXISTS subscription_product_id_purchase_time_transaction_id_idx
+    ON subscription (product_id, purchase_time DESC, original_transaction_id DESC);
+
+-- +migrate Down
+DROP INDEX IF EXISTS subscription_product_id_purchase_time_transaction_id_idx;
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2026 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS event_log (
+    PRIMARY KEY (id),
+
+    id          UUID         NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    properties  JSONB        NOT NULL DEFAULT '{}',
+    external    BOOLEAN      NOT NULL DEFAULT FALSE,
+    timestamp   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    create_time TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS event_log_name_timestamp_id_idx ON event_log (name, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS event_log_timestamp_id_idx ON event_log (timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS event_log_create_time_idx ON event_log (create_time);
+
+-- +migrate Down
+DROP TABLE IF EXISTS event_log;
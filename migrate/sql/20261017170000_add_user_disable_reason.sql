@@ -0,0 +1,29 @@
+/*
+ * Copyright 2026 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS disable_reason VARCHAR(512),
+    ADD COLUMN IF NOT EXISTS disable_until  TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_disable_until_idx ON users (disable_until) WHERE disable_until IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS users_disable_until_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disable_until,
+    DROP COLUMN IF EXISTS disable_reason;
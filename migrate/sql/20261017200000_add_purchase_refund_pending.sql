@@ -0,0 +1,27 @@
+/*
+ * Copyright 2026 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+ALTER TABLE purchase
+    ADD COLUMN IF NOT EXISTS refund_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS purchase_refund_pending_idx ON purchase (update_time) WHERE refund_pending;
+
+-- +migrate Down
+DROP INDEX IF EXISTS purchase_refund_pending_idx;
+
+ALTER TABLE purchase
+    DROP COLUMN IF EXISTS refund_pending;
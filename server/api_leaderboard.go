@@ -200,6 +200,8 @@ func (s *ApiServer) WriteLeaderboardRecord(ctx context.Context, in *api.WriteLea
 		return nil, status.Error(codes.NotFound, "Leaderboard not found.")
 	} else if err == ErrLeaderboardAuthoritative {
 		return nil, status.Error(codes.PermissionDenied, "Leaderboard only allows authoritative score submissions.")
+	} else if err == ErrLeaderboardMaxNumScoreReached {
+		return nil, status.Error(codes.FailedPrecondition, "Reached allowed max number of score attempts.")
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, "Error writing score to leaderboard.")
 	}
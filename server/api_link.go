@@ -51,7 +51,7 @@ func (s *ApiServer) LinkApple(ctx context.Context, in *api.AccountApple) (*empty
 		}
 	}
 
-	err := LinkApple(ctx, s.logger, s.db, s.config, s.socialClient, userID, in.Token)
+	_, err := LinkApple(ctx, s.logger, s.db, s.config, s.socialClient, userID, in.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +232,7 @@ func (s *ApiServer) LinkFacebook(ctx context.Context, in *api.LinkFacebookReques
 		return nil, status.Error(codes.InvalidArgument, "Facebook access token is required.")
 	}
 
-	err := LinkFacebook(ctx, s.logger, s.db, s.socialClient, s.tracker, s.router, userID, username, s.config.GetSocial().FacebookLimitedLogin.AppId, in.Account.Token, in.Sync == nil || in.Sync.Value)
+	_, err := LinkFacebook(ctx, s.logger, s.db, s.socialClient, s.tracker, s.router, s.runtime.NotificationPush(), userID, username, s.config.GetSocial().FacebookLimitedLogin.AppId, in.Account.Token, in.Sync == nil || in.Sync.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -280,7 +280,7 @@ func (s *ApiServer) LinkFacebookInstantGame(ctx context.Context, in *api.Account
 		return nil, status.Error(codes.InvalidArgument, "Signed Player Info for a Facebook Instant Game is required.")
 	}
 
-	err := LinkFacebookInstantGame(ctx, s.logger, s.db, s.config, s.socialClient, userID, in.SignedPlayerInfo)
+	_, err := LinkFacebookInstantGame(ctx, s.logger, s.db, s.config, s.socialClient, userID, in.SignedPlayerInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -324,7 +324,7 @@ func (s *ApiServer) LinkGameCenter(ctx context.Context, in *api.AccountGameCente
 		}
 	}
 
-	err := LinkGameCenter(ctx, s.logger, s.db, s.socialClient, userID, in.PlayerId, in.BundleId, in.TimestampSeconds, in.Salt, in.Signature, in.PublicKeyUrl)
+	_, err := LinkGameCenter(ctx, s.logger, s.db, s.socialClient, userID, in.PlayerId, in.BundleId, in.TimestampSeconds, in.Salt, in.Signature, in.PublicKeyUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -368,7 +368,7 @@ func (s *ApiServer) LinkGoogle(ctx context.Context, in *api.AccountGoogle) (*emp
 		}
 	}
 
-	err := LinkGoogle(ctx, s.logger, s.db, s.socialClient, userID, in.Token)
+	_, err := LinkGoogle(ctx, s.logger, s.db, s.socialClient, userID, in.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -417,7 +417,7 @@ func (s *ApiServer) LinkSteam(ctx context.Context, in *api.LinkSteamRequest) (*e
 		return nil, status.Error(codes.InvalidArgument, "Steam access token is required.")
 	}
 
-	err := LinkSteam(ctx, s.logger, s.db, s.config, s.socialClient, s.tracker, s.router, userID, username, in.Account.Token, in.Sync == nil || in.Sync.Value)
+	_, err := LinkSteam(ctx, s.logger, s.db, s.config, s.socialClient, s.tracker, s.router, s.runtime.NotificationPush(), userID, username, in.Account.Token, in.Sync == nil || in.Sync.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +63,7 @@ func (s *ApiServer) ValidatePurchaseApple(ctx context.Context, in *api.ValidateP
 		persist = in.Persist.GetValue()
 	}
 
-	validation, err := ValidatePurchasesApple(ctx, s.logger, s.db, userID, s.config.GetIAP().Apple.SharedPassword, in.Receipt, persist)
+	validation, err := ValidatePurchasesApple(ctx, s.logger, s.db, s.runtime.PurchaseRefund(), userID, s.config.GetIAP().Apple.SharedPassword, in.Receipt, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +120,7 @@ func (s *ApiServer) ValidatePurchaseGoogle(ctx context.Context, in *api.Validate
 		persist = in.Persist.GetValue()
 	}
 
-	validation, err := ValidatePurchaseGoogle(ctx, s.logger, s.db, userID, s.config.GetIAP().Google, in.Purchase, persist)
+	validation, err := ValidatePurchaseGoogle(ctx, s.logger, s.db, s.runtime.PurchaseRefund(), userID, s.config.GetIAP().Google, in.Purchase, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +183,7 @@ func (s *ApiServer) ValidatePurchaseHuawei(ctx context.Context, in *api.Validate
 		persist = in.Persist.GetValue()
 	}
 
-	validation, err := ValidatePurchaseHuawei(ctx, s.logger, s.db, userID, s.config.GetIAP().Huawei, in.Purchase, in.Signature, persist)
+	validation, err := ValidatePurchaseHuawei(ctx, s.logger, s.db, s.runtime.PurchaseRefund(), userID, s.config.GetIAP().Huawei, in.Purchase, in.Signature, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +240,7 @@ func (s *ApiServer) ValidatePurchaseFacebookInstant(ctx context.Context, in *api
 		persist = in.Persist.GetValue()
 	}
 
-	validation, err := ValidatePurchaseFacebookInstant(ctx, s.logger, s.db, userID, s.config.GetIAP().FacebookInstant, in.SignedRequest, persist)
+	validation, err := ValidatePurchaseFacebookInstant(ctx, s.logger, s.db, s.runtime.PurchaseRefund(), userID, s.config.GetIAP().FacebookInstant, in.SignedRequest, persist)
 	if err != nil {
 		return nil, err
 	}
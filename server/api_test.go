@@ -64,10 +64,13 @@ func (d *DummyMessageRouter) SendDeferred(*zap.Logger, []*DeferredMessage) {
 	panic("unused")
 }
 
-func (d *DummyMessageRouter) SendToPresenceIDs(*zap.Logger, []*PresenceID, *rtapi.Envelope, bool) {
+func (d *DummyMessageRouter) SendToPresenceIDs(*zap.Logger, []*PresenceID, *rtapi.Envelope, bool) int {
+	return 0
 }
-func (d *DummyMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) {}
-func (d *DummyMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool)                    {}
+func (d *DummyMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) int {
+	return 0
+}
+func (d *DummyMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool) {}
 
 type DummySession struct {
 	messages []*rtapi.Envelope
@@ -94,6 +97,7 @@ func (d *DummySession) SetUsername(string) {}
 func (d *DummySession) Vars() map[string]string {
 	return nil
 }
+func (d *DummySession) SetVars(map[string]string) {}
 
 func (d *DummySession) Expiry() int64 {
 	return int64(0)
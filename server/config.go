@@ -225,6 +225,12 @@ func ValidateConfig(logger *zap.Logger, c Config) map[string]string {
 	if c.GetRuntime().GetLuaRegistrySize() < 128 {
 		logger.Fatal("Lua runtime instance registry size must be >= 128", zap.Int("runtime.registry_size", c.GetRuntime().GetLuaRegistrySize()))
 	}
+	if c.GetRuntime().LuaJsonMaxDepth < 1 {
+		logger.Fatal("Lua runtime JSON encoding maximum depth must be >= 1", zap.Int("runtime.lua_json_max_depth", c.GetRuntime().LuaJsonMaxDepth))
+	}
+	if c.GetRuntime().LuaJsonMaxSize < 1 {
+		logger.Fatal("Lua runtime JSON encoding maximum size must be >= 1", zap.Int("runtime.lua_json_max_size", c.GetRuntime().LuaJsonMaxSize))
+	}
 	if c.GetRuntime().JsMinCount < 0 {
 		logger.Fatal("Minimum JavaScript runtime instance count must be >= 0", zap.Int("runtime.js_min_count", c.GetRuntime().JsMinCount))
 	}
@@ -237,6 +243,9 @@ func ValidateConfig(logger *zap.Logger, c Config) map[string]string {
 	if c.GetRuntime().EventQueueSize < 1 {
 		logger.Fatal("Runtime event queue stack size must be >= 1", zap.Int("runtime.event_queue_size", c.GetRuntime().EventQueueSize))
 	}
+	if c.GetRuntime().EventLogMaxAgeSec < 0 {
+		logger.Fatal("Runtime event log max age must be >= 0", zap.Int("runtime.event_log_max_age_sec", c.GetRuntime().EventLogMaxAgeSec))
+	}
 	if c.GetRuntime().EventQueueWorkers < 1 {
 		logger.Fatal("Runtime event queue workers must be >= 1", zap.Int("runtime.event_queue_workers", c.GetRuntime().EventQueueWorkers))
 	}
@@ -746,6 +755,7 @@ type SessionConfig struct {
 	SingleMatch           bool   `yaml:"single_match" json:"single_match" usage:"Only allow one match per user. Older matches receive a leave. Requires single socket to enable. Default false."`
 	SingleParty           bool   `yaml:"single_party" json:"single_party" usage:"Only allow one party per user. Older parties receive a leave. Requires single socket to enable. Default false."`
 	SingleSession         bool   `yaml:"single_session" json:"single_session" usage:"Only allow one session token per user. Older session tokens are invalidated in the session cache. Default false."`
+	CustomIdSalt          string `yaml:"custom_id_salt" json:"custom_id_salt" usage:"Salt used to hash custom IDs when hashed custom authentication is requested by the runtime. Changing it makes previously hashed custom IDs unreachable."`
 }
 
 func (cfg *SessionConfig) GetEncryptionKey() string {
@@ -1081,6 +1091,11 @@ type RuntimeConfig struct {
 	JsReadOnlyGlobals  bool              `yaml:"js_read_only_globals" json:"js_read_only_globals" usage:"When enabled marks all Javascript runtime globals as read-only to reduce memory footprint. Default true."`
 	LuaApiStacktrace   bool              `yaml:"lua_api_stacktrace" json:"lua_api_stacktrace" usage:"Include the Lua stacktrace in error responses returned to the client. Default false."`
 	JsEntrypoint       string            `yaml:"js_entrypoint" json:"js_entrypoint" usage:"Specifies the location of the bundled JavaScript runtime source code."`
+	EventLog           bool              `yaml:"event_log" json:"event_log" usage:"Persist custom runtime events to the database so they can be listed by the runtime. Default false."`
+	EventLogMaxAgeSec  int               `yaml:"event_log_max_age_sec" json:"event_log_max_age_sec" usage:"Number of seconds persisted runtime events are kept before they are deleted. 0 keeps them indefinitely. Default 2592000 (30 days)."`
+	RpcMetrics         bool              `yaml:"rpc_metrics" json:"rpc_metrics" usage:"Record invocation count, error count and latency metrics tagged by RPC ID for every runtime RPC function call. Default false."`
+	LuaJsonMaxDepth    int               `yaml:"lua_json_max_depth" json:"lua_json_max_depth" usage:"Maximum nesting depth of Lua tables encoded to JSON by the runtime json_encode functions. Default 128."`
+	LuaJsonMaxSize     int               `yaml:"lua_json_max_size" json:"lua_json_max_size" usage:"Maximum size in bytes of JSON produced by the Lua runtime json_encode functions. Default 16777216."`
 }
 
 func (r *RuntimeConfig) GetEnv() []string {
@@ -1170,6 +1185,11 @@ func NewRuntimeConfig() *RuntimeConfig {
 		LuaReadOnlyGlobals: true,
 		JsReadOnlyGlobals:  true,
 		LuaApiStacktrace:   false,
+		EventLog:           false,
+		EventLogMaxAgeSec:  2_592_000,
+		RpcMetrics:         false,
+		LuaJsonMaxDepth:    128,
+		LuaJsonMaxSize:     16_777_216,
 	}
 }
 
@@ -1536,8 +1556,9 @@ func (i IAPFacebookInstantConfig) GetAppSecret() string {
 var _ runtime.GoogleAuthConfig = &GoogleAuthConfig{}
 
 type GoogleAuthConfig struct {
-	CredentialsJSON string         `yaml:"credentials_json" json:"credentials_json" usage:"Google's Access Credentials."`
-	OAuthConfig     *oauth2.Config `yaml:"-" json:"-"`
+	CredentialsJSON  string         `yaml:"credentials_json" json:"credentials_json" usage:"Google's Access Credentials."`
+	AllowedAudiences []string       `yaml:"allowed_audiences" json:"allowed_audiences" usage:"List of OAuth client IDs accepted as the audience of Google ID tokens. If empty any audience is accepted."`
+	OAuthConfig      *oauth2.Config `yaml:"-" json:"-"`
 }
 
 func (cfg *GoogleAuthConfig) GetCredentialsJSON() string {
@@ -1551,6 +1572,11 @@ func (cfg *GoogleAuthConfig) Clone() *GoogleAuthConfig {
 
 	cfgCopy := *cfg
 
+	if cfg.AllowedAudiences != nil {
+		cfgCopy.AllowedAudiences = make([]string, len(cfg.AllowedAudiences))
+		copy(cfgCopy.AllowedAudiences, cfg.AllowedAudiences)
+	}
+
 	if cfg.OAuthConfig != nil {
 		c := *cfg.OAuthConfig
 		if cfg.OAuthConfig.Scopes != nil {
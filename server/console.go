@@ -175,6 +175,7 @@ type ConsoleServer struct {
 	leaderboardRankCache LeaderboardRankCache
 	leaderboardScheduler LeaderboardScheduler
 	api                  *ApiServer
+	runtime              *Runtime
 	rpcMethodCache       *rpcReflectCache
 	cookie               string
 	httpClient           *http.Client
@@ -223,6 +224,7 @@ func StartConsoleServer(logger *zap.Logger, startupLogger *zap.Logger, db *sql.D
 		leaderboardScheduler: leaderboardScheduler,
 		storageIndex:         storageIndex,
 		api:                  api,
+		runtime:              runtime,
 		cookie:               cookie,
 		httpClient:           &http.Client{Timeout: 5 * time.Second},
 	}
@@ -281,7 +283,7 @@ func StartConsoleServer(logger *zap.Logger, startupLogger *zap.Logger, db *sql.D
 	// Register public subscription callback endpoints
 	if config.GetIAP().Apple.NotificationsEndpointId != "" {
 		endpoint := fmt.Sprintf("/v2/console/apple/subscriptions/%s", config.GetIAP().Apple.NotificationsEndpointId)
-		grpcGatewayRouter.HandleFunc(endpoint, appleNotificationHandler(logger, db, runtime.PurchaseNotificationApple(), runtime.SubscriptionNotificationApple()))
+		grpcGatewayRouter.HandleFunc(endpoint, appleNotificationHandler(logger, db, runtime.PurchaseRefund(), runtime.PurchaseNotificationApple(), runtime.SubscriptionNotificationApple()))
 		logger.Info("Registered endpoint for Apple subscription notifications callback", zap.String("endpoint", endpoint))
 	}
 
@@ -130,7 +130,7 @@ func (s *ConsoleServer) DeleteGroupUser(ctx context.Context, in *console.DeleteG
 		return nil, status.Error(codes.InvalidArgument, "Requires a valid group ID.")
 	}
 
-	if err = KickGroupUsers(ctx, s.logger, s.db, s.tracker, s.router, s.streamManager, uuid.Nil, groupID, []uuid.UUID{userID}, true); err != nil {
+	if err = KickGroupUsers(ctx, s.logger, s.db, s.runtime.GroupEvent(), s.tracker, s.router, s.streamManager, uuid.Nil, groupID, []uuid.UUID{userID}, true); err != nil {
 		// Error already logged in function above.
 		if err == ErrEmptyMemberKick {
 			return nil, status.Error(codes.FailedPrecondition, "Cannot kick user from group.")
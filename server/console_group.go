@@ -170,7 +170,7 @@ func (s *ConsoleServer) DeleteGroup(ctx context.Context, in *console.DeleteGroup
 		return nil, status.Error(codes.InvalidArgument, "Requires a valid group ID.")
 	}
 
-	if err = DeleteGroup(ctx, s.logger, s.db, s.tracker, groupID, uuid.Nil); err != nil {
+	if err = DeleteGroup(ctx, s.logger, s.db, s.runtime.GroupEvent(), s.tracker, groupID, uuid.Nil); err != nil {
 		// Error already logged in function above.
 		return nil, status.Error(codes.Internal, "An error occurred while trying to delete the user.")
 	}
@@ -393,6 +393,9 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		}
 		return nil, status.Error(codes.Internal, "An error occurred while trying to demote the user in the group.")
 	}
+
+	groupEventInvoke(s.runtime.GroupEvent(), groupID, []uuid.UUID{userID}, GroupEventDemote)
+
 	return &emptypb.Empty{}, nil
 }
 
@@ -534,6 +537,8 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		return nil, status.Error(codes.Internal, "An error occurred while trying to promote the user in the group.")
 	}
 
+	groupEventInvoke(s.runtime.GroupEvent(), groupID, []uuid.UUID{userID}, GroupEventPromote)
+
 	return &emptypb.Empty{}, nil
 }
 
@@ -565,12 +570,12 @@ func (s *ConsoleServer) AddGroupUsers(ctx context.Context, in *console.AddGroupU
 				s.logger.Debug("Could not retrieve username to join user to group.", zap.Error(err), zap.String("user_id", uid.String()))
 				return nil, status.Error(codes.Internal, "An error occurred while trying to join the user to the group. Refresh the page to see any updates.")
 			}
-			if err = JoinGroup(ctx, s.logger, s.db, s.tracker, s.router, groupUid, uid, username.String); err != nil {
+			if err = JoinGroup(ctx, s.logger, s.db, s.runtime.GroupEvent(), s.tracker, s.router, s.runtime.NotificationPush(), groupUid, uid, username.String); err != nil {
 				return nil, status.Error(codes.Internal, "An error occurred while trying to join an user to the group, refresh the page: "+err.Error()+". Refresh the page to see any updates.")
 			}
 		}
 	} else {
-		if err = AddGroupUsers(ctx, s.logger, s.db, s.tracker, s.router, uuid.Nil, groupUid, uuids); err != nil {
+		if err = AddGroupUsers(ctx, s.logger, s.db, s.runtime.GroupEvent(), s.tracker, s.router, s.runtime.NotificationPush(), uuid.Nil, groupUid, uuids); err != nil {
 			return nil, status.Error(codes.Internal, "An error occurred while trying to add the users: "+err.Error())
 		}
 	}
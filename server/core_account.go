@@ -15,6 +15,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
 	"github.com/heroiclabs/nakama/v3/console"
 
 	"github.com/jackc/pgx/v5"
@@ -49,9 +51,18 @@ type accountUpdate struct {
 	langTag     *wrapperspb.StringValue
 	avatarURL   *wrapperspb.StringValue
 	metadata    *wrapperspb.StringValue
+	// Deep merge the metadata into the existing metadata instead of replacing it.
+	mergeMetadata bool
 }
 
 func GetAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userID uuid.UUID) (*api.Account, error) {
+	account, _, _, err := GetAccountWithBan(ctx, logger, db, statusRegistry, userID)
+	return account, err
+}
+
+// GetAccountWithBan returns the account as GetAccount does, along with the reason stored when the user was banned and
+// the time the ban will be lifted in UTC seconds, or 0 if the ban is permanent or the user is not banned.
+func GetAccountWithBan(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userID uuid.UUID) (*api.Account, string, int64, error) {
 	var username sql.NullString
 	var displayName sql.NullString
 	var avatarURL sql.NullString
@@ -73,6 +84,8 @@ func GetAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegis
 	var updateTime pgtype.Timestamptz
 	var verifyTime pgtype.Timestamptz
 	var disableTime pgtype.Timestamptz
+	var disableReason sql.NullString
+	var disableUntil pgtype.Timestamptz
 	var deviceIDs pgtype.FlatArray[string]
 
 	m := pgtype.NewMap()
@@ -80,16 +93,16 @@ func GetAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegis
 	query := `
 SELECT u.username, u.display_name, u.avatar_url, u.lang_tag, u.location, u.timezone, u.metadata, u.wallet,
 	u.email, u.apple_id, u.facebook_id, u.facebook_instant_game_id, u.google_id, u.gamecenter_id, u.steam_id, u.custom_id, u.edge_count,
-	u.create_time, u.update_time, u.verify_time, u.disable_time, array(select ud.id from user_device ud where u.id = ud.user_id)
+	u.create_time, u.update_time, u.verify_time, u.disable_time, u.disable_reason, u.disable_until, array(select ud.id from user_device ud where u.id = ud.user_id)
 FROM users u
 WHERE u.id = $1`
 
-	if err := db.QueryRowContext(ctx, query, userID).Scan(&username, &displayName, &avatarURL, &langTag, &location, &timezone, &metadata, &wallet, &email, &apple, &facebook, &facebookInstantGame, &google, &gamecenter, &steam, &customID, &edgeCount, &createTime, &updateTime, &verifyTime, &disableTime, m.SQLScanner(&deviceIDs)); err != nil {
+	if err := db.QueryRowContext(ctx, query, userID).Scan(&username, &displayName, &avatarURL, &langTag, &location, &timezone, &metadata, &wallet, &email, &apple, &facebook, &facebookInstantGame, &google, &gamecenter, &steam, &customID, &edgeCount, &createTime, &updateTime, &verifyTime, &disableTime, &disableReason, &disableUntil, m.SQLScanner(&deviceIDs)); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, ErrAccountNotFound
+			return nil, "", 0, ErrAccountNotFound
 		}
 		logger.Error("Error retrieving user account.", zap.Error(err))
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	devices := make([]*api.AccountDevice, 0, len(deviceIDs))
@@ -105,6 +118,10 @@ WHERE u.id = $1`
 	if disableTime.Valid && disableTime.Time.Unix() != 0 {
 		disableTimestamp = &timestamppb.Timestamp{Seconds: disableTime.Time.Unix()}
 	}
+	var unbanTime int64
+	if disableUntil.Valid {
+		unbanTime = disableUntil.Time.Unix()
+	}
 
 	online := false
 	if statusRegistry != nil {
@@ -138,7 +155,7 @@ WHERE u.id = $1`
 		CustomId:    customID.String,
 		VerifyTime:  verifyTimestamp,
 		DisableTime: disableTimestamp,
-	}, nil
+	}, disableReason.String, unbanTime, nil
 }
 
 func GetAccounts(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userIDs []string) ([]*api.Account, error) {
@@ -333,7 +350,25 @@ func updateAccounts(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates
 		}
 
 		if update.metadata != nil {
-			params = append(params, update.metadata.GetValue())
+			// The transaction may be retried, so the merged value must never be written back into the update itself.
+			metadata := update.metadata.GetValue()
+			if update.mergeMetadata {
+				// Lock the row so concurrent merges are applied one after the other rather than clobbering each other.
+				var existing []byte
+				if err := tx.QueryRow(ctx, "SELECT metadata FROM users WHERE id = $1 FOR UPDATE", update.userID).Scan(&existing); err != nil {
+					if errors.Is(err, pgx.ErrNoRows) {
+						return ErrAccountNotFound
+					}
+					logger.Error("Could not read user account metadata for merge.", zap.Error(err), zap.String("user_id", update.userID.String()))
+					return err
+				}
+				merged, err := mergeAccountMetadata(existing, []byte(metadata))
+				if err != nil {
+					return err
+				}
+				metadata = string(merged)
+			}
+			params = append(params, metadata)
 			updateStatements = append(updateStatements, "metadata = $"+strconv.Itoa(len(params)))
 			distinctStatements = append(distinctStatements, "metadata IS DISTINCT FROM $"+strconv.Itoa(len(params)))
 		}
@@ -365,7 +400,69 @@ func updateAccounts(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates
 	return nil
 }
 
+// mergeAccountMetadata deep merges the update into the existing metadata. Nested objects are merged key by key,
+// any other value in the update, including arrays and nulls, replaces the existing value (last writer wins).
+func mergeAccountMetadata(existing, update []byte) ([]byte, error) {
+	var existingMap map[string]any
+	if len(existing) > 0 {
+		if err := decodeMetadataMap(existing, &existingMap); err != nil {
+			return nil, errors.New("Existing metadata is not a JSON object.")
+		}
+	}
+	var updateMap map[string]any
+	if err := decodeMetadataMap(update, &updateMap); err != nil {
+		return nil, errors.New("Metadata must be a JSON object.")
+	}
+
+	return json.Marshal(deepMergeMaps(existingMap, updateMap))
+}
+
+// decodeMetadataMap decodes numbers as json.Number so integers beyond float64 precision survive the merge.
+func decodeMetadataMap(data []byte, m *map[string]any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(m); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected trailing data")
+	}
+	return nil
+}
+
+func deepMergeMaps(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			dst[k] = deepMergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// ExportAccountOptions control which sections are included in an account export. The zero value produces the full
+// export.
+type ExportAccountOptions struct {
+	ExcludeWallet   bool
+	ExcludeLedger   bool
+	ExcludeStorage  bool
+	ExcludeMessages bool
+	// Redact strips identifiers linking the account to devices and external services: email, custom ID, device IDs
+	// and social provider IDs.
+	Redact bool
+}
+
 func ExportAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID) (*console.AccountExport, error) {
+	return ExportAccountWithOptions(ctx, logger, db, userID, ExportAccountOptions{})
+}
+
+func ExportAccountWithOptions(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, opts ExportAccountOptions) (*console.AccountExport, error) {
 	// Core user account.
 	account, err := GetAccount(ctx, logger, db, nil, userID)
 	if err != nil {
@@ -383,11 +480,29 @@ func ExportAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, userID u
 		return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
 	}
 
+	if opts.ExcludeWallet {
+		account.Wallet = ""
+	}
+	if opts.Redact {
+		account.Email = ""
+		account.CustomId = ""
+		account.Devices = nil
+		account.User.FacebookId = ""
+		account.User.FacebookInstantGameId = ""
+		account.User.GoogleId = ""
+		account.User.GamecenterId = ""
+		account.User.SteamId = ""
+		account.User.AppleId = ""
+	}
+
 	// Messages.
-	messages, err := GetChannelMessages(ctx, logger, db, userID)
-	if err != nil {
-		logger.Error("Could not fetch messages", zap.Error(err), zap.String("user_id", userID.String()))
-		return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+	var messages []*api.ChannelMessage
+	if !opts.ExcludeMessages {
+		messages, err = GetChannelMessages(ctx, logger, db, userID)
+		if err != nil {
+			logger.Error("Could not fetch messages", zap.Error(err), zap.String("user_id", userID.String()))
+			return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+		}
 	}
 
 	// Leaderboard records.
@@ -415,17 +530,23 @@ func ExportAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, userID u
 	}
 
 	// Storage objects where user is the owner.
-	storageObjects, err := StorageReadAllUserObjects(ctx, logger, db, userID)
-	if err != nil {
-		logger.Error("Could not fetch notifications", zap.Error(err), zap.String("user_id", userID.String()))
-		return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+	var storageObjects []*api.StorageObject
+	if !opts.ExcludeStorage {
+		storageObjects, err = StorageReadAllUserObjects(ctx, logger, db, userID)
+		if err != nil {
+			logger.Error("Could not fetch notifications", zap.Error(err), zap.String("user_id", userID.String()))
+			return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+		}
 	}
 
 	// History of user's wallet.
-	walletLedgers, _, _, err := ListWalletLedger(ctx, logger, db, userID, nil, "")
-	if err != nil {
-		logger.Error("Could not fetch wallet ledger items", zap.Error(err), zap.String("user_id", userID.String()))
-		return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+	var walletLedgers []*walletLedger
+	if !opts.ExcludeLedger {
+		walletLedgers, _, _, err = ListWalletLedger(ctx, logger, db, userID, nil, "")
+		if err != nil {
+			logger.Error("Could not fetch wallet ledger items", zap.Error(err), zap.String("user_id", userID.String()))
+			return nil, status.Error(codes.Internal, "An error occurred while trying to export user data.")
+		}
 	}
 	wl := make([]*console.WalletLedger, len(walletLedgers))
 	for i, w := range walletLedgers {
@@ -523,3 +644,511 @@ func DeleteAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config C
 
 	return nil
 }
+
+// AccountAnonymizeResult describes the personal data removed from an account by AnonymizeAccount.
+type AccountAnonymizeResult struct {
+	// Username is the tombstone username that replaced the original.
+	Username string
+	// Fields lists the account fields that held a value and were scrubbed.
+	Fields []string
+	// Devices is the number of device IDs unlinked from the account.
+	Devices int64
+	// LeaderboardRecords is the number of leaderboard records that had the username replaced.
+	LeaderboardRecords int64
+	// Messages is the number of channel messages that had the username replaced.
+	Messages int64
+}
+
+// AnonymizeAccount scrubs personal data from an account instead of deleting it. The username is replaced with a
+// tombstone value, and the display name, avatar, location, timezone, email, password, social provider IDs, custom
+// ID, device IDs and metadata are cleared. The user row, wallet, ledger, leaderboard records and group memberships are
+// kept so historical leaderboards and economy data stay intact. Existing sessions are logged out and disconnected.
+func AnonymizeAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, sessionRegistry SessionRegistry, sessionCache SessionCache, tracker Tracker, userID uuid.UUID) (*AccountAnonymizeResult, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("cannot anonymize the system user")
+	}
+
+	result := &AccountAnonymizeResult{Username: "deleted-" + userID.String()}
+
+	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+		// Reset in case the transaction is retried.
+		result.Fields = make([]string, 0, 14)
+
+		query := `
+SELECT username, display_name, avatar_url, location, timezone, metadata, email, password, apple_id, facebook_id,
+	facebook_instant_game_id, google_id, gamecenter_id, steam_id, custom_id
+FROM users
+WHERE id = $1
+FOR UPDATE`
+		var username string
+		var metadata string
+		var password []byte
+		var displayName, avatarURL, location, timezone, email, appleID, facebookID, facebookInstantGameID, googleID, gamecenterID, steamID, customID sql.NullString
+		if err := tx.QueryRowContext(ctx, query, userID).Scan(&username, &displayName, &avatarURL, &location, &timezone, &metadata, &email, &password, &appleID, &facebookID, &facebookInstantGameID, &googleID, &gamecenterID, &steamID, &customID); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+
+		if username != result.Username {
+			result.Fields = append(result.Fields, "username")
+		}
+		for _, field := range []struct {
+			name  string
+			value sql.NullString
+		}{
+			{"display_name", displayName},
+			{"avatar_url", avatarURL},
+			{"location", location},
+			{"timezone", timezone},
+			{"email", email},
+			{"apple_id", appleID},
+			{"facebook_id", facebookID},
+			{"facebook_instant_game_id", facebookInstantGameID},
+			{"google_id", googleID},
+			{"gamecenter_id", gamecenterID},
+			{"steam_id", steamID},
+			{"custom_id", customID},
+		} {
+			if field.value.Valid && field.value.String != "" {
+				result.Fields = append(result.Fields, field.name)
+			}
+		}
+		if len(password) > 0 {
+			result.Fields = append(result.Fields, "password")
+		}
+		if metadata != "{}" {
+			result.Fields = append(result.Fields, "metadata")
+		}
+
+		query = `
+UPDATE users
+SET username = $2, display_name = NULL, avatar_url = NULL, location = NULL, timezone = NULL, metadata = '{}',
+	email = NULL, password = NULL, apple_id = NULL, facebook_id = NULL, facebook_instant_game_id = NULL, google_id = NULL,
+	gamecenter_id = NULL, steam_id = NULL, custom_id = NULL, update_time = now()
+WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, userID, result.Username); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM user_device WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		if result.Devices, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		res, err = tx.ExecContext(ctx, "UPDATE leaderboard_record SET username = $2 WHERE owner_id = $1 AND username IS DISTINCT FROM $2", userID, result.Username)
+		if err != nil {
+			return err
+		}
+		if result.LeaderboardRecords, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		res, err = tx.ExecContext(ctx, "UPDATE message SET username = $2 WHERE sender_id = $1 AND username <> $2", userID, result.Username)
+		if err != nil {
+			return err
+		}
+		if result.Messages, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		if err == ErrAccountNotFound {
+			return nil, err
+		}
+		logger.Error("Error occurred while trying to anonymize the user.", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+
+	// Logout and disconnect.
+	if err := SessionLogout(config, sessionCache, userID, "", ""); err != nil {
+		return nil, err
+	}
+	for _, presence := range tracker.ListPresenceIDByStream(PresenceStream{Mode: StreamModeNotifications, Subject: userID}) {
+		if err := sessionRegistry.Disconnect(ctx, presence.SessionID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("Anonymized user account.", zap.String("user_id", userID.String()), zap.Strings("fields", result.Fields))
+
+	return result, nil
+}
+
+// AccountMergeOptions controls optional parts of MergeAccounts.
+type AccountMergeOptions struct {
+	// LeaderboardRecords also moves leaderboard and tournament records from the source to the target.
+	LeaderboardRecords bool
+	// DeleteSource deletes the source account after the merge instead of disabling it.
+	DeleteSource bool
+}
+
+// AccountMergeResult describes the data moved by MergeAccounts. Conflicts count source data that was not moved
+// because the target already had an equivalent entry.
+type AccountMergeResult struct {
+	// Wallet is the target's wallet after the merge, or nil if the source wallet was empty.
+	Wallet           map[string]int64
+	StorageObjects   int64
+	StorageConflicts int64
+	Friends          int64
+	FriendConflicts  int64
+	Groups           int64
+	GroupConflicts   int64
+	// GroupsSkipped lists groups whose membership stayed with the source because moving it would exceed the group's
+	// max count.
+	GroupsSkipped              []string
+	LeaderboardRecords         int64
+	LeaderboardRecordConflicts int64
+	// SourceDeleted is false if DeleteSource was requested but the delete failed after the merge was committed, in
+	// which case the source is left disabled.
+	SourceDeleted bool
+}
+
+type accountMergeLeaderboardRecord struct {
+	leaderboardID string
+	expiryTime    int64
+	score         int64
+	subscore      int64
+	numScore      int32
+}
+
+// MergeAccounts moves the data of a source account into a target account in a single transaction, then disables the
+// source, or deletes it if requested. The delete runs after the merge is committed, so if it fails the source is left
+// disabled and the result reports it as not deleted. Conflicts are resolved per subsystem as follows:
+//   - Wallet: source balances are added to the target's balances, and the source wallet is emptied. Both changes are
+//     recorded in the wallet ledger. The merge fails if the result would be negative.
+//   - Storage: source objects are moved unless the target already has an object with the same collection and key, in
+//     which case the target's object is kept.
+//   - Friends: source relationships are moved unless the target already has a relationship with the same user, in
+//     which case the target's is kept. A relationship between the source and target is removed.
+//   - Groups: source memberships are moved unless the target is already in the group or has requested to join it. In
+//     that case the target keeps its membership, promoted to the source's role if that was higher, unless the target
+//     is banned from the group. Groups that would exceed their max count are skipped and reported in the result.
+//   - Leaderboard records, if requested: source records are moved unless the target already has a record in the same
+//     leaderboard period, in which case the target's record is kept.
+//
+// Device IDs, social provider IDs, notifications and channel messages stay with the source.
+func MergeAccounts(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, sessionRegistry SessionRegistry, sessionCache SessionCache, tracker Tracker, storageIndex StorageIndex, sourceID, targetID uuid.UUID, opts AccountMergeOptions) (*AccountMergeResult, error) {
+	if sourceID == uuid.Nil || targetID == uuid.Nil {
+		return nil, errors.New("cannot merge the system user")
+	}
+	if sourceID == targetID {
+		return nil, errors.New("cannot merge an account into itself")
+	}
+
+	metadata, err := json.Marshal(map[string]string{"merged_from": sourceID.String(), "merged_into": targetID.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	var result *AccountMergeResult
+	var movedObjects []*api.StorageObject
+	var movedRecords []*accountMergeLeaderboardRecord
+	if err = ExecuteInTxPgx(ctx, db, func(tx pgx.Tx) error {
+		// Reset in case the transaction is retried.
+		result = &AccountMergeResult{}
+		movedObjects = make([]*api.StorageObject, 0)
+		movedRecords = make([]*accountMergeLeaderboardRecord, 0)
+
+		var sourceWallet, targetUsername string
+		if err := tx.QueryRow(ctx, "SELECT wallet FROM users WHERE id = $1 FOR UPDATE", sourceID).Scan(&sourceWallet); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+		if err := tx.QueryRow(ctx, "SELECT username FROM users WHERE id = $1 FOR UPDATE", targetID).Scan(&targetUsername); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrAccountNotFound
+			}
+			return err
+		}
+
+		// Wallet.
+		balances := make(map[string]int64)
+		if err := json.Unmarshal([]byte(sourceWallet), &balances); err != nil {
+			return err
+		}
+		changeset := make(map[string]int64, len(balances))
+		reverse := make(map[string]int64, len(balances))
+		for currency, amount := range balances {
+			if amount != 0 {
+				changeset[currency] = amount
+				reverse[currency] = -amount
+			}
+		}
+		if len(changeset) > 0 {
+			results, err := updateWallets(ctx, logger, tx, []*walletUpdate{
+				{UserID: targetID, Changeset: changeset, Metadata: string(metadata)},
+				{UserID: sourceID, Changeset: reverse, Metadata: string(metadata)},
+			}, true)
+			if err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.UserID == targetID.String() {
+					result.Wallet = r.Updated
+				}
+			}
+		}
+
+		// Storage.
+		query := `
+UPDATE storage SET user_id = $2
+WHERE user_id = $1 AND NOT EXISTS (
+	SELECT 1 FROM storage AS s WHERE s.user_id = $2 AND s.collection = storage.collection AND s.key = storage.key
+)
+RETURNING collection, key, value, version, read, write, create_time, update_time`
+		rows, err := tx.Query(ctx, query, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			o := &api.StorageObject{UserId: targetID.String()}
+			var createTime, updateTime pgtype.Timestamptz
+			if err := rows.Scan(&o.Collection, &o.Key, &o.Value, &o.Version, &o.PermissionRead, &o.PermissionWrite, &createTime, &updateTime); err != nil {
+				rows.Close()
+				return err
+			}
+			o.CreateTime = timestamppb.New(createTime.Time)
+			o.UpdateTime = timestamppb.New(updateTime.Time)
+			movedObjects = append(movedObjects, o)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		result.StorageObjects = int64(len(movedObjects))
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM storage WHERE user_id = $1", sourceID).Scan(&result.StorageConflicts); err != nil {
+			return err
+		}
+
+		// Friends. Every user with a relationship to the source needs their edge count refreshed afterwards.
+		edgeUserIDs := []uuid.UUID{sourceID, targetID}
+		rows, err = tx.Query(ctx, "SELECT destination_id FROM user_edge WHERE source_id = $1 UNION SELECT source_id FROM user_edge WHERE destination_id = $1", sourceID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			edgeUserIDs = append(edgeUserIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM user_edge WHERE (source_id = $1 AND destination_id = $2) OR (source_id = $2 AND destination_id = $1)", sourceID, targetID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(ctx, "DELETE FROM user_edge WHERE source_id = $1 AND destination_id IN (SELECT destination_id FROM user_edge WHERE source_id = $2)", sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		result.FriendConflicts = res.RowsAffected()
+		if _, err := tx.Exec(ctx, "DELETE FROM user_edge WHERE destination_id = $1 AND source_id IN (SELECT source_id FROM user_edge WHERE destination_id = $2)", sourceID, targetID); err != nil {
+			return err
+		}
+		if res, err = tx.Exec(ctx, "UPDATE user_edge SET source_id = $2 WHERE source_id = $1", sourceID, targetID); err != nil {
+			return err
+		}
+		result.Friends = res.RowsAffected()
+		if _, err := tx.Exec(ctx, "UPDATE user_edge SET destination_id = $2 WHERE destination_id = $1", sourceID, targetID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE users SET edge_count = (SELECT COUNT(*) FROM user_edge WHERE source_id = users.id), update_time = now() WHERE id = ANY($1::UUID[])", edgeUserIDs); err != nil {
+			return err
+		}
+
+		// Groups. Every group the source is in needs its member count refreshed afterwards.
+		groupIDs := make([]uuid.UUID, 0)
+		rows, err = tx.Query(ctx, "SELECT destination_id FROM group_edge WHERE source_id = $1", sourceID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			groupIDs = append(groupIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(groupIDs) > 0 {
+			// Lock the groups, and skip any that would exceed their max count once the merged membership is counted.
+			// Lower states are higher roles, banned targets are never promoted, and states below 3 count as members.
+			query = `
+SELECT g.id FROM groups AS g
+JOIN group_edge AS s ON s.source_id = g.id AND s.destination_id = $1
+LEFT JOIN group_edge AS t ON t.source_id = g.id AND t.destination_id = $2
+WHERE g.id = ANY($3::UUID[])
+AND (SELECT COUNT(*) FROM group_edge AS e WHERE e.source_id = g.id AND e.state < 3 AND e.destination_id <> $1 AND e.destination_id <> $2)
+	+ CASE WHEN t.state = 4 THEN 0 WHEN LEAST(s.state, COALESCE(t.state, s.state)) < 3 THEN 1 ELSE 0 END > g.max_count
+FOR UPDATE OF g`
+			rows, err = tx.Query(ctx, query, sourceID, targetID, groupIDs)
+			if err != nil {
+				return err
+			}
+			skipped := make(map[uuid.UUID]struct{})
+			for rows.Next() {
+				var id uuid.UUID
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return err
+				}
+				skipped[id] = struct{}{}
+				result.GroupsSkipped = append(result.GroupsSkipped, id.String())
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			mergeGroupIDs := make([]uuid.UUID, 0, len(groupIDs))
+			for _, id := range groupIDs {
+				if _, ok := skipped[id]; !ok {
+					mergeGroupIDs = append(mergeGroupIDs, id)
+				}
+			}
+
+			query = `
+UPDATE group_edge SET state = s.state, update_time = now()
+FROM group_edge AS s
+WHERE group_edge.source_id = $2 AND s.source_id = $1 AND s.destination_id = group_edge.destination_id
+AND group_edge.destination_id = ANY($3::UUID[]) AND group_edge.state <> 4 AND s.state < group_edge.state`
+			if _, err := tx.Exec(ctx, query, sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			query = `
+UPDATE group_edge SET state = s.state, update_time = now()
+FROM group_edge AS s
+WHERE group_edge.destination_id = $2 AND s.destination_id = $1 AND s.source_id = group_edge.source_id
+AND group_edge.source_id = ANY($3::UUID[]) AND group_edge.state <> 4 AND s.state < group_edge.state`
+			if _, err := tx.Exec(ctx, query, sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			if res, err = tx.Exec(ctx, "DELETE FROM group_edge WHERE source_id = $1 AND destination_id = ANY($3::UUID[]) AND destination_id IN (SELECT destination_id FROM group_edge WHERE source_id = $2)", sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			result.GroupConflicts = res.RowsAffected()
+			if _, err := tx.Exec(ctx, "DELETE FROM group_edge WHERE destination_id = $1 AND source_id = ANY($3::UUID[]) AND source_id IN (SELECT source_id FROM group_edge WHERE destination_id = $2)", sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			if res, err = tx.Exec(ctx, "UPDATE group_edge SET source_id = $2 WHERE source_id = $1 AND destination_id = ANY($3::UUID[])", sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			result.Groups = res.RowsAffected()
+			if _, err := tx.Exec(ctx, "UPDATE group_edge SET destination_id = $2 WHERE destination_id = $1 AND source_id = ANY($3::UUID[])", sourceID, targetID, mergeGroupIDs); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "UPDATE groups SET edge_count = (SELECT COUNT(*) FROM group_edge WHERE source_id = groups.id AND state < 3), update_time = now() WHERE id = ANY($1::UUID[])", mergeGroupIDs); err != nil {
+				return err
+			}
+		}
+
+		// Leaderboard records.
+		if opts.LeaderboardRecords {
+			query = `
+UPDATE leaderboard_record SET owner_id = $2, username = $3
+WHERE owner_id = $1 AND NOT EXISTS (
+	SELECT 1 FROM leaderboard_record AS r
+	WHERE r.owner_id = $2 AND r.leaderboard_id = leaderboard_record.leaderboard_id AND r.expiry_time = leaderboard_record.expiry_time
+)
+RETURNING leaderboard_id, expiry_time, score, subscore, num_score`
+			rows, err = tx.Query(ctx, query, sourceID, targetID, targetUsername)
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				r := &accountMergeLeaderboardRecord{}
+				var expiryTime pgtype.Timestamptz
+				if err := rows.Scan(&r.leaderboardID, &expiryTime, &r.score, &r.subscore, &r.numScore); err != nil {
+					rows.Close()
+					return err
+				}
+				r.expiryTime = expiryTime.Time.Unix()
+				movedRecords = append(movedRecords, r)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			result.LeaderboardRecords = int64(len(movedRecords))
+			if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM leaderboard_record WHERE owner_id = $1", sourceID).Scan(&result.LeaderboardRecordConflicts); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE users SET disable_time = now(), disable_reason = $2, update_time = now() WHERE id = $1", sourceID, "merged into "+targetID.String()); err != nil {
+			return err
+		}
+
+		return nil
+	}); err != nil {
+		if err == ErrAccountNotFound {
+			return nil, err
+		}
+		if _, ok := err.(*runtime.WalletNegativeError); ok {
+			logger.Info("Could not merge accounts, wallet would become negative.", zap.String("source_id", sourceID.String()), zap.String("target_id", targetID.String()))
+			return nil, err
+		}
+		logger.Error("Error occurred while trying to merge accounts.", zap.Error(err), zap.String("source_id", sourceID.String()), zap.String("target_id", targetID.String()))
+		return nil, err
+	}
+
+	// Refresh the storage index and rank cache now that the moves are committed.
+	if len(movedObjects) > 0 {
+		deletes := make(StorageOpDeletes, 0, len(movedObjects))
+		for _, o := range movedObjects {
+			deletes = append(deletes, &StorageOpDelete{OwnerID: sourceID.String(), ObjectID: &api.DeleteStorageObjectId{Collection: o.Collection, Key: o.Key}})
+		}
+		storageIndex.Delete(ctx, deletes)
+		storageIndex.Write(ctx, movedObjects)
+	}
+	nowUnix := time.Now().UTC().Unix()
+	for _, r := range movedRecords {
+		if r.expiryTime != 0 && r.expiryTime <= nowUnix {
+			// Expired ranks are handled by the rank cache itself.
+			continue
+		}
+		leaderboard := leaderboardCache.Get(r.leaderboardID)
+		if leaderboard == nil {
+			continue
+		}
+		rankCache.Delete(r.leaderboardID, r.expiryTime, sourceID)
+		rankCache.Insert(r.leaderboardID, leaderboard.SortOrder, r.score, r.subscore, r.numScore, r.expiryTime, targetID, leaderboard.EnableRanks)
+	}
+
+	if opts.DeleteSource {
+		// The merge is already committed, so a failed delete leaves the source disabled rather than failing the merge.
+		if err := DeleteAccount(ctx, logger, db, config, leaderboardCache, rankCache, sessionRegistry, sessionCache, tracker, sourceID, false); err != nil {
+			logger.Warn("Merged user accounts but could not delete the source, it remains disabled.", zap.Error(err), zap.String("source_id", sourceID.String()), zap.String("target_id", targetID.String()))
+		} else {
+			result.SourceDeleted = true
+		}
+	}
+	if !result.SourceDeleted {
+		// Logout and disconnect the now disabled source.
+		sessionCache.Ban([]uuid.UUID{sourceID})
+		for _, presence := range tracker.ListPresenceIDByStream(PresenceStream{Mode: StreamModeNotifications, Subject: sourceID}) {
+			if err := sessionRegistry.Disconnect(ctx, presence.SessionID, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	logger.Info("Merged user accounts.", zap.String("source_id", sourceID.String()), zap.String("target_id", targetID.String()), zap.Bool("source_deleted", result.SourceDeleted))
+
+	return result, nil
+}
@@ -0,0 +1,270 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMergeAccountMetadata(t *testing.T) {
+	merged, err := mergeAccountMetadata(
+		[]byte(`{"a":1,"nested":{"x":1,"y":1},"list":[1,2]}`),
+		[]byte(`{"b":2,"nested":{"y":2,"z":2},"list":[3]}`),
+	)
+	if err != nil {
+		t.Fatalf("error merging metadata: %v", err.Error())
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(merged, &result); err != nil {
+		t.Fatalf("error decoding merged metadata: %v", err.Error())
+	}
+
+	assert.Equal(t, map[string]any{
+		"a":      float64(1),
+		"b":      float64(2),
+		"nested": map[string]any{"x": float64(1), "y": float64(2), "z": float64(2)},
+		"list":   []any{float64(3)},
+	}, result)
+
+	if _, err := mergeAccountMetadata([]byte(`{}`), []byte(`[]`)); err == nil {
+		t.Fatal("expected error merging non-object metadata")
+	}
+
+	// Integers beyond float64 precision are kept intact.
+	merged, err = mergeAccountMetadata([]byte(`{"id":9007199254740993}`), []byte(`{"count":18446744073709551615}`))
+	if err != nil {
+		t.Fatalf("error merging metadata: %v", err.Error())
+	}
+	assert.JSONEq(t, `{"id":9007199254740993,"count":18446744073709551615}`, string(merged))
+	assert.Contains(t, string(merged), "9007199254740993")
+}
+
+func TestUpdateAccountsConcurrentMetadataMerge(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	var wg sync.WaitGroup
+	for _, metadata := range []string{`{"first":{"value":1}}`, `{"second":{"value":2}}`} {
+		wg.Add(1)
+		go func(metadata string) {
+			defer wg.Done()
+			if err := UpdateAccounts(context.Background(), logger, db, []*accountUpdate{{
+				userID:        userID,
+				metadata:      &wrapperspb.StringValue{Value: metadata},
+				mergeMetadata: true,
+			}}); err != nil {
+				t.Errorf("error updating account: %v", err.Error())
+			}
+		}(metadata)
+	}
+	wg.Wait()
+
+	account, err := GetAccount(context.Background(), logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+
+	var metadata map[string]any
+	if err := json.Unmarshal([]byte(account.User.Metadata), &metadata); err != nil {
+		t.Fatalf("error decoding metadata: %v", err.Error())
+	}
+	assert.Contains(t, metadata, "first", "first merge should persist")
+	assert.Contains(t, metadata, "second", "second merge should persist")
+}
+
+func TestExportAccountWithOptions(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	if _, err := db.ExecContext(ctx, "UPDATE users SET email = $2, custom_id = $3, steam_id = $4 WHERE id = $1", userID, userID.String()+"@example.com", userID.String(), userID.String()); err != nil {
+		t.Fatalf("error updating user: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO user_device (id, user_id) VALUES ($1, $2)", userID.String(), userID); err != nil {
+		t.Fatalf("error inserting device: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO storage (collection, key, user_id, value, version) VALUES ('export', 'key', $1, '{}', 'version')", userID); err != nil {
+		t.Fatalf("error inserting storage object: %v", err.Error())
+	}
+
+	full, err := ExportAccount(ctx, logger, db, userID)
+	if err != nil {
+		t.Fatalf("error exporting account: %v", err.Error())
+	}
+	assert.NotEmpty(t, full.Account.Email)
+	assert.Len(t, full.Account.Devices, 1)
+	assert.Len(t, full.Objects, 1)
+
+	redacted, err := ExportAccountWithOptions(ctx, logger, db, userID, ExportAccountOptions{ExcludeStorage: true, ExcludeWallet: true, Redact: true})
+	if err != nil {
+		t.Fatalf("error exporting account: %v", err.Error())
+	}
+	assert.Empty(t, redacted.Account.Email)
+	assert.Empty(t, redacted.Account.CustomId)
+	assert.Empty(t, redacted.Account.Devices)
+	assert.Empty(t, redacted.Account.User.SteamId)
+	assert.Empty(t, redacted.Account.Wallet)
+	assert.Empty(t, redacted.Objects)
+	assert.Equal(t, full.Account.User.Username, redacted.Account.User.Username)
+}
+
+func TestAnonymizeAccount(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	if _, err := db.ExecContext(ctx, "UPDATE users SET display_name = 'Player', email = $2, custom_id = $3, metadata = '{\"name\":\"Player\"}', wallet = '{\"coins\":10}' WHERE id = $1", userID, userID.String()+"@example.com", userID.String()); err != nil {
+		t.Fatalf("error updating user: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO user_device (id, user_id) VALUES ($1, $2)", userID.String(), userID); err != nil {
+		t.Fatalf("error inserting device: %v", err.Error())
+	}
+
+	result, err := AnonymizeAccount(ctx, logger, db, cfg, NewLocalSessionRegistry(metrics), NewLocalSessionCache(3_600, 7_200), &testTracker{}, userID)
+	if err != nil {
+		t.Fatalf("error anonymizing account: %v", err.Error())
+	}
+	assert.Equal(t, "deleted-"+userID.String(), result.Username)
+	assert.ElementsMatch(t, []string{"username", "display_name", "email", "custom_id", "metadata"}, result.Fields)
+	assert.EqualValues(t, 1, result.Devices)
+
+	account, err := GetAccount(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+	assert.Equal(t, result.Username, account.User.Username)
+	assert.Empty(t, account.User.DisplayName)
+	assert.Empty(t, account.Email)
+	assert.Empty(t, account.CustomId)
+	assert.Empty(t, account.Devices)
+	assert.Equal(t, "{}", account.User.Metadata)
+	assert.Equal(t, `{"coins": 10}`, account.Wallet, "wallet should be kept")
+
+	// Anonymizing again has nothing left to scrub.
+	result, err = AnonymizeAccount(ctx, logger, db, cfg, NewLocalSessionRegistry(metrics), NewLocalSessionCache(3_600, 7_200), &testTracker{}, userID)
+	if err != nil {
+		t.Fatalf("error anonymizing account: %v", err.Error())
+	}
+	assert.Empty(t, result.Fields)
+}
+
+func TestMergeAccounts(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	sourceID := uuid.Must(uuid.NewV4())
+	targetID := uuid.Must(uuid.NewV4())
+	friendID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, sourceID)
+	InsertUser(t, db, targetID)
+	InsertUser(t, db, friendID)
+
+	if _, err := db.ExecContext(ctx, "UPDATE users SET wallet = '{\"coins\":10,\"gems\":2}' WHERE id = $1", sourceID); err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE users SET wallet = '{\"coins\":5}' WHERE id = $1", targetID); err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	for _, o := range []struct {
+		userID uuid.UUID
+		key    string
+		value  string
+	}{{sourceID, "a", `{"owner":"source"}`}, {sourceID, "b", `{"owner":"source"}`}, {targetID, "b", `{"owner":"target"}`}} {
+		if _, err := db.ExecContext(ctx, "INSERT INTO storage (collection, key, user_id, value, version, read, write) VALUES ('merge', $1, $2, $3, md5($3::VARCHAR), 1, 1)", o.key, o.userID, o.value); err != nil {
+			t.Fatalf("error inserting storage object: %v", err.Error())
+		}
+	}
+	// A full group whose members outnumber its max count, so recounting it with the source's membership moved would
+	// fail the max count check. The membership is skipped instead.
+	fullGroupID := uuid.Must(uuid.NewV4())
+	memberID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, memberID)
+	if _, err := db.ExecContext(ctx, "INSERT INTO groups (id, creator_id, name, edge_count, max_count) VALUES ($1, $2, $3, 2, 2)", fullGroupID, friendID, fullGroupID.String()); err != nil {
+		t.Fatalf("error inserting group: %v", err.Error())
+	}
+	for i, e := range []struct {
+		userID uuid.UUID
+		state  int
+	}{{friendID, 0}, {memberID, 2}, {sourceID, 2}} {
+		if _, err := db.ExecContext(ctx, "INSERT INTO group_edge (source_id, destination_id, state, position) VALUES ($1, $2, $3, $4), ($2, $1, $3, $4)", fullGroupID, e.userID, e.state, i+1); err != nil {
+			t.Fatalf("error inserting group edge: %v", err.Error())
+		}
+	}
+	for _, e := range [][2]uuid.UUID{{sourceID, friendID}, {friendID, sourceID}} {
+		if _, err := db.ExecContext(ctx, "INSERT INTO user_edge (source_id, destination_id, state, position) VALUES ($1, $2, 0, 1)", e[0], e[1]); err != nil {
+			t.Fatalf("error inserting friend edge: %v", err.Error())
+		}
+	}
+
+	storageIdx, err := NewLocalStorageIndex(logger, db, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatalf("error creating storage index: %v", err.Error())
+	}
+
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	result, err := MergeAccounts(ctx, logger, db, cfg, leaderboardCache, rankCache, NewLocalSessionRegistry(metrics), NewLocalSessionCache(3_600, 7_200), &testTracker{}, storageIdx, sourceID, targetID, AccountMergeOptions{})
+	if err != nil {
+		t.Fatalf("error merging accounts: %v", err.Error())
+	}
+	assert.Equal(t, map[string]int64{"coins": 15, "gems": 2}, result.Wallet)
+	assert.EqualValues(t, 1, result.StorageObjects)
+	assert.EqualValues(t, 1, result.StorageConflicts)
+	assert.EqualValues(t, 1, result.Friends)
+	assert.EqualValues(t, 0, result.FriendConflicts)
+	assert.EqualValues(t, 0, result.Groups)
+	assert.Equal(t, []string{fullGroupID.String()}, result.GroupsSkipped)
+	assert.False(t, result.SourceDeleted)
+
+	var value string
+	if err := db.QueryRowContext(ctx, "SELECT value FROM storage WHERE collection = 'merge' AND key = 'b' AND user_id = $1", targetID).Scan(&value); err != nil {
+		t.Fatalf("error reading storage object: %v", err.Error())
+	}
+	assert.JSONEq(t, `{"owner":"target"}`, value, "target object should be kept on conflict")
+
+	var friendState int
+	if err := db.QueryRowContext(ctx, "SELECT state FROM user_edge WHERE source_id = $1 AND destination_id = $2", friendID, targetID).Scan(&friendState); err != nil {
+		t.Fatalf("error reading friend edge: %v", err.Error())
+	}
+	assert.Equal(t, 0, friendState)
+
+	account, err := GetAccount(ctx, logger, db, nil, sourceID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+	assert.NotNil(t, account.DisableTime, "source should be disabled")
+	assert.EqualValues(t, 0, account.User.EdgeCount)
+
+	// Merging an account into itself is rejected.
+	_, err = MergeAccounts(ctx, logger, db, cfg, leaderboardCache, rankCache, NewLocalSessionRegistry(metrics), NewLocalSessionCache(3_600, 7_200), &testTracker{}, storageIdx, targetID, targetID, AccountMergeOptions{})
+	assert.Error(t, err)
+}
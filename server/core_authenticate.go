@@ -16,10 +16,14 @@ package server
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -179,6 +183,23 @@ func AuthenticateCustom(ctx context.Context, logger *zap.Logger, db *sql.DB, cus
 	return userID, username, true, nil
 }
 
+// HashCustomID produces a deterministic salted hash of a custom ID so the plaintext identifier is never stored.
+func HashCustomID(salt, customID string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	_, _ = mac.Write([]byte(customID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuthenticateCustomHashed authenticates using a salted hash of the given custom ID in place of the raw value.
+func AuthenticateCustomHashed(ctx context.Context, logger *zap.Logger, db *sql.DB, salt, customID, username string, create bool) (string, string, bool, error) {
+	if salt == "" {
+		logger.Error("Hashed custom authentication requested but no custom ID salt is configured.")
+		return "", "", false, status.Error(codes.FailedPrecondition, "Hashed custom ID authentication is not configured.")
+	}
+
+	return AuthenticateCustom(ctx, logger, db, HashCustomID(salt, customID), username, create)
+}
+
 func AuthenticateDevice(ctx context.Context, logger *zap.Logger, db *sql.DB, deviceID, username string, create bool) (string, string, bool, error) {
 	found := true
 
@@ -624,12 +645,16 @@ func RemapGoogleId(ctx context.Context, logger *zap.Logger, db *sql.DB, googlePr
 	return err
 }
 
-func AuthenticateGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, client *social.Client, idToken, username string, create bool) (string, string, bool, error) {
+func AuthenticateGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, client *social.Client, idToken, username string, create bool, allowedAudiences []string) (string, string, bool, error) {
 	googleProfile, err := client.CheckGoogleToken(ctx, idToken)
 	if err != nil {
 		logger.Info("Could not authenticate Google profile.", zap.Error(err))
 		return "", "", false, status.Error(codes.Unauthenticated, "Could not authenticate Google profile.")
 	}
+	if !checkGoogleAudience(googleProfile, allowedAudiences) {
+		logger.Info("Google ID token audience is not allowed.", zap.Strings("allowed_audiences", allowedAudiences))
+		return "", "", false, status.Error(codes.Unauthenticated, "Could not authenticate Google profile.")
+	}
 	found := true
 
 	// Look for an existing account.
@@ -756,6 +781,19 @@ func AuthenticateGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, cli
 	return userID, username, true, nil
 }
 
+// checkGoogleAudience reports whether an ID token profile was issued for one of the allowed OAuth client IDs.
+// Profiles obtained through the server's own authorization code exchange carry no audience and are always accepted.
+func checkGoogleAudience(profile social.GoogleProfile, allowedAudiences []string) bool {
+	if len(allowedAudiences) == 0 {
+		return true
+	}
+	jwtProfile, ok := profile.(*social.JWTGoogleProfile)
+	if !ok {
+		return true
+	}
+	return slices.Contains(allowedAudiences, jwtProfile.Aud)
+}
+
 func AuthenticateSteam(ctx context.Context, logger *zap.Logger, db *sql.DB, client *social.Client, appID int, publisherKey, token, username string, create bool) (string, string, string, bool, error) {
 	steamProfile, err := client.GetSteamProfile(ctx, publisherKey, appID, token)
 	if err != nil {
@@ -824,7 +862,7 @@ func AuthenticateSteam(ctx context.Context, logger *zap.Logger, db *sql.DB, clie
 	return userID, username, steamID, true, nil
 }
 
-func importSteamFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, client *social.Client, userID uuid.UUID, username, publisherKey, steamId string, reset bool) error {
+func importSteamFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, client *social.Client, userID uuid.UUID, username, publisherKey, steamId string, reset bool) error {
 	logger = logger.With(zap.String("userID", userID.String()))
 
 	steamProfiles, err := client.GetSteamFriends(ctx, publisherKey, steamId)
@@ -894,13 +932,13 @@ func importSteamFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tra
 	}
 
 	if len(friendUserIDs) != 0 {
-		sendFriendAddedNotification(ctx, logger, db, tracker, messageRouter, userID, username, friendUserIDs)
+		sendFriendAddedNotification(ctx, logger, db, tracker, messageRouter, pushFn, userID, username, friendUserIDs)
 	}
 
 	return nil
 }
 
-func importFacebookFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, client *social.Client, userID uuid.UUID, username, token string, reset bool) error {
+func importFacebookFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, client *social.Client, userID uuid.UUID, username, token string, reset bool) error {
 	logger = logger.With(zap.String("userID", userID.String()))
 
 	facebookProfiles, err := client.GetFacebookFriends(ctx, token)
@@ -968,7 +1006,7 @@ func importFacebookFriends(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	}
 
 	if len(friendUserIDs) != 0 {
-		sendFriendAddedNotification(ctx, logger, db, tracker, messageRouter, userID, username, friendUserIDs)
+		sendFriendAddedNotification(ctx, logger, db, tracker, messageRouter, pushFn, userID, username, friendUserIDs)
 	}
 
 	return nil
@@ -1100,7 +1138,7 @@ AND EXISTS
 	return friendUserIDs
 }
 
-func sendFriendAddedNotification(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, userID uuid.UUID, username string, friendUserIDs []uuid.UUID) {
+func sendFriendAddedNotification(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username string, friendUserIDs []uuid.UUID) {
 	notifications := make(map[uuid.UUID][]*api.Notification, len(friendUserIDs))
 	content, _ := json.Marshal(map[string]interface{}{"username": username})
 	subject := "Your friend has just joined the game"
@@ -1117,5 +1155,5 @@ func sendFriendAddedNotification(ctx context.Context, logger *zap.Logger, db *sq
 		}}
 	}
 	// Any error is already logged before it's returned here.
-	_ = NotificationSend(ctx, logger, db, tracker, messageRouter, notifications)
+	_ = NotificationSend(ctx, logger, db, tracker, messageRouter, pushFn, notifications)
 }
@@ -0,0 +1,74 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama/v3/social"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashCustomID(t *testing.T) {
+	customID := uuid.Must(uuid.NewV4()).String()
+
+	hash := HashCustomID("salt", customID)
+	assert.Equal(t, hash, HashCustomID("salt", customID), "same input and salt must produce the same hash")
+	assert.NotEqual(t, customID, hash, "hash must not contain the plaintext ID")
+	assert.NotEqual(t, hash, HashCustomID("other-salt", customID), "different salts must produce different hashes")
+	assert.LessOrEqual(t, len(hash), 128, "hash must fit the custom ID column")
+}
+
+func TestAuthenticateCustomHashed(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	customID := uuid.Must(uuid.NewV4()).String()
+	salt := "test-salt"
+
+	userID, _, created, err := AuthenticateCustomHashed(context.Background(), logger, db, salt, customID, uuid.Must(uuid.NewV4()).String(), true)
+	if err != nil {
+		t.Fatalf("error creating user: %v", err.Error())
+	}
+	assert.True(t, created, "user should be created")
+
+	sameUserID, _, created, err := AuthenticateCustomHashed(context.Background(), logger, db, salt, customID, uuid.Must(uuid.NewV4()).String(), true)
+	if err != nil {
+		t.Fatalf("error authenticating user: %v", err.Error())
+	}
+	assert.False(t, created, "user should not be created again")
+	assert.Equal(t, userID, sameUserID, "same custom ID should map to the same user")
+
+	var storedCustomID string
+	if err := db.QueryRowContext(context.Background(), "SELECT custom_id FROM users WHERE id = $1", userID).Scan(&storedCustomID); err != nil {
+		t.Fatalf("error reading user: %v", err.Error())
+	}
+	assert.NotEqual(t, customID, storedCustomID, "plaintext custom ID should not be stored")
+
+	if _, _, _, err = AuthenticateCustomHashed(context.Background(), logger, db, "", customID, "", false); err == nil {
+		t.Fatal("expected error without a configured salt")
+	}
+}
+
+func TestCheckGoogleAudience(t *testing.T) {
+	profile := &social.JWTGoogleProfile{Aud: "client-a"}
+
+	assert.True(t, checkGoogleAudience(profile, nil), "empty allow list should accept any audience")
+	assert.True(t, checkGoogleAudience(profile, []string{"client-b", "client-a"}), "listed audience should be accepted")
+	assert.False(t, checkGoogleAudience(profile, []string{"client-b"}), "unlisted audience should be rejected")
+	assert.True(t, checkGoogleAudience(&social.GooglePlayServiceProfile{}, []string{"client-b"}), "code exchange profiles carry no audience")
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -57,9 +58,18 @@ type channelMessageListCursor struct {
 	Id               string
 	Forward          bool
 	IsNext           bool
+	Since            int64
+	Until            int64
 }
 
 func ChannelMessagesList(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream, channelID string, limit int, forward bool, cursor string) (*api.ChannelMessageList, error) {
+	return ChannelMessagesListRange(ctx, logger, db, caller, stream, channelID, limit, forward, 0, 0, cursor)
+}
+
+// ChannelMessagesListRange lists channel messages like ChannelMessagesList, restricted to messages created at or after
+// since and before until, both in Unix seconds. A bound of 0 leaves that side of the window open. Cursors are only
+// valid for the window they were issued for.
+func ChannelMessagesListRange(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream, channelID string, limit int, forward bool, since, until int64, cursor string) (*api.ChannelMessageList, error) {
 	var incomingCursor *channelMessageListCursor
 	if cursor != "" {
 		cb, err := base64.StdEncoding.DecodeString(cursor)
@@ -86,6 +96,9 @@ func ChannelMessagesList(ctx context.Context, logger *zap.Logger, db *sql.DB, ca
 		} else if stream.Label != incomingCursor.StreamLabel {
 			// Stream label does not match.
 			return nil, runtime.ErrChannelCursorInvalid
+		} else if since != incomingCursor.Since || until != incomingCursor.Until {
+			// Cursor is for a different time window.
+			return nil, runtime.ErrChannelCursorInvalid
 		}
 	}
 
@@ -113,8 +126,21 @@ func ChannelMessagesList(ctx context.Context, logger *zap.Logger, db *sql.DB, ca
 		}
 	}
 
+	params := []interface{}{stream.Mode, stream.Subject, stream.Subcontext, stream.Label, limit + 1}
+	if incomingCursor != nil {
+		params = append(params, time.Unix(incomingCursor.CreateTime, 0).UTC(), incomingCursor.Id)
+	}
+
 	query := `SELECT id, code, sender_id, username, content, create_time, update_time FROM message
 WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3::UUID AND stream_label = $4`
+	if since != 0 {
+		params = append(params, time.Unix(since, 0).UTC())
+		query += " AND create_time >= $" + strconv.Itoa(len(params))
+	}
+	if until != 0 {
+		params = append(params, time.Unix(until, 0).UTC())
+		query += " AND create_time < $" + strconv.Itoa(len(params))
+	}
 	if incomingCursor == nil {
 		if forward {
 			query += " ORDER BY create_time ASC, id ASC"
@@ -131,10 +157,6 @@ WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3:
 		}
 	}
 	query += " LIMIT $5"
-	params := []interface{}{stream.Mode, stream.Subject, stream.Subcontext, stream.Label, limit + 1}
-	if incomingCursor != nil {
-		params = append(params, time.Unix(incomingCursor.CreateTime, 0).UTC(), incomingCursor.Id)
-	}
 
 	rows, err := db.QueryContext(ctx, query, params...)
 	if err != nil {
@@ -166,6 +188,8 @@ WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3:
 				Id:               dbID,
 				Forward:          forward,
 				IsNext:           true,
+				Since:            since,
+				Until:            until,
 			}
 			break
 		}
@@ -211,6 +235,8 @@ WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3:
 				Id:               dbID,
 				Forward:          forward,
 				IsNext:           false,
+				Since:            since,
+				Until:            until,
 			}
 		}
 	}
@@ -290,7 +316,21 @@ WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3:
 	}, nil
 }
 
+// How long a dedupe token blocks repeated sends of the same message.
+const channelMessageDedupeWindow = 5 * time.Minute
+
 func ChannelMessageSend(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, channelStream PresenceStream, channelId, content, senderId, senderUsername string, persist bool) (*rtapi.ChannelMessageAck, error) {
+	return ChannelMessageSendDedupe(ctx, logger, db, router, channelStream, channelId, content, senderId, senderUsername, persist, "")
+}
+
+// ChannelMessageSendDedupe sends a channel message like ChannelMessageSend. If a dedupe token is given and the same
+// sender already sent a message with that token to the channel within the dedupe window, nothing is sent and the ack
+// describes the existing message instead. Dedupe tokens require the message to be persisted.
+func ChannelMessageSendDedupe(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, channelStream PresenceStream, channelId, content, senderId, senderUsername string, persist bool, dedupeToken string) (*rtapi.ChannelMessageAck, error) {
+	if dedupeToken != "" && !persist {
+		return nil, errors.New("channel message dedupe token requires a persistent message")
+	}
+
 	ts := timestamppb.New(time.Now().UTC())
 	message := &api.ChannelMessage{
 		ChannelId:  channelId,
@@ -323,7 +363,57 @@ func ChannelMessageSend(ctx context.Context, logger *zap.Logger, db *sql.DB, rou
 		message.UserIdTwo, ack.UserIdTwo = channelStream.Subcontext.String(), channelStream.Subcontext.String()
 	}
 
-	if persist {
+	if persist && dedupeToken != "" {
+		var duplicate bool
+		if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+			duplicate = false
+
+			// Tokens are unique per sender and channel, so release a token whose dedupe window has passed before reusing it.
+			query := `UPDATE message SET dedupe_token = NULL
+WHERE sender_id = $1 AND dedupe_token = $2 AND create_time < $3
+AND stream_mode = $4 AND stream_subject = $5::UUID AND stream_descriptor = $6::UUID AND stream_label = $7`
+			if _, err := tx.ExecContext(ctx, query, message.SenderId, dedupeToken, message.CreateTime.AsTime().Add(-channelMessageDedupeWindow), channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label); err != nil {
+				return err
+			}
+
+			// The unique index on the token makes concurrent sends with the same token insert at most one message.
+			query = `INSERT INTO message (id, code, sender_id, username, stream_mode, stream_subject, stream_descriptor, stream_label, content, create_time, update_time, dedupe_token)
+VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10, $11)
+ON CONFLICT (sender_id, dedupe_token, stream_mode, stream_subject, stream_descriptor, stream_label) WHERE dedupe_token IS NOT NULL DO NOTHING
+RETURNING id`
+			var dbID string
+			err := tx.QueryRowContext(ctx, query, message.MessageId, message.Code.Value, message.SenderId, message.Username, channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label, message.Content, message.CreateTime.AsTime(), dedupeToken).Scan(&dbID)
+			if err == nil {
+				return nil
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			// Nothing was inserted, acknowledge the message already sent with this token.
+			var dbUsername string
+			var dbCreateTime pgtype.Timestamptz
+			var dbUpdateTime pgtype.Timestamptz
+			query = `SELECT id, username, create_time, update_time FROM message
+WHERE sender_id = $1 AND dedupe_token = $2
+AND stream_mode = $3 AND stream_subject = $4::UUID AND stream_descriptor = $5::UUID AND stream_label = $6`
+			if err := tx.QueryRowContext(ctx, query, message.SenderId, dedupeToken, channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label).Scan(&dbID, &dbUsername, &dbCreateTime, &dbUpdateTime); err != nil {
+				return err
+			}
+			duplicate = true
+			ack.MessageId = dbID
+			ack.Username = dbUsername
+			ack.CreateTime = timestamppb.New(dbCreateTime.Time)
+			ack.UpdateTime = timestamppb.New(dbUpdateTime.Time)
+			return nil
+		}); err != nil {
+			logger.Error("Error persisting channel message", zap.Error(err))
+			return nil, errChannelMessagePersist
+		}
+		if duplicate {
+			// The original send already reached the channel.
+			return ack, nil
+		}
+	} else if persist {
 		query := `INSERT INTO message (id, code, sender_id, username, stream_mode, stream_subject, stream_descriptor, stream_label, content, create_time, update_time)
 VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		_, err := db.ExecContext(ctx, query, message.MessageId, message.Code.Value, message.SenderId, message.Username, channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label, message.Content, message.CreateTime.AsTime())
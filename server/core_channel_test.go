@@ -0,0 +1,111 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelMessageSendDedupe(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	router := &DummyMessageRouter{}
+
+	senderID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, senderID)
+
+	channelID := "2..." + uuid.Must(uuid.NewV4()).String()
+	streamResult, err := ChannelIdToStream(channelID)
+	if err != nil {
+		t.Fatalf("error converting channel id: %v", err.Error())
+	}
+	stream := streamResult.Stream
+
+	first, err := ChannelMessageSendDedupe(ctx, logger, db, router, stream, channelID, "{}", senderID.String(), "", true, "token")
+	if err != nil {
+		t.Fatalf("error sending first message: %v", err.Error())
+	}
+	second, err := ChannelMessageSendDedupe(ctx, logger, db, router, stream, channelID, "{}", senderID.String(), "", true, "token")
+	if err != nil {
+		t.Fatalf("error sending second message: %v", err.Error())
+	}
+	assert.Equal(t, first.MessageId, second.MessageId, "repeated token should return the existing message")
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM message WHERE sender_id = $1", senderID).Scan(&count); err != nil {
+		t.Fatalf("error counting messages: %v", err.Error())
+	}
+	assert.Equal(t, 1, count)
+
+	other, err := ChannelMessageSendDedupe(ctx, logger, db, router, stream, channelID, "{}", senderID.String(), "", true, "other")
+	if err != nil {
+		t.Fatalf("error sending third message: %v", err.Error())
+	}
+	assert.NotEqual(t, first.MessageId, other.MessageId, "a different token should send a new message")
+}
+
+func TestChannelMessageSendDedupeConcurrent(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	router := &DummyMessageRouter{}
+
+	senderID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, senderID)
+
+	channelID := "2..." + uuid.Must(uuid.NewV4()).String()
+	streamResult, err := ChannelIdToStream(channelID)
+	if err != nil {
+		t.Fatalf("error converting channel id: %v", err.Error())
+	}
+	stream := streamResult.Stream
+
+	const sends = 10
+	messageIDs := make([]string, sends)
+	errs := make([]error, sends)
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ack, err := ChannelMessageSendDedupe(ctx, logger, db, router, stream, channelID, "{}", senderID.String(), "", true, "token")
+			errs[i] = err
+			if ack != nil {
+				messageIDs[i] = ack.MessageId
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < sends; i++ {
+		if assert.NoError(t, errs[i]) {
+			assert.Equal(t, messageIDs[0], messageIDs[i], "concurrent sends with the same token should return one message")
+		}
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM message WHERE sender_id = $1", senderID).Scan(&count); err != nil {
+		t.Fatalf("error counting messages: %v", err.Error())
+	}
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/heroiclabs/nakama/v3/internal/cronexpr"
+)
+
+// CronExpression is a parsed CRON expression evaluated against wall clock time in a given location.
+type CronExpression struct {
+	expr       *cronexpr.Expression
+	hasSeconds bool
+}
+
+// ParseCronExpression parses a standard 5-field CRON expression, or a 6-field expression whose first field is seconds.
+// Expressions with 7 fields are passed through unchanged with their leading seconds and trailing year fields.
+func ParseCronExpression(expression string) (*CronExpression, error) {
+	fields := strings.Fields(expression)
+	if len(fields) == 6 {
+		// The parser reads 6 fields as minutes to years, add the year field so the first is read as seconds instead.
+		expression += " *"
+	}
+
+	expr, err := cronexpr.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return &CronExpression{expr: expr, hasSeconds: len(fields) >= 6}, nil
+}
+
+// Next returns the first time strictly after t that the expression fires at in the given location.
+//
+// Local times that occur twice when clocks go back only fire at their first occurrence. Local times skipped when
+// clocks go forward fire the same distance past the transition, so "30 2 * * *" fires at 03:30 on that day.
+func (c *CronExpression) Next(t time.Time, loc *time.Location) time.Time {
+	wall := cronWallClock(t, loc)
+	for {
+		wall = c.expr.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		if next := cronWallClockInstant(wall, loc); next.After(t) {
+			return next.UTC()
+		}
+		// The first occurrence of this local time was already at or before t.
+	}
+}
+
+// Prev returns the last time strictly before t that the expression fired at in the given location, following the
+// same daylight saving time rules as Next.
+func (c *CronExpression) Prev(t time.Time, loc *time.Location) time.Time {
+	wall := cronWallClock(t, loc)
+	for {
+		wall = c.expr.Last(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		if prev := cronWallClockInstant(wall, loc); prev.Before(t) {
+			return prev.UTC()
+		}
+		// A skipped local time resolved to after t.
+	}
+}
+
+// Match returns true if the expression fires at t in the given location. Expressions without a seconds field are
+// matched against the start of the minute t falls in.
+func (c *CronExpression) Match(t time.Time, loc *time.Location) bool {
+	if c.hasSeconds {
+		t = t.Truncate(time.Second)
+	} else {
+		t = t.Truncate(time.Minute)
+	}
+	return c.Next(t.Add(-time.Second), loc).Equal(t)
+}
+
+// Represent the local wall clock time of t as a UTC time, so the expression can be evaluated without time zone
+// transitions.
+func cronWallClock(t time.Time, loc *time.Location) time.Time {
+	l := t.In(loc)
+	return time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), l.Minute(), l.Second(), 0, time.UTC)
+}
+
+// Resolve a wall clock time produced by cronWallClock to an instant in the given location. Ambiguous local times resolve
+// to their earliest instant, and skipped local times are read with the offset in effect before the transition.
+func cronWallClockInstant(wall time.Time, loc *time.Location) time.Time {
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+
+	before := wall.Add(-time.Duration(offsetBefore) * time.Second)
+	after := wall.Add(-time.Duration(offsetAfter) * time.Second)
+	beforeValid := cronWallClock(before, loc).Equal(wall)
+	afterValid := cronWallClock(after, loc).Equal(wall)
+
+	switch {
+	case beforeValid && afterValid:
+		if after.Before(before) {
+			return after
+		}
+		return before
+	case afterValid:
+		return after
+	default:
+		// Either only valid with the earlier offset, or skipped entirely.
+		return before
+	}
+}
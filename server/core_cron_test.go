@@ -0,0 +1,98 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronExpressionDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err.Error())
+	}
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("error parsing time: %v", err.Error())
+		}
+		return ts.UTC()
+	}
+
+	t.Run("skipped local time", func(t *testing.T) {
+		// Clocks go forward from 02:00 EST to 03:00 EDT on 2024-03-10, 02:30 does not occur.
+		expr, err := ParseCronExpression("30 2 * * *")
+		if err != nil {
+			t.Fatalf("error parsing expression: %v", err.Error())
+		}
+
+		next := expr.Next(utc("2024-03-10T05:00:00Z"), loc)
+		assert.Equal(t, utc("2024-03-10T07:30:00Z"), next, "should fire at 03:30 EDT")
+		assert.Equal(t, utc("2024-03-11T06:30:00Z"), expr.Next(next, loc), "should fire at 02:30 EDT the next day")
+		assert.Equal(t, utc("2024-03-10T07:30:00Z"), expr.Prev(utc("2024-03-10T16:00:00Z"), loc))
+		assert.Equal(t, utc("2024-03-09T07:30:00Z"), expr.Prev(utc("2024-03-10T07:10:00Z"), loc), "03:10 EDT is before the skipped time fires")
+	})
+
+	t.Run("repeated local time", func(t *testing.T) {
+		// Clocks go back from 02:00 EDT to 01:00 EST on 2024-11-03, 01:30 occurs twice.
+		expr, err := ParseCronExpression("30 1 * * *")
+		if err != nil {
+			t.Fatalf("error parsing expression: %v", err.Error())
+		}
+
+		next := expr.Next(utc("2024-11-03T04:00:00Z"), loc)
+		assert.Equal(t, utc("2024-11-03T05:30:00Z"), next, "should fire at 01:30 EDT")
+		assert.Equal(t, utc("2024-11-04T06:30:00Z"), expr.Next(next, loc), "should not fire again at 01:30 EST")
+		assert.Equal(t, utc("2024-11-04T06:30:00Z"), expr.Next(utc("2024-11-03T06:15:00Z"), loc), "01:15 EST is after the first occurrence")
+		assert.Equal(t, utc("2024-11-03T05:30:00Z"), expr.Prev(utc("2024-11-03T12:00:00Z"), loc))
+		assert.True(t, expr.Match(utc("2024-11-03T05:30:00Z"), loc))
+		assert.False(t, expr.Match(utc("2024-11-03T06:30:00Z"), loc))
+	})
+
+	t.Run("hourly across repeated hour", func(t *testing.T) {
+		expr, err := ParseCronExpression("0 * * * *")
+		if err != nil {
+			t.Fatalf("error parsing expression: %v", err.Error())
+		}
+
+		var fired []time.Time
+		for ts := utc("2024-11-03T04:30:00Z"); len(fired) < 3; {
+			ts = expr.Next(ts, loc)
+			fired = append(fired, ts)
+		}
+		assert.Equal(t, []time.Time{utc("2024-11-03T05:00:00Z"), utc("2024-11-03T07:00:00Z"), utc("2024-11-03T08:00:00Z")}, fired)
+	})
+}
+
+func TestCronExpressionSeconds(t *testing.T) {
+	expr, err := ParseCronExpression("15 0 9 * * *")
+	if err != nil {
+		t.Fatalf("error parsing expression: %v", err.Error())
+	}
+
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 7, 1, 9, 0, 15, 0, time.UTC), expr.Next(from, time.UTC))
+	assert.True(t, expr.Match(time.Date(2024, 7, 1, 9, 0, 15, 0, time.UTC), time.UTC))
+	assert.False(t, expr.Match(time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC), time.UTC))
+
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err.Error())
+	}
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 15, 0, time.UTC), expr.Next(from, loc), "09:00:15 JST is 00:00:15 UTC")
+}
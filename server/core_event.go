@@ -0,0 +1,208 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ErrEventLogCursorInvalid = errors.New("event log cursor invalid")
+
+const (
+	eventLogSweepInterval  = time.Minute
+	eventLogSweepBatchSize = 1_000
+)
+
+type eventLogListCursor struct {
+	Timestamp int64
+	ID        []byte
+}
+
+// EventLogWrite persists a custom runtime event so it can later be listed with EventLogList. Events without a
+// timestamp are recorded with the current time.
+func EventLogWrite(ctx context.Context, logger *zap.Logger, db *sql.DB, evt *api.Event) error {
+	properties := evt.Properties
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	propertiesBytes, err := json.Marshal(properties)
+	if err != nil {
+		logger.Error("Could not encode event properties.", zap.String("name", evt.Name), zap.Error(err))
+		return err
+	}
+
+	ts := time.Now().UTC()
+	if evt.Timestamp != nil {
+		ts = evt.Timestamp.AsTime()
+	}
+
+	query := "INSERT INTO event_log (id, name, properties, external, timestamp) VALUES ($1, $2, $3, $4, $5)"
+	if _, err = db.ExecContext(ctx, query, uuid.Must(uuid.NewV4()), evt.Name, propertiesBytes, evt.External, ts); err != nil {
+		logger.Error("Could not write event to event log.", zap.String("name", evt.Name), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// EventLogList lists persisted events newest first. An empty name lists events of any name, and a zero since or until
+// leaves that end of the time range open. The time range includes since and excludes until, both in UTC seconds.
+func EventLogList(ctx context.Context, logger *zap.Logger, db *sql.DB, name string, since, until int64, limit int, cursor string) ([]*api.Event, string, error) {
+	var incomingCursor *eventLogListCursor
+	if cursor != "" {
+		cb, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrEventLogCursorInvalid
+		}
+		incomingCursor = &eventLogListCursor{}
+		if err = gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, "", ErrEventLogCursorInvalid
+		}
+	}
+
+	params := []interface{}{limit + 1}
+	query := "SELECT id, name, properties, external, timestamp FROM event_log WHERE true"
+	if name != "" {
+		params = append(params, name)
+		query += fmt.Sprintf(" AND name = $%d", len(params))
+	}
+	if since > 0 {
+		params = append(params, time.Unix(since, 0).UTC())
+		query += fmt.Sprintf(" AND timestamp >= $%d", len(params))
+	}
+	if until > 0 {
+		params = append(params, time.Unix(until, 0).UTC())
+		query += fmt.Sprintf(" AND timestamp < $%d", len(params))
+	}
+	if incomingCursor != nil {
+		params = append(params, time.UnixMicro(incomingCursor.Timestamp).UTC(), uuid.FromBytesOrNil(incomingCursor.ID))
+		query += fmt.Sprintf(" AND (timestamp, id) < ($%d, $%d)", len(params)-1, len(params))
+	}
+	query += " ORDER BY timestamp DESC, id DESC LIMIT $1"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Could not list events from event log.", zap.Error(err))
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	events := make([]*api.Event, 0, limit)
+	var outgoingCursor *eventLogListCursor
+	var lastID uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		var evtName string
+		var properties []byte
+		var external bool
+		var ts pgtype.Timestamptz
+		if err = rows.Scan(&id, &evtName, &properties, &external, &ts); err != nil {
+			logger.Error("Could not scan event from event log.", zap.Error(err))
+			return nil, "", err
+		}
+
+		if len(events) >= limit {
+			last := events[len(events)-1]
+			outgoingCursor = &eventLogListCursor{Timestamp: last.Timestamp.AsTime().UnixMicro(), ID: lastID.Bytes()}
+			break
+		}
+
+		evt := &api.Event{
+			Name:      evtName,
+			External:  external,
+			Timestamp: timestamppb.New(ts.Time),
+		}
+		if err = json.Unmarshal(properties, &evt.Properties); err != nil {
+			logger.Error("Could not decode event properties from event log.", zap.Error(err))
+			return nil, "", err
+		}
+		events = append(events, evt)
+		lastID = id
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Could not list events from event log.", zap.Error(err))
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if outgoingCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err = gob.NewEncoder(cursorBuf).Encode(outgoingCursor); err != nil {
+			logger.Error("Could not encode event log cursor.", zap.Error(err))
+			return nil, "", err
+		}
+		nextCursor = base64.RawURLEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return events, nextCursor, nil
+}
+
+// StartEventLogSweeper periodically deletes persisted events older than the configured max age, until the context is
+// cancelled. It does nothing if the event log is disabled or events are kept indefinitely.
+func StartEventLogSweeper(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config) {
+	if !config.GetRuntime().EventLog || config.GetRuntime().EventLogMaxAgeSec == 0 {
+		return
+	}
+	maxAge := time.Duration(config.GetRuntime().EventLogMaxAgeSec) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(eventLogSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := eventLogSweepExpired(ctx, logger, db, time.Now().UTC().Add(-maxAge)); err != nil && ctx.Err() == nil {
+					logger.Error("Error sweeping expired event log entries", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func eventLogSweepExpired(ctx context.Context, logger *zap.Logger, db *sql.DB, before time.Time) error {
+	query := "DELETE FROM event_log WHERE id IN (SELECT id FROM event_log WHERE create_time < $1 LIMIT $2)"
+
+	for {
+		res, err := db.ExecContext(ctx, query, before, eventLogSweepBatchSize)
+		if err != nil {
+			return err
+		}
+
+		deleted, _ := res.RowsAffected()
+		if deleted > 0 {
+			logger.Debug("Swept expired event log entries", zap.Int64("count", deleted))
+		}
+		if deleted < eventLogSweepBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEventLogList(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	name := "test_event_" + uuid.Must(uuid.NewV4()).String()
+	now := time.Now().Unix()
+
+	for i := int64(0); i < 3; i++ {
+		require.NoError(t, EventLogWrite(ctx, logger, db, &api.Event{
+			Name:       name,
+			Properties: map[string]string{"index": string(rune('a' + i))},
+			Timestamp:  &timestamppb.Timestamp{Seconds: now - 10 + i},
+		}))
+	}
+
+	events, cursor, err := EventLogList(ctx, logger, db, name, 0, 0, 2, "")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.NotEmpty(t, cursor)
+	require.Equal(t, "c", events[0].Properties["index"], "newest event should be listed first")
+	require.Equal(t, "b", events[1].Properties["index"])
+
+	events, cursor, err = EventLogList(ctx, logger, db, name, 0, 0, 2, cursor)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Empty(t, cursor)
+	require.Equal(t, "a", events[0].Properties["index"])
+
+	events, _, err = EventLogList(ctx, logger, db, name, now-9, now-8, 10, "")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "b", events[0].Properties["index"])
+
+	_, _, err = EventLogList(ctx, logger, db, name, 0, 0, 10, "not a cursor")
+	require.ErrorIs(t, err, ErrEventLogCursorInvalid)
+}
+
+func TestEventLogSweepExpired(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	name := "test_event_" + uuid.Must(uuid.NewV4()).String()
+
+	require.NoError(t, EventLogWrite(ctx, logger, db, &api.Event{Name: name}))
+	_, err := db.ExecContext(ctx, "UPDATE event_log SET create_time = now() - INTERVAL '2 hours' WHERE name = $1", name)
+	require.NoError(t, err)
+	require.NoError(t, EventLogWrite(ctx, logger, db, &api.Event{Name: name}))
+
+	require.NoError(t, eventLogSweepExpired(ctx, logger, db, time.Now().UTC().Add(-time.Hour)))
+
+	events, _, err := EventLogList(ctx, logger, db, name, 0, 0, 10, "")
+	require.NoError(t, err)
+	require.Len(t, events, 1, "only the event older than the max age should be swept")
+}
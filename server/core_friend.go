@@ -40,6 +40,8 @@ type edgeListCursor struct {
 	// ID fields.
 	State    int64
 	Position int64
+	// Tie-breaker for edges sharing a position, only set by listings that order by it.
+	UserID uuid.UUID
 }
 
 // Only used to get all friend IDs for the console. NOTE: Not intended for use in client/runtime APIs.
@@ -104,7 +106,7 @@ func GetFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegis
 SELECT id, username, display_name, avatar_url,
 	lang_tag, location, timezone, users.metadata,
 	create_time, users.update_time, user_edge.update_time, state, position,
-	facebook_id, google_id, gamecenter_id, steam_id, facebook_instant_game_id, apple_id, user_edge.metadata
+	facebook_id, google_id, gamecenter_id, steam_id, facebook_instant_game_id, apple_id, `+friendEdgeMetadataColumn+`
 FROM users, user_edge WHERE id = destination_id AND source_id = $1 AND destination_id IN (%s)`, strings.Join(placeholders, ","))
 	params := append([]any{userID}, uids...)
 	rows, err := db.QueryContext(ctx, query, params...)
@@ -185,7 +187,27 @@ FROM users, user_edge WHERE id = destination_id AND source_id = $1 AND destinati
 	return friends, nil
 }
 
+// friendEdgeMetadataColumn selects the edge metadata, exposing any metadata attached to a pending received invite under
+// an "invite" key. Invite metadata is kept apart from the edge metadata so it never outlives the invite.
+const friendEdgeMetadataColumn = `CASE
+		WHEN state = 2 AND user_edge.invite_metadata <> '{}'::JSONB THEN user_edge.metadata || jsonb_build_object('invite', user_edge.invite_metadata)
+		ELSE user_edge.metadata
+	END`
+
 func ListFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userID uuid.UUID, limit int, state *wrapperspb.Int32Value, cursor string) (*api.FriendList, error) {
+	return ListFriendsFiltered(ctx, logger, db, statusRegistry, userID, limit, state, false, cursor)
+}
+
+// ListFriendsFiltered lists a user's friend edges like ListFriends. If mutualOnly is set only confirmed friendships
+// with a matching friend edge in the other direction are returned, and the state filter must be empty or friend(0).
+func ListFriendsFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userID uuid.UUID, limit int, state *wrapperspb.Int32Value, mutualOnly bool, cursor string) (*api.FriendList, error) {
+	if mutualOnly {
+		if state != nil && state.Value != 0 {
+			return nil, errors.New("mutual friends filter only applies to the friend state")
+		}
+		state = &wrapperspb.Int32Value{Value: 0}
+	}
+
 	var incomingCursor *edgeListCursor
 	if cursor != "" {
 		cb, err := base64.StdEncoding.DecodeString(cursor)
@@ -208,7 +230,7 @@ func ListFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegi
 SELECT id, username, display_name, avatar_url,
 	lang_tag, location, timezone, users.metadata,
 	create_time, users.update_time, user_edge.update_time, state, position,
-	facebook_id, google_id, gamecenter_id, steam_id, facebook_instant_game_id, apple_id, user_edge.metadata
+	facebook_id, google_id, gamecenter_id, steam_id, facebook_instant_game_id, apple_id, ` + friendEdgeMetadataColumn + `
 FROM users, user_edge WHERE id = destination_id AND source_id = $1`
 	params = append(params, userID)
 	if state != nil {
@@ -216,6 +238,9 @@ FROM users, user_edge WHERE id = destination_id AND source_id = $1`
 		query += " AND state = $2"
 		params = append(params, state.Value)
 	}
+	if mutualOnly {
+		query += " AND EXISTS (SELECT 1 FROM user_edge reverse_edge WHERE reverse_edge.source_id = user_edge.destination_id AND reverse_edge.destination_id = $1 AND reverse_edge.state = 0)"
+	}
 	if incomingCursor != nil {
 		query += " AND (source_id, state, position) >= ($1, $2, $3)"
 		if state == nil {
@@ -320,6 +345,122 @@ FROM users, user_edge WHERE id = destination_id AND source_id = $1`
 	return &api.FriendList{Friends: friends, Cursor: outgoingCursor}, nil
 }
 
+// FriendsOfFriendsMutualCounts counts, for each candidate, how many of the user's friends are also friends with them.
+func FriendsOfFriendsMutualCounts(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, candidateIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(candidateIDs))
+	if len(candidateIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `SELECT fof.destination_id, COUNT(*)
+FROM user_edge f, user_edge fof
+WHERE f.source_id = $1 AND f.state = 0
+AND fof.source_id = f.destination_id AND fof.state = 0
+AND fof.destination_id = ANY($2::UUID[])
+GROUP BY fof.destination_id`
+	rows, err := db.QueryContext(ctx, query, userID, candidateIDs)
+	if err != nil {
+		logger.Error("Could not count mutual friends.", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var candidateID uuid.UUID
+		var count int
+		if err = rows.Scan(&candidateID, &count); err != nil {
+			logger.Error("Error scanning mutual friend counts.", zap.Error(err))
+			return nil, err
+		}
+		counts[candidateID.String()] = count
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Could not count mutual friends.", zap.Error(err))
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// ListFriendsOfFriendsByMutualCount returns up to limit friends of friends ranked by the number of the user's friends
+// connecting to them, most connected first, along with those counts. Ties are broken by user ID. Users with any
+// existing relationship to the user are excluded. Only the friends of the user's traversalBudget most recently added
+// friends are inspected, which bounds the cost for users with very many friends at the expense of ranking accuracy.
+func ListFriendsOfFriendsByMutualCount(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, userID uuid.UUID, limit, traversalBudget int) (*api.FriendsOfFriendsList, map[string]int, error) {
+	// Every edge of the traversed friends is counted before ranking, so the budget never decides which candidates
+	// survive independently of their mutual count.
+	query := `SELECT fof.destination_id AS candidate_id, MIN(f.destination_id), COUNT(*) AS mutual_count
+FROM (
+	SELECT destination_id
+	FROM user_edge
+	WHERE source_id = $1 AND state = 0
+	ORDER BY position DESC, destination_id ASC
+	LIMIT $2
+) AS f, user_edge fof
+WHERE fof.source_id = f.destination_id AND fof.state = 0
+AND fof.destination_id != $1
+AND NOT EXISTS (SELECT 1 FROM user_edge existing WHERE existing.source_id = $1 AND existing.destination_id = fof.destination_id)
+GROUP BY fof.destination_id
+ORDER BY mutual_count DESC, candidate_id ASC
+LIMIT $3`
+	rows, err := db.QueryContext(ctx, query, userID, traversalBudget, limit)
+	if err != nil {
+		logger.Error("Could not list friends of friends.", zap.Error(err))
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]string, 0, limit)
+	referrers := make(map[string]string, limit)
+	counts := make(map[string]int, limit)
+	for rows.Next() {
+		var candidateID, referrerID uuid.UUID
+		var count int
+		if err = rows.Scan(&candidateID, &referrerID, &count); err != nil {
+			logger.Error("Error scanning friends of friends.", zap.Error(err))
+			return nil, nil, err
+		}
+		userIDs = append(userIDs, candidateID.String())
+		referrers[candidateID.String()] = referrerID.String()
+		counts[candidateID.String()] = count
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Could not list friends of friends.", zap.Error(err))
+		return nil, nil, err
+	}
+	_ = rows.Close()
+
+	if len(userIDs) == 0 {
+		return &api.FriendsOfFriendsList{FriendsOfFriends: []*api.FriendsOfFriendsList_FriendOfFriend{}}, counts, nil
+	}
+
+	users, err := GetUsers(ctx, logger, db, statusRegistry, userIDs, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userMap := make(map[string]*api.User, len(users.Users))
+	for _, user := range users.Users {
+		userMap[user.Id] = user
+	}
+
+	fof := make([]*api.FriendsOfFriendsList_FriendOfFriend, 0, len(userIDs))
+	for _, id := range userIDs {
+		user, ok := userMap[id]
+		if !ok {
+			// can happen if account was deleted before GetUsers call, skip.
+			continue
+		}
+
+		fof = append(fof, &api.FriendsOfFriendsList_FriendOfFriend{
+			Referrer: referrers[id],
+			User:     user,
+		})
+	}
+
+	return &api.FriendsOfFriendsList{FriendsOfFriends: fof}, counts, nil
+}
+
 type friendsOfFriendsListCursor struct {
 	SourceId      string
 	DestinationId string
@@ -469,17 +610,36 @@ AND state = 0
 	return &api.FriendsOfFriendsList{FriendsOfFriends: fof, Cursor: outgoingCursor}, nil
 }
 
-func AddFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, userID uuid.UUID, username string, friendIDs []string, metadata string) error {
+func AddFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username string, friendIDs []string, metadata string) error {
+	_, err := AddFriendsWithStates(ctx, logger, db, tracker, messageRouter, pushFn, userID, username, friendIDs, metadata)
+	return err
+}
+
+// AddFriendsWithStates adds friends as AddFriends does, and returns the resulting edge state from the user to each target,
+// using the api.Friend_State values. A target has no entry if no edge exists after the add, for example because the
+// target does not exist or has blocked the user.
+func AddFriendsWithStates(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username string, friendIDs []string, metadata string) (map[string]api.Friend_State, error) {
+	return AddFriendsWithInvites(ctx, logger, db, tracker, messageRouter, pushFn, userID, username, friendIDs, metadata, nil)
+}
+
+// AddFriendsWithInvites adds friends as AddFriendsWithStates does, also storing the given per-target invite metadata on
+// the edge from each target back to the user. While the invite is pending the target sees it under the "invite" key of
+// the friend metadata when listing their friends. Invite metadata is kept apart from the target's own friend metadata
+// and is cleared once the invite is accepted. It is only stored when a new invite is created, and is not used when the
+// add accepts an invite from the target instead.
+func AddFriendsWithInvites(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username string, friendIDs []string, metadata string, inviteMetadata map[string]string) (map[string]api.Friend_State, error) {
 	uniqueFriendIDs := make(map[string]struct{})
 	for _, fid := range friendIDs {
 		uniqueFriendIDs[fid] = struct{}{}
 	}
 
 	var notificationToSend map[string]bool
+	var states map[string]api.Friend_State
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any notifications that may have been prepared by previous attempts.
 		notificationToSend = make(map[string]bool)
+		states = make(map[string]api.Friend_State, len(uniqueFriendIDs))
 
 		for id := range uniqueFriendIDs {
 			// Check to see if user has already blocked friend, if so, don't add friend or send notification.
@@ -493,20 +653,32 @@ func AddFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tra
 			} else if err == nil {
 				// the block was found, don't add friend or send notification.
 				logger.Info("Ignoring previously blocked friend. Delete friend first before attempting to add.", zap.String("user", userID.String()), zap.String("friend", id))
+				states[id] = api.Friend_BLOCKED
 				continue
 			}
 
-			isFriendAccept, addFriendErr := addFriend(ctx, logger, tx, userID, id, metadata)
+			isFriendAccept, addFriendErr := addFriend(ctx, logger, tx, userID, id, metadata, inviteMetadata[id])
 			if addFriendErr == nil {
 				notificationToSend[id] = isFriendAccept
 			} else if addFriendErr != sql.ErrNoRows { // Check to see if friend had blocked user.
 				return addFriendErr
 			}
+
+			// Read back the resulting edge, which also covers targets that were already friends or invited.
+			var state int
+			if err := tx.QueryRowContext(ctx, "SELECT state FROM user_edge WHERE source_id = $1 AND destination_id = $2", userID, id).Scan(&state); err != nil {
+				if err != sql.ErrNoRows {
+					logger.Debug("Failed to read edge state.", zap.Error(err), zap.String("user", userID.String()), zap.String("friend", id))
+					return err
+				}
+				continue
+			}
+			states[id] = api.Friend_State(state)
 		}
 		return nil
 	}); err != nil {
 		logger.Error("Error adding friends.", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	notifications := make(map[uuid.UUID][]*api.Notification)
@@ -531,9 +703,9 @@ func AddFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tra
 	}
 
 	// Any error is already logged before it's returned here.
-	_ = NotificationSend(ctx, logger, db, tracker, messageRouter, notifications)
+	_ = NotificationSend(ctx, logger, db, tracker, messageRouter, pushFn, notifications)
 
-	return nil
+	return states, nil
 }
 
 func UpdateFriendMetadata(ctx context.Context, logger *zap.Logger, db *sql.DB, userID, friendUserID uuid.UUID, metadata map[string]any) error {
@@ -557,14 +729,17 @@ func UpdateFriendMetadata(ctx context.Context, logger *zap.Logger, db *sql.DB, u
 }
 
 // Returns "true" if accepting an invite, otherwise false.
-func addFriend(ctx context.Context, logger *zap.Logger, tx *sql.Tx, userID uuid.UUID, friendID, metadata string) (bool, error) {
+func addFriend(ctx context.Context, logger *zap.Logger, tx *sql.Tx, userID uuid.UUID, friendID, metadata, inviteMetadata string) (bool, error) {
 	if metadata == "" {
 		metadata = "{}"
 	}
+	if inviteMetadata == "" {
+		inviteMetadata = "{}"
+	}
 
 	// Mark an invite as accepted, if one was in place.
 	res, err := tx.ExecContext(ctx, `
-UPDATE user_edge SET state = 0, update_time = now(),
+UPDATE user_edge SET state = 0, update_time = now(), invite_metadata = '{}'::JSONB,
 	metadata = CASE
 		WHEN source_id = $2 AND destination_id = $1 THEN metadata || $3::JSONB
 		ELSE metadata
@@ -586,12 +761,12 @@ OR (source_id = $2 AND destination_id = $1 AND state = 2)
 	position := fmt.Sprintf("%v", time.Now().UTC().UnixNano())
 	// If no edge updates took place, it's either a new invite being set up, or user was blocked off by friend.
 	_, err = tx.ExecContext(ctx, `
-INSERT INTO user_edge (source_id, destination_id, state, position, update_time, metadata)
-SELECT source_id, destination_id, state, position, update_time, metadata
+INSERT INTO user_edge (source_id, destination_id, state, position, update_time, metadata, invite_metadata)
+SELECT source_id, destination_id, state, position, update_time, metadata, invite_metadata
 FROM (VALUES
-  ($1::UUID, $2::UUID, 1, $3::BIGINT, now(), $4::JSONB),
-  ($2::UUID, $1::UUID, 2, $3::BIGINT, now(), '{}'::JSONB)
-) AS ue(source_id, destination_id, state, position, update_time, metadata)
+  ($1::UUID, $2::UUID, 1, $3::BIGINT, now(), $4::JSONB, '{}'::JSONB),
+  ($2::UUID, $1::UUID, 2, $3::BIGINT, now(), '{}'::JSONB, $5::JSONB)
+) AS ue(source_id, destination_id, state, position, update_time, metadata, invite_metadata)
 WHERE
 	EXISTS (SELECT id FROM users WHERE id = $2::UUID)
 	AND
@@ -601,7 +776,7 @@ WHERE
    WHERE source_id = $2::UUID AND destination_id = $1::UUID AND state = 3
   )
 ON CONFLICT (source_id, destination_id) DO NOTHING
-`, userID, friendID, position, metadata)
+`, userID, friendID, position, metadata, inviteMetadata)
 	if err != nil {
 		logger.Debug("Failed to insert new user edge link.", zap.Error(err), zap.String("user", userID.String()), zap.String("friend", friendID))
 		return false, err
@@ -711,7 +886,7 @@ func BlockFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker T
 
 func blockFriend(ctx context.Context, logger *zap.Logger, tx *sql.Tx, tracker Tracker, userID uuid.UUID, friendID string) error {
 	// Try to update any previous edge between these users.
-	res, err := tx.ExecContext(ctx, "UPDATE user_edge SET state = 3, update_time = now() WHERE source_id = $1 AND destination_id = $2",
+	res, err := tx.ExecContext(ctx, "UPDATE user_edge SET state = 3, update_time = now(), invite_metadata = '{}'::JSONB WHERE source_id = $1 AND destination_id = $2",
 		userID, friendID)
 	if err != nil {
 		logger.Debug("Failed to update user edge state.", zap.Error(err), zap.String("user", userID.String()), zap.String("friend", friendID))
@@ -50,48 +50,48 @@ func TestServer_ListFriendsOfFriends(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := addFriend(ctx, logger, tx, uid, uidA1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uid, uidA1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidA1, uid.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidA1, uid.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidA1, uidA2.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidA1, uidA2.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidA2, uidA1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidA2, uidA1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidA1, uidA3.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidA1, uidA3.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidA3, uidA1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidA3, uidA1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
 
-	if _, err := addFriend(ctx, logger, tx, uid, uidB1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uid, uidB1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB1, uid.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB1, uid.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB1, uidB2.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB1, uidB2.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB2, uidB1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB2, uidB1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB1, uidB3.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB1, uidB3.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB3, uidB1.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB3, uidB1.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
 
-	if _, err := addFriend(ctx, logger, tx, uid, uidB3.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uid, uidB3.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := addFriend(ctx, logger, tx, uidB3, uid.String(), ""); err != nil {
+	if _, err := addFriend(ctx, logger, tx, uidB3, uid.String(), "", ""); err != nil {
 		t.Fatal(err)
 	}
 
@@ -145,3 +145,70 @@ func TestServer_ListFriendsOfFriends(t *testing.T) {
 		assert.Empty(t, fof.Cursor)
 	})
 }
+
+func TestAddFriendsWithStates(t *testing.T) {
+	ctx := context.Background()
+
+	db := NewDB(t)
+	defer db.Close()
+
+	uid := uuid.Must(uuid.NewV4())
+	inviter := uuid.Must(uuid.NewV4())
+	invitee := uuid.Must(uuid.NewV4())
+	for _, id := range []uuid.UUID{uid, inviter, invitee} {
+		InsertUser(t, db, id)
+	}
+
+	// The inviter has already sent the user a friend request.
+	if _, err := AddFriendsWithStates(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, nil, inviter, inviter.String(), []string{uid.String()}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := AddFriendsWithStates(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, nil, uid, uid.String(), []string{inviter.String(), invitee.String(), uuid.Must(uuid.NewV4()).String()}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, states, 2, "unknown user should have no state")
+	assert.Equal(t, api.Friend_FRIEND, states[inviter.String()], "accepted invite should be a friend")
+	assert.Equal(t, api.Friend_INVITE_SENT, states[invitee.String()], "new invite should be sent")
+}
+
+func TestAddFriendsWithInvites(t *testing.T) {
+	ctx := context.Background()
+
+	db := NewDB(t)
+	defer db.Close()
+
+	uid := uuid.Must(uuid.NewV4())
+	invitee := uuid.Must(uuid.NewV4())
+	for _, id := range []uuid.UUID{uid, invitee} {
+		InsertUser(t, db, id)
+	}
+
+	if _, err := AddFriendsWithInvites(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, nil, uid, uid.String(), []string{invitee.String()}, "", map[string]string{invitee.String(): `{"message":"Hi, we played together"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	friends, err := ListFriends(ctx, logger, db, NewLocalStatusRegistry(logger, cfg, NewLocalSessionRegistry(metrics), protojsonMarshaler), invitee, 10, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, friends.Friends, 1) {
+		assert.EqualValues(t, api.Friend_INVITE_RECEIVED, friends.Friends[0].State.GetValue())
+		assert.JSONEq(t, `{"invite":{"message":"Hi, we played together"}}`, friends.Friends[0].Metadata)
+	}
+
+	// Accepting the invite clears the invite metadata and leaves only the invitee's own friend metadata.
+	if _, err := AddFriendsWithStates(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, nil, invitee, invitee.String(), []string{uid.String()}, `{"note":"met in game"}`); err != nil {
+		t.Fatal(err)
+	}
+	friends, err = ListFriends(ctx, logger, db, NewLocalStatusRegistry(logger, cfg, NewLocalSessionRegistry(metrics), protojsonMarshaler), invitee, 10, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, friends.Friends, 1) {
+		assert.EqualValues(t, api.Friend_FRIEND, friends.Friends[0].State.GetValue())
+		assert.JSONEq(t, `{"note":"met in game"}`, friends.Friends[0].Metadata)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -48,6 +49,31 @@ var ErrEmptyMemberKick = errors.New("could not kick member")
 
 const BANNED_CODE = 4
 
+// Group membership change types passed to the group event hook.
+const (
+	GroupEventCreate      = "create"
+	GroupEventDelete      = "delete"
+	GroupEventJoin        = "join"
+	GroupEventJoinRequest = "join_request"
+	GroupEventLeave       = "leave"
+	GroupEventAdd         = "add"
+	GroupEventKick        = "kick"
+	GroupEventBan         = "ban"
+	GroupEventPromote     = "promote"
+	GroupEventDemote      = "demote"
+)
+
+// GroupEventFunction is notified of committed group membership changes, with the users whose membership changed.
+type GroupEventFunction func(groupID uuid.UUID, userIDs []uuid.UUID, event string)
+
+// groupEventInvoke notifies the group event function, if one is set, of a membership change affecting the given users.
+func groupEventInvoke(fn GroupEventFunction, groupID uuid.UUID, userIDs []uuid.UUID, event string) {
+	if fn == nil || len(userIDs) == 0 {
+		return
+	}
+	fn(groupID, userIDs, event)
+}
+
 type groupListCursor struct {
 	Lang       string
 	EdgeCount  int32
@@ -67,9 +93,48 @@ func (c *groupListCursor) GetUpdateTime() time.Time {
 	return time.Unix(0, c.UpdateTime)
 }
 
-func CreateGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, creatorID uuid.UUID, name, lang, desc, avatarURL, metadata string, open bool, maxCount int) (*api.Group, error) {
+// GroupCreateMember is an additional user added to a group as part of its creation.
+type GroupCreateMember struct {
+	UserID uuid.UUID
+	// State is the membership state: superadmin(0), admin(1), member(2) or join_request(3).
+	State int
+}
+
+func CreateGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, userID uuid.UUID, creatorID uuid.UUID, name, lang, desc, avatarURL, metadata string, open bool, maxCount int) (*api.Group, error) {
+	group, _, err := CreateGroupWithMembers(ctx, logger, db, groupEventFn, userID, creatorID, name, lang, desc, avatarURL, metadata, open, maxCount, nil)
+	return group, err
+}
+
+// CreateGroupWithMembers creates a group and adds the given members in the same transaction, returning the group and the
+// number of members added besides the creator, not counting join requests. Join requests are only kept for closed groups, in open groups they are
+// added as regular members. The group is not created if the members would exceed its max count.
+func CreateGroupWithMembers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, userID uuid.UUID, creatorID uuid.UUID, name, lang, desc, avatarURL, metadata string, open bool, maxCount int, members []*GroupCreateMember) (*api.Group, int, error) {
 	if userID == uuid.Nil {
-		return nil, runtime.ErrGroupCreatorInvalid
+		return nil, 0, runtime.ErrGroupCreatorInvalid
+	}
+
+	memberIDs := make([]uuid.UUID, 0, len(members))
+	memberStates := make(map[uuid.UUID]int, len(members))
+	var memberCount int
+	for _, member := range members {
+		if member.UserID == userID {
+			continue
+		}
+		if _, found := memberStates[member.UserID]; found {
+			continue
+		}
+		state := member.State
+		if state < 0 || state > 3 {
+			return nil, 0, fmt.Errorf("invalid group member state: %d", state)
+		}
+		if open && state == 3 {
+			state = 2
+		}
+		if state < 3 {
+			memberCount++
+		}
+		memberIDs = append(memberIDs, member.UserID)
+		memberStates[member.UserID] = state
 	}
 
 	state := 1
@@ -127,36 +192,113 @@ RETURNING id, creator_id, name, description, avatar_url, state, edge_count, lang
 		}
 
 		group = groups[0]
-		_, err = groupAddUser(ctx, db, tx, uuid.Must(uuid.FromString(group.Id)), userID, 0)
+		groupID := uuid.Must(uuid.FromString(group.Id))
+		_, err = groupAddUser(ctx, db, tx, groupID, userID, 0)
 		if err != nil {
 			logger.Debug("Could not add user to group.", zap.Error(err))
 			return err
 		}
 
+		if len(memberIDs) == 0 {
+			return nil
+		}
+
+		var existingCount int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(id) FROM users WHERE id = ANY($1::UUID[])", memberIDs).Scan(&existingCount); err != nil {
+			logger.Debug("Could not look up group members.", zap.Error(err))
+			return err
+		}
+		if existingCount != len(memberIDs) {
+			return runtime.ErrGroupUserNotFound
+		}
+
+		// Each edge needs a distinct position, so they can't rely on the clock as single additions do.
+		position := time.Now().UTC().UnixNano()
+		query := `
+INSERT INTO group_edge
+	(position, state, source_id, destination_id)
+VALUES
+	($1, $2, $3, $4),
+	($1, $2, $4, $3)`
+		for i, memberID := range memberIDs {
+			if _, err := tx.ExecContext(ctx, query, position+int64(i)+1, memberStates[memberID], groupID, memberID); err != nil {
+				logger.Debug("Could not add member to group.", zap.Error(err), zap.String("user_id", memberID.String()))
+				return err
+			}
+		}
+
+		if memberCount > 0 {
+			query = "UPDATE groups SET edge_count = edge_count + $2 WHERE id = $1::UUID AND edge_count + $2 <= max_count RETURNING edge_count"
+			if err := tx.QueryRowContext(ctx, query, groupID, memberCount).Scan(&group.EdgeCount); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return runtime.ErrGroupFull
+				}
+				logger.Debug("Could not update group edge_count.", zap.Error(err))
+				return err
+			}
+		}
+
 		return nil
 	}); err != nil {
 		if errors.Is(err, runtime.ErrGroupNameInUse) {
-			return nil, runtime.ErrGroupNameInUse
+			return nil, 0, runtime.ErrGroupNameInUse
+		}
+		if errors.Is(err, runtime.ErrGroupFull) || errors.Is(err, runtime.ErrGroupUserNotFound) {
+			logger.Info("Could not create group with members.", zap.String("name", name), zap.Error(err))
+			return nil, 0, err
 		}
 		logger.Error("Error creating group.", zap.Error(err))
-		return nil, err
+		return nil, 0, err
+	}
+
+	groupID := uuid.Must(uuid.FromString(group.Id))
+	groupEventInvoke(groupEventFn, groupID, []uuid.UUID{userID}, GroupEventCreate)
+	addedIDs := make([]uuid.UUID, 0, memberCount)
+	requestedIDs := make([]uuid.UUID, 0, len(memberIDs)-memberCount)
+	for _, memberID := range memberIDs {
+		if memberStates[memberID] < 3 {
+			addedIDs = append(addedIDs, memberID)
+		} else {
+			requestedIDs = append(requestedIDs, memberID)
+		}
 	}
+	groupEventInvoke(groupEventFn, groupID, addedIDs, GroupEventAdd)
+	groupEventInvoke(groupEventFn, groupID, requestedIDs, GroupEventJoinRequest)
 
-	logger.Info("Group created.", zap.String("group_id", group.Id), zap.String("user_id", userID.String()))
+	logger.Info("Group created.", zap.String("group_id", group.Id), zap.String("user_id", userID.String()), zap.Int("members_added", memberCount))
 
-	return group, nil
+	return group, memberCount, nil
+}
+
+// GroupVersionConflictError is returned when a group update is rejected because the group's version no longer
+// matches the expected version, the current version is included so the caller can re-read and retry.
+type GroupVersionConflictError struct {
+	GroupID string
+	Version int64
+}
+
+func (e *GroupVersionConflictError) Error() string {
+	return fmt.Sprintf("Group update rejected - version check failed: group ID %q, current version %d.", e.GroupID, e.Version)
 }
 
 func UpdateGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uuid.UUID, userID uuid.UUID, creatorID uuid.UUID, name, lang, desc, avatar, metadata *wrapperspb.StringValue, open *wrapperspb.BoolValue, maxCount int) error {
+	_, err := UpdateGroupWithVersion(ctx, logger, db, groupID, userID, creatorID, name, lang, desc, avatar, metadata, open, maxCount, -1)
+	return err
+}
+
+// UpdateGroupWithVersion updates a group and returns its new version. Every successful update increments the version.
+// If expectedVersion is 0 or greater the update is only applied when the group's current version matches, otherwise a
+// *GroupVersionConflictError carrying the current version is returned. A negative expectedVersion skips the check.
+func UpdateGroupWithVersion(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uuid.UUID, userID uuid.UUID, creatorID uuid.UUID, name, lang, desc, avatar, metadata *wrapperspb.StringValue, open *wrapperspb.BoolValue, maxCount int, expectedVersion int64) (int64, error) {
 	if userID != uuid.Nil {
 		allowedUser, err := groupCheckUserPermission(ctx, logger, db, groupID, userID, 1)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		if !allowedUser {
 			logger.Info("User does not have permission to update group.", zap.String("group", groupID.String()), zap.String("user", userID.String()))
-			return runtime.ErrGroupPermissionDenied
+			return 0, runtime.ErrGroupPermissionDenied
 		}
 	}
 
@@ -221,40 +363,68 @@ func UpdateGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uu
 	if creatorID != uuid.Nil {
 		statements = append(statements, "creator_id = $"+strconv.Itoa(index))
 		params = append(params, creatorID)
+		index++
 	}
 
 	if len(statements) == 0 {
 		logger.Info("Did not update group as no fields were changed.")
-		return runtime.ErrGroupNoUpdateOps
+		return 0, runtime.ErrGroupNoUpdateOps
 	}
 
-	query := "UPDATE groups SET update_time = now(), " + strings.Join(statements, ", ") + " WHERE (id = $1) AND (disable_time = '1970-01-01 00:00:00 UTC')"
-	res, err := db.ExecContext(ctx, query, params...)
-	if err != nil {
+	query := "UPDATE groups SET update_time = now(), version = version + 1, " + strings.Join(statements, ", ") + " WHERE (id = $1) AND (disable_time = '1970-01-01 00:00:00 UTC')"
+	if expectedVersion >= 0 {
+		query += " AND (version = $" + strconv.Itoa(index) + ")"
+		params = append(params, expectedVersion)
+	}
+	query += " RETURNING version"
+
+	var version int64
+	if err := db.QueryRowContext(ctx, query, params...).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if expectedVersion >= 0 {
+				// Distinguish a stale version from a group that does not exist or is disabled.
+				var currentVersion int64
+				if err := db.QueryRowContext(ctx, "SELECT version FROM groups WHERE id = $1 AND disable_time = '1970-01-01 00:00:00 UTC'", groupID).Scan(&currentVersion); err == nil {
+					logger.Info("Could not update group as version check failed.", zap.String("group_id", groupID.String()), zap.Int64("expected_version", expectedVersion), zap.Int64("version", currentVersion))
+					return 0, &GroupVersionConflictError{GroupID: groupID.String(), Version: currentVersion}
+				} else if !errors.Is(err, sql.ErrNoRows) {
+					logger.Error("Could not read group version.", zap.Error(err))
+					return 0, err
+				}
+			}
+			return 0, runtime.ErrGroupNotUpdated
+		}
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == dbErrorUniqueViolation {
 			logger.Info("Could not update group as it already exists.", zap.String("group_id", groupID.String()))
-			return runtime.ErrGroupNameInUse
+			return 0, runtime.ErrGroupNameInUse
 		}
 		logger.Error("Could not update group.", zap.Error(err))
-		return err
+		return 0, err
 	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		logger.Error("Could not get rows affected after group update query.", zap.Error(err))
-		return err
-	}
-	if rowsAffected == 0 {
-		return runtime.ErrGroupNotUpdated
-	}
+	logger.Info("Group updated.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()), zap.Int64("version", version))
 
-	logger.Info("Group updated.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
+	return version, nil
+}
 
-	return nil
+// GetGroupMetadataVersion reads a group's metadata together with its current version, for use as the expected
+// version in a subsequent UpdateGroupWithVersion call.
+func GetGroupMetadataVersion(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uuid.UUID) (string, int64, error) {
+	var metadata string
+	var version int64
+	if err := db.QueryRowContext(ctx, "SELECT metadata, version FROM groups WHERE id = $1 AND disable_time = '1970-01-01 00:00:00 UTC'", groupID).Scan(&metadata, &version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, runtime.ErrGroupNotFound
+		}
+		logger.Error("Could not read group metadata.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return "", 0, err
+	}
+
+	return metadata, version, nil
 }
 
-func DeleteGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, groupID uuid.UUID, userID uuid.UUID) error {
+func DeleteGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, groupID uuid.UUID, userID uuid.UUID) error {
 	if userID != uuid.Nil {
 		// only super-admins can delete group.
 		allowedUser, err := groupCheckUserPermission(ctx, logger, db, groupID, userID, 0)
@@ -268,7 +438,29 @@ func DeleteGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tr
 		}
 	}
 
+	var memberIDs []uuid.UUID
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+		memberIDs = memberIDs[:0]
+		if groupEventFn != nil {
+			// Members are read in the same transaction as the delete so the event covers exactly the removed members.
+			rows, err := tx.QueryContext(ctx, "SELECT destination_id FROM group_edge WHERE source_id = $1::UUID AND state < $2", groupID, 3)
+			if err != nil {
+				logger.Debug("Could not look up group members.", zap.Error(err))
+				return err
+			}
+			for rows.Next() {
+				var memberID uuid.UUID
+				if err := rows.Scan(&memberID); err != nil {
+					_ = rows.Close()
+					return err
+				}
+				memberIDs = append(memberIDs, memberID)
+			}
+			_ = rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
 		return deleteGroup(ctx, logger, tx, groupID)
 	}); err != nil {
 		logger.Error("Error deleting group.", zap.Error(err))
@@ -281,12 +473,14 @@ func DeleteGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tr
 		Subject: groupID,
 	})
 
+	groupEventInvoke(groupEventFn, groupID, memberIDs, GroupEventDelete)
+
 	logger.Info("Group deleted.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
 
 	return nil
 }
 
-func JoinGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, groupID uuid.UUID, userID uuid.UUID, username string) error {
+func JoinGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, router MessageRouter, pushFn NotificationPushFunction, groupID uuid.UUID, userID uuid.UUID, username string) error {
 	query := `
 SELECT id, creator_id, name, description, avatar_url, state, edge_count, lang_tag, max_count, metadata, create_time, update_time
 FROM groups
@@ -372,10 +566,12 @@ WHERE (id = $1) AND (disable_time = '1970-01-01 00:00:00 UTC')`
 
 			if len(notifications) > 0 {
 				// Any error is already logged before it's returned here.
-				_ = NotificationSend(ctx, logger, db, tracker, router, notifications)
+				_ = NotificationSend(ctx, logger, db, tracker, router, pushFn, notifications)
 			}
 		}
 
+		groupEventInvoke(groupEventFn, groupID, []uuid.UUID{userID}, GroupEventJoinRequest)
+
 		logger.Info("Added join request to group.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
 		return nil
 	}
@@ -452,11 +648,13 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 
 	router.SendToStream(logger, stream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
 
+	groupEventInvoke(groupEventFn, groupID, []uuid.UUID{userID}, GroupEventJoin)
+
 	logger.Info("Successfully joined group.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
 	return nil
 }
 
-func LeaveGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, streamManager StreamManager, groupID uuid.UUID, userID uuid.UUID, username string) error {
+func LeaveGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, router MessageRouter, streamManager StreamManager, groupID uuid.UUID, userID uuid.UUID, username string) error {
 	var myState sql.NullInt64
 	query := "SELECT state FROM group_edge WHERE source_id = $1::UUID AND destination_id = $2::UUID"
 	if err := db.QueryRowContext(ctx, query, groupID, userID).Scan(&myState); err != nil {
@@ -510,7 +708,7 @@ func LeaveGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tra
 		if otherSuperadminCount == 0 {
 			if otherMemberCount == 0 {
 				// The caller is a superadmin and the last member of the group, convert this operation to a deletion.
-				return DeleteGroup(ctx, logger, db, tracker, groupID, userID)
+				return DeleteGroup(ctx, logger, db, groupEventFn, tracker, groupID, userID)
 			}
 
 			logger.Info("Cannot leave group as user is last superadmin.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
@@ -596,11 +794,13 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		}
 	}
 
+	groupEventInvoke(groupEventFn, groupID, []uuid.UUID{userID}, GroupEventLeave)
+
 	logger.Info("Successfully left group.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
 	return nil
 }
 
-func AddGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
+func AddGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, router MessageRouter, pushFn NotificationPushFunction, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
 	if caller != uuid.Nil {
 		var dbState sql.NullInt64
 		query := "SELECT state FROM group_edge WHERE source_id = $1::UUID AND destination_id = $2::UUID"
@@ -652,11 +852,13 @@ func AddGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker
 	}
 	ts := time.Now().Unix()
 	var messages []*api.ChannelMessage
+	var added []uuid.UUID
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any notifications/messages that may have been prepared by previous attempts.
 		notifications = make(map[uuid.UUID][]*api.Notification, len(userIDs))
 		messages = make([]*api.ChannelMessage, 0, len(userIDs))
+		added = make([]uuid.UUID, 0, len(userIDs))
 
 		for _, uid := range userIDs {
 			if uid == caller {
@@ -741,6 +943,7 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 			}
 
 			messages = append(messages, message)
+			added = append(added, uid)
 
 			notifications[uid] = []*api.Notification{
 				{
@@ -765,13 +968,14 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 
 	if len(notifications) > 0 {
 		// Any error is already logged before it's returned here.
-		_ = NotificationSend(ctx, logger, db, tracker, router, notifications)
+		_ = NotificationSend(ctx, logger, db, tracker, router, pushFn, notifications)
 	}
 
+	groupEventInvoke(groupEventFn, groupID, added, GroupEventAdd)
 	return nil
 }
 
-func BanGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, streamManager StreamManager, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
+func BanGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, router MessageRouter, streamManager StreamManager, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
 	myState := 0
 	if caller != uuid.Nil {
 		var dbState sql.NullInt64
@@ -805,10 +1009,12 @@ func BanGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker
 	ts := time.Now().Unix()
 	var messages []*api.ChannelMessage
 	kicked := make(map[uuid.UUID]struct{}, len(userIDs))
+	var banned []uuid.UUID
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any messages that may have been prepared by previous attempts.
 		messages = make([]*api.ChannelMessage, 0, len(userIDs))
+		banned = make([]uuid.UUID, 0, len(userIDs))
 		// Position to use for new banned edges.
 		position := time.Now().UTC().UnixNano()
 
@@ -876,6 +1082,11 @@ UPDATE SET state = $2, update_time = now()`
 				return err
 			}
 
+			// Users that were already banned are re-inserted unchanged.
+			if deletedState.Int64 != BANNED_CODE {
+				banned = append(banned, uid)
+			}
+
 			// Only update group edge count and send messages when we kicked valid members, not invites.
 			if deletedState.Int64 < 3 {
 				query = "UPDATE groups SET edge_count = edge_count - 1, update_time = now() WHERE id = $1::UUID"
@@ -940,10 +1151,11 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		}
 	}
 
+	groupEventInvoke(groupEventFn, groupID, banned, GroupEventBan)
 	return nil
 }
 
-func KickGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, streamManager StreamManager, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID, strictError bool) error {
+func KickGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, tracker Tracker, router MessageRouter, streamManager StreamManager, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID, strictError bool) error {
 	myState := 0
 	if caller != uuid.Nil {
 		var dbState sql.NullInt64
@@ -990,10 +1202,12 @@ func KickGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker
 	ts := time.Now().Unix()
 	var messages []*api.ChannelMessage
 	kicked := make(map[uuid.UUID]struct{}, len(userIDs))
+	var kickedIDs []uuid.UUID
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any messages that may have been prepared by previous attempts.
 		messages = make([]*api.ChannelMessage, 0, len(userIDs))
+		kickedIDs = make([]uuid.UUID, 0, len(userIDs))
 
 		for _, uid := range userIDs {
 			// Shouldn't kick self.
@@ -1094,6 +1308,7 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 				}
 				messages = append(messages, message)
 				kicked[uid] = struct{}{}
+				kickedIDs = append(kickedIDs, uid)
 			}
 		}
 		return nil
@@ -1116,10 +1331,11 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		}
 	}
 
+	groupEventInvoke(groupEventFn, groupID, kickedIDs, GroupEventKick)
 	return nil
 }
 
-func PromoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
+func PromoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, router MessageRouter, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
 	myState := 0
 	if caller != uuid.Nil {
 		var dbState sql.NullInt64
@@ -1165,10 +1381,12 @@ func PromoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, rout
 
 	ts := time.Now().Unix()
 	var messages []*api.ChannelMessage
+	var promoted []uuid.UUID
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any messages that may have been prepared by previous attempts.
 		messages = make([]*api.ChannelMessage, 0, len(userIDs))
+		promoted = make([]uuid.UUID, 0, len(userIDs))
 
 		for _, uid := range userIDs {
 			if uid == caller {
@@ -1230,6 +1448,7 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 				return err
 			}
 			messages = append(messages, message)
+			promoted = append(promoted, uid)
 		}
 		return nil
 	}); err != nil {
@@ -1241,10 +1460,11 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		router.SendToStream(logger, stream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
 	}
 
+	groupEventInvoke(groupEventFn, groupID, promoted, GroupEventPromote)
 	return nil
 }
 
-func DemoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
+func DemoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, groupEventFn GroupEventFunction, router MessageRouter, caller uuid.UUID, groupID uuid.UUID, userIDs []uuid.UUID) error {
 	myState := 0
 	if caller != uuid.Nil {
 		var dbState sql.NullInt64
@@ -1290,10 +1510,12 @@ func DemoteGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, route
 
 	ts := time.Now().Unix()
 	var messages []*api.ChannelMessage
+	var demoted []uuid.UUID
 
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
 		// If the transaction is retried ensure we wipe any messages that may have been prepared by previous attempts.
 		messages = make([]*api.ChannelMessage, 0, len(userIDs))
+		demoted = make([]uuid.UUID, 0, len(userIDs))
 
 		for _, uid := range userIDs {
 			if uid == caller {
@@ -1370,6 +1592,7 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 				return err
 			}
 			messages = append(messages, message)
+			demoted = append(demoted, uid)
 		}
 		return nil
 	}); err != nil {
@@ -1381,10 +1604,21 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 		router.SendToStream(logger, stream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
 	}
 
+	groupEventInvoke(groupEventFn, groupID, demoted, GroupEventDemote)
 	return nil
 }
 
 func ListGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, groupID uuid.UUID, limit int, state *wrapperspb.Int32Value, cursor string) (*api.GroupUserList, error) {
+	var states []int32
+	if state != nil {
+		states = []int32{state.Value}
+	}
+	return ListGroupUsersByStates(ctx, logger, db, statusRegistry, groupID, limit, states, cursor)
+}
+
+// ListGroupUsersByStates lists group members, optionally restricted to a set of membership states such as superadmin and
+// admin. Members are ordered by state, join time and user ID, so paging stays stable while members join or leave.
+func ListGroupUsersByStates(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, groupID uuid.UUID, limit int, states []int32, cursor string) (*api.GroupUserList, error) {
 	var incomingCursor *edgeListCursor
 	if cursor != "" {
 		cb, err := base64.StdEncoding.DecodeString(cursor)
@@ -1397,12 +1631,12 @@ func ListGroupUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, statusR
 		}
 
 		// Cursor and filter mismatch. Perhaps the caller has sent an old cursor with a changed filter.
-		if state != nil && int64(state.Value) != incomingCursor.State {
+		if len(states) != 0 && !slices.Contains(states, int32(incomingCursor.State)) {
 			return nil, runtime.ErrGroupUserInvalidCursor
 		}
 	}
 
-	params := make([]interface{}, 0, 4)
+	params := make([]interface{}, 0, 5)
 	query := `
 SELECT u.id, u.username, u.display_name, u.avatar_url,
 	u.lang_tag, u.location, u.timezone, u.metadata,
@@ -1411,22 +1645,24 @@ SELECT u.id, u.username, u.display_name, u.avatar_url,
 FROM users u, group_edge ge
 WHERE u.id = ge.destination_id AND ge.source_id = $1`
 	params = append(params, groupID)
-	if state != nil {
-		// Assumes the state has already been validated before this function.
-		query += " AND ge.state = $2"
-		params = append(params, state.Value)
-	} else {
+	switch len(states) {
+	case 0:
 		// Hint for the query analyzer to ensure it performs and index scan on the appropriate range of the group_edge pkey.
 		query += " AND ge.state >= 0 AND ge.state <= 3"
+	case 1:
+		// Assumes the state has already been validated before this function.
+		query += " AND ge.state = $2"
+		params = append(params, states[0])
+	default:
+		query += " AND ge.state = ANY($2)"
+		params = append(params, states)
 	}
 	if incomingCursor != nil {
-		query += " AND (ge.source_id, ge.state, ge.position) >= ($1, $2, $3)"
-		if state == nil {
-			params = append(params, incomingCursor.State)
-		}
-		params = append(params, incomingCursor.Position)
+		// Members removed since the cursor was issued are simply absent, the position and user ID tie-breaker ensure no member is repeated or skipped.
+		params = append(params, incomingCursor.State, incomingCursor.Position, incomingCursor.UserID)
+		query += fmt.Sprintf(" AND (ge.source_id, ge.state, ge.position, ge.destination_id) >= ($1, $%d, $%d, $%d)", len(params)-2, len(params)-1, len(params))
 	}
-	query += " ORDER BY ge.state ASC, ge.position ASC"
+	query += " ORDER BY ge.state ASC, ge.position ASC, ge.destination_id ASC"
 	if limit != 0 {
 		// Console API can select all group users in one request. Client/runtime calls will set a non-0 limit.
 		params = append(params, limit+1)
@@ -1479,7 +1715,7 @@ WHERE u.id = ge.destination_id AND ge.source_id = $1`
 
 		if limit != 0 && len(groupUsers) >= limit {
 			cursorBuf := new(bytes.Buffer)
-			if err := gob.NewEncoder(cursorBuf).Encode(&edgeListCursor{State: state.Int64, Position: position.Int64}); err != nil {
+			if err := gob.NewEncoder(cursorBuf).Encode(&edgeListCursor{State: state.Int64, Position: position.Int64, UserID: uuid.FromStringOrNil(id)}); err != nil {
 				_ = rows.Close()
 				logger.Error("Error creating group user list cursor", zap.Error(err))
 				return nil, err
@@ -2168,16 +2404,38 @@ WHERE group_edge.destination_id = $1`
 }
 
 func GetRandomGroups(ctx context.Context, logger *zap.Logger, db *sql.DB, count int) ([]*api.Group, error) {
+	return GetRandomGroupsFiltered(ctx, logger, db, count, nil, 0)
+}
+
+// GetRandomGroupsFiltered returns up to count random groups, optionally only those with the given open state and, if
+// maxFullness is greater than 0, only those whose member count is below that fraction of their max count. A maxFullness
+// of 1 therefore excludes groups at capacity. Returns an empty list if no groups qualify.
+func GetRandomGroupsFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, count int, open *bool, maxFullness float64) ([]*api.Group, error) {
 	if count == 0 {
 		return []*api.Group{}, nil
 	}
 
+	params := []interface{}{uuid.Must(uuid.NewV4()).String(), count}
+	var filterSQL string
+	if open != nil {
+		state := 0
+		if !*open {
+			state = 1
+		}
+		params = append(params, state)
+		filterSQL += fmt.Sprintf(" AND state = $%v", len(params))
+	}
+	if maxFullness > 0 {
+		params = append(params, maxFullness)
+		filterSQL += fmt.Sprintf(" AND edge_count < max_count * $%v::FLOAT", len(params))
+	}
+
 	query := `
 SELECT id, creator_id, name, description, avatar_url, state, edge_count, lang_tag, max_count, metadata, create_time, update_time
 FROM groups
-WHERE id > $1
+WHERE id > $1` + filterSQL + `
 LIMIT $2`
-	rows, err := db.QueryContext(ctx, query, uuid.Must(uuid.NewV4()).String(), count)
+	rows, err := db.QueryContext(ctx, query, params...)
 	if err != nil {
 		logger.Error("Error retrieving random groups.", zap.Error(err))
 		return nil, err
@@ -2196,7 +2454,8 @@ LIMIT $2`
 
 	if len(groups) < count {
 		// Need more groups.
-		rows, err = db.QueryContext(ctx, query, uuid.Nil.String(), count)
+		params[0] = uuid.Nil.String()
+		rows, err = db.QueryContext(ctx, query, params...)
 		if err != nil {
 			logger.Error("Error retrieving random groups.", zap.Error(err))
 			return nil, err
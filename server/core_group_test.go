@@ -0,0 +1,226 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestListGroupUsersByStatesPaginationWithKick(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	statusRegistry := NewLocalStatusRegistry(logger, cfg, NewLocalSessionRegistry(metrics), protojsonMarshaler)
+
+	creatorID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, creatorID)
+	group, err := CreateGroup(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", true, 10_000)
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	groupID := uuid.Must(uuid.FromString(group.Id))
+
+	// Seed the remaining members in bulk, adding them one by one is too slow for a group of this size.
+	memberIDs := make([]uuid.UUID, 4999)
+	for i := range memberIDs {
+		memberIDs[i] = uuid.Must(uuid.NewV4())
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (id, username) SELECT id, id::STRING FROM unnest($1::UUID[]) AS id", memberIDs); err != nil {
+		t.Fatalf("error inserting users: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, `
+INSERT INTO group_edge (position, state, source_id, destination_id)
+SELECT $2::BIGINT + m.ord, 2, $3::UUID, m.id FROM unnest($1::UUID[]) WITH ORDINALITY AS m(id, ord)
+UNION ALL
+SELECT $2::BIGINT + m.ord, 2, m.id, $3::UUID FROM unnest($1::UUID[]) WITH ORDINALITY AS m(id, ord)`, memberIDs, time.Now().UTC().UnixNano(), groupID); err != nil {
+		t.Fatalf("error inserting group edges: %v", err.Error())
+	}
+
+	kickedID := memberIDs[3000]
+	seen := make(map[string]int, 5000)
+	var cursor string
+	for page := 0; ; page++ {
+		list, err := ListGroupUsersByStates(ctx, logger, db, statusRegistry, groupID, 100, nil, cursor)
+		if err != nil {
+			t.Fatalf("error listing group users: %v", err.Error())
+		}
+		for _, groupUser := range list.GroupUsers {
+			seen[groupUser.User.Id]++
+		}
+
+		if page == 10 {
+			if err := KickGroupUsers(ctx, logger, db, nil, &testTracker{}, &DummyMessageRouter{}, testStreamManager{}, uuid.Nil, groupID, []uuid.UUID{kickedID}, true); err != nil {
+				t.Fatalf("error kicking group user: %v", err.Error())
+			}
+		}
+
+		if list.Cursor == "" {
+			break
+		}
+		cursor = list.Cursor
+	}
+
+	assert.Len(t, seen, 4999, "every member except the kicked one should be listed")
+	for userID, count := range seen {
+		assert.Equal(t, 1, count, "member %v listed more than once", userID)
+	}
+	assert.NotContains(t, seen, kickedID.String())
+
+	admins, err := ListGroupUsersByStates(ctx, logger, db, statusRegistry, groupID, 100, []int32{0, 1}, "")
+	if err != nil {
+		t.Fatalf("error listing group admins: %v", err.Error())
+	}
+	if assert.Len(t, admins.GroupUsers, 1) {
+		assert.Equal(t, creatorID.String(), admins.GroupUsers[0].User.Id)
+	}
+}
+
+func TestCreateGroupWithMembers(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	creatorID := uuid.Must(uuid.NewV4())
+	adminID := uuid.Must(uuid.NewV4())
+	memberID := uuid.Must(uuid.NewV4())
+	for _, userID := range []uuid.UUID{creatorID, adminID, memberID} {
+		InsertUser(t, db, userID)
+	}
+	members := []*GroupCreateMember{{UserID: adminID, State: 1}, {UserID: memberID, State: 2}}
+
+	group, added, err := CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", false, 3, members)
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	assert.Equal(t, 2, added)
+	assert.EqualValues(t, 3, group.EdgeCount)
+
+	// Join requests are kept in closed groups but are not counted as members.
+	requesterID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, requesterID)
+	group, added, err = CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", false, 3, append(members, &GroupCreateMember{UserID: requesterID, State: 3}))
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	assert.Equal(t, 2, added)
+	assert.EqualValues(t, 3, group.EdgeCount)
+
+	name := uuid.Must(uuid.NewV4()).String()
+	_, _, err = CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, name, "", "", "", "", false, 2, members)
+	assert.ErrorIs(t, err, runtime.ErrGroupFull)
+
+	// The failed creation must not leave a group behind.
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM groups WHERE name = $1", name).Scan(&count); err != nil {
+		t.Fatalf("error counting groups: %v", err.Error())
+	}
+	assert.Zero(t, count)
+}
+
+func TestUpdateGroupWithVersion(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	group, err := CreateGroup(ctx, logger, db, nil, userID, userID, uuid.Must(uuid.NewV4()).String(), "", "", "", "{}", false, 10)
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	groupID := uuid.FromStringOrNil(group.Id)
+
+	_, version, err := GetGroupMetadataVersion(ctx, logger, db, groupID)
+	if err != nil {
+		t.Fatalf("error reading group version: %v", err.Error())
+	}
+	assert.EqualValues(t, 0, version)
+
+	// Two officers read the same version, the first update wins.
+	newVersion, err := UpdateGroupWithVersion(ctx, logger, db, groupID, uuid.Nil, uuid.Nil, nil, nil, nil, nil, &wrapperspb.StringValue{Value: `{"motd":"first"}`}, nil, 0, version)
+	if err != nil {
+		t.Fatalf("error updating group: %v", err.Error())
+	}
+	assert.EqualValues(t, 1, newVersion)
+
+	_, err = UpdateGroupWithVersion(ctx, logger, db, groupID, uuid.Nil, uuid.Nil, nil, nil, nil, nil, &wrapperspb.StringValue{Value: `{"motd":"second"}`}, nil, 0, version)
+	var conflictErr *GroupVersionConflictError
+	if assert.ErrorAs(t, err, &conflictErr) {
+		assert.Equal(t, group.Id, conflictErr.GroupID)
+		assert.EqualValues(t, 1, conflictErr.Version)
+	}
+
+	metadata, version, err := GetGroupMetadataVersion(ctx, logger, db, groupID)
+	if err != nil {
+		t.Fatalf("error reading group version: %v", err.Error())
+	}
+	assert.JSONEq(t, `{"motd":"first"}`, metadata)
+	assert.EqualValues(t, 1, version)
+
+	// Updates without an expected version always apply and still increment the version.
+	err = UpdateGroup(ctx, logger, db, groupID, uuid.Nil, uuid.Nil, nil, nil, nil, nil, &wrapperspb.StringValue{Value: `{"motd":"third"}`}, nil, 0)
+	assert.NoError(t, err)
+	_, version, err = GetGroupMetadataVersion(ctx, logger, db, groupID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, version)
+}
+
+func TestGetRandomGroupsFiltered(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	creatorID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, creatorID)
+
+	full, _, err := CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", true, 1, nil)
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	closed, _, err := CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", false, 10, nil)
+	if err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+	// An open group with space, so there is always at least one match.
+	if _, _, err := CreateGroupWithMembers(ctx, logger, db, nil, creatorID, creatorID, uuid.Must(uuid.NewV4()).String(), "", "", "", "", true, 10, nil); err != nil {
+		t.Fatalf("error creating group: %v", err.Error())
+	}
+
+	open := true
+	groups, err := GetRandomGroupsFiltered(ctx, logger, db, 1000, &open, 1)
+	if err != nil {
+		t.Fatalf("error getting random groups: %v", err.Error())
+	}
+	require.NotEmpty(t, groups, "open groups with space should be returned")
+	for _, group := range groups {
+		assert.True(t, group.Open.Value, "only open groups should be returned")
+		assert.Less(t, group.EdgeCount, group.MaxCount, "full groups should not be returned")
+		assert.NotEqual(t, full.Id, group.Id)
+		assert.NotEqual(t, closed.Id, group.Id)
+	}
+}
@@ -0,0 +1,499 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchemaSubset is a compiled schema in a restricted subset of JSON Schema, not a full JSON Schema implementation.
+// Only these validation keywords are supported: type, enum, const, properties, required, additionalProperties,
+// minProperties, maxProperties, items, minItems, maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf and not. Annotation keywords such as title or
+// description are ignored. Schemas using references, formats, conditionals or any other keyword are rejected when
+// compiled rather than silently validating less than expected.
+type JSONSchemaSubset struct {
+	alwaysFalse bool
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constVal interface{}
+
+	properties           map[string]*JSONSchemaSubset
+	required             []string
+	additionalProperties *JSONSchemaSubset
+	minProperties        int
+	maxProperties        int
+	items                *JSONSchemaSubset
+	minItems             int
+	maxItems             int
+	uniqueItems          bool
+	minLength            int
+	maxLength            int
+	pattern              *regexp.Regexp
+	minimum              *float64
+	maximum              *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	multipleOf           *float64
+	allOf, anyOf, oneOf  []*JSONSchemaSubset
+	not                  *JSONSchemaSubset
+}
+
+var jsonSchemaTypes = map[string]bool{"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true}
+
+// Keywords that carry no validation meaning. Definitions are only reachable through references, which are rejected.
+var jsonSchemaAnnotations = map[string]bool{"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true, "title": true, "description": true, "default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true}
+
+// CompileJSONSchemaSubsetString parses and compiles a schema document written in the supported JSON Schema subset.
+func CompileJSONSchemaSubsetString(schema string) (*JSONSchemaSubset, error) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(schema), &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %s", err.Error())
+	}
+	return CompileJSONSchemaSubset(raw)
+}
+
+// CompileJSONSchemaSubset compiles a schema in the supported JSON Schema subset already decoded into Go values, as
+// produced by encoding/json or a runtime value conversion.
+func CompileJSONSchemaSubset(raw interface{}) (*JSONSchemaSubset, error) {
+	return compileJSONSchema(raw, "#")
+}
+
+func compileJSONSchema(raw interface{}, path string) (*JSONSchemaSubset, error) {
+	s := &JSONSchemaSubset{minProperties: -1, maxProperties: -1, minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+
+	var m map[string]interface{}
+	switch v := raw.(type) {
+	case bool:
+		s.alwaysFalse = !v
+		return s, nil
+	case map[string]interface{}:
+		m = v
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", path)
+	}
+
+	var err error
+	for keyword, value := range m {
+		keywordPath := path + "/" + keyword
+		switch keyword {
+		case "$ref", "$dynamicRef", "$recursiveRef":
+			return nil, fmt.Errorf("%s: references are not supported", keywordPath)
+		case "type":
+			switch t := value.(type) {
+			case string:
+				s.types = []string{t}
+			case []interface{}:
+				for _, e := range t {
+					str, ok := e.(string)
+					if !ok {
+						return nil, fmt.Errorf("%s: must be a string or an array of strings", keywordPath)
+					}
+					s.types = append(s.types, str)
+				}
+			default:
+				return nil, fmt.Errorf("%s: must be a string or an array of strings", keywordPath)
+			}
+			for _, t := range s.types {
+				if !jsonSchemaTypes[t] {
+					return nil, fmt.Errorf("%s: unknown type %q", keywordPath, t)
+				}
+			}
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", keywordPath)
+			}
+			s.enum = make([]interface{}, 0, len(values))
+			for _, e := range values {
+				s.enum = append(s.enum, jsonSchemaNormalize(e))
+			}
+		case "const":
+			s.hasConst = true
+			s.constVal = jsonSchemaNormalize(value)
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", keywordPath)
+			}
+			s.properties = make(map[string]*JSONSchemaSubset, len(properties))
+			for name, property := range properties {
+				if s.properties[name], err = compileJSONSchema(property, keywordPath+"/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			names, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array of strings", keywordPath)
+			}
+			for _, e := range names {
+				name, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: must be an array of strings", keywordPath)
+				}
+				s.required = append(s.required, name)
+			}
+		case "additionalProperties":
+			if s.additionalProperties, err = compileJSONSchema(value, keywordPath); err != nil {
+				return nil, err
+			}
+		case "items":
+			if s.items, err = compileJSONSchema(value, keywordPath); err != nil {
+				return nil, err
+			}
+		case "not":
+			if s.not, err = compileJSONSchema(value, keywordPath); err != nil {
+				return nil, err
+			}
+		case "allOf", "anyOf", "oneOf":
+			subschemas, ok := value.([]interface{})
+			if !ok || len(subschemas) == 0 {
+				return nil, fmt.Errorf("%s: must be a non-empty array of schemas", keywordPath)
+			}
+			compiled := make([]*JSONSchemaSubset, 0, len(subschemas))
+			for i, subschema := range subschemas {
+				c, err := compileJSONSchema(subschema, keywordPath+"/"+strconv.Itoa(i))
+				if err != nil {
+					return nil, err
+				}
+				compiled = append(compiled, c)
+			}
+			switch keyword {
+			case "allOf":
+				s.allOf = compiled
+			case "anyOf":
+				s.anyOf = compiled
+			default:
+				s.oneOf = compiled
+			}
+		case "minProperties", "maxProperties", "minItems", "maxItems", "minLength", "maxLength":
+			f, ok := jsonSchemaNumber(value)
+			if !ok || f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+				return nil, fmt.Errorf("%s: must be a non-negative integer", keywordPath)
+			}
+			switch keyword {
+			case "minProperties":
+				s.minProperties = int(f)
+			case "maxProperties":
+				s.maxProperties = int(f)
+			case "minItems":
+				s.minItems = int(f)
+			case "maxItems":
+				s.maxItems = int(f)
+			case "minLength":
+				s.minLength = int(f)
+			default:
+				s.maxLength = int(f)
+			}
+		case "uniqueItems":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a boolean", keywordPath)
+			}
+			s.uniqueItems = b
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", keywordPath)
+			}
+			if s.pattern, err = regexp.Compile(str); err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %s", keywordPath, err.Error())
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+			f, ok := jsonSchemaNumber(value)
+			if !ok || (keyword == "multipleOf" && f <= 0) {
+				return nil, fmt.Errorf("%s: must be a number", keywordPath)
+			}
+			switch keyword {
+			case "minimum":
+				s.minimum = &f
+			case "maximum":
+				s.maximum = &f
+			case "exclusiveMinimum":
+				s.exclusiveMinimum = &f
+			case "exclusiveMaximum":
+				s.exclusiveMaximum = &f
+			default:
+				s.multipleOf = &f
+			}
+		default:
+			if !jsonSchemaAnnotations[keyword] {
+				return nil, fmt.Errorf("%s: unsupported keyword %q", keywordPath, keyword)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Validate checks a value against the schema and returns a description of every violation found, each prefixed with
+// the JSON pointer of the offending value. An empty result means the value is valid.
+func (s *JSONSchemaSubset) Validate(value interface{}) []string {
+	return s.validate(jsonSchemaNormalize(value), "#", nil)
+}
+
+func (s *JSONSchemaSubset) validate(value interface{}, path string, errs []string) []string {
+	if s.alwaysFalse {
+		return append(errs, fmt.Sprintf("%s: no value is allowed", path))
+	}
+
+	if len(s.types) != 0 {
+		matched := false
+		for _, t := range s.types {
+			if jsonSchemaIsType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			// Type mismatches make the remaining keywords meaningless for this value.
+			return append(errs, fmt.Sprintf("%s: expected type %s", path, strings.Join(s.types, " or ")))
+		}
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: value is not one of the allowed values", path))
+		}
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constVal, value) {
+		errs = append(errs, fmt.Sprintf("%s: value does not match the expected constant", path))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = s.validateObject(v, path, errs)
+	case []interface{}:
+		errs = s.validateArray(v, path, errs)
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength >= 0 && length < s.minLength {
+			errs = append(errs, fmt.Sprintf("%s: length must be at least %d", path, s.minLength))
+		}
+		if s.maxLength >= 0 && length > s.maxLength {
+			errs = append(errs, fmt.Sprintf("%s: length must be at most %d", path, s.maxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs = append(errs, fmt.Sprintf("%s: does not match pattern %q", path, s.pattern.String()))
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			errs = append(errs, fmt.Sprintf("%s: must be >= %v", path, *s.minimum))
+		}
+		if s.maximum != nil && v > *s.maximum {
+			errs = append(errs, fmt.Sprintf("%s: must be <= %v", path, *s.maximum))
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			errs = append(errs, fmt.Sprintf("%s: must be > %v", path, *s.exclusiveMinimum))
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			errs = append(errs, fmt.Sprintf("%s: must be < %v", path, *s.exclusiveMaximum))
+		}
+		if s.multipleOf != nil {
+			if q := v / *s.multipleOf; q != math.Trunc(q) {
+				errs = append(errs, fmt.Sprintf("%s: must be a multiple of %v", path, *s.multipleOf))
+			}
+		}
+	}
+
+	for _, subschema := range s.allOf {
+		errs = subschema.validate(value, path, errs)
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, subschema := range s.anyOf {
+			if len(subschema.validate(value, path, nil)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s: must match at least one schema in anyOf", path))
+		}
+	}
+	if s.oneOf != nil {
+		matches := 0
+		for _, subschema := range s.oneOf {
+			if len(subschema.validate(value, path, nil)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			errs = append(errs, fmt.Sprintf("%s: must match exactly one schema in oneOf, matched %d", path, matches))
+		}
+	}
+	if s.not != nil && len(s.not.validate(value, path, nil)) == 0 {
+		errs = append(errs, fmt.Sprintf("%s: must not match the schema in not", path))
+	}
+
+	return errs
+}
+
+func (s *JSONSchemaSubset) validateObject(v map[string]interface{}, path string, errs []string) []string {
+	if s.minProperties >= 0 && len(v) < s.minProperties {
+		errs = append(errs, fmt.Sprintf("%s: must have at least %d properties", path, s.minProperties))
+	}
+	if s.maxProperties >= 0 && len(v) > s.maxProperties {
+		errs = append(errs, fmt.Sprintf("%s: must have at most %d properties", path, s.maxProperties))
+	}
+	for _, name := range s.required {
+		if _, found := v[name]; !found {
+			errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+		}
+	}
+
+	// Visit properties in a stable order so the reported errors are deterministic.
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPath := path + "/" + jsonSchemaEscapePointer(name)
+		if property, found := s.properties[name]; found {
+			errs = property.validate(v[name], propertyPath, errs)
+		} else if s.additionalProperties != nil {
+			if s.additionalProperties.alwaysFalse {
+				errs = append(errs, fmt.Sprintf("%s: additional property is not allowed", propertyPath))
+			} else {
+				errs = s.additionalProperties.validate(v[name], propertyPath, errs)
+			}
+		}
+	}
+	return errs
+}
+
+func (s *JSONSchemaSubset) validateArray(v []interface{}, path string, errs []string) []string {
+	if s.minItems >= 0 && len(v) < s.minItems {
+		errs = append(errs, fmt.Sprintf("%s: must have at least %d items", path, s.minItems))
+	}
+	if s.maxItems >= 0 && len(v) > s.maxItems {
+		errs = append(errs, fmt.Sprintf("%s: must have at most %d items", path, s.maxItems))
+	}
+	if s.uniqueItems {
+	uniqueLoop:
+		for i := 1; i < len(v); i++ {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					errs = append(errs, fmt.Sprintf("%s: items must be unique", path))
+					break uniqueLoop
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range v {
+			errs = s.items.validate(item, path+"/"+strconv.Itoa(i), errs)
+		}
+	}
+	return errs
+}
+
+func jsonSchemaIsType(value interface{}, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		switch v := value.(type) {
+		case []interface{}:
+			return true
+		case map[string]interface{}:
+			// Runtime tables and some decoders cannot tell an empty array from an empty object.
+			return len(v) == 0
+		}
+		return false
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	case "string":
+		_, ok := value.(string)
+		return ok
+	}
+	return false
+}
+
+// jsonSchemaNormalize converts all numeric representations to float64 so values decoded by different means compare
+// equal. Unknown types are left as-is and fail any type check.
+func jsonSchemaNormalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = jsonSchemaNormalize(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = jsonSchemaNormalize(e)
+		}
+		return a
+	default:
+		if f, ok := jsonSchemaNumber(v); ok {
+			return f
+		}
+		return v
+	}
+}
+
+func jsonSchemaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func jsonSchemaEscapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := CompileJSONSchemaSubsetString(`{
+		"type": "object",
+		"required": ["name", "score"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+			"score": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"enum": ["red", "blue"]}, "uniqueItems": true},
+			"mode": {"oneOf": [{"const": "ranked"}, {"const": "casual"}]}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("error compiling schema: %v", err.Error())
+	}
+
+	assert.Empty(t, schema.Validate(map[string]interface{}{"name": "alice", "score": int64(10), "tags": []interface{}{"red"}, "mode": "ranked"}))
+
+	// Values decoded from JSON use float64 for all numbers.
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(`{"name": "bob", "score": 3, "tags": []}`), &decoded); err != nil {
+		t.Fatalf("error decoding value: %v", err.Error())
+	}
+	assert.Empty(t, schema.Validate(decoded))
+
+	// Runtime tables cannot distinguish an empty array from an empty object.
+	assert.Empty(t, schema.Validate(map[string]interface{}{"name": "bob", "score": 3, "tags": map[string]interface{}{}}))
+
+	errs := schema.Validate(map[string]interface{}{"name": "Alice!", "score": 1.5, "tags": []interface{}{"red", "red", "green"}, "mode": "solo", "extra": true})
+	assert.Equal(t, []string{
+		`#/extra: additional property is not allowed`,
+		`#/mode: must match exactly one schema in oneOf, matched 0`,
+		`#/name: does not match pattern "^[a-z]+$"`,
+		`#/score: expected type integer`,
+		`#/tags: items must be unique`,
+		`#/tags/2: value is not one of the allowed values`,
+	}, errs)
+
+	errs = schema.Validate(map[string]interface{}{"name": "alice"})
+	assert.Equal(t, []string{`#: missing required property "score"`}, errs)
+
+	errs = schema.Validate("alice")
+	assert.Equal(t, []string{`#: expected type object`}, errs)
+}
+
+func TestJSONSchemaCompileErrors(t *testing.T) {
+	for _, schema := range []string{
+		`not json`,
+		`"string"`,
+		`{"type": "unknown"}`,
+		`{"$ref": "#/definitions/user"}`,
+		`{"properties": {"name": {"$ref": "#/definitions/name"}}}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"anyOf": []}`,
+		`{"multipleOf": 0}`,
+		`{"type": "object", "patternProperties": {"^a": {"type": "string"}}}`,
+		`{"items": {"contains": {"type": "string"}}}`,
+		`{"typ": "string"}`,
+	} {
+		_, err := CompileJSONSchemaSubsetString(schema)
+		assert.Error(t, err, schema)
+	}
+}
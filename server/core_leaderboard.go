@@ -23,6 +23,7 @@ import (
 	"errors"
 	"github.com/heroiclabs/nakama-common/runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,8 +43,14 @@ var (
 	ErrLeaderboardAuthoritative = errors.New("leaderboard only allows authoritative submissions")
 	ErrLeaderboardInvalidCursor = errors.New("leaderboard cursor invalid")
 	ErrInvalidOperator          = errors.New("invalid operator")
+
+	ErrLeaderboardMaxNumScoreReached  = errors.New("leaderboard max num score reached")
+	ErrLeaderboardRecordExpiryInvalid = errors.New("leaderboard record expiry must be in the future")
 )
 
+// Excludes records whose own expiry, set when they were written, has passed.
+const leaderboardRecordActiveFilter = " AND (record_expiry_time IS NULL OR record_expiry_time > now())"
+
 type leaderboardRecordListCursor struct {
 	// Query hint.
 	IsNext bool
@@ -139,158 +146,18 @@ func LeaderboardRecordsList(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	var nextCursorStr, prevCursorStr string
 
 	if limit != nil {
-		limitNumber := int(limit.Value)
-		incomingCursor, err := unmarshalLeaderboardRecordsListCursor(leaderboardId, expiryTime, cursor)
-		if err != nil {
-			return nil, err
-		}
-
-		query := "SELECT owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time FROM leaderboard_record WHERE leaderboard_id = $1 AND expiry_time = $2"
-		if incomingCursor == nil {
-			if leaderboard.SortOrder == LeaderboardSortOrderAscending {
-				query += " ORDER BY score ASC, subscore ASC, owner_id ASC"
-			} else {
-				query += " ORDER BY score DESC, subscore DESC, owner_id DESC"
-			}
-		} else {
-			if (leaderboard.SortOrder == LeaderboardSortOrderAscending && incomingCursor.IsNext) || (leaderboard.SortOrder == LeaderboardSortOrderDescending && !incomingCursor.IsNext) {
-				// Ascending and next page == descending and previous page.
-				query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) > ($1, $2, $4, $5, $6) ORDER BY score ASC, subscore ASC, owner_id ASC"
-			} else {
-				// Ascending and previous page == descending and next page.
-				query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) < ($1, $2, $4, $5, $6) ORDER BY score DESC, subscore DESC, owner_id DESC"
-			}
-		}
-		query += " LIMIT $3"
-		params := make([]interface{}, 0, 6)
-		params = append(params, leaderboardId, time.Unix(expiryTime, 0).UTC(), limitNumber+1)
-		if incomingCursor != nil {
-			params = append(params, incomingCursor.Score, incomingCursor.Subscore, incomingCursor.OwnerId)
-		}
-
-		rows, err := db.QueryContext(ctx, query, params...)
+		var err error
+		records, nextCursorStr, prevCursorStr, err = listLeaderboardRecordsPage(ctx, logger, db, leaderboardId, leaderboard.SortOrder, expiryTime, int(limit.Value), cursor, nil)
 		if err != nil {
-			logger.Error("Error listing leaderboard records", zap.Error(err))
 			return nil, err
 		}
-
-		rank := int64(0)
-		if incomingCursor != nil {
-			rank = incomingCursor.Rank
-		}
-		records = make([]*api.LeaderboardRecord, 0, limitNumber)
-		var nextCursor, prevCursor *leaderboardRecordListCursor
-
-		var dbOwnerID string
-		var dbUsername sql.NullString
-		var dbScore int64
-		var dbSubscore int64
-		var dbNumScore int32
-		var dbMaxNumScore int32
-		var dbMetadata string
-		var dbCreateTime pgtype.Timestamptz
-		var dbUpdateTime pgtype.Timestamptz
-		for rows.Next() {
-			if len(records) >= limitNumber {
-				nextCursor = &leaderboardRecordListCursor{
-					IsNext:        true,
-					LeaderboardId: leaderboardId,
-					ExpiryTime:    expiryTime,
-					Score:         dbScore,
-					Subscore:      dbSubscore,
-					OwnerId:       dbOwnerID,
-					Rank:          rank,
-				}
-				break
-			}
-
-			err = rows.Scan(&dbOwnerID, &dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime)
-			if err != nil {
-				_ = rows.Close()
-				logger.Error("Error parsing listed leaderboard records", zap.Error(err))
-				return nil, err
-			}
-
-			if incomingCursor != nil && !incomingCursor.IsNext {
-				rank--
-			} else {
-				rank++
-			}
-
-			record := &api.LeaderboardRecord{
-				LeaderboardId: leaderboardId,
-				OwnerId:       dbOwnerID,
-				Score:         dbScore,
-				Subscore:      dbSubscore,
-				NumScore:      dbNumScore,
-				MaxNumScore:   uint32(dbMaxNumScore),
-				Metadata:      dbMetadata,
-				CreateTime:    &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
-				UpdateTime:    &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
-				Rank:          rank,
-			}
-			if dbUsername.Valid {
-				record.Username = &wrapperspb.StringValue{Value: dbUsername.String}
-			}
-			if expiryTime != 0 {
-				record.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
-			}
-
-			records = append(records, record)
-
-			// There can only be a previous page if this is a paginated listing.
-			if incomingCursor != nil && prevCursor == nil {
-				prevCursor = &leaderboardRecordListCursor{
-					IsNext:        false,
-					LeaderboardId: leaderboardId,
-					ExpiryTime:    expiryTime,
-					Score:         dbScore,
-					Subscore:      dbSubscore,
-					OwnerId:       dbOwnerID,
-					Rank:          rank,
-				}
-			}
-		}
-		_ = rows.Close()
-
-		if incomingCursor != nil && !incomingCursor.IsNext {
-			// If this was a previous page listing, flip the results to their normal order and swap the cursors.
-			if nextCursor != nil && prevCursor != nil {
-				nextCursor, nextCursor.IsNext, nextCursor.Rank, prevCursor, prevCursor.IsNext, prevCursor.Rank = prevCursor, prevCursor.IsNext, prevCursor.Rank, nextCursor, nextCursor.IsNext, nextCursor.Rank
-			} else if nextCursor != nil {
-				nextCursor, prevCursor = nil, nextCursor
-				prevCursor.IsNext = !prevCursor.IsNext
-			} else if prevCursor != nil {
-				nextCursor, prevCursor = prevCursor, nil
-				nextCursor.IsNext = !nextCursor.IsNext
-			}
-
-			for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
-				records[i], records[i].Rank, records[j], records[j].Rank = records[j], records[j].Rank, records[i], records[i].Rank
-			}
-		}
-
-		if nextCursor != nil {
-			nextCursorStr, err = marshalLeaderboardRecordsListCursor(nextCursor)
-			if err != nil {
-				logger.Error("Error creating leaderboard records list next cursor", zap.Error(err))
-				return nil, err
-			}
-		}
-		if prevCursor != nil {
-			prevCursorStr, err = marshalLeaderboardRecordsListCursor(prevCursor)
-			if err != nil {
-				logger.Error("Error creating leaderboard records list previous cursor", zap.Error(err))
-				return nil, err
-			}
-		}
 	}
 
 	if len(ownerIds) != 0 {
 		params := []any{leaderboardId, time.Unix(expiryTime, 0).UTC(), ownerIds}
 		query := `SELECT owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time
 FROM leaderboard_record
-WHERE leaderboard_id = $1 AND expiry_time = $2 AND owner_id = ANY($3)`
+WHERE leaderboard_id = $1 AND expiry_time = $2 AND owner_id = ANY($3)` + leaderboardRecordActiveFilter
 
 		rows, err := db.QueryContext(ctx, query, params...)
 		if err != nil {
@@ -379,6 +246,200 @@ WHERE leaderboard_id = $1 AND expiry_time = $2 AND owner_id = ANY($3)`
 	}, nil
 }
 
+// listLeaderboardRecordsPage reads a page of records in leaderboard sort order, optionally restricted to a set of owners.
+func listLeaderboardRecordsPage(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardId string, sortOrder int, expiryTime int64, limitNumber int, cursor string, ownerFilter []string) ([]*api.LeaderboardRecord, string, string, error) {
+	incomingCursor, err := unmarshalLeaderboardRecordsListCursor(leaderboardId, expiryTime, cursor)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	query := "SELECT owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time FROM leaderboard_record WHERE leaderboard_id = $1 AND expiry_time = $2" + leaderboardRecordActiveFilter
+	if len(ownerFilter) != 0 {
+		// Owner IDs follow the limit and any cursor parameters.
+		if incomingCursor == nil {
+			query += " AND owner_id = ANY($4)"
+		} else {
+			query += " AND owner_id = ANY($7)"
+		}
+	}
+	if incomingCursor == nil {
+		if sortOrder == LeaderboardSortOrderAscending {
+			query += " ORDER BY score ASC, subscore ASC, owner_id ASC"
+		} else {
+			query += " ORDER BY score DESC, subscore DESC, owner_id DESC"
+		}
+	} else {
+		if (sortOrder == LeaderboardSortOrderAscending && incomingCursor.IsNext) || (sortOrder == LeaderboardSortOrderDescending && !incomingCursor.IsNext) {
+			// Ascending and next page == descending and previous page.
+			query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) > ($1, $2, $4, $5, $6) ORDER BY score ASC, subscore ASC, owner_id ASC"
+		} else {
+			// Ascending and previous page == descending and next page.
+			query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) < ($1, $2, $4, $5, $6) ORDER BY score DESC, subscore DESC, owner_id DESC"
+		}
+	}
+	query += " LIMIT $3"
+	params := make([]interface{}, 0, 7)
+	params = append(params, leaderboardId, time.Unix(expiryTime, 0).UTC(), limitNumber+1)
+	if incomingCursor != nil {
+		params = append(params, incomingCursor.Score, incomingCursor.Subscore, incomingCursor.OwnerId)
+	}
+	if len(ownerFilter) != 0 {
+		params = append(params, ownerFilter)
+	}
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error listing leaderboard records", zap.Error(err))
+		return nil, "", "", err
+	}
+
+	rank := int64(0)
+	if incomingCursor != nil {
+		rank = incomingCursor.Rank
+	}
+	records := make([]*api.LeaderboardRecord, 0, limitNumber)
+	var nextCursor, prevCursor *leaderboardRecordListCursor
+
+	var dbOwnerID string
+	var dbUsername sql.NullString
+	var dbScore int64
+	var dbSubscore int64
+	var dbNumScore int32
+	var dbMaxNumScore int32
+	var dbMetadata string
+	var dbCreateTime pgtype.Timestamptz
+	var dbUpdateTime pgtype.Timestamptz
+	for rows.Next() {
+		if len(records) >= limitNumber {
+			nextCursor = &leaderboardRecordListCursor{
+				IsNext:        true,
+				LeaderboardId: leaderboardId,
+				ExpiryTime:    expiryTime,
+				Score:         dbScore,
+				Subscore:      dbSubscore,
+				OwnerId:       dbOwnerID,
+				Rank:          rank,
+			}
+			break
+		}
+
+		err = rows.Scan(&dbOwnerID, &dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime)
+		if err != nil {
+			_ = rows.Close()
+			logger.Error("Error parsing listed leaderboard records", zap.Error(err))
+			return nil, "", "", err
+		}
+
+		if incomingCursor != nil && !incomingCursor.IsNext {
+			rank--
+		} else {
+			rank++
+		}
+
+		record := &api.LeaderboardRecord{
+			LeaderboardId: leaderboardId,
+			OwnerId:       dbOwnerID,
+			Score:         dbScore,
+			Subscore:      dbSubscore,
+			NumScore:      dbNumScore,
+			MaxNumScore:   uint32(dbMaxNumScore),
+			Metadata:      dbMetadata,
+			CreateTime:    &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+			UpdateTime:    &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
+			Rank:          rank,
+		}
+		if dbUsername.Valid {
+			record.Username = &wrapperspb.StringValue{Value: dbUsername.String}
+		}
+		if expiryTime != 0 {
+			record.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
+		}
+
+		records = append(records, record)
+
+		// There can only be a previous page if this is a paginated listing.
+		if incomingCursor != nil && prevCursor == nil {
+			prevCursor = &leaderboardRecordListCursor{
+				IsNext:        false,
+				LeaderboardId: leaderboardId,
+				ExpiryTime:    expiryTime,
+				Score:         dbScore,
+				Subscore:      dbSubscore,
+				OwnerId:       dbOwnerID,
+				Rank:          rank,
+			}
+		}
+	}
+	_ = rows.Close()
+
+	if incomingCursor != nil && !incomingCursor.IsNext {
+		// If this was a previous page listing, flip the results to their normal order and swap the cursors.
+		if nextCursor != nil && prevCursor != nil {
+			nextCursor, nextCursor.IsNext, nextCursor.Rank, prevCursor, prevCursor.IsNext, prevCursor.Rank = prevCursor, prevCursor.IsNext, prevCursor.Rank, nextCursor, nextCursor.IsNext, nextCursor.Rank
+		} else if nextCursor != nil {
+			nextCursor, prevCursor = nil, nextCursor
+			prevCursor.IsNext = !prevCursor.IsNext
+		} else if prevCursor != nil {
+			nextCursor, prevCursor = prevCursor, nil
+			nextCursor.IsNext = !nextCursor.IsNext
+		}
+
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[i].Rank, records[j], records[j].Rank = records[j], records[j].Rank, records[i], records[i].Rank
+		}
+	}
+
+	var nextCursorStr, prevCursorStr string
+	if nextCursor != nil {
+		nextCursorStr, err = marshalLeaderboardRecordsListCursor(nextCursor)
+		if err != nil {
+			logger.Error("Error creating leaderboard records list next cursor", zap.Error(err))
+			return nil, "", "", err
+		}
+	}
+	if prevCursor != nil {
+		prevCursorStr, err = marshalLeaderboardRecordsListCursor(prevCursor)
+		if err != nil {
+			logger.Error("Error creating leaderboard records list previous cursor", zap.Error(err))
+			return nil, "", "", err
+		}
+	}
+
+	return records, nextCursorStr, prevCursorStr, nil
+}
+
+// LeaderboardRecordsListOwners pages through the records of the given owners only, in leaderboard sort order. Each
+// record carries its rank within the whole leaderboard rather than its position among the given owners.
+func LeaderboardRecordsListOwners(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardId string, ownerIds []string, limit int, cursor string, overrideExpiry int64) (*api.LeaderboardRecordList, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	expiryTime, recordsPossible := calculateExpiryOverride(overrideExpiry, leaderboard)
+	if !recordsPossible || len(ownerIds) == 0 {
+		return &api.LeaderboardRecordList{}, nil
+	}
+
+	records, nextCursorStr, prevCursorStr, err := listLeaderboardRecordsPage(ctx, logger, db, leaderboardId, leaderboard.SortOrder, expiryTime, limit, cursor, ownerIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Positional ranks within the owner subset are meaningless, replace them with leaderboard ranks where available.
+	for _, record := range records {
+		record.Rank = 0
+	}
+	rankCount := rankCache.Fill(leaderboardId, expiryTime, records, leaderboard.EnableRanks)
+
+	return &api.LeaderboardRecordList{
+		Records:    records,
+		NextCursor: nextCursorStr,
+		PrevCursor: prevCursorStr,
+		RankCount:  rankCount,
+	}, nil
+}
+
 func marshalLeaderboardRecordsListCursor(cursor *leaderboardRecordListCursor) (string, error) {
 	cursorBuf := new(bytes.Buffer)
 	if err := gob.NewEncoder(cursorBuf).Encode(cursor); err != nil {
@@ -413,13 +474,46 @@ func unmarshalLeaderboardRecordsListCursor(leaderboardId string, expiryTime int6
 }
 
 func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string, overrideOperator api.Operator) (*api.LeaderboardRecord, error) {
+	return LeaderboardRecordWriteWithExpiry(ctx, logger, db, leaderboardCache, rankCache, caller, leaderboardId, ownerID, username, score, subscore, metadata, overrideOperator, 0)
+}
+
+// LeaderboardRecordExpiryClear passed as the record expiry removes any expiry previously set on the record.
+const LeaderboardRecordExpiryClear = int64(-1)
+
+// LeaderboardRecordWriteWithExpiry writes a record that, if recordExpiry is a positive unix time, is excluded from
+// listings and haystacks once that time passes. The record is not deleted; the next write to it after expiry
+// replaces its score, subscore and attempt count as if it were new. A write with an expiry replaces the record's
+// expiry, a write with LeaderboardRecordExpiryClear removes it, and any other write, including one that does not
+// change the record (for example a worse score on a "best" leaderboard), leaves the existing expiry in place.
+//
+// Expired records are dropped from the rank cache by the leaderboard scheduler, which checks once a minute, so ranks
+// may still count a record for up to a minute after it expires.
+//
+// The per-record expiry is independent of the leaderboard's reset schedule. If the leaderboard resets first the
+// record is expired by the reset as usual, so a per-record expiry later than the next reset has no effect.
+func LeaderboardRecordWriteWithExpiry(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string, overrideOperator api.Operator, recordExpiry int64) (*api.LeaderboardRecord, error) {
+	if recordExpiry > 0 && recordExpiry <= time.Now().UTC().Unix() {
+		return nil, ErrLeaderboardRecordExpiryInvalid
+	}
+	record, _, err := leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, caller, leaderboardId, ownerID, username, score, subscore, metadata, overrideOperator, false, recordExpiry)
+	return record, err
+}
+
+// LeaderboardRecordWriteIfBetter stores the given score only if it improves on the owner's existing record under the
+// leaderboard's sort order, comparing score first and then subscore. The returned flag reports whether the record was
+// written; when it was not, the existing record is returned unchanged.
+func LeaderboardRecordWriteIfBetter(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string) (*api.LeaderboardRecord, bool, error) {
+	return leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, caller, leaderboardId, ownerID, username, score, subscore, metadata, api.Operator_NO_OVERRIDE, true, 0)
+}
+
+func leaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string, overrideOperator api.Operator, onlyIfBetter bool, recordExpiry int64) (*api.LeaderboardRecord, bool, error) {
 	leaderboard := leaderboardCache.Get(leaderboardId)
 	if leaderboard == nil {
-		return nil, ErrLeaderboardNotFound
+		return nil, false, ErrLeaderboardNotFound
 	}
 
 	if leaderboard.Authoritative && caller != uuid.Nil {
-		return nil, ErrLeaderboardAuthoritative
+		return nil, false, ErrLeaderboardAuthoritative
 	}
 
 	expiryTime := int64(0)
@@ -439,11 +533,13 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		case api.Operator_DECREMENT:
 			operator = LeaderboardOperatorDecrement
 		default:
-			return nil, ErrInvalidOperator
+			return nil, false, ErrInvalidOperator
 		}
 	}
 
-	var opSQL string
+	// The new score and subscore of an existing record that has not passed its own expiry.
+	var scoreSQL string
+	var subscoreSQL string
 	var filterSQL string
 	var scoreDelta int64
 	var subscoreDelta int64
@@ -451,21 +547,24 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	var subscoreAbs int64
 	switch operator {
 	case LeaderboardOperatorIncrement:
-		opSQL = "score = leaderboard_record.score + $8, subscore = leaderboard_record.subscore + $9"
-		filterSQL = " WHERE $8 <> 0 OR $9 <> 0"
+		scoreSQL = "leaderboard_record.score + $9"
+		subscoreSQL = "leaderboard_record.subscore + $10"
+		filterSQL = " WHERE $9 <> 0 OR $10 <> 0"
 		scoreDelta = score
 		subscoreDelta = subscore
 		scoreAbs = score
 		subscoreAbs = subscore
 	case LeaderboardOperatorDecrement:
-		opSQL = "score = GREATEST(leaderboard_record.score - $8, 0), subscore = GREATEST(leaderboard_record.subscore - $9, 0)"
-		filterSQL = " WHERE $8 <> 0 OR $9 <> 0"
+		scoreSQL = "GREATEST(leaderboard_record.score - $9, 0)"
+		subscoreSQL = "GREATEST(leaderboard_record.subscore - $10, 0)"
+		filterSQL = " WHERE $9 <> 0 OR $10 <> 0"
 		scoreDelta = score
 		subscoreDelta = subscore
 		scoreAbs = 0
 		subscoreAbs = 0
 	case LeaderboardOperatorSet:
-		opSQL = "score = $4, subscore = $5"
+		scoreSQL = "$4"
+		subscoreSQL = "$5"
 		filterSQL = " WHERE leaderboard_record.score <> $4 OR leaderboard_record.subscore <> $5"
 		scoreDelta = score
 		subscoreDelta = subscore
@@ -476,11 +575,13 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	default:
 		if leaderboard.SortOrder == LeaderboardSortOrderAscending {
 			// Lower score is better.
-			opSQL = "score = LEAST(leaderboard_record.score, $4), subscore = LEAST(leaderboard_record.subscore, $5)"
+			scoreSQL = "LEAST(leaderboard_record.score, $4)"
+			subscoreSQL = "LEAST(leaderboard_record.subscore, $5)"
 			filterSQL = " WHERE leaderboard_record.score > $4 OR leaderboard_record.subscore > $5"
 		} else {
 			// Higher score is better.
-			opSQL = "score = GREATEST(leaderboard_record.score, $4), subscore = GREATEST(leaderboard_record.subscore, $5)"
+			scoreSQL = "GREATEST(leaderboard_record.score, $4)"
+			subscoreSQL = "GREATEST(leaderboard_record.subscore, $5)"
 			filterSQL = " WHERE leaderboard_record.score < $4 OR leaderboard_record.subscore < $5"
 		}
 		scoreDelta = score
@@ -489,13 +590,51 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		subscoreAbs = subscore
 	}
 
-	query := `INSERT INTO leaderboard_record (leaderboard_id, owner_id, username, score, subscore, metadata, expiry_time)
-            VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::JSONB), $7)
+	if onlyIfBetter {
+		// Replace the existing record only when the new score and subscore pair ranks strictly higher.
+		scoreSQL = "$4"
+		subscoreSQL = "$5"
+		if leaderboard.SortOrder == LeaderboardSortOrderAscending {
+			filterSQL = " WHERE (leaderboard_record.score, leaderboard_record.subscore) > ($4, $5)"
+		} else {
+			filterSQL = " WHERE (leaderboard_record.score, leaderboard_record.subscore) < ($4, $5)"
+		}
+		scoreAbs = score
+		subscoreAbs = subscore
+	}
+
+	// Leaderboards created with a score attempt cap only accept writes while the owner is still under it.
+	maxNumScore := leaderboardDefaultMaxNumScore
+	if leaderboard.MaxNumScore > 0 && leaderboard.MaxNumScore != leaderboardDefaultMaxNumScore {
+		maxNumScore = leaderboard.MaxNumScore
+		filterSQL = " WHERE (" + strings.TrimPrefix(filterSQL, " WHERE ") + ") AND leaderboard_record.num_score < leaderboard_record.max_num_score"
+	}
+
+	// A record past its own expiry is replaced outright, whatever the operator, score comparison or attempt cap.
+	const expiredSQL = "leaderboard_record.record_expiry_time <= now()"
+	filterSQL = " WHERE " + expiredSQL + " OR (" + strings.TrimPrefix(filterSQL, " WHERE ") + ")"
+
+	// An existing expiry is kept unless the write sets a new one or explicitly clears it. A record replaced after its
+	// expiry takes only the expiry given with this write.
+	recordExpirySQL := "CASE WHEN " + expiredSQL + " THEN $8 ELSE COALESCE($8, leaderboard_record.record_expiry_time) END"
+	if recordExpiry == LeaderboardRecordExpiryClear {
+		recordExpirySQL = "NULL"
+	}
+
+	query := `INSERT INTO leaderboard_record (leaderboard_id, owner_id, username, score, subscore, metadata, expiry_time, max_num_score, record_expiry_time)
+            VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::JSONB), $7, ` + strconv.Itoa(maxNumScore) + `, $8)
             ON CONFLICT (owner_id, leaderboard_id, expiry_time)
-            DO UPDATE SET ` + opSQL + `, num_score = leaderboard_record.num_score + 1, metadata = COALESCE($6, leaderboard_record.metadata), username = COALESCE($3, leaderboard_record.username), update_time = now()` + filterSQL + `
+            DO UPDATE SET
+                score = CASE WHEN ` + expiredSQL + ` THEN $4 ELSE ` + scoreSQL + ` END,
+                subscore = CASE WHEN ` + expiredSQL + ` THEN $5 ELSE ` + subscoreSQL + ` END,
+                num_score = CASE WHEN ` + expiredSQL + ` THEN 1 ELSE leaderboard_record.num_score + 1 END,
+                metadata = COALESCE($6, leaderboard_record.metadata),
+                username = COALESCE($3, leaderboard_record.username),
+                record_expiry_time = ` + recordExpirySQL + `,
+                update_time = now()` + filterSQL + `
             RETURNING username, score, subscore, num_score, max_num_score, metadata, create_time, update_time`
 
-	params := make([]interface{}, 0, 9)
+	params := make([]interface{}, 0, 10)
 	params = append(params, leaderboardId, ownerID)
 	if username == "" {
 		params = append(params, nil)
@@ -509,7 +648,12 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		params = append(params, metadata)
 	}
 	params = append(params, time.Unix(expiryTime, 0).UTC())
-	if operator == LeaderboardOperatorIncrement || operator == LeaderboardOperatorDecrement {
+	if recordExpiry <= 0 {
+		params = append(params, nil)
+	} else {
+		params = append(params, time.Unix(recordExpiry, 0).UTC())
+	}
+	if !onlyIfBetter && (operator == LeaderboardOperatorIncrement || operator == LeaderboardOperatorDecrement) {
 		params = append(params, scoreDelta, subscoreDelta)
 	}
 
@@ -531,7 +675,7 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		var pgErr *pgconn.PgError
 		if err != sql.ErrNoRows && !(errors.As(err, &pgErr) && pgErr.Code == dbErrorUniqueViolation && strings.Contains(pgErr.Message, "leaderboard_record_pkey")) {
 			logger.Error("Error writing leaderboard record", zap.Error(err))
-			return nil, err
+			return nil, false, err
 		}
 
 		// If no rows were returned then both of these criteria must have been met:
@@ -543,9 +687,13 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		err = db.QueryRowContext(ctx, query, leaderboardId, ownerID, time.Unix(expiryTime, 0).UTC()).Scan(&dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime)
 		if err != nil {
 			logger.Error("Error after writing leaderboard record", zap.Error(err))
-			return nil, err
+			return nil, false, err
 		}
 		unchanged = true
+
+		if maxNumScore != leaderboardDefaultMaxNumScore && dbNumScore >= dbMaxNumScore {
+			return nil, false, ErrLeaderboardMaxNumScoreReached
+		}
 	}
 
 	var rank int64
@@ -575,7 +723,7 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		record.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
 	}
 
-	return record, nil
+	return record, !unchanged, nil
 }
 
 func LeaderboardRecordDelete(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID string) error {
@@ -715,57 +863,20 @@ func calculatePrevReset(currentTime time.Time, startTime int64, resetSchedule *c
 
 func getLeaderboardRecordsHaystack(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, ownerID uuid.UUID, limit int, leaderboardId, cursor string, sortOrder int, expiryTime time.Time) (*api.LeaderboardRecordList, error) {
 	if cursor == "" {
-		var dbLeaderboardID string
-		var dbOwnerID string
-		var dbUsername sql.NullString
-		var dbScore int64
-		var dbSubscore int64
-		var dbNumScore int32
-		var dbMaxNumScore int32
-		var dbMetadata string
-		var dbCreateTime pgtype.Timestamptz
-		var dbUpdateTime pgtype.Timestamptz
-		var dbExpiryTime pgtype.Timestamptz
-
-		findQuery := `SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time
-		FROM leaderboard_record
-		WHERE owner_id = $1
-		AND leaderboard_id = $2
-		AND expiry_time = $3`
-		logger.Debug("Leaderboard haystack lookup", zap.String("query", findQuery))
-		err := db.QueryRowContext(ctx, findQuery, ownerID, leaderboardId, expiryTime).Scan(&dbLeaderboardID, &dbOwnerID, &dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime, &dbExpiryTime)
-		if err == sql.ErrNoRows {
+		ownerRecord, err := getLeaderboardOwnerRecord(ctx, logger, db, leaderboardId, ownerID, expiryTime)
+		if err != nil {
+			return nil, err
+		}
+		if ownerRecord == nil {
 			return &api.LeaderboardRecordList{
 				Records: []*api.LeaderboardRecord{},
 			}, nil
-		} else if err != nil {
-			logger.Error("Could not load owner record in leaderboard records list haystack", zap.Error(err), zap.String("leaderboard_id", leaderboardId), zap.String("owner_id", ownerID.String()))
-			return nil, err
-		}
-
-		ownerRecord := &api.LeaderboardRecord{
-			// Record populated later.
-			LeaderboardId: dbLeaderboardID,
-			OwnerId:       dbOwnerID,
-			Score:         dbScore,
-			Subscore:      dbSubscore,
-			NumScore:      dbNumScore,
-			MaxNumScore:   uint32(dbMaxNumScore),
-			Metadata:      dbMetadata,
-			CreateTime:    &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
-			UpdateTime:    &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
-		}
-		if dbUsername.Valid {
-			ownerRecord.Username = &wrapperspb.StringValue{Value: dbUsername.String}
-		}
-		if expiryTime := dbExpiryTime.Time.Unix(); expiryTime != 0 {
-			ownerRecord.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
 		}
 
 		query := `SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time
 	FROM leaderboard_record
 	WHERE leaderboard_id = $1
-	AND expiry_time = $2`
+	AND expiry_time = $2` + leaderboardRecordActiveFilter
 
 		// First half.
 		params := []interface{}{leaderboardId, expiryTime, ownerRecord.Score, ownerRecord.Subscore, ownerID}
@@ -911,6 +1022,180 @@ func getLeaderboardRecordsHaystack(ctx context.Context, logger *zap.Logger, db *
 	}
 }
 
+// LeaderboardRecordsHaystackExpand returns the owner's record together with up to expandUp records ranked immediately
+// above it and up to expandDown records ranked immediately below it. Fewer records are returned near the top or bottom
+// of the leaderboard, so at most expandUp+1+expandDown records are returned.
+func LeaderboardRecordsHaystackExpand(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardId string, ownerID uuid.UUID, expandUp, expandDown int, overrideExpiry int64) (*api.LeaderboardRecordList, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	expiryTime, recordsPossible := calculateExpiryOverride(overrideExpiry, leaderboard)
+	if !recordsPossible {
+		// If the expiry time is in the past, we won't have any records to return.
+		return &api.LeaderboardRecordList{Records: []*api.LeaderboardRecord{}}, nil
+	}
+
+	return getLeaderboardRecordsHaystackExpand(ctx, logger, db, rankCache, leaderboard, ownerID, expandUp, expandDown, time.Unix(expiryTime, 0).UTC())
+}
+
+func getLeaderboardRecordsHaystackExpand(ctx context.Context, logger *zap.Logger, db *sql.DB, rankCache LeaderboardRankCache, leaderboard *Leaderboard, ownerID uuid.UUID, expandUp, expandDown int, expiryTime time.Time) (*api.LeaderboardRecordList, error) {
+	ownerRecord, err := getLeaderboardOwnerRecord(ctx, logger, db, leaderboard.Id, ownerID, expiryTime)
+	if err != nil {
+		return nil, err
+	}
+	if ownerRecord == nil {
+		return &api.LeaderboardRecordList{Records: []*api.LeaderboardRecord{}}, nil
+	}
+
+	aboveRecords, moreAbove, err := getLeaderboardRecordsNeighbours(ctx, logger, db, leaderboard.Id, leaderboard.SortOrder, expiryTime, ownerRecord, true, expandUp)
+	if err != nil {
+		return nil, err
+	}
+	belowRecords, moreBelow, err := getLeaderboardRecordsNeighbours(ctx, logger, db, leaderboard.Id, leaderboard.SortOrder, expiryTime, ownerRecord, false, expandDown)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*api.LeaderboardRecord, 0, len(aboveRecords)+1+len(belowRecords))
+	records = append(records, aboveRecords...)
+	records = append(records, ownerRecord)
+	records = append(records, belowRecords...)
+
+	rankCount := rankCache.Fill(leaderboard.Id, expiryTime.Unix(), records, leaderboard.EnableRanks)
+
+	var prevCursorStr string
+	if moreAbove {
+		record := records[0]
+		prevCursorStr, err = marshalLeaderboardRecordsListCursor(&leaderboardRecordListCursor{
+			IsNext:        false,
+			LeaderboardId: record.LeaderboardId,
+			ExpiryTime:    expiryTime.Unix(),
+			Score:         record.Score,
+			Subscore:      record.Subscore,
+			OwnerId:       record.OwnerId,
+			Rank:          record.Rank,
+		})
+		if err != nil {
+			logger.Error("Error creating leaderboard records list previous cursor", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	var nextCursorStr string
+	if moreBelow {
+		record := records[len(records)-1]
+		nextCursorStr, err = marshalLeaderboardRecordsListCursor(&leaderboardRecordListCursor{
+			IsNext:        true,
+			LeaderboardId: record.LeaderboardId,
+			ExpiryTime:    expiryTime.Unix(),
+			Score:         record.Score,
+			Subscore:      record.Subscore,
+			OwnerId:       record.OwnerId,
+			Rank:          record.Rank,
+		})
+		if err != nil {
+			logger.Error("Error creating leaderboard records list next cursor", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return &api.LeaderboardRecordList{Records: records, PrevCursor: prevCursorStr, NextCursor: nextCursorStr, RankCount: rankCount}, nil
+}
+
+// getLeaderboardRecordsNeighbours reads up to limit records ranked immediately above or below the owner record, in
+// leaderboard order, and reports whether further records exist beyond them.
+func getLeaderboardRecordsNeighbours(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardId string, sortOrder int, expiryTime time.Time, ownerRecord *api.LeaderboardRecord, above bool, limit int) ([]*api.LeaderboardRecord, bool, error) {
+	query := `SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time
+FROM leaderboard_record
+WHERE leaderboard_id = $1
+AND expiry_time = $2` + leaderboardRecordActiveFilter
+	if (sortOrder == LeaderboardSortOrderAscending) != above {
+		query += " AND (score, subscore, owner_id) > ($3, $4, $5) ORDER BY score ASC, subscore ASC, owner_id ASC"
+	} else {
+		query += " AND (score, subscore, owner_id) < ($3, $4, $5) ORDER BY score DESC, subscore DESC, owner_id DESC"
+	}
+	query += " LIMIT $6"
+
+	rows, err := db.QueryContext(ctx, query, leaderboardId, expiryTime, ownerRecord.Score, ownerRecord.Subscore, ownerRecord.OwnerId, limit+1)
+	if err != nil {
+		logger.Error("Could not execute leaderboard records list query", zap.Error(err))
+		return nil, false, err
+	}
+	// rows.Close() called in parseLeaderboardRecords
+
+	records, err := parseLeaderboardRecords(logger, rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	more := false
+	if len(records) > limit {
+		more = true
+		records = records[:limit]
+	}
+
+	if above {
+		// Records above the owner were read walking up the leaderboard, restore leaderboard order.
+		for left, right := 0, len(records)-1; left < right; left, right = left+1, right-1 {
+			records[left], records[right] = records[right], records[left]
+		}
+	}
+
+	return records, more, nil
+}
+
+// getLeaderboardOwnerRecord reads the owner's record for the given leaderboard period, or nil if there is none.
+func getLeaderboardOwnerRecord(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardId string, ownerID uuid.UUID, expiryTime time.Time) (*api.LeaderboardRecord, error) {
+	var dbLeaderboardID string
+	var dbOwnerID string
+	var dbUsername sql.NullString
+	var dbScore int64
+	var dbSubscore int64
+	var dbNumScore int32
+	var dbMaxNumScore int32
+	var dbMetadata string
+	var dbCreateTime pgtype.Timestamptz
+	var dbUpdateTime pgtype.Timestamptz
+	var dbExpiryTime pgtype.Timestamptz
+
+	findQuery := `SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time
+	FROM leaderboard_record
+	WHERE owner_id = $1
+	AND leaderboard_id = $2
+	AND expiry_time = $3` + leaderboardRecordActiveFilter
+	logger.Debug("Leaderboard haystack lookup", zap.String("query", findQuery))
+	err := db.QueryRowContext(ctx, findQuery, ownerID, leaderboardId, expiryTime).Scan(&dbLeaderboardID, &dbOwnerID, &dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime, &dbExpiryTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		logger.Error("Could not load owner record in leaderboard records list haystack", zap.Error(err), zap.String("leaderboard_id", leaderboardId), zap.String("owner_id", ownerID.String()))
+		return nil, err
+	}
+
+	ownerRecord := &api.LeaderboardRecord{
+		// Record populated later.
+		LeaderboardId: dbLeaderboardID,
+		OwnerId:       dbOwnerID,
+		Score:         dbScore,
+		Subscore:      dbSubscore,
+		NumScore:      dbNumScore,
+		MaxNumScore:   uint32(dbMaxNumScore),
+		Metadata:      dbMetadata,
+		CreateTime:    &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+		UpdateTime:    &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
+	}
+	if dbUsername.Valid {
+		ownerRecord.Username = &wrapperspb.StringValue{Value: dbUsername.String}
+	}
+	if expiryTime := dbExpiryTime.Time.Unix(); expiryTime != 0 {
+		ownerRecord.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
+	}
+
+	return ownerRecord, nil
+}
+
 func parseLeaderboardRecords(logger *zap.Logger, rows *sql.Rows) ([]*api.LeaderboardRecord, error) {
 	defer rows.Close()
 	records := make([]*api.LeaderboardRecord, 0, 10)
@@ -986,7 +1271,7 @@ func disableLeaderboardRanks(ctx context.Context, logger *zap.Logger, db *sql.DB
 		return errors.New("failed to disable tournament ranks")
 	}
 
-	leaderboardCache.Insert(l.Id, l.Authoritative, l.SortOrder, l.Operator, l.ResetScheduleStr, l.Metadata, l.CreateTime, false)
+	leaderboardCache.Insert(l.Id, l.Authoritative, l.SortOrder, l.Operator, l.ResetScheduleStr, l.Metadata, l.CreateTime, false, l.MaxNumScore)
 
 	expiryTime := int64(0)
 	if l.ResetSchedule != nil {
@@ -997,3 +1282,20 @@ func disableLeaderboardRanks(ctx context.Context, logger *zap.Logger, db *sql.DB
 
 	return nil
 }
+
+// LeaderboardRanksRecalculate rebuilds the cached ranks for the current period of a leaderboard from its persisted
+// records, returning the number of records with a cached rank.
+func LeaderboardRanksRecalculate(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardID string) (int, error) {
+	leaderboard := leaderboardCache.Get(leaderboardID)
+	if leaderboard == nil {
+		return 0, ErrLeaderboardNotFound
+	}
+
+	count, err := rankCache.Rebuild(ctx, db, leaderboard)
+	if err != nil {
+		logger.Error("Error rebuilding leaderboard rank cache", zap.String("leaderboard_id", leaderboardID), zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,393 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestLeaderboardRecordWriteIfBetter(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	for _, tc := range []struct {
+		name      string
+		sortOrder int
+		better    int64
+		worse     int64
+	}{
+		{name: "ascending", sortOrder: LeaderboardSortOrderAscending, better: 5, worse: 20},
+		{name: "descending", sortOrder: LeaderboardSortOrderDescending, better: 20, worse: 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			leaderboardID := uuid.Must(uuid.NewV4()).String()
+			if _, _, err := leaderboardCache.Create(ctx, leaderboardID, true, tc.sortOrder, LeaderboardOperatorSet, "", "", true); err != nil {
+				t.Fatalf("error creating leaderboard: %v", err.Error())
+			}
+
+			ownerID := uuid.Must(uuid.NewV4())
+			InsertUser(t, db, ownerID)
+
+			record, written, err := LeaderboardRecordWriteIfBetter(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", 10, 0, "")
+			if err != nil {
+				t.Fatalf("error writing first record: %v", err.Error())
+			}
+			assert.True(t, written, "first record should be written")
+			assert.EqualValues(t, 10, record.Score)
+
+			record, written, err = LeaderboardRecordWriteIfBetter(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", tc.worse, 0, "")
+			if err != nil {
+				t.Fatalf("error writing worse record: %v", err.Error())
+			}
+			assert.False(t, written, "worse record should not be written")
+			assert.EqualValues(t, 10, record.Score)
+
+			record, written, err = LeaderboardRecordWriteIfBetter(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", 10, 0, "")
+			if err != nil {
+				t.Fatalf("error writing equal record: %v", err.Error())
+			}
+			assert.False(t, written, "equal record should not be written")
+			assert.EqualValues(t, 10, record.Score)
+
+			record, written, err = LeaderboardRecordWriteIfBetter(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", tc.better, 0, "")
+			if err != nil {
+				t.Fatalf("error writing better record: %v", err.Error())
+			}
+			assert.True(t, written, "better record should be written")
+			assert.EqualValues(t, tc.better, record.Score)
+		})
+	}
+}
+
+func TestLeaderboardRecordsHaystackExpand(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	leaderboardID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.Create(ctx, leaderboardID, true, LeaderboardSortOrderDescending, LeaderboardOperatorSet, "", "", true); err != nil {
+		t.Fatalf("error creating leaderboard: %v", err.Error())
+	}
+
+	var ownerID uuid.UUID
+	for _, score := range []int64{10, 20, 30, 40, 50} {
+		userID := uuid.Must(uuid.NewV4())
+		InsertUser(t, db, userID)
+		if _, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, userID.String(), "", score, 0, "", 0); err != nil {
+			t.Fatalf("error writing record: %v", err.Error())
+		}
+		if score == 30 {
+			ownerID = userID
+		}
+	}
+
+	list, err := LeaderboardRecordsHaystackExpand(ctx, logger, db, leaderboardCache, rankCache, leaderboardID, ownerID, 1, 5, 0)
+	if err != nil {
+		t.Fatalf("error listing haystack: %v", err.Error())
+	}
+
+	scores := make([]int64, 0, len(list.Records))
+	for _, record := range list.Records {
+		scores = append(scores, record.Score)
+	}
+	assert.Equal(t, []int64{40, 30, 20, 10}, scores, "records should be clamped at the bottom of the leaderboard")
+	assert.NotEmpty(t, list.PrevCursor, "a record above the window should produce a previous cursor")
+	assert.Empty(t, list.NextCursor, "the bottom of the leaderboard should not produce a next cursor")
+}
+
+func TestValidateLeaderboardCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		sortOrder     int
+		operator      int
+		resetSchedule string
+		maxNumScore   int
+		wantErr       bool
+	}{
+		{"valid best", LeaderboardSortOrderDescending, LeaderboardOperatorBest, "0 0 * * 1", 0, false},
+		{"valid ascending decr", LeaderboardSortOrderAscending, LeaderboardOperatorDecrement, "", 3, false},
+		{"valid descending incr", LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", 0, false},
+		{"valid ascending set", LeaderboardSortOrderAscending, LeaderboardOperatorSet, "", 0, false},
+		{"unknown sort order", 5, LeaderboardOperatorBest, "", 0, true},
+		{"unknown operator", LeaderboardSortOrderDescending, 9, "", 0, true},
+		{"valid ascending incr", LeaderboardSortOrderAscending, LeaderboardOperatorIncrement, "", 0, false},
+		{"valid descending decr", LeaderboardSortOrderDescending, LeaderboardOperatorDecrement, "", 0, false},
+		{"invalid cron", LeaderboardSortOrderDescending, LeaderboardOperatorBest, "not a cron", 0, true},
+		{"cron that never fires", LeaderboardSortOrderDescending, LeaderboardOperatorBest, "0 0 30 2 *", 0, true},
+		{"negative max num score", LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLeaderboardCreate(tt.sortOrder, tt.operator, tt.resetSchedule, tt.maxNumScore)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLeaderboardRecordWriteMaxNumScore(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	leaderboardID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.CreateWithMaxNumScore(ctx, leaderboardID, true, LeaderboardSortOrderDescending, LeaderboardOperatorSet, "", "", true, 2); err != nil {
+		t.Fatalf("error creating leaderboard: %v", err.Error())
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, ownerID)
+
+	for i := int64(1); i <= 2; i++ {
+		record, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", i, 0, "", api.Operator_NO_OVERRIDE)
+		if err != nil {
+			t.Fatalf("error writing record %d: %v", i, err.Error())
+		}
+		assert.EqualValues(t, 2, record.MaxNumScore)
+	}
+
+	_, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", 3, 0, "", api.Operator_NO_OVERRIDE)
+	assert.ErrorIs(t, err, ErrLeaderboardMaxNumScoreReached)
+}
+
+func TestLeaderboardRecordWriteWithExpiry(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	leaderboardID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.Create(ctx, leaderboardID, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", true); err != nil {
+		t.Fatalf("error creating leaderboard: %v", err.Error())
+	}
+
+	expiringID := uuid.Must(uuid.NewV4())
+	permanentID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, expiringID)
+	InsertUser(t, db, permanentID)
+
+	_, err := LeaderboardRecordWriteWithExpiry(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 100, 0, "", api.Operator_NO_OVERRIDE, time.Now().Add(-time.Minute).Unix())
+	assert.ErrorIs(t, err, ErrLeaderboardRecordExpiryInvalid)
+
+	if _, err = LeaderboardRecordWriteWithExpiry(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 100, 0, "", api.Operator_NO_OVERRIDE, time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("error writing expiring record: %v", err.Error())
+	}
+	if _, err = LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, permanentID.String(), "", 50, 0, "", api.Operator_NO_OVERRIDE); err != nil {
+		t.Fatalf("error writing permanent record: %v", err.Error())
+	}
+
+	list, err := LeaderboardRecordsList(ctx, logger, db, leaderboardCache, rankCache, leaderboardID, wrapperspb.Int32(10), "", nil, 0)
+	if err != nil {
+		t.Fatalf("error listing records: %v", err.Error())
+	}
+	assert.Len(t, list.Records, 2)
+
+	// Expire the record without waiting for it.
+	if _, err = db.ExecContext(ctx, "UPDATE leaderboard_record SET record_expiry_time = now() - INTERVAL '1 second' WHERE leaderboard_id = $1 AND owner_id = $2", leaderboardID, expiringID); err != nil {
+		t.Fatalf("error expiring record: %v", err.Error())
+	}
+
+	list, err = LeaderboardRecordsList(ctx, logger, db, leaderboardCache, rankCache, leaderboardID, wrapperspb.Int32(10), "", []string{expiringID.String()}, 0)
+	if err != nil {
+		t.Fatalf("error listing records: %v", err.Error())
+	}
+	if assert.Len(t, list.Records, 1) {
+		assert.Equal(t, permanentID.String(), list.Records[0].OwnerId)
+		assert.EqualValues(t, 1, list.Records[0].Rank)
+	}
+	assert.Empty(t, list.OwnerRecords)
+
+	// A lower score replaces the expired record even though the leaderboard keeps the best score.
+	record, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 10, 0, "", api.Operator_NO_OVERRIDE)
+	if err != nil {
+		t.Fatalf("error writing over expired record: %v", err.Error())
+	}
+	assert.EqualValues(t, 10, record.Score)
+	assert.EqualValues(t, 1, record.NumScore)
+	assert.EqualValues(t, 2, record.Rank)
+
+	recordExpiry := func() sql.NullTime {
+		var expiry sql.NullTime
+		if err := db.QueryRowContext(ctx, "SELECT record_expiry_time FROM leaderboard_record WHERE leaderboard_id = $1 AND owner_id = $2", leaderboardID, expiringID).Scan(&expiry); err != nil {
+			t.Fatalf("error reading record expiry: %v", err.Error())
+		}
+		return expiry
+	}
+
+	// Writes without an expiry keep the existing one, until it is explicitly cleared.
+	expiry := time.Now().Add(time.Hour).Unix()
+	if _, err = LeaderboardRecordWriteWithExpiry(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 20, 0, "", api.Operator_NO_OVERRIDE, expiry); err != nil {
+		t.Fatalf("error writing expiring record: %v", err.Error())
+	}
+	if _, err = LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 30, 0, "", api.Operator_NO_OVERRIDE); err != nil {
+		t.Fatalf("error writing record: %v", err.Error())
+	}
+	if kept := recordExpiry(); assert.True(t, kept.Valid) {
+		assert.Equal(t, expiry, kept.Time.Unix())
+	}
+	if _, err = LeaderboardRecordWriteWithExpiry(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, expiringID.String(), "", 40, 0, "", api.Operator_NO_OVERRIDE, LeaderboardRecordExpiryClear); err != nil {
+		t.Fatalf("error clearing record expiry: %v", err.Error())
+	}
+	assert.False(t, recordExpiry().Valid)
+}
+
+func TestLeaderboardCacheDeleteMany(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+	scheduler := NewLocalLeaderboardScheduler(logger, db, cfg, leaderboardCache, rankCache)
+
+	if _, err := leaderboardCache.DeleteMany(ctx, rankCache, scheduler, "", 0); err == nil {
+		t.Fatal("expected an error without any criteria")
+	}
+
+	prefix := "daily-" + uuid.Must(uuid.NewV4()).String() + "-"
+	ids := []string{prefix + "1", prefix + "2", "other-" + uuid.Must(uuid.NewV4()).String()}
+	for _, id := range ids {
+		if _, _, err := leaderboardCache.Create(ctx, id, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", true); err != nil {
+			t.Fatalf("error creating leaderboard: %v", err.Error())
+		}
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, ownerID)
+	if _, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, ids[0], ownerID.String(), "", 1, 0, "", api.Operator_NO_OVERRIDE); err != nil {
+		t.Fatalf("error writing record: %v", err.Error())
+	}
+
+	count, err := leaderboardCache.DeleteMany(ctx, rankCache, scheduler, prefix, 0)
+	if err != nil {
+		t.Fatalf("error deleting leaderboards: %v", err.Error())
+	}
+	assert.Equal(t, 2, count)
+	assert.Nil(t, leaderboardCache.Get(ids[0]))
+	assert.Nil(t, leaderboardCache.Get(ids[1]))
+	assert.NotNil(t, leaderboardCache.Get(ids[2]))
+
+	var records int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leaderboard_record WHERE leaderboard_id = $1", ids[0]).Scan(&records); err != nil {
+		t.Fatalf("error counting records: %v", err.Error())
+	}
+	assert.Zero(t, records)
+
+	// Leaderboards without an end time never match an end time criteria.
+	_, err = leaderboardCache.DeleteMany(ctx, rankCache, scheduler, "", time.Now().Unix())
+	if err != nil {
+		t.Fatalf("error deleting leaderboards: %v", err.Error())
+	}
+	assert.NotNil(t, leaderboardCache.Get(ids[2]))
+}
+
+func TestLeaderboardRanksRecalculate(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	if _, err := LeaderboardRanksRecalculate(ctx, logger, db, leaderboardCache, rankCache, uuid.Must(uuid.NewV4()).String()); err != ErrLeaderboardNotFound {
+		t.Fatalf("expected leaderboard not found, got: %v", err)
+	}
+
+	leaderboardID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.Create(ctx, leaderboardID, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", true); err != nil {
+		t.Fatalf("error creating leaderboard: %v", err.Error())
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, ownerID)
+	if _, err := LeaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardID, ownerID.String(), "", 10, 0, "", api.Operator_NO_OVERRIDE); err != nil {
+		t.Fatalf("error writing record: %v", err.Error())
+	}
+
+	// A score written directly to the database is not reflected in the rank cache.
+	importedOwnerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, importedOwnerID)
+	if _, err := db.ExecContext(ctx, "INSERT INTO leaderboard_record (leaderboard_id, owner_id, score, expiry_time) VALUES ($1, $2, $3, $4)", leaderboardID, importedOwnerID, 20, time.Unix(0, 0).UTC()); err != nil {
+		t.Fatalf("error importing record: %v", err.Error())
+	}
+	assert.EqualValues(t, 0, rankCache.Get(leaderboardID, 0, importedOwnerID))
+
+	count, err := LeaderboardRanksRecalculate(ctx, logger, db, leaderboardCache, rankCache, leaderboardID)
+	if err != nil {
+		t.Fatalf("error recalculating ranks: %v", err.Error())
+	}
+	assert.Equal(t, 2, count)
+	assert.EqualValues(t, 1, rankCache.Get(leaderboardID, 0, importedOwnerID))
+	assert.EqualValues(t, 2, rankCache.Get(leaderboardID, 0, ownerID))
+
+	// A newer cached entry stands for a write received during the rebuild and is kept, while a cached owner without an
+	// active record is dropped.
+	rankCache.Insert(leaderboardID, LeaderboardSortOrderDescending, 30, 0, 100, 0, ownerID, true)
+	staleOwnerID := uuid.Must(uuid.NewV4())
+	rankCache.Insert(leaderboardID, LeaderboardSortOrderDescending, 5, 0, 1, 0, staleOwnerID, true)
+	count, err = LeaderboardRanksRecalculate(ctx, logger, db, leaderboardCache, rankCache, leaderboardID)
+	if err != nil {
+		t.Fatalf("error recalculating ranks: %v", err.Error())
+	}
+	assert.Equal(t, 2, count)
+	assert.EqualValues(t, 1, rankCache.Get(leaderboardID, 0, ownerID))
+	assert.EqualValues(t, 2, rankCache.Get(leaderboardID, 0, importedOwnerID))
+	assert.EqualValues(t, 0, rankCache.Get(leaderboardID, 0, staleOwnerID))
+
+	// Leaderboards with ranks disabled are not cached.
+	noRanksID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.Create(ctx, noRanksID, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", false); err != nil {
+		t.Fatalf("error creating leaderboard: %v", err.Error())
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO leaderboard_record (leaderboard_id, owner_id, score, expiry_time) VALUES ($1, $2, $3, $4)", noRanksID, importedOwnerID, 20, time.Unix(0, 0).UTC()); err != nil {
+		t.Fatalf("error importing record: %v", err.Error())
+	}
+	count, err = LeaderboardRanksRecalculate(ctx, logger, db, leaderboardCache, rankCache, noRanksID)
+	if err != nil {
+		t.Fatalf("error recalculating ranks: %v", err.Error())
+	}
+	assert.Equal(t, 0, count)
+	assert.EqualValues(t, 0, rankCache.Get(noRanksID, 0, importedOwnerID))
+}
@@ -31,19 +31,27 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func LinkApple(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, userID uuid.UUID, token string) error {
+// LinkedProfile holds the verified provider profile details of a newly linked account, where available.
+type LinkedProfile struct {
+	ID          string
+	Email       string
+	DisplayName string
+	AvatarURL   string
+}
+
+func LinkApple(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, userID uuid.UUID, token string) (*LinkedProfile, error) {
 	if config.GetSocial().Apple.BundleId == "" {
-		return status.Error(codes.FailedPrecondition, "Apple authentication is not configured.")
+		return nil, status.Error(codes.FailedPrecondition, "Apple authentication is not configured.")
 	}
 
 	if token == "" {
-		return status.Error(codes.InvalidArgument, "Apple ID token is required.")
+		return nil, status.Error(codes.InvalidArgument, "Apple ID token is required.")
 	}
 
 	profile, err := socialClient.CheckAppleToken(ctx, config.GetSocial().Apple.BundleId, token)
 	if err != nil {
 		logger.Info("Could not authenticate Apple profile.", zap.Error(err))
-		return status.Error(codes.Unauthenticated, "Could not authenticate Apple profile.")
+		return nil, status.Error(codes.Unauthenticated, "Could not authenticate Apple profile.")
 	}
 
 	res, err := db.ExecContext(ctx, `
@@ -59,9 +67,9 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link Apple ID.", zap.Error(err), zap.Any("input", token))
-		return status.Error(codes.Internal, "Error while trying to link Apple ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Apple ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "Apple ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "Apple ID is already in use.")
 	}
 
 	// Import email address, if it exists.
@@ -73,12 +81,12 @@ AND (NOT EXISTS
 				logger.Warn("Skipping apple account email import as it is already set in another user.", zap.Error(err), zap.String("appleID", profile.ID), zap.String("user_id", userID.String()))
 			} else {
 				logger.Error("Failed to import apple account email.", zap.Error(err), zap.String("appleID", profile.ID), zap.String("user_id", userID.String()))
-				return status.Error(codes.Internal, "Error importing apple account email.")
+				return nil, status.Error(codes.Internal, "Error importing apple account email.")
 			}
 		}
 	}
 
-	return nil
+	return &LinkedProfile{ID: profile.ID, Email: profile.Email}, nil
 }
 
 func LinkCustom(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, customID string) error {
@@ -194,9 +202,9 @@ AND (NOT EXISTS
 	return nil
 }
 
-func LinkFacebook(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, tracker Tracker, router MessageRouter, userID uuid.UUID, username, appId, token string, sync bool) error {
+func LinkFacebook(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, tracker Tracker, router MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username, appId, token string, sync bool) (*LinkedProfile, error) {
 	if token == "" {
-		return status.Error(codes.InvalidArgument, "Facebook access token is required.")
+		return nil, status.Error(codes.InvalidArgument, "Facebook access token is required.")
 	}
 
 	var facebookProfile *social.FacebookProfile
@@ -208,7 +216,7 @@ func LinkFacebook(ctx context.Context, logger *zap.Logger, db *sql.DB, socialCli
 		facebookProfile, err = socialClient.GetFacebookProfile(ctx, token)
 		if err != nil {
 			logger.Info("Could not authenticate Facebook profile.", zap.Error(err))
-			return status.Error(codes.Unauthenticated, "Could not authenticate Facebook profile.")
+			return nil, status.Error(codes.Unauthenticated, "Could not authenticate Facebook profile.")
 		}
 		importFriendsPossible = true
 	}
@@ -226,9 +234,9 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link Facebook ID.", zap.Error(err), zap.Any("input", token))
-		return status.Error(codes.Internal, "Error while trying to link Facebook ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Facebook ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "Facebook ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "Facebook ID is already in use.")
 	}
 
 	// Import email address, if it exists.
@@ -240,28 +248,28 @@ AND (NOT EXISTS
 				logger.Warn("Skipping facebook account email import as it is already set in another user.", zap.Error(err), zap.String("facebookID", facebookProfile.ID), zap.String("username", username), zap.String("user_id", userID.String()))
 			} else {
 				logger.Error("Failed to import facebook account email.", zap.Error(err), zap.String("facebookID", facebookProfile.ID), zap.String("username", username), zap.String("user_id", userID.String()))
-				return status.Error(codes.Internal, "Error importing facebook account email.")
+				return nil, status.Error(codes.Internal, "Error importing facebook account email.")
 			}
 		}
 	}
 
 	// Import friends if requested.
 	if sync && importFriendsPossible {
-		_ = importFacebookFriends(ctx, logger, db, tracker, router, socialClient, userID, username, token, false)
+		_ = importFacebookFriends(ctx, logger, db, tracker, router, pushFn, socialClient, userID, username, token, false)
 	}
 
-	return nil
+	return &LinkedProfile{ID: facebookProfile.ID, Email: facebookProfile.Email, DisplayName: facebookProfile.Name, AvatarURL: facebookProfile.Picture.Data.Url}, nil
 }
 
-func LinkFacebookInstantGame(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, userID uuid.UUID, signedPlayerInfo string) error {
+func LinkFacebookInstantGame(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, userID uuid.UUID, signedPlayerInfo string) (*LinkedProfile, error) {
 	if signedPlayerInfo == "" {
-		return status.Error(codes.InvalidArgument, "Signed Player Info for a Facebook Instant Game is required.")
+		return nil, status.Error(codes.InvalidArgument, "Signed Player Info for a Facebook Instant Game is required.")
 	}
 
 	facebookInstantGameID, err := socialClient.ExtractFacebookInstantGameID(signedPlayerInfo, config.GetSocial().FacebookInstantGame.AppSecret)
 	if err != nil {
 		logger.Info("Could not authenticate Facebook Instant Game profile.", zap.Error(err))
-		return status.Error(codes.Unauthenticated, "Could not authenticate Facebook Instant Game profile.")
+		return nil, status.Error(codes.Unauthenticated, "Could not authenticate Facebook Instant Game profile.")
 	}
 
 	res, err := db.ExecContext(ctx, `
@@ -277,32 +285,32 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link Facebook Instant Game ID.", zap.Error(err), zap.Any("input", signedPlayerInfo))
-		return status.Error(codes.Internal, "Error while trying to link Facebook Instant Game ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Facebook Instant Game ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "Facebook Instant Game ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "Facebook Instant Game ID is already in use.")
 	}
-	return nil
+	return &LinkedProfile{ID: facebookInstantGameID}, nil
 }
 
-func LinkGameCenter(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, userID uuid.UUID, playerID string, bundleID string, timestamp int64, salt string, signature string, publicKeyURL string) error {
+func LinkGameCenter(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, userID uuid.UUID, playerID string, bundleID string, timestamp int64, salt string, signature string, publicKeyURL string) (*LinkedProfile, error) {
 	if bundleID == "" {
-		return status.Error(codes.InvalidArgument, "GameCenter bundle ID is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter bundle ID is required.")
 	} else if playerID == "" {
-		return status.Error(codes.InvalidArgument, "GameCenter player ID is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter player ID is required.")
 	} else if publicKeyURL == "" {
-		return status.Error(codes.InvalidArgument, "GameCenter public key URL is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter public key URL is required.")
 	} else if salt == "" {
-		return status.Error(codes.InvalidArgument, "GameCenter salt is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter salt is required.")
 	} else if signature == "" {
-		return status.Error(codes.InvalidArgument, "GameCenter signature is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter signature is required.")
 	} else if timestamp == 0 {
-		return status.Error(codes.InvalidArgument, "GameCenter timestamp is required.")
+		return nil, status.Error(codes.InvalidArgument, "GameCenter timestamp is required.")
 	}
 
 	valid, err := socialClient.CheckGameCenterID(ctx, playerID, bundleID, timestamp, salt, signature, publicKeyURL)
 	if !valid || err != nil {
 		logger.Info("Could not authenticate GameCenter profile.", zap.Error(err), zap.Bool("valid", valid))
-		return status.Error(codes.Unauthenticated, "Could not authenticate GameCenter profile.")
+		return nil, status.Error(codes.Unauthenticated, "Could not authenticate GameCenter profile.")
 	}
 
 	res, err := db.ExecContext(ctx, `
@@ -318,22 +326,22 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link GameCenter ID.", zap.Error(err), zap.Any("input", playerID))
-		return status.Error(codes.Internal, "Error while trying to link GameCenter ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link GameCenter ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "GameCenter ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "GameCenter ID is already in use.")
 	}
-	return nil
+	return &LinkedProfile{ID: playerID}, nil
 }
 
-func LinkGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, userID uuid.UUID, idToken string) error {
+func LinkGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClient *social.Client, userID uuid.UUID, idToken string) (*LinkedProfile, error) {
 	if idToken == "" {
-		return status.Error(codes.InvalidArgument, "Google access token is required.")
+		return nil, status.Error(codes.InvalidArgument, "Google access token is required.")
 	}
 
 	googleProfile, err := socialClient.CheckGoogleToken(ctx, idToken)
 	if err != nil {
 		logger.Info("Could not authenticate Google profile.", zap.Error(err))
-		return status.Error(codes.Unauthenticated, "Could not authenticate Google profile.")
+		return nil, status.Error(codes.Unauthenticated, "Could not authenticate Google profile.")
 	}
 
 	displayName := googleProfile.GetDisplayName()
@@ -354,7 +362,7 @@ func LinkGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, socialClien
 	if err != nil {
 		logger.Error("Could not remap Google ID.", zap.Error(err), zap.String("googleId", googleProfile.GetGoogleId()),
 			zap.String("originalGoogleId", googleProfile.GetOriginalGoogleId()), zap.Any("input", idToken))
-		return status.Error(codes.Internal, "Error while trying to link Google ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Google ID.")
 	}
 
 	res, err := db.ExecContext(ctx, `
@@ -370,9 +378,9 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link Google ID.", zap.Error(err), zap.Any("input", idToken))
-		return status.Error(codes.Internal, "Error while trying to link Google ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Google ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "Google ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "Google ID is already in use.")
 	}
 
 	// Import email address, if it exists.
@@ -384,27 +392,27 @@ AND (NOT EXISTS
 				logger.Warn("Skipping google account email import as it is already set in another user.", zap.Error(err), zap.String("googleID", googleProfile.GetGoogleId()), zap.String("created_user_id", userID.String()))
 			} else {
 				logger.Error("Failed to import google account email.", zap.Error(err), zap.String("googleID", googleProfile.GetGoogleId()), zap.String("created_user_id", userID.String()))
-				return status.Error(codes.Internal, "Error importing google account email.")
+				return nil, status.Error(codes.Internal, "Error importing google account email.")
 			}
 		}
 	}
 
-	return nil
+	return &LinkedProfile{ID: googleProfile.GetGoogleId(), Email: googleProfile.GetEmail(), DisplayName: googleProfile.GetDisplayName(), AvatarURL: googleProfile.GetAvatarImageUrl()}, nil
 }
 
-func LinkSteam(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, tracker Tracker, router MessageRouter, userID uuid.UUID, username, token string, sync bool) error {
+func LinkSteam(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, socialClient *social.Client, tracker Tracker, router MessageRouter, pushFn NotificationPushFunction, userID uuid.UUID, username, token string, sync bool) (*LinkedProfile, error) {
 	if config.GetSocial().Steam.PublisherKey == "" || config.GetSocial().Steam.AppID == 0 {
-		return status.Error(codes.FailedPrecondition, "Steam authentication is not configured.")
+		return nil, status.Error(codes.FailedPrecondition, "Steam authentication is not configured.")
 	}
 
 	if token == "" {
-		return status.Error(codes.InvalidArgument, "Steam access token is required.")
+		return nil, status.Error(codes.InvalidArgument, "Steam access token is required.")
 	}
 
 	steamProfile, err := socialClient.GetSteamProfile(ctx, config.GetSocial().Steam.PublisherKey, config.GetSocial().Steam.AppID, token)
 	if err != nil {
 		logger.Info("Could not authenticate Steam profile.", zap.Error(err))
-		return status.Error(codes.Unauthenticated, "Could not authenticate Steam profile.")
+		return nil, status.Error(codes.Unauthenticated, "Could not authenticate Steam profile.")
 	}
 
 	res, err := db.ExecContext(ctx, `
@@ -420,16 +428,16 @@ AND (NOT EXISTS
 
 	if err != nil {
 		logger.Error("Could not link Steam ID.", zap.Error(err), zap.Any("input", token))
-		return status.Error(codes.Internal, "Error while trying to link Steam ID.")
+		return nil, status.Error(codes.Internal, "Error while trying to link Steam ID.")
 	} else if count, _ := res.RowsAffected(); count == 0 {
-		return status.Error(codes.AlreadyExists, "Steam ID is already in use.")
+		return nil, status.Error(codes.AlreadyExists, "Steam ID is already in use.")
 	}
 
 	// Import friends if requested.
 	if sync {
 		steamID := strconv.FormatUint(steamProfile.SteamID, 10)
-		_ = importSteamFriends(ctx, logger, db, tracker, router, socialClient, userID, username, config.GetSocial().Steam.PublisherKey, steamID, false)
+		_ = importSteamFriends(ctx, logger, db, tracker, router, pushFn, socialClient, userID, username, config.GetSocial().Steam.PublisherKey, steamID, false)
 	}
 
-	return nil
+	return &LinkedProfile{ID: strconv.FormatUint(steamProfile.SteamID, 10)}, nil
 }
@@ -0,0 +1,103 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"go.uber.org/zap"
+)
+
+// Locks are stored as system-owned storage objects with no client permissions, in a collection reserved for them.
+const lockStorageCollection = "_nakama_locks"
+
+type lockStorageValue struct {
+	Token      string `json:"token"`
+	ExpireTime int64  `json:"expire_time"`
+}
+
+// LockAcquire attempts to take a cluster-wide lock for the given key, held until it is released or the TTL passes.
+// Returns a token that must be presented to release the lock, or an empty token if the lock is currently held.
+// Expiry is computed and compared against the database clock, so nodes with skewed clocks agree on it.
+func LockAcquire(ctx context.Context, logger *zap.Logger, db *sql.DB, key string, ttl time.Duration) (string, error) {
+	var nowMs int64
+	var currentValue, currentVersion sql.NullString
+	query := `
+SELECT (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, s.value, s.version
+FROM (VALUES (1)) AS v LEFT JOIN storage AS s ON s.collection = $1 AND s.key = $2 AND s.user_id = $3`
+	if err := db.QueryRowContext(ctx, query, lockStorageCollection, key, uuid.Nil).Scan(&nowMs, &currentValue, &currentVersion); err != nil {
+		logger.Error("Could not read lock.", zap.Error(err), zap.String("key", key))
+		return "", err
+	}
+	found := currentVersion.Valid
+
+	if found {
+		var current lockStorageValue
+		if err := json.Unmarshal([]byte(currentValue.String), &current); err != nil {
+			logger.Error("Could not parse lock.", zap.Error(err), zap.String("key", key))
+			return "", err
+		}
+		if current.ExpireTime > nowMs {
+			// Held by someone else.
+			return "", nil
+		}
+	}
+
+	token := uuid.Must(uuid.NewV4()).String()
+	valueBytes, err := json.Marshal(&lockStorageValue{Token: token, ExpireTime: nowMs + ttl.Milliseconds()})
+	if err != nil {
+		return "", err
+	}
+	hash := md5.Sum(valueBytes)
+	version := hex.EncodeToString(hash[:])
+
+	// Both branches are compare-and-set operations, if a concurrent caller acquired the lock first no row is affected.
+	var result sql.Result
+	if found {
+		result, err = db.ExecContext(ctx, "UPDATE storage SET value = $4, version = $5, update_time = now() WHERE collection = $1 AND key = $2 AND user_id = $3 AND version = $6", lockStorageCollection, key, uuid.Nil, string(valueBytes), version, currentVersion.String)
+	} else {
+		result, err = db.ExecContext(ctx, `
+INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time)
+VALUES ($1, $2, $3, $4, $5, 0, 0, now(), now())
+ON CONFLICT (collection, key, user_id) DO NOTHING`, lockStorageCollection, key, uuid.Nil, string(valueBytes), version)
+	}
+	if err != nil {
+		logger.Error("Could not write lock.", zap.Error(err), zap.String("key", key))
+		return "", err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected != 1 {
+		return "", nil
+	}
+
+	return token, nil
+}
+
+// LockRelease releases a lock previously acquired with LockAcquire. Returns false if the token does not match the
+// current holder, which includes the case where the lock expired and was acquired by someone else.
+func LockRelease(ctx context.Context, logger *zap.Logger, db *sql.DB, key, token string) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM storage WHERE collection = $1 AND key = $2 AND user_id = $3 AND value->>'token' = $4", lockStorageCollection, key, uuid.Nil, token)
+	if err != nil {
+		logger.Error("Could not release lock.", zap.Error(err), zap.String("key", key))
+		return false, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected == 1, nil
+}
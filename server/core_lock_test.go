@@ -0,0 +1,88 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockAcquireContended(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	key := uuid.Must(uuid.NewV4()).String()
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := LockAcquire(ctx, logger, db, key, time.Minute)
+			if err != nil {
+				t.Errorf("error acquiring lock: %v", err.Error())
+				return
+			}
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+
+	var holder string
+	for _, token := range tokens {
+		if token != "" {
+			assert.Empty(t, holder, "lock acquired more than once")
+			holder = token
+		}
+	}
+	if !assert.NotEmpty(t, holder) {
+		return
+	}
+
+	// Only the holder can release the lock.
+	released, err := LockRelease(ctx, logger, db, key, uuid.Must(uuid.NewV4()).String())
+	assert.NoError(t, err)
+	assert.False(t, released)
+	released, err = LockRelease(ctx, logger, db, key, holder)
+	assert.NoError(t, err)
+	assert.True(t, released)
+
+	token, err := LockAcquire(ctx, logger, db, key, time.Millisecond)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	// An expired lock can be taken over, after which the old token no longer releases it.
+	time.Sleep(10 * time.Millisecond)
+	newToken, err := LockAcquire(ctx, logger, db, key, time.Minute)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, newToken)
+	released, err = LockRelease(ctx, logger, db, key, token)
+	assert.NoError(t, err)
+	assert.False(t, released)
+
+	// Expiry is based on the database clock.
+	var expireTime, nowMs int64
+	if err := db.QueryRowContext(ctx, "SELECT (value->>'expire_time')::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT FROM storage WHERE collection = $1 AND key = $2", lockStorageCollection, key).Scan(&expireTime, &nowMs); err != nil {
+		t.Fatalf("error reading lock: %v", err.Error())
+	}
+	assert.InDelta(t, nowMs+time.Minute.Milliseconds(), expireTime, float64(10*time.Second.Milliseconds()))
+}
@@ -17,6 +17,8 @@ package server
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
 
 	"github.com/heroiclabs/nakama-common/api"
 	"github.com/heroiclabs/nakama-common/runtime"
@@ -24,6 +26,37 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	MultiUpdateOperationAccount       = "account_update"
+	MultiUpdateOperationStorageWrite  = "storage_write"
+	MultiUpdateOperationStorageDelete = "storage_delete"
+	MultiUpdateOperationWallet        = "wallet_update"
+)
+
+// MultiUpdateError identifies which operation caused a multi update to fail. The underlying error is available through
+// errors.Is and errors.As.
+type MultiUpdateError struct {
+	Operation string
+	// Index of the failed operation within its group, or -1 if the failure is not attributable to a single operation.
+	Index int
+	Err   error
+}
+
+func (e *MultiUpdateError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s failed: %s", e.Operation, e.Err.Error())
+	}
+	return fmt.Sprintf("%s %d failed: %s", e.Operation, e.Index, e.Err.Error())
+}
+
+func (e *MultiUpdateError) Unwrap() error {
+	return e.Err
+}
+
+// MultiUpdate applies all account updates, storage writes, storage deletes and wallet updates, in that order, in a
+// single database transaction. If any operation fails the transaction is rolled back so none of the changes persist,
+// and the returned error is a *MultiUpdateError naming the failed operation and its index. The Go and JavaScript
+// runtimes unwrap it, so only the Lua runtime reports the failed operation.
 func MultiUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, metrics Metrics, accountUpdates []*accountUpdate, storageWrites StorageOpWrites, storageDeletes StorageOpDeletes, storageIndex StorageIndex, walletUpdates []*walletUpdate, updateLedger bool) ([]*api.StorageObjectAck, []*runtime.WalletUpdateResult, error) {
 	if len(accountUpdates) == 0 && len(storageWrites) == 0 && len(storageDeletes) == 0 && len(walletUpdates) == 0 {
 		return nil, nil, nil
@@ -32,41 +65,64 @@ func MultiUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, metrics Me
 	var storageWriteAcks []*api.StorageObjectAck
 	var storageWriteOps StorageOpWrites
 	var walletUpdateResults []*runtime.WalletUpdateResult
+	var failedOperation string
+	var failedIndex int
 
 	if err := ExecuteInTxPgx(ctx, db, func(tx pgx.Tx) error {
 		storageWriteAcks = nil
 		walletUpdateResults = nil
+		failedOperation = ""
+		failedIndex = -1
 
-		// Execute any account updates.
-		updateErr := updateAccounts(ctx, logger, tx, accountUpdates)
-		if updateErr != nil {
-			return updateErr
+		// Execute any account updates, one at a time so a failure can be attributed to its index.
+		for i, update := range accountUpdates {
+			if updateErr := updateAccounts(ctx, logger, tx, []*accountUpdate{update}); updateErr != nil {
+				failedOperation, failedIndex = MultiUpdateOperationAccount, i
+				return updateErr
+			}
 		}
 
 		// Execute any storage updates.
-		storageWriteOps, storageWriteAcks, updateErr = storageWriteObjects(ctx, logger, metrics, tx, true, storageWrites)
+		var updateErr error
+		storageWriteOps, storageWriteAcks, failedIndex, updateErr = storageWriteObjectsWithIndex(ctx, logger, metrics, tx, true, storageWrites)
 		if updateErr != nil {
+			failedOperation = MultiUpdateOperationStorageWrite
 			return updateErr
 		}
 
-		// Execute any storage deletes.
-		deleteErr := storageDeleteObjects(ctx, logger, tx, true, storageDeletes)
-		if deleteErr != nil {
-			return deleteErr
+		// Execute any storage deletes one at a time, in the same consistent order as a batch delete.
+		deleteOrder := make([]int, len(storageDeletes))
+		for i := range deleteOrder {
+			deleteOrder[i] = i
+		}
+		sort.SliceStable(deleteOrder, func(a, b int) bool {
+			return storageDeletes.Less(deleteOrder[a], deleteOrder[b])
+		})
+		for _, i := range deleteOrder {
+			if deleteErr := storageDeleteObjects(ctx, logger, tx, true, StorageOpDeletes{storageDeletes[i]}); deleteErr != nil {
+				failedOperation, failedIndex = MultiUpdateOperationStorageDelete, i
+				return deleteErr
+			}
 		}
 
 		// Execute any wallet updates.
-		walletUpdateResults, updateErr = updateWallets(ctx, logger, tx, walletUpdates, updateLedger)
+		walletUpdateResults, failedIndex, updateErr = updateWalletsWithIndex(ctx, logger, tx, walletUpdates, updateLedger)
 		if updateErr != nil {
+			failedOperation = MultiUpdateOperationWallet
 			return updateErr
 		}
 
 		return nil
 	}); err != nil {
 		if e, ok := err.(*statusError); ok {
-			return nil, walletUpdateResults, e.Cause()
+			err = e.Cause()
+		} else {
+			logger.Error("Error running multi update.", zap.Error(err))
+		}
+		if failedOperation != "" {
+			// Errors from starting or committing the transaction are not attributed to any one operation.
+			err = &MultiUpdateError{Operation: failedOperation, Index: failedIndex, Err: err}
 		}
-		logger.Error("Error running multi update.", zap.Error(err))
 		return nil, walletUpdateResults, err
 	}
 
@@ -0,0 +1,111 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMultiUpdateRollback(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	newWrite := func(key, version string) *StorageOpWrite {
+		return &StorageOpWrite{
+			OwnerID: userID.String(),
+			Object: &api.WriteStorageObject{
+				Collection:      "multi",
+				Key:             key,
+				Value:           "{}",
+				Version:         version,
+				PermissionRead:  &wrapperspb.Int32Value{Value: 1},
+				PermissionWrite: &wrapperspb.Int32Value{Value: 1},
+			},
+		}
+	}
+
+	// The last storage write expects a version that does not exist, so the whole batch must be rejected.
+	_, _, err := MultiUpdate(ctx, logger, db, metrics,
+		[]*accountUpdate{{userID: userID, displayName: &wrapperspb.StringValue{Value: "updated"}}},
+		StorageOpWrites{newWrite("first", ""), newWrite("second", ""), newWrite("third", "missing-version")},
+		nil,
+		storageIdx,
+		[]*walletUpdate{{UserID: userID, Changeset: map[string]int64{"coins": 100}, Metadata: "{}"}},
+		true)
+	if err == nil {
+		t.Fatal("expected multi update to fail")
+	}
+
+	var multiErr *MultiUpdateError
+	if assert.True(t, errors.As(err, &multiErr), "error was not a multi update error") {
+		assert.Equal(t, MultiUpdateOperationStorageWrite, multiErr.Operation)
+		assert.Equal(t, 2, multiErr.Index)
+		assert.Contains(t, multiErr.Error(), "storage_write 2 failed")
+	}
+	assert.ErrorIs(t, err, runtime.ErrStorageRejectedVersion)
+
+	account, err := GetAccount(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+	assert.Empty(t, account.User.DisplayName, "account update persisted")
+	assert.Equal(t, "{}", account.Wallet, "wallet update persisted")
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM storage WHERE collection = 'multi' AND user_id = $1", userID).Scan(&count); err != nil {
+		t.Fatalf("error counting storage objects: %v", err.Error())
+	}
+	assert.Equal(t, 0, count, "storage writes persisted")
+
+	count = 0
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM wallet_ledger WHERE user_id = $1", userID).Scan(&count); err != nil {
+		t.Fatalf("error counting wallet ledger entries: %v", err.Error())
+	}
+	assert.Equal(t, 0, count, "wallet ledger entries persisted")
+}
+
+func TestRuntimeGoMultiUpdateUnwrapsErrors(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	nk := NewRuntimeGoNakamaModule(logger, db, nil, cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	_, _, err := nk.MultiUpdate(ctx, nil, []*runtime.StorageWrite{{
+		Collection: "multi",
+		Key:        "rejected",
+		UserID:     userID.String(),
+		Value:      "{}",
+		Version:    "missing-version",
+	}}, nil, nil, true)
+
+	// Go runtime callers compare against the sentinel error directly.
+	assert.True(t, err == runtime.ErrStorageRejectedVersion, "expected the unwrapped rejected version error, got %v", err)
+}
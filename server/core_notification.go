@@ -53,7 +53,7 @@ type notificationCacheableCursor struct {
 	CreateTime     int64
 }
 
-func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, notifications map[uuid.UUID][]*api.Notification) error {
+func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, notifications map[uuid.UUID][]*api.Notification) error {
 	persistentNotifications := make(map[uuid.UUID][]*api.Notification, len(notifications))
 	for userID, ns := range notifications {
 		for _, userNotification := range ns {
@@ -73,6 +73,9 @@ func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, track
 		if err := NotificationSave(ctx, logger, db, persistentNotifications); err != nil {
 			return err
 		}
+		if pushFn != nil {
+			pushFn(persistentNotifications)
+		}
 	}
 
 	recipients := make(map[PresenceStream][]*PresenceID, len(notifications))
@@ -103,7 +106,7 @@ func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, track
 	return nil
 }
 
-func NotificationSendAll(ctx context.Context, logger *zap.Logger, db *sql.DB, gotracker Tracker, messageRouter MessageRouter, notification *api.Notification) error {
+func NotificationSendAll(ctx context.Context, logger *zap.Logger, db *sql.DB, gotracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, notification *api.Notification) error {
 	// Non-persistent notifications don't need to work through all database users, just use currently connected notification streams.
 	if !notification.Persistent {
 		env := &rtapi.Envelope{
@@ -178,6 +181,9 @@ func NotificationSendAll(ctx context.Context, logger *zap.Logger, db *sql.DB, go
 				notificationLogger.Error("Failed to save persistent notifications", zap.Error(err))
 				return
 			}
+			if pushFn != nil {
+				pushFn(sends)
+			}
 
 			// Deliver live notifications to connected users.
 			for userID, notifications := range sends {
@@ -202,18 +208,154 @@ func NotificationSendAll(ctx context.Context, logger *zap.Logger, db *sql.DB, go
 	return nil
 }
 
+// NotificationFilter scopes a notification fan-out to a subset of users. Exactly one of the
+// user ID list, group ID or storage index query must be set.
+type NotificationFilter struct {
+	UserIDs    []uuid.UUID
+	GroupID    uuid.UUID
+	IndexName  string
+	IndexQuery string
+}
+
+const notificationSendFilteredBatchSize = 10_000
+
+func NotificationSendFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, pushFn NotificationPushFunction, storageIndex StorageIndex, notification *api.Notification, filter *NotificationFilter) error {
+	if filter == nil {
+		return errors.New("expects a notification filter")
+	}
+
+	var filterCount int
+	if len(filter.UserIDs) > 0 {
+		filterCount++
+	}
+	if filter.GroupID != uuid.Nil {
+		filterCount++
+	}
+	if filter.IndexName != "" {
+		filterCount++
+	}
+	if filterCount != 1 {
+		return errors.New("expects exactly one of user IDs, group ID or storage index filter")
+	}
+
+	// Each batch is delivered through the regular send path, so only one page of recipients is held in memory at a time.
+	sendBatch := func(userIDs []uuid.UUID) error {
+		sends := make(map[uuid.UUID][]*api.Notification, len(userIDs))
+		for _, userID := range userIDs {
+			if _, found := sends[userID]; found {
+				continue
+			}
+			sends[userID] = []*api.Notification{{
+				Id:         uuid.Must(uuid.NewV4()).String(),
+				Subject:    notification.Subject,
+				Content:    notification.Content,
+				Code:       notification.Code,
+				SenderId:   notification.SenderId,
+				CreateTime: notification.CreateTime,
+				Persistent: notification.Persistent,
+			}}
+		}
+		return NotificationSend(ctx, logger, db, tracker, messageRouter, pushFn, sends)
+	}
+
+	switch {
+	case len(filter.UserIDs) > 0:
+		for start := 0; start < len(filter.UserIDs); start += notificationSendFilteredBatchSize {
+			end := min(start+notificationSendFilteredBatchSize, len(filter.UserIDs))
+			if err := sendBatch(filter.UserIDs[start:end]); err != nil {
+				return err
+			}
+		}
+	case filter.GroupID != uuid.Nil:
+		// Only superadmin(0), admin(1) and member(2) states are considered group members.
+		query := `
+SELECT destination_id, state, position
+FROM group_edge
+WHERE source_id = $1 AND state >= 0 AND state <= 2 AND (state, position) > ($2, $3)
+ORDER BY state ASC, position ASC
+LIMIT $4`
+		var state, position int64 = -1, 0
+		for {
+			rows, err := db.QueryContext(ctx, query, filter.GroupID, state, position, notificationSendFilteredBatchSize)
+			if err != nil {
+				logger.Error("Could not list group members to send notification.", zap.Error(err), zap.String("group_id", filter.GroupID.String()))
+				return err
+			}
+			userIDs := make([]uuid.UUID, 0, notificationSendFilteredBatchSize)
+			for rows.Next() {
+				var userID uuid.UUID
+				if err := rows.Scan(&userID, &state, &position); err != nil {
+					_ = rows.Close()
+					logger.Error("Could not scan group member to send notification.", zap.Error(err), zap.String("group_id", filter.GroupID.String()))
+					return err
+				}
+				userIDs = append(userIDs, userID)
+			}
+			_ = rows.Close()
+
+			if len(userIDs) > 0 {
+				if err := sendBatch(userIDs); err != nil {
+					return err
+				}
+			}
+
+			// Stop pagination when reaching the last (incomplete) page.
+			if len(userIDs) < notificationSendFilteredBatchSize {
+				break
+			}
+		}
+	default:
+		var cursor string
+		for {
+			objects, newCursor, err := storageIndex.List(ctx, uuid.Nil, filter.IndexName, filter.IndexQuery, notificationSendFilteredBatchSize, nil, cursor)
+			if err != nil {
+				return err
+			}
+			userIDs := make([]uuid.UUID, 0, len(objects.GetObjects()))
+			for _, object := range objects.GetObjects() {
+				userID, err := uuid.FromString(object.UserId)
+				if err != nil || userID == uuid.Nil {
+					// System owned objects have no recipient.
+					continue
+				}
+				userIDs = append(userIDs, userID)
+			}
+
+			if len(userIDs) > 0 {
+				if err := sendBatch(userIDs); err != nil {
+					return err
+				}
+			}
+
+			if newCursor == "" {
+				break
+			}
+			cursor = newCursor
+		}
+	}
+
+	return nil
+}
+
 func NotificationList(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, limit int, cursor string, cacheable bool) (*api.NotificationList, error) {
+	list, _, err := NotificationListFiltered(ctx, logger, db, userID, limit, cursor, cacheable, nil)
+	return list, err
+}
+
+// NotificationListFiltered lists notifications as NotificationList does, optionally only those with the given read state.
+// Also returns the read state of each listed notification keyed by notification ID, as the API type does not carry it.
+func NotificationListFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, limit int, cursor string, cacheable bool, read *bool) (*api.NotificationList, map[string]bool, error) {
 	var nc *notificationCacheableCursor
 	if cursor != "" {
 		nc = &notificationCacheableCursor{}
 		cb, err := base64.RawURLEncoding.DecodeString(cursor)
 		if err != nil {
 			logger.Warn("Could not base64 decode notification cursor.", zap.String("cursor", cursor))
-			return nil, status.Error(codes.InvalidArgument, "Malformed cursor was used.")
+			return nil, nil, status.Error(codes.InvalidArgument, "Malformed cursor was used.")
 		}
 		if err = gob.NewDecoder(bytes.NewReader(cb)).Decode(nc); err != nil {
 			logger.Warn("Could not decode notification cursor.", zap.String("cursor", cursor))
-			return nil, status.Error(codes.InvalidArgument, "Malformed cursor was used.")
+			return nil, nil, status.Error(codes.InvalidArgument, "Malformed cursor was used.")
 		}
 	}
 
@@ -231,19 +373,26 @@ func NotificationList(ctx context.Context, logger *zap.Logger, db *sql.DB, userI
 		params = append(params, &pgtype.Timestamptz{Time: time.Unix(0, nc.CreateTime).UTC(), Valid: true}, uuid.FromBytesOrNil(nc.NotificationID))
 	}
 
+	readQuery := ""
+	if read != nil {
+		params = append(params, *read)
+		readQuery = fmt.Sprintf(" AND read = $%d", len(params))
+	}
+
 	query := `
-SELECT id, subject, content, code, sender_id, create_time
+SELECT id, subject, content, code, sender_id, create_time, read
 FROM notification
-WHERE user_id = $1` + cursorQuery + `
+WHERE user_id = $1` + cursorQuery + readQuery + `
 ORDER BY create_time ASC, id ASC` + limitQuery
 
 	rows, err := db.QueryContext(ctx, query, params...)
 	if err != nil {
 		logger.Error("Could not retrieve notifications.", zap.Error(err))
-		return nil, err
+		return nil, nil, err
 	}
 
 	notifications := make([]*api.Notification, 0, limit)
+	readStates := make(map[string]bool, limit)
 	var lastCreateTime int64
 	var resultCount int
 	var hasNextPage bool
@@ -255,11 +404,13 @@ ORDER BY create_time ASC, id ASC` + limitQuery
 		}
 		no := &api.Notification{Persistent: true, CreateTime: &timestamppb.Timestamp{}}
 		var createTime pgtype.Timestamptz
-		if err := rows.Scan(&no.Id, &no.Subject, &no.Content, &no.Code, &no.SenderId, &createTime); err != nil {
+		var noRead bool
+		if err := rows.Scan(&no.Id, &no.Subject, &no.Content, &no.Code, &no.SenderId, &createTime, &noRead); err != nil {
 			_ = rows.Close()
 			logger.Error("Could not scan notification from database.", zap.Error(err))
-			return nil, err
+			return nil, nil, err
 		}
+		readStates[no.Id] = noRead
 
 		lastCreateTime = createTime.Time.UnixNano()
 		no.CreateTime.Seconds = createTime.Time.Unix()
@@ -280,7 +431,7 @@ ORDER BY create_time ASC, id ASC` + limitQuery
 				newCursor := &notificationCacheableCursor{NotificationID: nil, CreateTime: 0}
 				if err := gob.NewEncoder(cursorBuf).Encode(newCursor); err != nil {
 					logger.Error("Could not create new cursor.", zap.Error(err))
-					return nil, err
+					return nil, nil, err
 				}
 				notificationList.CacheableCursor = base64.RawURLEncoding.EncodeToString(cursorBuf.Bytes())
 			}
@@ -295,14 +446,61 @@ ORDER BY create_time ASC, id ASC` + limitQuery
 			}
 			if err := gob.NewEncoder(cursorBuf).Encode(newCursor); err != nil {
 				logger.Error("Could not create new cursor.", zap.Error(err))
-				return nil, err
+				return nil, nil, err
 			}
 
 			notificationList.CacheableCursor = base64.RawURLEncoding.EncodeToString(cursorBuf.Bytes())
 		}
 	}
 
-	return notificationList, nil
+	return notificationList, readStates, nil
+}
+
+// NotificationsCount counts the notifications stored for a user, optionally only those with the given read state.
+func NotificationsCount(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, read *bool) (int64, error) {
+	query := "SELECT count(*) FROM notification WHERE user_id = $1"
+	params := []any{userID}
+	if read != nil {
+		query += " AND read = $2"
+		params = append(params, *read)
+	}
+
+	var count int64
+	if err := db.QueryRowContext(ctx, query, params...).Scan(&count); err != nil {
+		logger.Error("Could not count notifications.", zap.Error(err), zap.String("user_id", userID.String()))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// NotificationsMarkRead marks the given notifications belonging to a user as read, and returns how many were
+// previously unread. Unknown IDs and notifications already read are ignored.
+func NotificationsMarkRead(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, notificationIDs []string) (int64, error) {
+	if len(notificationIDs) == 0 {
+		// NOOP
+		return 0, nil
+	}
+
+	for _, id := range notificationIDs {
+		if _, err := uuid.FromString(id); err != nil {
+			return 0, errors.New("expects id to be a valid uuid")
+		}
+	}
+
+	result, err := db.ExecContext(ctx, "UPDATE notification SET read = TRUE WHERE user_id = $1 AND id = ANY($2) AND read = FALSE", userID, notificationIDs)
+	if err != nil {
+		logger.Error("Could not mark notifications as read.", zap.Error(err))
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Could not mark notifications as read.", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
 }
 
 func NotificationDelete(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, notificationIDs []string) error {
@@ -319,6 +517,24 @@ func NotificationDelete(ctx context.Context, logger *zap.Logger, db *sql.DB, use
 	return nil
 }
 
+// NotificationsDeleteOlderThan deletes all of a user's stored notifications created before the given time in a single
+// statement, and returns how many were deleted.
+func NotificationsDeleteOlderThan(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM notification WHERE user_id = $1 AND create_time < $2", userID, before)
+	if err != nil {
+		logger.Error("Could not delete notifications.", zap.Error(err), zap.String("user_id", userID.String()))
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Could not delete notifications.", zap.Error(err), zap.String("user_id", userID.String()))
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func NotificationSave(ctx context.Context, logger *zap.Logger, db *sql.DB, notifications map[uuid.UUID][]*api.Notification) error {
 	ids := make([]string, 0, len(notifications))
 	userIds := make([]uuid.UUID, 0, len(notifications))
@@ -356,6 +572,66 @@ SELECT
 	return nil
 }
 
+// NotificationPushFunction receives persistent notifications once they are stored, keyed by recipient.
+type NotificationPushFunction func(notifications map[uuid.UUID][]*api.Notification)
+
+// Forward persistent notifications to the push hook, together with each recipient's device tokens read from the
+// "push_tokens" list in their account metadata. Recipients without device tokens are skipped. Delivery is
+// best-effort, errors are logged and never surfaced to the caller since the notifications have already been stored.
+func notificationPush(ctx context.Context, logger *zap.Logger, db *sql.DB, fn RuntimeNotificationPushFunction, notifications map[uuid.UUID][]*api.Notification) {
+	if fn == nil || len(notifications) == 0 {
+		return
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(notifications))
+	for userID := range notifications {
+		userIDs = append(userIDs, userID)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, metadata FROM users WHERE id = ANY($1::UUID[])", userIDs)
+	if err != nil {
+		logger.Error("Failed to load push tokens for notifications", zap.Error(err))
+		return
+	}
+	pushTokens := make(map[uuid.UUID][]string, len(userIDs))
+	for rows.Next() {
+		var userID uuid.UUID
+		var metadata []byte
+		if err := rows.Scan(&userID, &metadata); err != nil {
+			_ = rows.Close()
+			logger.Error("Failed to scan push tokens for notifications", zap.Error(err))
+			return
+		}
+		var parsed struct {
+			PushTokens []string `json:"push_tokens"`
+		}
+		if err := json.Unmarshal(metadata, &parsed); err != nil {
+			logger.Debug("Could not read push tokens from user metadata", zap.String("user_id", userID.String()), zap.Error(err))
+			continue
+		}
+		if len(parsed.PushTokens) > 0 {
+			pushTokens[userID] = parsed.PushTokens
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.Error("Failed to load push tokens for notifications", zap.Error(err))
+		return
+	}
+
+	for userID, ns := range notifications {
+		tokens, found := pushTokens[userID]
+		if !found {
+			continue
+		}
+		for _, n := range ns {
+			if err := fn(ctx, userID, n, tokens); err != nil {
+				logger.Warn("Failed to push notification", zap.String("user_id", userID.String()), zap.String("id", n.Id), zap.Error(err))
+			}
+		}
+	}
+}
+
 func NotificationsGetId(ctx context.Context, logger *zap.Logger, db *sql.DB, userID string, ids ...string) ([]*runtime.Notification, error) {
 	if len(ids) == 0 {
 		return []*runtime.Notification{}, nil
@@ -0,0 +1,210 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationsMarkRead(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	notifications := make([]*api.Notification, 0, 3)
+	for i := 0; i < 3; i++ {
+		notifications = append(notifications, &api.Notification{
+			Id:         uuid.Must(uuid.NewV4()).String(),
+			Subject:    "subject",
+			Content:    "{}",
+			Code:       1,
+			SenderId:   uuid.Nil.String(),
+			Persistent: true,
+		})
+	}
+	if err := NotificationSave(ctx, logger, db, map[uuid.UUID][]*api.Notification{userID: notifications}); err != nil {
+		t.Fatalf("error saving notifications: %v", err.Error())
+	}
+
+	read, unread := true, false
+	count, err := NotificationsCount(ctx, logger, db, userID, &unread)
+	if err != nil {
+		t.Fatalf("error counting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 3, count, "new notifications should be unread")
+
+	marked, err := NotificationsMarkRead(ctx, logger, db, userID, []string{notifications[0].Id, notifications[1].Id})
+	if err != nil {
+		t.Fatalf("error marking notifications read: %v", err.Error())
+	}
+	assert.EqualValues(t, 2, marked)
+
+	// Marking again is a no-op.
+	marked, err = NotificationsMarkRead(ctx, logger, db, userID, []string{notifications[0].Id})
+	if err != nil {
+		t.Fatalf("error marking notifications read: %v", err.Error())
+	}
+	assert.EqualValues(t, 0, marked)
+
+	count, err = NotificationsCount(ctx, logger, db, userID, &unread)
+	if err != nil {
+		t.Fatalf("error counting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 1, count)
+
+	list, readStates, err := NotificationListFiltered(ctx, logger, db, userID, 10, "", false, &read)
+	if err != nil {
+		t.Fatalf("error listing notifications: %v", err.Error())
+	}
+	assert.Len(t, list.Notifications, 2)
+	assert.Equal(t, map[string]bool{notifications[0].Id: true, notifications[1].Id: true}, readStates)
+
+	list, _, err = NotificationListFiltered(ctx, logger, db, userID, 10, "", false, &unread)
+	if err != nil {
+		t.Fatalf("error listing notifications: %v", err.Error())
+	}
+	if assert.Len(t, list.Notifications, 1) {
+		assert.Equal(t, notifications[2].Id, list.Notifications[0].Id)
+	}
+
+	// Unfiltered lists report each notification's read state.
+	list, readStates, err = NotificationListFiltered(ctx, logger, db, userID, 10, "", false, nil)
+	if err != nil {
+		t.Fatalf("error listing notifications: %v", err.Error())
+	}
+	assert.Len(t, list.Notifications, 3)
+	assert.Equal(t, map[string]bool{notifications[0].Id: true, notifications[1].Id: true, notifications[2].Id: false}, readStates)
+
+	count, err = NotificationsCount(ctx, logger, db, userID, nil)
+	if err != nil {
+		t.Fatalf("error counting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 3, count)
+}
+
+func TestNotificationsDeleteOlderThan(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	otherID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	InsertUser(t, db, otherID)
+
+	notifications := make(map[uuid.UUID][]*api.Notification, 2)
+	for _, id := range []uuid.UUID{userID, otherID} {
+		for i := 0; i < 3; i++ {
+			notifications[id] = append(notifications[id], &api.Notification{
+				Id:         uuid.Must(uuid.NewV4()).String(),
+				Subject:    "subject",
+				Content:    "{}",
+				Code:       1,
+				SenderId:   uuid.Nil.String(),
+				Persistent: true,
+			})
+		}
+	}
+	if err := NotificationSave(ctx, logger, db, notifications); err != nil {
+		t.Fatalf("error saving notifications: %v", err.Error())
+	}
+
+	// Age two of the user's notifications and one of the other user's.
+	if _, err := db.ExecContext(ctx, "UPDATE notification SET create_time = now() - INTERVAL '10 days' WHERE id = ANY($1)", []string{notifications[userID][0].Id, notifications[userID][1].Id, notifications[otherID][0].Id}); err != nil {
+		t.Fatalf("error ageing notifications: %v", err.Error())
+	}
+
+	deleted, err := NotificationsDeleteOlderThan(ctx, logger, db, userID, time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("error deleting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 2, deleted)
+
+	count, err := NotificationsCount(ctx, logger, db, userID, nil)
+	if err != nil {
+		t.Fatalf("error counting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 1, count)
+
+	count, err = NotificationsCount(ctx, logger, db, otherID, nil)
+	if err != nil {
+		t.Fatalf("error counting notifications: %v", err.Error())
+	}
+	assert.EqualValues(t, 3, count, "other users' notifications should be untouched")
+}
+
+func TestNotificationSendPush(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	if _, err := db.ExecContext(ctx, `UPDATE users SET metadata = '{"push_tokens": ["token1", "token2"]}' WHERE id = $1`, userID); err != nil {
+		t.Fatalf("error setting push tokens: %v", err.Error())
+	}
+	noTokensUserID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, noTokensUserID)
+
+	pushed := make(map[uuid.UUID][]string)
+	hook := func(ctx context.Context, userID uuid.UUID, notification *api.Notification, pushTokens []string) error {
+		pushed[userID] = pushTokens
+		return errors.New("push provider unavailable")
+	}
+	// Run the hook inline rather than through the event queue so the results can be checked right after the send.
+	pushFn := func(notifications map[uuid.UUID][]*api.Notification) {
+		notificationPush(ctx, logger, db, hook, notifications)
+	}
+
+	notification := func(persistent bool) *api.Notification {
+		return &api.Notification{
+			Id:         uuid.Must(uuid.NewV4()).String(),
+			Subject:    "subject",
+			Content:    "{}",
+			Code:       1,
+			SenderId:   uuid.Nil.String(),
+			Persistent: persistent,
+		}
+	}
+
+	// Push failures must not fail the in-app notification.
+	if err := NotificationSend(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, pushFn, map[uuid.UUID][]*api.Notification{
+		userID:         {notification(true)},
+		noTokensUserID: {notification(true)},
+	}); err != nil {
+		t.Fatalf("error sending notifications: %v", err.Error())
+	}
+	assert.Len(t, pushed, 1)
+	assert.Equal(t, []string{"token1", "token2"}, pushed[userID])
+
+	// Non-persistent notifications are not pushed.
+	pushed = make(map[uuid.UUID][]string)
+	if err := NotificationSend(ctx, logger, db, &testTracker{}, &DummyMessageRouter{}, pushFn, map[uuid.UUID][]*api.Notification{
+		userID: {notification(false)},
+	}); err != nil {
+		t.Fatalf("error sending notifications: %v", err.Error())
+	}
+	assert.Empty(t, pushed)
+}
@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid/v5"
@@ -41,7 +42,7 @@ var ErrPurchasesListInvalidCursor = errors.New("purchases list cursor invalid")
 
 var httpc = &http.Client{Timeout: 5 * time.Second}
 
-func ValidatePurchasesApple(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, password, receipt string, persist bool) (*api.ValidatePurchaseResponse, error) {
+func ValidatePurchasesApple(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, userID uuid.UUID, password, receipt string, persist bool) (*api.ValidatePurchaseResponse, error) {
 	validation, raw, err := iap.ValidateReceiptApple(ctx, httpc, receipt, password)
 	if err != nil {
 		if err != context.Canceled {
@@ -144,7 +145,7 @@ func ValidatePurchasesApple(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		return &api.ValidatePurchaseResponse{ValidatedPurchases: validatedPurchases}, nil
 	}
 
-	purchases, err := upsertPurchases(ctx, db, storagePurchases)
+	purchases, err := upsertPurchases(ctx, logger, db, refundFn, storagePurchases)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +175,7 @@ func ValidatePurchasesApple(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	}, nil
 }
 
-func ValidatePurchaseGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, config *IAPGoogleConfig, receipt string, persist bool) (*api.ValidatePurchaseResponse, error) {
+func ValidatePurchaseGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, userID uuid.UUID, config *IAPGoogleConfig, receipt string, persist bool) (*api.ValidatePurchaseResponse, error) {
 	gResponse, gReceipt, raw, err := iap.ValidateReceiptGoogle(ctx, httpc, config.ClientEmail, config.PrivateKey, receipt)
 	if err != nil {
 		if err != context.Canceled {
@@ -220,7 +221,7 @@ func ValidatePurchaseGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		return &api.ValidatePurchaseResponse{ValidatedPurchases: validatedPurchases}, nil
 	}
 
-	purchases, err := upsertPurchases(ctx, db, []*storagePurchase{sPurchase})
+	purchases, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{sPurchase})
 	if err != nil {
 		if err != context.Canceled {
 			logger.Error("Error storing Google receipt", zap.Error(err))
@@ -253,7 +254,7 @@ func ValidatePurchaseGoogle(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	}, nil
 }
 
-func ValidatePurchaseHuawei(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, config *IAPHuaweiConfig, inAppPurchaseData, signature string, persist bool) (*api.ValidatePurchaseResponse, error) {
+func ValidatePurchaseHuawei(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, userID uuid.UUID, config *IAPHuaweiConfig, inAppPurchaseData, signature string, persist bool) (*api.ValidatePurchaseResponse, error) {
 	validation, data, raw, err := iap.ValidateReceiptHuawei(ctx, httpc, config.PublicKey, config.ClientID, config.ClientSecret, inAppPurchaseData, signature)
 	if err != nil {
 		if err != context.Canceled {
@@ -302,7 +303,7 @@ func ValidatePurchaseHuawei(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		return &api.ValidatePurchaseResponse{ValidatedPurchases: validatedPurchases}, nil
 	}
 
-	purchases, err := upsertPurchases(ctx, db, []*storagePurchase{sPurchase})
+	purchases, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{sPurchase})
 	if err != nil {
 		if err != context.Canceled {
 			logger.Error("Error storing Huawei receipt", zap.Error(err))
@@ -335,7 +336,7 @@ func ValidatePurchaseHuawei(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	}, nil
 }
 
-func ValidatePurchaseFacebookInstant(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, config *IAPFacebookInstantConfig, signedRequest string, persist bool) (*api.ValidatePurchaseResponse, error) {
+func ValidatePurchaseFacebookInstant(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, userID uuid.UUID, config *IAPFacebookInstantConfig, signedRequest string, persist bool) (*api.ValidatePurchaseResponse, error) {
 	payment, rawResponse, err := iap.ValidateReceiptFacebookInstant(config.AppSecret, signedRequest)
 	if err != nil {
 		if err != context.Canceled {
@@ -370,7 +371,7 @@ func ValidatePurchaseFacebookInstant(ctx context.Context, logger *zap.Logger, db
 		return &api.ValidatePurchaseResponse{ValidatedPurchases: validatedPurchases}, nil
 	}
 
-	purchases, err := upsertPurchases(ctx, db, []*storagePurchase{sPurchase})
+	purchases, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{sPurchase})
 	if err != nil {
 		if err != context.Canceled {
 			logger.Error("Error storing Facebook Instant receipt", zap.Error(err))
@@ -461,13 +462,23 @@ func GetPurchaseByTransactionId(ctx context.Context, logger *zap.Logger, db *sql
 }
 
 type purchasesListCursor struct {
-	TransactionId string
-	PurchaseTime  *timestamppb.Timestamp
-	UserId        string
-	IsNext        bool
+	TransactionId   string
+	PurchaseTime    *timestamppb.Timestamp
+	UserId          string
+	IsNext          bool
+	ProductId       string
+	CreateTimeStart int64
+	CreateTimeEnd   int64
 }
 
 func ListPurchases(ctx context.Context, logger *zap.Logger, db *sql.DB, userID string, limit int, cursor string) (*api.PurchaseList, error) {
+	return ListPurchasesFiltered(ctx, logger, db, userID, "", 0, 0, limit, cursor)
+}
+
+// ListPurchasesFiltered lists stored validated purchases, optionally restricted to a product ID and to purchases
+// stored at or after createTimeStart and before createTimeEnd, both in Unix seconds. A bound of 0 leaves that side of
+// the window open. Cursors are only valid for the same set of filters.
+func ListPurchasesFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, userID, productID string, createTimeStart, createTimeEnd int64, limit int, cursor string) (*api.PurchaseList, error) {
 	var incomingCursor *purchasesListCursor
 	if cursor != "" {
 		cb, err := base64.URLEncoding.DecodeString(cursor)
@@ -482,6 +493,10 @@ func ListPurchases(ctx context.Context, logger *zap.Logger, db *sql.DB, userID s
 			// userID filter was set and has changed, cursor is now invalid
 			return nil, ErrPurchasesListInvalidCursor
 		}
+		if productID != incomingCursor.ProductId || createTimeStart != incomingCursor.CreateTimeStart || createTimeEnd != incomingCursor.CreateTimeEnd {
+			// Remaining filters have changed, cursor is now invalid.
+			return nil, ErrPurchasesListInvalidCursor
+		}
 	}
 
 	query := `
@@ -500,45 +515,40 @@ FROM
 	purchase
 `
 
-	params := make([]interface{}, 0)
+	params := make([]interface{}, 0, 8)
+	predicates := make([]string, 0, 5)
+	if userID != "" {
+		params = append(params, userID)
+		predicates = append(predicates, fmt.Sprintf("user_id = $%d", len(params)))
+	}
+	if productID != "" {
+		params = append(params, productID)
+		predicates = append(predicates, fmt.Sprintf("product_id = $%d", len(params)))
+	}
+	if createTimeStart != 0 {
+		params = append(params, time.Unix(createTimeStart, 0).UTC())
+		predicates = append(predicates, fmt.Sprintf("create_time >= $%d", len(params)))
+	}
+	if createTimeEnd != 0 {
+		params = append(params, time.Unix(createTimeEnd, 0).UTC())
+		predicates = append(predicates, fmt.Sprintf("create_time < $%d", len(params)))
+	}
 
+	order := "DESC"
 	if incomingCursor != nil {
-		if incomingCursor.IsNext {
-			if userID == "" {
-				query += `
-WHERE (purchase_time, user_id, transaction_id) < ($1, $2, $3)
-ORDER BY purchase_time DESC, user_id DESC, transaction_id DESC
-LIMIT $4`
-			} else {
-				query += `
-WHERE user_id = $2
-	AND (purchase_time, user_id, transaction_id) < ($1, $2, $3)
-ORDER BY purchase_time DESC, user_id DESC, transaction_id DESC
-LIMIT $4`
-			}
-		} else {
-			if userID == "" {
-				query += `
-WHERE (purchase_time, user_id, transaction_id) > ($1, $2, $3)
-ORDER BY purchase_time, user_id, transaction_id
-LIMIT $4`
-			} else {
-				query += `
-WHERE user_id = $2
-	AND (purchase_time, user_id, transaction_id) > ($1, $2, $3)
-ORDER BY purchase_time, user_id, transaction_id
-LIMIT $4`
-			}
+		op := "<"
+		if !incomingCursor.IsNext {
+			op = ">"
+			order = "ASC"
 		}
 		params = append(params, incomingCursor.PurchaseTime.AsTime(), incomingCursor.UserId, incomingCursor.TransactionId)
-	} else {
-		if userID == "" {
-			query += " ORDER BY purchase_time DESC, user_id DESC, transaction_id DESC LIMIT $1"
-		} else {
-			query += " WHERE user_id = $1 ORDER BY purchase_time DESC, user_id DESC, transaction_id DESC LIMIT $2"
-			params = append(params, userID)
-		}
+		predicates = append(predicates, fmt.Sprintf("(purchase_time, user_id, transaction_id) %s ($%d, $%d, $%d)", op, len(params)-2, len(params)-1, len(params)))
+	}
+
+	if len(predicates) > 0 {
+		query += "WHERE " + strings.Join(predicates, " AND ") + "\n"
 	}
+	query += fmt.Sprintf("ORDER BY purchase_time %[1]s, user_id %[1]s, transaction_id %[1]s LIMIT $%[2]d", order, len(params)+1)
 
 	if limit > 0 {
 		params = append(params, limit+1)
@@ -571,10 +581,13 @@ LIMIT $4`
 	for rows.Next() {
 		if len(purchases) >= limit {
 			nextCursor = &purchasesListCursor{
-				TransactionId: transactionId,
-				PurchaseTime:  timestamppb.New(purchaseTime.Time),
-				UserId:        dbUserID.String(),
-				IsNext:        true,
+				TransactionId:   transactionId,
+				PurchaseTime:    timestamppb.New(purchaseTime.Time),
+				UserId:          dbUserID.String(),
+				IsNext:          true,
+				ProductId:       productID,
+				CreateTimeStart: createTimeStart,
+				CreateTimeEnd:   createTimeEnd,
 			}
 			break
 		}
@@ -606,10 +619,13 @@ LIMIT $4`
 
 		if incomingCursor != nil && prevCursor == nil {
 			prevCursor = &purchasesListCursor{
-				TransactionId: transactionId,
-				PurchaseTime:  timestamppb.New(purchaseTime.Time),
-				UserId:        dbUserID.String(),
-				IsNext:        false,
+				TransactionId:   transactionId,
+				PurchaseTime:    timestamppb.New(purchaseTime.Time),
+				UserId:          dbUserID.String(),
+				IsNext:          false,
+				ProductId:       productID,
+				CreateTimeStart: createTimeStart,
+				CreateTimeEnd:   createTimeEnd,
 			}
 		}
 	}
@@ -668,9 +684,142 @@ type storagePurchase struct {
 	refundTime    time.Time
 	environment   api.StoreEnvironment
 	seenBefore    bool // Set by upsertPurchases
+	refunded      bool // Set by upsertPurchases, true only if this upsert first marked the purchase as refunded
+}
+
+// PurchaseRefundFunction wakes the purchase refund dispatcher after an upsert recorded a new refund.
+type PurchaseRefundFunction func()
+
+const (
+	purchaseRefundSweepInterval  = time.Minute
+	purchaseRefundSweepBatchSize = 100
+)
+
+// PurchaseRefundDispatcher delivers refunds recorded by purchase upserts to the runtime refund hook. The upsert that
+// first marks a purchase refunded also marks it pending, and the marker is only cleared once the hook returns without
+// error. Hook errors are retried on the next sweep and refunds still pending after a restart are delivered on startup,
+// so a refund may be delivered more than once but is never lost.
+type PurchaseRefundDispatcher struct {
+	logger   *zap.Logger
+	db       *sql.DB
+	fn       RuntimePurchaseRefundFunction
+	notifyCh chan struct{}
+}
+
+func NewPurchaseRefundDispatcher(ctx context.Context, logger *zap.Logger, db *sql.DB, fn RuntimePurchaseRefundFunction) *PurchaseRefundDispatcher {
+	d := &PurchaseRefundDispatcher{
+		logger:   logger,
+		db:       db,
+		fn:       fn,
+		notifyCh: make(chan struct{}, 1),
+	}
+
+	go func() {
+		ticker := time.NewTicker(purchaseRefundSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := d.deliver(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Error delivering purchase refunds", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.notifyCh:
+			}
+		}
+	}()
+
+	return d
+}
+
+// Notify wakes the dispatcher without blocking, a wake-up that is already pending covers this one.
+func (d *PurchaseRefundDispatcher) Notify() {
+	select {
+	case d.notifyCh <- struct{}{}:
+	default:
+	}
 }
 
-func upsertPurchases(ctx context.Context, db *sql.DB, purchases []*storagePurchase) ([]*storagePurchase, error) {
+// Run the refund hook for pending refunds, one batch at a time. Stops at the first batch with a failed delivery so
+// failing refunds are retried on the next sweep rather than immediately.
+func (d *PurchaseRefundDispatcher) deliver(ctx context.Context) error {
+	query := `
+SELECT user_id, store, transaction_id, product_id, purchase_time, raw_response, environment, create_time, update_time, refund_time
+FROM purchase
+WHERE refund_pending
+ORDER BY update_time ASC
+LIMIT $1`
+
+	for {
+		rows, err := d.db.QueryContext(ctx, query, purchaseRefundSweepBatchSize)
+		if err != nil {
+			return err
+		}
+
+		purchases := make([]*api.ValidatedPurchase, 0, purchaseRefundSweepBatchSize)
+		for rows.Next() {
+			var dbUserID uuid.NullUUID
+			var store api.StoreProvider
+			var transactionID string
+			var productID string
+			var purchaseTime pgtype.Timestamptz
+			var rawResponse string
+			var environment api.StoreEnvironment
+			var createTime pgtype.Timestamptz
+			var updateTime pgtype.Timestamptz
+			var refundTime pgtype.Timestamptz
+			if err := rows.Scan(&dbUserID, &store, &transactionID, &productID, &purchaseTime, &rawResponse, &environment, &createTime, &updateTime, &refundTime); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			var userID string
+			if dbUserID.Valid && !dbUserID.UUID.IsNil() {
+				userID = dbUserID.UUID.String()
+			}
+			purchases = append(purchases, &api.ValidatedPurchase{
+				UserId:           userID,
+				ProductId:        productID,
+				TransactionId:    transactionID,
+				Store:            store,
+				PurchaseTime:     timestamppb.New(purchaseTime.Time),
+				CreateTime:       timestamppb.New(createTime.Time),
+				UpdateTime:       timestamppb.New(updateTime.Time),
+				RefundTime:       timestamppb.New(refundTime.Time),
+				ProviderResponse: rawResponse,
+				SeenBefore:       true,
+				Environment:      environment,
+			})
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		failed := false
+		for _, purchase := range purchases {
+			// Purchases of deleted users have no items left to revoke.
+			if purchase.UserId != "" {
+				if err := d.fn(ctx, uuid.FromStringOrNil(purchase.UserId), purchase); err != nil {
+					d.logger.Error("Error running purchase refund hook", zap.Error(err), zap.String("user_id", purchase.UserId), zap.String("transaction_id", purchase.TransactionId))
+					failed = true
+					continue
+				}
+			}
+			if _, err := d.db.ExecContext(ctx, "UPDATE purchase SET refund_pending = false WHERE transaction_id = $1", purchase.TransactionId); err != nil {
+				return err
+			}
+		}
+
+		if failed || len(purchases) < purchaseRefundSweepBatchSize {
+			return nil
+		}
+	}
+}
+
+func upsertPurchases(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, purchases []*storagePurchase) ([]*storagePurchase, error) {
 	if len(purchases) < 1 {
 		return nil, errors.New("expects at least one receipt")
 	}
@@ -685,6 +834,7 @@ func upsertPurchases(ctx context.Context, db *sql.DB, purchases []*storagePurcha
 	rawResponseParams := make([]string, 0, len(purchases))
 	environmentParams := make([]api.StoreEnvironment, 0, len(purchases))
 	refundTimeParams := make([]time.Time, 0, len(purchases))
+	refundPendingParams := make([]bool, 0, len(purchases))
 
 	for _, purchase := range purchases {
 		if purchase.refundTime.IsZero() {
@@ -703,35 +853,46 @@ func upsertPurchases(ctx context.Context, db *sql.DB, purchases []*storagePurcha
 		rawResponseParams = append(rawResponseParams, purchase.rawResponse)
 		environmentParams = append(environmentParams, purchase.environment)
 		refundTimeParams = append(refundTimeParams, purchase.refundTime)
+		refundPendingParams = append(refundPendingParams, purchase.refundTime.Unix() != 0)
 	}
 
+	// The previous refund time is read in the same snapshot as the upsert, to detect purchases this call marks refunded.
 	query := `
-INSERT INTO purchase
-	(
+WITH previous AS (
+	SELECT transaction_id, refund_time FROM purchase WHERE transaction_id = ANY($3::text[])
+), upserted AS (
+	INSERT INTO purchase
+		(
+			user_id,
+			store,
+			transaction_id,
+			product_id,
+			purchase_time,
+			raw_response,
+			environment,
+			refund_time,
+			refund_pending
+		)
+	SELECT unnest($1::uuid[]), unnest($2::smallint[]), unnest($3::text[]), unnest($4::text[]), unnest($5::timestamptz[]), unnest($6::jsonb[]), unnest($7::smallint[]), unnest($8::timestamptz[]), unnest($9::bool[])
+	ON CONFLICT
+		(transaction_id)
+	DO UPDATE SET
+		refund_time = EXCLUDED.refund_time,
+		refund_pending = purchase.refund_pending OR (purchase.refund_time = '1970-01-01 00:00:00 UTC' AND EXCLUDED.refund_pending),
+		update_time = now()
+	RETURNING
 		user_id,
-		store,
 		transaction_id,
-		product_id,
-		purchase_time,
-		raw_response,
-		environment,
+		create_time,
+		update_time,
 		refund_time
-	)
-SELECT unnest($1::uuid[]), unnest($2::smallint[]), unnest($3::text[]), unnest($4::text[]), unnest($5::timestamptz[]), unnest($6::jsonb[]), unnest($7::smallint[]), unnest($8::timestamptz[])
-ON CONFLICT
-	(transaction_id)
-DO UPDATE SET
-	refund_time = EXCLUDED.refund_time,
-	update_time = now()
-RETURNING
-	user_id,
-	transaction_id,
-	create_time,
-	update_time,
-	refund_time
+)
+SELECT upserted.user_id, upserted.transaction_id, upserted.create_time, upserted.update_time, upserted.refund_time, COALESCE(previous.refund_time, '1970-01-01 00:00:00 UTC')
+FROM upserted
+LEFT JOIN previous ON previous.transaction_id = upserted.transaction_id
 `
 
-	rows, err := db.QueryContext(ctx, query, userIdParams, storeParams, transactionIdParams, productIdParams, purchaseTimeParams, rawResponseParams, environmentParams, refundTimeParams)
+	rows, err := db.QueryContext(ctx, query, userIdParams, storeParams, transactionIdParams, productIdParams, purchaseTimeParams, rawResponseParams, environmentParams, refundTimeParams, refundPendingParams)
 	if err != nil {
 		return nil, err
 	}
@@ -742,7 +903,8 @@ RETURNING
 		var createTime pgtype.Timestamptz
 		var updateTime pgtype.Timestamptz
 		var refundTime pgtype.Timestamptz
-		if err = rows.Scan(&dbUserID, &transactionId, &createTime, &updateTime, &refundTime); err != nil {
+		var previousRefundTime pgtype.Timestamptz
+		if err = rows.Scan(&dbUserID, &transactionId, &createTime, &updateTime, &refundTime, &previousRefundTime); err != nil {
 			_ = rows.Close()
 			return nil, err
 		}
@@ -752,6 +914,7 @@ RETURNING
 		storedPurchase.seenBefore = updateTime.Time.After(createTime.Time)
 		if refundTime.Time.Unix() != 0 {
 			storedPurchase.refundTime = refundTime.Time
+			storedPurchase.refunded = previousRefundTime.Time.Unix() == 0
 		}
 	}
 	_ = rows.Close()
@@ -764,6 +927,16 @@ RETURNING
 		storedPurchases = append(storedPurchases, purchase)
 	}
 
+	// The upsert is a single statement, so the pending refunds are committed by the time the dispatcher is woken.
+	if refundFn != nil {
+		for _, purchase := range storedPurchases {
+			if purchase.refunded {
+				refundFn()
+				break
+			}
+		}
+	}
+
 	return storedPurchases, nil
 }
 
@@ -0,0 +1,117 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsertPurchasesRefundHook(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	var notified int
+	refundFn := func() {
+		notified++
+	}
+
+	transactionID := "refund-hook-" + userID.String()
+	purchase := func(refundTime time.Time) *storagePurchase {
+		return &storagePurchase{
+			userID:        userID,
+			store:         api.StoreProvider_GOOGLE_PLAY_STORE,
+			productId:     "gems_100",
+			transactionId: transactionID,
+			purchaseTime:  time.Now().Add(-time.Hour),
+			refundTime:    refundTime,
+			environment:   api.StoreEnvironment_SANDBOX,
+		}
+	}
+
+	// A valid purchase is not marked as a pending refund.
+	if _, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{purchase(time.Time{})}); err != nil {
+		t.Fatalf("error storing purchase: %v", err.Error())
+	}
+	assert.Equal(t, 0, notified)
+	assert.False(t, purchaseRefundPending(t, db, transactionID))
+
+	// The first upsert to mark the purchase refunded stores a pending refund and wakes the dispatcher.
+	refundTime := time.Now().Truncate(time.Second)
+	if _, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{purchase(refundTime)}); err != nil {
+		t.Fatalf("error storing refunded purchase: %v", err.Error())
+	}
+	assert.Equal(t, 1, notified)
+	assert.True(t, purchaseRefundPending(t, db, transactionID))
+
+	// Repeated refund notifications for the same purchase do not record it again.
+	if _, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{purchase(refundTime)}); err != nil {
+		t.Fatalf("error storing refunded purchase: %v", err.Error())
+	}
+	assert.Equal(t, 1, notified)
+
+	// A failed hook leaves the refund pending, the next delivery retries it and clears the marker.
+	var calls int
+	hookErr := errors.New("hook failed")
+	d := &PurchaseRefundDispatcher{
+		logger:   logger,
+		db:       db,
+		notifyCh: make(chan struct{}, 1),
+		fn: func(ctx context.Context, refundUserID uuid.UUID, p *api.ValidatedPurchase) error {
+			if p.TransactionId != transactionID {
+				return nil
+			}
+			calls++
+			assert.Equal(t, userID, refundUserID)
+			assert.Equal(t, "gems_100", p.ProductId)
+			assert.Equal(t, refundTime.Unix(), p.RefundTime.AsTime().Unix())
+			if calls == 1 {
+				return hookErr
+			}
+			return nil
+		},
+	}
+
+	if err := d.deliver(ctx); err != nil {
+		t.Fatalf("error delivering refunds: %v", err.Error())
+	}
+	assert.Equal(t, 1, calls)
+	assert.True(t, purchaseRefundPending(t, db, transactionID))
+
+	if err := d.deliver(ctx); err != nil {
+		t.Fatalf("error delivering refunds: %v", err.Error())
+	}
+	assert.Equal(t, 2, calls)
+	assert.False(t, purchaseRefundPending(t, db, transactionID))
+}
+
+func purchaseRefundPending(t *testing.T, db *sql.DB, transactionID string) bool {
+	var pending bool
+	if err := db.QueryRowContext(context.Background(), "SELECT refund_pending FROM purchase WHERE transaction_id = $1", transactionID).Scan(&pending); err != nil {
+		t.Fatalf("error reading purchase: %v", err.Error())
+	}
+	return pending
+}
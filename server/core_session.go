@@ -18,6 +18,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -94,3 +95,52 @@ func SessionLogout(config Config, sessionCache SessionCache, userID uuid.UUID, t
 	sessionCache.Remove(userID, maybeSessionExp, maybeSessionTokenId, maybeRefreshExp, maybeRefreshTokenId)
 	return nil
 }
+
+// SessionLogoutAll invalidates every session and refresh token issued to the user and disconnects all of the user's
+// live sessions on this node. It returns the number of live sessions disconnected.
+func SessionLogoutAll(ctx context.Context, sessionCache SessionCache, sessionRegistry SessionRegistry, userID uuid.UUID) (int, error) {
+	sessionCache.RemoveAll(userID)
+
+	sessionIDs := make([]uuid.UUID, 0, 1)
+	sessionRegistry.Range(func(session Session) bool {
+		if session.UserID() == userID {
+			sessionIDs = append(sessionIDs, session.ID())
+		}
+		return true
+	})
+
+	// Disconnect outside the range, closing sessions removes them from the registry.
+	for i, sessionID := range sessionIDs {
+		if err := sessionRegistry.Disconnect(ctx, sessionID, false); err != nil {
+			return i, err
+		}
+	}
+
+	return len(sessionIDs), nil
+}
+
+// SessionVarsUpdate replaces the vars of a live session on this node and rotates its token claims. The session keeps
+// its ID, and subsequent realtime messages and hooks on the session see the new vars. A new session and refresh token
+// pair carrying the new vars is issued and returned so the caller can hand it to the client, after which HTTP
+// requests, new sockets and session refreshes see the new vars too. Tokens already issued to the client remain valid
+// and keep the old vars until they expire or the client switches to the rotated tokens.
+func SessionVarsUpdate(config Config, sessionCache SessionCache, sessionRegistry SessionRegistry, sessionID uuid.UUID, vars map[string]string) (string, string, error) {
+	session := sessionRegistry.Get(sessionID)
+	if session == nil {
+		return "", "", ErrSessionNotFound
+	}
+
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	session.SetVars(vars)
+
+	userID := session.UserID()
+	tokenID := uuid.Must(uuid.NewV4()).String()
+	tokenIssuedAt := time.Now().Unix()
+	token, tokenExp := generateToken(config, tokenID, tokenIssuedAt, userID.String(), session.Username(), vars)
+	refreshToken, refreshTokenExp := generateRefreshToken(config, tokenID, tokenIssuedAt, userID.String(), session.Username(), vars)
+	sessionCache.Add(userID, tokenExp, tokenID, refreshTokenExp, tokenID)
+
+	return token, refreshToken, nil
+}
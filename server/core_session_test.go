@@ -0,0 +1,115 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+)
+
+type logoutTestSession struct {
+	DummySession
+	id       uuid.UUID
+	registry SessionRegistry
+	closed   bool
+}
+
+func (s *logoutTestSession) ID() uuid.UUID {
+	return s.id
+}
+
+func (s *logoutTestSession) Close(msg string, reason runtime.PresenceReason, envelopes ...*rtapi.Envelope) {
+	s.closed = true
+	s.registry.Remove(s.id)
+}
+
+func TestSessionLogoutAll(t *testing.T) {
+	sessionCache := NewLocalSessionCache(60, 3600)
+	defer sessionCache.Stop()
+	sessionRegistry := NewLocalSessionRegistry(metrics)
+
+	userID := uuid.Must(uuid.NewV4())
+	otherUserID := uuid.Must(uuid.NewV4())
+	sessions := make([]*logoutTestSession, 0, 3)
+	for _, uid := range []uuid.UUID{userID, userID, otherUserID} {
+		session := &logoutTestSession{DummySession: DummySession{uid: uid}, id: uuid.Must(uuid.NewV4()), registry: sessionRegistry}
+		sessionRegistry.Add(session)
+		sessions = append(sessions, session)
+	}
+
+	// Tokens issued before the logout.
+	issuedAt := time.Now().Add(-10 * time.Second).Unix()
+
+	count, err := SessionLogoutAll(context.Background(), sessionCache, sessionRegistry, userID)
+	if err != nil {
+		t.Fatalf("error logging out: %v", err.Error())
+	}
+
+	assert.Equal(t, 2, count)
+	assert.True(t, sessions[0].closed)
+	assert.True(t, sessions[1].closed)
+	assert.False(t, sessions[2].closed, "other users' sessions should stay connected")
+	assert.Equal(t, 1, sessionRegistry.Count())
+
+	assert.False(t, sessionCache.IsValidSession(userID, issuedAt+60, "token"))
+	assert.False(t, sessionCache.IsValidRefresh(userID, issuedAt+3600, "refresh"))
+	assert.True(t, sessionCache.IsValidSession(otherUserID, issuedAt+60, "token"))
+}
+
+func TestSessionVarsUpdate(t *testing.T) {
+	sessionCache := NewLocalSessionCache(60, 3600)
+	defer sessionCache.Stop()
+	sessionRegistry := NewLocalSessionRegistry(metrics)
+
+	vars := map[string]string{"region": "eu"}
+	session := &sessionWS{id: uuid.Must(uuid.NewV4()), userID: uuid.Must(uuid.NewV4()), username: atomic.NewString("alice"), vars: atomic.NewPointer(&vars)}
+	sessionRegistry.Add(session)
+
+	token, refreshToken, err := SessionVarsUpdate(cfg, sessionCache, sessionRegistry, session.ID(), map[string]string{"region": "us", "tier": "gold"})
+	if err != nil {
+		t.Fatalf("error updating session vars: %v", err.Error())
+	}
+	assert.Equal(t, map[string]string{"region": "us", "tier": "gold"}, session.Vars())
+
+	// The rotated tokens carry the new vars and are accepted by the session cache.
+	userID, username, tokenVars, exp, tokenID, _, ok := parseToken([]byte(cfg.GetSession().EncryptionKey), token)
+	assert.True(t, ok)
+	assert.Equal(t, session.UserID(), userID)
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, map[string]string{"region": "us", "tier": "gold"}, tokenVars)
+	assert.True(t, sessionCache.IsValidSession(userID, exp, tokenID))
+	_, _, refreshVars, refreshExp, refreshTokenID, _, ok := parseToken([]byte(cfg.GetSession().RefreshEncryptionKey), refreshToken)
+	assert.True(t, ok)
+	assert.Equal(t, tokenVars, refreshVars)
+	assert.True(t, sessionCache.IsValidRefresh(userID, refreshExp, refreshTokenID))
+
+	// Clearing the vars leaves an empty map rather than nil.
+	_, _, err = SessionVarsUpdate(cfg, sessionCache, sessionRegistry, session.ID(), nil)
+	if err != nil {
+		t.Fatalf("error updating session vars: %v", err.Error())
+	}
+	assert.NotNil(t, session.Vars())
+	assert.Empty(t, session.Vars())
+
+	_, _, err = SessionVarsUpdate(cfg, sessionCache, sessionRegistry, uuid.Must(uuid.NewV4()), map[string]string{})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -50,6 +50,9 @@ type StorageOpWrites []*StorageOpWrite
 type StorageOpWrite struct {
 	OwnerID string
 	Object  *api.WriteStorageObject
+	// TTL in seconds after which the object expires, or 0 for objects that never expire. Every write replaces any
+	// previous expiry, so writing an existing object without a TTL makes it permanent.
+	TTL int64
 }
 
 // Desired `read` persmission after this Op completes
@@ -99,6 +102,18 @@ type StorageOpDelete struct {
 	ObjectID *api.DeleteStorageObjectId
 }
 
+// StorageDeleteRejectedError identifies the object whose delete was rejected, because it was not found, its stored version
+// did not match the expected version, or the caller lacked permission.
+type StorageDeleteRejectedError struct {
+	Collection string
+	Key        string
+	UserID     string
+}
+
+func (e *StorageDeleteRejectedError) Error() string {
+	return fmt.Sprintf("Storage delete rejected - not found, version check failed, or permission denied: collection %q, key %q, user ID %q.", e.Collection, e.Key, e.UserID)
+}
+
 func (s StorageOpDeletes) Len() int {
 	return len(s)
 }
@@ -191,14 +206,14 @@ func StorageListObjectsAll(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1` + cursorQuery + `
+WHERE collection = $1 AND (expire_time IS NULL OR expire_time > now())` + cursorQuery + `
 ORDER BY read ASC, key ASC, user_id ASC
 LIMIT $2`
 	} else {
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND read >= 2` + cursorQuery + `
+WHERE collection = $1 AND read >= 2 AND (expire_time IS NULL OR expire_time > now())` + cursorQuery + `
 ORDER BY read ASC, key ASC, user_id ASC
 LIMIT $2`
 	}
@@ -239,7 +254,7 @@ func StorageListObjectsPublicReadUser(ctx context.Context, logger *zap.Logger, d
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND read = 2 AND user_id = $2 ` + cursorQuery + `
+WHERE collection = $1 AND read = 2 AND user_id = $2 AND (expire_time IS NULL OR expire_time > now()) ` + cursorQuery + `
 ORDER BY key ASC
 LIMIT $3`
 
@@ -279,7 +294,7 @@ func StorageListObjectsUser(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND user_id = $2 AND read >= 1 ` + cursorQuery + `
+WHERE collection = $1 AND user_id = $2 AND read >= 1 AND (expire_time IS NULL OR expire_time > now()) ` + cursorQuery + `
 ORDER BY read ASC, key ASC
 LIMIT $3`
 	if authoritative {
@@ -287,7 +302,7 @@ LIMIT $3`
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND user_id = $2 AND read >= 0 ` + cursorQuery + `
+WHERE collection = $1 AND user_id = $2 AND read >= 0 AND (expire_time IS NULL OR expire_time > now()) ` + cursorQuery + `
 ORDER BY read ASC, key ASC
 LIMIT $3`
 	}
@@ -320,7 +335,7 @@ func StorageReadAllUserObjects(ctx context.Context, logger *zap.Logger, db *sql.
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE user_id = $1`
+WHERE user_id = $1 AND (expire_time IS NULL OR expire_time > now())`
 
 	var objects []*api.StorageObject
 	err := ExecuteRetryable(func() error {
@@ -545,6 +560,14 @@ SELECT collection, key, user_id, value, version, read, write, create_time, updat
 		params = append(params, caller)
 	}
 
+	// Objects past their expiry are not visible, even if they have not been swept yet.
+	if len(distinctArgs) == 3 && caller == uuid.Nil {
+		query += ` WHERE `
+	} else {
+		query += ` AND `
+	}
+	query += `(expire_time IS NULL OR expire_time > now())`
+
 	var objects *api.StorageObjects
 	err := ExecuteRetryablePgx(ctx, db, func(conn *pgx.Conn) error {
 		rows, _ := conn.Query(ctx, query, params...)
@@ -608,6 +631,13 @@ func StorageWriteObjects(ctx context.Context, logger *zap.Logger, db *sql.DB, me
 }
 
 func storageWriteObjects(ctx context.Context, logger *zap.Logger, metrics Metrics, tx pgx.Tx, authoritativeWrite bool, ops StorageOpWrites) (StorageOpWrites, []*api.StorageObjectAck, error) {
+	sortedOps, acks, _, err := storageWriteObjectsWithIndex(ctx, logger, metrics, tx, authoritativeWrite, ops)
+	return sortedOps, acks, err
+}
+
+// storageWriteObjectsWithIndex is storageWriteObjects, additionally returning the index in ops of the write that
+// failed, or -1 on success.
+func storageWriteObjectsWithIndex(ctx context.Context, logger *zap.Logger, metrics Metrics, tx pgx.Tx, authoritativeWrite bool, ops StorageOpWrites) (StorageOpWrites, []*api.StorageObjectAck, int, error) {
 	// Ensure writes are processed in a consistent order to avoid deadlocks from concurrent operations.
 	// Sorting done on a copy to ensure we don't modify the input, which may be re-used on transaction retries.
 	sortedOps := make(StorageOpWrites, 0, len(ops))
@@ -640,16 +670,16 @@ func storageWriteObjects(ctx context.Context, logger *zap.Logger, metrics Metric
 		if err != nil && errors.As(err, &pgErr) {
 			if pgErr.Code == dbErrorUniqueViolation {
 				metrics.StorageWriteRejectCount(map[string]string{"collection": object.Collection, "reason": "version"}, 1)
-				return nil, nil, runtime.ErrStorageRejectedVersion
+				return nil, nil, indexedOps[op], runtime.ErrStorageRejectedVersion
 			}
-			return nil, nil, err
+			return nil, nil, indexedOps[op], err
 		} else if err == pgx.ErrNoRows {
 			// Not every case from storagePrepWriteObject can return NoRows, but those
 			// which do are always ErrStorageRejectedVersion
 			metrics.StorageWriteRejectCount(map[string]string{"collection": object.Collection, "reason": "version"}, 1)
-			return nil, nil, runtime.ErrStorageRejectedVersion
+			return nil, nil, indexedOps[op], runtime.ErrStorageRejectedVersion
 		} else if err != nil {
-			return nil, nil, err
+			return nil, nil, indexedOps[op], err
 		}
 
 		if !isUpsert {
@@ -658,11 +688,11 @@ func storageWriteObjects(ctx context.Context, logger *zap.Logger, metrics Metric
 			if !authoritativeWrite && resultWrite != 1 {
 				// - permission: non-authoritative write & original row write != 1
 				metrics.StorageWriteRejectCount(map[string]string{"collection": object.Collection, "reason": "permission"}, 1)
-				return nil, nil, runtime.ErrStorageRejectedPermission
+				return nil, nil, indexedOps[op], runtime.ErrStorageRejectedPermission
 			} else if object.Version != "" {
 				// - version mismatch
 				metrics.StorageWriteRejectCount(map[string]string{"collection": object.Collection, "reason": "version"}, 1)
-				return nil, nil, runtime.ErrStorageRejectedVersion
+				return nil, nil, indexedOps[op], runtime.ErrStorageRejectedVersion
 			}
 		}
 
@@ -677,7 +707,7 @@ func storageWriteObjects(ctx context.Context, logger *zap.Logger, metrics Metric
 		acks[indexedOps[op]] = ack
 	}
 
-	return sortedOps, acks, nil
+	return sortedOps, acks, -1, nil
 }
 
 func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWrite) {
@@ -688,9 +718,12 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 	newPermissionRead := op.permissionRead()
 	newPermissionWrite := op.permissionWrite()
 
-	params := []interface{}{object.Collection, object.Key, ownerID, object.Value, newVersion, newPermissionRead, newPermissionWrite}
+	params := []interface{}{object.Collection, object.Key, ownerID, object.Value, newVersion, newPermissionRead, newPermissionWrite, op.TTL}
 	var query string
 
+	// No expiry unless a TTL is given, in which case it is relative to the time of the write.
+	expireTime := "CASE WHEN $8 > 0 THEN now() + $8 * INTERVAL '1 second' END"
+
 	writeCheck := ""
 	// Respect permissions in non-authoritative writes.
 	if !authoritativeWrite {
@@ -708,8 +741,8 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 		// That is returned values are final state of the row regardless of UPDATE success
 		query = `
 		WITH upd AS (
-			UPDATE storage SET value = $4, version = $5, read = $6, write = $7, update_time = now()
-			WHERE collection = $1 AND key = $2 AND user_id = $3 AND version = $8
+			UPDATE storage SET value = $4, version = $5, read = $6, write = $7, update_time = now(), expire_time = ` + expireTime + `
+			WHERE collection = $1 AND key = $2 AND user_id = $3 AND version = $9 AND (storage.expire_time IS NULL OR storage.expire_time > now())
 		` + writeCheck + `
 			RETURNING read, write, version, create_time, update_time
 		)
@@ -734,12 +767,12 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 		// check for existing row.
 		query = `
 		WITH upd AS (
-			INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+			INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time, expire_time)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now(), ` + expireTime + `)
 			ON CONFLICT (collection, key, user_id) DO
-				UPDATE SET value = $4, version = $5, read = $6, write = $7, update_time = now()
+				UPDATE SET value = $4, version = $5, read = $6, write = $7, update_time = now(), expire_time = ` + expireTime + `
 				WHERE TRUE` + writeCheck + `
-				AND NOT (storage.version = $5 AND storage.read = $6 AND storage.write = $7) -- micro optimization: don't update row unnecessarily
+				AND NOT (storage.version = $5 AND storage.read = $6 AND storage.write = $7 AND $8 = 0 AND storage.expire_time IS NULL) -- micro optimization: don't update row unnecessarily
 			RETURNING read, write, version, create_time, update_time
 		)
 		(SELECT read, write, version, create_time, update_time, true AS upsert FROM upd)
@@ -754,9 +787,13 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 	case object.Version == "*":
 		// OCC if-not-exists, and all other non-OCC cases.
 		// Existing permission checks are not applicable for new storage objects.
+		// An expired object that has not been swept yet does not count as existing.
 		query = `
-		INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+		INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time, expire_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now(), ` + expireTime + `)
+		ON CONFLICT (collection, key, user_id) DO
+			UPDATE SET value = $4, version = $5, read = $6, write = $7, create_time = now(), update_time = now(), expire_time = ` + expireTime + `
+			WHERE storage.expire_time <= now()
 		RETURNING read, write, version, create_time, update_time, true AS upsert`
 
 		// Outcomes:
@@ -819,7 +856,7 @@ func storageDeleteObjects(ctx context.Context, logger *zap.Logger, tx pgx.Tx, au
 			continue
 		}
 		if rowsAffected := result.RowsAffected(); rowsAffected == 0 {
-			return StatusError(codes.InvalidArgument, "Storage delete rejected.", errors.New("Storage delete rejected - not found, version check failed, or permission denied."))
+			return StatusError(codes.InvalidArgument, "Storage delete rejected.", &StorageDeleteRejectedError{Collection: op.ObjectID.Collection, Key: op.ObjectID.Key, UserID: op.OwnerID})
 		}
 	}
 
@@ -844,3 +881,69 @@ func storageIndexWrite(ctx context.Context, storageIndex StorageIndex, ops Stora
 
 	storageIndex.Write(ctx, sw)
 }
+
+const (
+	storageExpirySweepInterval  = time.Minute
+	storageExpirySweepBatchSize = 1_000
+)
+
+// StartStorageExpirySweeper periodically deletes storage objects whose TTL has elapsed, until the context is cancelled.
+// Expired objects are already excluded from reads and listings, so sweeping only reclaims space and index entries.
+func StartStorageExpirySweeper(ctx context.Context, logger *zap.Logger, db *sql.DB, storageIndex StorageIndex) {
+	go func() {
+		ticker := time.NewTicker(storageExpirySweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := storageSweepExpired(ctx, logger, db, storageIndex); err != nil && ctx.Err() == nil {
+					logger.Error("Error sweeping expired storage objects", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func storageSweepExpired(ctx context.Context, logger *zap.Logger, db *sql.DB, storageIndex StorageIndex) error {
+	query := `
+DELETE FROM storage WHERE (collection, key, user_id) IN (
+	SELECT collection, key, user_id FROM storage WHERE expire_time <= now() LIMIT $1
+)
+RETURNING collection, key, user_id`
+
+	for {
+		rows, err := db.QueryContext(ctx, query, storageExpirySweepBatchSize)
+		if err != nil {
+			return err
+		}
+
+		ops := make(StorageOpDeletes, 0, storageExpirySweepBatchSize)
+		for rows.Next() {
+			var collection, key string
+			var userID uuid.UUID
+			if err := rows.Scan(&collection, &key, &userID); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			ops = append(ops, &StorageOpDelete{
+				OwnerID:  userID.String(),
+				ObjectID: &api.DeleteStorageObjectId{Collection: collection, Key: key},
+			})
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ops) > 0 {
+			storageIndex.Delete(ctx, ops)
+			logger.Debug("Swept expired storage objects", zap.Int("count", len(ops)))
+		}
+		if len(ops) < storageExpirySweepBatchSize {
+			return nil
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"crypto/md5"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -2758,3 +2759,117 @@ func TestStorageReadObjectsAllDistinctArgs(t *testing.T) {
 	assert.ElementsMatch(t, []string{key1, key2}, []string{readData.Objects[0].Key, readData.Objects[1].Key}, "key did not match")
 	assert.ElementsMatch(t, []string{uid1.String(), uid2.String()}, []string{readData.Objects[0].UserId, readData.Objects[1].UserId}, "user id did not match")
 }
+
+func TestStorageWriteExpiredObjects(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, uid)
+	key := GenerateString()
+
+	ops := StorageOpWrites{&StorageOpWrite{
+		OwnerID: uid.String(),
+		Object: &api.WriteStorageObject{
+			Collection:      "testcollection",
+			Key:             key,
+			Value:           "{\"foo\":\"bar\"}",
+			PermissionRead:  &wrapperspb.Int32Value{Value: 1},
+			PermissionWrite: &wrapperspb.Int32Value{Value: 1},
+		},
+		TTL: 60,
+	}}
+	_, code, err := StorageWriteObjects(ctx, logger, db, metrics, storageIdx, true, ops)
+	assert.Nil(t, err, "err was not nil")
+	assert.Equal(t, codes.OK, code, "code was not 0")
+
+	ids := []*api.ReadStorageObjectId{{Collection: "testcollection", Key: key, UserId: uid.String()}}
+	readData, err := StorageReadObjects(ctx, logger, db, uid, ids)
+	assert.Nil(t, err, "err was not nil")
+	assert.Len(t, readData.Objects, 1, "unexpired object was not read")
+
+	if _, err := db.ExecContext(ctx, "UPDATE storage SET expire_time = now() - INTERVAL '1 second' WHERE collection = 'testcollection' AND key = $1 AND user_id = $2", key, uid); err != nil {
+		t.Fatalf("error expiring object: %v", err.Error())
+	}
+
+	readData, err = StorageReadObjects(ctx, logger, db, uid, ids)
+	assert.Nil(t, err, "err was not nil")
+	assert.Len(t, readData.Objects, 0, "expired object was read")
+
+	// An expired object does not prevent an if-not-exists write.
+	ops[0].Object.Version = "*"
+	ops[0].TTL = 0
+	_, code, err = StorageWriteObjects(ctx, logger, db, metrics, storageIdx, true, ops)
+	assert.Nil(t, err, "err was not nil")
+	assert.Equal(t, codes.OK, code, "code was not 0")
+
+	if err := storageSweepExpired(ctx, logger, db, storageIdx); err != nil {
+		t.Fatalf("error sweeping expired objects: %v", err.Error())
+	}
+
+	readData, err = StorageReadObjects(ctx, logger, db, uid, ids)
+	assert.Nil(t, err, "err was not nil")
+	assert.Len(t, readData.Objects, 1, "permanent object was swept")
+}
+
+func TestStorageRemoveRuntimeStaleVersionRejected(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	uid := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, uid)
+
+	staleKey := GenerateString()
+	otherKey := GenerateString()
+	write := func(key, value string) *api.StorageObjectAck {
+		acks, _, err := StorageWriteObjects(context.Background(), logger, db, metrics, storageIdx, true, StorageOpWrites{
+			&StorageOpWrite{
+				OwnerID: uid.String(),
+				Object: &api.WriteStorageObject{
+					Collection: "testcollection",
+					Key:        key,
+					Value:      value,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("error writing storage object: %v", err.Error())
+		}
+		return acks.Acks[0]
+	}
+	stale := write(staleKey, `{"foo":"bar"}`)
+	current := write(staleKey, `{"foo":"baz"}`)
+	assert.NotEqual(t, stale.Version, current.Version, "version did not change")
+	other := write(otherKey, `{}`)
+
+	deleteOps := StorageOpDeletes{
+		&StorageOpDelete{
+			OwnerID:  uid.String(),
+			ObjectID: &api.DeleteStorageObjectId{Collection: "testcollection", Key: otherKey, Version: other.Version},
+		},
+		&StorageOpDelete{
+			OwnerID:  uid.String(),
+			ObjectID: &api.DeleteStorageObjectId{Collection: "testcollection", Key: staleKey, Version: stale.Version},
+		},
+	}
+
+	code, err := StorageDeleteObjects(context.Background(), logger, db, storageIdx, true, deleteOps)
+	assert.Equal(t, codes.InvalidArgument, code, "code did not match InvalidArgument.")
+	var rejectedErr *StorageDeleteRejectedError
+	if !errors.As(err, &rejectedErr) {
+		t.Fatalf("expected storage delete rejected error, got: %v", err)
+	}
+	assert.Equal(t, staleKey, rejectedErr.Key, "rejected key did not match")
+	assert.Equal(t, uid.String(), rejectedErr.UserID, "rejected user id did not match")
+
+	// The rejected delete must not remove any object in the batch.
+	objects, err := StorageReadObjects(context.Background(), logger, db, uuid.Nil, []*api.ReadStorageObjectId{
+		{Collection: "testcollection", Key: staleKey, UserId: uid.String()},
+		{Collection: "testcollection", Key: otherKey, UserId: uid.String()},
+	})
+	if err != nil {
+		t.Fatalf("error reading storage objects: %v", err.Error())
+	}
+	assert.Len(t, objects.Objects, 2, "objects were deleted")
+}
@@ -46,6 +46,102 @@ import (
 var ErrSubscriptionsListInvalidCursor = errors.New("subscriptions list cursor invalid")
 var ErrSubscriptionNotFound = errors.New("subscription not found")
 
+// SubscriptionStatus refines the active flag of a validated subscription with the provider's billing state, so that
+// subscriptions in a billing retry window can be told apart from those that have lapsed.
+type SubscriptionStatus int
+
+const (
+	SubscriptionStatusActive SubscriptionStatus = iota
+	// Payment failed but the provider is retrying it, and the user should keep access until the grace period ends.
+	SubscriptionStatusGracePeriod
+	// Payment failed and the grace period, if any, has ended. Access should be suspended, but the provider is still
+	// retrying and the subscription is recovered if the payment succeeds.
+	SubscriptionStatusOnHold
+	SubscriptionStatusPaused
+	SubscriptionStatusExpired
+	SubscriptionStatusRefunded
+)
+
+func (s SubscriptionStatus) String() string {
+	switch s {
+	case SubscriptionStatusActive:
+		return "active"
+	case SubscriptionStatusGracePeriod:
+		return "grace_period"
+	case SubscriptionStatusOnHold:
+		return "on_hold"
+	case SubscriptionStatusPaused:
+		return "paused"
+	case SubscriptionStatusExpired:
+		return "expired"
+	case SubscriptionStatusRefunded:
+		return "refunded"
+	}
+
+	return ""
+}
+
+// Google Play subscription payment states, see https://developers.google.com/android-publisher/api-ref/rest/v3/purchases.subscriptions
+const googleSubscriptionPaymentStatePending = 0
+
+// GetSubscriptionStatus derives the status of a subscription from its expiry and refund times, and the grace period,
+// billing retry and pause state found in the provider's validation response. If the provider response cannot be
+// parsed the status falls back to active or expired based on the expiry time alone.
+func GetSubscriptionStatus(subscription *api.ValidatedSubscription, now time.Time) SubscriptionStatus {
+	if subscription.RefundTime != nil && subscription.RefundTime.AsTime().Unix() > 0 {
+		return SubscriptionStatusRefunded
+	}
+
+	expired := subscription.ExpiryTime == nil || !subscription.ExpiryTime.AsTime().After(now)
+
+	switch subscription.Store {
+	case api.StoreProvider_APPLE_APP_STORE:
+		if !expired {
+			return SubscriptionStatusActive
+		}
+		var validation iap.ValidateReceiptAppleResponse
+		if err := json.Unmarshal([]byte(subscription.ProviderResponse), &validation); err != nil {
+			break
+		}
+		for _, renewal := range validation.PendingRenewalInfo {
+			if renewal.OriginalTransactionId != subscription.OriginalTransactionId {
+				continue
+			}
+			if renewal.GracePeriodExpiresDateMs != "" {
+				if gracePeriodExpires, err := strconv.ParseInt(renewal.GracePeriodExpiresDateMs, 10, 64); err == nil && parseMillisecondUnixTimestamp(gracePeriodExpires).After(now) {
+					return SubscriptionStatusGracePeriod
+				}
+			}
+			if renewal.IsInBillingRetryPeriod == "1" {
+				return SubscriptionStatusOnHold
+			}
+		}
+	case api.StoreProvider_GOOGLE_PLAY_STORE:
+		var validation iap.ValidateSubscriptionReceiptGoogleResponse
+		if err := json.Unmarshal([]byte(subscription.ProviderResponse), &validation); err != nil {
+			break
+		}
+		// Google extends the expiry time to the end of the grace period while the payment is pending.
+		if !expired {
+			if validation.PaymentState == googleSubscriptionPaymentStatePending && validation.AutoRenewing {
+				return SubscriptionStatusGracePeriod
+			}
+			return SubscriptionStatusActive
+		}
+		if validation.AutoResumeTimeMillis != "" {
+			return SubscriptionStatusPaused
+		}
+		if validation.PaymentState == googleSubscriptionPaymentStatePending && validation.AutoRenewing {
+			return SubscriptionStatusOnHold
+		}
+	}
+
+	if expired {
+		return SubscriptionStatusExpired
+	}
+	return SubscriptionStatusActive
+}
+
 type subscriptionsListCursor struct {
 	OriginalTransactionId string
 	PurchaseTime          *timestamppb.Timestamp
@@ -495,6 +591,150 @@ WHERE
 	}, nil
 }
 
+type subscriptionsListByProductCursor struct {
+	ProductId             string
+	ActiveOnly            bool
+	PurchaseTime          *timestamppb.Timestamp
+	OriginalTransactionId string
+}
+
+// ListSubscriptionsByProduct lists stored validated subscriptions for a product across all users, most recently
+// purchased first. If activeOnly is set, expired and refunded subscriptions are skipped.
+func ListSubscriptionsByProduct(ctx context.Context, logger *zap.Logger, db *sql.DB, productID string, activeOnly bool, limit int, cursor string) (*api.SubscriptionList, error) {
+	var incomingCursor *subscriptionsListByProductCursor
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, ErrSubscriptionsListInvalidCursor
+		}
+		incomingCursor = &subscriptionsListByProductCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, ErrSubscriptionsListInvalidCursor
+		}
+		if productID != incomingCursor.ProductId || activeOnly != incomingCursor.ActiveOnly {
+			// Filters have changed, cursor is now invalid.
+			return nil, ErrSubscriptionsListInvalidCursor
+		}
+	}
+
+	if limit <= 0 {
+		limit = 100 // Default limit to 100 subscriptions if not set
+	}
+
+	now := time.Now().UTC()
+	params := []interface{}{productID}
+	predicateConf := "product_id = $1"
+	if activeOnly {
+		params = append(params, now, time.Unix(0, 0).UTC())
+		predicateConf += " AND expire_time > $2 AND refund_time <= $3"
+	}
+	if incomingCursor != nil {
+		params = append(params, incomingCursor.PurchaseTime.AsTime(), incomingCursor.OriginalTransactionId)
+		predicateConf += fmt.Sprintf(" AND (purchase_time, original_transaction_id) < ($%v, $%v)", len(params)-1, len(params))
+	}
+	params = append(params, limit+1)
+
+	query := fmt.Sprintf(`
+SELECT
+	original_transaction_id,
+	user_id,
+	product_id,
+	store,
+	purchase_time,
+	create_time,
+	update_time,
+	expire_time,
+	refund_time,
+	environment,
+	raw_response,
+	raw_notification
+FROM
+	subscription
+WHERE %s
+ORDER BY purchase_time DESC, original_transaction_id DESC
+LIMIT $%v`, predicateConf, len(params))
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error retrieving subscriptions.", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nextCursor *subscriptionsListByProductCursor
+	subscriptions := make([]*api.ValidatedSubscription, 0, limit)
+
+	for rows.Next() {
+		var originalTransactionId string
+		var dbUserID uuid.UUID
+		var dbProductID string
+		var store api.StoreProvider
+		var purchaseTime pgtype.Timestamptz
+		var createTime pgtype.Timestamptz
+		var updateTime pgtype.Timestamptz
+		var expireTime pgtype.Timestamptz
+		var refundTime pgtype.Timestamptz
+		var environment api.StoreEnvironment
+		var rawResponse string
+		var rawNotification string
+
+		if err = rows.Scan(&originalTransactionId, &dbUserID, &dbProductID, &store, &purchaseTime, &createTime, &updateTime, &expireTime, &refundTime, &environment, &rawResponse, &rawNotification); err != nil {
+			logger.Error("Error retrieving subscriptions.", zap.Error(err))
+			return nil, err
+		}
+
+		if len(subscriptions) >= limit {
+			last := subscriptions[len(subscriptions)-1]
+			nextCursor = &subscriptionsListByProductCursor{
+				ProductId:             productID,
+				ActiveOnly:            activeOnly,
+				PurchaseTime:          last.PurchaseTime,
+				OriginalTransactionId: last.OriginalTransactionId,
+			}
+			break
+		}
+
+		active := expireTime.Time.After(now) && refundTime.Time.Unix() <= 0
+
+		suid := dbUserID.String()
+		if dbUserID.IsNil() {
+			suid = ""
+		}
+
+		subscriptions = append(subscriptions, &api.ValidatedSubscription{
+			UserId:                suid,
+			ProductId:             dbProductID,
+			OriginalTransactionId: originalTransactionId,
+			Store:                 store,
+			PurchaseTime:          timestamppb.New(purchaseTime.Time),
+			CreateTime:            timestamppb.New(createTime.Time),
+			UpdateTime:            timestamppb.New(updateTime.Time),
+			ExpiryTime:            timestamppb.New(expireTime.Time),
+			RefundTime:            timestamppb.New(refundTime.Time),
+			Active:                active,
+			Environment:           environment,
+			ProviderResponse:      rawResponse,
+			ProviderNotification:  rawNotification,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error retrieving subscriptions.", zap.Error(err))
+		return nil, err
+	}
+
+	var nextCursorStr string
+	if nextCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(nextCursor); err != nil {
+			logger.Error("Error creating subscriptions list cursor", zap.Error(err))
+			return nil, err
+		}
+		nextCursorStr = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return &api.SubscriptionList{ValidatedSubscriptions: subscriptions, Cursor: nextCursorStr}, nil
+}
+
 func getSubscriptionByOriginalTransactionId(ctx context.Context, logger *zap.Logger, db *sql.DB, originalTransactionId string) (*api.ValidatedSubscription, error) {
 	var (
 		dbUserId                uuid.UUID
@@ -693,7 +933,7 @@ type appleNotificationTransactionInfo struct {
 const AppleNotificationTypeRefund = "REFUND"
 
 // Store providers notification callback handler functions
-func appleNotificationHandler(logger *zap.Logger, db *sql.DB, purchaseNotificationCallback RuntimePurchaseNotificationAppleFunction, subscriptionNotificationCallback RuntimeSubscriptionNotificationAppleFunction) http.HandlerFunc {
+func appleNotificationHandler(logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, purchaseNotificationCallback RuntimePurchaseNotificationAppleFunction, subscriptionNotificationCallback RuntimeSubscriptionNotificationAppleFunction) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -947,7 +1187,7 @@ func appleNotificationHandler(logger *zap.Logger, db *sql.DB, purchaseNotificati
 					environment:   env,
 				}
 
-				dbPurchases, err := upsertPurchases(ctx, db, []*storagePurchase{purchase})
+				dbPurchases, err := upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{purchase})
 				if err != nil {
 					logger.Error("Failed to store App Store notification purchase data")
 					w.WriteHeader(http.StatusInternalServerError)
@@ -999,11 +1239,13 @@ type googleStoreNotificationMessage struct {
 }
 
 type googleDeveloperNotification struct {
-	Version                  string                          `json:"version"`
-	PackageName              string                          `json:"packageName"`
-	EventTimeMillis          string                          `json:"eventTimeMillis"`
-	SubscriptionNotification *googleSubscriptionNotification `json:"subscriptionNotification"`
-	TestNotification         map[string]string               `json:"testNotification"`
+	Version                    string                            `json:"version"`
+	PackageName                string                            `json:"packageName"`
+	EventTimeMillis            string                            `json:"eventTimeMillis"`
+	SubscriptionNotification   *googleSubscriptionNotification   `json:"subscriptionNotification"`
+	OneTimeProductNotification *googleOneTimeProductNotification `json:"oneTimeProductNotification"`
+	VoidedPurchaseNotification *googleVoidedPurchaseNotification `json:"voidedPurchaseNotification"`
+	TestNotification           map[string]string                 `json:"testNotification"`
 }
 
 type googleSubscriptionNotification struct {
@@ -1013,6 +1255,193 @@ type googleSubscriptionNotification struct {
 	SubscriptionId   string `json:"subscriptionId"`
 }
 
+type googleOneTimeProductNotification struct {
+	Version          string `json:"version"`
+	NotificationType int    `json:"notificationType"`
+	PurchaseToken    string `json:"purchaseToken"`
+	Sku              string `json:"sku"`
+}
+
+type googleVoidedPurchaseNotification struct {
+	PurchaseToken string `json:"purchaseToken"`
+	OrderId       string `json:"orderId"`
+	ProductType   int    `json:"productType"`
+	RefundType    int    `json:"refundType"`
+}
+
+// Google Play Billing notification types, see https://developer.android.com/google/play/billing/rtdn-reference
+const (
+	googleSubscriptionNotificationOnHold  = 5
+	googleSubscriptionNotificationPaused  = 10
+	googleSubscriptionNotificationRevoked = 12
+	googleSubscriptionNotificationExpired = 13
+
+	googleOneTimeProductNotificationCanceled = 2
+
+	googleVoidedPurchaseProductTypeSubscription = 1
+	googleVoidedPurchaseProductTypeOneTime      = 2
+)
+
+var ErrGoogleNotificationInvalid = errors.New("google notification invalid")
+var ErrGoogleNotificationUnknownToken = errors.New("google notification purchase token not found")
+
+// IngestGoogleNotification applies a decoded Google Play Billing Real-Time Developer Notification to the stored
+// purchase or subscription it references. Revocations and voided purchases mark the record as refunded, while
+// expirations, holds and pauses end the subscription at the event time. The notification must reference a purchase
+// token that was previously validated and stored, otherwise ErrGoogleNotificationUnknownToken is returned.
+// Exactly one of the returned purchase or subscription is set.
+func IngestGoogleNotification(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, payload string) (*api.ValidatedPurchase, *api.ValidatedSubscription, error) {
+	var notification *googleDeveloperNotification
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil || notification == nil {
+		return nil, nil, ErrGoogleNotificationInvalid
+	}
+
+	eventTime := time.Now().UTC()
+	if notification.EventTimeMillis != "" {
+		eventTimeMillis, err := strconv.ParseInt(notification.EventTimeMillis, 10, 64)
+		if err != nil {
+			return nil, nil, ErrGoogleNotificationInvalid
+		}
+		eventTime = parseMillisecondUnixTimestamp(eventTimeMillis)
+	}
+
+	switch {
+	case notification.SubscriptionNotification != nil:
+		var refunded, ended bool
+		switch notification.SubscriptionNotification.NotificationType {
+		case googleSubscriptionNotificationRevoked:
+			refunded, ended = true, true
+		case googleSubscriptionNotificationExpired, googleSubscriptionNotificationOnHold, googleSubscriptionNotificationPaused:
+			ended = true
+		}
+		subscription, err := ingestGoogleSubscriptionNotification(ctx, logger, db, notification.SubscriptionNotification.PurchaseToken, payload, eventTime, refunded, ended)
+		return nil, subscription, err
+	case notification.OneTimeProductNotification != nil:
+		refunded := notification.OneTimeProductNotification.NotificationType == googleOneTimeProductNotificationCanceled
+		purchase, err := ingestGooglePurchaseNotification(ctx, logger, db, refundFn, notification.OneTimeProductNotification.PurchaseToken, eventTime, refunded)
+		return purchase, nil, err
+	case notification.VoidedPurchaseNotification != nil:
+		switch notification.VoidedPurchaseNotification.ProductType {
+		case googleVoidedPurchaseProductTypeSubscription:
+			subscription, err := ingestGoogleSubscriptionNotification(ctx, logger, db, notification.VoidedPurchaseNotification.PurchaseToken, payload, eventTime, true, true)
+			return nil, subscription, err
+		case googleVoidedPurchaseProductTypeOneTime:
+			purchase, err := ingestGooglePurchaseNotification(ctx, logger, db, refundFn, notification.VoidedPurchaseNotification.PurchaseToken, eventTime, true)
+			return purchase, nil, err
+		}
+	}
+
+	// Test notifications and unknown notification kinds do not reference a stored purchase.
+	return nil, nil, ErrGoogleNotificationInvalid
+}
+
+func ingestGoogleSubscriptionNotification(ctx context.Context, logger *zap.Logger, db *sql.DB, purchaseToken, payload string, eventTime time.Time, refunded, ended bool) (*api.ValidatedSubscription, error) {
+	if purchaseToken == "" {
+		return nil, ErrGoogleNotificationInvalid
+	}
+
+	sub, err := getSubscriptionByOriginalTransactionId(ctx, logger, db, purchaseToken)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil || sub.Store != api.StoreProvider_GOOGLE_PLAY_STORE {
+		return nil, ErrGoogleNotificationUnknownToken
+	}
+
+	var userID uuid.UUID
+	if sub.UserId != "" {
+		userID = uuid.FromStringOrNil(sub.UserId)
+	}
+
+	storageSub := &storageSubscription{
+		originalTransactionId: sub.OriginalTransactionId,
+		userID:                userID,
+		store:                 sub.Store,
+		productId:             sub.ProductId,
+		purchaseTime:          sub.PurchaseTime.AsTime(),
+		environment:           sub.Environment,
+		expireTime:            sub.ExpiryTime.AsTime(),
+		refundTime:            sub.RefundTime.AsTime(),
+		rawNotification:       payload,
+	}
+	if refunded && storageSub.refundTime.Unix() <= 0 {
+		storageSub.refundTime = eventTime
+	}
+	if ended && eventTime.Before(storageSub.expireTime) {
+		storageSub.expireTime = eventTime
+	}
+
+	if err = upsertSubscription(ctx, db, storageSub); err != nil {
+		logger.Error("Failed to store Google Play Billing notification subscription data", zap.Error(err))
+		return nil, err
+	}
+
+	suid := storageSub.userID.String()
+	if storageSub.userID.IsNil() {
+		suid = ""
+	}
+
+	return &api.ValidatedSubscription{
+		UserId:                suid,
+		ProductId:             storageSub.productId,
+		OriginalTransactionId: storageSub.originalTransactionId,
+		Store:                 storageSub.store,
+		PurchaseTime:          timestamppb.New(storageSub.purchaseTime),
+		CreateTime:            timestamppb.New(storageSub.createTime),
+		UpdateTime:            timestamppb.New(storageSub.updateTime),
+		Environment:           storageSub.environment,
+		ExpiryTime:            timestamppb.New(storageSub.expireTime),
+		RefundTime:            timestamppb.New(storageSub.refundTime),
+		ProviderResponse:      storageSub.rawResponse,
+		ProviderNotification:  storageSub.rawNotification,
+		Active:                storageSub.expireTime.After(time.Now()) && storageSub.refundTime.Unix() <= 0,
+	}, nil
+}
+
+func ingestGooglePurchaseNotification(ctx context.Context, logger *zap.Logger, db *sql.DB, refundFn PurchaseRefundFunction, purchaseToken string, eventTime time.Time, refunded bool) (*api.ValidatedPurchase, error) {
+	if purchaseToken == "" {
+		return nil, ErrGoogleNotificationInvalid
+	}
+
+	purchase, err := GetPurchaseByTransactionId(ctx, logger, db, purchaseToken)
+	if err != nil {
+		return nil, err
+	}
+	if purchase == nil || purchase.Store != api.StoreProvider_GOOGLE_PLAY_STORE {
+		return nil, ErrGoogleNotificationUnknownToken
+	}
+
+	var userID uuid.UUID
+	if purchase.UserId != "" {
+		userID = uuid.FromStringOrNil(purchase.UserId)
+	}
+
+	sPurchase := &storagePurchase{
+		userID:        userID,
+		store:         purchase.Store,
+		productId:     purchase.ProductId,
+		transactionId: purchase.TransactionId,
+		rawResponse:   purchase.ProviderResponse,
+		purchaseTime:  purchase.PurchaseTime.AsTime(),
+		refundTime:    purchase.RefundTime.AsTime(),
+		environment:   purchase.Environment,
+	}
+	if refunded && sPurchase.refundTime.Unix() <= 0 {
+		sPurchase.refundTime = eventTime
+	}
+
+	if _, err = upsertPurchases(ctx, logger, db, refundFn, []*storagePurchase{sPurchase}); err != nil {
+		logger.Error("Failed to store Google Play Billing notification purchase data", zap.Error(err))
+		return nil, err
+	}
+
+	purchase.RefundTime = timestamppb.New(sPurchase.refundTime)
+	purchase.UpdateTime = timestamppb.New(sPurchase.updateTime)
+	purchase.SeenBefore = true
+
+	return purchase, nil
+}
+
 func googleNotificationHandler(logger *zap.Logger, db *sql.DB, config *IAPGoogleConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
@@ -0,0 +1,81 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetSubscriptionStatus(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	ms := func(t time.Time) string {
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+
+	appleResponse := func(gracePeriodExpires, billingRetry string) string {
+		return `{"status": 0, "pending_renewal_info": [{"original_transaction_id": "1000", "grace_period_expires_date_ms": "` + gracePeriodExpires + `", "is_in_billing_retry_period": "` + billingRetry + `"}]}`
+	}
+	googleResponse := func(paymentState int, autoRenewing bool, autoResume string) string {
+		return `{"paymentState": ` + strconv.Itoa(paymentState) + `, "autoRenewing": ` + strconv.FormatBool(autoRenewing) + `, "autoResumeTimeMillis": "` + autoResume + `"}`
+	}
+
+	tests := []struct {
+		name       string
+		store      api.StoreProvider
+		expiry     time.Time
+		refundTime time.Time
+		response   string
+		expected   SubscriptionStatus
+	}{
+		{"apple active", api.StoreProvider_APPLE_APP_STORE, future, time.Time{}, appleResponse("", "0"), SubscriptionStatusActive},
+		{"apple grace period", api.StoreProvider_APPLE_APP_STORE, past, time.Time{}, appleResponse(ms(future), "1"), SubscriptionStatusGracePeriod},
+		{"apple billing retry after grace period", api.StoreProvider_APPLE_APP_STORE, past, time.Time{}, appleResponse(ms(past), "1"), SubscriptionStatusOnHold},
+		{"apple billing retry", api.StoreProvider_APPLE_APP_STORE, past, time.Time{}, appleResponse("", "1"), SubscriptionStatusOnHold},
+		{"apple expired", api.StoreProvider_APPLE_APP_STORE, past, time.Time{}, appleResponse("", "0"), SubscriptionStatusExpired},
+		{"apple refunded", api.StoreProvider_APPLE_APP_STORE, future, past, appleResponse("", "0"), SubscriptionStatusRefunded},
+		{"google active", api.StoreProvider_GOOGLE_PLAY_STORE, future, time.Time{}, googleResponse(1, true, ""), SubscriptionStatusActive},
+		{"google free trial", api.StoreProvider_GOOGLE_PLAY_STORE, future, time.Time{}, googleResponse(2, true, ""), SubscriptionStatusActive},
+		{"google grace period", api.StoreProvider_GOOGLE_PLAY_STORE, future, time.Time{}, googleResponse(0, true, ""), SubscriptionStatusGracePeriod},
+		{"google account hold", api.StoreProvider_GOOGLE_PLAY_STORE, past, time.Time{}, googleResponse(0, true, ""), SubscriptionStatusOnHold},
+		{"google paused", api.StoreProvider_GOOGLE_PLAY_STORE, past, time.Time{}, googleResponse(1, true, ms(future)), SubscriptionStatusPaused},
+		{"google expired", api.StoreProvider_GOOGLE_PLAY_STORE, past, time.Time{}, googleResponse(1, false, ""), SubscriptionStatusExpired},
+		{"google refunded", api.StoreProvider_GOOGLE_PLAY_STORE, future, past, googleResponse(1, true, ""), SubscriptionStatusRefunded},
+		{"unparseable response active", api.StoreProvider_GOOGLE_PLAY_STORE, future, time.Time{}, "", SubscriptionStatusActive},
+		{"unparseable response expired", api.StoreProvider_APPLE_APP_STORE, past, time.Time{}, "", SubscriptionStatusExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription := &api.ValidatedSubscription{
+				OriginalTransactionId: "1000",
+				Store:                 tt.store,
+				ExpiryTime:            timestamppb.New(tt.expiry),
+				ProviderResponse:      tt.response,
+			}
+			if !tt.refundTime.IsZero() {
+				subscription.RefundTime = timestamppb.New(tt.refundTime)
+			}
+			assert.Equal(t, tt.expected.String(), GetSubscriptionStatus(subscription, now).String())
+		})
+	}
+}
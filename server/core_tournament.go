@@ -20,7 +20,9 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/gob"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -29,6 +31,7 @@ import (
 	"github.com/heroiclabs/nakama-common/runtime"
 	"github.com/heroiclabs/nakama/v3/internal/cronexpr"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
@@ -39,6 +42,9 @@ import (
 // Internal error used to signal out of transactional wrappers.
 var errTournamentWriteNoop = errors.New("tournament write noop")
 
+var ErrTournamentRecordNotFound = errors.New("tournament record not found")
+var ErrTournamentEntryCostJoinNotRequired = errors.New("tournament entry cost requires a tournament with join required")
+
 type TournamentListCursor struct {
 	Id string
 }
@@ -108,19 +114,92 @@ func TournamentAddAttempt(ctx context.Context, logger *zap.Logger, db *sql.DB, c
 	return nil
 }
 
+// TournamentAddAttemptWithMode adjusts the owner's remaining score attempts for the current tournament period and
+// returns the resulting remaining count. By default count is a delta added to the owner's attempt allowance, if absolute
+// is true it is instead the exact number of remaining attempts to leave the owner with, so the allowance is set to the
+// owner's submitted scores plus count, clamped to the tournament's configured max. Returns
+// ErrTournamentRecordNotFound if the owner has no record for the current period.
+func TournamentAddAttemptWithMode(ctx context.Context, logger *zap.Logger, db *sql.DB, cache LeaderboardCache, leaderboardId string, owner string, count int, absolute bool) (int, error) {
+	if absolute && count < 0 {
+		return 0, errors.New("absolute attempt count must be 0 or greater")
+	}
+	leaderboard := cache.Get(leaderboardId)
+	if leaderboard == nil {
+		// If it does not exist treat it as success.
+		return 0, runtime.ErrTournamentNotFound
+	}
+	if !leaderboard.IsTournament() {
+		// Leaderboard exists but is not a tournament, treat it as success.
+		return 0, runtime.ErrTournamentNotFound
+	}
+
+	nowTime := time.Now().UTC()
+	nowUnix := nowTime.Unix()
+
+	_, endActive, expiryTime := calculateTournamentDeadlines(leaderboard.StartTime, leaderboard.EndTime, int64(leaderboard.Duration), leaderboard.ResetSchedule, nowTime)
+	if endActive <= nowUnix {
+		logger.Info("Cannot add attempt outside of tournament duration.")
+		return 0, runtime.ErrTournamentOutsideDuration
+	}
+
+	// Both modes resolve in a single statement, so corrections never race with concurrent score submissions.
+	query := `UPDATE leaderboard_record SET max_num_score = (max_num_score + $1) WHERE leaderboard_id = $2 AND owner_id = $3 AND expiry_time = $4 RETURNING max_num_score - num_score`
+	params := []interface{}{count, leaderboardId, owner, time.Unix(expiryTime, 0).UTC()}
+	if absolute {
+		maxNumScore := leaderboard.MaxNumScore
+		if maxNumScore <= 0 {
+			maxNumScore = leaderboardDefaultMaxNumScore
+		}
+		query = `UPDATE leaderboard_record SET max_num_score = GREATEST(LEAST(num_score + $1, $5), num_score) WHERE leaderboard_id = $2 AND owner_id = $3 AND expiry_time = $4 RETURNING max_num_score - num_score`
+		params = append(params, maxNumScore)
+	}
+
+	var remaining int
+	err := db.QueryRowContext(ctx, query, params...).Scan(&remaining)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrTournamentRecordNotFound
+		}
+		logger.Error("Could not update max attempt counter", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Max attempt count was updated", zap.Int("count", count), zap.Bool("absolute", absolute), zap.Int("remaining", remaining), zap.String("owner", owner), zap.String("leaderboard_id", leaderboardId))
+	return remaining, nil
+}
+
 func TournamentJoin(ctx context.Context, logger *zap.Logger, db *sql.DB, cache LeaderboardCache, rankCache LeaderboardRankCache, ownerID uuid.UUID, username, tournamentId string) error {
+	_, err := TournamentJoinWithEntryCost(ctx, logger, db, cache, rankCache, ownerID, username, tournamentId, nil)
+	return err
+}
+
+// TournamentJoinWithEntryCost joins the tournament, deducting the given entry cost from the owner's wallet in the same
+// transaction. The join is rolled back if the owner cannot afford the cost, and the cost is not deducted if the join
+// fails. Owners who have already joined the current period are not charged again. Returns the owner's wallet after
+// the deduction, or nil if nothing was deducted.
+func TournamentJoinWithEntryCost(ctx context.Context, logger *zap.Logger, db *sql.DB, cache LeaderboardCache, rankCache LeaderboardRankCache, ownerID uuid.UUID, username, tournamentId string, entryCost map[string]int64) (map[string]int64, error) {
+	for currency, amount := range entryCost {
+		if amount <= 0 {
+			return nil, fmt.Errorf("tournament entry cost for '%v' must be greater than zero", currency)
+		}
+	}
+
 	leaderboard := cache.Get(tournamentId)
 	if leaderboard == nil {
 		// If it does not exist treat it as success.
-		return runtime.ErrTournamentNotFound
+		return nil, runtime.ErrTournamentNotFound
 	}
 	if !leaderboard.IsTournament() {
 		// Leaderboard exists but is not a tournament.
-		return runtime.ErrTournamentNotFound
+		return nil, runtime.ErrTournamentNotFound
 	}
 
 	if !leaderboard.JoinRequired {
-		return nil
+		if len(entryCost) > 0 {
+			// There is no join to charge for.
+			return nil, ErrTournamentEntryCostJoinNotRequired
+		}
+		return nil, nil
 	}
 
 	now := time.Now().UTC()
@@ -128,53 +207,82 @@ func TournamentJoin(ctx context.Context, logger *zap.Logger, db *sql.DB, cache L
 	_, endActive, expiryTime := calculateTournamentDeadlines(leaderboard.StartTime, leaderboard.EndTime, int64(leaderboard.Duration), leaderboard.ResetSchedule, now)
 	if endActive <= nowUnix {
 		logger.Info("Cannot join tournament outside of tournament duration.")
-		return runtime.ErrTournamentOutsideDuration
+		return nil, runtime.ErrTournamentOutsideDuration
+	}
+
+	var entryCostUpdate *walletUpdate
+	if len(entryCost) > 0 {
+		changeset := make(map[string]int64, len(entryCost))
+		for currency, amount := range entryCost {
+			changeset[currency] = -amount
+		}
+		metadata, err := json.Marshal(map[string]string{"tournament_id": tournamentId})
+		if err != nil {
+			return nil, err
+		}
+		entryCostUpdate = &walletUpdate{UserID: ownerID, Changeset: changeset, Metadata: string(metadata)}
 	}
 
 	var isNewJoin bool
-	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+	var wallet map[string]int64
+	if err := ExecuteInTxPgx(ctx, db, func(tx pgx.Tx) error {
+		// Reset in case the transaction is retried.
+		isNewJoin = false
+		wallet = nil
+
 		query := `INSERT INTO leaderboard_record
 (leaderboard_id, owner_id, expiry_time, username, num_score, max_num_score)
 VALUES
 ($1, $2, $3, $4, $5, $6)
 ON CONFLICT(owner_id, leaderboard_id, expiry_time) DO NOTHING`
-		result, err := tx.ExecContext(ctx, query, tournamentId, ownerID.String(), time.Unix(expiryTime, 0).UTC(), username, 0, leaderboard.MaxNumScore)
+		result, err := tx.Exec(ctx, query, tournamentId, ownerID.String(), time.Unix(expiryTime, 0).UTC(), username, 0, leaderboard.MaxNumScore)
 		if err != nil {
 			return err
 		}
 
-		if rowsAffected, err := result.RowsAffected(); err != nil {
-			return err
-		} else if rowsAffected != 1 {
+		if result.RowsAffected() != 1 {
 			// Owner has already joined this tournament, treat it as a no-op.
 			return nil
 		}
 
 		if leaderboard.HasMaxSize() {
 			query = "UPDATE leaderboard SET size = size+1 WHERE id = $1 AND size < max_size"
-			result, err = tx.ExecContext(ctx, query, tournamentId)
+			result, err = tx.Exec(ctx, query, tournamentId)
 			if err != nil {
 				return err
 			}
 
-			if rowsAffected, err := result.RowsAffected(); err != nil {
-				return err
-			} else if rowsAffected == 0 {
+			if result.RowsAffected() == 0 {
 				// Tournament is full.
 				return runtime.ErrTournamentMaxSizeReached
 			}
 		}
 
+		if entryCostUpdate != nil {
+			results, err := updateWallets(ctx, logger, tx, []*walletUpdate{entryCostUpdate}, true)
+			if err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				return ErrAccountNotFound
+			}
+			wallet = results[0].Updated
+		}
+
 		isNewJoin = true
 
 		return nil
 	}); err != nil {
 		if errors.Is(err, runtime.ErrTournamentMaxSizeReached) {
 			logger.Info("Failed to join tournament, reached max size allowed.", zap.String("tournament_id", tournamentId), zap.String("owner", ownerID.String()), zap.String("username", username))
-			return err
+			return nil, err
+		}
+		if _, ok := err.(*runtime.WalletNegativeError); ok {
+			logger.Info("Failed to join tournament, insufficient funds for entry cost.", zap.String("tournament_id", tournamentId), zap.String("owner", ownerID.String()), zap.String("username", username))
+			return nil, err
 		}
 		logger.Error("Could not join tournament.", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// Ensure new tournament joiner is included in the rank cache.
@@ -183,7 +291,94 @@ ON CONFLICT(owner_id, leaderboard_id, expiry_time) DO NOTHING`
 	}
 
 	logger.Info("Joined tournament.", zap.String("tournament_id", tournamentId), zap.String("owner", ownerID.String()), zap.String("username", username))
-	return nil
+	return wallet, nil
+}
+
+// TournamentEntryStatus describes a user's standing in the current period of a tournament.
+type TournamentEntryStatus struct {
+	// CanEnter is true if the user may submit a score to the current period.
+	CanEnter bool
+	// AlreadyJoined is true if the user has joined or submitted to the current period.
+	AlreadyJoined bool
+}
+
+// TournamentsEntryStatus computes the entry status of the given user for each of the given tournaments, keyed by
+// tournament ID. Tournaments that no longer exist are omitted.
+func TournamentsEntryStatus(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, userID uuid.UUID, tournaments []*api.Tournament) (map[string]*TournamentEntryStatus, error) {
+	statuses := make(map[string]*TournamentEntryStatus, len(tournaments))
+	if len(tournaments) == 0 {
+		return statuses, nil
+	}
+
+	now := time.Now().UTC()
+	expiries := make(map[string]int64, len(tournaments))
+	tournamentIDs := make([]string, 0, len(tournaments))
+	for _, tournament := range tournaments {
+		leaderboard := leaderboardCache.Get(tournament.Id)
+		if leaderboard == nil || !leaderboard.IsTournament() {
+			continue
+		}
+		_, _, expiryUnix := calculateTournamentDeadlines(leaderboard.StartTime, leaderboard.EndTime, int64(leaderboard.Duration), leaderboard.ResetSchedule, now)
+		expiries[tournament.Id] = expiryUnix
+		tournamentIDs = append(tournamentIDs, tournament.Id)
+	}
+	if len(tournamentIDs) == 0 {
+		return statuses, nil
+	}
+
+	type entry struct {
+		numScore    int
+		maxNumScore int
+	}
+	entries := make(map[string]entry, len(tournamentIDs))
+
+	query := "SELECT leaderboard_id, expiry_time, num_score, max_num_score FROM leaderboard_record WHERE owner_id = $1 AND leaderboard_id = ANY($2::text[])"
+	rows, err := db.QueryContext(ctx, query, userID, tournamentIDs)
+	if err != nil {
+		logger.Error("Error reading tournament entries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dbLeaderboardID string
+		var dbExpiryTime pgtype.Timestamptz
+		var dbNumScore int
+		var dbMaxNumScore int
+		if err := rows.Scan(&dbLeaderboardID, &dbExpiryTime, &dbNumScore, &dbMaxNumScore); err != nil {
+			logger.Error("Error parsing tournament entries", zap.Error(err))
+			return nil, err
+		}
+		// Only entries in the current period count, older periods are kept until they are cleaned up.
+		if dbExpiryTime.Time.Unix() != expiries[dbLeaderboardID] {
+			continue
+		}
+		entries[dbLeaderboardID] = entry{numScore: dbNumScore, maxNumScore: dbMaxNumScore}
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error reading tournament entries", zap.Error(err))
+		return nil, err
+	}
+
+	nowUnix := now.Unix()
+	for _, tournament := range tournaments {
+		if _, found := expiries[tournament.Id]; !found {
+			continue
+		}
+
+		status := &TournamentEntryStatus{}
+		if e, found := entries[tournament.Id]; found {
+			// Size limits do not apply to existing entrants, only schedule and remaining attempts matter.
+			active := int64(tournament.StartActive) <= nowUnix && (tournament.EndActive == 0 || int64(tournament.EndActive) > nowUnix)
+			status.AlreadyJoined = true
+			status.CanEnter = active && (e.maxNumScore == 0 || e.numScore < e.maxNumScore)
+		} else {
+			status.CanEnter = tournament.CanEnter
+		}
+		statuses[tournament.Id] = status
+	}
+
+	return statuses, nil
 }
 
 func TournamentsGet(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, tournamentIDs []string) ([]*api.Tournament, error) {
@@ -633,6 +828,22 @@ func TournamentRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	return record, nil
 }
 
+// TournamentRecordRankCount returns the number of ranked records in the tournament period the given record belongs to,
+// or 0 if ranks are disabled for the tournament.
+func TournamentRecordRankCount(leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, record *api.LeaderboardRecord) int64 {
+	leaderboard := leaderboardCache.Get(record.LeaderboardId)
+	if leaderboard == nil {
+		return 0
+	}
+
+	var expiryUnix int64
+	if record.ExpiryTime != nil {
+		expiryUnix = record.ExpiryTime.Seconds
+	}
+
+	return rankCache.Fill(leaderboard.Id, expiryUnix, nil, leaderboard.EnableRanks)
+}
+
 func TournamentRecordDelete(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, tournamentID, ownerID string) error {
 	tournament := leaderboardCache.Get(tournamentID)
 
@@ -669,18 +880,12 @@ func TournamentRecordsHaystack(ctx context.Context, logger *zap.Logger, db *sql.
 
 	sortOrder := leaderboard.SortOrder
 
-	expiry := expiryOverride
-	if expiry == 0 {
-		now := time.Now().UTC()
-		_, _, expiry = calculateTournamentDeadlines(leaderboard.StartTime, leaderboard.EndTime, int64(leaderboard.Duration), leaderboard.ResetSchedule, now)
-		if expiry != 0 && expiry <= now.Unix() {
-			// if the expiry time is in the past, we wont have any records to return
-			return &api.TournamentRecordList{Records: []*api.LeaderboardRecord{}}, nil
-		}
+	expiryTime, recordsPossible := tournamentHaystackExpiry(leaderboard, expiryOverride)
+	if !recordsPossible {
+		// if the expiry time is in the past, we wont have any records to return
+		return &api.TournamentRecordList{Records: []*api.LeaderboardRecord{}}, nil
 	}
 
-	expiryTime := time.Unix(expiry, 0).UTC()
-
 	results, err := getLeaderboardRecordsHaystack(ctx, logger, db, leaderboardCache, rankCache, ownerId, limit, leaderboard.Id, cursor, sortOrder, expiryTime)
 	if err != nil {
 		return nil, err
@@ -691,6 +896,41 @@ func TournamentRecordsHaystack(ctx context.Context, logger *zap.Logger, db *sql.
 	return tournamentRecordList, nil
 }
 
+// TournamentRecordsHaystackExpand returns the owner's tournament record together with up to expandUp records ranked
+// immediately above it and up to expandDown records ranked immediately below it.
+func TournamentRecordsHaystackExpand(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardId string, ownerId uuid.UUID, expandUp, expandDown int, expiryOverride int64) (*api.TournamentRecordList, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil || !leaderboard.IsTournament() {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	expiryTime, recordsPossible := tournamentHaystackExpiry(leaderboard, expiryOverride)
+	if !recordsPossible {
+		return &api.TournamentRecordList{Records: []*api.LeaderboardRecord{}}, nil
+	}
+
+	results, err := getLeaderboardRecordsHaystackExpand(ctx, logger, db, rankCache, leaderboard, ownerId, expandUp, expandDown, expiryTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.TournamentRecordList{Records: results.Records, NextCursor: results.NextCursor, PrevCursor: results.PrevCursor, RankCount: results.RankCount}, nil
+}
+
+// tournamentHaystackExpiry resolves the period to read haystack records from, reporting false if it has already ended.
+func tournamentHaystackExpiry(leaderboard *Leaderboard, expiryOverride int64) (time.Time, bool) {
+	expiry := expiryOverride
+	if expiry == 0 {
+		now := time.Now().UTC()
+		_, _, expiry = calculateTournamentDeadlines(leaderboard.StartTime, leaderboard.EndTime, int64(leaderboard.Duration), leaderboard.ResetSchedule, now)
+		if expiry != 0 && expiry <= now.Unix() {
+			return time.Time{}, false
+		}
+	}
+
+	return time.Unix(expiry, 0).UTC(), true
+}
+
 func calculateTournamentDeadlines(startTime, endTime, duration int64, resetSchedule *cronexpr.Expression, t time.Time) (int64, int64, int64) {
 	tUnix := t.UTC().Unix()
 	if resetSchedule != nil {
@@ -841,7 +1081,7 @@ func DisableTournamentRanks(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		return errors.New("failed to disable leaderboard ranks")
 	}
 
-	leaderboardCache.Insert(l.Id, l.Authoritative, l.SortOrder, l.Operator, l.ResetScheduleStr, l.Metadata, l.CreateTime, false)
+	leaderboardCache.Insert(l.Id, l.Authoritative, l.SortOrder, l.Operator, l.ResetScheduleStr, l.Metadata, l.CreateTime, false, l.MaxNumScore)
 
 	_, _, expiryUnix := calculateTournamentDeadlines(l.StartTime, l.EndTime, int64(l.Duration), l.ResetSchedule, time.Now())
 	rankCache.DeleteLeaderboard(l.Id, expiryUnix)
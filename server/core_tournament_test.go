@@ -15,9 +15,12 @@
 package server
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
 	"github.com/heroiclabs/nakama/v3/internal/cronexpr"
 	"github.com/stretchr/testify/require"
 )
@@ -110,3 +113,92 @@ func TestTournamentNowIsBeforeStart(t *testing.T) {
 	// 12 October 2023, 9:00:00
 	require.Equal(t, int64(1697101200), endActiveUnix, "End active times should be equal.")
 }
+
+func TestTournamentAddAttemptWithMode(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	tournamentID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.CreateTournament(ctx, tournamentID, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", "", "", 0, int(time.Now().Unix()), 0, 3600, 100, 3, true, true); err != nil {
+		t.Fatalf("error creating tournament: %v", err.Error())
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, ownerID)
+	require.NoError(t, TournamentJoin(ctx, logger, db, leaderboardCache, rankCache, ownerID, ownerID.String(), tournamentID))
+
+	remaining, err := TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), 10, true)
+	require.NoError(t, err)
+	require.Equal(t, 3, remaining, "absolute set should clamp to tournament max")
+
+	remaining, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), 1, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, remaining)
+
+	remaining, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), 2, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, remaining)
+
+	// A submitted score counts against the allowance, absolute mode sets the remaining attempts on top of it.
+	_, err = TournamentRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, tournamentID, ownerID, ownerID.String(), 1, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	remaining, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), 0, true)
+	require.NoError(t, err)
+	require.Equal(t, 0, remaining, "absolute zero should leave no attempts")
+	remaining, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), 10, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, remaining, "absolute set should clamp the allowance to tournament max")
+
+	// Owners without a record in the current period are reported rather than treated as having no attempts.
+	otherID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, otherID)
+	_, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, otherID.String(), 1, false)
+	require.ErrorIs(t, err, ErrTournamentRecordNotFound)
+
+	_, err = TournamentAddAttemptWithMode(ctx, logger, db, leaderboardCache, tournamentID, ownerID.String(), -1, true)
+	require.Error(t, err)
+}
+
+func TestTournamentJoinWithEntryCost(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	cfg := NewConfig(logger)
+	leaderboardCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	rankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, leaderboardCache)
+
+	tournamentID := uuid.Must(uuid.NewV4()).String()
+	if _, _, err := leaderboardCache.CreateTournament(ctx, tournamentID, true, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "", "", "", 0, int(time.Now().Unix()), 0, 3600, 100, 3, true, true); err != nil {
+		t.Fatalf("error creating tournament: %v", err.Error())
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, ownerID)
+	_, err := UpdateWallets(ctx, logger, db, []*walletUpdate{{UserID: ownerID, Changeset: map[string]int64{"coins": 50}, Metadata: "{}"}}, false)
+	require.NoError(t, err)
+
+	// Cannot afford the entry cost, the join must be rolled back.
+	_, err = TournamentJoinWithEntryCost(ctx, logger, db, leaderboardCache, rankCache, ownerID, ownerID.String(), tournamentID, map[string]int64{"coins": 100})
+	require.Error(t, err)
+	var count int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM leaderboard_record WHERE leaderboard_id = $1 AND owner_id = $2", tournamentID, ownerID).Scan(&count))
+	require.Equal(t, 0, count, "failed join should not leave a record")
+
+	wallet, err := TournamentJoinWithEntryCost(ctx, logger, db, leaderboardCache, rankCache, ownerID, ownerID.String(), tournamentID, map[string]int64{"coins": 30})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), wallet["coins"])
+
+	// Joining again does not charge the entry cost a second time.
+	wallet, err = TournamentJoinWithEntryCost(ctx, logger, db, leaderboardCache, rankCache, ownerID, ownerID.String(), tournamentID, map[string]int64{"coins": 30})
+	require.NoError(t, err)
+	require.Nil(t, wallet)
+
+	_, err = TournamentJoinWithEntryCost(ctx, logger, db, leaderboardCache, rankCache, ownerID, ownerID.String(), tournamentID, map[string]int64{"coins": 0})
+	require.Error(t, err)
+}
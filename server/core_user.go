@@ -18,6 +18,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math/rand"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
@@ -27,41 +30,90 @@ import (
 )
 
 func GetUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, ids, usernames, fbIDs []string) (*api.Users, error) {
+	where, params := getUsersWhere(ids, usernames, fbIDs)
 	query := `
 SELECT id, username, display_name, avatar_url, lang_tag, location, timezone, metadata,
 	apple_id, facebook_id, facebook_instant_game_id, google_id, gamecenter_id, steam_id, edge_count, create_time, update_time
 FROM users
-WHERE`
+WHERE` + where
 
-	params := make([]any, 0)
-	counter := 1
-	useSQLOr := false
-
-	if len(ids) > 0 {
-		params = append(params, ids)
-		query = query + fmt.Sprintf(" id = ANY($%d)", counter)
-		counter++
-		useSQLOr = true
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error retrieving user accounts.", zap.Error(err), zap.Strings("user_ids", ids), zap.Strings("usernames", usernames), zap.Strings("facebook_ids", fbIDs))
+		return nil, err
 	}
 
-	if len(usernames) > 0 {
-		params = append(params, usernames)
-		if useSQLOr {
-			query = query + " OR"
+	users := &api.Users{Users: make([]*api.User, 0)}
+	for rows.Next() {
+		user, err := convertUser(rows)
+		if err != nil {
+			_ = rows.Close()
+			logger.Error("Error retrieving user accounts.", zap.Error(err), zap.Strings("user_ids", ids), zap.Strings("usernames", usernames), zap.Strings("facebook_ids", fbIDs))
+			return nil, err
 		}
-		query = query + fmt.Sprintf(" username = ANY($%d::text[])", counter)
-		counter++
-		useSQLOr = true
+		users.Users = append(users.Users, user)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		logger.Error("Error retrieving user accounts.", zap.Error(err), zap.Strings("user_ids", ids), zap.Strings("usernames", usernames), zap.Strings("facebook_ids", fbIDs))
+		return nil, err
 	}
 
-	if len(fbIDs) > 0 {
-		params = append(params, fbIDs)
-		if useSQLOr {
-			query = query + " OR"
+	statusRegistry.FillOnlineUsers(users.Users)
+
+	return users, nil
+}
+
+// Fields that can be selected individually with GetUsersFields, each matching its column name.
+var userSelectableFields = map[string]bool{
+	"username":                 true,
+	"display_name":             true,
+	"avatar_url":               true,
+	"lang_tag":                 true,
+	"location":                 true,
+	"timezone":                 true,
+	"metadata":                 true,
+	"apple_id":                 true,
+	"facebook_id":              true,
+	"facebook_instant_game_id": true,
+	"google_id":                true,
+	"gamecenter_id":            true,
+	"steam_id":                 true,
+	"edge_count":               true,
+	"create_time":              true,
+	"update_time":              true,
+}
+
+// GetUsersFields is like GetUsers but only reads the requested fields, leaving all others unset. The user ID is
+// always included, and online status is only looked up if "online" is requested. An empty field list reads the full
+// user records.
+func GetUsersFields(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, ids, usernames, fbIDs []string, fields []string) (*api.Users, error) {
+	if len(fields) == 0 {
+		return GetUsers(ctx, logger, db, statusRegistry, ids, usernames, fbIDs)
+	}
+
+	columns := make([]string, 1, len(fields)+1)
+	columns[0] = "id"
+	selected := make(map[string]bool, len(fields))
+	var online bool
+	for _, field := range fields {
+		if field == "user_id" || selected[field] {
+			continue
 		}
-		query = query + fmt.Sprintf(" facebook_id = ANY($%d::text[])", counter)
+		if field == "online" {
+			online = true
+			continue
+		}
+		if !userSelectableFields[field] {
+			return nil, fmt.Errorf("unknown user field: %s", field)
+		}
+		selected[field] = true
+		columns = append(columns, field)
 	}
 
+	where, params := getUsersWhere(ids, usernames, fbIDs)
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM users WHERE" + where
+
 	rows, err := db.QueryContext(ctx, query, params...)
 	if err != nil {
 		logger.Error("Error retrieving user accounts.", zap.Error(err), zap.Strings("user_ids", ids), zap.Strings("usernames", usernames), zap.Strings("facebook_ids", fbIDs))
@@ -70,12 +122,72 @@ WHERE`
 
 	users := &api.Users{Users: make([]*api.User, 0)}
 	for rows.Next() {
-		user, err := convertUser(rows)
-		if err != nil {
+		var id string
+		strs := make([]sql.NullString, len(columns))
+		var metadata []byte
+		var edgeCount int
+		var createTime, updateTime pgtype.Timestamptz
+
+		dest := make([]any, len(columns))
+		dest[0] = &id
+		for i, column := range columns[1:] {
+			switch column {
+			case "metadata":
+				dest[i+1] = &metadata
+			case "edge_count":
+				dest[i+1] = &edgeCount
+			case "create_time":
+				dest[i+1] = &createTime
+			case "update_time":
+				dest[i+1] = &updateTime
+			default:
+				dest[i+1] = &strs[i+1]
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
 			_ = rows.Close()
 			logger.Error("Error retrieving user accounts.", zap.Error(err), zap.Strings("user_ids", ids), zap.Strings("usernames", usernames), zap.Strings("facebook_ids", fbIDs))
 			return nil, err
 		}
+
+		user := &api.User{Id: uuid.FromStringOrNil(id).String()}
+		for i, column := range columns[1:] {
+			str := strs[i+1].String
+			switch column {
+			case "username":
+				user.Username = str
+			case "display_name":
+				user.DisplayName = str
+			case "avatar_url":
+				user.AvatarUrl = str
+			case "lang_tag":
+				user.LangTag = str
+			case "location":
+				user.Location = str
+			case "timezone":
+				user.Timezone = str
+			case "metadata":
+				user.Metadata = string(metadata)
+			case "apple_id":
+				user.AppleId = str
+			case "facebook_id":
+				user.FacebookId = str
+			case "facebook_instant_game_id":
+				user.FacebookInstantGameId = str
+			case "google_id":
+				user.GoogleId = str
+			case "gamecenter_id":
+				user.GamecenterId = str
+			case "steam_id":
+				user.SteamId = str
+			case "edge_count":
+				user.EdgeCount = int32(edgeCount)
+			case "create_time":
+				user.CreateTime = &timestamppb.Timestamp{Seconds: createTime.Time.Unix()}
+			case "update_time":
+				user.UpdateTime = &timestamppb.Timestamp{Seconds: updateTime.Time.Unix()}
+			}
+		}
 		users.Users = append(users.Users, user)
 	}
 	_ = rows.Close()
@@ -84,11 +196,47 @@ WHERE`
 		return nil, err
 	}
 
-	statusRegistry.FillOnlineUsers(users.Users)
+	if online {
+		statusRegistry.FillOnlineUsers(users.Users)
+	}
 
 	return users, nil
 }
 
+func getUsersWhere(ids, usernames, fbIDs []string) (string, []any) {
+	var query string
+	params := make([]any, 0)
+	counter := 1
+	useSQLOr := false
+
+	if len(ids) > 0 {
+		params = append(params, ids)
+		query = query + fmt.Sprintf(" id = ANY($%d)", counter)
+		counter++
+		useSQLOr = true
+	}
+
+	if len(usernames) > 0 {
+		params = append(params, usernames)
+		if useSQLOr {
+			query = query + " OR"
+		}
+		query = query + fmt.Sprintf(" username = ANY($%d::text[])", counter)
+		counter++
+		useSQLOr = true
+	}
+
+	if len(fbIDs) > 0 {
+		params = append(params, fbIDs)
+		if useSQLOr {
+			query = query + " OR"
+		}
+		query = query + fmt.Sprintf(" facebook_id = ANY($%d::text[])", counter)
+	}
+
+	return query, params
+}
+
 func GetRandomUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, count int) ([]*api.User, error) {
 	if count == 0 {
 		return []*api.User{}, nil
@@ -153,6 +301,61 @@ LIMIT $2`
 	return users, nil
 }
 
+// Number of storage index entries read per page when selecting filtered random users.
+const randomUsersFilteredPageSize = 1_000
+
+// GetRandomUsersFiltered selects random users among the owners of storage index entries matching the query. Every
+// matching owner is equally likely to be selected, and the selected users are returned in random order.
+func GetRandomUsersFiltered(ctx context.Context, logger *zap.Logger, db *sql.DB, statusRegistry StatusRegistry, storageIndex StorageIndex, indexName, query string, count int) ([]*api.User, error) {
+	if count == 0 {
+		return []*api.User{}, nil
+	}
+
+	// Reservoir sample the distinct owners across every page of matches, so selection does not depend on index order.
+	seen := make(map[string]struct{})
+	candidates := make([]string, 0, count)
+	var cursor string
+	for {
+		objects, nextCursor, err := storageIndex.List(ctx, uuid.Nil, indexName, query, randomUsersFilteredPageSize, nil, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects.GetObjects() {
+			if object.UserId == "" || object.UserId == uuid.Nil.String() {
+				continue
+			}
+			if _, found := seen[object.UserId]; found {
+				continue
+			}
+			seen[object.UserId] = struct{}{}
+			if len(candidates) < count {
+				candidates = append(candidates, object.UserId)
+			} else if i := rand.Intn(len(seen)); i < count {
+				candidates[i] = object.UserId
+			}
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	if len(candidates) == 0 {
+		return []*api.User{}, nil
+	}
+
+	users, err := GetUsers(ctx, logger, db, statusRegistry, candidates, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Neither the reservoir nor the user lookup returns users in random order.
+	rand.Shuffle(len(users.Users), func(i, j int) {
+		users.Users[i], users.Users[j] = users.Users[j], users.Users[i]
+	})
+
+	return users.Users, nil
+}
+
 func DeleteUser(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (int64, error) {
 	res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
@@ -163,11 +366,34 @@ func DeleteUser(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (int64, error
 }
 
 func BanUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, sessionCache SessionCache, sessionRegistry SessionRegistry, tracker Tracker, ids []uuid.UUID) error {
-	query := "UPDATE users SET disable_time = now() WHERE id = ANY($1::UUID[])"
-	_, err := db.ExecContext(ctx, query, ids)
+	_, err := BanUsersWithOptions(ctx, logger, db, config, sessionCache, sessionRegistry, tracker, ids, 0, "")
+	return err
+}
+
+// banMaxDurationSec is the longest ban duration in seconds accepted from the runtime, well below the range of time.Duration.
+const banMaxDurationSec = 100 * 365 * 24 * 60 * 60
+
+// BanUsersWithOptions bans users as BanUsers does, storing an optional reason on each account. A positive duration
+// lifts the ban automatically once it has elapsed. Returns the time the ban will be lifted in UTC seconds, or 0 if the
+// ban is permanent.
+func BanUsersWithOptions(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, sessionCache SessionCache, sessionRegistry SessionRegistry, tracker Tracker, ids []uuid.UUID, duration time.Duration, reason string) (int64, error) {
+	var disableReason *string
+	if reason != "" {
+		disableReason = &reason
+	}
+	var disableUntil *time.Time
+	var unbanTime int64
+	if duration > 0 {
+		until := time.Now().UTC().Add(duration)
+		disableUntil = &until
+		unbanTime = until.Unix()
+	}
+
+	query := "UPDATE users SET disable_time = now(), disable_reason = $2, disable_until = $3 WHERE id = ANY($1::UUID[])"
+	_, err := db.ExecContext(ctx, query, ids, disableReason, disableUntil)
 	if err != nil {
 		logger.Error("Error banning user accounts.", zap.Error(err), zap.Any("ids", ids))
-		return err
+		return 0, err
 	}
 
 	sessionCache.Ban(ids)
@@ -176,16 +402,16 @@ func BanUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config
 		// Disconnect.
 		for _, presence := range tracker.ListPresenceIDByStream(PresenceStream{Mode: StreamModeNotifications, Subject: id}) {
 			if err = sessionRegistry.Disconnect(ctx, presence.SessionID, true); err != nil {
-				return err
+				return 0, err
 			}
 		}
 	}
 
-	return nil
+	return unbanTime, nil
 }
 
 func UnbanUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, sessionCache SessionCache, ids []uuid.UUID) error {
-	query := "UPDATE users SET disable_time = '1970-01-01 00:00:00 UTC' WHERE id = ANY($1::UUID[])"
+	query := "UPDATE users SET disable_time = '1970-01-01 00:00:00 UTC', disable_reason = NULL, disable_until = NULL WHERE id = ANY($1::UUID[])"
 	_, err := db.ExecContext(ctx, query, ids)
 	if err != nil {
 		logger.Error("Error unbanning user accounts.", zap.Error(err), zap.Any("ids", ids))
@@ -197,6 +423,59 @@ func UnbanUsers(ctx context.Context, logger *zap.Logger, db *sql.DB, sessionCach
 	return nil
 }
 
+const banExpirySweepInterval = time.Minute
+
+// StartBanExpirySweeper periodically lifts bans whose duration has elapsed, until the context is cancelled.
+func StartBanExpirySweeper(ctx context.Context, logger *zap.Logger, db *sql.DB, sessionCache SessionCache) {
+	go func() {
+		ticker := time.NewTicker(banExpirySweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := banSweepExpired(ctx, logger, db, sessionCache); err != nil && ctx.Err() == nil {
+					logger.Error("Error lifting expired user bans", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func banSweepExpired(ctx context.Context, logger *zap.Logger, db *sql.DB, sessionCache SessionCache) error {
+	query := `
+UPDATE users SET disable_time = '1970-01-01 00:00:00 UTC', disable_reason = NULL, disable_until = NULL
+WHERE disable_until <= now()
+RETURNING id`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		sessionCache.Unban(ids)
+		logger.Info("Lifted expired user bans.", zap.Int("count", len(ids)))
+	}
+
+	return nil
+}
+
 func UserExistsAndDoesNotBlock(ctx context.Context, db *sql.DB, checkUserID, blocksUserID uuid.UUID) (bool, error) {
 	var count int
 	err := db.QueryRowContext(ctx, `
@@ -288,3 +567,33 @@ func fetchUserID(ctx context.Context, db *sql.DB, usernames []string) ([]string,
 
 	return ids, nil
 }
+
+// ResolveUsernames maps each known username to its user ID, omitting usernames with no matching user.
+func ResolveUsernames(ctx context.Context, logger *zap.Logger, db *sql.DB, usernames []string) (map[string]string, error) {
+	ids := make(map[string]string, len(usernames))
+	if len(usernames) == 0 {
+		return ids, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, username FROM users WHERE username = ANY($1::text[])", usernames)
+	if err != nil {
+		logger.Error("Error resolving usernames.", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, username string
+		if err := rows.Scan(&id, &username); err != nil {
+			logger.Error("Error scanning resolved username.", zap.Error(err))
+			return nil, err
+		}
+		ids[username] = id
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error resolving usernames.", zap.Error(err))
+		return nil, err
+	}
+
+	return ids, nil
+}
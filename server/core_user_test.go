@@ -0,0 +1,106 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUsersFields(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	statusRegistry := NewLocalStatusRegistry(logger, cfg, NewLocalSessionRegistry(metrics), protojsonMarshaler)
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	if _, err := db.ExecContext(ctx, `UPDATE users SET avatar_url = 'avatar', metadata = '{"level":3}' WHERE id = $1`, userID); err != nil {
+		t.Fatalf("error updating user: %v", err.Error())
+	}
+
+	users, err := GetUsersFields(ctx, logger, db, statusRegistry, []string{userID.String()}, nil, nil, []string{"username", "avatar_url"})
+	if err != nil {
+		t.Fatalf("error getting users: %v", err.Error())
+	}
+	if assert.Len(t, users.Users, 1) {
+		user := users.Users[0]
+		assert.Equal(t, userID.String(), user.Id)
+		assert.Equal(t, userID.String(), user.Username)
+		assert.Equal(t, "avatar", user.AvatarUrl)
+		assert.Empty(t, user.Metadata)
+		assert.Nil(t, user.CreateTime)
+	}
+
+	_, err = GetUsersFields(ctx, logger, db, statusRegistry, []string{userID.String()}, nil, nil, []string{"password"})
+	assert.Error(t, err)
+}
+
+func TestBanUsersWithDuration(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	sessionCache := NewLocalSessionCache(3_600, 7_200)
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	unbanTime, err := BanUsersWithOptions(ctx, logger, db, cfg, sessionCache, NewLocalSessionRegistry(metrics), &testTracker{}, []uuid.UUID{userID}, time.Hour, "cheating")
+	if err != nil {
+		t.Fatalf("error banning user: %v", err.Error())
+	}
+	assert.InDelta(t, time.Now().Add(time.Hour).Unix(), unbanTime, 5)
+
+	_, reason, until, err := GetAccountWithBan(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting ban: %v", err.Error())
+	}
+	assert.Equal(t, "cheating", reason)
+	assert.Equal(t, unbanTime, until)
+
+	// Bans that have not expired yet are kept.
+	if err = banSweepExpired(ctx, logger, db, sessionCache); err != nil {
+		t.Fatalf("error sweeping bans: %v", err.Error())
+	}
+	account, err := GetAccount(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+	assert.NotNil(t, account.DisableTime)
+
+	if _, err = db.ExecContext(ctx, "UPDATE users SET disable_until = now() - INTERVAL '1 minute' WHERE id = $1", userID); err != nil {
+		t.Fatalf("error updating user: %v", err.Error())
+	}
+	if err = banSweepExpired(ctx, logger, db, sessionCache); err != nil {
+		t.Fatalf("error sweeping bans: %v", err.Error())
+	}
+	account, err = GetAccount(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting account: %v", err.Error())
+	}
+	assert.Nil(t, account.DisableTime)
+
+	_, reason, until, err = GetAccountWithBan(ctx, logger, db, nil, userID)
+	if err != nil {
+		t.Fatalf("error getting ban: %v", err.Error())
+	}
+	assert.Empty(t, reason)
+	assert.Zero(t, until)
+}
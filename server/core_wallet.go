@@ -45,6 +45,24 @@ type walletUpdate struct {
 	Changeset map[string]int64
 	// Metadata is expected to be a valid JSON string already.
 	Metadata string
+	// Optional per-currency balance limits. A spend that would take a currency below its minimum rejects the whole
+	// update, a grant that would take it above its maximum is capped. A minimum replaces the default check that
+	// balances never go negative, so a negative minimum allows an overdraft down to that value.
+	Min map[string]int64
+	Max map[string]int64
+}
+
+// WalletMinBalanceError is returned when a wallet update would take a currency below its configured minimum balance.
+type WalletMinBalanceError struct {
+	UserID  string
+	Path    string
+	Current int64
+	Amount  int64
+	Min     int64
+}
+
+func (e *WalletMinBalanceError) Error() string {
+	return fmt.Sprintf("wallet update rejected value below minimum %v at path '%v'", e.Min, e.Path)
 }
 
 // Not an API entity, only used to send data to runtime environment.
@@ -96,7 +114,9 @@ func UpdateWallets(ctx context.Context, logger *zap.Logger, db *sql.DB, updates
 		}
 		return nil
 	}); err != nil {
-		if _, ok := err.(*runtime.WalletNegativeError); !ok {
+		switch err.(type) {
+		case *runtime.WalletNegativeError, *WalletMinBalanceError:
+		default:
 			logger.Error("Error updating wallets.", zap.Error(err))
 		}
 		// Ensure there are no partially updated wallets returned as results, they would not be reflected in database anyway.
@@ -110,8 +130,15 @@ func UpdateWallets(ctx context.Context, logger *zap.Logger, db *sql.DB, updates
 }
 
 func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates []*walletUpdate, updateLedger bool) ([]*runtime.WalletUpdateResult, error) {
+	results, _, err := updateWalletsWithIndex(ctx, logger, tx, updates, updateLedger)
+	return results, err
+}
+
+// updateWalletsWithIndex is updateWallets, additionally returning the index of the update that failed, or -1 if the
+// failure is not attributable to a single update.
+func updateWalletsWithIndex(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates []*walletUpdate, updateLedger bool) ([]*runtime.WalletUpdateResult, int, error) {
 	if len(updates) == 0 {
-		return nil, nil
+		return nil, -1, nil
 	}
 
 	ids := make([]uuid.UUID, 0, len(updates))
@@ -126,7 +153,7 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 	rows, err := tx.Query(ctx, initialQuery, ids)
 	if err != nil {
 		logger.Debug("Error retrieving user wallets.", zap.Error(err))
-		return nil, err
+		return nil, -1, err
 	}
 	for rows.Next() {
 		var id string
@@ -135,7 +162,7 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 		if err != nil {
 			rows.Close()
 			logger.Debug("Error reading user wallets.", zap.Error(err))
-			return nil, err
+			return nil, -1, err
 		}
 
 		var walletMap map[string]int64
@@ -143,7 +170,7 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 		if err != nil {
 			rows.Close()
 			logger.Debug("Error converting user wallet.", zap.String("user_id", id), zap.Error(err))
-			return nil, err
+			return nil, -1, err
 		}
 
 		wallets[id] = walletMap
@@ -168,7 +195,7 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 	}
 
 	// Go through the changesets and attempt to calculate the new state for each wallet.
-	for _, update := range updates {
+	for i, update := range updates {
 		userID := update.UserID.String()
 		walletMap, ok := wallets[userID]
 		if !ok {
@@ -183,18 +210,39 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 		}
 		result := &runtime.WalletUpdateResult{UserID: userID, Previous: previousMap}
 
+		// The changes actually applied, which differ from the requested changeset when a grant is capped.
+		applied := make(map[string]int64, len(update.Changeset))
 		for k, v := range update.Changeset {
 			// Existing value may be 0 or missing.
 			newValue := walletMap[k] + v
-			if newValue < 0 {
+			if minBalance, ok := update.Min[k]; ok {
+				// A configured minimum replaces the default non-negative check, and may allow a negative balance.
+				if v < 0 && newValue < minBalance {
+					return nil, i, &WalletMinBalanceError{
+						UserID:  userID,
+						Path:    k,
+						Current: walletMap[k],
+						Amount:  v,
+						Min:     minBalance,
+					}
+				}
+			} else if newValue < 0 {
 				// Insufficient funds
-				return nil, &runtime.WalletNegativeError{
+				return nil, i, &runtime.WalletNegativeError{
 					UserID:  userID,
 					Path:    k,
 					Current: walletMap[k],
 					Amount:  v,
 				}
 			}
+			if maxBalance, ok := update.Max[k]; ok && v > 0 && newValue > maxBalance {
+				// Cap the grant, but never reduce a balance that was already above the maximum.
+				newValue = walletMap[k]
+				if newValue < maxBalance {
+					newValue = maxBalance
+				}
+			}
+			applied[k] = newValue - walletMap[k]
 			walletMap[k] = newValue
 		}
 
@@ -204,17 +252,17 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 		walletData, err := json.Marshal(walletMap)
 		if err != nil {
 			logger.Debug("Error converting new user wallet.", zap.String("user_id", userID), zap.Error(err))
-			return nil, err
+			return nil, i, err
 		}
 		updatedWallets[userID] = walletData
 		updateOrder = append(updateOrder, userID)
 
 		// Prepare ledger updates if needed.
 		if updateLedger {
-			changesetData, err := json.Marshal(update.Changeset)
+			changesetData, err := json.Marshal(applied)
 			if err != nil {
 				logger.Debug("Error converting new user wallet changeset.", zap.String("user_id", update.UserID.String()), zap.Error(err))
-				return nil, err
+				return nil, i, err
 			}
 
 			idParams = append(idParams, uuid.Must(uuid.NewV4()))
@@ -239,7 +287,7 @@ func updateWallets(ctx context.Context, logger *zap.Logger, tx pgx.Tx, updates [
 			_, err = tx.Exec(ctx, "UPDATE users SET update_time = now(), wallet = $2 WHERE id = $1", userID, updatedWallet)
 			if err != nil {
 				logger.Debug("Error writing user wallet.", zap.String("user_id", userID), zap.Error(err))
-				return nil, err
+				return nil, -1, err
 			}
 		}
 
@@ -253,12 +301,22 @@ SELECT
 `, idParams, userIdParams, changesetParams, metadataParams)
 			if err != nil {
 				logger.Debug("Error writing user wallet ledgers.", zap.Error(err))
-				return nil, err
+				return nil, -1, err
 			}
 		}
 	}
 
-	return results, nil
+	return results, -1, nil
+}
+
+// walletUpdateClamped reports whether any grant in the update was capped by its maximum balance.
+func walletUpdateClamped(update *walletUpdate, result *runtime.WalletUpdateResult) bool {
+	for k, v := range update.Changeset {
+		if result.Updated[k] != result.Previous[k]+v {
+			return true
+		}
+	}
+	return false
 }
 
 func UpdateWalletLedger(ctx context.Context, logger *zap.Logger, db *sql.DB, id uuid.UUID, metadata string) (*walletLedger, error) {
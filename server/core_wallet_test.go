@@ -17,6 +17,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -578,3 +579,111 @@ func TestUpdateWalletRepeatedSingleUser(t *testing.T) {
 	assert.IsType(t, float64(0), wallet["value"], "wallet value was not float64")
 	assert.Equal(t, float64(6), wallet["value"].(float64), "wallet value did not match")
 }
+
+func TestUpdateWalletsMinBalanceRejected(t *testing.T) {
+	db := NewDB(t)
+
+	userID, _, _, err := AuthenticateCustom(context.Background(), logger, db, uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String(), true)
+	if err != nil {
+		t.Fatalf("error creating user: %v", err.Error())
+	}
+	uid := uuid.FromStringOrNil(userID)
+
+	if _, err = UpdateWallets(context.Background(), logger, db, []*walletUpdate{{UserID: uid, Changeset: map[string]int64{"coins": 100, "gems": 10}, Metadata: "{}"}}, false); err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+
+	// Spending below the minimum must reject the whole update, including changes to other currencies.
+	_, err = UpdateWallets(context.Background(), logger, db, []*walletUpdate{{
+		UserID:    uid,
+		Changeset: map[string]int64{"coins": -60, "gems": -5},
+		Metadata:  "{}",
+		Min:       map[string]int64{"coins": 50},
+	}}, false)
+	var minErr *WalletMinBalanceError
+	if !errors.As(err, &minErr) {
+		t.Fatalf("expected minimum balance error, got: %v", err)
+	}
+	assert.Equal(t, "coins", minErr.Path)
+	assert.EqualValues(t, 100, minErr.Current)
+
+	account, err := GetAccount(context.Background(), logger, db, nil, uid)
+	if err != nil {
+		t.Fatalf("error getting user: %v", err.Error())
+	}
+	var wallet map[string]int64
+	if err = json.Unmarshal([]byte(account.Wallet), &wallet); err != nil {
+		t.Fatalf("json unmarshal error: %v", err.Error())
+	}
+	assert.Equal(t, map[string]int64{"coins": 100, "gems": 10}, wallet, "wallet should be unchanged")
+
+	// Spending down to the minimum is allowed.
+	update := &walletUpdate{UserID: uid, Changeset: map[string]int64{"coins": -50}, Metadata: "{}", Min: map[string]int64{"coins": 50}}
+	results, err := UpdateWallets(context.Background(), logger, db, []*walletUpdate{update}, false)
+	if err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	assert.EqualValues(t, 50, results[0].Updated["coins"])
+	assert.False(t, walletUpdateClamped(update, results[0]))
+
+	// A negative minimum allows an overdraft, and takes precedence over the default non-negative check.
+	update = &walletUpdate{UserID: uid, Changeset: map[string]int64{"coins": -80}, Metadata: "{}", Min: map[string]int64{"coins": -50}}
+	results, err = UpdateWallets(context.Background(), logger, db, []*walletUpdate{update}, false)
+	if err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	assert.EqualValues(t, -30, results[0].Updated["coins"])
+
+	_, err = UpdateWallets(context.Background(), logger, db, []*walletUpdate{{UserID: uid, Changeset: map[string]int64{"coins": -30}, Metadata: "{}", Min: map[string]int64{"coins": -50}}}, false)
+	if !errors.As(err, &minErr) {
+		t.Fatalf("expected minimum balance error, got: %v", err)
+	}
+	assert.EqualValues(t, -50, minErr.Min)
+}
+
+func TestUpdateWalletsMaxBalanceCapped(t *testing.T) {
+	db := NewDB(t)
+
+	userID, _, _, err := AuthenticateCustom(context.Background(), logger, db, uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String(), true)
+	if err != nil {
+		t.Fatalf("error creating user: %v", err.Error())
+	}
+	uid := uuid.FromStringOrNil(userID)
+
+	update := &walletUpdate{UserID: uid, Changeset: map[string]int64{"coins": 80, "gems": 5}, Metadata: "{}", Max: map[string]int64{"coins": 100}}
+	results, err := UpdateWallets(context.Background(), logger, db, []*walletUpdate{update}, false)
+	if err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	assert.EqualValues(t, 80, results[0].Updated["coins"])
+	assert.False(t, walletUpdateClamped(update, results[0]))
+
+	// A grant past the maximum is capped rather than rejected.
+	update = &walletUpdate{UserID: uid, Changeset: map[string]int64{"coins": 50, "gems": 5}, Metadata: "{}", Max: map[string]int64{"coins": 100}}
+	results, err = UpdateWallets(context.Background(), logger, db, []*walletUpdate{update}, true)
+	if err != nil {
+		t.Fatalf("error updating wallet: %v", err.Error())
+	}
+	assert.EqualValues(t, 100, results[0].Updated["coins"])
+	assert.EqualValues(t, 10, results[0].Updated["gems"])
+	assert.True(t, walletUpdateClamped(update, results[0]))
+
+	account, err := GetAccount(context.Background(), logger, db, nil, uid)
+	if err != nil {
+		t.Fatalf("error getting user: %v", err.Error())
+	}
+	var wallet map[string]int64
+	if err = json.Unmarshal([]byte(account.Wallet), &wallet); err != nil {
+		t.Fatalf("json unmarshal error: %v", err.Error())
+	}
+	assert.Equal(t, map[string]int64{"coins": 100, "gems": 10}, wallet)
+
+	// The ledger records the capped change that was applied, not the requested one.
+	ledger, _, _, err := ListWalletLedger(context.Background(), logger, db, uid, nil, "")
+	if err != nil {
+		t.Fatalf("error listing wallet ledger: %v", err.Error())
+	}
+	if assert.Len(t, ledger, 1) {
+		assert.Equal(t, map[string]int64{"coins": 20, "gems": 5}, ledger[0].Changeset)
+	}
+}
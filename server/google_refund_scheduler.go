@@ -47,6 +47,7 @@ type LocalGoogleRefundScheduler struct {
 
 	fnPurchaseRefund     RuntimePurchaseNotificationGoogleFunction
 	fnSubscriptionRefund RuntimeSubscriptionNotificationGoogleFunction
+	purchaseRefundFn     PurchaseRefundFunction
 
 	ctx         context.Context
 	ctxCancelFn context.CancelFunc
@@ -70,6 +71,7 @@ func NewGoogleRefundScheduler(logger *zap.Logger, db *sql.DB, config Config) Goo
 func (g *LocalGoogleRefundScheduler) Start(runtime *Runtime) {
 	g.fnPurchaseRefund = runtime.PurchaseNotificationGoogle()
 	g.fnSubscriptionRefund = runtime.SubscriptionNotificationGoogle()
+	g.purchaseRefundFn = runtime.PurchaseRefund()
 
 	if !g.config.GetIAP().Google.Enabled() {
 		return
@@ -135,7 +137,7 @@ func (g *LocalGoogleRefundScheduler) Start(runtime *Runtime) {
 								environment:   purchase.Environment,
 							}
 
-							dbPurchases, err := upsertPurchases(g.ctx, g.db, []*storagePurchase{sPurchase})
+							dbPurchases, err := upsertPurchases(g.ctx, g.logger, g.db, g.purchaseRefundFn, []*storagePurchase{sPurchase})
 							if err != nil {
 								g.logger.Error("Failed to upsert Google voided purchase", zap.Error(err), zap.String("purchase_token", vr.PurchaseToken))
 								continue
@@ -0,0 +1,50 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalJSONMarshal(t *testing.T) {
+	// Key ordering and number formatting examples from RFC 8785.
+	var value interface{}
+	if err := json.Unmarshal([]byte(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`), &value); err != nil {
+		t.Fatalf("error decoding value: %v", err.Error())
+	}
+	output, err := CanonicalJSONMarshal(value)
+	if err != nil {
+		t.Fatalf("error encoding value: %v", err.Error())
+	}
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(output))
+
+	// Keys sort by UTF-16 code units, which differs from UTF-8 byte order for characters outside the BMP.
+	output, err = CanonicalJSONMarshal(map[string]interface{}{"\U0001F600": 1, "\uFB33": 2, "a": int64(3), "<&>": -0.0})
+	if err != nil {
+		t.Fatalf("error encoding value: %v", err.Error())
+	}
+	assert.Equal(t, "{\"<&>\":0,\"a\":3,\"\U0001F600\":1,\"\uFB33\":2}", string(output))
+
+	_, err = CanonicalJSONMarshal(map[string]interface{}{"nan": math.NaN()})
+	assert.Error(t, err)
+}
@@ -25,6 +25,7 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,6 +48,9 @@ const (
 	LeaderboardOperatorDecrement
 )
 
+// Database default for max_num_score. Leaderboards created without a score attempt cap keep this value and are not limited.
+const leaderboardDefaultMaxNumScore = 1000000
+
 type Leaderboard struct {
 	Id               string
 	Authoritative    bool
@@ -156,12 +160,14 @@ type LeaderboardCache interface {
 	ListAll(limit int, reverse bool, cursor *LeaderboardAllCursor) ([]*Leaderboard, int, *LeaderboardAllCursor)
 	RefreshAllLeaderboards(ctx context.Context) error
 	Create(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, enableRanks bool) (*Leaderboard, bool, error)
-	Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64, enableRanks bool)
+	CreateWithMaxNumScore(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, enableRanks bool, maxNumScore int) (*Leaderboard, bool, error)
+	Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64, enableRanks bool, maxNumScore int)
 	List(limit int, cursor *LeaderboardListCursor) ([]*Leaderboard, *LeaderboardListCursor, error)
 	CreateTournament(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata, title, description string, category, startTime, endTime, duration, maxSize, maxNumScore int, joinRequired, enableRanks bool) (*Leaderboard, bool, error)
 	InsertTournament(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata, title, description string, category, duration, maxSize, maxNumScore int, joinRequired bool, createTime, startTime, endTime int64, enableRanks bool)
 	ListTournaments(now int64, categoryStart, categoryEnd int, startTime, endTime int64, limit int, cursor *TournamentListCursor) ([]*Leaderboard, *TournamentListCursor, error)
 	Delete(ctx context.Context, rankCache LeaderboardRankCache, scheduler LeaderboardScheduler, id string) (bool, error)
+	DeleteMany(ctx context.Context, rankCache LeaderboardRankCache, scheduler LeaderboardScheduler, idPrefix string, endedBefore int64) (int, error)
 	Remove(id string)
 }
 
@@ -366,6 +372,12 @@ func (l *LocalLeaderboardCache) ListAll(limit int, reverse bool, cursor *Leaderb
 }
 
 func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, enableRanks bool) (*Leaderboard, bool, error) {
+	return l.CreateWithMaxNumScore(ctx, id, authoritative, sortOrder, operator, resetSchedule, metadata, enableRanks, 0)
+}
+
+// CreateWithMaxNumScore creates a leaderboard as Create does, additionally capping the number of score submissions each
+// owner may make per reset period. A maxNumScore of 0 leaves submissions unlimited.
+func (l *LocalLeaderboardCache) CreateWithMaxNumScore(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, enableRanks bool, maxNumScore int) (*Leaderboard, bool, error) {
 	l.RLock()
 	if leaderboard, ok := l.leaderboards[id]; ok {
 		// Creation is an idempotent operation.
@@ -374,6 +386,10 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 	}
 	l.RUnlock()
 
+	if err := validateLeaderboardCreate(sortOrder, operator, resetSchedule, maxNumScore); err != nil {
+		return nil, false, err
+	}
+
 	var expr *cronexpr.Expression
 	var err error
 	if resetSchedule != "" {
@@ -383,18 +399,21 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 			return nil, false, err
 		}
 	}
+	if maxNumScore == 0 {
+		maxNumScore = leaderboardDefaultMaxNumScore
+	}
 
 	// Insert into database first.
-	query := "INSERT INTO leaderboard (id, authoritative, sort_order, operator, metadata, enable_ranks"
+	query := "INSERT INTO leaderboard (id, authoritative, sort_order, operator, metadata, enable_ranks, max_num_score"
 	if resetSchedule != "" {
 		query += ", reset_schedule"
 	}
-	query += ") VALUES ($1, $2, $3, $4, $5, $6"
+	query += ") VALUES ($1, $2, $3, $4, $5, $6, $7"
 	if resetSchedule != "" {
-		query += ", $7"
+		query += ", $8"
 	}
 	query += ") RETURNING create_time"
-	params := []interface{}{id, authoritative, sortOrder, operator, metadata, enableRanks}
+	params := []interface{}{id, authoritative, sortOrder, operator, metadata, enableRanks, maxNumScore}
 	if resetSchedule != "" {
 		params = append(params, resetSchedule)
 	}
@@ -404,7 +423,7 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == dbErrorUniqueViolation {
 			// Concurrent attempt at creating the leaderboard, to keep idempotency query the existing leaderboard data.
-			if err = l.db.QueryRowContext(ctx, "SELECT authoritative, sort_order, operator, COALESCE(reset_schedule, ''), metadata, create_time FROM leaderboard WHERE id = $1", id).Scan(&authoritative, &sortOrder, &operator, &resetSchedule, &metadata, &createTime); err != nil {
+			if err = l.db.QueryRowContext(ctx, "SELECT authoritative, sort_order, operator, COALESCE(reset_schedule, ''), metadata, max_num_score, create_time FROM leaderboard WHERE id = $1", id).Scan(&authoritative, &sortOrder, &operator, &resetSchedule, &metadata, &maxNumScore, &createTime); err != nil {
 				l.logger.Error("Error retrieving leaderboard", zap.Error(err))
 				return nil, false, err
 			}
@@ -431,6 +450,7 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 		ResetSchedule:    expr,
 		Metadata:         metadata,
 		CreateTime:       createTime.Time.Unix(),
+		MaxNumScore:      maxNumScore,
 		EnableRanks:      enableRanks,
 	}
 
@@ -448,7 +468,33 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 	return leaderboard, true, nil
 }
 
-func (l *LocalLeaderboardCache) Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64, enableRanks bool) {
+// validateLeaderboardCreate checks leaderboard creation parameters so invalid configurations are reported when the
+// leaderboard is created, rather than surfacing on the first record write or reset.
+func validateLeaderboardCreate(sortOrder, operator int, resetSchedule string, maxNumScore int) error {
+	switch sortOrder {
+	case LeaderboardSortOrderAscending, LeaderboardSortOrderDescending:
+	default:
+		return fmt.Errorf("invalid sort order %d, expects ascending or descending", sortOrder)
+	}
+	if _, ok := OperatorIntToEnum[operator]; !ok {
+		return fmt.Errorf("invalid operator %d, expects best, set, incr or decr", operator)
+	}
+	if resetSchedule != "" {
+		expr, err := cronexpr.Parse(resetSchedule)
+		if err != nil {
+			return fmt.Errorf("invalid reset schedule %q: %v", resetSchedule, err.Error())
+		}
+		if expr.Next(time.Now().UTC()).IsZero() {
+			return fmt.Errorf("invalid reset schedule %q: expression never matches a future time", resetSchedule)
+		}
+	}
+	if maxNumScore < 0 {
+		return fmt.Errorf("invalid max num score %d, expects 0 or greater", maxNumScore)
+	}
+	return nil
+}
+
+func (l *LocalLeaderboardCache) Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64, enableRanks bool, maxNumScore int) {
 	var expr *cronexpr.Expression
 	var err error
 	if resetSchedule != "" {
@@ -469,6 +515,7 @@ func (l *LocalLeaderboardCache) Insert(id string, authoritative bool, sortOrder,
 		ResetSchedule:    expr,
 		Metadata:         metadata,
 		CreateTime:       createTime,
+		MaxNumScore:      maxNumScore,
 		EnableRanks:      enableRanks,
 	}
 
@@ -858,6 +905,72 @@ func (l *LocalLeaderboardCache) Delete(ctx context.Context, rankCache Leaderboar
 	return rowsAffected != 0 || err != nil, nil
 }
 
+// DeleteMany deletes all leaderboards and tournaments, along with their records, whose ID starts with the given prefix
+// and whose end time is at or before the given UTC unix time. Either criteria is ignored if empty, but at least one
+// must be set. Returns the number of leaderboards deleted.
+func (l *LocalLeaderboardCache) DeleteMany(ctx context.Context, rankCache LeaderboardRankCache, scheduler LeaderboardScheduler, idPrefix string, endedBefore int64) (int, error) {
+	if idPrefix == "" && endedBefore <= 0 {
+		return 0, errors.New("expects an id prefix or an end time")
+	}
+
+	now := time.Now().UTC()
+	ids := make([]string, 0)
+	expiries := make(map[string]int64)
+	l.RLock()
+	for _, leaderboard := range l.allList {
+		if idPrefix != "" && !strings.HasPrefix(leaderboard.Id, idPrefix) {
+			continue
+		}
+		if endedBefore > 0 && (leaderboard.EndTime == 0 || leaderboard.EndTime > endedBefore) {
+			continue
+		}
+		ids = append(ids, leaderboard.Id)
+
+		var expiryUnix int64
+		if leaderboard.ResetSchedule != nil {
+			expiryUnix = leaderboard.ResetSchedule.Next(now).UTC().Unix()
+		}
+		if leaderboard.EndTime > 0 && expiryUnix > leaderboard.EndTime {
+			expiryUnix = leaderboard.EndTime
+		}
+		expiries[leaderboard.Id] = expiryUnix
+	}
+	l.RUnlock()
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Delete from database first, records are removed along with their leaderboards.
+	query := "DELETE FROM leaderboard WHERE id = ANY($1::TEXT[])"
+	res, err := l.db.ExecContext(ctx, query, ids)
+	if err != nil {
+		l.logger.Error("Error deleting leaderboards", zap.Error(err))
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		l.logger.Error("Error counting deleted leaderboards", zap.Error(err))
+		return 0, err
+	}
+
+	// Then delete from cache.
+	for _, id := range ids {
+		l.Remove(id)
+	}
+
+	scheduler.Update()
+
+	for _, id := range ids {
+		if expiryUnix := expiries[id]; expiryUnix > now.Unix() || expiryUnix == 0 {
+			// Clear any cached ranks that have not yet expired.
+			rankCache.DeleteLeaderboard(id, expiryUnix)
+		}
+	}
+
+	return int(rowsAffected), nil
+}
+
 func (l *LocalLeaderboardCache) Remove(id string) {
 	l.Lock()
 	if leaderboard, ok := l.leaderboards[id]; ok {
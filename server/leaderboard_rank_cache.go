@@ -39,6 +39,7 @@ type LeaderboardRankCache interface {
 	Delete(leaderboardId string, expiryUnix int64, ownerID uuid.UUID) bool
 	DeleteLeaderboard(leaderboardId string, expiryUnix int64) bool
 	TrimExpired(nowUnix int64) bool
+	Rebuild(ctx context.Context, db *sql.DB, leaderboard *Leaderboard) (int, error)
 }
 
 type LeaderboardWithExpiry struct {
@@ -449,6 +450,140 @@ func (l *LocalLeaderboardRankCache) TrimExpired(nowUnix int64) bool {
 	return true
 }
 
+// Rebuild reloads the cached ranks for the current period of the leaderboard from the database, returning the number
+// of cached records. Entries are merged per owner, so a write received while the rebuild is in progress is kept over
+// the older record read from the database, and owners without an active record are dropped. This reads every record
+// in the current period and is expensive for large leaderboards.
+func (l *LocalLeaderboardRankCache) Rebuild(ctx context.Context, db *sql.DB, leaderboard *Leaderboard) (int, error) {
+	if l.blacklistAll {
+		// If all rank caching is disabled.
+		return 0, nil
+	}
+	if !leaderboard.EnableRanks {
+		// If ranks are disabled for this leaderboard.
+		return 0, nil
+	}
+	if _, ok := l.blacklistIds[leaderboard.Id]; ok {
+		// If rank caching is disabled for this particular leaderboard.
+		return 0, nil
+	}
+
+	// Current expiry for this leaderboard.
+	// This matches calculateTournamentDeadlines
+	nowTime := time.Now().UTC()
+	var expiryUnix int64
+	if leaderboard.ResetSchedule != nil {
+		expiryUnix = leaderboard.ResetSchedule.Next(nowTime).UTC().Unix()
+		if leaderboard.EndTime > 0 && expiryUnix > leaderboard.EndTime {
+			expiryUnix = leaderboard.EndTime
+		}
+	} else {
+		expiryUnix = leaderboard.EndTime
+	}
+
+	key := LeaderboardWithExpiry{LeaderboardId: leaderboard.Id, Expiry: expiryUnix}
+	if expiryUnix != 0 && expiryUnix <= nowTime.Unix() {
+		// Last scores for this leaderboard have expired, there is nothing to cache.
+		l.Lock()
+		delete(l.cache, key)
+		l.Unlock()
+		return 0, nil
+	}
+
+	l.Lock()
+	rankCache, found := l.cache[key]
+	if !found {
+		rankCache = &RankCache{cache: skiplist.New(), owners: map[uuid.UUID]cachedRecord{}}
+		l.cache[key] = rankCache
+	}
+	l.Unlock()
+
+	// Generations cached before reading the database, to tell stale entries apart from writes received in parallel.
+	rankCache.RLock()
+	previous := make(map[uuid.UUID]int32, len(rankCache.owners))
+	for ownerID, record := range rankCache.owners {
+		previous[ownerID] = record.generation
+	}
+	rankCache.RUnlock()
+
+	records := make(map[uuid.UUID]cachedRecord)
+	expiryTime := time.Unix(expiryUnix, 0).UTC()
+	batchSize := 10_000
+	var score int64
+	var subscore int64
+	var generation int32
+	var ownerIDStr string
+	for {
+		query := "SELECT owner_id, score, subscore, num_score FROM leaderboard_record WHERE leaderboard_id = $1 AND expiry_time = $2" + leaderboardRecordActiveFilter
+		params := []interface{}{leaderboard.Id, expiryTime}
+		if ownerIDStr != "" {
+			query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) > ($1, $2, $3, $4, $5)"
+			params = append(params, score, subscore, ownerIDStr)
+		}
+		query += fmt.Sprintf(" ORDER BY leaderboard_id ASC, expiry_time ASC, score ASC, subscore ASC, owner_id ASC LIMIT %d", batchSize)
+
+		rows, err := db.QueryContext(ctx, query, params...)
+		if err != nil {
+			return 0, err
+		}
+
+		count := 0
+		for rows.Next() {
+			if err = rows.Scan(&ownerIDStr, &score, &subscore, &generation); err != nil {
+				_ = rows.Close()
+				return 0, err
+			}
+			ownerID, err := uuid.FromString(ownerIDStr)
+			if err != nil {
+				_ = rows.Close()
+				return 0, err
+			}
+
+			records[ownerID] = cachedRecord{generation: generation, record: newRank(leaderboard.SortOrder, score, subscore, ownerID)}
+			count++
+		}
+		_ = rows.Close()
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+
+		// Stop pagination when reaching the last (incomplete) page.
+		if count < batchSize {
+			break
+		}
+	}
+
+	rankCache.Lock()
+	for ownerID, record := range records {
+		if cached, found := rankCache.owners[ownerID]; found {
+			if cached.generation > record.generation {
+				// An update may have been received in parallel, keep it.
+				continue
+			}
+			rankCache.cache.Delete(cached.record)
+		}
+		rankCache.owners[ownerID] = record
+		rankCache.cache.Insert(record.record)
+	}
+	for ownerID, generation := range previous {
+		if _, found := records[ownerID]; found {
+			continue
+		}
+		cached, found := rankCache.owners[ownerID]
+		if !found || cached.generation != generation {
+			// Deleted or updated in parallel.
+			continue
+		}
+		// Unchanged since before the rebuild, but no longer has an active record.
+		rankCache.cache.Delete(cached.record)
+		delete(rankCache.owners, ownerID)
+	}
+	count := len(rankCache.owners)
+	rankCache.Unlock()
+
+	return count, nil
+}
+
 func leaderboardCacheInitWorker(
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -511,7 +646,7 @@ func leaderboardCacheInitWorker(
 		for {
 			ranks := make(map[uuid.UUID]skiplist.Interface, batchSize)
 
-			query := "SELECT owner_id, score, subscore, num_score FROM leaderboard_record WHERE leaderboard_id = $1 AND expiry_time = $2"
+			query := "SELECT owner_id, score, subscore, num_score FROM leaderboard_record WHERE leaderboard_id = $1 AND expiry_time = $2" + leaderboardRecordActiveFilter
 			params := []interface{}{leaderboard.Id, expiryTime}
 			if ownerIDStr != "" {
 				query += " AND (leaderboard_id, expiry_time, score, subscore, owner_id) > ($1, $2, $3, $4, $5)"
@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -102,9 +103,55 @@ func NewLocalLeaderboardScheduler(logger *zap.Logger, db *sql.DB, config Config,
 		}
 	}()
 
+	// Remove records written with their own expiry from the rank cache once they expire. Listings exclude expired records
+	// straight away, but ranks may count them until the next pass, up to a minute after they expire.
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		since := time.Unix(0, 0).UTC()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C:
+				since = s.trimExpiredRecordRanks(since, t.UTC())
+			}
+		}
+	}()
+
 	return s
 }
 
+// trimExpiredRecordRanks removes records whose own expiry passed after since and at or before until from the rank
+// cache, and returns the time to resume from on the next pass.
+func (ls *LocalLeaderboardScheduler) trimExpiredRecordRanks(since, until time.Time) time.Time {
+	query := "SELECT leaderboard_id, expiry_time, owner_id FROM leaderboard_record WHERE record_expiry_time > $1 AND record_expiry_time <= $2"
+	rows, err := ls.db.QueryContext(ls.ctx, query, since, until)
+	if err != nil {
+		ls.logger.Error("Error reading expired leaderboard records", zap.Error(err))
+		return since
+	}
+	defer rows.Close()
+
+	var leaderboardID string
+	var expiryTime time.Time
+	var ownerID uuid.UUID
+	for rows.Next() {
+		if err = rows.Scan(&leaderboardID, &expiryTime, &ownerID); err != nil {
+			ls.logger.Error("Error parsing expired leaderboard records", zap.Error(err))
+			return since
+		}
+		ls.rankCache.Delete(leaderboardID, expiryTime.Unix(), ownerID)
+	}
+	if err = rows.Err(); err != nil {
+		ls.logger.Error("Error reading expired leaderboard records", zap.Error(err))
+		return since
+	}
+
+	return until
+}
+
 func (ls *LocalLeaderboardScheduler) Start(runtime *Runtime) {
 	ls.logger.Info("Leaderboard scheduler start")
 	ls.started = true
@@ -163,6 +163,7 @@ func (s *testMetrics) Api(name string, elapsed time.Duration, recvBytes, sentByt
 
 func (s *testMetrics) ApiRpc(id string, elapsed time.Duration, recvBytes, sentBytes int64, isErr bool) {
 }
+func (s *testMetrics) RuntimeRpc(id string, elapsed time.Duration, isErr bool)              {}
 func (s *testMetrics) ApiBefore(name string, elapsed time.Duration, isErr bool)             {}
 func (s *testMetrics) ApiAfter(name string, elapsed time.Duration, isErr bool)              {}
 func (s *testMetrics) Message(recvBytes int64, isErr bool)                                  {}
@@ -184,6 +185,8 @@ func (s *testMetrics) StorageWriteRejectCount(tags map[string]string, delta int6
 func (s *testMetrics) CustomCounter(name string, tags map[string]string, delta int64)       {}
 func (s *testMetrics) CustomGauge(name string, tags map[string]string, value float64)       {}
 func (s *testMetrics) CustomTimer(name string, tags map[string]string, value time.Duration) {}
+func (s *testMetrics) CustomHistogramRegister(name string, buckets []float64) error         { return nil }
+func (s *testMetrics) CustomHistogram(name string, tags map[string]string, value float64)   {}
 
 // testMessageRouter is used for testing, and can fire a callback
 // when the SendToPresenceIDs method is invoked
@@ -191,14 +194,17 @@ type testMessageRouter struct {
 	sendToPresence func(presences []*PresenceID, envelope *rtapi.Envelope)
 }
 
-func (s *testMessageRouter) SendToPresenceIDs(_ *zap.Logger, presences []*PresenceID, envelope *rtapi.Envelope, _ bool) {
+func (s *testMessageRouter) SendToPresenceIDs(_ *zap.Logger, presences []*PresenceID, envelope *rtapi.Envelope, _ bool) int {
 	if s.sendToPresence != nil {
 		s.sendToPresence(presences, envelope)
 	}
+	return len(presences)
 }
-func (s *testMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) {}
-func (s *testMessageRouter) SendDeferred(*zap.Logger, []*DeferredMessage)                    {}
-func (s *testMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool)                    {}
+func (s *testMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) int {
+	return 0
+}
+func (s *testMessageRouter) SendDeferred(*zap.Logger, []*DeferredMessage) {}
+func (s *testMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool) {}
 
 // testTracker implements the Tracker interface and does nothing
 type testTracker struct{}
@@ -207,7 +213,9 @@ func (s *testTracker) SetMatchJoinListener(func(id uuid.UUID, joins []*MatchPres
 func (s *testTracker) SetMatchLeaveListener(func(id uuid.UUID, leaves []*MatchPresence)) {}
 func (s *testTracker) SetPartyJoinListener(func(id uuid.UUID, joins []*Presence))        {}
 func (s *testTracker) SetPartyLeaveListener(func(id uuid.UUID, leaves []*Presence))      {}
-func (s *testTracker) Stop()                                                             {}
+func (s *testTracker) SetStreamPresenceListeners(map[uint8]func(stream PresenceStream, joins, leaves []*Presence)) {
+}
+func (s *testTracker) Stop() {}
 
 // Track returns success true/false, and new presence true/false.
 func (s *testTracker) Track(ctx context.Context, sessionID uuid.UUID, stream PresenceStream, userID uuid.UUID, meta PresenceMeta) (bool, bool) {
@@ -278,6 +286,11 @@ func (s *testTracker) ListByStream(stream PresenceStream, includeHidden bool, in
 	return nil
 }
 
+// List a page of presences by stream ordered by presence ID.
+func (s *testTracker) ListByStreamPage(stream PresenceStream, includeHidden bool, includeNotHidden bool, limit int, after *PresenceID) ([]*Presence, *PresenceID) {
+	return nil, nil
+}
+
 // Fast lookup of local session IDs to use for message delivery.
 func (s *testTracker) ListLocalSessionIDByStream(stream PresenceStream) []uuid.UUID {
 	return nil
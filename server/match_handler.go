@@ -96,15 +96,17 @@ type MatchHandler struct {
 	tick int64
 
 	// Control elements.
-	emptyTicks    int
-	maxEmptyTicks int
-	inputCh       chan *MatchDataMessage
-	ticker        *time.Ticker
-	callCh        chan func(*MatchHandler)
-	joinAttemptCh chan func(*MatchHandler)
-	signalCh      chan func(*MatchHandler)
-	stopCh        chan struct{}
-	stopped       *atomic.Bool
+	emptyTicks     int
+	maxEmptyTicks  int
+	stopEmptyTicks int
+	stopSignalled  bool
+	inputCh        chan *MatchDataMessage
+	ticker         *time.Ticker
+	callCh         chan func(*MatchHandler)
+	joinAttemptCh  chan func(*MatchHandler)
+	signalCh       chan func(*MatchHandler)
+	stopCh         chan struct{}
+	stopped        *atomic.Bool
 
 	deferredCh chan *DeferredMessage
 
@@ -115,7 +117,7 @@ type MatchHandler struct {
 	state interface{}
 }
 
-func NewMatchHandler(logger *zap.Logger, config Config, sessionRegistry SessionRegistry, matchRegistry MatchRegistry, router MessageRouter, core RuntimeMatchCore, id uuid.UUID, node string, stopped *atomic.Bool, params map[string]interface{}) (*MatchHandler, error) {
+func NewMatchHandler(logger *zap.Logger, config Config, sessionRegistry SessionRegistry, matchRegistry MatchRegistry, router MessageRouter, core RuntimeMatchCore, id uuid.UUID, node string, stopped *atomic.Bool, params map[string]interface{}, opts *MatchCreateOptions) (*MatchHandler, error) {
 	presenceList := NewMatchPresenceList()
 	deferredCh := make(chan *DeferredMessage, config.GetMatch().DeferredQueueSize)
 	deferMessageFn := func(msg *DeferredMessage) error {
@@ -134,6 +136,11 @@ func NewMatchHandler(logger *zap.Logger, config Config, sessionRegistry SessionR
 		return nil, err
 	}
 
+	var stopEmptyTicks int
+	if opts != nil && opts.StopWhenEmptyAfterSec > 0 {
+		stopEmptyTicks = rateInt * opts.StopWhenEmptyAfterSec
+	}
+
 	// Construct the match.
 	mh := &MatchHandler{
 		logger:          logger,
@@ -156,9 +163,10 @@ func NewMatchHandler(logger *zap.Logger, config Config, sessionRegistry SessionR
 
 		tick: 0,
 
-		emptyTicks:    0,
-		maxEmptyTicks: rateInt * config.GetMatch().MaxEmptySec,
-		inputCh:       make(chan *MatchDataMessage, config.GetMatch().InputQueueSize),
+		emptyTicks:     0,
+		maxEmptyTicks:  rateInt * config.GetMatch().MaxEmptySec,
+		stopEmptyTicks: stopEmptyTicks,
+		inputCh:        make(chan *MatchDataMessage, config.GetMatch().InputQueueSize),
 		// Ticker below.
 		callCh:        make(chan func(mh *MatchHandler), config.GetMatch().CallQueueSize),
 		joinAttemptCh: make(chan func(mh *MatchHandler), config.GetMatch().JoinAttemptQueueSize),
@@ -313,15 +321,21 @@ func loop(mh *MatchHandler) {
 	}
 
 	// Check if the match has been empty too long.
-	if mh.maxEmptyTicks > 0 {
+	if mh.maxEmptyTicks > 0 || mh.stopEmptyTicks > 0 {
 		if mh.PresenceList.size.Load() == 0 {
 			mh.emptyTicks++
-			if mh.emptyTicks >= mh.maxEmptyTicks {
+			if mh.maxEmptyTicks > 0 && mh.emptyTicks >= mh.maxEmptyTicks {
 				// Match has reached its empty limit.
 				mh.Stop()
 				mh.logger.Warn("Stopping idle empty match", zap.Int64("tick", mh.tick), zap.Int("empty_ticks", mh.emptyTicks))
 				return
 			}
+			if mh.stopEmptyTicks > 0 && mh.emptyTicks >= mh.stopEmptyTicks && !mh.stopSignalled {
+				// Match has reached the empty limit it was created with, give the handler a chance to terminate cleanly.
+				mh.stopSignalled = true
+				mh.logger.Info("Signalling empty match to stop", zap.Int64("tick", mh.tick), zap.Int("empty_ticks", mh.emptyTicks))
+				mh.QueueTerminate(0)
+			}
 		} else if mh.emptyTicks > 0 {
 			// If the match is not empty make sure to reset any counter value.
 			// Only consecutive empty ticks should count towards the limit.
@@ -84,11 +84,19 @@ type MatchGetStateResult struct {
 	State     string
 }
 
+// MatchCreateOptions holds optional per-match behaviour set at creation time.
+type MatchCreateOptions struct {
+	// If greater than 0, the match is signalled to terminate once it has had no presences for this many consecutive seconds.
+	StopWhenEmptyAfterSec int
+}
+
 type MatchRegistry interface {
 	// Create and start a new match, given a Lua module name or registered Go or JS match function.
 	CreateMatch(ctx context.Context, createFn RuntimeMatchCreateFunction, module string, params map[string]interface{}) (string, error)
+	// Create and start a new match as with CreateMatch, applying the given creation options.
+	CreateMatchWithOptions(ctx context.Context, createFn RuntimeMatchCreateFunction, module string, params map[string]interface{}, opts *MatchCreateOptions) (string, error)
 	// Register and initialise a match that's ready to run.
-	NewMatch(logger *zap.Logger, id uuid.UUID, core RuntimeMatchCore, stopped *atomic.Bool, params map[string]interface{}) (*MatchHandler, error)
+	NewMatch(logger *zap.Logger, id uuid.UUID, core RuntimeMatchCore, stopped *atomic.Bool, params map[string]interface{}, opts *MatchCreateOptions) (*MatchHandler, error)
 	// Return a match by ID.
 	GetMatch(ctx context.Context, id string) (*api.Match, string, error)
 	// Remove a tracked match and ensure all its presences are cleaned up.
@@ -99,6 +107,11 @@ type MatchRegistry interface {
 	// List (and optionally filter) currently running matches.
 	// This can list across both authoritative and relayed matches.
 	ListMatches(ctx context.Context, limit int, authoritative *wrapperspb.BoolValue, label *wrapperspb.StringValue, minSize *wrapperspb.Int32Value, maxSize *wrapperspb.Int32Value, query *wrapperspb.StringValue, node *wrapperspb.StringValue) ([]*api.Match, []string, error)
+	// Count currently running matches using the same authoritative, label and query filters as ListMatches, without listing them.
+	CountMatches(ctx context.Context, authoritative *wrapperspb.BoolValue, label *wrapperspb.StringValue, query *wrapperspb.StringValue) (int, error)
+	// Stop all matches on this node created from the given handler, returning the number of matches signalled or stopped.
+	// Matches that had already stopped are not counted.
+	StopMatchesByHandler(handlerName string, graceSeconds int) int
 	// Stop the match registry and close all matches it's tracking.
 	Stop(graceSeconds int) chan struct{}
 	// Returns the total number of currently active authoritative matches.
@@ -224,6 +237,10 @@ func (r *LocalMatchRegistry) processLabelUpdates(batch *index.Batch) {
 }
 
 func (r *LocalMatchRegistry) CreateMatch(ctx context.Context, createFn RuntimeMatchCreateFunction, module string, params map[string]interface{}) (string, error) {
+	return r.CreateMatchWithOptions(ctx, createFn, module, params, nil)
+}
+
+func (r *LocalMatchRegistry) CreateMatchWithOptions(ctx context.Context, createFn RuntimeMatchCreateFunction, module string, params map[string]interface{}, opts *MatchCreateOptions) (string, error) {
 	buf := &bytes.Buffer{}
 	if err := gob.NewEncoder(buf).Encode(params); err != nil {
 		return "", runtime.ErrCannotEncodeParams
@@ -245,7 +262,7 @@ func (r *LocalMatchRegistry) CreateMatch(ctx context.Context, createFn RuntimeMa
 	}
 
 	// Start the match.
-	mh, err := r.NewMatch(matchLogger, id, core, stopped, params)
+	mh, err := r.NewMatch(matchLogger, id, core, stopped, params, opts)
 	if err != nil {
 		return "", fmt.Errorf("error creating match: %v", err.Error())
 	}
@@ -253,13 +270,13 @@ func (r *LocalMatchRegistry) CreateMatch(ctx context.Context, createFn RuntimeMa
 	return mh.IDStr, nil
 }
 
-func (r *LocalMatchRegistry) NewMatch(logger *zap.Logger, id uuid.UUID, core RuntimeMatchCore, stopped *atomic.Bool, params map[string]interface{}) (*MatchHandler, error) {
+func (r *LocalMatchRegistry) NewMatch(logger *zap.Logger, id uuid.UUID, core RuntimeMatchCore, stopped *atomic.Bool, params map[string]interface{}, opts *MatchCreateOptions) (*MatchHandler, error) {
 	if r.stopped.Load() {
 		// Server is shutting down, reject new matches.
 		return nil, errors.New("shutdown in progress")
 	}
 
-	match, err := NewMatchHandler(logger, r.config, r.sessionRegistry, r, r.router, core, id, r.node, stopped, params)
+	match, err := NewMatchHandler(logger, r.config, r.sessionRegistry, r, r.router, core, id, r.node, stopped, params, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -644,6 +661,96 @@ func (r *LocalMatchRegistry) ListMatches(ctx context.Context, limit int, authori
 	return results, nodes, nil
 }
 
+func (r *LocalMatchRegistry) CountMatches(ctx context.Context, authoritative *wrapperspb.BoolValue, label *wrapperspb.StringValue, queryString *wrapperspb.StringValue) (int, error) {
+	var q bluge.Query
+	switch {
+	case queryString != nil:
+		if queryString.Value == "" {
+			q = bluge.NewMatchAllQuery()
+		} else {
+			parsed, err := ParseQueryString(queryString.Value)
+			if err != nil {
+				return 0, fmt.Errorf("error parsing query string: %v", err.Error())
+			}
+			q = parsed
+		}
+	case label != nil:
+		indexQuery := bluge.NewTermQuery(label.Value)
+		indexQuery.SetField("label_string")
+		q = indexQuery
+	default:
+		// Without a label or query filter there is no need to consult the index.
+		var count int
+		if authoritative == nil || authoritative.Value {
+			count += int(r.matchCount.Load())
+		}
+		if authoritative == nil || !authoritative.Value {
+			count += len(r.tracker.CountByStreamModeFilter(MatchFilterRelayed))
+		}
+		return count, nil
+	}
+
+	if authoritative != nil && !authoritative.Value {
+		// A filter on label or query is requested but authoritative matches are not allowed.
+		return 0, nil
+	}
+
+	indexReader, err := r.indexWriter.Reader()
+	if err != nil {
+		return 0, fmt.Errorf("error accessing index reader: %v", err.Error())
+	}
+	defer func() {
+		if err := indexReader.Close(); err != nil {
+			r.logger.Error("error closing index reader", zap.Error(err))
+		}
+	}()
+
+	dmi, err := indexReader.Search(ctx, bluge.NewTopNSearch(0, q).WithStandardAggregations())
+	if err != nil {
+		return 0, fmt.Errorf("error counting matches: %v", err.Error())
+	}
+
+	return int(dmi.Aggregations().Count()), nil
+}
+
+func (r *LocalMatchRegistry) StopMatchesByHandler(handlerName string, graceSeconds int) int {
+	seen := make(map[uuid.UUID]struct{})
+	var count int
+	// Sweep again while passes find new matches, so matches created while signalling are not missed. The number of
+	// passes is bounded in case matches of this handler are being created continuously.
+	for pass := 0; pass < 10; pass++ {
+		var found bool
+		r.matches.Range(func(id uuid.UUID, mh *MatchHandler) bool {
+			if _, ok := seen[id]; ok || mh.HandlerName() != handlerName {
+				return true
+			}
+			seen[id] = struct{}{}
+			found = true
+			if mh.QueueTerminate(graceSeconds) {
+				count++
+				if graceSeconds > 0 {
+					// The terminate function may return a state to keep the match running, so end it once the grace period expires.
+					time.AfterFunc(time.Duration(graceSeconds)*time.Second, mh.Stop)
+				}
+			} else if !mh.stopped.Load() {
+				// If the call queue is full stop the match outright rather than leave it running.
+				mh.Stop()
+				count++
+			}
+			return true
+		})
+		if !found {
+			break
+		}
+	}
+
+	if count > 0 {
+		r.logger.Info("Stopped matches by handler", zap.String("handler_name", handlerName), zap.Int("count", count))
+	}
+
+	return count
+}
+
 func (r *LocalMatchRegistry) Stop(graceSeconds int) chan struct{} {
 	// Mark the match registry as stopped, but allow further calls here to signal periodic termination to any matches still running.
 	r.stopped.Store(true)
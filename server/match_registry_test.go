@@ -28,6 +28,7 @@ import (
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncode(t *testing.T) {
@@ -138,6 +139,89 @@ func TestMatchRegistryAuthoritativeMatchAndJoin(t *testing.T) {
 	}
 }
 
+// should create authoritative match that stops itself after staying empty
+func TestMatchRegistryAuthoritativeMatchStopWhenEmpty(t *testing.T) {
+	consoleLogger := loggerForTest(t)
+	matchRegistry, runtimeMatchCreateFunc, err := createTestMatchRegistry(t, consoleLogger)
+	if err != nil {
+		t.Fatalf("error creating test match registry: %v", err)
+	}
+	defer matchRegistry.Stop(0)
+
+	res, err := matchRegistry.CreateMatchWithOptions(context.Background(),
+		runtimeMatchCreateFunc, "match", map[string]interface{}{}, &MatchCreateOptions{StopWhenEmptyAfterSec: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = matchRegistry.CreateMatch(context.Background(),
+		runtimeMatchCreateFunc, "match", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := matchRegistry.Count(); count != 2 {
+		t.Fatalf("expected 2 matches, got %d", count)
+	}
+
+	// Drive the empty ticks directly rather than waiting for the match ticker.
+	matchID, err := matchUUIDFromString(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mh, found := matchRegistry.matches.Load(matchID)
+	if !found {
+		t.Fatal("expected match handler to be registered")
+	}
+	for i := 0; i < mh.stopEmptyTicks; i++ {
+		if !mh.queueCall(loop) {
+			break
+		}
+	}
+
+	require.Eventually(t, func() bool { return matchRegistry.Count() == 1 }, 500*time.Millisecond, 10*time.Millisecond, "expected empty match to stop")
+}
+
+func TestMatchRegistryStopMatchesByHandler(t *testing.T) {
+	consoleLogger := loggerForTest(t)
+	matchRegistry, runtimeMatchCreateFunc, err := createTestMatchRegistry(t, consoleLogger)
+	if err != nil {
+		t.Fatalf("error creating test match registry: %v", err)
+	}
+	defer matchRegistry.Stop(0)
+
+	for i := 0; i < 2; i++ {
+		_, err = matchRegistry.CreateMatch(context.Background(),
+			runtimeMatchCreateFunc, "match", map[string]interface{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := matchRegistry.StopMatchesByHandler("other", 0); count != 0 {
+		t.Fatalf("expected no matches of other handler, got %d", count)
+	}
+	if count := matchRegistry.StopMatchesByHandler("module", 0); count != 2 {
+		t.Fatalf("expected 2 matches signalled, got %d", count)
+	}
+
+	require.Eventually(t, func() bool { return matchRegistry.Count() == 0 }, 2*time.Second, 10*time.Millisecond)
+
+	// Matches that keep running after their terminate function are stopped once the grace period expires.
+	_, err = matchRegistry.CreateMatch(context.Background(),
+		runtimeMatchCreateFunc, "match", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := matchRegistry.StopMatchesByHandler("module", 1); count != 1 {
+		t.Fatalf("expected 1 match signalled, got %d", count)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if count := matchRegistry.Count(); count != 1 {
+		t.Fatalf("expected match to run during the grace period, got %d matches", count)
+	}
+	require.Eventually(t, func() bool { return matchRegistry.Count() == 0 }, 2*time.Second, 10*time.Millisecond)
+}
+
 // should create authoritative match, list matches without querying
 func TestMatchRegistryAuthoritativeMatchAndListMatches(t *testing.T) {
 	consoleLogger := loggerForTest(t)
@@ -243,6 +327,40 @@ func TestMatchRegistryAuthoritativeMatchAndListMatchesWithQuerying(t *testing.T)
 	}
 }
 
+// should create authoritative matches, count matches with querying
+func TestMatchRegistryAuthoritativeMatchCountMatchesWithQuerying(t *testing.T) {
+	consoleLogger := loggerForTest(t)
+	matchRegistry, runtimeMatchCreateFunc, err := createTestMatchRegistry(t, consoleLogger)
+	if err != nil {
+		t.Fatalf("error creating test match registry: %v", err)
+	}
+	defer matchRegistry.Stop(0)
+
+	for _, skill := range []int{40, 60, 80} {
+		_, err = matchRegistry.CreateMatch(context.Background(),
+			runtimeMatchCreateFunc, "match", map[string]interface{}{
+				"label": fmt.Sprintf(`{"skill":%d}`, skill),
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matchRegistry.processLabelUpdates(bluge.NewBatch())
+
+	count, err := matchRegistry.CountMatches(context.Background(), nil, nil, wrapperspb.String("+label.skill:>=50"))
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = matchRegistry.CountMatches(context.Background(), wrapperspb.Bool(false), nil, wrapperspb.String("+label.skill:>=50"))
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	count, err = matchRegistry.CountMatches(context.Background(), wrapperspb.Bool(true), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+}
+
 // should create authoritative match, list matches with query *
 func TestMatchRegistryAuthoritativeMatchAndListAllMatchesWithQueryStar(t *testing.T) {
 	consoleLogger := loggerForTest(t)
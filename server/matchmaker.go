@@ -401,12 +401,19 @@ func (m *LocalMatchmaker) Process() {
 
 	m.Unlock()
 
-	// Run the custom matching function if one is registered in the runtime, otherwise use the default process function.
+	// Use proposals from the runtime propose function if one is registered and returns any. Otherwise run the custom
+	// matching function if one is registered in the runtime, or else the default process function.
 	var matchedEntries [][]*MatchmakerEntry
 	var expiredActiveIndexes []string
-	if m.runtime.matchmakerOverrideFunction != nil {
+	var proposed bool
+	if m.runtime.matchmakerProposeFunction != nil {
+		matchedEntries, expiredActiveIndexes, proposed = m.processProposed(activeIndexesCopy, indexesCopy)
+	}
+	switch {
+	case proposed:
+	case m.runtime.matchmakerOverrideFunction != nil:
 		matchedEntries, expiredActiveIndexes = m.processCustom(activeIndexesCopy, indexCount, indexesCopy)
-	} else {
+	default:
 		matchedEntries, expiredActiveIndexes = m.processDefault(activeIndexCount, activeIndexesCopy, indexCount, indexesCopy)
 	}
 
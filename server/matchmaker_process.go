@@ -630,3 +630,103 @@ func combineIndexes(from []*MatchmakerIndex, min, max int) <-chan []*MatchmakerI
 	}()
 	return c
 }
+
+// processProposed asks the runtime propose function to group tickets from the whole pool. Returns false if the function
+// declined to propose anything or failed, in which case the regular process should run instead. Proposals naming
+// unknown or already selected tickets, repeating a session, or failing any included ticket's count constraints are
+// discarded.
+func (m *LocalMatchmaker) processProposed(activeIndexesCopy map[string]*MatchmakerIndex, indexesCopy map[string]*MatchmakerIndex) ([][]*MatchmakerEntry, []string, bool) {
+	if m.active.Load() != 1 {
+		return nil, nil, false
+	}
+
+	tickets := make([]*MatchmakerIndex, 0, len(indexesCopy))
+	for _, index := range indexesCopy {
+		tickets = append(tickets, index)
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].CreatedAt < tickets[j].CreatedAt
+	})
+
+	proposals, err := m.runtime.matchmakerProposeFunction(m.ctx, tickets)
+	if err != nil {
+		m.logger.Error("error running matchmaker propose function", zap.Error(err))
+		return nil, nil, false
+	}
+	if proposals == nil {
+		return nil, nil, false
+	}
+
+	expiredActiveIndexes := make([]string, 0, 10)
+	for ticket, index := range activeIndexesCopy {
+		index.Intervals++
+		if index.Intervals >= m.config.GetMatchmaker().MaxIntervals || index.MinCount == index.MaxCount {
+			expiredActiveIndexes = append(expiredActiveIndexes, ticket)
+		}
+	}
+
+	matchedEntries := make([][]*MatchmakerEntry, 0, len(proposals))
+	selectedTickets := make(map[string]struct{}, len(indexesCopy))
+	batch := bluge.NewBatch()
+	var batchSize int
+	for _, proposal := range proposals {
+		indexes := make([]*MatchmakerIndex, 0, len(proposal))
+		sessionIDs := make(map[string]struct{}, len(proposal))
+		var count int
+		valid := len(proposal) > 0
+		for _, ticket := range proposal {
+			index, found := indexesCopy[ticket]
+			if !found {
+				valid = false
+				break
+			}
+			if _, found := selectedTickets[ticket]; found {
+				valid = false
+				break
+			}
+			for sessionID := range index.SessionIDs {
+				if _, found := sessionIDs[sessionID]; found {
+					valid = false
+					break
+				}
+				sessionIDs[sessionID] = struct{}{}
+			}
+			if !valid {
+				break
+			}
+			indexes = append(indexes, index)
+			count += index.Count
+		}
+		if !valid {
+			m.logger.Debug("discarding invalid matchmaker proposal", zap.Strings("tickets", proposal))
+			continue
+		}
+
+		for _, index := range indexes {
+			if index.MinCount > count || index.MaxCount < count || count%index.CountMultiple != 0 {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			m.logger.Debug("discarding matchmaker proposal that does not satisfy ticket counts", zap.Strings("tickets", proposal), zap.Int("count", count))
+			continue
+		}
+
+		currentMatchedEntries := make([]*MatchmakerEntry, 0, count)
+		for _, index := range indexes {
+			currentMatchedEntries = append(currentMatchedEntries, index.Entries...)
+			selectedTickets[index.Ticket] = struct{}{}
+			batchSize++
+			batch.Delete(bluge.Identifier(index.Ticket))
+		}
+		matchedEntries = append(matchedEntries, currentMatchedEntries)
+	}
+	if batchSize > 0 {
+		if err := m.indexWriter.Batch(batch); err != nil {
+			m.logger.Error("error deleting matchmaker process entries batch", zap.Error(err))
+		}
+	}
+
+	return matchedEntries, expiredActiveIndexes, true
+}
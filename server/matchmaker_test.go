@@ -1473,6 +1473,107 @@ func TestMatchmakerAddMultipleAndSomeMatchOptionalTextAlteringScore(t *testing.T
 	// cannot be assured which one
 }
 
+// should match the tickets proposed by the runtime hook and leave invalid proposals in the pool
+func TestMatchmakerProposed(t *testing.T) {
+	consoleLogger := loggerForTest(t)
+	matchesSeen := make(map[string]*rtapi.MatchmakerMatched)
+	matchMaker, cleanup, err := createTestMatchmaker(t, consoleLogger, true, func(presences []*PresenceID, envelope *rtapi.Envelope) {
+		if len(presences) == 1 {
+			matchesSeen[presences[0].SessionID.String()] = envelope.GetMatchmakerMatched()
+		}
+	})
+	if err != nil {
+		t.Fatalf("error creating test matchmaker: %v", err)
+	}
+	defer cleanup()
+
+	sessionIDs := make([]uuid.UUID, 0, 3)
+	tickets := make([]string, 0, 3)
+	for _, name := range []string{"a", "b", "c"} {
+		sessionID := uuid.Must(uuid.NewV4())
+		ticket, _, err := matchMaker.Add(context.Background(), []*MatchmakerPresence{
+			{
+				UserId:    name,
+				SessionId: name,
+				Username:  name,
+				Node:      name,
+				SessionID: sessionID,
+			},
+		}, sessionID.String(), "",
+			"properties.d1:foo",
+			2, 2, 1,
+			map[string]string{
+				"d1": "foo",
+			}, map[string]float64{})
+		if err != nil {
+			t.Fatalf("error matchmaker add: %v", err)
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+		tickets = append(tickets, ticket)
+	}
+
+	var proposeCalls int
+	matchMaker.runtime.matchmakerProposeFunction = func(ctx context.Context, pool []*MatchmakerIndex) ([][]string, error) {
+		proposeCalls++
+		assert.Len(t, pool, 3)
+		// The first and last tickets form a valid match, the second ticket alone is below its min count.
+		return [][]string{{tickets[0], tickets[2]}, {tickets[1]}}, nil
+	}
+
+	matchMaker.Process()
+
+	assert.Equal(t, 1, proposeCalls)
+	assert.Contains(t, matchesSeen, sessionIDs[0].String())
+	assert.Contains(t, matchesSeen, sessionIDs[2].String())
+	assert.NotContains(t, matchesSeen, sessionIDs[1].String())
+	assert.Len(t, matchMaker.indexes, 1, "unmatched ticket should remain in the pool")
+}
+
+// should fall back to the default matching process when the runtime hook proposes nothing
+func TestMatchmakerProposedFallback(t *testing.T) {
+	consoleLogger := loggerForTest(t)
+	matchesSeen := make(map[string]*rtapi.MatchmakerMatched)
+	matchMaker, cleanup, err := createTestMatchmaker(t, consoleLogger, true, func(presences []*PresenceID, envelope *rtapi.Envelope) {
+		if len(presences) == 1 {
+			matchesSeen[presences[0].SessionID.String()] = envelope.GetMatchmakerMatched()
+		}
+	})
+	if err != nil {
+		t.Fatalf("error creating test matchmaker: %v", err)
+	}
+	defer cleanup()
+
+	for _, name := range []string{"a", "b"} {
+		sessionID := uuid.Must(uuid.NewV4())
+		if _, _, err := matchMaker.Add(context.Background(), []*MatchmakerPresence{
+			{
+				UserId:    name,
+				SessionId: name,
+				Username:  name,
+				Node:      name,
+				SessionID: sessionID,
+			},
+		}, sessionID.String(), "",
+			"properties.d1:foo",
+			2, 2, 1,
+			map[string]string{
+				"d1": "foo",
+			}, map[string]float64{}); err != nil {
+			t.Fatalf("error matchmaker add: %v", err)
+		}
+	}
+
+	// Returning nil leaves matching to the default process.
+	matchMaker.runtime.matchmakerProposeFunction = func(ctx context.Context, pool []*MatchmakerIndex) ([][]string, error) {
+		return nil, nil
+	}
+
+	matchMaker.Process()
+
+	assert.Len(t, matchesSeen, 2)
+	assert.Empty(t, matchMaker.indexes)
+}
+
 // should add to matchmaker and match authoritative
 func TestMatchmakerAddAndMatchAuthoritative(t *testing.T) {
 	consoleLogger := loggerForTest(t)
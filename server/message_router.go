@@ -31,8 +31,10 @@ type DeferredMessage struct {
 
 // MessageRouter is responsible for sending a message to a list of presences or to an entire stream.
 type MessageRouter interface {
-	SendToPresenceIDs(*zap.Logger, []*PresenceID, *rtapi.Envelope, bool)
-	SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool)
+	// SendToPresenceIDs returns a best-effort count of the presences the message was delivered to.
+	SendToPresenceIDs(*zap.Logger, []*PresenceID, *rtapi.Envelope, bool) int
+	// SendToStream returns a best-effort count of the presences the message was delivered to.
+	SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) int
 	SendDeferred(*zap.Logger, []*DeferredMessage)
 	SendToAll(*zap.Logger, *rtapi.Envelope, bool)
 }
@@ -51,15 +53,16 @@ func NewLocalMessageRouter(sessionRegistry SessionRegistry, tracker Tracker, pro
 	}
 }
 
-func (r *LocalMessageRouter) SendToPresenceIDs(logger *zap.Logger, presenceIDs []*PresenceID, envelope *rtapi.Envelope, reliable bool) {
+func (r *LocalMessageRouter) SendToPresenceIDs(logger *zap.Logger, presenceIDs []*PresenceID, envelope *rtapi.Envelope, reliable bool) int {
 	if len(presenceIDs) == 0 {
-		return
+		return 0
 	}
 
 	// Prepare payload variables but do not initialize until we hit a session that needs them to avoid unnecessary work.
 	var payloadProtobuf []byte
 	var payloadJSON []byte
 
+	var delivered int
 	for _, presenceID := range presenceIDs {
 		session := r.sessionRegistry.Get(presenceID.SessionID)
 		if session == nil {
@@ -75,7 +78,7 @@ func (r *LocalMessageRouter) SendToPresenceIDs(logger *zap.Logger, presenceIDs [
 				payloadProtobuf, err = proto.Marshal(envelope)
 				if err != nil {
 					logger.Error("Could not marshal message", zap.Error(err))
-					return
+					return delivered
 				}
 			}
 			err = session.SendBytes(payloadProtobuf, reliable)
@@ -88,20 +91,24 @@ func (r *LocalMessageRouter) SendToPresenceIDs(logger *zap.Logger, presenceIDs [
 					payloadJSON = buf
 				} else {
 					logger.Error("Could not marshal message", zap.Error(err))
-					return
+					return delivered
 				}
 			}
 			err = session.SendBytes(payloadJSON, reliable)
 		}
 		if err != nil {
 			logger.Error("Failed to route message", zap.String("sid", presenceID.SessionID.String()), zap.Error(err))
+			continue
 		}
+		delivered++
 	}
+
+	return delivered
 }
 
-func (r *LocalMessageRouter) SendToStream(logger *zap.Logger, stream PresenceStream, envelope *rtapi.Envelope, reliable bool) {
+func (r *LocalMessageRouter) SendToStream(logger *zap.Logger, stream PresenceStream, envelope *rtapi.Envelope, reliable bool) int {
 	presenceIDs := r.tracker.ListPresenceIDByStream(stream)
-	r.SendToPresenceIDs(logger, presenceIDs, envelope, reliable)
+	return r.SendToPresenceIDs(logger, presenceIDs, envelope, reliable)
 }
 
 func (r *LocalMessageRouter) SendDeferred(logger *zap.Logger, messages []*DeferredMessage) {
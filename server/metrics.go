@@ -17,9 +17,11 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -40,6 +42,7 @@ type Metrics interface {
 
 	Api(name string, elapsed time.Duration, recvBytes, sentBytes int64, isErr bool)
 	ApiRpc(id string, elapsed time.Duration, recvBytes, sentBytes int64, isErr bool)
+	RuntimeRpc(id string, elapsed time.Duration, isErr bool)
 	ApiBefore(name string, elapsed time.Duration, isErr bool)
 	ApiAfter(name string, elapsed time.Duration, isErr bool)
 
@@ -67,8 +70,13 @@ type Metrics interface {
 	CustomCounter(name string, tags map[string]string, delta int64)
 	CustomGauge(name string, tags map[string]string, value float64)
 	CustomTimer(name string, tags map[string]string, value time.Duration)
+	CustomHistogramRegister(name string, buckets []float64) error
+	CustomHistogram(name string, tags map[string]string, value float64)
 }
 
+// Bucket upper bounds used by custom histograms that were not registered with their own buckets.
+var defaultCustomHistogramBuckets = tally.MustMakeExponentialValueBuckets(1, 2, 20)
+
 var _ Metrics = &LocalMetrics{}
 
 type LocalMetrics struct {
@@ -92,6 +100,8 @@ type LocalMetrics struct {
 	prometheusCustomScope tally.Scope
 	prometheusCloser      io.Closer
 	prometheusHTTPServer  *http.Server
+
+	customHistogramBuckets *MapOf[string, tally.Buckets]
 }
 
 func NewLocalMetrics(logger, startupLogger *zap.Logger, db *sql.DB, config Config) *LocalMetrics {
@@ -113,6 +123,8 @@ func NewLocalMetrics(logger, startupLogger *zap.Logger, db *sql.DB, config Confi
 		currentReqCount:  atomic.NewInt64(0),
 		currentRecvBytes: atomic.NewInt64(0),
 		currentSentBytes: atomic.NewInt64(0),
+
+		customHistogramBuckets: &MapOf[string, tally.Buckets]{},
 	}
 
 	go func() {
@@ -298,6 +310,16 @@ func (m *LocalMetrics) ApiRpc(id string, elapsed time.Duration, recvBytes, sentB
 	}
 }
 
+// RuntimeRpc records a single RPC function execution, whichever transport invoked it.
+func (m *LocalMetrics) RuntimeRpc(id string, elapsed time.Duration, isErr bool) {
+	taggedScope := m.PrometheusScope.Tagged(map[string]string{"rpc_id": id})
+	taggedScope.Counter("runtime_rpc_count").Inc(1)
+	taggedScope.Timer("runtime_rpc_latency_ms").Record(elapsed)
+	if isErr {
+		taggedScope.Counter("runtime_rpc_errors").Inc(1)
+	}
+}
+
 func (m *LocalMetrics) ApiBefore(name string, elapsed time.Duration, isErr bool) {
 	name = "before_" + strings.TrimPrefix(name, API_PREFIX)
 
@@ -476,3 +498,37 @@ func (m *LocalMetrics) CustomTimer(name string, tags map[string]string, value ti
 	}
 	scope.Timer(name).Record(value)
 }
+
+// CustomHistogramRegister sets the bucket upper bounds for the histogram with the specified name. Buckets must be
+// registered before the first value is recorded to the histogram, and cannot be changed once registered. Registering
+// the same buckets again is a no-op.
+func (m *LocalMetrics) CustomHistogramRegister(name string, buckets []float64) error {
+	if len(buckets) == 0 {
+		return errors.New("expects at least one bucket")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return errors.New("expects buckets in strictly increasing order")
+		}
+	}
+	if existing, loaded := m.customHistogramBuckets.LoadOrStore(name, tally.ValueBuckets(slices.Clone(buckets))); loaded {
+		if existingBuckets, ok := existing.(tally.ValueBuckets); ok && slices.Equal(existingBuckets, buckets) {
+			return nil
+		}
+		return fmt.Errorf("histogram %q is already registered with different buckets", name)
+	}
+	return nil
+}
+
+// CustomHistogram records the given value to a histogram with the specified name and tags.
+func (m *LocalMetrics) CustomHistogram(name string, tags map[string]string, value float64) {
+	scope := m.prometheusCustomScope
+	if len(tags) != 0 {
+		scope = scope.Tagged(tags)
+	}
+	buckets, ok := m.customHistogramBuckets.Load(name)
+	if !ok {
+		buckets = defaultCustomHistogramBuckets
+	}
+	scope.Histogram(name, buckets).RecordValue(value)
+}
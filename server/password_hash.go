@@ -0,0 +1,182 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var ErrPasswordHashMalformed = errors.New("password hash malformed")
+
+const (
+	argon2idMaxMemory      = 1024 * 1024 // KiB, 1 GiB.
+	argon2idMaxIterations  = 64
+	scryptMaxCostLog2      = 20
+	passwordHashSaltLength = 16
+	passwordHashKeyLength  = 32
+)
+
+// Argon2idParams are the cost parameters for argon2id password hashes.
+type Argon2idParams struct {
+	Memory      uint32 // In KiB.
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultArgon2idParams follow the second recommended option in RFC 9106.
+var DefaultArgon2idParams = Argon2idParams{Memory: 64 * 1024, Iterations: 3, Parallelism: 4}
+
+func (p Argon2idParams) validate() error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Memory > argon2idMaxMemory {
+		return fmt.Errorf("argon2id memory must be between %d and %d KiB", 8*uint32(p.Parallelism), argon2idMaxMemory)
+	}
+	if p.Iterations < 1 || p.Iterations > argon2idMaxIterations {
+		return fmt.Errorf("argon2id iterations must be between 1 and %d", argon2idMaxIterations)
+	}
+	if p.Parallelism < 1 {
+		return errors.New("argon2id parallelism must be at least 1")
+	}
+	return nil
+}
+
+// Argon2idHash hashes a password with argon2id and a random salt, and returns it in the standard encoded format
+// "$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>".
+func Argon2idHash(password string, params Argon2idParams) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, passwordHashSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, passwordHashKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations, params.Parallelism, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Argon2idVerify checks a password against an argon2id hash in the standard encoded format, as produced by
+// Argon2idHash and most other argon2 implementations. Returns ErrPasswordHashMalformed if the hash cannot be parsed or
+// its parameters are out of the accepted range.
+func Argon2idVerify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false, ErrPasswordHashMalformed
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrPasswordHashMalformed
+	}
+
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return false, ErrPasswordHashMalformed
+	}
+	if err := params.validate(); err != nil {
+		return false, ErrPasswordHashMalformed
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return false, ErrPasswordHashMalformed
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, ErrPasswordHashMalformed
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// ScryptParams are the cost parameters for scrypt password hashes.
+type ScryptParams struct {
+	CostLog2    int // N is 2^CostLog2.
+	BlockSize   int // r.
+	Parallelism int // p.
+}
+
+// DefaultScryptParams are the interactive login parameters recommended by the scrypt authors.
+var DefaultScryptParams = ScryptParams{CostLog2: 15, BlockSize: 8, Parallelism: 1}
+
+func (p ScryptParams) validate() error {
+	if p.CostLog2 < 1 || p.CostLog2 > scryptMaxCostLog2 {
+		return fmt.Errorf("scrypt cost must be between 1 and %d", scryptMaxCostLog2)
+	}
+	if p.BlockSize < 1 || p.Parallelism < 1 || p.BlockSize*p.Parallelism >= 1<<30 {
+		return errors.New("scrypt block size and parallelism must be at least 1 and their product less than 2^30")
+	}
+	return nil
+}
+
+// ScryptHash hashes a password with scrypt and a random salt, and returns it in the encoded format
+// "$scrypt$ln=<cost log2>,r=<block size>,p=<parallelism>$<salt>$<hash>".
+func ScryptHash(password string, params ScryptParams) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, passwordHashSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<params.CostLog2, params.BlockSize, params.Parallelism, passwordHashKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", params.CostLog2, params.BlockSize, params.Parallelism, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// ScryptVerify checks a password against a scrypt hash in the encoded format produced by ScryptHash. Returns
+// ErrPasswordHashMalformed if the hash cannot be parsed or its parameters are out of the accepted range.
+func ScryptVerify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != "scrypt" {
+		return false, ErrPasswordHashMalformed
+	}
+
+	var params ScryptParams
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &params.CostLog2, &params.BlockSize, &params.Parallelism); err != nil {
+		return false, ErrPasswordHashMalformed
+	}
+	if err := params.validate(); err != nil {
+		return false, ErrPasswordHashMalformed
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(salt) == 0 {
+		return false, ErrPasswordHashMalformed
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(key) == 0 {
+		return false, ErrPasswordHashMalformed
+	}
+
+	other, err := scrypt.Key([]byte(password), salt, 1<<params.CostLog2, params.BlockSize, params.Parallelism, len(key))
+	if err != nil {
+		return false, ErrPasswordHashMalformed
+	}
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgon2idHashVerify(t *testing.T) {
+	hash, err := Argon2idHash("password", Argon2idParams{Memory: 1024, Iterations: 2, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("error hashing: %v", err.Error())
+	}
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=1024,t=2,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+	match, err := Argon2idVerify("password", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = Argon2idVerify("wrong", hash)
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	for _, malformed := range []string{
+		"",
+		"not a hash",
+		"$argon2i$v=19$m=1024,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=16$m=1024,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=1024,t=2$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=4294967295,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=1024,t=2,p=1$!!!$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+	} {
+		match, err = Argon2idVerify("password", malformed)
+		assert.ErrorIs(t, err, ErrPasswordHashMalformed, malformed)
+		assert.False(t, match)
+	}
+
+	_, err = Argon2idHash("password", Argon2idParams{Memory: 1024, Iterations: 0, Parallelism: 1})
+	assert.Error(t, err)
+}
+
+func TestScryptHashVerify(t *testing.T) {
+	hash, err := ScryptHash("password", ScryptParams{CostLog2: 10, BlockSize: 8, Parallelism: 1})
+	if err != nil {
+		t.Fatalf("error hashing: %v", err.Error())
+	}
+
+	match, err := ScryptVerify("password", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = ScryptVerify("wrong", hash)
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	// Hash produced by Python's hashlib.scrypt with the same encoding.
+	match, err = ScryptVerify("password", "$scrypt$ln=10,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$ZEBCzLptWM7dhpNJDU2HbQ945ovKHmVEozHkePPbSqw")
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	for _, malformed := range []string{
+		"",
+		"$scrypt$ln=10,r=8$MDEyMzQ1Njc4OWFiY2RlZg$ZEBCzLptWM7dhpNJDU2HbQ945ovKHmVEozHkePPbSqw",
+		"$scrypt$ln=40,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$ZEBCzLptWM7dhpNJDU2HbQ945ovKHmVEozHkePPbSqw",
+		"$scrypt$ln=10,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$",
+	} {
+		match, err = ScryptVerify("password", malformed)
+		assert.ErrorIs(t, err, ErrPasswordHashMalformed, malformed)
+		assert.False(t, match)
+	}
+}
@@ -37,7 +37,9 @@ import (
 )
 
 var (
-	ErrRuntimeRPCNotFound = errors.New("RPC function not found")
+	ErrRuntimeRPCNotFound        = errors.New("RPC function not found")
+	ErrRuntimeRPCPayloadTooLarge = errors.New("RPC payload too large")
+	ErrRuntimeRPCUnauthenticated = errors.New("RPC requires an authenticated session")
 )
 
 const API_PREFIX = "/nakama.api.Nakama/"
@@ -217,6 +219,7 @@ type (
 
 	RuntimeMatchmakerMatchedFunction  func(ctx context.Context, entries []*MatchmakerEntry) (string, bool, error)
 	RuntimeMatchmakerOverrideFunction func(ctx context.Context, candidateMatches [][]*MatchmakerEntry) (matches [][]*MatchmakerEntry)
+	RuntimeMatchmakerProposeFunction  func(ctx context.Context, tickets []*MatchmakerIndex) (proposals [][]string, err error)
 
 	RuntimeMatchCreateFunction       func(ctx context.Context, logger *zap.Logger, id uuid.UUID, node string, stopped *atomic.Bool, name string) (RuntimeMatchCore, error)
 	RuntimeMatchDeferMessageFunction func(msg *DeferredMessage) error
@@ -226,6 +229,14 @@ type (
 
 	RuntimeLeaderboardResetFunction func(ctx context.Context, leaderboard *api.Leaderboard, reset int64) error
 
+	RuntimeGroupEventFunction func(ctx context.Context, groupID, userID uuid.UUID, event string) error
+
+	RuntimeNotificationPushFunction func(ctx context.Context, userID uuid.UUID, notification *api.Notification, pushTokens []string) error
+
+	RuntimeStreamPresenceFunction func(ctx context.Context, stream PresenceStream, joins, leaves []*Presence) error
+
+	RuntimePurchaseRefundFunction func(ctx context.Context, userID uuid.UUID, purchase *api.ValidatedPurchase) error
+
 	RuntimePurchaseNotificationAppleFunction      func(ctx context.Context, purchase *api.ValidatedPurchase, providerPayload string) error
 	RuntimeSubscriptionNotificationAppleFunction  func(ctx context.Context, subscription *api.ValidatedSubscription, providerPayload string) error
 	RuntimePurchaseNotificationGoogleFunction     func(ctx context.Context, purchase *api.ValidatedPurchase, providerPayload string) error
@@ -268,6 +279,12 @@ const (
 	RuntimeExecutionModeSubscriptionNotificationGoogle
 	RuntimeExecutionModeStorageIndexFilter
 	RuntimeExecutionModeShutdown
+	RuntimeExecutionModeGroupEvent
+	RuntimeExecutionModeMatchmakerPropose
+	RuntimeExecutionModeNotificationPush
+	RuntimeExecutionModeStreamPresence
+	RuntimeExecutionModePurchaseRefund
+	RuntimeExecutionModeNodeSubscriber
 )
 
 func (e RuntimeExecutionMode) String() string {
@@ -308,6 +325,18 @@ func (e RuntimeExecutionMode) String() string {
 		return "storage_index_filter"
 	case RuntimeExecutionModeShutdown:
 		return "shutdown"
+	case RuntimeExecutionModeGroupEvent:
+		return "group_event"
+	case RuntimeExecutionModeMatchmakerPropose:
+		return "matchmaker_propose"
+	case RuntimeExecutionModeNotificationPush:
+		return "notification_push"
+	case RuntimeExecutionModeStreamPresence:
+		return "stream_presence"
+	case RuntimeExecutionModePurchaseRefund:
+		return "purchase_refund"
+	case RuntimeExecutionModeNodeSubscriber:
+		return "node_subscriber"
 	}
 
 	return ""
@@ -531,6 +560,7 @@ type Runtime struct {
 
 	matchmakerMatchedFunction  RuntimeMatchmakerMatchedFunction
 	matchmakerOverrideFunction RuntimeMatchmakerOverrideFunction
+	matchmakerProposeFunction  RuntimeMatchmakerProposeFunction
 
 	tournamentEndFunction                  RuntimeTournamentEndFunction
 	tournamentResetFunction                RuntimeTournamentResetFunction
@@ -545,6 +575,14 @@ type Runtime struct {
 
 	leaderboardResetFunction RuntimeLeaderboardResetFunction
 
+	groupEventFunction GroupEventFunction
+
+	notificationPushFunction NotificationPushFunction
+
+	streamPresenceListeners map[uint8]func(stream PresenceStream, joins, leaves []*Presence)
+
+	purchaseRefundFunction PurchaseRefundFunction
+
 	eventFunctions *RuntimeEventFunctions
 
 	shutdownFunction RuntimeShutdownFunction
@@ -552,6 +590,60 @@ type Runtime struct {
 	fleetManager runtime.FleetManager
 }
 
+// runtimeCoreHooks forwards events fired by core functions to the runtime hooks registered for them. Runtime functions
+// are created before the runtime providers have registered any hook, so they are handed the methods of a shared
+// instance and each hook is bound once every provider has loaded. Events fired while a hook is unbound are dropped.
+type runtimeCoreHooks struct {
+	logger     *zap.Logger
+	db         *sql.DB
+	eventQueue *RuntimeEventQueue
+
+	groupEvent       RuntimeGroupEventFunction
+	notificationPush RuntimeNotificationPushFunction
+	purchaseRefund   PurchaseRefundFunction
+}
+
+// queue runs a hook invocation on the runtime event queue, so the core function firing the hook does not wait for it
+// and the hook never runs while the caller holds a runtime instance.
+func (h *runtimeCoreHooks) queue(fn func()) {
+	h.eventQueue.Queue(fn)
+}
+
+// GroupEvent runs the group event hook once for each user whose membership changed. The change is already committed
+// when the hook runs, so hook errors are logged rather than returned.
+func (h *runtimeCoreHooks) GroupEvent(groupID uuid.UUID, userIDs []uuid.UUID, event string) {
+	fn := h.groupEvent
+	if fn == nil {
+		return
+	}
+	h.queue(func() {
+		for _, userID := range userIDs {
+			if err := fn(context.Background(), groupID, userID, event); err != nil {
+				h.logger.Error("Error running group event hook.", zap.Error(err), zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()), zap.String("type", event))
+			}
+		}
+	})
+}
+
+// NotificationPush forwards stored notifications to the push hook. Looking up recipients' push tokens is part of the
+// queued work, so it does not delay the send either.
+func (h *runtimeCoreHooks) NotificationPush(notifications map[uuid.UUID][]*api.Notification) {
+	fn := h.notificationPush
+	if fn == nil {
+		return
+	}
+	h.queue(func() {
+		notificationPush(context.Background(), h.logger, h.db, fn, notifications)
+	})
+}
+
+// PurchaseRefund wakes the purchase refund dispatcher, which delivers recorded refunds to the hook on its own schedule.
+func (h *runtimeCoreHooks) PurchaseRefund() {
+	if fn := h.purchaseRefund; fn != nil {
+		fn()
+	}
+}
+
 type MatchNamesListFunction func() []string
 
 type MatchProvider struct {
@@ -665,19 +757,22 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 
 	matchProvider := NewMatchProvider()
 
-	goModules, goRPCFns, goBeforeRtFns, goAfterRtFns, goBeforeReqFns, goAfterReqFns, goMatchmakerMatchedFn, goMatchmakerCustomMatchingFn, goTournamentEndFn, goTournamentResetFn, goLeaderboardResetFn, goShutdownFn, goPurchaseNotificationAppleFn, goSubscriptionNotificationAppleFn, goPurchaseNotificationGoogleFn, goSubscriptionNotificationGoogleFn, goIndexFilterFns, fleetManager, httpHandlers, allEventFns, goMatchNamesListFn, err := NewRuntimeProviderGo(ctx, logger, startupLogger, db, protojsonMarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, storageIndex, runtimeConfig.Path, paths, eventQueue, matchProvider, fmCallbackHandler)
+	// Runtime functions that fire core hooks are created before any hook is registered.
+	coreHooks := &runtimeCoreHooks{logger: logger, db: db, eventQueue: eventQueue}
+
+	goModules, goRPCFns, goBeforeRtFns, goAfterRtFns, goBeforeReqFns, goAfterReqFns, goMatchmakerMatchedFn, goMatchmakerCustomMatchingFn, goTournamentEndFn, goTournamentResetFn, goLeaderboardResetFn, goShutdownFn, goPurchaseNotificationAppleFn, goSubscriptionNotificationAppleFn, goPurchaseNotificationGoogleFn, goSubscriptionNotificationGoogleFn, goIndexFilterFns, fleetManager, httpHandlers, allEventFns, goMatchNamesListFn, err := NewRuntimeProviderGo(ctx, logger, startupLogger, db, protojsonMarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, storageIndex, runtimeConfig.Path, paths, eventQueue, coreHooks.GroupEvent, coreHooks.NotificationPush, coreHooks.PurchaseRefund, matchProvider, fmCallbackHandler)
 	if err != nil {
 		startupLogger.Error("Error initialising Go runtime provider", zap.Error(err))
 		return nil, nil, err
 	}
 
-	luaModules, luaRPCFns, luaBeforeRtFns, luaAfterRtFns, luaBeforeReqFns, luaAfterReqFns, luaMatchmakerMatchedFn, luaTournamentEndFn, luaTournamentResetFn, luaLeaderboardResetFn, luaShutdownFn, luaPurchaseNotificationAppleFn, luaSubscriptionNotificationAppleFn, luaPurchaseNotificationGoogleFn, luaSubscriptionNotificationGoogleFn, luaIndexFilterFns, err := NewRuntimeProviderLua(ctx, logger, startupLogger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, allEventFns.eventFunction, runtimeConfig.Path, paths, matchProvider, storageIndex)
+	luaModules, luaRPCFns, luaBeforeRtFns, luaAfterRtFns, luaBeforeReqFns, luaAfterReqFns, luaMatchmakerMatchedFn, luaTournamentEndFn, luaTournamentResetFn, luaLeaderboardResetFn, luaShutdownFn, luaPurchaseNotificationAppleFn, luaSubscriptionNotificationAppleFn, luaPurchaseNotificationGoogleFn, luaSubscriptionNotificationGoogleFn, luaIndexFilterFns, luaGroupEventFn, luaMatchmakerProposeFn, luaNotificationPushFn, luaStreamPresenceFns, luaPurchaseRefundFn, err := NewRuntimeProviderLua(ctx, logger, startupLogger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, allEventFns.eventFunction, coreHooks.GroupEvent, coreHooks.NotificationPush, coreHooks.PurchaseRefund, runtimeConfig.Path, paths, matchProvider, storageIndex)
 	if err != nil {
 		startupLogger.Error("Error initialising Lua runtime provider", zap.Error(err))
 		return nil, nil, err
 	}
 
-	jsModules, jsRPCFns, jsBeforeRtFns, jsAfterRtFns, jsBeforeReqFns, jsAfterReqFns, jsMatchmakerMatchedFn, jsTournamentEndFn, jsTournamentResetFn, jsLeaderboardResetFn, jsShutdownFn, jsPurchaseNotificationAppleFn, jsSubscriptionNotificationAppleFn, jsPurchaseNotificationGoogleFn, jsSubscriptionNotificationGoogleFn, jsIndexFilterFns, err := NewRuntimeProviderJS(ctx, logger, startupLogger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, allEventFns.eventFunction, runtimeConfig.Path, runtimeConfig.JsEntrypoint, matchProvider, storageIndex)
+	jsModules, jsRPCFns, jsBeforeRtFns, jsAfterRtFns, jsBeforeReqFns, jsAfterReqFns, jsMatchmakerMatchedFn, jsTournamentEndFn, jsTournamentResetFn, jsLeaderboardResetFn, jsShutdownFn, jsPurchaseNotificationAppleFn, jsSubscriptionNotificationAppleFn, jsPurchaseNotificationGoogleFn, jsSubscriptionNotificationGoogleFn, jsIndexFilterFns, err := NewRuntimeProviderJS(ctx, logger, startupLogger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, allEventFns.eventFunction, coreHooks.GroupEvent, coreHooks.NotificationPush, coreHooks.PurchaseRefund, runtimeConfig.Path, runtimeConfig.JsEntrypoint, matchProvider, storageIndex)
 	if err != nil {
 		startupLogger.Error("Error initialising JavaScript runtime provider", zap.Error(err))
 		return nil, nil, err
@@ -721,6 +816,11 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 		goRpcIDs[id] = true
 		startupLogger.Info("Registered Go runtime RPC function invocation", zap.String("id", id))
 	}
+	if config.GetRuntime().RpcMetrics {
+		for id, fn := range allRPCFunctions {
+			allRPCFunctions[id] = runtimeRpcWithMetrics(metrics, id, fn)
+		}
+	}
 
 	allBeforeRtFunctions := make(map[string]RuntimeBeforeRtFunction, len(jsBeforeRtFns)+len(luaBeforeRtFns)+len(goBeforeRtFns))
 	for id, fn := range jsBeforeRtFns {
@@ -2543,6 +2643,13 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 		startupLogger.Info("Registered Go runtime Matchmaker Override function invocation")
 	}
 
+	var allMatchmakerProposeFunction RuntimeMatchmakerProposeFunction
+	switch {
+	case luaMatchmakerProposeFn != nil:
+		allMatchmakerProposeFunction = luaMatchmakerProposeFn
+		startupLogger.Info("Registered Lua runtime Matchmaker Propose function invocation")
+	}
+
 	var allTournamentEndFunction RuntimeTournamentEndFunction
 	switch {
 	case goTournamentEndFn != nil:
@@ -2634,6 +2741,44 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 		startupLogger.Info("Registered JavaScript runtime Subscription Notification Google function invocation")
 	}
 
+	// Only the Lua runtime can register a group event hook, the Go and JavaScript runtime interfaces do not expose one.
+	var allGroupEventFunction GroupEventFunction
+	if luaGroupEventFn != nil {
+		coreHooks.groupEvent = luaGroupEventFn
+		allGroupEventFunction = coreHooks.GroupEvent
+		startupLogger.Info("Registered Lua runtime Group Event function invocation")
+	}
+
+	var allNotificationPushFunction NotificationPushFunction
+	switch {
+	case luaNotificationPushFn != nil:
+		coreHooks.notificationPush = luaNotificationPushFn
+		allNotificationPushFunction = coreHooks.NotificationPush
+		startupLogger.Info("Registered Lua runtime Notification Push function invocation")
+	}
+
+	// Only the Lua runtime can register a purchase refund hook, the Go and JavaScript runtime interfaces do not expose one.
+	var allPurchaseRefundFunction PurchaseRefundFunction
+	switch {
+	case luaPurchaseRefundFn != nil:
+		coreHooks.purchaseRefund = NewPurchaseRefundDispatcher(ctx, logger, db, luaPurchaseRefundFn).Notify
+		allPurchaseRefundFunction = coreHooks.PurchaseRefund
+		startupLogger.Info("Registered Lua runtime Purchase Refund function invocation")
+	}
+
+	allStreamPresenceListeners := make(map[uint8]func(stream PresenceStream, joins, leaves []*Presence), len(luaStreamPresenceFns))
+	for mode, fn := range luaStreamPresenceFns {
+		allStreamPresenceListeners[mode] = func(stream PresenceStream, joins, leaves []*Presence) {
+			// Run outside the tracker's event processing so slow callbacks do not delay presence events.
+			eventQueue.Queue(func() {
+				if err := fn(context.Background(), stream, joins, leaves); err != nil {
+					logger.Error("Error running stream presence function", zap.Uint8("mode", stream.Mode), zap.Error(err))
+				}
+			})
+		}
+		startupLogger.Info("Registered Lua runtime Stream Presence function invocation", zap.Uint8("mode", mode))
+	}
+
 	var allShutdownFunction RuntimeShutdownFunction
 	switch {
 	case goShutdownFn != nil:
@@ -2690,9 +2835,14 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 		afterReqFunctions:                      allAfterReqFunctions,
 		matchmakerMatchedFunction:              allMatchmakerMatchedFunction,
 		matchmakerOverrideFunction:             allMatchmakerOverrideFunction,
+		matchmakerProposeFunction:              allMatchmakerProposeFunction,
 		tournamentEndFunction:                  allTournamentEndFunction,
 		tournamentResetFunction:                allTournamentResetFunction,
 		leaderboardResetFunction:               allLeaderboardResetFunction,
+		groupEventFunction:                     allGroupEventFunction,
+		notificationPushFunction:               allNotificationPushFunction,
+		streamPresenceListeners:                allStreamPresenceListeners,
+		purchaseRefundFunction:                 allPurchaseRefundFunction,
 		purchaseNotificationAppleFunction:      allPurchaseNotificationAppleFunction,
 		subscriptionNotificationAppleFunction:  allSubscriptionNotificationAppleFunction,
 		purchaseNotificationGoogleFunction:     allPurchaseNotificationGoogleFunction,
@@ -2783,6 +2933,17 @@ func (r *Runtime) Rpc(id string) RuntimeRpcFunction {
 	return r.rpcFunctions[id]
 }
 
+// runtimeRpcWithMetrics wraps an RPC function to record its invocation count, error count and latency tagged by RPC
+// ID. The function's result, error and status code are returned unchanged.
+func runtimeRpcWithMetrics(metrics Metrics, id string, fn RuntimeRpcFunction) RuntimeRpcFunction {
+	return func(ctx context.Context, headers, queryParams map[string][]string, userID, username string, vars map[string]string, expiry int64, sessionID, clientIP, clientPort, lang, payload string) (string, error, codes.Code) {
+		start := time.Now()
+		result, err, code := fn(ctx, headers, queryParams, userID, username, vars, expiry, sessionID, clientIP, clientPort, lang, payload)
+		metrics.RuntimeRpc(id, time.Since(start), err != nil)
+		return result, err, code
+	}
+}
+
 func (r *Runtime) BeforeRt(id string) RuntimeBeforeRtFunction {
 	return r.beforeRtFunctions[id]
 }
@@ -3479,6 +3640,22 @@ func (r *Runtime) LeaderboardReset() RuntimeLeaderboardResetFunction {
 	return r.leaderboardResetFunction
 }
 
+func (r *Runtime) GroupEvent() GroupEventFunction {
+	return r.groupEventFunction
+}
+
+func (r *Runtime) NotificationPush() NotificationPushFunction {
+	return r.notificationPushFunction
+}
+
+func (r *Runtime) PurchaseRefund() PurchaseRefundFunction {
+	return r.purchaseRefundFunction
+}
+
+func (r *Runtime) StreamPresenceListeners() map[uint8]func(stream PresenceStream, joins, leaves []*Presence) {
+	return r.streamPresenceListeners
+}
+
 func (r *Runtime) Event() RuntimeEventCustomFunction {
 	return r.eventFunctions.eventFunction
 }
@@ -60,6 +60,9 @@ type RuntimeGoNakamaModule struct {
 	streamManager        StreamManager
 	router               MessageRouter
 	eventFn              RuntimeEventCustomFunction
+	groupEventFn         GroupEventFunction
+	notificationPushFn   NotificationPushFunction
+	purchaseRefundFn     PurchaseRefundFunction
 	node                 string
 	matchCreateFn        RuntimeMatchCreateFunction
 	satori               runtime.Satori
@@ -268,7 +271,7 @@ func (n *RuntimeGoNakamaModule) AuthenticateFacebook(ctx context.Context, token
 	dbUserID, dbUsername, created, err := AuthenticateFacebook(ctx, n.logger, n.db, n.socialClient, n.config.GetSocial().FacebookLimitedLogin.AppId, token, username, create)
 	if err == nil && importFriends {
 		// Errors are logged before this point and failure here does not invalidate the whole operation.
-		_ = importFacebookFriends(ctx, n.logger, n.db, n.tracker, n.router, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, token, false)
+		_ = importFacebookFriends(ctx, n.logger, n.db, n.tracker, n.router, n.notificationPushFn, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, token, false)
 	}
 
 	return dbUserID, dbUsername, created, err
@@ -369,7 +372,7 @@ func (n *RuntimeGoNakamaModule) AuthenticateGoogle(ctx context.Context, token, u
 		return "", "", false, errors.New("expects id to be valid, must be 1-128 bytes")
 	}
 
-	return AuthenticateGoogle(ctx, n.logger, n.db, n.socialClient, token, username, create)
+	return AuthenticateGoogle(ctx, n.logger, n.db, n.socialClient, token, username, create, n.config.GetGoogleAuth().AllowedAudiences)
 }
 
 // @group authenticate
@@ -727,7 +730,8 @@ func (n *RuntimeGoNakamaModule) LinkApple(ctx context.Context, userID, token str
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkApple(ctx, n.logger, n.db, n.config, n.socialClient, id, token)
+	_, err = LinkApple(ctx, n.logger, n.db, n.config, n.socialClient, id, token)
+	return err
 }
 
 // @group authenticate
@@ -790,7 +794,8 @@ func (n *RuntimeGoNakamaModule) LinkFacebook(ctx context.Context, userID, userna
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkFacebook(ctx, n.logger, n.db, n.socialClient, n.tracker, n.router, id, username, n.config.GetSocial().FacebookLimitedLogin.AppId, token, importFriends)
+	_, err = LinkFacebook(ctx, n.logger, n.db, n.socialClient, n.tracker, n.router, n.notificationPushFn, id, username, n.config.GetSocial().FacebookLimitedLogin.AppId, token, importFriends)
+	return err
 }
 
 // @group authenticate
@@ -805,7 +810,8 @@ func (n *RuntimeGoNakamaModule) LinkFacebookInstantGame(ctx context.Context, use
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkFacebookInstantGame(ctx, n.logger, n.db, n.config, n.socialClient, id, signedPlayerInfo)
+	_, err = LinkFacebookInstantGame(ctx, n.logger, n.db, n.config, n.socialClient, id, signedPlayerInfo)
+	return err
 }
 
 // @group authenticate
@@ -825,7 +831,8 @@ func (n *RuntimeGoNakamaModule) LinkGameCenter(ctx context.Context, userID, play
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkGameCenter(ctx, n.logger, n.db, n.socialClient, id, playerID, bundleID, timestamp, salt, signature, publicKeyUrl)
+	_, err = LinkGameCenter(ctx, n.logger, n.db, n.socialClient, id, playerID, bundleID, timestamp, salt, signature, publicKeyUrl)
+	return err
 }
 
 // @group authenticate
@@ -840,7 +847,8 @@ func (n *RuntimeGoNakamaModule) LinkGoogle(ctx context.Context, userID, token st
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkGoogle(ctx, n.logger, n.db, n.socialClient, id, token)
+	_, err = LinkGoogle(ctx, n.logger, n.db, n.socialClient, id, token)
+	return err
 }
 
 // @group authenticate
@@ -857,7 +865,8 @@ func (n *RuntimeGoNakamaModule) LinkSteam(ctx context.Context, userID, username,
 		return errors.New("user ID must be a valid identifier")
 	}
 
-	return LinkSteam(ctx, n.logger, n.db, n.config, n.socialClient, n.tracker, n.router, id, username, token, importFriends)
+	_, err = LinkSteam(ctx, n.logger, n.db, n.config, n.socialClient, n.tracker, n.router, n.notificationPushFn, id, username, token, importFriends)
+	return err
 }
 
 // @group utils
@@ -1668,7 +1677,7 @@ func (n *RuntimeGoNakamaModule) NotificationSend(ctx context.Context, userID, su
 		uid: nots,
 	}
 
-	return NotificationSend(ctx, n.logger, n.db, n.tracker, n.router, notifications)
+	return NotificationSend(ctx, n.logger, n.db, n.tracker, n.router, n.notificationPushFn, notifications)
 }
 
 // @group notifications
@@ -1724,7 +1733,7 @@ func (n *RuntimeGoNakamaModule) NotificationsSend(ctx context.Context, notificat
 		ns[uid] = no
 	}
 
-	return NotificationSend(ctx, n.logger, n.db, n.tracker, n.router, ns)
+	return NotificationSend(ctx, n.logger, n.db, n.tracker, n.router, n.notificationPushFn, ns)
 }
 
 // @group notifications
@@ -1763,7 +1772,7 @@ func (n *RuntimeGoNakamaModule) NotificationSendAll(ctx context.Context, subject
 		CreateTime: createTime,
 	}
 
-	return NotificationSendAll(ctx, n.logger, n.db, n.tracker, n.router, not)
+	return NotificationSendAll(ctx, n.logger, n.db, n.tracker, n.router, n.notificationPushFn, not)
 }
 
 // @group notifications
@@ -2293,7 +2302,7 @@ func (n *RuntimeGoNakamaModule) StorageIndexList(ctx context.Context, callerID,
 }
 
 // @group users
-// @summary Update account, storage, and wallet information simultaneously.
+// @summary Update account, storage, and wallet information simultaneously. All changes are applied in a single transaction, so if any operation fails none of them persist and the error names the failed operation and its index within its group.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
 // @param accountUpdates(type=[]*runtime.AccountUpdate) Array of account information to be updated.
 // @param storageWrites(type=[]*runtime.StorageWrite) Array of storage objects to be updated.
@@ -2445,7 +2454,13 @@ func (n *RuntimeGoNakamaModule) MultiUpdate(ctx context.Context, accountUpdates
 		}
 	}
 
-	return MultiUpdate(ctx, n.logger, n.db, n.metrics, accountUpdateOps, storageWriteOps, storageDeleteOps, n.storageIndex, walletUpdateOps, updateLedger)
+	acks, results, err := MultiUpdate(ctx, n.logger, n.db, n.metrics, accountUpdateOps, storageWriteOps, storageDeleteOps, n.storageIndex, walletUpdateOps, updateLedger)
+	var multiErr *MultiUpdateError
+	if errors.As(err, &multiErr) {
+		// Go runtime callers match the runtime sentinel and typed errors directly, so they are returned unwrapped.
+		err = multiErr.Err
+	}
+	return acks, results, err
 }
 
 // @group leaderboards
@@ -2490,7 +2505,7 @@ func (n *RuntimeGoNakamaModule) LeaderboardCreate(ctx context.Context, id string
 
 	if resetSchedule != "" {
 		if _, err := cronexpr.Parse(resetSchedule); err != nil {
-			return errors.New("expects reset schedule to be a valid CRON expression")
+			return fmt.Errorf("expects reset schedule to be a valid CRON expression: %v", err.Error())
 		}
 	}
 
@@ -3165,7 +3180,7 @@ func (n *RuntimeGoNakamaModule) PurchaseValidateApple(ctx context.Context, userI
 		return nil, errors.New("receipt cannot be empty string")
 	}
 
-	validation, err := ValidatePurchasesApple(ctx, n.logger, n.db, uid, password, receipt, persist)
+	validation, err := ValidatePurchasesApple(ctx, n.logger, n.db, n.purchaseRefundFn, uid, password, receipt, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -3218,7 +3233,7 @@ func (n *RuntimeGoNakamaModule) PurchaseValidateGoogle(ctx context.Context, user
 		PrivateKey:  privateKey,
 	}
 
-	validation, err := ValidatePurchaseGoogle(ctx, n.logger, n.db, uid, configOverride, receipt, persist)
+	validation, err := ValidatePurchaseGoogle(ctx, n.logger, n.db, n.purchaseRefundFn, uid, configOverride, receipt, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -3255,7 +3270,7 @@ func (n *RuntimeGoNakamaModule) PurchaseValidateHuawei(ctx context.Context, user
 		return nil, errors.New("inAppPurchaseData cannot be empty string")
 	}
 
-	validation, err := ValidatePurchaseHuawei(ctx, n.logger, n.db, uid, n.config.GetIAP().Huawei, inAppPurchaseData, signature, persist)
+	validation, err := ValidatePurchaseHuawei(ctx, n.logger, n.db, n.purchaseRefundFn, uid, n.config.GetIAP().Huawei, inAppPurchaseData, signature, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -3285,7 +3300,7 @@ func (n *RuntimeGoNakamaModule) PurchaseValidateFacebookInstant(ctx context.Cont
 		return nil, errors.New("signedRequest cannot be empty string")
 	}
 
-	validation, err := ValidatePurchaseFacebookInstant(ctx, n.logger, n.db, uid, n.config.GetIAP().FacebookInstant, signedRequest, persist)
+	validation, err := ValidatePurchaseFacebookInstant(ctx, n.logger, n.db, n.purchaseRefundFn, uid, n.config.GetIAP().FacebookInstant, signedRequest, persist)
 	if err != nil {
 		return nil, err
 	}
@@ -3525,7 +3540,7 @@ func (n *RuntimeGoNakamaModule) GroupCreate(ctx context.Context, userID, name, c
 		return nil, errors.New("expects max_count to be >= 1")
 	}
 
-	return CreateGroup(ctx, n.logger, n.db, uid, cid, name, langTag, description, avatarUrl, metadataStr, open, maxCount)
+	return CreateGroup(ctx, n.logger, n.db, n.groupEventFn, uid, cid, name, langTag, description, avatarUrl, metadataStr, open, maxCount)
 }
 
 // @group groups
@@ -3612,7 +3627,7 @@ func (n *RuntimeGoNakamaModule) GroupDelete(ctx context.Context, id string) erro
 		return errors.New("expects group ID to be a valid identifier")
 	}
 
-	return DeleteGroup(ctx, n.logger, n.db, n.tracker, groupID, uuid.Nil)
+	return DeleteGroup(ctx, n.logger, n.db, n.groupEventFn, n.tracker, groupID, uuid.Nil)
 }
 
 // @group groups
@@ -3637,7 +3652,7 @@ func (n *RuntimeGoNakamaModule) GroupUserJoin(ctx context.Context, groupID, user
 		return errors.New("expects a username string")
 	}
 
-	return JoinGroup(ctx, n.logger, n.db, n.tracker, n.router, group, user, username)
+	return JoinGroup(ctx, n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.notificationPushFn, group, user, username)
 }
 
 // @group groups
@@ -3662,7 +3677,7 @@ func (n *RuntimeGoNakamaModule) GroupUserLeave(ctx context.Context, groupID, use
 		return errors.New("expects a username string")
 	}
 
-	return LeaveGroup(ctx, n.logger, n.db, n.tracker, n.router, n.streamManager, group, user, username)
+	return LeaveGroup(ctx, n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, group, user, username)
 }
 
 // @group groups
@@ -3702,7 +3717,7 @@ func (n *RuntimeGoNakamaModule) GroupUsersAdd(ctx context.Context, callerID, gro
 		users = append(users, uid)
 	}
 
-	return AddGroupUsers(ctx, n.logger, n.db, n.tracker, n.router, caller, group, users)
+	return AddGroupUsers(ctx, n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.notificationPushFn, caller, group, users)
 }
 
 // @group groups
@@ -3742,7 +3757,7 @@ func (n *RuntimeGoNakamaModule) GroupUsersBan(ctx context.Context, callerID, gro
 		users = append(users, uid)
 	}
 
-	return BanGroupUsers(ctx, n.logger, n.db, n.tracker, n.router, n.streamManager, caller, group, users)
+	return BanGroupUsers(ctx, n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, caller, group, users)
 }
 
 // @group groups
@@ -3782,7 +3797,7 @@ func (n *RuntimeGoNakamaModule) GroupUsersKick(ctx context.Context, callerID, gr
 		users = append(users, uid)
 	}
 
-	return KickGroupUsers(ctx, n.logger, n.db, n.tracker, n.router, n.streamManager, caller, group, users, false)
+	return KickGroupUsers(ctx, n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, caller, group, users, false)
 }
 
 // @group groups
@@ -3822,7 +3837,7 @@ func (n *RuntimeGoNakamaModule) GroupUsersPromote(ctx context.Context, callerID,
 		users = append(users, uid)
 	}
 
-	return PromoteGroupUsers(ctx, n.logger, n.db, n.router, caller, group, users)
+	return PromoteGroupUsers(ctx, n.logger, n.db, n.groupEventFn, n.router, caller, group, users)
 }
 
 // @group groups
@@ -3862,7 +3877,7 @@ func (n *RuntimeGoNakamaModule) GroupUsersDemote(ctx context.Context, callerID,
 		users = append(users, uid)
 	}
 
-	return DemoteGroupUsers(ctx, n.logger, n.db, n.router, caller, group, users)
+	return DemoteGroupUsers(ctx, n.logger, n.db, n.groupEventFn, n.router, caller, group, users)
 }
 
 // @group groups
@@ -4173,7 +4188,7 @@ func (n *RuntimeGoNakamaModule) FriendsAdd(ctx context.Context, userID string, u
 		metadataStr = string(bytes)
 	}
 
-	err = AddFriends(ctx, n.logger, n.db, n.tracker, n.router, userUUID, username, allIDs, metadataStr)
+	err = AddFriends(ctx, n.logger, n.db, n.tracker, n.router, n.notificationPushFn, userUUID, username, allIDs, metadataStr)
 	if err != nil {
 		return err
 	}
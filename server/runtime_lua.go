@@ -27,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
@@ -65,6 +66,21 @@ type RuntimeLuaCallbacks struct {
 	PurchaseNotificationGoogle     *lua.LFunction
 	SubscriptionNotificationGoogle *lua.LFunction
 	StorageIndexFilter             *MapOf[string, *lua.LFunction]
+	GroupEvent                     *lua.LFunction
+	MatchmakerPropose              *lua.LFunction
+	NotificationPush               *lua.LFunction
+	StreamPresence                 *MapOf[string, *lua.LFunction]
+	PurchaseRefund                 *lua.LFunction
+	RPCOptions                     *MapOf[string, *RuntimeLuaRPCOptions]
+	NodeSubscriber                 *MapOf[string, *lua.LFunction]
+}
+
+// RuntimeLuaRPCOptions are checks applied to an RPC call before its function runs.
+type RuntimeLuaRPCOptions struct {
+	// Larger payloads are rejected, 0 leaves only the server-wide request size limit.
+	MaxPayloadSize int
+	// Calls without an authenticated user session, such as those made with the HTTP key, are rejected.
+	RequireAuth bool
 }
 
 type RuntimeLuaModule struct {
@@ -104,6 +120,7 @@ type RuntimeProviderLua struct {
 	stdLibs              map[string]lua.LGFunction
 
 	once         *sync.Once
+	nodeBus      *RuntimeLuaNodeBus
 	poolCh       chan *RuntimeLua
 	maxCount     uint32
 	currentCount *atomic.Uint32
@@ -112,18 +129,19 @@ type RuntimeProviderLua struct {
 	statsCtx context.Context
 }
 
-func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, eventFn RuntimeEventCustomFunction, rootPath string, paths []string, matchProvider *MatchProvider, storageIndex StorageIndex) ([]string, map[string]RuntimeRpcFunction, map[string]RuntimeBeforeRtFunction, map[string]RuntimeAfterRtFunction, *RuntimeBeforeReqFunctions, *RuntimeAfterReqFunctions, RuntimeMatchmakerMatchedFunction, RuntimeTournamentEndFunction, RuntimeTournamentResetFunction, RuntimeLeaderboardResetFunction, RuntimeShutdownFunction, RuntimePurchaseNotificationAppleFunction, RuntimeSubscriptionNotificationAppleFunction, RuntimePurchaseNotificationGoogleFunction, RuntimeSubscriptionNotificationGoogleFunction, map[string]RuntimeStorageIndexFilterFunction, error) {
+func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, eventFn RuntimeEventCustomFunction, groupEventFn GroupEventFunction, notificationPushFn NotificationPushFunction, purchaseRefundFn PurchaseRefundFunction, rootPath string, paths []string, matchProvider *MatchProvider, storageIndex StorageIndex) ([]string, map[string]RuntimeRpcFunction, map[string]RuntimeBeforeRtFunction, map[string]RuntimeAfterRtFunction, *RuntimeBeforeReqFunctions, *RuntimeAfterReqFunctions, RuntimeMatchmakerMatchedFunction, RuntimeTournamentEndFunction, RuntimeTournamentResetFunction, RuntimeLeaderboardResetFunction, RuntimeShutdownFunction, RuntimePurchaseNotificationAppleFunction, RuntimeSubscriptionNotificationAppleFunction, RuntimePurchaseNotificationGoogleFunction, RuntimeSubscriptionNotificationGoogleFunction, map[string]RuntimeStorageIndexFilterFunction, RuntimeGroupEventFunction, RuntimeMatchmakerProposeFunction, RuntimeNotificationPushFunction, map[uint8]RuntimeStreamPresenceFunction, RuntimePurchaseRefundFunction, error) {
 	startupLogger.Info("Initialising Lua runtime provider", zap.String("path", rootPath))
 
 	// Load Lua modules into memory by reading the file contents. No evaluation/execution at this stage.
 	moduleCache, modulePaths, stdLibs, err := openLuaModules(startupLogger, rootPath, paths)
 	if err != nil {
 		// Errors already logged in the function call above.
-		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 	}
 
 	once := &sync.Once{}
 	localCache := NewRuntimeLuaLocalCache(ctx)
+	nodeBus := NewRuntimeLuaNodeBus()
 	rpcFunctions := make(map[string]RuntimeRpcFunction, 0)
 	beforeRtFunctions := make(map[string]RuntimeBeforeRtFunction, 0)
 	afterRtFunctions := make(map[string]RuntimeAfterRtFunction, 0)
@@ -134,11 +152,16 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 	var tournamentResetFunction RuntimeTournamentResetFunction
 	var leaderboardResetFunction RuntimeLeaderboardResetFunction
 	var shutdownFunction RuntimeShutdownFunction
+	var groupEventFunction RuntimeGroupEventFunction
+	var matchmakerProposeFunction RuntimeMatchmakerProposeFunction
+	var notificationPushFunction RuntimeNotificationPushFunction
+	var purchaseRefundFunction RuntimePurchaseRefundFunction
 	var purchaseNotificationAppleFunction RuntimePurchaseNotificationAppleFunction
 	var subscriptionNotificationAppleFunction RuntimeSubscriptionNotificationAppleFunction
 	var purchaseNotificationGoogleFunction RuntimePurchaseNotificationGoogleFunction
 	var subscriptionNotificationGoogleFunction RuntimeSubscriptionNotificationGoogleFunction
 	storageIndexFilterFunctions := make(map[string]RuntimeStorageIndexFilterFunction, 0)
+	streamPresenceFunctions := make(map[uint8]RuntimeStreamPresenceFunction, 0)
 
 	var sharedReg *lua.LTable
 	var sharedGlobals *lua.LTable
@@ -161,6 +184,7 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 		stdLibs:              stdLibs,
 
 		once:     once,
+		nodeBus:  nodeBus,
 		poolCh:   make(chan *RuntimeLua, config.GetRuntime().GetLuaMaxCount()),
 		maxCount: uint32(config.GetRuntime().GetLuaMaxCount()),
 		// Set the current count assuming we'll warm up the pool in a moment.
@@ -171,11 +195,11 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 
 	matchProvider.RegisterCreateFn("lua",
 		func(ctx context.Context, logger *zap.Logger, id uuid.UUID, node string, stopped *atomic.Bool, name string) (RuntimeMatchCore, error) {
-			return NewRuntimeLuaMatchCore(logger, name, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, once, localCache, eventFn, nil, nil, id, node, stopped, name, matchProvider, storageIndex)
+			return NewRuntimeLuaMatchCore(logger, name, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, once, localCache, nodeBus, eventFn, groupEventFn, notificationPushFn, purchaseRefundFn, nil, nil, id, node, stopped, name, matchProvider, storageIndex)
 		},
 	)
 
-	r, err := newRuntimeLuaVM(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, moduleCache, once, localCache, storageIndex, matchProvider.CreateMatch, eventFn, func(execMode RuntimeExecutionMode, id string) {
+	r, err := newRuntimeLuaVM(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, moduleCache, once, localCache, nodeBus, storageIndex, matchProvider.CreateMatch, eventFn, groupEventFn, notificationPushFn, purchaseRefundFn, func(execMode RuntimeExecutionMode, id string) {
 		switch execMode {
 		case RuntimeExecutionModeRPC:
 			rpcFunctions[id] = func(ctx context.Context, headers, queryParams map[string][]string, userID, username string, vars map[string]string, expiry int64, sessionID, clientIP, clientPort, lang, payload string) (string, error, codes.Code) {
@@ -1169,6 +1193,22 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 			shutdownFunction = func(ctx context.Context) {
 				runtimeProviderLua.Shutdown(ctx)
 			}
+		case RuntimeExecutionModeGroupEvent:
+			groupEventFunction = func(ctx context.Context, groupID, userID uuid.UUID, event string) error {
+				return runtimeProviderLua.GroupEvent(ctx, groupID, userID, event)
+			}
+		case RuntimeExecutionModeMatchmakerPropose:
+			matchmakerProposeFunction = func(ctx context.Context, tickets []*MatchmakerIndex) ([][]string, error) {
+				return runtimeProviderLua.MatchmakerPropose(ctx, tickets)
+			}
+		case RuntimeExecutionModeNotificationPush:
+			notificationPushFunction = func(ctx context.Context, userID uuid.UUID, notification *api.Notification, pushTokens []string) error {
+				return runtimeProviderLua.NotificationPush(ctx, userID, notification, pushTokens)
+			}
+		case RuntimeExecutionModePurchaseRefund:
+			purchaseRefundFunction = func(ctx context.Context, userID uuid.UUID, purchase *api.ValidatedPurchase) error {
+				return runtimeProviderLua.PurchaseRefund(ctx, userID, purchase)
+			}
 		case RuntimeExecutionModePurchaseNotificationApple:
 			purchaseNotificationAppleFunction = func(ctx context.Context, purchase *api.ValidatedPurchase, providerPayload string) error {
 				return runtimeProviderLua.PurchaseNotificationApple(ctx, purchase, providerPayload)
@@ -1189,10 +1229,18 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 			storageIndexFilterFunctions[id] = func(ctx context.Context, write *StorageOpWrite) (bool, error) {
 				return runtimeProviderLua.StorageIndexFilter(ctx, id, write)
 			}
+		case RuntimeExecutionModeStreamPresence:
+			mode, err := strconv.ParseUint(id, 10, 8)
+			if err != nil {
+				return
+			}
+			streamPresenceFunctions[uint8(mode)] = func(ctx context.Context, stream PresenceStream, joins, leaves []*Presence) error {
+				return runtimeProviderLua.StreamPresence(ctx, stream, joins, leaves)
+			}
 		}
 	})
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 	}
 
 	if config.GetRuntime().GetLuaReadOnlyGlobals() {
@@ -1245,7 +1293,7 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 		r.Stop()
 
 		runtimeProviderLua.newFn = func() *RuntimeLua {
-			r, err := newRuntimeLuaVM(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, moduleCache, once, localCache, storageIndex, matchProvider.CreateMatch, eventFn, nil)
+			r, err := newRuntimeLuaVM(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, stdLibs, moduleCache, once, localCache, nodeBus, storageIndex, matchProvider.CreateMatch, eventFn, groupEventFn, notificationPushFn, purchaseRefundFn, nil)
 			if err != nil {
 				logger.Fatal("Failed to initialize Lua runtime", zap.Error(err))
 			}
@@ -1263,10 +1311,12 @@ func NewRuntimeProviderLua(ctx context.Context, logger, startupLogger *zap.Logge
 			runtimeProviderLua.poolCh <- runtimeProviderLua.newFn()
 		}
 		runtimeProviderLua.metrics.GaugeLuaRuntimes(float64(config.GetRuntime().GetLuaMinCount()))
+
+		go runtimeProviderLua.deliverNodeMessages(ctx)
 	}
 	startupLogger.Info("Allocated minimum Lua runtime pool")
 
-	return modulePaths, rpcFunctions, beforeRtFunctions, afterRtFunctions, beforeReqFunctions, afterReqFunctions, matchmakerMatchedFunction, tournamentEndFunction, tournamentResetFunction, leaderboardResetFunction, shutdownFunction, purchaseNotificationAppleFunction, subscriptionNotificationAppleFunction, purchaseNotificationGoogleFunction, subscriptionNotificationGoogleFunction, storageIndexFilterFunctions, nil
+	return modulePaths, rpcFunctions, beforeRtFunctions, afterRtFunctions, beforeReqFunctions, afterReqFunctions, matchmakerMatchedFunction, tournamentEndFunction, tournamentResetFunction, leaderboardResetFunction, shutdownFunction, purchaseNotificationAppleFunction, subscriptionNotificationAppleFunction, purchaseNotificationGoogleFunction, subscriptionNotificationGoogleFunction, storageIndexFilterFunctions, groupEventFunction, matchmakerProposeFunction, notificationPushFunction, streamPresenceFunctions, purchaseRefundFunction, nil
 }
 
 func CheckRuntimeProviderLua(logger *zap.Logger, config Config, version string, paths []string) error {
@@ -1351,6 +1401,16 @@ func (rp *RuntimeProviderLua) Rpc(ctx context.Context, id string, headers, query
 		rp.Put(r)
 		return "", ErrRuntimeRPCNotFound, codes.NotFound
 	}
+	if options, found := r.callbacks.RPCOptions.Load(id); found {
+		if options.RequireAuth && userID == "" {
+			rp.Put(r)
+			return "", ErrRuntimeRPCUnauthenticated, codes.Unauthenticated
+		}
+		if options.MaxPayloadSize > 0 && len(payload) > options.MaxPayloadSize {
+			rp.Put(r)
+			return "", ErrRuntimeRPCPayloadTooLarge, codes.InvalidArgument
+		}
+	}
 
 	// Set context value used for logging
 	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"rpc_id": id})
@@ -1743,6 +1803,105 @@ func (rp *RuntimeProviderLua) MatchmakerMatched(ctx context.Context, entries []*
 	return "", false, errors.New("Unexpected return type from runtime Matchmaker Matched hook, must be string or nil.")
 }
 
+func (rp *RuntimeProviderLua) MatchmakerPropose(ctx context.Context, tickets []*MatchmakerIndex) ([][]string, error) {
+	r, err := rp.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lf := r.GetCallback(RuntimeExecutionModeMatchmakerPropose, "")
+	if lf == nil {
+		rp.Put(r)
+		return nil, errors.New("Runtime Matchmaker Propose function not found.")
+	}
+
+	luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModeMatchmakerPropose, nil, nil, 0, "", "", nil, "", "", "", "")
+
+	ticketsTable := r.vm.CreateTable(len(tickets), 0)
+	for i, ticket := range tickets {
+		presencesTable := r.vm.CreateTable(len(ticket.Entries), 0)
+		for j, entry := range ticket.Entries {
+			presenceTable := r.vm.CreateTable(0, 4)
+			presenceTable.RawSetString("user_id", lua.LString(entry.Presence.UserId))
+			presenceTable.RawSetString("session_id", lua.LString(entry.Presence.SessionId))
+			presenceTable.RawSetString("username", lua.LString(entry.Presence.Username))
+			presenceTable.RawSetString("node", lua.LString(entry.Presence.Node))
+			presencesTable.RawSetInt(j+1, presenceTable)
+		}
+
+		propertiesTable := r.vm.CreateTable(0, len(ticket.StringProperties)+len(ticket.NumericProperties))
+		for k, v := range ticket.StringProperties {
+			propertiesTable.RawSetString(k, lua.LString(v))
+		}
+		for k, v := range ticket.NumericProperties {
+			propertiesTable.RawSetString(k, lua.LNumber(v))
+		}
+
+		ticketTable := r.vm.CreateTable(0, 9)
+		ticketTable.RawSetString("ticket", lua.LString(ticket.Ticket))
+		ticketTable.RawSetString("presences", presencesTable)
+		ticketTable.RawSetString("properties", propertiesTable)
+		ticketTable.RawSetString("count", lua.LNumber(ticket.Count))
+		ticketTable.RawSetString("min_count", lua.LNumber(ticket.MinCount))
+		ticketTable.RawSetString("max_count", lua.LNumber(ticket.MaxCount))
+		ticketTable.RawSetString("count_multiple", lua.LNumber(ticket.CountMultiple))
+		ticketTable.RawSetString("create_time", lua.LNumber(time.Unix(0, ticket.CreatedAt).Unix()))
+		if ticket.PartyId != "" {
+			ticketTable.RawSetString("party_id", lua.LString(ticket.PartyId))
+		}
+
+		ticketsTable.RawSetInt(i+1, ticketTable)
+	}
+
+	// Set context value used for logging
+	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModeMatchmakerPropose.String()})
+	vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModeMatchmakerPropose, nil, nil, 0, "", "", nil, "", "", "", "")
+	r.vm.SetContext(vmCtx)
+	retValue, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, ticketsTable)
+	r.vm.SetContext(context.Background())
+	rp.Put(r)
+	if err != nil {
+		return nil, fmt.Errorf("Error running runtime Matchmaker Propose hook: %v", err.Error())
+	}
+
+	if retValue == nil || retValue == lua.LNil {
+		// No proposals, the default matching process is used.
+		return nil, nil
+	}
+
+	proposalsTable, ok := retValue.(*lua.LTable)
+	if !ok {
+		return nil, errors.New("Unexpected return type from runtime Matchmaker Propose hook, must be table or nil.")
+	}
+
+	proposals := make([][]string, 0, proposalsTable.Len())
+	var conversionErr error
+	proposalsTable.ForEach(func(_ lua.LValue, v lua.LValue) {
+		if conversionErr != nil {
+			return
+		}
+
+		proposalTable, ok := v.(*lua.LTable)
+		if !ok {
+			conversionErr = errors.New("Invalid return value from runtime Matchmaker Propose hook, proposals must be tables of ticket IDs.")
+			return
+		}
+		proposal := make([]string, 0, proposalTable.Len())
+		proposalTable.ForEach(func(_ lua.LValue, v lua.LValue) {
+			if v.Type() != lua.LTString {
+				conversionErr = errors.New("Invalid return value from runtime Matchmaker Propose hook, ticket IDs must be strings.")
+				return
+			}
+			proposal = append(proposal, v.String())
+		})
+		proposals = append(proposals, proposal)
+	})
+	if conversionErr != nil {
+		return nil, conversionErr
+	}
+
+	return proposals, nil
+}
+
 func (rp *RuntimeProviderLua) TournamentEnd(ctx context.Context, tournament *api.Tournament, end, reset int64) error {
 	r, err := rp.Get(ctx)
 	if err != nil {
@@ -1943,6 +2102,192 @@ func (rp *RuntimeProviderLua) LeaderboardReset(ctx context.Context, leaderboard
 	return errors.New("Unexpected return type from runtime Leaderboard Reset hook, must be nil.")
 }
 
+func (rp *RuntimeProviderLua) GroupEvent(ctx context.Context, groupID, userID uuid.UUID, event string) error {
+	r, err := rp.Get(ctx)
+	if err != nil {
+		return err
+	}
+	lf := r.GetCallback(RuntimeExecutionModeGroupEvent, "")
+	if lf == nil {
+		rp.Put(r)
+		return errors.New("Runtime Group Event function not found.")
+	}
+
+	luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModeGroupEvent, nil, nil, 0, "", "", nil, "", "", "", "")
+
+	eventTable := r.vm.CreateTable(0, 3)
+	eventTable.RawSetString("group_id", lua.LString(groupID.String()))
+	eventTable.RawSetString("user_id", lua.LString(userID.String()))
+	eventTable.RawSetString("type", lua.LString(event))
+
+	// Set context value used for logging
+	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModeGroupEvent.String()})
+	vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModeGroupEvent, nil, nil, 0, "", "", nil, "", "", "", "")
+	r.vm.SetContext(vmCtx)
+	retValue, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, eventTable)
+	r.vm.SetContext(context.Background())
+	rp.Put(r)
+	if err != nil {
+		return fmt.Errorf("Error running runtime Group Event hook: %v", err.Error())
+	}
+
+	if retValue == nil || retValue == lua.LNil {
+		// No return value needed.
+		return nil
+	}
+
+	return errors.New("Unexpected return type from runtime Group Event hook, must be nil.")
+}
+
+func (rp *RuntimeProviderLua) NotificationPush(ctx context.Context, userID uuid.UUID, notification *api.Notification, pushTokens []string) error {
+	r, err := rp.Get(ctx)
+	if err != nil {
+		return err
+	}
+	lf := r.GetCallback(RuntimeExecutionModeNotificationPush, "")
+	if lf == nil {
+		rp.Put(r)
+		return errors.New("Runtime Notification Push function not found.")
+	}
+
+	luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModeNotificationPush, nil, nil, 0, "", "", nil, "", "", "", "")
+
+	notificationTable := r.vm.CreateTable(0, 8)
+	notificationTable.RawSetString("id", lua.LString(notification.Id))
+	notificationTable.RawSetString("user_id", lua.LString(userID.String()))
+	notificationTable.RawSetString("subject", lua.LString(notification.Subject))
+	notificationTable.RawSetString("code", lua.LNumber(notification.Code))
+	notificationTable.RawSetString("sender_id", lua.LString(notification.SenderId))
+	notificationTable.RawSetString("persistent", lua.LBool(notification.Persistent))
+	if notification.CreateTime != nil {
+		notificationTable.RawSetString("create_time", lua.LNumber(notification.CreateTime.Seconds))
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(notification.Content), &content); err != nil {
+		rp.Put(r)
+		return fmt.Errorf("failed to convert notification content: %s", err.Error())
+	}
+	notificationTable.RawSetString("content", RuntimeLuaConvertMap(r.vm, content))
+
+	tokensTable := r.vm.CreateTable(len(pushTokens), 0)
+	for i, token := range pushTokens {
+		tokensTable.RawSetInt(i+1, lua.LString(token))
+	}
+
+	// Set context value used for logging
+	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModeNotificationPush.String()})
+	vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModeNotificationPush, nil, nil, 0, "", "", nil, "", "", "", "")
+	r.vm.SetContext(vmCtx)
+	retValue, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, notificationTable, tokensTable)
+	r.vm.SetContext(context.Background())
+	rp.Put(r)
+	if err != nil {
+		return fmt.Errorf("Error running runtime Notification Push hook: %v", err.Error())
+	}
+
+	if retValue == nil || retValue == lua.LNil {
+		// No return value needed.
+		return nil
+	}
+
+	return errors.New("Unexpected return type from runtime Notification Push hook, must be nil.")
+}
+
+func (rp *RuntimeProviderLua) PurchaseRefund(ctx context.Context, userID uuid.UUID, purchase *api.ValidatedPurchase) error {
+	r, err := rp.Get(ctx)
+	if err != nil {
+		return err
+	}
+	lf := r.GetCallback(RuntimeExecutionModePurchaseRefund, "")
+	if lf == nil {
+		rp.Put(r)
+		return errors.New("Runtime Purchase Refund function not found.")
+	}
+
+	luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModePurchaseRefund, nil, nil, 0, "", "", nil, "", "", "", "")
+
+	purchaseTable := purchaseToLuaTable(r.vm, purchase)
+
+	// Set context value used for logging
+	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModePurchaseRefund.String()})
+	vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModePurchaseRefund, nil, nil, 0, "", "", nil, "", "", "", "")
+	r.vm.SetContext(vmCtx)
+	retValue, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, lua.LString(userID.String()), purchaseTable)
+	r.vm.SetContext(context.Background())
+	rp.Put(r)
+	if err != nil {
+		return fmt.Errorf("Error running runtime Purchase Refund hook: %v", err.Error())
+	}
+
+	if retValue == nil || retValue == lua.LNil {
+		// No return value needed.
+		return nil
+	}
+
+	return errors.New("Unexpected return type from runtime Purchase Refund hook, must be nil.")
+}
+
+func (rp *RuntimeProviderLua) StreamPresence(ctx context.Context, stream PresenceStream, joins, leaves []*Presence) error {
+	r, err := rp.Get(ctx)
+	if err != nil {
+		return err
+	}
+	lf := r.GetCallback(RuntimeExecutionModeStreamPresence, strconv.Itoa(int(stream.Mode)))
+	if lf == nil {
+		rp.Put(r)
+		return errors.New("Runtime Stream Presence function not found.")
+	}
+
+	luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModeStreamPresence, nil, nil, 0, "", "", nil, "", "", "", "")
+
+	streamTable := r.vm.CreateTable(0, 4)
+	streamTable.RawSetString("mode", lua.LNumber(stream.Mode))
+	if stream.Subject != uuid.Nil {
+		streamTable.RawSetString("subject", lua.LString(stream.Subject.String()))
+	}
+	if stream.Subcontext != uuid.Nil {
+		streamTable.RawSetString("subcontext", lua.LString(stream.Subcontext.String()))
+	}
+	if stream.Label != "" {
+		streamTable.RawSetString("label", lua.LString(stream.Label))
+	}
+
+	presencesTable := func(presences []*Presence) *lua.LTable {
+		presencesTable := r.vm.CreateTable(len(presences), 0)
+		for i, p := range presences {
+			presenceTable := r.vm.CreateTable(0, 8)
+			presenceTable.RawSetString("user_id", lua.LString(p.UserID.String()))
+			presenceTable.RawSetString("session_id", lua.LString(p.ID.SessionID.String()))
+			presenceTable.RawSetString("node", lua.LString(p.ID.Node))
+			presenceTable.RawSetString("hidden", lua.LBool(p.Meta.Hidden))
+			presenceTable.RawSetString("persistence", lua.LBool(p.Meta.Persistence))
+			presenceTable.RawSetString("username", lua.LString(p.Meta.Username))
+			presenceTable.RawSetString("status", lua.LString(p.Meta.Status))
+			presenceTable.RawSetString("reason", lua.LNumber(p.GetReason()))
+			presencesTable.RawSetInt(i+1, presenceTable)
+		}
+		return presencesTable
+	}
+
+	// Set context value used for logging
+	vmCtx := context.WithValue(ctx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModeStreamPresence.String()})
+	vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModeStreamPresence, nil, nil, 0, "", "", nil, "", "", "", "")
+	r.vm.SetContext(vmCtx)
+	retValue, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, streamTable, presencesTable(joins), presencesTable(leaves))
+	r.vm.SetContext(context.Background())
+	rp.Put(r)
+	if err != nil {
+		return fmt.Errorf("Error running runtime Stream Presence hook: %v", err.Error())
+	}
+
+	if retValue == nil || retValue == lua.LNil {
+		// No return value needed.
+		return nil
+	}
+
+	return errors.New("Unexpected return type from runtime Stream Presence hook, must be nil.")
+}
+
 func (rp *RuntimeProviderLua) Shutdown(ctx context.Context) {
 	r, err := rp.Get(ctx)
 	if err != nil {
@@ -2202,9 +2547,15 @@ func (rp *RuntimeProviderLua) Get(ctx context.Context) (*RuntimeLua, error) {
 }
 
 func (rp *RuntimeProviderLua) Put(r *RuntimeLua) {
+	// Messages may have been broadcast while the runtime was in use. Check before returning it to the pool, where it
+	// may be picked up again at once.
+	pending := r.nodeBus != nil && r.nodeBus.Seq() != r.nodeBusSeq
 	select {
 	case rp.poolCh <- r:
 		// Runtime is successfully returned to the pool.
+		if pending {
+			r.nodeBus.Notify()
+		}
 	default:
 		// The pool is over capacity. Should never happen but guard anyway.
 		// Safe to continue processing, the runtime is just discarded.
@@ -2212,6 +2563,32 @@ func (rp *RuntimeProviderLua) Put(r *RuntimeLua) {
 	}
 }
 
+// deliverNodeMessages runs until the context is cancelled, passing node broadcast messages to the subscribers in idle
+// pooled runtimes. Runtimes in use when a message is broadcast receive it once they are returned to the pool.
+func (rp *RuntimeProviderLua) deliverNodeMessages(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-rp.nodeBus.NotifyCh():
+		}
+
+		// Visit each idle runtime once, runtimes are returned to the back of the pool.
+		for i, count := 0, len(rp.poolCh); i < count; i++ {
+			var r *RuntimeLua
+			select {
+			case r = <-rp.poolCh:
+			default:
+			}
+			if r == nil {
+				break
+			}
+			r.deliverNodeMessages(ctx)
+			rp.Put(r)
+		}
+	}
+}
+
 type RuntimeLua struct {
 	logger    *zap.Logger
 	node      string
@@ -2220,6 +2597,43 @@ type RuntimeLua struct {
 	luaEnv    *lua.LTable
 	env       map[string]string
 	callbacks *RuntimeLuaCallbacks
+
+	nodeBus    *RuntimeLuaNodeBus
+	nodeBusSeq uint64
+}
+
+// deliverNodeMessages runs this runtime's node subscribers for any messages broadcast since it last delivered them.
+// Each subscriber call is bounded by luaNodeBusDeliveryTimeout.
+func (r *RuntimeLua) deliverNodeMessages(ctx context.Context) {
+	if r.nodeBus == nil || r.nodeBus.Seq() == r.nodeBusSeq {
+		return
+	}
+
+	messages, seq, missed := r.nodeBus.Since(r.nodeBusSeq)
+	r.nodeBusSeq = seq
+	if missed > 0 {
+		r.logger.Warn("Lua runtime missed node broadcast messages", zap.Uint64("missed", missed))
+	}
+
+	for _, message := range messages {
+		lf := r.GetCallback(RuntimeExecutionModeNodeSubscriber, message.topic)
+		if lf == nil {
+			continue
+		}
+
+		luaCtx := NewRuntimeLuaContext(r.vm, r.node, r.version, r.luaEnv, RuntimeExecutionModeNodeSubscriber, nil, nil, 0, "", "", nil, "", "", "", "")
+
+		deliveryCtx, cancel := context.WithTimeout(ctx, luaNodeBusDeliveryTimeout)
+		vmCtx := context.WithValue(deliveryCtx, ctxLoggerFields{}, map[string]string{"mode": RuntimeExecutionModeNodeSubscriber.String(), "topic": message.topic})
+		vmCtx = NewRuntimeGoContext(vmCtx, r.node, r.version, r.env, RuntimeExecutionModeNodeSubscriber, nil, nil, 0, "", "", nil, "", "", "", "")
+		r.vm.SetContext(vmCtx)
+		_, err, _, _ := r.invokeFunction(r.vm, lf, luaCtx, lua.LString(message.payload))
+		r.vm.SetContext(context.Background())
+		cancel()
+		if err != nil {
+			r.logger.Error("Error running runtime node subscriber", zap.String("topic", message.topic), zap.Error(err))
+		}
+	}
 }
 
 func (r *RuntimeLua) loadModules(moduleCache *RuntimeLuaModuleCache) error {
@@ -2323,6 +2737,12 @@ func (r *RuntimeLua) GetCallback(e RuntimeExecutionMode, key string) *lua.LFunct
 		return r.callbacks.LeaderboardReset
 	case RuntimeExecutionModeShutdown:
 		return r.callbacks.Shutdown
+	case RuntimeExecutionModeGroupEvent:
+		return r.callbacks.GroupEvent
+	case RuntimeExecutionModeMatchmakerPropose:
+		return r.callbacks.MatchmakerPropose
+	case RuntimeExecutionModeNotificationPush:
+		return r.callbacks.NotificationPush
 	case RuntimeExecutionModePurchaseNotificationApple:
 		return r.callbacks.PurchaseNotificationApple
 	case RuntimeExecutionModeSubscriptionNotificationApple:
@@ -2337,6 +2757,20 @@ func (r *RuntimeLua) GetCallback(e RuntimeExecutionMode, key string) *lua.LFunct
 			return nil
 		}
 		return fn
+	case RuntimeExecutionModePurchaseRefund:
+		return r.callbacks.PurchaseRefund
+	case RuntimeExecutionModeStreamPresence:
+		fn, found := r.callbacks.StreamPresence.Load(key)
+		if !found {
+			return nil
+		}
+		return fn
+	case RuntimeExecutionModeNodeSubscriber:
+		fn, found := r.callbacks.NodeSubscriber.Load(key)
+		if !found {
+			return nil
+		}
+		return fn
 	}
 
 	return nil
@@ -2463,7 +2897,7 @@ func checkRuntimeLuaVM(logger *zap.Logger, config Config, version string, stdLib
 		vm.Push(lua.LString(name))
 		vm.Call(1, 0)
 	}
-	nakamaModule := NewRuntimeLuaNakamaModule(logger, nil, nil, nil, config, version, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	nakamaModule := NewRuntimeLuaNakamaModule(logger, nil, nil, nil, config, version, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 	vm.PreloadModule("nakama", nakamaModule.Loader)
 
 	preload := vm.GetField(vm.GetField(vm.Get(lua.EnvironIndex), "package"), "preload")
@@ -2484,7 +2918,7 @@ func checkRuntimeLuaVM(logger *zap.Logger, config Config, version string, stdLib
 	return nil
 }
 
-func newRuntimeLuaVM(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, stdLibs map[string]lua.LGFunction, moduleCache *RuntimeLuaModuleCache, once *sync.Once, localCache *RuntimeLuaLocalCache, storageIndex StorageIndex, matchCreateFn RuntimeMatchCreateFunction, eventFn RuntimeEventCustomFunction, announceCallbackFn func(RuntimeExecutionMode, string)) (*RuntimeLua, error) {
+func newRuntimeLuaVM(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, stdLibs map[string]lua.LGFunction, moduleCache *RuntimeLuaModuleCache, once *sync.Once, localCache *RuntimeLuaLocalCache, nodeBus *RuntimeLuaNodeBus, storageIndex StorageIndex, matchCreateFn RuntimeMatchCreateFunction, eventFn RuntimeEventCustomFunction, groupEventFn GroupEventFunction, notificationPushFn NotificationPushFunction, purchaseRefundFn PurchaseRefundFunction, announceCallbackFn func(RuntimeExecutionMode, string)) (*RuntimeLua, error) {
 	vm := lua.NewState(lua.Options{
 		CallStackSize:       config.GetRuntime().GetLuaCallStackSize(),
 		RegistrySize:        config.GetRuntime().GetLuaRegistrySize(),
@@ -2502,6 +2936,9 @@ func newRuntimeLuaVM(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojs
 		Before:             &MapOf[string, *lua.LFunction]{},
 		After:              &MapOf[string, *lua.LFunction]{},
 		StorageIndexFilter: &MapOf[string, *lua.LFunction]{},
+		StreamPresence:     &MapOf[string, *lua.LFunction]{},
+		RPCOptions:         &MapOf[string, *RuntimeLuaRPCOptions]{},
+		NodeSubscriber:     &MapOf[string, *lua.LFunction]{},
 	}
 	registerCallbackFn := func(e RuntimeExecutionMode, key string, fn *lua.LFunction) {
 		switch e {
@@ -2529,9 +2966,23 @@ func newRuntimeLuaVM(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojs
 			callbacks.SubscriptionNotificationGoogle = fn
 		case RuntimeExecutionModeStorageIndexFilter:
 			callbacks.StorageIndexFilter.Store(key, fn)
+		case RuntimeExecutionModeGroupEvent:
+			callbacks.GroupEvent = fn
+		case RuntimeExecutionModeMatchmakerPropose:
+			callbacks.MatchmakerPropose = fn
+		case RuntimeExecutionModeNotificationPush:
+			callbacks.NotificationPush = fn
+		case RuntimeExecutionModeStreamPresence:
+			callbacks.StreamPresence.Store(key, fn)
+		case RuntimeExecutionModePurchaseRefund:
+			callbacks.PurchaseRefund = fn
+		case RuntimeExecutionModeNodeSubscriber:
+			callbacks.NodeSubscriber.Store(key, fn)
 		}
 	}
-	nakamaModule := NewRuntimeLuaNakamaModule(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, rankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, once, localCache, storageIndex, matchCreateFn, eventFn, registerCallbackFn, announceCallbackFn)
+	nakamaModule := NewRuntimeLuaNakamaModule(logger, db, protojsonMarshaler, protojsonUnmarshaler, config, version, socialClient, leaderboardCache, rankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, once, localCache, nodeBus, storageIndex, matchCreateFn, eventFn, groupEventFn, notificationPushFn, purchaseRefundFn, registerCallbackFn, announceCallbackFn, func(id string, options *RuntimeLuaRPCOptions) {
+		callbacks.RPCOptions.Store(id, options)
+	})
 	vm.PreloadModule("nakama", nakamaModule.Loader)
 	r := &RuntimeLua{
 		logger:    logger,
@@ -2541,7 +2992,12 @@ func newRuntimeLuaVM(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojs
 		luaEnv:    RuntimeLuaConvertMapString(vm, config.GetRuntime().Environment),
 		env:       config.GetRuntime().Environment,
 		callbacks: callbacks,
+
+		nodeBus:    nodeBus,
+		nodeBusSeq: nodeBus.Seq(),
 	}
 
-	return r, r.loadModules(moduleCache)
+	err := r.loadModules(moduleCache)
+	nakamaModule.initialized = true
+	return r, err
 }
@@ -16,6 +16,7 @@ package server
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	lua "github.com/heroiclabs/nakama/v3/internal/gopher-lua"
@@ -241,3 +242,111 @@ func RuntimeLuaConvertLuaValue(lv lua.LValue) interface{} {
 		return v
 	}
 }
+
+// RuntimeLuaConvertLuaValueLimited converts a Lua value like RuntimeLuaConvertLuaValue, but returns an error instead of
+// recursing without bound when tables reference themselves, are nested deeper than maxDepth, or would encode to more
+// than roughly maxSize bytes of JSON. Tables shared between several parents are allowed but count towards the size each
+// time they appear. A maxDepth or maxSize of 0 disables that limit.
+func RuntimeLuaConvertLuaValueLimited(lv lua.LValue, maxDepth, maxSize int) (interface{}, error) {
+	c := &runtimeLuaLimitedConverter{
+		maxDepth: maxDepth,
+		maxSize:  maxSize,
+		path:     make(map[*lua.LTable]struct{}),
+	}
+	return c.convert(lv, 0)
+}
+
+type runtimeLuaLimitedConverter struct {
+	maxDepth int
+	maxSize  int
+	size     int
+	// Tables currently being converted, from the root down to the current value.
+	path map[*lua.LTable]struct{}
+}
+
+func (c *runtimeLuaLimitedConverter) grow(n int) error {
+	c.size += n
+	if c.maxSize > 0 && c.size > c.maxSize {
+		return fmt.Errorf("value exceeds maximum encoded size of %d bytes", c.maxSize)
+	}
+	return nil
+}
+
+func (c *runtimeLuaLimitedConverter) convert(lv lua.LValue, depth int) (interface{}, error) {
+	switch v := lv.(type) {
+	case *lua.LNilType:
+		return nil, c.grow(4)
+	case lua.LBool:
+		return bool(v), c.grow(5)
+	case lua.LString:
+		return string(v), c.grow(len(v) + 2)
+	case lua.LNumber:
+		vf := float64(v)
+		vi := int64(v)
+		if vf == float64(vi) {
+			// If it's a whole number use an actual integer type.
+			return vi, c.grow(len(strconv.FormatInt(vi, 10)))
+		}
+		return vf, c.grow(len(strconv.FormatFloat(vf, 'g', -1, 64)))
+	case *lua.LTable:
+		if _, found := c.path[v]; found {
+			return nil, fmt.Errorf("table contains a reference to itself")
+		}
+		if c.maxDepth > 0 && depth >= c.maxDepth {
+			return nil, fmt.Errorf("table nesting exceeds maximum depth of %d", c.maxDepth)
+		}
+		c.path[v] = struct{}{}
+		defer delete(c.path, v)
+
+		if err := c.grow(2); err != nil {
+			return nil, err
+		}
+
+		maxn := v.MaxN()
+		if maxn == 0 {
+			// Table.
+			ret := make(map[string]interface{})
+			var err error
+			v.ForEach(func(key, value lua.LValue) {
+				if err != nil {
+					return
+				}
+				var k, val interface{}
+				if k, err = c.convert(key, depth+1); err != nil {
+					return
+				}
+				keyStr := fmt.Sprint(k)
+				// Separators around the entry.
+				if err = c.grow(2); err != nil {
+					return
+				}
+				if val, err = c.convert(value, depth+1); err != nil {
+					return
+				}
+				ret[keyStr] = val
+			})
+			if err != nil {
+				return nil, err
+			}
+			return ret, nil
+		}
+		// Array.
+		ret := make([]interface{}, 0, maxn)
+		for i := 1; i <= maxn; i++ {
+			val, err := c.convert(v.RawGetInt(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if err = c.grow(1); err != nil {
+				return nil, err
+			}
+			ret = append(ret, val)
+		}
+		return ret, nil
+	case *lua.LFunction:
+		str := v.String()
+		return str, c.grow(len(str) + 2)
+	default:
+		return v, nil
+	}
+}
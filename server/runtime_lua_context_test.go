@@ -0,0 +1,70 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	lua "github.com/heroiclabs/nakama/v3/internal/gopher-lua"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeLuaConvertLuaValueLimitedSelfReference(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+
+	if err := l.DoString(`t = {name = "loop"}; t.self = t`); err != nil {
+		t.Fatalf("error building table: %v", err.Error())
+	}
+
+	_, err := RuntimeLuaConvertLuaValueLimited(l.GetGlobal("t"), 128, 16_777_216)
+	assert.ErrorContains(t, err, "reference to itself")
+}
+
+func TestRuntimeLuaConvertLuaValueLimitedDepth(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+
+	if err := l.DoString(`t = {}; local c = t; for i = 1, 100000 do c.next = {}; c = c.next end`); err != nil {
+		t.Fatalf("error building table: %v", err.Error())
+	}
+
+	_, err := RuntimeLuaConvertLuaValueLimited(l.GetGlobal("t"), 128, 16_777_216)
+	assert.ErrorContains(t, err, "maximum depth of 128")
+
+	// Shallow tables within the limit convert as before.
+	if err := l.DoString(`s = {a = {b = {1, 2, "c"}}}`); err != nil {
+		t.Fatalf("error building table: %v", err.Error())
+	}
+	value, err := RuntimeLuaConvertLuaValueLimited(l.GetGlobal("s"), 3, 16_777_216)
+	assert.NoError(t, err)
+	assert.Equal(t, RuntimeLuaConvertLuaValue(l.GetGlobal("s")), value)
+
+	_, err = RuntimeLuaConvertLuaValueLimited(l.GetGlobal("s"), 2, 16_777_216)
+	assert.Error(t, err)
+}
+
+func TestRuntimeLuaConvertLuaValueLimitedSize(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+
+	// Each level references the previous one twice, so the encoded size doubles per level without any cycle.
+	if err := l.DoString(`t = {"x"}; for i = 1, 60 do t = {t, t} end`); err != nil {
+		t.Fatalf("error building table: %v", err.Error())
+	}
+
+	_, err := RuntimeLuaConvertLuaValueLimited(l.GetGlobal("t"), 128, 1_024)
+	assert.ErrorContains(t, err, "maximum encoded size of 1024 bytes")
+}
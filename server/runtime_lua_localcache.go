@@ -27,12 +27,27 @@ type luaLocalCacheData struct {
 	expirationTime time.Time
 }
 
+type luaLocalCacheRateLimit struct {
+	tokens     float64
+	updateTime time.Time
+	window     time.Duration
+}
+
 type RuntimeLuaLocalCache struct {
 	sync.RWMutex
 
 	ctx context.Context
 
 	data map[string]luaLocalCacheData
+
+	rateLimitsMutex sync.Mutex
+	rateLimits      map[string]*luaLocalCacheRateLimit
+
+	// JSON Schemas registered by name with json_schema_subset_register, shared by every VM.
+	jsonSchemas *MapOf[string, *JSONSchemaSubset]
+
+	// Prepared statements for sql_query, shared by every VM and closed when the context is cancelled.
+	sqlStmts *runtimeLuaSQLStmtCache
 }
 
 func NewRuntimeLuaLocalCache(ctx context.Context) *RuntimeLuaLocalCache {
@@ -40,6 +55,12 @@ func NewRuntimeLuaLocalCache(ctx context.Context) *RuntimeLuaLocalCache {
 		ctx: ctx,
 
 		data: make(map[string]luaLocalCacheData),
+
+		rateLimits: make(map[string]*luaLocalCacheRateLimit),
+
+		jsonSchemas: &MapOf[string, *JSONSchemaSubset]{},
+
+		sqlStmts: newRuntimeLuaSQLStmtCache(),
 	}
 
 	go func() {
@@ -48,6 +69,7 @@ func NewRuntimeLuaLocalCache(ctx context.Context) *RuntimeLuaLocalCache {
 			select {
 			case <-lc.ctx.Done():
 				ticker.Stop()
+				lc.sqlStmts.Close()
 				return
 			case t := <-ticker.C:
 				lc.Lock()
@@ -57,6 +79,15 @@ func NewRuntimeLuaLocalCache(ctx context.Context) *RuntimeLuaLocalCache {
 					}
 				}
 				lc.Unlock()
+
+				// Rate limits that have fully refilled are equivalent to absent ones.
+				lc.rateLimitsMutex.Lock()
+				for key, rateLimit := range lc.rateLimits {
+					if t.Sub(rateLimit.updateTime) >= rateLimit.window {
+						delete(lc.rateLimits, key)
+					}
+				}
+				lc.rateLimitsMutex.Unlock()
 			}
 		}
 	}()
@@ -114,3 +145,37 @@ func (lc *RuntimeLuaLocalCache) Clear() {
 	clear(lc.data)
 	lc.Unlock()
 }
+
+// RateLimitCheck consumes one token from the bucket identified by the key, if one is available. Buckets hold up to
+// limit tokens and refill continuously at a rate of limit tokens per window. Returns whether the token was consumed,
+// and how many whole tokens remain.
+func (lc *RuntimeLuaLocalCache) RateLimitCheck(key string, limit int, window time.Duration) (bool, int) {
+	t := time.Now()
+
+	lc.rateLimitsMutex.Lock()
+	defer lc.rateLimitsMutex.Unlock()
+
+	rateLimit, found := lc.rateLimits[key]
+	if !found {
+		rateLimit = &luaLocalCacheRateLimit{tokens: float64(limit)}
+		lc.rateLimits[key] = rateLimit
+	} else {
+		rateLimit.tokens = min(float64(limit), rateLimit.tokens+t.Sub(rateLimit.updateTime).Seconds()*float64(limit)/window.Seconds())
+	}
+	rateLimit.updateTime = t
+	rateLimit.window = window
+
+	if rateLimit.tokens < 1 {
+		return false, 0
+	}
+	rateLimit.tokens--
+	return true, int(rateLimit.tokens)
+}
+
+func (lc *RuntimeLuaLocalCache) JSONSchemaSubsetRegister(name string, schema *JSONSchemaSubset) {
+	lc.jsonSchemas.Store(name, schema)
+}
+
+func (lc *RuntimeLuaLocalCache) JSONSchemaSubset(name string) (*JSONSchemaSubset, bool) {
+	return lc.jsonSchemas.Load(name)
+}
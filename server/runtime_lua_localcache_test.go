@@ -0,0 +1,70 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeLuaLocalCacheRateLimitCheck(t *testing.T) {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+	defer ctxCancelFn()
+	lc := NewRuntimeLuaLocalCache(ctx)
+
+	for i := 2; i >= 0; i-- {
+		allowed, remaining := lc.RateLimitCheck("rpc:user", 3, time.Hour)
+		assert.True(t, allowed)
+		assert.Equal(t, i, remaining)
+	}
+	allowed, remaining := lc.RateLimitCheck("rpc:user", 3, time.Hour)
+	assert.False(t, allowed)
+	assert.Zero(t, remaining)
+
+	// Other keys have their own allowance.
+	allowed, _ = lc.RateLimitCheck("rpc:other", 3, time.Hour)
+	assert.True(t, allowed)
+
+	// Tokens refill over the window.
+	allowed, _ = lc.RateLimitCheck("rpc:fast", 1, 50*time.Millisecond)
+	assert.True(t, allowed)
+	allowed, _ = lc.RateLimitCheck("rpc:fast", 1, 50*time.Millisecond)
+	assert.False(t, allowed)
+	time.Sleep(60 * time.Millisecond)
+	allowed, _ = lc.RateLimitCheck("rpc:fast", 1, 50*time.Millisecond)
+	assert.True(t, allowed)
+
+	// Concurrent callers never exceed the limit.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var allowedCount int
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, _ := lc.RateLimitCheck("rpc:concurrent", 10, time.Hour); allowed {
+				mu.Lock()
+				allowedCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, allowedCount)
+}
@@ -16,6 +16,8 @@ package server
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"context"
 	"crypto"
 	"crypto/aes"
@@ -24,7 +26,9 @@ import (
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
@@ -35,8 +39,11 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -52,6 +59,7 @@ import (
 	"github.com/heroiclabs/nakama/v3/internal/satori"
 	"github.com/heroiclabs/nakama/v3/social"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -82,19 +90,33 @@ type RuntimeLuaNakamaModule struct {
 	router               MessageRouter
 	once                 *sync.Once
 	localCache           *RuntimeLuaLocalCache
+	nodeBus              *RuntimeLuaNodeBus
 	registerCallbackFn   func(RuntimeExecutionMode, string, *lua.LFunction)
 	announceCallbackFn   func(RuntimeExecutionMode, string)
+	rpcOptionsFn         func(string, *RuntimeLuaRPCOptions)
 	httpClient           *http.Client
 	httpClientInsecure   *http.Client
 
 	node          string
 	matchCreateFn RuntimeMatchCreateFunction
 	eventFn       RuntimeEventCustomFunction
+	groupEventFn  GroupEventFunction
+
+	notificationPushFn NotificationPushFunction
+	purchaseRefundFn   PurchaseRefundFunction
+
+	// Set once the modules have finished loading, after which init only functions raise an error.
+	initialized bool
+
+	// Defaults for http_request calls set with http_request_defaults, keyed by the base URL they apply to.
+	httpDefaults map[string]*runtimeLuaHTTPDefaults
+	// Named HTTP clients registered with http_client_register.
+	httpClientProfiles map[string]*http.Client
 
 	satori runtime.Satori
 }
 
-func NewRuntimeLuaNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, once *sync.Once, localCache *RuntimeLuaLocalCache, storageIndex StorageIndex, matchCreateFn RuntimeMatchCreateFunction, eventFn RuntimeEventCustomFunction, registerCallbackFn func(RuntimeExecutionMode, string, *lua.LFunction), announceCallbackFn func(RuntimeExecutionMode, string)) *RuntimeLuaNakamaModule {
+func NewRuntimeLuaNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, once *sync.Once, localCache *RuntimeLuaLocalCache, nodeBus *RuntimeLuaNodeBus, storageIndex StorageIndex, matchCreateFn RuntimeMatchCreateFunction, eventFn RuntimeEventCustomFunction, groupEventFn GroupEventFunction, notificationPushFn NotificationPushFunction, purchaseRefundFn PurchaseRefundFunction, registerCallbackFn func(RuntimeExecutionMode, string, *lua.LFunction), announceCallbackFn func(RuntimeExecutionMode, string), rpcOptionsFn func(string, *RuntimeLuaRPCOptions)) *RuntimeLuaNakamaModule {
 	return &RuntimeLuaNakamaModule{
 		logger:               logger,
 		db:                   db,
@@ -116,15 +138,23 @@ func NewRuntimeLuaNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshale
 		router:               router,
 		once:                 once,
 		localCache:           localCache,
+		nodeBus:              nodeBus,
 		storageIndex:         storageIndex,
 		registerCallbackFn:   registerCallbackFn,
 		announceCallbackFn:   announceCallbackFn,
+		rpcOptionsFn:         rpcOptionsFn,
 		httpClient:           &http.Client{},
 		httpClientInsecure:   &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}},
 
 		node:          config.GetName(),
 		matchCreateFn: matchCreateFn,
 		eventFn:       eventFn,
+		groupEventFn:  groupEventFn,
+
+		notificationPushFn: notificationPushFn,
+		purchaseRefundFn:   purchaseRefundFn,
+
+		httpClientProfiles: make(map[string]*http.Client),
 
 		satori: satori.NewSatoriClient(
 			logger,
@@ -137,6 +167,16 @@ func NewRuntimeLuaNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshale
 	}
 }
 
+// Raises an error if the modules have already been loaded. Used by functions that configure state shared by every
+// runtime instance, since a call made later would only reach the one instance that happened to run it.
+func (n *RuntimeLuaNakamaModule) checkInit(l *lua.LState, name string) bool {
+	if n.initialized {
+		l.RaiseError("%s can only be called while the module is loading", name)
+		return false
+	}
+	return true
+}
+
 func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 	functions := map[string]lua.LGFunction{
 		"register_rpc":                       n.registerRPC,
@@ -145,40 +185,65 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"register_rt_before":                 n.registerRTBefore,
 		"register_rt_after":                  n.registerRTAfter,
 		"register_matchmaker_matched":        n.registerMatchmakerMatched,
+		"register_matchmaker_propose":        n.registerMatchmakerPropose,
 		"register_tournament_end":            n.registerTournamentEnd,
 		"register_tournament_reset":          n.registerTournamentReset,
 		"register_leaderboard_reset":         n.registerLeaderboardReset,
 		"register_shutdown":                  n.registerShutdown,
+		"register_group_event":               n.registerGroupEvent,
+		"register_notification_push":         n.registerNotificationPush,
+		"register_purchase_refund":           n.registerPurchaseRefund,
+		"register_stream_presence":           n.registerStreamPresence,
+		"register_node_subscriber":           n.registerNodeSubscriber,
 		"register_storage_index":             n.registerStorageIndex,
 		"register_storage_index_filter":      n.registerStorageIndexFilter,
 		"run_once":                           n.runOnce,
 		"get_context":                        n.getContext,
 		"event":                              n.event,
+		"events_list":                        n.eventsList,
 		"metrics_counter_add":                n.metricsCounterAdd,
 		"metrics_gauge_set":                  n.metricsGaugeSet,
 		"metrics_timer_record":               n.metricsTimerRecord,
+		"metrics_histogram_register":         n.metricsHistogramRegister,
+		"metrics_histogram_observe":          n.metricsHistogramObserve,
 		"localcache_get":                     n.localcacheGet,
 		"localcache_put":                     n.localcachePut,
 		"localcache_delete":                  n.localcacheDelete,
 		"localcache_clear":                   n.localcacheClear,
+		"node_broadcast":                     n.nodeBroadcast,
+		"rate_limit_check":                   n.rateLimitCheck,
+		"lock_acquire":                       n.lockAcquire,
+		"lock_release":                       n.lockRelease,
 		"time":                               n.time,
 		"cron_prev":                          n.cronPrev,
 		"cron_next":                          n.cronNext,
+		"cron_match":                         n.cronMatch,
 		"sql_exec":                           n.sqlExec,
 		"sql_query":                          n.sqlQuery,
+		"sql_query_each":                     n.sqlQueryEach,
 		"uuid_v4":                            n.uuidV4,
+		"uuid_v5":                            n.uuidV5,
 		"uuid_bytes_to_string":               n.uuidBytesToString,
 		"uuid_string_to_bytes":               n.uuidStringToBytes,
 		"http_request":                       n.httpRequest,
+		"http_request_defaults":              n.httpRequestDefaults,
+		"http_client_register":               n.httpClientRegister,
 		"jwt_generate":                       n.jwtGenerate,
 		"json_encode":                        n.jsonEncode,
+		"json_encode_canonical":              n.jsonEncodeCanonical,
 		"json_decode":                        n.jsonDecode,
+		"json_schema_subset_register":        n.jsonSchemaSubsetRegister,
+		"json_validate_subset":               n.jsonValidateSubset,
 		"base64_encode":                      n.base64Encode,
 		"base64_decode":                      n.base64Decode,
 		"base64url_encode":                   n.base64URLEncode,
 		"base64url_decode":                   n.base64URLDecode,
 		"base16_encode":                      n.base16Encode,
 		"base16_decode":                      n.base16Decode,
+		"gzip_compress":                      n.gzipCompress,
+		"gzip_decompress":                    n.gzipDecompress,
+		"deflate_compress":                   n.deflateCompress,
+		"deflate_decompress":                 n.deflateDecompress,
 		"aes128_encrypt":                     n.aes128Encrypt,
 		"aes128_decrypt":                     n.aes128Decrypt,
 		"aes256_encrypt":                     n.aes256Encrypt,
@@ -186,9 +251,16 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"md5_hash":                           n.md5Hash,
 		"sha256_hash":                        n.sha256Hash,
 		"hmac_sha256_hash":                   n.hmacSHA256Hash,
+		"hmac_sha1_hash":                     n.hmacSHA1Hash,
+		"hmac_sha512_hash":                   n.hmacSHA512Hash,
+		"hmac_equal":                         n.hmacEqual,
 		"rsa_sha256_hash":                    n.rsaSHA256Hash,
 		"bcrypt_hash":                        n.bcryptHash,
 		"bcrypt_compare":                     n.bcryptCompare,
+		"argon2id_hash":                      n.argon2idHash,
+		"argon2id_verify":                    n.argon2idVerify,
+		"scrypt_hash":                        n.scryptHash,
+		"scrypt_verify":                      n.scryptVerify,
 		"authenticate_apple":                 n.authenticateApple,
 		"authenticate_custom":                n.authenticateCustom,
 		"authenticate_device":                n.authenticateDevice,
@@ -203,14 +275,18 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"logger_info":                        n.loggerInfo,
 		"logger_warn":                        n.loggerWarn,
 		"logger_error":                       n.loggerError,
+		"logger_with_fields":                 n.loggerWithFields,
 		"account_get_id":                     n.accountGetId,
 		"accounts_get_id":                    n.accountsGetId,
 		"account_update_id":                  n.accountUpdateId,
 		"account_delete_id":                  n.accountDeleteId,
 		"account_export_id":                  n.accountExportId,
+		"account_merge":                      n.accountMerge,
 		"users_get_id":                       n.usersGetId,
 		"users_get_username":                 n.usersGetUsername,
+		"users_resolve_usernames":            n.usersResolveUsernames,
 		"users_get_friend_status":            n.usersGetFriendStatus,
+		"users_online_status":                n.usersOnlineStatus,
 		"users_get_random":                   n.usersGetRandom,
 		"users_ban_id":                       n.usersBanId,
 		"users_unban_id":                     n.usersUnbanId,
@@ -239,20 +315,29 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"stream_user_leave":                  n.streamUserLeave,
 		"stream_user_kick":                   n.streamUserKick,
 		"stream_count":                       n.streamCount,
+		"stream_get":                         n.streamGet,
 		"stream_close":                       n.streamClose,
 		"stream_send":                        n.streamSend,
 		"stream_send_raw":                    n.streamSendRaw,
 		"session_disconnect":                 n.sessionDisconnect,
 		"session_logout":                     n.sessionLogout,
+		"session_logout_all":                 n.sessionLogoutAll,
+		"session_vars_update":                n.sessionVarsUpdate,
 		"match_create":                       n.matchCreate,
 		"match_get":                          n.matchGet,
 		"match_list":                         n.matchList,
+		"match_count":                        n.matchCount,
 		"match_signal":                       n.matchSignal,
+		"matches_stop_by_handler":            n.matchesStopByHandler,
 		"notification_send":                  n.notificationSend,
 		"notifications_send":                 n.notificationsSend,
 		"notification_send_all":              n.notificationSendAll,
+		"notification_send_filtered":         n.notificationSendFiltered,
 		"notifications_list":                 n.notificationsList,
+		"notifications_count":                n.notificationsCount,
 		"notifications_delete":               n.notificationsDelete,
+		"notifications_delete_older_than":    n.notificationsDeleteOlderThan,
+		"notifications_mark_read":            n.notificationsMarkRead,
 		"notifications_get_id":               n.notificationsGetId,
 		"notifications_delete_id":            n.notificationsDeleteId,
 		"notifications_update":               n.notificationsUpdate,
@@ -269,16 +354,20 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"multi_update":                       n.multiUpdate,
 		"leaderboard_create":                 n.leaderboardCreate,
 		"leaderboard_delete":                 n.leaderboardDelete,
+		"leaderboards_delete":                n.leaderboardsDelete,
+		"leaderboard_ranks_recalculate":      n.leaderboardRanksRecalculate,
 		"leaderboard_list":                   n.leaderboardList,
 		"leaderboard_ranks_disable":          n.leaderboardRanksDisable,
 		"leaderboard_records_list":           n.leaderboardRecordsList,
 		"leaderboard_records_list_cursor_from_rank": n.leaderboardRecordsListCursorFromRank,
+		"leaderboard_records_list_owners":           n.leaderboardRecordsListOwners,
 		"leaderboard_record_write":                  n.leaderboardRecordWrite,
 		"leaderboard_records_haystack":              n.leaderboardRecordsHaystack,
 		"leaderboard_record_delete":                 n.leaderboardRecordDelete,
 		"leaderboards_get_id":                       n.leaderboardsGetId,
 		"purchase_validate_apple":                   n.purchaseValidateApple,
 		"purchase_validate_google":                  n.purchaseValidateGoogle,
+		"purchase_google_notification_ingest":       n.purchaseGoogleNotificationIngest,
 		"purchase_validate_huawei":                  n.purchaseValidateHuawei,
 		"purchase_validate_facebook_instant":        n.purchaseValidateFacebookInstant,
 		"purchase_get_by_transaction_id":            n.purchaseGetByTransactionId,
@@ -287,6 +376,7 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"subscription_validate_google":              n.subscriptionValidateGoogle,
 		"subscription_get_by_product_id":            n.subscriptionGetByProductId,
 		"subscriptions_list":                        n.subscriptionsList,
+		"subscriptions_list_by_product":             n.subscriptionsListByProduct,
 		"tournament_create":                         n.tournamentCreate,
 		"tournament_delete":                         n.tournamentDelete,
 		"tournament_add_attempt":                    n.tournamentAddAttempt,
@@ -301,6 +391,7 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"groups_get_id":                             n.groupsGetId,
 		"group_create":                              n.groupCreate,
 		"group_update":                              n.groupUpdate,
+		"group_metadata_get":                        n.groupMetadataGet,
 		"group_delete":                              n.groupDelete,
 		"group_user_join":                           n.groupUserJoin,
 		"group_user_leave":                          n.groupUserLeave,
@@ -325,7 +416,9 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"channel_message_remove":                    n.channelMessageRemove,
 		"channel_messages_list":                     n.channelMessagesList,
 		"channel_id_build":                          n.channelIdBuild,
+		"channel_id_parse":                          n.channelIdParse,
 		"storage_index_list":                        n.storageIndexList,
+		"storage_index_count":                       n.storageIndexCount,
 		"get_config":                                n.getConfig,
 		"get_satori":                                n.getSatori,
 	}
@@ -340,6 +433,8 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 // @summary Registers a function for use with client RPC to the server.
 // @param fn(type=function) A function reference which will be executed on each RPC message.
 // @param id(type=string) The unique identifier used to register the function for RPC.
+// @param maxPayloadSize(type=number, optional=true, default=0) The maximum payload size in bytes accepted by this RPC. Larger payloads are rejected with an invalid argument error before the function runs. By default only the server-wide request size limit applies.
+// @param requireAuth(type=bool, optional=true, default=false) Reject calls without an authenticated user session, such as those made with the HTTP key, with an unauthenticated error before the function runs.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) registerRPC(l *lua.LState) int {
 	fn := l.CheckFunction(1)
@@ -350,8 +445,19 @@ func (n *RuntimeLuaNakamaModule) registerRPC(l *lua.LState) int {
 		return 0
 	}
 
+	maxPayloadSize := l.OptInt(3, 0)
+	if maxPayloadSize < 0 {
+		l.ArgError(3, "expects max payload size to be 0 or greater")
+		return 0
+	}
+
+	requireAuth := l.OptBool(4, false)
+
 	id = strings.ToLower(id)
 
+	if (maxPayloadSize > 0 || requireAuth) && n.rpcOptionsFn != nil {
+		n.rpcOptionsFn(id, &RuntimeLuaRPCOptions{MaxPayloadSize: maxPayloadSize, RequireAuth: requireAuth})
+	}
 	if n.registerCallbackFn != nil {
 		n.registerCallbackFn(RuntimeExecutionModeRPC, id, fn)
 	}
@@ -477,6 +583,22 @@ func (n *RuntimeLuaNakamaModule) registerMatchmakerMatched(l *lua.LState) int {
 	return 0
 }
 
+// @group hooks
+// @summary Registers a function that proposes matches from the matchmaker ticket pool, replacing the built-in matching on each interval where it returns a value. The function receives the list of tickets in the pool, oldest first, each with 'ticket', 'presences', 'properties', 'count', 'min_count', 'max_count', 'count_multiple', 'create_time' and an optional 'party_id'. It returns a list of proposed matches, each a list of ticket IDs. Proposals that reuse a ticket or session, or whose total player count does not satisfy every included ticket's min/max count and count multiple, are discarded. Return nil to fall back to the built-in matching for that interval.
+// @param fn(type=function) A function reference which will be executed on each matchmaker interval.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerMatchmakerPropose(l *lua.LState) int {
+	fn := l.CheckFunction(1)
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModeMatchmakerPropose, "", fn)
+	}
+	if n.announceCallbackFn != nil {
+		n.announceCallbackFn(RuntimeExecutionModeMatchmakerPropose, "")
+	}
+	return 0
+}
+
 // @group hooks
 // @summary Registers a function to be run when a tournament ends.
 // @param fn(type=function) A function reference which will be executed on each tournament end.
@@ -525,6 +647,95 @@ func (n *RuntimeLuaNakamaModule) registerLeaderboardReset(l *lua.LState) int {
 	return 0
 }
 
+// @group hooks
+// @summary Registers a function to be run after a user's group membership changes, through the client API, the console or runtime functions. The function receives a table with 'group_id', 'user_id' and 'type', one of 'create', 'delete', 'join', 'join_request', 'leave', 'add', 'kick', 'ban', 'promote' or 'demote'. A 'create' is sent for the group creator and a 'delete' for each member of a deleted group. A 'join_request' is sent when a user asks to join a closed group, and is followed by an 'add' or 'promote' if an admin accepts it. Only users whose membership actually changed are reported, so repeated or rejected operations send no event. The function runs asynchronously after the change is committed, so errors it raises are only logged.
+// @param fn(type=function) A function reference which will be executed for each user whose group membership changes.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerGroupEvent(l *lua.LState) int {
+	fn := l.CheckFunction(1)
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModeGroupEvent, "", fn)
+	}
+	if n.announceCallbackFn != nil {
+		n.announceCallbackFn(RuntimeExecutionModeGroupEvent, "")
+	}
+	return 0
+}
+
+// @group hooks
+// @summary Registers a function to forward persistent notifications to a push provider. It runs once for each persistent notification after it is stored, and receives the notification and a list of the recipient's device tokens, read from the 'push_tokens' list in their account metadata. Recipients without device tokens are skipped. Delivery is best-effort, errors raised by the function are only logged and never fail the in-app notification.
+// @param fn(type=function) A function reference which will be executed for each persistent notification sent to a user with device tokens.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerNotificationPush(l *lua.LState) int {
+	fn := l.CheckFunction(1)
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModeNotificationPush, "", fn)
+	}
+	if n.announceCallbackFn != nil {
+		n.announceCallbackFn(RuntimeExecutionModeNotificationPush, "")
+	}
+	return 0
+}
+
+// @group hooks
+// @summary Registers a function to be run when presences join or leave any stream with the given mode on this node. The function receives the stream, a list of presences that joined and a list of presences that left, either of which may be empty. It runs asynchronously after the presence events, and errors raised by the function are only logged.
+// @param fn(type=function) A function reference which will be executed for each batch of presence events on a stream of this mode.
+// @param mode(type=number) The stream mode to receive presence events for.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerStreamPresence(l *lua.LState) int {
+	fn := l.CheckFunction(1)
+	mode := l.CheckInt(2)
+	if mode < 0 || mode > math.MaxUint8 {
+		l.ArgError(2, "expects a valid stream mode")
+		return 0
+	}
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModeStreamPresence, strconv.Itoa(mode), fn)
+	}
+	if n.announceCallbackFn != nil {
+		n.announceCallbackFn(RuntimeExecutionModeStreamPresence, strconv.Itoa(mode))
+	}
+	return 0
+}
+
+// @group hooks
+// @summary Registers a function to receive messages broadcast on a topic with node_broadcast. Every runtime on this node runs its own copy of the function shortly after the broadcast, once it is idle, which makes it suitable for invalidating per-runtime caches. Each call is cancelled if it runs for longer than 5 seconds. Messages are node-local and are not delivered to other nodes in a cluster, nor to match handlers.
+// @param topic(type=string) The topic to receive messages for.
+// @param fn(type=function) A function reference which will be executed with each message payload broadcast on the topic.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerNodeSubscriber(l *lua.LState) int {
+	topic := l.CheckString(1)
+	if topic == "" {
+		l.ArgError(1, "expects topic string")
+		return 0
+	}
+	fn := l.CheckFunction(2)
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModeNodeSubscriber, topic, fn)
+	}
+	return 0
+}
+
+// @group hooks
+// @summary Registers a function to be run when a stored purchase is first marked as refunded or voided, either by a purchase validation or by an Apple or Google server notification. Use it to revoke items granted for the purchase, for example by deducting from the user's wallet. It runs asynchronously after the refund has been stored. A refund stays pending until the function returns without error, so errors are retried periodically and refunds pending at shutdown are delivered after a restart. The function may see the same refund more than once and should be idempotent, for example by checking the transaction ID.
+// @param fn(type=function) A function reference which will be executed with the user ID and the refunded purchase, including its product ID.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) registerPurchaseRefund(l *lua.LState) int {
+	fn := l.CheckFunction(1)
+
+	if n.registerCallbackFn != nil {
+		n.registerCallbackFn(RuntimeExecutionModePurchaseRefund, "", fn)
+	}
+	if n.announceCallbackFn != nil {
+		n.announceCallbackFn(RuntimeExecutionModePurchaseRefund, "")
+	}
+	return 0
+}
+
 // @group hooks
 // @summary Registers a function to be run when the server received a shutdown signal. The function only fires if grace_period_sec > 0.
 // @param fn(type=function) A function reference which will be executed on server shutdown.
@@ -706,6 +917,73 @@ func (n *RuntimeLuaNakamaModule) event(l *lua.LState) int {
 	return 0
 }
 
+// @group events
+// @summary List events previously generated with event, newest first. Requires the runtime event log to be enabled in the server configuration. Events are kept for the configured event log max age.
+// @param name(type=string, optional=true) Only list events with this name. Lists events of any name if empty.
+// @param since(type=number, optional=true, default=0) Only list events at or after this numeric UTC value in seconds. Unbounded if 0.
+// @param until(type=number, optional=true, default=0) Only list events before this numeric UTC value in seconds. Unbounded if 0.
+// @param limit(type=number, optional=true, default=100) The maximum number of events to return, between 1 and 1000.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from a previous result.
+// @return events(table) A list of events, each with name, properties, timestamp and external fields.
+// @return cursor(string) A cursor to fetch the next page of events, or nil if there are no more events.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) eventsList(l *lua.LState) int {
+	if !n.config.GetRuntime().EventLog {
+		l.RaiseError("event log is not enabled")
+		return 0
+	}
+
+	name := l.OptString(1, "")
+
+	since := l.OptInt64(2, 0)
+	if since < 0 {
+		l.ArgError(2, "expects since to be 0 or greater")
+		return 0
+	}
+
+	until := l.OptInt64(3, 0)
+	if until < 0 {
+		l.ArgError(3, "expects until to be 0 or greater")
+		return 0
+	}
+
+	limit := l.OptInt(4, 100)
+	if limit < 1 || limit > 1000 {
+		l.ArgError(4, "expects limit to be 1-1000")
+		return 0
+	}
+
+	cursor := l.OptString(5, "")
+
+	events, nextCursor, err := EventLogList(l.Context(), n.logger, n.db, name, since, until, limit, cursor)
+	if err != nil {
+		if err == ErrEventLogCursorInvalid {
+			l.ArgError(5, "expects cursor to be valid when provided")
+			return 0
+		}
+		l.RaiseError("failed to list events: %v", err.Error())
+		return 0
+	}
+
+	eventsTable := l.CreateTable(len(events), 0)
+	for i, evt := range events {
+		eventTable := l.CreateTable(0, 4)
+		eventTable.RawSetString("name", lua.LString(evt.Name))
+		eventTable.RawSetString("properties", RuntimeLuaConvertMapString(l, evt.Properties))
+		eventTable.RawSetString("timestamp", lua.LNumber(evt.Timestamp.Seconds))
+		eventTable.RawSetString("external", lua.LBool(evt.External))
+		eventsTable.RawSetInt(i+1, eventTable)
+	}
+	l.Push(eventsTable)
+
+	if nextCursor == "" {
+		l.Push(lua.LNil)
+	} else {
+		l.Push(lua.LString(nextCursor))
+	}
+	return 2
+}
+
 // @group metrics
 // @summary Add a custom metrics counter.
 // @param name(type=string) The name of the custom metrics counter.
@@ -757,6 +1035,60 @@ func (n *RuntimeLuaNakamaModule) metricsTimerRecord(l *lua.LState) int {
 	return 0
 }
 
+// @group metrics
+// @summary Set the bucket upper bounds of a custom metrics histogram. Must be called before the first value is recorded to the histogram, histograms that are not registered use exponential buckets from 1 to 524288. Registering the same buckets again has no effect, registering different buckets for the same histogram is an error.
+// @param name(type=string) The name of the custom metrics histogram.
+// @param buckets(type=table) The bucket upper bounds, in strictly increasing order.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) metricsHistogramRegister(l *lua.LState) int {
+	name := l.CheckString(1)
+	if name == "" {
+		l.ArgError(1, "expects histogram name")
+		return 0
+	}
+
+	bucketsTable := l.CheckTable(2)
+	buckets := make([]float64, 0, bucketsTable.Len())
+	var conversionError bool
+	bucketsTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+		bucket, ok := v.(lua.LNumber)
+		if !ok {
+			conversionError = true
+			return
+		}
+		buckets = append(buckets, float64(bucket))
+	})
+	if conversionError {
+		l.ArgError(2, "expects buckets to be a table of numbers")
+		return 0
+	}
+
+	if err := n.metrics.CustomHistogramRegister(name, buckets); err != nil {
+		l.RaiseError("failed to register histogram: %s", err.Error())
+	}
+	return 0
+}
+
+// @group metrics
+// @summary Record a value to a custom metrics histogram.
+// @param name(type=string) The name of the custom metrics histogram.
+// @param tags(type=table) The metrics tags associated with this histogram.
+// @param value(type=number) A value to record to this metric.
+func (n *RuntimeLuaNakamaModule) metricsHistogramObserve(l *lua.LState) int {
+	name := l.CheckString(1)
+	tags, err := RuntimeLuaConvertLuaTableString(l.OptTable(2, nil))
+	if err != nil {
+		l.ArgError(2, err.Error())
+	}
+	value := float64(l.CheckNumber(3))
+	n.metrics.CustomHistogram(name, tags, value)
+
+	return 0
+}
+
 func (n *RuntimeLuaNakamaModule) localcacheGet(l *lua.LState) int {
 	key := l.CheckString(1)
 	if key == "" {
@@ -817,6 +1149,121 @@ func (n *RuntimeLuaNakamaModule) localcacheClear(_ *lua.LState) int {
 	return 0
 }
 
+// @group utils
+// @summary Broadcast a message to the functions registered with register_node_subscriber for a topic. The message is node-local: it reaches every runtime on this node, including the caller's, but not other nodes in a cluster.
+// @param topic(type=string) The topic to broadcast on.
+// @param payload(type=string) The message payload.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) nodeBroadcast(l *lua.LState) int {
+	topic := l.CheckString(1)
+	if topic == "" {
+		l.ArgError(1, "expects topic string")
+		return 0
+	}
+	payload := l.CheckString(2)
+
+	if n.nodeBus == nil {
+		l.RaiseError("node broadcast is not available")
+		return 0
+	}
+
+	n.nodeBus.Publish(topic, payload)
+
+	return 0
+}
+
+// @group utils
+// @summary Check and consume a request allowance from a token bucket rate limiter. Buckets are kept in memory and are per-node, so in a cluster each node enforces its own limit.
+// @param key(type=string) The rate limit key, for example combining an RPC name and a user ID.
+// @param limit(type=number) The maximum number of requests allowed within the window.
+// @param windowSeconds(type=number) The window in seconds over which the full allowance is replenished.
+// @return allowed(bool) True if the request is allowed, false if it should be rejected.
+// @return remaining(number) The number of requests still allowed right now.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) rateLimitCheck(l *lua.LState) int {
+	key := l.CheckString(1)
+	if key == "" {
+		l.ArgError(1, "expects key string")
+		return 0
+	}
+	limit := l.CheckInt(2)
+	if limit < 1 {
+		l.ArgError(2, "expects limit to be 1 or more")
+		return 0
+	}
+	windowSeconds := l.CheckNumber(3)
+	if windowSeconds <= 0 {
+		l.ArgError(3, "expects window seconds to be greater than 0")
+		return 0
+	}
+
+	allowed, remaining := n.localCache.RateLimitCheck(key, limit, time.Duration(float64(windowSeconds)*float64(time.Second)))
+
+	l.Push(lua.LBool(allowed))
+	l.Push(lua.LNumber(remaining))
+	return 2
+}
+
+// @group utils
+// @summary Acquire a lock shared by all nodes in the cluster, held until it is released or the TTL expires.
+// @param key(type=string) The lock key.
+// @param ttlSeconds(type=number) How long the lock is held for if it is not released, in seconds.
+// @return token(string) A token to release the lock with, or false if the lock is currently held.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) lockAcquire(l *lua.LState) int {
+	key := l.CheckString(1)
+	if key == "" {
+		l.ArgError(1, "expects key string")
+		return 0
+	}
+	ttlSeconds := l.CheckNumber(2)
+	if ttlSeconds <= 0 {
+		l.ArgError(2, "expects ttl seconds to be greater than 0")
+		return 0
+	}
+
+	token, err := LockAcquire(l.Context(), n.logger, n.db, key, time.Duration(float64(ttlSeconds)*float64(time.Second)))
+	if err != nil {
+		l.RaiseError("failed to acquire lock: %s", err.Error())
+		return 0
+	}
+
+	if token == "" {
+		l.Push(lua.LFalse)
+	} else {
+		l.Push(lua.LString(token))
+	}
+	return 1
+}
+
+// @group utils
+// @summary Release a lock acquired with lock_acquire. The lock is only released if the token matches the current holder.
+// @param key(type=string) The lock key.
+// @param token(type=string) The token returned when the lock was acquired.
+// @return released(bool) True if the lock was released, false if the token does not match the current holder.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) lockRelease(l *lua.LState) int {
+	key := l.CheckString(1)
+	if key == "" {
+		l.ArgError(1, "expects key string")
+		return 0
+	}
+	token := l.CheckString(2)
+	if token == "" {
+		l.ArgError(2, "expects token string")
+		return 0
+	}
+
+	released, err := LockRelease(l.Context(), n.logger, n.db, key, token)
+	if err != nil {
+		l.RaiseError("failed to release lock: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LBool(released))
+	return 1
+}
+
 // @group utils
 // @summary Get the current UTC time in milliseconds using the system wall clock.
 // @return t(int) A number representing the current UTC time in milliseconds.
@@ -846,8 +1293,9 @@ func (n *RuntimeLuaNakamaModule) time(l *lua.LState) int {
 
 // @group utils
 // @summary Parses a CRON expression and a timestamp in UTC seconds, and returns the next matching timestamp in UTC seconds.
-// @param expression(type=string) A valid CRON expression in standard format, for example "0 0 * * *" (meaning at midnight).
+// @param expression(type=string) A valid CRON expression in standard format, for example "0 0 * * *" (meaning at midnight), or with a leading seconds field, for example "30 0 0 * * *".
 // @param timestamp(type=number) A time value expressed as UTC seconds.
+// @param timezone(type=string, optional=true, default="UTC") An IANA time zone name the expression is evaluated in, for example "America/New_York". Local times repeated when clocks go back only match once, local times skipped when clocks go forward match the same distance past the transition.
 // @return next_ts(number) The next UTC seconds timestamp (number) that matches the given CRON expression, and is immediately after the given timestamp.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) cronNext(l *lua.LState) int {
@@ -862,13 +1310,12 @@ func (n *RuntimeLuaNakamaModule) cronNext(l *lua.LState) int {
 		return 0
 	}
 
-	expr, err := cronexpr.Parse(cron)
-	if err != nil {
-		l.ArgError(1, "expects a valid cron string")
+	expr, loc := n.cronParseArgs(l)
+	if expr == nil {
 		return 0
 	}
 	t := time.Unix(ts, 0).UTC()
-	next := expr.Next(t)
+	next := expr.Next(t, loc)
 	nextTs := next.UTC().Unix()
 	l.Push(lua.LNumber(nextTs))
 	return 1
@@ -876,8 +1323,9 @@ func (n *RuntimeLuaNakamaModule) cronNext(l *lua.LState) int {
 
 // @group utils
 // @summary Parses a CRON expression and a timestamp in UTC seconds, and returns the previous matching timestamp in UTC seconds.
-// @param expression(type=string) A valid CRON expression in standard format, for example "0 0 * * *" (meaning at midnight).
+// @param expression(type=string) A valid CRON expression in standard format, for example "0 0 * * *" (meaning at midnight), or with a leading seconds field, for example "30 0 0 * * *".
 // @param timestamp(type=number) A time value expressed as UTC seconds.
+// @param timezone(type=string, optional=true, default="UTC") An IANA time zone name the expression is evaluated in, for example "America/New_York". Local times repeated when clocks go back only match once, local times skipped when clocks go forward match the same distance past the transition.
 // @return prev_ts(number) The previous UTC seconds timestamp (number) that matches the given CRON expression, and is immediately before the given timestamp.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) cronPrev(l *lua.LState) int {
@@ -892,18 +1340,65 @@ func (n *RuntimeLuaNakamaModule) cronPrev(l *lua.LState) int {
 		return 0
 	}
 
-	expr, err := cronexpr.Parse(cron)
-	if err != nil {
-		l.ArgError(1, "expects a valid cron string")
+	expr, loc := n.cronParseArgs(l)
+	if expr == nil {
 		return 0
 	}
 	t := time.Unix(ts, 0).UTC()
-	next := expr.Last(t)
+	next := expr.Prev(t, loc)
 	nextTs := next.UTC().Unix()
 	l.Push(lua.LNumber(nextTs))
 	return 1
 }
 
+// @group utils
+// @summary Parses a CRON expression and a timestamp in UTC seconds, and returns whether the CRON expression fires at that timestamp. Unless the expression has a seconds field, the timestamp is normalized to the start of its minute before matching.
+// @param expression(type=string) A valid CRON expression in standard format, for example "0 0 * * *" (meaning at midnight), or with a leading seconds field, for example "30 0 0 * * *".
+// @param timestamp(type=number) A time value expressed as UTC seconds.
+// @param timezone(type=string, optional=true, default="UTC") An IANA time zone name the expression is evaluated in, for example "America/New_York".
+// @return match(bool) True if the given CRON expression fires at the given timestamp, false otherwise.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) cronMatch(l *lua.LState) int {
+	cron := l.CheckString(1)
+	if cron == "" {
+		l.ArgError(1, "expects cron string")
+		return 0
+	}
+	ts := l.CheckInt64(2)
+	if ts == 0 {
+		l.ArgError(2, "expects timestamp in seconds")
+		return 0
+	}
+
+	expr, loc := n.cronParseArgs(l)
+	if expr == nil {
+		return 0
+	}
+	match := expr.Match(time.Unix(ts, 0).UTC(), loc)
+	l.Push(lua.LBool(match))
+	return 1
+}
+
+// Parse the CRON expression and optional time zone arguments shared by the cron functions. Returns a nil expression
+// if an argument error was raised.
+func (n *RuntimeLuaNakamaModule) cronParseArgs(l *lua.LState) (*CronExpression, *time.Location) {
+	expr, err := ParseCronExpression(l.CheckString(1))
+	if err != nil {
+		l.ArgError(1, "expects a valid cron string")
+		return nil, nil
+	}
+
+	loc := time.UTC
+	if timezone := l.OptString(3, ""); timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			l.ArgError(3, "expects a valid IANA time zone name")
+			return nil, nil
+		}
+	}
+
+	return expr, loc
+}
+
 // @group utils
 // @summary Execute an arbitrary SQL query and return the number of rows affected. Typically an "INSERT", "DELETE", or "UPDATE" statement with no return columns.
 // @param query(type=string) A SQL query to execute.
@@ -947,16 +1442,122 @@ func (n *RuntimeLuaNakamaModule) sqlExec(l *lua.LState) int {
 	return 1
 }
 
-// @group utils
-// @summary Execute an arbitrary SQL query that is expected to return row data. Typically a "SELECT" statement.
-// @param query(type=string) A SQL query to execute.
-// @param parameters(type=table) Arbitrary parameters to pass to placeholders in the query.
-// @return result(table) A table of rows and the respective columns and values.
-// @return error(error) An optional error value if an error occurred.
-func (n *RuntimeLuaNakamaModule) sqlQuery(l *lua.LState) int {
-	query := l.CheckString(1)
-	if query == "" {
-		l.ArgError(1, "expects query string")
+// Maximum number of prepared statements cached per Lua runtime, further queries run unprepared.
+const runtimeLuaSQLStmtCacheSize = 128
+
+// Prepared statements for "sql_query" keyed by query text. Statements are prepared on the database pool and shared by
+// every VM in the runtime through the local cache, which closes them on shutdown.
+type runtimeLuaSQLStmtCache struct {
+	sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newRuntimeLuaSQLStmtCache() *runtimeLuaSQLStmtCache {
+	return &runtimeLuaSQLStmtCache{stmts: make(map[string]*sql.Stmt)}
+}
+
+// Get returns the prepared statement for the query, preparing it if needed. Returns nil without error if the cache is
+// full and the query should run unprepared.
+func (c *runtimeLuaSQLStmtCache) Get(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	c.Lock()
+	defer c.Unlock()
+	if stmt, found := c.stmts[query]; found {
+		return stmt, nil
+	}
+	if len(c.stmts) >= runtimeLuaSQLStmtCacheSize {
+		return nil, nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// Close closes and removes every cached statement.
+func (c *runtimeLuaSQLStmtCache) Close() {
+	c.Lock()
+	for query, stmt := range c.stmts {
+		_ = stmt.Close()
+		delete(c.stmts, query)
+	}
+	c.Unlock()
+}
+
+// Apply "sql_query" type hints to params, where hints map 1-based param positions to type names.
+func runtimeLuaSQLApplyTypeHints(params []interface{}, hints map[string]interface{}) error {
+	for key, hint := range hints {
+		position, err := strconv.Atoi(key)
+		if err != nil || position < 1 || position > len(params) {
+			return fmt.Errorf("type hint key %q is not a param position", key)
+		}
+		param := params[position-1]
+		if param == nil {
+			continue
+		}
+
+		switch hint {
+		case "int64":
+			switch v := param.(type) {
+			case int64:
+			case float64:
+				if v != math.Trunc(v) {
+					return fmt.Errorf("param %d expects an integer value", position)
+				}
+				params[position-1] = int64(v)
+			case string:
+				i, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					return fmt.Errorf("param %d expects an integer value", position)
+				}
+				params[position-1] = i
+			default:
+				return fmt.Errorf("param %d expects an integer value", position)
+			}
+		case "float64":
+			switch v := param.(type) {
+			case int64:
+				params[position-1] = float64(v)
+			case float64:
+			default:
+				return fmt.Errorf("param %d expects a number value", position)
+			}
+		case "string":
+			switch v := param.(type) {
+			case string:
+			case int64:
+				params[position-1] = strconv.FormatInt(v, 10)
+			case float64:
+				params[position-1] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				params[position-1] = strconv.FormatBool(v)
+			default:
+				return fmt.Errorf("param %d expects a string value", position)
+			}
+		case "bool":
+			if _, ok := param.(bool); !ok {
+				return fmt.Errorf("param %d expects a boolean value", position)
+			}
+		default:
+			return fmt.Errorf("param %d type hint must be one of int64, float64, string or bool", position)
+		}
+	}
+	return nil
+}
+
+// @group utils
+// @summary Execute an arbitrary SQL query that is expected to return row data. Typically a "SELECT" statement.
+// @param query(type=string) A SQL query to execute.
+// @param parameters(type=table) Arbitrary parameters to pass to placeholders in the query.
+// @param options(type=table, optional=true) Query options. 'cache' (bool) reuses a prepared statement for this query text; prepared statements are shared by every runtime on the node until shutdown, up to a fixed number of distinct queries. 'types' (table) maps param positions to type hints, one of 'int64', 'float64', 'string' or 'bool', so for example {[1] = "float64"} sends a whole number as a float.
+// @return result(table) A table of rows and the respective columns and values.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) sqlQuery(l *lua.LState) int {
+	query := l.CheckString(1)
+	if query == "" {
+		l.ArgError(1, "expects query string")
 		return 0
 	}
 	paramsTable := l.OptTable(2, nil)
@@ -970,10 +1571,60 @@ func (n *RuntimeLuaNakamaModule) sqlQuery(l *lua.LState) int {
 		}
 	}
 
+	var cache bool
+	if options := l.OptTable(3, nil); options != nil {
+		switch v := options.RawGetString("cache").(type) {
+		case *lua.LNilType:
+		case lua.LBool:
+			cache = bool(v)
+		default:
+			l.ArgError(3, "expects cache option to be a boolean")
+			return 0
+		}
+
+		switch v := options.RawGetString("types").(type) {
+		case *lua.LNilType:
+		case *lua.LTable:
+			hints := make(map[string]interface{}, v.Len())
+			var conversionError bool
+			v.ForEach(func(k lua.LValue, hint lua.LValue) {
+				if k.Type() != lua.LTNumber || hint.Type() != lua.LTString {
+					conversionError = true
+					return
+				}
+				hints[k.String()] = hint.String()
+			})
+			if conversionError {
+				l.ArgError(3, "expects types option to map param positions to type names")
+				return 0
+			}
+			if err := runtimeLuaSQLApplyTypeHints(params, hints); err != nil {
+				l.ArgError(3, err.Error())
+				return 0
+			}
+		default:
+			l.ArgError(3, "expects types option to be a table")
+			return 0
+		}
+	}
+
+	var stmt *sql.Stmt
+	if cache {
+		var err error
+		if stmt, err = n.localCache.sqlStmts.Get(l.Context(), n.db, query); err != nil {
+			l.RaiseError("sql query prepare error: %v", err.Error())
+			return 0
+		}
+	}
+
 	var rows *sql.Rows
 	var err error
 	err = ExecuteRetryable(func() error {
-		rows, err = n.db.QueryContext(l.Context(), query, params...)
+		if stmt != nil {
+			rows, err = stmt.QueryContext(l.Context(), params...)
+		} else {
+			rows, err = n.db.QueryContext(l.Context(), query, params...)
+		}
 		return err
 	})
 	if err != nil {
@@ -1018,6 +1669,89 @@ func (n *RuntimeLuaNakamaModule) sqlQuery(l *lua.LState) int {
 	return 1
 }
 
+// @group utils
+// @summary Execute an arbitrary SQL query and call a function with each row as it is read, without holding the full result in memory. Suitable for exports and other queries returning many rows.
+// @param query(type=string) A SQL query to execute.
+// @param parameters(type=table) Arbitrary parameters to pass to placeholders in the query.
+// @param fn(type=function) A function called with each row, as a table of column names to values. Return false to stop reading further rows.
+// @return count(number) The number of rows passed to the function.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) sqlQueryEach(l *lua.LState) int {
+	query := l.CheckString(1)
+	if query == "" {
+		l.ArgError(1, "expects query string")
+		return 0
+	}
+	paramsTable := l.OptTable(2, nil)
+	var params []interface{}
+	if paramsTable != nil && paramsTable.Len() != 0 {
+		var ok bool
+		params, ok = RuntimeLuaConvertLuaValue(paramsTable).([]interface{})
+		if !ok {
+			l.ArgError(2, "expects a list of params as a table")
+			return 0
+		}
+	}
+	fn := l.CheckFunction(3)
+
+	var rows *sql.Rows
+	var err error
+	err = ExecuteRetryable(func() error {
+		rows, err = n.db.QueryContext(l.Context(), query, params...)
+		return err
+	})
+	if err != nil {
+		l.RaiseError("sql query error: %v", err.Error())
+		return 0
+	}
+	defer rows.Close()
+
+	resultColumns, err := rows.Columns()
+	if err != nil {
+		l.RaiseError("sql query column lookup error: %v", err.Error())
+		return 0
+	}
+	resultColumnCount := len(resultColumns)
+	resultRowValues := make([]interface{}, resultColumnCount)
+	resultRowPointers := make([]interface{}, resultColumnCount)
+	for i := range resultRowValues {
+		resultRowPointers[i] = &resultRowValues[i]
+	}
+
+	var count int64
+	for rows.Next() {
+		if err = rows.Scan(resultRowPointers...); err != nil {
+			l.RaiseError("sql query scan error: %v", err.Error())
+			return 0
+		}
+
+		rowTable := l.CreateTable(0, resultColumnCount)
+		for j, col := range resultColumns {
+			rowTable.RawSetString(col, RuntimeLuaConvertValue(l, resultRowValues[j]))
+		}
+		count++
+
+		l.Push(fn)
+		l.Push(rowTable)
+		if err = l.PCall(1, 1, nil); err != nil {
+			l.RaiseError("error in sql_query_each function: %v", err.Error())
+			return 0
+		}
+		ret := l.Get(-1)
+		l.Pop(1)
+		if ret == lua.LFalse {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		l.RaiseError("sql query row scan error: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
 // @group utils
 // @summary Generate a version 4 UUID in the standard 36-character string representation.
 // @return u(string) The newly generated version 4 UUID identifier string.
@@ -1027,6 +1761,28 @@ func (n *RuntimeLuaNakamaModule) uuidV4(l *lua.LState) int {
 	return 1
 }
 
+// @group utils
+// @summary Generate a version 5 UUID from a namespace UUID and a name. The same namespace and name always produce the same UUID.
+// @param namespace(type=string) The namespace UUID in the standard 36-character string representation.
+// @param name(type=string) The name to derive the UUID from.
+// @return u(string) The version 5 UUID identifier string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) uuidV5(l *lua.LState) int {
+	namespace, err := uuid.FromString(l.CheckString(1))
+	if err != nil {
+		l.ArgError(1, "expects namespace to be a valid UUID")
+		return 0
+	}
+	name := l.CheckString(2)
+	if name == "" {
+		l.ArgError(2, "expects name string")
+		return 0
+	}
+
+	l.Push(lua.LString(uuid.NewV5(namespace, name).String()))
+	return 1
+}
+
 // @group utils
 // @summary Convert the 16-byte raw representation of a UUID into the equivalent 36-character standard UUID string representation. Will raise an error if the input is not valid and cannot be converted.
 // @param uuid_bytes(type=string) The UUID bytes to convert.
@@ -1073,9 +1829,13 @@ func (n *RuntimeLuaNakamaModule) uuidStringToBytes(l *lua.LState) int {
 // @param method(type=string) The HTTP method verb used with the request.
 // @param headers(type=table, optional=true) A table of headers used with the request.
 // @param content(type=string, optional=true) The bytes to send with the request.
-// @param timeout(type=number, optional=true, default=5000) Timeout of the request in milliseconds.
+// @param timeout(type=number, optional=true, default=5000) Timeout of the request in milliseconds. Defaults to the timeout set with http_request_defaults, if any.
 // @param insecure(type=bool, optional=true, default=false) Set to true to skip request TLS validations.
-// @return returnVal(table) Code, Headers, and Body response values for the HTTP response.
+// @param retries(type=number, optional=true, default=0) Number of times to retry GET and HEAD requests that fail with a connection error or a 5xx status code. All attempts share the request timeout.
+// @param retryBackoffMs(type=number, optional=true, default=100) Delay before the first retry in milliseconds, doubled on each subsequent retry.
+// @param retryNonIdempotent(type=bool, optional=true, default=false) Set to true to also retry POST, PUT, PATCH and DELETE requests.
+// @param client(type=string, optional=true) Name of an HTTP client registered with http_client_register, to use its client certificate and CA bundle. Cannot be combined with insecure.
+// @return returnVal(table) Code, Headers, and Body response values for the HTTP response, and the number of attempts made.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) httpRequest(l *lua.LState) int {
 	url := l.CheckString(1)
@@ -1100,51 +1860,127 @@ func (n *RuntimeLuaNakamaModule) httpRequest(l *lua.LState) int {
 		return 0
 	}
 
-	// Set a custom timeout if one is provided, or use the default.
-	timeoutMs := l.OptInt64(5, 5000)
+	// Defaults only apply to requests under the base URL they were registered for, so credentials are never sent to other hosts.
+	defaults := n.httpRequestDefaultsFor(url)
+
+	// Set a custom timeout if one is provided, or use the default for the URL if one was set, or the server default.
+	defaultTimeoutMs := int64(5_000)
+	if defaults != nil && defaults.timeoutMs > 0 {
+		defaultTimeoutMs = defaults.timeoutMs
+	}
+	timeoutMs := l.OptInt64(5, defaultTimeoutMs)
 	if timeoutMs <= 0 {
-		timeoutMs = 5_000
+		timeoutMs = defaultTimeoutMs
 	}
 
 	insecure := l.OptBool(6, false)
 
-	// Prepare request body, if any.
-	var requestBody io.Reader
-	if body != "" {
-		requestBody = strings.NewReader(body)
+	retries := l.OptInt(7, 0)
+	if retries < 0 || retries > 10 {
+		l.ArgError(7, "expects retries to be between 0 and 10")
+		return 0
 	}
-
-	ctx, ctxCancelFn := context.WithTimeout(l.Context(), time.Duration(timeoutMs)*time.Millisecond)
-	defer ctxCancelFn()
-
-	// Prepare the request.
-	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
-	if err != nil {
-		l.RaiseError("HTTP request error: %v", err.Error())
+	retryBackoffMs := l.OptInt64(8, 100)
+	if retryBackoffMs < 0 {
+		l.ArgError(8, "expects retry backoff to be a non-negative number")
 		return 0
 	}
+	if !l.OptBool(9, false) && method != http.MethodGet && method != http.MethodHead {
+		retries = 0
+	}
+
+	client := n.httpClient
+	if insecure {
+		client = n.httpClientInsecure
+	}
+	if clientName := l.OptString(10, ""); clientName != "" {
+		if insecure {
+			l.ArgError(10, "expects no named client when skipping TLS validations")
+			return 0
+		}
+		profile, found := n.httpClientProfiles[clientName]
+		if !found {
+			l.ArgError(10, fmt.Sprintf("HTTP client %q is not registered", clientName))
+			return 0
+		}
+		client = profile
+	}
 
-	// Apply any request headers.
+	// Convert request headers once, they are applied to every attempt.
 	httpHeaders := RuntimeLuaConvertLuaTable(headers)
-	for k, v := range httpHeaders {
-		vs, ok := v.(string)
-		if !ok {
+	for _, v := range httpHeaders {
+		if _, ok := v.(string); !ok {
 			l.RaiseError("HTTP header values must be strings")
 			return 0
 		}
-		req.Header.Add(k, vs)
 	}
+	// Default headers for the URL apply unless the call sets a header of the same name.
+	if defaults != nil && len(defaults.headers) != 0 {
+		callHeaders := make(map[string]struct{}, len(httpHeaders))
+		for k := range httpHeaders {
+			callHeaders[http.CanonicalHeaderKey(k)] = struct{}{}
+		}
+		for k, v := range defaults.headers {
+			if _, found := callHeaders[http.CanonicalHeaderKey(k)]; !found {
+				httpHeaders[k] = v
+			}
+		}
+	}
+
+	// The timeout covers all attempts, and is further bounded by any deadline on the calling context.
+	ctx, ctxCancelFn := context.WithTimeout(l.Context(), time.Duration(timeoutMs)*time.Millisecond)
+	defer ctxCancelFn()
 
-	// Execute the request.
 	var resp *http.Response
-	if insecure {
-		resp, err = n.httpClientInsecure.Do(req)
-	} else {
-		resp, err = n.httpClient.Do(req)
-	}
-	if err != nil {
-		l.RaiseError("HTTP request error: %v", err.Error())
-		return 0
+	var attempts int
+	for {
+		attempts++
+
+		// Prepare request body, if any. A fresh reader is needed for each attempt.
+		var requestBody io.Reader
+		if body != "" {
+			requestBody = strings.NewReader(body)
+		}
+
+		// Prepare the request.
+		req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
+		if err != nil {
+			l.RaiseError("HTTP request error: %v", err.Error())
+			return 0
+		}
+
+		// Apply any request headers.
+		for k, v := range httpHeaders {
+			req.Header.Add(k, v.(string))
+		}
+
+		// Execute the request.
+		resp, err = client.Do(req)
+
+		// Retry connection errors and server errors, unless the overall timeout has already expired.
+		if attempts <= retries && ((err != nil && ctx.Err() == nil) || (err == nil && resp.StatusCode >= http.StatusInternalServerError)) {
+			backoff := time.Duration(retryBackoffMs) * time.Millisecond << (attempts - 1)
+			// Only retry if there is time left for another attempt after the backoff, otherwise keep the current result.
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > backoff {
+				if resp != nil {
+					_, _ = io.Copy(io.Discard, resp.Body)
+					_ = resp.Body.Close()
+				}
+				select {
+				case <-ctx.Done():
+					l.RaiseError("HTTP request error: %v", ctx.Err().Error())
+					return 0
+				case <-time.After(backoff):
+				}
+				continue
+			}
+		}
+
+		if err != nil {
+			l.RaiseError("HTTP request error: %v", err.Error())
+			return 0
+		}
+		break
 	}
 	// Read the response body.
 	responseBody, err := io.ReadAll(resp.Body)
@@ -1166,7 +2002,172 @@ func (n *RuntimeLuaNakamaModule) httpRequest(l *lua.LState) int {
 	l.Push(lua.LNumber(resp.StatusCode))
 	l.Push(RuntimeLuaConvertMap(l, responseHeaders))
 	l.Push(lua.LString(responseBody))
-	return 3
+	l.Push(lua.LNumber(attempts))
+	return 4
+}
+
+// @group utils
+// @summary Set defaults applied to http_request calls whose URL falls under the given base URL, typically to centralise partner API configuration. Defaults are only applied to requests with the same scheme and host, and a path at or below the base URL path, so default headers such as API keys are never sent elsewhere. When several base URLs match a request the longest one is used. Arguments passed to an individual http_request call take precedence over these defaults, and a header set in the call replaces a default header of the same name. Setting defaults for the same base URL again replaces them. Can only be called while the module is loading.
+// @param baseUrl(type=string) The base URL the defaults apply to, including scheme and host, for example 'https://api.example.com/v1'.
+// @param options(type=table) Default 'timeout' (number) in milliseconds, and default 'headers' (table) of header names to string values, such as an API key.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) httpRequestDefaults(l *lua.LState) int {
+	if !n.checkInit(l, "http_request_defaults") {
+		return 0
+	}
+
+	base, err := url.Parse(l.CheckString(1))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		l.ArgError(1, "expects base URL with http or https scheme and a host")
+		return 0
+	}
+	options := l.CheckTable(2)
+
+	var timeoutMs int64
+	switch v := options.RawGetString("timeout").(type) {
+	case *lua.LNilType:
+	case lua.LNumber:
+		if timeoutMs = int64(v); timeoutMs <= 0 {
+			l.ArgError(2, "expects timeout option to be a positive number")
+			return 0
+		}
+	default:
+		l.ArgError(2, "expects timeout option to be a number")
+		return 0
+	}
+
+	var headers map[string]string
+	switch v := options.RawGetString("headers").(type) {
+	case *lua.LNilType:
+	case *lua.LTable:
+		var err error
+		if headers, err = RuntimeLuaConvertLuaTableString(v); err != nil {
+			l.ArgError(2, "expects headers option to map header names to string values")
+			return 0
+		}
+	default:
+		l.ArgError(2, "expects headers option to be a table")
+		return 0
+	}
+
+	defaults := &runtimeLuaHTTPDefaults{
+		scheme:    strings.ToLower(base.Scheme),
+		host:      strings.ToLower(base.Host),
+		path:      strings.TrimSuffix(base.Path, "/"),
+		timeoutMs: timeoutMs,
+		headers:   headers,
+	}
+	if n.httpDefaults == nil {
+		n.httpDefaults = make(map[string]*runtimeLuaHTTPDefaults, 1)
+	}
+	n.httpDefaults[defaults.scheme+"://"+defaults.host+defaults.path] = defaults
+	return 0
+}
+
+type runtimeLuaHTTPDefaults struct {
+	scheme    string
+	host      string
+	path      string
+	timeoutMs int64
+	headers   map[string]string
+}
+
+// Find the defaults registered for the longest base URL the request URL falls under, if any.
+func (n *RuntimeLuaNakamaModule) httpRequestDefaultsFor(requestURL string) *runtimeLuaHTTPDefaults {
+	if len(n.httpDefaults) == 0 {
+		return nil
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return nil
+	}
+
+	var match *runtimeLuaHTTPDefaults
+	for _, defaults := range n.httpDefaults {
+		if !strings.EqualFold(u.Scheme, defaults.scheme) || !strings.EqualFold(u.Host, defaults.host) {
+			continue
+		}
+		if defaults.path != "" && u.Path != defaults.path && !strings.HasPrefix(u.Path, defaults.path+"/") {
+			continue
+		}
+		if match == nil || len(defaults.path) > len(match.path) {
+			match = defaults
+		}
+	}
+	return match
+}
+
+// @group utils
+// @summary Register a named HTTP client with a client certificate for mutual TLS, a custom CA bundle to verify servers against, or both, typically once at module init. Select it by name in http_request. The default clients are unchanged. Registering a name again replaces the previous client. Can only be called while the module is loading.
+// @param name(type=string) The name of the client.
+// @param options(type=table) PEM encoded 'cert' (string) and 'key' (string) client certificate and private key, which must be given together, and PEM encoded 'ca' (string) bundle of certificates trusted in place of the system roots. Use file_read to load them from files.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) httpClientRegister(l *lua.LState) int {
+	if !n.checkInit(l, "http_client_register") {
+		return 0
+	}
+
+	name := l.CheckString(1)
+	if name == "" {
+		l.ArgError(1, "expects name string")
+		return 0
+	}
+	options := l.CheckTable(2)
+
+	pem := make(map[string]string, 3)
+	for _, k := range []string{"cert", "key", "ca"} {
+		switch v := options.RawGetString(k).(type) {
+		case *lua.LNilType:
+		case lua.LString:
+			pem[k] = string(v)
+		default:
+			l.ArgError(2, fmt.Sprintf("expects %s option to be a PEM string", k))
+			return 0
+		}
+	}
+
+	client, err := runtimeLuaHTTPClientProfile(pem["cert"], pem["key"], pem["ca"])
+	if err != nil {
+		l.ArgError(2, err.Error())
+		return 0
+	}
+
+	if previous, found := n.httpClientProfiles[name]; found {
+		previous.CloseIdleConnections()
+	}
+	n.httpClientProfiles[name] = client
+	return 0
+}
+
+// runtimeLuaHTTPClientProfile builds an HTTP client presenting the given client certificate, and verifying servers
+// against the given CA bundle rather than the system roots if one is given.
+func runtimeLuaHTTPClientProfile(certPEM, keyPEM, caPEM string) (*http.Client, error) {
+	if (certPEM == "") != (keyPEM == "") {
+		return nil, errors.New("expects cert and key options to be given together")
+	}
+	if certPEM == "" && caPEM == "" {
+		return nil, errors.New("expects a cert and key, a ca, or both")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if caPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, errors.New("invalid ca: no PEM certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
 }
 
 // @group utils
@@ -1243,7 +2244,7 @@ func (n *RuntimeLuaNakamaModule) jwtGenerate(l *lua.LState) int {
 }
 
 // @group utils
-// @summary Encode the input as JSON.
+// @summary Encode the input as JSON. Tables that reference themselves, or that exceed the configured maximum nesting depth or encoded size, raise an error.
 // @param value(type=string) The input to encode as JSON .
 // @return jsonBytes(string) The encoded JSON string.
 // @return error(error) An optional error value if an error occurred.
@@ -1254,12 +2255,47 @@ func (n *RuntimeLuaNakamaModule) jsonEncode(l *lua.LState) int {
 		return 0
 	}
 
-	jsonData := RuntimeLuaConvertLuaValue(value)
+	jsonData, err := RuntimeLuaConvertLuaValueLimited(value, n.config.GetRuntime().LuaJsonMaxDepth, n.config.GetRuntime().LuaJsonMaxSize)
+	if err != nil {
+		l.RaiseError("error encoding to JSON: %v", err.Error())
+		return 0
+	}
 	jsonBytes, err := json.Marshal(jsonData)
 	if err != nil {
 		l.RaiseError("error encoding to JSON: %v", err.Error())
 		return 0
 	}
+	if maxSize := n.config.GetRuntime().LuaJsonMaxSize; maxSize > 0 && len(jsonBytes) > maxSize {
+		l.RaiseError("error encoding to JSON: value exceeds maximum encoded size of %d bytes", maxSize)
+		return 0
+	}
+
+	l.Push(lua.LString(jsonBytes))
+	return 1
+}
+
+// @group utils
+// @summary Encode the input as canonical JSON, with object keys sorted and no insignificant whitespace, following RFC 8785. Equal inputs always produce the same output, making it suitable for signing or hashing. The same depth and size limits as json_encode apply.
+// @param value(type=any) The input to encode as JSON.
+// @return jsonBytes(string) The encoded JSON string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) jsonEncodeCanonical(l *lua.LState) int {
+	value := l.Get(1)
+	if value == nil {
+		l.ArgError(1, "expects a non-nil value to encode")
+		return 0
+	}
+
+	jsonData, err := RuntimeLuaConvertLuaValueLimited(value, n.config.GetRuntime().LuaJsonMaxDepth, n.config.GetRuntime().LuaJsonMaxSize)
+	if err != nil {
+		l.RaiseError("error encoding to JSON: %v", err.Error())
+		return 0
+	}
+	jsonBytes, err := CanonicalJSONMarshal(jsonData)
+	if err != nil {
+		l.RaiseError("error encoding to JSON: %v", err.Error())
+		return 0
+	}
 
 	l.Push(lua.LString(jsonBytes))
 	return 1
@@ -1287,6 +2323,82 @@ func (n *RuntimeLuaNakamaModule) jsonDecode(l *lua.LState) int {
 	return 1
 }
 
+// @group utils
+// @summary Register a named schema, written in the restricted JSON Schema subset supported by json_validate_subset, so it can later be referenced by name in json_validate_subset, for example from a before storage write hook. Must be called while the module is loading, schemas are shared by all runtime instances. Registering a name again replaces the previous schema.
+// @param name(type=string) The name of the schema.
+// @param schema(type=table) The schema as a table, or as a JSON string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) jsonSchemaSubsetRegister(l *lua.LState) int {
+	if !n.checkInit(l, "json_schema_subset_register") {
+		return 0
+	}
+
+	name := l.CheckString(1)
+	if name == "" {
+		l.ArgError(1, "expects name string")
+		return 0
+	}
+
+	schema, err := runtimeLuaCompileJSONSchemaSubset(l.Get(2))
+	if err != nil {
+		l.ArgError(2, err.Error())
+		return 0
+	}
+
+	n.localCache.JSONSchemaSubsetRegister(name, schema)
+	return 0
+}
+
+// @group utils
+// @summary Validate a value against a schema written in a restricted subset of JSON Schema. This is not a full JSON Schema implementation: only the type, enum, const, properties, required, additionalProperties, minProperties, maxProperties, items, minItems, maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf and not keywords are supported, and schemas using any other validation keyword, including references and formats, are rejected.
+// @param schema(type=table) The schema as a table, or the name of a schema registered with json_schema_subset_register.
+// @param value(type=any) The value to validate. JSON strings should be decoded with json_decode first.
+// @return valid(bool) True if the value is valid, false otherwise.
+// @return errors(table) A list of strings describing each violation, empty if the value is valid.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) jsonValidateSubset(l *lua.LState) int {
+	var schema *JSONSchemaSubset
+	switch v := l.Get(1).(type) {
+	case lua.LString:
+		var found bool
+		if schema, found = n.localCache.JSONSchemaSubset(string(v)); !found {
+			l.ArgError(1, fmt.Sprintf("schema %q is not registered", string(v)))
+			return 0
+		}
+	case *lua.LTable:
+		var err error
+		if schema, err = runtimeLuaCompileJSONSchemaSubset(v); err != nil {
+			l.ArgError(1, err.Error())
+			return 0
+		}
+	default:
+		l.ArgError(1, "expects schema table or registered schema name")
+		return 0
+	}
+
+	errs := schema.Validate(RuntimeLuaConvertLuaValue(l.Get(2)))
+
+	errsTable := l.CreateTable(len(errs), 0)
+	for i, e := range errs {
+		errsTable.RawSetInt(i+1, lua.LString(e))
+	}
+
+	l.Push(lua.LBool(len(errs) == 0))
+	l.Push(errsTable)
+	return 2
+}
+
+func runtimeLuaCompileJSONSchemaSubset(lv lua.LValue) (*JSONSchemaSubset, error) {
+	switch v := lv.(type) {
+	case lua.LString:
+		return CompileJSONSchemaSubsetString(string(v))
+	case *lua.LTable:
+		return CompileJSONSchemaSubset(RuntimeLuaConvertLuaValue(v))
+	default:
+		return nil, errors.New("expects schema table or JSON string")
+	}
+}
+
 // @group utils
 // @summary Base64 encode a string input.
 // @param input(type=string) The string which will be base64 encoded.
@@ -1435,6 +2547,124 @@ func (n *RuntimeLuaNakamaModule) base16Decode(l *lua.LState) int {
 	return 1
 }
 
+// @group utils
+// @summary Compress input using gzip.
+// @param input(type=string) The input string to compress.
+// @param level(type=number, optional=true, default=-1) The compression level, from 1 (best speed) to 9 (best compression). Use -1 for the default level or 0 for no compression.
+// @return output(string) The gzip compressed input.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) gzipCompress(l *lua.LState) int {
+	return compress(l, func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	})
+}
+
+// @group utils
+// @summary Decompress gzip compressed input.
+// @param input(type=string) The gzip compressed input string.
+// @param maxSize(type=number, optional=true, default=10485760) The maximum size in bytes of the decompressed output. An error is raised if the output would be larger.
+// @return output(string) The decompressed input.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) gzipDecompress(l *lua.LState) int {
+	return decompress(l, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+// @group utils
+// @summary Compress input using raw deflate, without any gzip or zlib framing.
+// @param input(type=string) The input string to compress.
+// @param level(type=number, optional=true, default=-1) The compression level, from 1 (best speed) to 9 (best compression). Use -1 for the default level or 0 for no compression.
+// @return output(string) The deflate compressed input.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) deflateCompress(l *lua.LState) int {
+	return compress(l, func(w io.Writer, level int) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+}
+
+// @group utils
+// @summary Decompress raw deflate compressed input.
+// @param input(type=string) The deflate compressed input string.
+// @param maxSize(type=number, optional=true, default=10485760) The maximum size in bytes of the decompressed output. An error is raised if the output would be larger.
+// @return output(string) The decompressed input.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) deflateDecompress(l *lua.LState) int {
+	return decompress(l, func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	})
+}
+
+// Not annotated as not exported and available in the Lua runtime
+func compress(l *lua.LState, newWriter func(w io.Writer, level int) (io.WriteCloser, error)) int {
+	input := l.CheckString(1)
+	if input == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+	level := l.OptInt(2, flate.DefaultCompression)
+	if level < flate.DefaultCompression || level > flate.BestCompression {
+		l.ArgError(2, "expects level to be between -1 and 9")
+		return 0
+	}
+
+	var buf bytes.Buffer
+	w, err := newWriter(&buf, level)
+	if err != nil {
+		l.RaiseError("error compressing input: %v", err.Error())
+		return 0
+	}
+	if _, err = w.Write([]byte(input)); err != nil {
+		l.RaiseError("error compressing input: %v", err.Error())
+		return 0
+	}
+	if err = w.Close(); err != nil {
+		l.RaiseError("error compressing input: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LString(buf.String()))
+	return 1
+}
+
+// Default maximum size of the output of the Lua decompression functions, guarding against decompression bombs.
+const luaDecompressDefaultMaxSize = 10 * 1024 * 1024
+
+// Not annotated as not exported and available in the Lua runtime
+func decompress(l *lua.LState, newReader func(r io.Reader) (io.ReadCloser, error)) int {
+	input := l.CheckString(1)
+	if input == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+	maxSize := l.OptInt64(2, luaDecompressDefaultMaxSize)
+	if maxSize <= 0 {
+		l.ArgError(2, "expects max size to be greater than 0")
+		return 0
+	}
+
+	r, err := newReader(strings.NewReader(input))
+	if err != nil {
+		l.RaiseError("error decompressing input: %v", err.Error())
+		return 0
+	}
+	defer r.Close()
+
+	// Read one byte past the limit to tell output that fits exactly from output that is too large.
+	output, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		l.RaiseError("error decompressing input: %v", err.Error())
+		return 0
+	}
+	if int64(len(output)) > maxSize {
+		l.RaiseError("error decompressing input: output exceeds max size of %d bytes", maxSize)
+		return 0
+	}
+
+	l.Push(lua.LString(output))
+	return 1
+}
+
 // Not annotated as not exported and available in the Lua runtime
 func aesEncrypt(l *lua.LState, keySize int) int {
 	input := l.CheckString(1)
@@ -1626,12 +2856,36 @@ func (n *RuntimeLuaNakamaModule) rsaSHA256Hash(l *lua.LState) int {
 }
 
 // @group utils
-// @summary Create a HMAC-SHA256 hash from input and key.
+// @summary Create a HMAC-SHA256 hash from input and key.
+// @param input(type=string) The input string to hash.
+// @param key(type=string) The hashing key.
+// @return mac(string) Hashed input as a string using the key.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) hmacSHA256Hash(l *lua.LState) int {
+	return n.hmacHash(l, sha256.New)
+}
+
+// @group utils
+// @summary Create a HMAC-SHA1 hash from input and key.
+// @param input(type=string) The input string to hash.
+// @param key(type=string) The hashing key.
+// @return mac(string) Hashed input as a string using the key.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) hmacSHA1Hash(l *lua.LState) int {
+	return n.hmacHash(l, sha1.New)
+}
+
+// @group utils
+// @summary Create a HMAC-SHA512 hash from input and key.
 // @param input(type=string) The input string to hash.
 // @param key(type=string) The hashing key.
 // @return mac(string) Hashed input as a string using the key.
 // @return error(error) An optional error value if an error occurred.
-func (n *RuntimeLuaNakamaModule) hmacSHA256Hash(l *lua.LState) int {
+func (n *RuntimeLuaNakamaModule) hmacSHA512Hash(l *lua.LState) int {
+	return n.hmacHash(l, sha512.New)
+}
+
+func (n *RuntimeLuaNakamaModule) hmacHash(l *lua.LState, h func() hash.Hash) int {
 	input := l.CheckString(1)
 	if input == "" {
 		l.ArgError(1, "expects input string")
@@ -1643,7 +2897,7 @@ func (n *RuntimeLuaNakamaModule) hmacSHA256Hash(l *lua.LState) int {
 		return 0
 	}
 
-	mac := hmac.New(sha256.New, []byte(key))
+	mac := hmac.New(h, []byte(key))
 	_, err := mac.Write([]byte(input))
 	if err != nil {
 		l.RaiseError("error creating hash: %v", err.Error())
@@ -1654,6 +2908,19 @@ func (n *RuntimeLuaNakamaModule) hmacSHA256Hash(l *lua.LState) int {
 	return 1
 }
 
+// @group utils
+// @summary Compare two MACs or signatures in constant time, to avoid leaking timing information during verification.
+// @param a(type=string) The first MAC to compare.
+// @param b(type=string) The second MAC to compare.
+// @return result(bool) True if they are the same, false otherwise.
+func (n *RuntimeLuaNakamaModule) hmacEqual(l *lua.LState) int {
+	a := l.CheckString(1)
+	b := l.CheckString(2)
+
+	l.Push(lua.LBool(hmac.Equal([]byte(a), []byte(b))))
+	return 1
+}
+
 // @group utils
 // @summary Generate one-way hashed string using bcrypt.
 // @param input(type=string) The input string to bcrypt.
@@ -1681,7 +2948,7 @@ func (n *RuntimeLuaNakamaModule) bcryptHash(l *lua.LState) int {
 // @param hash(type=string) The bcrypted input string.
 // @param plaintext(type=string) Plaintext input to compare against.
 // @return result(bool) True if they are the same, false otherwise.
-// @return error(error) An optional error value if an error occurred.
+// @return error(string) A description of the problem if the hash is malformed, nil otherwise. A malformed hash also returns false.
 func (n *RuntimeLuaNakamaModule) bcryptCompare(l *lua.LState) int {
 	hash := l.CheckString(1)
 	if hash == "" {
@@ -1695,16 +2962,183 @@ func (n *RuntimeLuaNakamaModule) bcryptCompare(l *lua.LState) int {
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintext))
-	if err == nil {
+	switch {
+	case err == nil:
 		l.Push(lua.LBool(true))
-		return 1
-	} else if errors.Is(err, bcrypt.ErrHashTooShort) || errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		l.Push(lua.LNil)
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
 		l.Push(lua.LBool(false))
-		return 1
+		l.Push(lua.LNil)
+	default:
+		// Malformed hash, let the caller tell it apart from a wrong password without raising.
+		l.Push(lua.LBool(false))
+		l.Push(lua.LString(fmt.Sprintf("error comparing hash and plaintext: %v", err.Error())))
 	}
+	return 2
+}
 
-	l.RaiseError("error comparing hash and plaintext: %v", err.Error())
-	return 0
+// @group utils
+// @summary Generate one-way hashed string using argon2id, in the standard encoded format.
+// @param input(type=string) The input string to hash.
+// @param options(type=table, optional=true) Cost parameters: 'memory' in KiB (default 65536), 'iterations' (default 3) and 'parallelism' (default 4).
+// @return hash(string) Hashed string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) argon2idHash(l *lua.LState) int {
+	input := l.CheckString(1)
+	if input == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+
+	params := DefaultArgon2idParams
+	if options := l.OptTable(2, nil); options != nil {
+		memory, ok := runtimeLuaOptPasswordHashParam(options, "memory", int64(params.Memory))
+		if !ok {
+			l.ArgError(2, "expects memory option to be a positive integer")
+			return 0
+		}
+		iterations, ok := runtimeLuaOptPasswordHashParam(options, "iterations", int64(params.Iterations))
+		if !ok {
+			l.ArgError(2, "expects iterations option to be a positive integer")
+			return 0
+		}
+		parallelism, ok := runtimeLuaOptPasswordHashParam(options, "parallelism", int64(params.Parallelism))
+		if !ok || parallelism > math.MaxUint8 {
+			l.ArgError(2, "expects parallelism option to be an integer between 1 and 255")
+			return 0
+		}
+		if memory > math.MaxUint32 || iterations > math.MaxUint32 {
+			l.ArgError(2, "expects memory and iterations options to be within range")
+			return 0
+		}
+		params = Argon2idParams{Memory: uint32(memory), Iterations: uint32(iterations), Parallelism: uint8(parallelism)}
+	}
+
+	hash, err := Argon2idHash(input, params)
+	if err != nil {
+		l.RaiseError("error hashing input: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LString(hash))
+	return 1
+}
+
+// @group utils
+// @summary Compare an argon2id hash in the standard encoded format against a plaintext input.
+// @param hash(type=string) The argon2id hash string.
+// @param plaintext(type=string) Plaintext input to compare against.
+// @return result(bool) True if they are the same, false otherwise.
+// @return error(string) A description of the problem if the hash is malformed, nil otherwise. A malformed hash also returns false.
+func (n *RuntimeLuaNakamaModule) argon2idVerify(l *lua.LState) int {
+	hash := l.CheckString(1)
+	if hash == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+	plaintext := l.CheckString(2)
+	if plaintext == "" {
+		l.ArgError(2, "expects string")
+		return 0
+	}
+
+	match, err := Argon2idVerify(plaintext, hash)
+	l.Push(lua.LBool(match))
+	if err != nil {
+		l.Push(lua.LString(fmt.Sprintf("error comparing hash and plaintext: %v", err.Error())))
+	} else {
+		l.Push(lua.LNil)
+	}
+	return 2
+}
+
+// @group utils
+// @summary Generate one-way hashed string using scrypt.
+// @param input(type=string) The input string to hash.
+// @param options(type=table, optional=true) Cost parameters: 'cost_log2' (default 15), 'block_size' (default 8) and 'parallelism' (default 1).
+// @return hash(string) Hashed string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) scryptHash(l *lua.LState) int {
+	input := l.CheckString(1)
+	if input == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+
+	params := DefaultScryptParams
+	if options := l.OptTable(2, nil); options != nil {
+		costLog2, ok := runtimeLuaOptPasswordHashParam(options, "cost_log2", int64(params.CostLog2))
+		if !ok {
+			l.ArgError(2, "expects cost_log2 option to be a positive integer")
+			return 0
+		}
+		blockSize, ok := runtimeLuaOptPasswordHashParam(options, "block_size", int64(params.BlockSize))
+		if !ok {
+			l.ArgError(2, "expects block_size option to be a positive integer")
+			return 0
+		}
+		parallelism, ok := runtimeLuaOptPasswordHashParam(options, "parallelism", int64(params.Parallelism))
+		if !ok {
+			l.ArgError(2, "expects parallelism option to be a positive integer")
+			return 0
+		}
+		if blockSize > math.MaxInt32 || parallelism > math.MaxInt32 {
+			l.ArgError(2, "expects block_size and parallelism options to be within range")
+			return 0
+		}
+		params = ScryptParams{CostLog2: int(costLog2), BlockSize: int(blockSize), Parallelism: int(parallelism)}
+	}
+
+	hash, err := ScryptHash(input, params)
+	if err != nil {
+		l.RaiseError("error hashing input: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LString(hash))
+	return 1
+}
+
+// @group utils
+// @summary Compare a scrypt hash against a plaintext input.
+// @param hash(type=string) The scrypt hash string.
+// @param plaintext(type=string) Plaintext input to compare against.
+// @return result(bool) True if they are the same, false otherwise.
+// @return error(string) A description of the problem if the hash is malformed, nil otherwise. A malformed hash also returns false.
+func (n *RuntimeLuaNakamaModule) scryptVerify(l *lua.LState) int {
+	hash := l.CheckString(1)
+	if hash == "" {
+		l.ArgError(1, "expects string")
+		return 0
+	}
+	plaintext := l.CheckString(2)
+	if plaintext == "" {
+		l.ArgError(2, "expects string")
+		return 0
+	}
+
+	match, err := ScryptVerify(plaintext, hash)
+	l.Push(lua.LBool(match))
+	if err != nil {
+		l.Push(lua.LString(fmt.Sprintf("error comparing hash and plaintext: %v", err.Error())))
+	} else {
+		l.Push(lua.LNil)
+	}
+	return 2
+}
+
+func runtimeLuaOptPasswordHashParam(options *lua.LTable, key string, def int64) (int64, bool) {
+	switch v := options.RawGetString(key).(type) {
+	case *lua.LNilType:
+		return def, true
+	case lua.LNumber:
+		if float64(v) != math.Trunc(float64(v)) || v < 1 {
+			return 0, false
+		}
+		return int64(v), true
+	default:
+		return 0, false
+	}
 }
 
 // @group authenticate
@@ -1761,6 +3195,7 @@ func (n *RuntimeLuaNakamaModule) authenticateApple(l *lua.LState) int {
 // @param id(type=string) Custom ID to use to authenticate the user. Must be between 6-128 characters.
 // @param username(type=string, optional=true) The user's username. If left empty, one is generated.
 // @param create(type=bool, optional=true, default=true) Create user if one didn't exist previously.
+// @param hashed(type=bool, optional=true, default=false) Store and look up a salted hash of the custom ID instead of the raw value. Requires 'session.custom_id_salt' to be configured.
 // @return userID(string) The user ID of the authenticated user.
 // @return username(string) The username of the authenticated user.
 // @return created(bool) Value indicating if this account was just created or already existed.
@@ -1794,7 +3229,17 @@ func (n *RuntimeLuaNakamaModule) authenticateCustom(l *lua.LState) int {
 	// Parse create flag, if any.
 	create := l.OptBool(3, true)
 
-	dbUserID, dbUsername, created, err := AuthenticateCustom(l.Context(), n.logger, n.db, id, username, create)
+	// Parse hashed flag, if any.
+	hashed := l.OptBool(4, false)
+
+	var dbUserID, dbUsername string
+	var created bool
+	var err error
+	if hashed {
+		dbUserID, dbUsername, created, err = AuthenticateCustomHashed(l.Context(), n.logger, n.db, n.config.GetSession().CustomIdSalt, id, username, create)
+	} else {
+		dbUserID, dbUsername, created, err = AuthenticateCustom(l.Context(), n.logger, n.db, id, username, create)
+	}
 	if err != nil {
 		l.RaiseError("error authenticating: %v", err.Error())
 		return 0
@@ -1980,7 +3425,7 @@ func (n *RuntimeLuaNakamaModule) authenticateFacebook(l *lua.LState) int {
 	// Import friends if requested.
 	if importFriends {
 		// Errors are logged before this point and failure here does not invalidate the whole operation.
-		_ = importFacebookFriends(l.Context(), n.logger, n.db, n.tracker, n.router, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, token, false)
+		_ = importFacebookFriends(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, token, false)
 	}
 
 	l.Push(lua.LString(dbUserID))
@@ -2112,6 +3557,7 @@ func (n *RuntimeLuaNakamaModule) authenticateGameCenter(l *lua.LState) int {
 // @param token(type=string) Google OAuth access token.
 // @param username(type=string, optional=true) The user's username. If left empty, one is generated.
 // @param create(type=bool, optional=true, default=true) Create user if one didn't exist previously.
+// @param audiences(type=table, optional=true) List of OAuth client IDs the token audience must match. Defaults to the 'google_auth.allowed_audiences' configuration, any audience is accepted if both are empty.
 // @return userID(string) The user ID of the authenticated user.
 // @return username(string) The username of the authenticated user.
 // @return created(bool) Value indicating if this account was just created or already existed.
@@ -2139,7 +3585,28 @@ func (n *RuntimeLuaNakamaModule) authenticateGoogle(l *lua.LState) int {
 	// Parse create flag, if any.
 	create := l.OptBool(3, true)
 
-	dbUserID, dbUsername, created, err := AuthenticateGoogle(l.Context(), n.logger, n.db, n.socialClient, token, username, create)
+	// Parse allowed audiences, if any.
+	audiences := n.config.GetGoogleAuth().AllowedAudiences
+	if audiencesTable := l.OptTable(4, nil); audiencesTable != nil && audiencesTable.Len() > 0 {
+		audiences = make([]string, 0, audiencesTable.Len())
+		conversionError := false
+		audiencesTable.ForEach(func(k, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			if v.Type() != lua.LTString || v.String() == "" {
+				conversionError = true
+				l.ArgError(4, "expects audiences to be non-empty strings")
+				return
+			}
+			audiences = append(audiences, v.String())
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	dbUserID, dbUsername, created, err := AuthenticateGoogle(l.Context(), n.logger, n.db, n.socialClient, token, username, create, audiences)
 	if err != nil {
 		l.RaiseError("error authenticating: %v", err.Error())
 		return 0
@@ -2201,7 +3668,7 @@ func (n *RuntimeLuaNakamaModule) authenticateSteam(l *lua.LState) int {
 	// Import friends if requested.
 	if importFriends {
 		// Errors are logged before this point and failure here does not invalidate the whole operation.
-		_ = importSteamFriends(l.Context(), n.logger, n.db, n.tracker, n.router, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, n.config.GetSocial().Steam.PublisherKey, steamID, false)
+		_ = importSteamFriends(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, n.socialClient, uuid.FromStringOrNil(dbUserID), dbUsername, n.config.GetSocial().Steam.PublisherKey, steamID, false)
 	}
 
 	l.Push(lua.LString(dbUserID))
@@ -2413,6 +3880,68 @@ func (n *RuntimeLuaNakamaModule) loggerError(l *lua.LState) int {
 	return 1
 }
 
+// @group logger
+// @summary Create a logger handle that attaches the given fields to every message it writes, as structured log fields rather than part of the message text.
+// @param fields(type=table) A table of string keys to values to attach to each log line.
+// @return logger(table) A logger handle with 'debug', 'info', 'warn' and 'error' functions, each taking a message string.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) loggerWithFields(l *lua.LState) int {
+	fieldsTable := l.CheckTable(1)
+
+	fields := make([]zap.Field, 0, fieldsTable.Len()+1)
+	fields = append(fields, zap.String("runtime", "lua"))
+	if logFields, ok := l.Context().Value(ctxLoggerFields{}).(map[string]string); ok {
+		for key, val := range logFields {
+			fields = append(fields, zap.String(key, val))
+		}
+	}
+
+	var conversionError bool
+	fieldsTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+		if k.Type() != lua.LTString {
+			conversionError = true
+			return
+		}
+		fields = append(fields, zap.Any(k.String(), RuntimeLuaConvertLuaValue(v)))
+	})
+	if conversionError {
+		l.ArgError(1, "expects fields keys to be strings")
+		return 0
+	}
+
+	logFn := func(level zapcore.Level) lua.LGFunction {
+		return func(l *lua.LState) int {
+			// Allow both logger.info("msg") and logger:info("msg") call styles.
+			idx := 1
+			if l.Get(1).Type() == lua.LTTable {
+				idx = 2
+			}
+			message := l.CheckString(idx)
+			if message == "" {
+				l.ArgError(idx, "expects message string")
+				return 0
+			}
+
+			n.logger.Log(level, message, fields...)
+
+			l.Push(lua.LString(message))
+			return 1
+		}
+	}
+
+	handle := l.CreateTable(0, 4)
+	handle.RawSetString("debug", l.NewFunction(logFn(zapcore.DebugLevel)))
+	handle.RawSetString("info", l.NewFunction(logFn(zapcore.InfoLevel)))
+	handle.RawSetString("warn", l.NewFunction(logFn(zapcore.WarnLevel)))
+	handle.RawSetString("error", l.NewFunction(logFn(zapcore.ErrorLevel)))
+
+	l.Push(handle)
+	return 1
+}
+
 // @group accounts
 // @summary Fetch account information by user ID.
 // @param userId(type=string) User ID to fetch information for. Must be valid UUID.
@@ -2430,7 +3959,7 @@ func (n *RuntimeLuaNakamaModule) accountGetId(l *lua.LState) int {
 		return 0
 	}
 
-	account, err := GetAccount(l.Context(), n.logger, n.db, n.statusRegistry, userID)
+	account, disableReason, disableUntil, err := GetAccountWithBan(l.Context(), n.logger, n.db, n.statusRegistry, userID)
 	if err != nil {
 		l.RaiseError("failed to get account for user_id %s: %s", userID, err.Error())
 		return 0
@@ -2512,6 +4041,12 @@ func (n *RuntimeLuaNakamaModule) accountGetId(l *lua.LState) int {
 	}
 	if account.DisableTime != nil {
 		accountTable.RawSetString("disable_time", lua.LNumber(account.DisableTime.Seconds))
+		if disableReason != "" {
+			accountTable.RawSetString("disable_reason", lua.LString(disableReason))
+		}
+		if disableUntil != 0 {
+			accountTable.RawSetString("disable_until", lua.LNumber(disableUntil))
+		}
 	}
 
 	l.Push(accountTable)
@@ -2654,6 +4189,8 @@ func (n *RuntimeLuaNakamaModule) accountsGetId(l *lua.LState) int {
 // @group users
 // @summary Fetch one or more users by ID.
 // @param userIds(type=table) A Lua table of user IDs to fetch.
+// @param facebookIds(type=table, optional=true) A Lua table of Facebook IDs to fetch.
+// @param fields(type=table, optional=true) A list of user record fields to return, for example 'username' and 'avatar_url'. The user ID is always returned. Fields not listed are not read, and metadata is only decoded if requested. Defaults to the full user record.
 // @return users(table) A table of user record objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) usersGetId(l *lua.LState) int {
@@ -2704,13 +4241,36 @@ func (n *RuntimeLuaNakamaModule) usersGetId(l *lua.LState) int {
 		facebookIDs = facebookIDStrings
 	}
 
+	// Fields selection, if any.
+	var fields []string
+	if fieldsIn := l.OptTable(3, nil); fieldsIn != nil {
+		fieldsTable, ok := RuntimeLuaConvertLuaValue(fieldsIn).([]interface{})
+		if !ok {
+			l.ArgError(3, "invalid fields list")
+			return 0
+		}
+		fields = make([]string, 0, len(fieldsTable))
+		for _, f := range fieldsTable {
+			field, ok := f.(string)
+			if !ok || field == "" {
+				l.ArgError(3, "each field must be a string")
+				return 0
+			}
+			if field != "user_id" && field != "online" && !userSelectableFields[field] {
+				l.ArgError(3, fmt.Sprintf("unknown user field: %s", field))
+				return 0
+			}
+			fields = append(fields, field)
+		}
+	}
+
 	if userIDs == nil && facebookIDs == nil {
 		l.Push(l.CreateTable(0, 0))
 		return 1
 	}
 
 	// Get the user accounts.
-	users, err := GetUsers(l.Context(), n.logger, n.db, n.statusRegistry, userIDs, nil, facebookIDs)
+	users, err := GetUsersFields(l.Context(), n.logger, n.db, n.statusRegistry, userIDs, nil, facebookIDs, fields)
 	if err != nil {
 		l.RaiseError("failed to get users: %s", err.Error())
 		return 0
@@ -2719,7 +4279,13 @@ func (n *RuntimeLuaNakamaModule) usersGetId(l *lua.LState) int {
 	// Convert and push the values.
 	usersTable := l.CreateTable(len(users.Users), 0)
 	for i, user := range users.Users {
-		userTable, err := userToLuaTable(l, user)
+		var userTable *lua.LTable
+		var err error
+		if len(fields) == 0 {
+			userTable, err = userToLuaTable(l, user)
+		} else {
+			userTable, err = userFieldsToLuaTable(l, user, fields)
+		}
 		if err != nil {
 			l.RaiseError("failed to encode users: %s", err.Error())
 			return 0
@@ -2774,6 +4340,67 @@ func userToLuaTable(l *lua.LState, user *api.User) (*lua.LTable, error) {
 	return ut, nil
 }
 
+func userFieldsToLuaTable(l *lua.LState, user *api.User, fields []string) (*lua.LTable, error) {
+	ut := l.CreateTable(0, len(fields)+1)
+	ut.RawSetString("user_id", lua.LString(user.Id))
+	for _, field := range fields {
+		switch field {
+		case "username":
+			ut.RawSetString(field, lua.LString(user.Username))
+		case "display_name":
+			ut.RawSetString(field, lua.LString(user.DisplayName))
+		case "avatar_url":
+			ut.RawSetString(field, lua.LString(user.AvatarUrl))
+		case "lang_tag":
+			ut.RawSetString(field, lua.LString(user.LangTag))
+		case "location":
+			ut.RawSetString(field, lua.LString(user.Location))
+		case "timezone":
+			ut.RawSetString(field, lua.LString(user.Timezone))
+		case "apple_id":
+			if user.AppleId != "" {
+				ut.RawSetString(field, lua.LString(user.AppleId))
+			}
+		case "facebook_id":
+			if user.FacebookId != "" {
+				ut.RawSetString(field, lua.LString(user.FacebookId))
+			}
+		case "facebook_instant_game_id":
+			if user.FacebookInstantGameId != "" {
+				ut.RawSetString(field, lua.LString(user.FacebookInstantGameId))
+			}
+		case "google_id":
+			if user.GoogleId != "" {
+				ut.RawSetString(field, lua.LString(user.GoogleId))
+			}
+		case "gamecenter_id":
+			if user.GamecenterId != "" {
+				ut.RawSetString(field, lua.LString(user.GamecenterId))
+			}
+		case "steam_id":
+			if user.SteamId != "" {
+				ut.RawSetString(field, lua.LString(user.SteamId))
+			}
+		case "online":
+			ut.RawSetString(field, lua.LBool(user.Online))
+		case "edge_count":
+			ut.RawSetString(field, lua.LNumber(user.EdgeCount))
+		case "create_time":
+			ut.RawSetString(field, lua.LNumber(user.CreateTime.Seconds))
+		case "update_time":
+			ut.RawSetString(field, lua.LNumber(user.UpdateTime.Seconds))
+		case "metadata":
+			metadataMap := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(user.Metadata), &metadataMap); err != nil {
+				return nil, fmt.Errorf("failed to convert user metadata to json: %s", err.Error())
+			}
+			ut.RawSetString(field, RuntimeLuaConvertMap(l, metadataMap))
+		}
+	}
+
+	return ut, nil
+}
+
 func groupToLuaTable(l *lua.LState, group *api.Group) (*lua.LTable, error) {
 	gt := l.CreateTable(0, 12)
 	gt.RawSetString("id", lua.LString(group.Id))
@@ -2799,6 +4426,21 @@ func groupToLuaTable(l *lua.LState, group *api.Group) (*lua.LTable, error) {
 	return gt, nil
 }
 
+func linkedProfileToLuaTable(l *lua.LState, profile *LinkedProfile) *lua.LTable {
+	lt := l.CreateTable(0, 4)
+	lt.RawSetString("id", lua.LString(profile.ID))
+	if profile.Email != "" {
+		lt.RawSetString("email", lua.LString(profile.Email))
+	}
+	if profile.DisplayName != "" {
+		lt.RawSetString("display_name", lua.LString(profile.DisplayName))
+	}
+	if profile.AvatarURL != "" {
+		lt.RawSetString("avatar_url", lua.LString(profile.AvatarURL))
+	}
+	return lt
+}
+
 func purchaseValidationToLuaTable(l *lua.LState, validation *api.ValidatePurchaseResponse) *lua.LTable {
 	validatedPurchasesTable := l.CreateTable(len(validation.ValidatedPurchases), 0)
 	for i, p := range validation.ValidatedPurchases {
@@ -2845,7 +4487,7 @@ func subscriptionValidationToLuaTable(l *lua.LState, validation *api.ValidateSub
 }
 
 func subscriptionToLuaTable(l *lua.LState, p *api.ValidatedSubscription) *lua.LTable {
-	validatedSubscriptionTable := l.CreateTable(0, 13)
+	validatedSubscriptionTable := l.CreateTable(0, 14)
 	validatedSubscriptionTable.RawSetString("user_id", lua.LString(p.UserId))
 	validatedSubscriptionTable.RawSetString("product_id", lua.LString(p.ProductId))
 	validatedSubscriptionTable.RawSetString("original_transaction_id", lua.LString(p.OriginalTransactionId))
@@ -2865,6 +4507,7 @@ func subscriptionToLuaTable(l *lua.LState, p *api.ValidatedSubscription) *lua.LT
 	validatedSubscriptionTable.RawSetString("environment", lua.LString(p.Environment.String()))
 	validatedSubscriptionTable.RawSetString("expiry_time", lua.LNumber(p.ExpiryTime.Seconds))
 	validatedSubscriptionTable.RawSetString("active", lua.LBool(p.Active))
+	validatedSubscriptionTable.RawSetString("status", lua.LString(GetSubscriptionStatus(p, time.Now()).String()))
 	validatedSubscriptionTable.RawSetString("provider_response", lua.LString(p.ProviderResponse))
 	validatedSubscriptionTable.RawSetString("provider_notification", lua.LString(p.ProviderNotification))
 
@@ -2930,6 +4573,46 @@ func (n *RuntimeLuaNakamaModule) usersGetUsername(l *lua.LState) int {
 	return 1
 }
 
+// @group users
+// @summary Resolve usernames to user IDs without fetching full user records.
+// @param usernames(type=table) A table of usernames to resolve.
+// @return ids(table) A table mapping each known username to its user ID. Unknown usernames are omitted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) usersResolveUsernames(l *lua.LState) int {
+	input := l.CheckTable(1)
+
+	usernames := make([]string, 0, input.Len())
+	conversionError := false
+	input.ForEach(func(k, v lua.LValue) {
+		if conversionError {
+			return
+		}
+		if v.Type() != lua.LTString || v.String() == "" {
+			conversionError = true
+			l.ArgError(1, "each username must be a string")
+			return
+		}
+		usernames = append(usernames, v.String())
+	})
+	if conversionError {
+		return 0
+	}
+
+	ids, err := ResolveUsernames(l.Context(), n.logger, n.db, usernames)
+	if err != nil {
+		l.RaiseError("failed to resolve usernames: %s", err.Error())
+		return 0
+	}
+
+	idsTable := l.CreateTable(0, len(ids))
+	for username, id := range ids {
+		idsTable.RawSetString(username, lua.LString(id))
+	}
+
+	l.Push(idsTable)
+	return 1
+}
+
 // @group users
 // @summary Get user's friend status information for a list of target users.
 // @param userID (type=string) The current user ID.
@@ -2990,23 +4673,66 @@ func (n *RuntimeLuaNakamaModule) usersGetFriendStatus(l *lua.LState) int {
 		metadataMap := make(map[string]interface{})
 		err = json.Unmarshal([]byte(f.Metadata), &metadataMap)
 		if err != nil {
-			l.RaiseError("failed to unmarshal friend metadata: %s", err.Error())
-			return 0
+			l.RaiseError("failed to unmarshal friend metadata: %s", err.Error())
+			return 0
+		}
+		metadataTable := RuntimeLuaConvertMap(l, metadataMap)
+		ft.RawSetString("metadata", metadataTable)
+
+		userFriends.RawSetInt(i+1, ft)
+	}
+
+	l.Push(userFriends)
+	return 1
+}
+
+// @group users
+// @summary Check whether one or more users are currently online, in a single lookup. Last-seen times are not included: the server only tracks which users are connected now and does not record when a user disconnects, so there is no last-seen time to report.
+// @param userIds(type=table) A list of user IDs to check.
+// @return statuses(table) A table of user IDs to a boolean, true if the user has at least one connected session.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) usersOnlineStatus(l *lua.LState) int {
+	userIDsTable := l.CheckTable(1)
+
+	userIDs := make([]uuid.UUID, 0, userIDsTable.Len())
+	var conversionError bool
+	userIDsTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+		if v.Type() != lua.LTString {
+			conversionError = true
+			l.ArgError(1, "expects each user ID to be a string")
+			return
+		}
+		userID, err := uuid.FromString(v.String())
+		if err != nil {
+			conversionError = true
+			l.ArgError(1, "expects each user ID to be a valid identifier")
+			return
 		}
-		metadataTable := RuntimeLuaConvertMap(l, metadataMap)
-		ft.RawSetString("metadata", metadataTable)
+		userIDs = append(userIDs, userID)
+	})
+	if conversionError {
+		return 0
+	}
 
-		userFriends.RawSetInt(i+1, ft)
+	statuses := n.statusRegistry.OnlineStatuses(userIDs)
+
+	statusesTable := l.CreateTable(0, len(statuses))
+	for userID, online := range statuses {
+		statusesTable.RawSetString(userID.String(), lua.LBool(online))
 	}
 
-	l.Push(userFriends)
+	l.Push(statusesTable)
 	return 1
 }
 
 // @group users
 // @summary Fetch one or more users randomly.
 // @param count(type=int) The number of users to fetch.
-// @return users(table) A list of user record objects.
+// @param filter(type=table, optional=true) A table with an `index_name` (string) and `query` (string) using the storage index query syntax. Users are drawn uniformly at random from the owners of all matching index entries.
+// @return users(table) A list of user record objects. Empty if no users match the filter.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) usersGetRandom(l *lua.LState) int {
 	count := l.OptInt(1, 0)
@@ -3016,7 +4742,32 @@ func (n *RuntimeLuaNakamaModule) usersGetRandom(l *lua.LState) int {
 		return 0
 	}
 
-	users, err := GetRandomUsers(l.Context(), n.logger, n.db, n.statusRegistry, count)
+	var indexName, query string
+	if filterTable := l.OptTable(2, nil); filterTable != nil {
+		indexNameValue := filterTable.RawGetString("index_name")
+		if indexNameValue.Type() != lua.LTString || indexNameValue.String() == "" {
+			l.ArgError(2, "expects filter index_name to be a non-empty string")
+			return 0
+		}
+		indexName = indexNameValue.String()
+
+		queryValue := filterTable.RawGetString("query")
+		if queryValue != lua.LNil {
+			if queryValue.Type() != lua.LTString {
+				l.ArgError(2, "expects filter query to be a string")
+				return 0
+			}
+			query = queryValue.String()
+		}
+	}
+
+	var users []*api.User
+	var err error
+	if indexName != "" {
+		users, err = GetRandomUsersFiltered(l.Context(), n.logger, n.db, n.statusRegistry, n.storageIndex, indexName, query, count)
+	} else {
+		users, err = GetRandomUsers(l.Context(), n.logger, n.db, n.statusRegistry, count)
+	}
 	if err != nil {
 		l.RaiseError("failed to get users: %s", err.Error())
 		return 0
@@ -3040,6 +4791,9 @@ func (n *RuntimeLuaNakamaModule) usersGetRandom(l *lua.LState) int {
 // @group users
 // @summary Ban one or more users by ID.
 // @param userIds(type=table) A table of user IDs to ban.
+// @param duration(type=number, optional=true, default=0) How long the ban lasts in seconds, after which it is lifted automatically. The ban is permanent if 0. At most 100 years.
+// @param reason(type=string, optional=true, default="") A reason for the ban, stored on each account and returned as 'disable_reason' by account_get_id.
+// @return unbanTime(number) The time the ban will be lifted as numeric UTC seconds, or 0 if the ban is permanent.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) usersBanId(l *lua.LState) int {
 	// Input table validation.
@@ -3076,14 +4830,27 @@ func (n *RuntimeLuaNakamaModule) usersBanId(l *lua.LState) int {
 		uids = append(uids, uid)
 	}
 
+	duration := l.OptInt64(2, 0)
+	if duration < 0 || duration > banMaxDurationSec {
+		l.ArgError(2, fmt.Sprintf("expects duration to be between 0 and %d", banMaxDurationSec))
+		return 0
+	}
+
+	reason := l.OptString(3, "")
+	if len(reason) > 512 {
+		l.ArgError(3, "expects reason to be at most 512 bytes")
+		return 0
+	}
+
 	// Ban the user accounts.
-	err := BanUsers(l.Context(), n.logger, n.db, n.config, n.sessionCache, n.sessionRegistry, n.tracker, uids)
+	unbanTime, err := BanUsersWithOptions(l.Context(), n.logger, n.db, n.config, n.sessionCache, n.sessionRegistry, n.tracker, uids, time.Duration(duration)*time.Second, reason)
 	if err != nil {
 		l.RaiseError("failed to ban users: %s", err.Error())
 		return 0
 	}
 
-	return 0
+	l.Push(lua.LNumber(unbanTime))
+	return 1
 }
 
 // @group users
@@ -3139,6 +4906,7 @@ func (n *RuntimeLuaNakamaModule) usersUnbanId(l *lua.LState) int {
 // @summary Link Apple authentication to a user ID.
 // @param userId(type=string) The user ID to be linked.
 // @param token(type=string) Apple sign in token.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkApple(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3154,10 +4922,14 @@ func (n *RuntimeLuaNakamaModule) linkApple(l *lua.LState) int {
 		return 0
 	}
 
-	if err := LinkApple(l.Context(), n.logger, n.db, n.config, n.socialClient, id, token); err != nil {
+	profile, err := LinkApple(l.Context(), n.logger, n.db, n.config, n.socialClient, id, token)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
@@ -3247,6 +5019,7 @@ func (n *RuntimeLuaNakamaModule) linkEmail(l *lua.LState) int {
 // @param username(type=string, optional=true) If left empty, one is generated.
 // @param token(type=string) Facebook OAuth or Limited Login (JWT) access token.
 // @param importFriends(type=bool, optional=true, default=true) Whether to automatically import Facebook friends after authentication.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkFacebook(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3268,16 +5041,21 @@ func (n *RuntimeLuaNakamaModule) linkFacebook(l *lua.LState) int {
 	}
 	importFriends := l.OptBool(4, true)
 
-	if err := LinkFacebook(l.Context(), n.logger, n.db, n.socialClient, n.tracker, n.router, id, username, n.config.GetSocial().FacebookLimitedLogin.AppId, token, importFriends); err != nil {
+	profile, err := LinkFacebook(l.Context(), n.logger, n.db, n.socialClient, n.tracker, n.router, n.notificationPushFn, id, username, n.config.GetSocial().FacebookLimitedLogin.AppId, token, importFriends)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
 // @summary Link Facebook Instant Game authentication to a user ID.
 // @param userId(type=string) The user ID to be linked.
 // @param playerInfo(type=string) Facebook player info.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkFacebookInstantGame(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3293,10 +5071,14 @@ func (n *RuntimeLuaNakamaModule) linkFacebookInstantGame(l *lua.LState) int {
 		return 0
 	}
 
-	if err := LinkFacebookInstantGame(l.Context(), n.logger, n.db, n.config, n.socialClient, id, signedPlayerInfo); err != nil {
+	profile, err := LinkFacebookInstantGame(l.Context(), n.logger, n.db, n.config, n.socialClient, id, signedPlayerInfo)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
@@ -3308,6 +5090,7 @@ func (n *RuntimeLuaNakamaModule) linkFacebookInstantGame(l *lua.LState) int {
 // @param salt(type=string) A random string returned by Game Center authentication on client.
 // @param signature(type=string) A signature returned by Game Center authentication on client.
 // @param publicKeyUrl(type=string) A URL to the public key returned by Game Center authentication on client.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkGameCenter(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3348,16 +5131,21 @@ func (n *RuntimeLuaNakamaModule) linkGameCenter(l *lua.LState) int {
 		return 0
 	}
 
-	if err := LinkGameCenter(l.Context(), n.logger, n.db, n.socialClient, id, playerID, bundleID, ts, salt, signature, publicKeyURL); err != nil {
+	profile, err := LinkGameCenter(l.Context(), n.logger, n.db, n.socialClient, id, playerID, bundleID, ts, salt, signature, publicKeyURL)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
 // @summary Link Google authentication to a user ID.
 // @param userId(type=string) The user ID to be linked.
 // @param token(type=string) Google OAuth access token.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkGoogle(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3373,10 +5161,14 @@ func (n *RuntimeLuaNakamaModule) linkGoogle(l *lua.LState) int {
 		return 0
 	}
 
-	if err := LinkGoogle(l.Context(), n.logger, n.db, n.socialClient, id, token); err != nil {
+	profile, err := LinkGoogle(l.Context(), n.logger, n.db, n.socialClient, id, token)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
@@ -3385,6 +5177,7 @@ func (n *RuntimeLuaNakamaModule) linkGoogle(l *lua.LState) int {
 // @param username(type=string) If left empty, one is generated.
 // @param token(type=string) Steam access token.
 // @param importFriends(type=bool, optiona=true, default=true) Whether to automatically import Steam friends after authentication.
+// @return profile(table) The verified provider profile with `id`, and `email`, `display_name` and `avatar_url` when available.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) linkSteam(l *lua.LState) int {
 	userID := l.CheckString(1)
@@ -3406,10 +5199,14 @@ func (n *RuntimeLuaNakamaModule) linkSteam(l *lua.LState) int {
 	}
 	importFriends := l.OptBool(4, true)
 
-	if err := LinkSteam(l.Context(), n.logger, n.db, n.config, n.socialClient, n.tracker, n.router, id, username, token, importFriends); err != nil {
+	profile, err := LinkSteam(l.Context(), n.logger, n.db, n.config, n.socialClient, n.tracker, n.router, n.notificationPushFn, id, username, token, importFriends)
+	if err != nil {
 		l.RaiseError("error linking: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(linkedProfileToLuaTable(l, profile))
+	return 1
 }
 
 // @group authenticate
@@ -3644,7 +5441,10 @@ func (n *RuntimeLuaNakamaModule) unlinkSteam(l *lua.LState) int {
 // @param stream(type=table) A stream object consisting of a `mode` (int), `subject` (string), `descriptor` (string) and `label` (string).
 // @param includeHidden(type=bool, optional=true, default=true) Include stream presences marked as hidden in the results.
 // @param includeNotHidden(type=bool, optional=true, default=true) Include stream presences not marked as hidden in the results.
+// @param limit(type=int, optional=true, default=0) Maximum number of presences to return, between 1 and 10000. If not set all presences are returned.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Requires a limit to be set.
 // @return presences(table) Table of stream presences and their information.
+// @return cursor(string) A cursor to fetch the next page of results, nil if there are no further results.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) streamUserList(l *lua.LState) int {
 	// Parse input stream identifier.
@@ -3703,7 +5503,35 @@ func (n *RuntimeLuaNakamaModule) streamUserList(l *lua.LState) int {
 	// Optional argument to include not hidden presences in the list or not, default true.
 	includeNotHidden := l.OptBool(3, true)
 
-	presences := n.tracker.ListByStream(stream, includeHidden, includeNotHidden)
+	limit := l.OptInt(4, 0)
+	if limit < 0 || limit > 10_000 {
+		l.ArgError(4, "expects limit to be value between 1 and 10000")
+		return 0
+	}
+
+	cursor := l.OptString(5, "")
+	if cursor != "" && limit == 0 {
+		l.ArgError(5, "expects limit to be set when using a cursor")
+		return 0
+	}
+
+	var presences []*Presence
+	var nextCursor string
+	if limit == 0 {
+		presences = n.tracker.ListByStream(stream, includeHidden, includeNotHidden)
+	} else {
+		after, err := decodePresenceListCursor(cursor)
+		if err != nil {
+			l.ArgError(5, "expects cursor to be valid when provided")
+			return 0
+		}
+		var next *PresenceID
+		presences, next = n.tracker.ListByStreamPage(stream, includeHidden, includeNotHidden, limit, after)
+		if nextCursor, err = encodePresenceListCursor(next); err != nil {
+			l.RaiseError("failed to create cursor: %s", err.Error())
+			return 0
+		}
+	}
 
 	presencesTable := l.CreateTable(len(presences), 0)
 	for i, p := range presences {
@@ -3720,7 +5548,12 @@ func (n *RuntimeLuaNakamaModule) streamUserList(l *lua.LState) int {
 	}
 
 	l.Push(presencesTable)
-	return 1
+	if nextCursor != "" {
+		l.Push(lua.LString(nextCursor))
+	} else {
+		l.Push(lua.LNil)
+	}
+	return 2
 }
 
 // @group streams
@@ -4357,6 +6190,93 @@ func (n *RuntimeLuaNakamaModule) streamCount(l *lua.LState) int {
 	return 1
 }
 
+// @group streams
+// @summary Get the occupancy of a stream in a single tracker lookup.
+// @param stream(type=table) A stream object consisting of a `mode` (int), `subject` (string), `descriptor` (string) and `label` (string).
+// @return stream(table) A table with `exists` (bool), `count` (number), and the stream `mode`, `subject`, `subcontext` and `label`. Untracked streams have `exists` set to false.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) streamGet(l *lua.LState) int {
+	// Parse input stream identifier.
+	streamTable := l.CheckTable(1)
+	if streamTable == nil {
+		l.ArgError(1, "expects a valid stream")
+		return 0
+	}
+	stream := PresenceStream{}
+	conversionError := false
+	streamTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+
+		switch k.String() {
+		case "mode":
+			if v.Type() != lua.LTNumber {
+				conversionError = true
+				l.ArgError(1, "stream mode must be a number")
+				return
+			}
+			stream.Mode = uint8(lua.LVAsNumber(v))
+		case "subject":
+			if v.Type() != lua.LTString {
+				conversionError = true
+				l.ArgError(1, "stream subject must be a string")
+				return
+			}
+			sid, err := uuid.FromString(v.String())
+			if err != nil {
+				conversionError = true
+				l.ArgError(1, "stream subject must be a valid identifier")
+				return
+			}
+			stream.Subject = sid
+		case "subcontext":
+			if v.Type() != lua.LTString {
+				conversionError = true
+				l.ArgError(1, "stream subcontext must be a string")
+				return
+			}
+			sid, err := uuid.FromString(v.String())
+			if err != nil {
+				conversionError = true
+				l.ArgError(1, "stream subcontext must be a valid identifier")
+				return
+			}
+			stream.Subcontext = sid
+		case "label":
+			if v.Type() != lua.LTString {
+				conversionError = true
+				l.ArgError(1, "stream label must be a string")
+				return
+			}
+			stream.Label = v.String()
+		}
+	})
+	if conversionError {
+		return 0
+	}
+
+	// A stream is only tracked while it has at least one presence, so the count alone determines existence.
+	count := n.tracker.CountByStream(stream)
+
+	result := l.CreateTable(0, 6)
+	result.RawSetString("exists", lua.LBool(count > 0))
+	result.RawSetString("count", lua.LNumber(count))
+	result.RawSetString("mode", lua.LNumber(stream.Mode))
+	if stream.Subject != uuid.Nil {
+		result.RawSetString("subject", lua.LString(stream.Subject.String()))
+	}
+	if stream.Subcontext != uuid.Nil {
+		result.RawSetString("subcontext", lua.LString(stream.Subcontext.String()))
+	}
+	if stream.Label != "" {
+		result.RawSetString("label", lua.LString(stream.Label))
+	}
+
+	l.Push(result)
+	return 1
+}
+
 // @group streams
 // @summary Close a stream and remove all presences on it.
 // @param stream(type=table) A stream object consisting of a `mode` (int), `subject` (string), `descriptor` (string) and `label` (string).
@@ -4433,6 +6353,8 @@ func (n *RuntimeLuaNakamaModule) streamClose(l *lua.LState) int {
 // @param data(type=string) The data to send.
 // @param presences(type=table) Table of presences to receive the sent data. If not set, will be sent to all presences.
 // @param reliable(type=bool, optiona=true, default=true) Whether the sender has been validated prior.
+// @return delivered(number) A best-effort count of the presences on this node the data was delivered to.
+// @return missing(number) When presences are given, how many of them could not be delivered to, for example because they have disconnected. Not returned when sending to the whole stream.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) streamSend(l *lua.LState) int {
 	// Parse input stream identifier.
@@ -4582,13 +6504,16 @@ func (n *RuntimeLuaNakamaModule) streamSend(l *lua.LState) int {
 
 	if len(presenceIDs) == 0 {
 		// Sending to whole stream.
-		n.router.SendToStream(n.logger, stream, msg, reliable)
-	} else {
-		// Sending to a subset of stream users.
-		n.router.SendToPresenceIDs(n.logger, presenceIDs, msg, reliable)
+		delivered := n.router.SendToStream(n.logger, stream, msg, reliable)
+		l.Push(lua.LNumber(delivered))
+		return 1
 	}
 
-	return 0
+	// Sending to a subset of stream users.
+	delivered := n.router.SendToPresenceIDs(n.logger, presenceIDs, msg, reliable)
+	l.Push(lua.LNumber(delivered))
+	l.Push(lua.LNumber(len(presenceIDs) - delivered))
+	return 2
 }
 
 // @group streams
@@ -4597,6 +6522,8 @@ func (n *RuntimeLuaNakamaModule) streamSend(l *lua.LState) int {
 // @param msg(type=&rtapi.Envelope{}) The message to send.
 // @param presences(type=table) Table of presences to receive the sent data. If not set, will be sent to all presences.
 // @param reliable(type=bool, optiona=true, default=true) Whether the sender has been validated prior.
+// @return delivered(number) A best-effort count of the presences on this node the message was delivered to.
+// @return missing(number) When presences are given, how many of them could not be delivered to, for example because they have disconnected. Not returned when sending to the whole stream.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) streamSendRaw(l *lua.LState) int {
 	// Parse input stream identifier.
@@ -4740,13 +6667,16 @@ func (n *RuntimeLuaNakamaModule) streamSendRaw(l *lua.LState) int {
 
 	if len(presenceIDs) == 0 {
 		// Sending to whole stream.
-		n.router.SendToStream(n.logger, stream, msg, reliable)
-	} else {
-		// Sending to a subset of stream users.
-		n.router.SendToPresenceIDs(n.logger, presenceIDs, msg, reliable)
+		delivered := n.router.SendToStream(n.logger, stream, msg, reliable)
+		l.Push(lua.LNumber(delivered))
+		return 1
 	}
 
-	return 0
+	// Sending to a subset of stream users.
+	delivered := n.router.SendToPresenceIDs(n.logger, presenceIDs, msg, reliable)
+	l.Push(lua.LNumber(delivered))
+	l.Push(lua.LNumber(len(presenceIDs) - delivered))
+	return 2
 }
 
 // @group sessions
@@ -4811,10 +6741,92 @@ func (n *RuntimeLuaNakamaModule) sessionLogout(l *lua.LState) int {
 	return 0
 }
 
+// @group sessions
+// @summary Log out a user from all of their sessions, invalidating every issued token and disconnecting any live sessions.
+// @param userId(type=string) The ID of the user to be logged out.
+// @return count(number) The number of live sessions that were disconnected.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) sessionLogoutAll(l *lua.LState) int {
+	userIDString := l.CheckString(1)
+	if userIDString == "" {
+		l.ArgError(1, "expects user id")
+		return 0
+	}
+	userID, err := uuid.FromString(userIDString)
+	if err != nil {
+		l.ArgError(1, "expects valid user id")
+		return 0
+	}
+
+	count, err := SessionLogoutAll(l.Context(), n.sessionCache, n.sessionRegistry, userID)
+	if err != nil {
+		l.RaiseError("failed to logout: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
+// @group sessions
+// @summary Replace the vars of a live session and rotate its token claims. The session keeps its ID and subsequent realtime messages on it see the new vars. A new session and refresh token pair carrying the new vars is returned, pass it to the client so HTTP requests, new sockets and session refreshes see the new vars too. Tokens already issued to the client keep the old vars until the client switches to the rotated ones.
+// @param sessionId(type=string) The ID of the session to update.
+// @param vars(type=table) The new session vars as string keys and values.
+// @return token(string) The rotated session token carrying the new vars.
+// @return refreshToken(string) The rotated refresh token carrying the new vars.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) sessionVarsUpdate(l *lua.LState) int {
+	sessionIDString := l.CheckString(1)
+	if sessionIDString == "" {
+		l.ArgError(1, "expects session id")
+		return 0
+	}
+	sessionID, err := uuid.FromString(sessionIDString)
+	if err != nil {
+		l.ArgError(1, "expects valid session id")
+		return 0
+	}
+
+	vars := l.CheckTable(2)
+	var conversionError string
+	varsMap := make(map[string]string, vars.Len())
+	vars.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError != "" {
+			return
+		}
+
+		if k.Type() != lua.LTString {
+			conversionError = "vars keys must be strings"
+			return
+		}
+		if v.Type() != lua.LTString {
+			conversionError = "vars values must be strings"
+			return
+		}
+
+		varsMap[k.String()] = v.String()
+	})
+	if conversionError != "" {
+		l.ArgError(2, conversionError)
+		return 0
+	}
+
+	token, refreshToken, err := SessionVarsUpdate(n.config, n.sessionCache, n.sessionRegistry, sessionID, varsMap)
+	if err != nil {
+		l.RaiseError("failed to update session vars: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LString(token))
+	l.Push(lua.LString(refreshToken))
+	return 2
+}
+
 // @group matches
 // @summary Create a new authoritative realtime multiplayer match running on the given runtime module name. The given params are passed to the match's init hook.
 // @param module(type=string) The name of an available runtime module that will be responsible for the match. This was registered in InitModule.
 // @param params(type=any, optional=true) Any value to pass to the match init hook.
+// @param options(type=table, optional=true) Match options. 'stop_when_empty_after_seconds' (number) signals the match to terminate once it has had no presences for that many consecutive seconds. Disabled by default.
 // @return matchId(string) The match ID of the newly created match. Clients can immediately use this ID to join the match.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) matchCreate(l *lua.LState) int {
@@ -4836,7 +6848,23 @@ func (n *RuntimeLuaNakamaModule) matchCreate(l *lua.LState) int {
 		}
 	}
 
-	id, err := n.matchRegistry.CreateMatch(l.Context(), n.matchCreateFn, module, paramsMap)
+	var opts *MatchCreateOptions
+	if options := l.OptTable(3, nil); options != nil {
+		switch v := options.RawGetString("stop_when_empty_after_seconds").(type) {
+		case *lua.LNilType:
+		case lua.LNumber:
+			if v < 0 {
+				l.ArgError(3, "expects stop_when_empty_after_seconds option to be 0 or greater")
+				return 0
+			}
+			opts = &MatchCreateOptions{StopWhenEmptyAfterSec: int(v)}
+		default:
+			l.ArgError(3, "expects stop_when_empty_after_seconds option to be a number")
+			return 0
+		}
+	}
+
+	id, err := n.matchRegistry.CreateMatchWithOptions(l.Context(), n.matchCreateFn, module, paramsMap, opts)
 	if err != nil {
 		l.RaiseError("error creating match: %s", err.Error())
 		return 0
@@ -4886,30 +6914,102 @@ func (n *RuntimeLuaNakamaModule) matchGet(l *lua.LState) int {
 		match.RawSetString("handler_name", lua.LNil)
 	}
 
-	l.Push(match)
+	l.Push(match)
+	return 1
+}
+
+// @group matches
+// @summary Allow the match handler to be sent a reservation signal to mark a user ID or session ID into the match state ahead of their join attempt and eventual join flow. Called when the match handler receives a runtime signal.
+// @param id(type=string) The user ID or session ID to send a reservation signal for.
+// @param data(type=string) An arbitrary input supplied by the runtime caller of the signal.
+// @return state(any) An (optionally) updated state. May be any non-nil value, or nil to end the match.
+// @return data(string) Arbitrary data to return to the runtime caller of the signal. May be a string or nil.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) matchSignal(l *lua.LState) int {
+	// Parse match ID.
+	id := l.CheckString(1)
+	// Parse signal data, if any.
+	data := l.OptString(2, "")
+
+	responseData, err := n.matchRegistry.Signal(l.Context(), id, data)
+	if err != nil {
+		l.RaiseError("failed to signal match: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LString(responseData))
+	return 1
+}
+
+// @group matches
+// @summary Stop every authoritative match running on this node that was created from the given handler. Each match's terminate function is called with the grace period before it ends. Matches on other nodes in a cluster are not affected.
+// @param handlerName(type=string) The name of the match handler, as used when creating the matches.
+// @param graceSeconds(type=number, optional=true, default=0) Grace period passed to the terminate function of each match. Matches end as soon as the terminate function returns if this is 0, otherwise they are stopped when the grace period expires.
+// @return count(number) The number of matches signalled to stop.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) matchesStopByHandler(l *lua.LState) int {
+	handlerName := l.CheckString(1)
+	if handlerName == "" {
+		l.ArgError(1, "expects handler name string")
+		return 0
+	}
+
+	graceSeconds := l.OptInt(2, 0)
+	if graceSeconds < 0 {
+		l.ArgError(2, "expects grace seconds to be 0 or greater")
+		return 0
+	}
+
+	count := n.matchRegistry.StopMatchesByHandler(handlerName, graceSeconds)
+
+	l.Push(lua.LNumber(count))
 	return 1
 }
 
 // @group matches
-// @summary Allow the match handler to be sent a reservation signal to mark a user ID or session ID into the match state ahead of their join attempt and eventual join flow. Called when the match handler receives a runtime signal.
-// @param id(type=string) The user ID or session ID to send a reservation signal for.
-// @param data(type=string) An arbitrary input supplied by the runtime caller of the signal.
-// @return state(any) An (optionally) updated state. May be any non-nil value, or nil to end the match.
-// @return data(string) Arbitrary data to return to the runtime caller of the signal. May be a string or nil.
+// @summary Count currently running realtime multiplayer matches, optionally filtered by authoritative mode, label, and query, without listing them.
+// @param authoritative(type=bool, optional=true, default=nil) Set true to only count authoritative matches, false to only count relayed matches and nil to count both.
+// @param label(type=string, optional=true, default="") A label to filter authoritative matches by. Default "" means any label matches.
+// @param query(type=string, optional=true) Additional query parameters to shortlist matches.
+// @return count(number) The number of matches matching the parameters criteria.
 // @return error(error) An optional error value if an error occurred.
-func (n *RuntimeLuaNakamaModule) matchSignal(l *lua.LState) int {
-	// Parse match ID.
-	id := l.CheckString(1)
-	// Parse signal data, if any.
-	data := l.OptString(2, "")
+func (n *RuntimeLuaNakamaModule) matchCount(l *lua.LState) int {
+	// Parse authoritative flag.
+	var authoritative *wrapperspb.BoolValue
+	if v := l.Get(1); v.Type() != lua.LTNil {
+		if v.Type() != lua.LTBool {
+			l.ArgError(1, "expects authoritative true/false or nil")
+			return 0
+		}
+		authoritative = &wrapperspb.BoolValue{Value: lua.LVAsBool(v)}
+	}
 
-	responseData, err := n.matchRegistry.Signal(l.Context(), id, data)
+	// Parse label filter.
+	var label *wrapperspb.StringValue
+	if v := l.Get(2); v.Type() != lua.LTNil {
+		if v.Type() != lua.LTString {
+			l.ArgError(2, "expects label string or nil")
+			return 0
+		}
+		label = &wrapperspb.StringValue{Value: lua.LVAsString(v)}
+	}
+
+	var query *wrapperspb.StringValue
+	if v := l.Get(3); v.Type() != lua.LTNil {
+		if v.Type() != lua.LTString {
+			l.ArgError(3, "expects query string or nil")
+			return 0
+		}
+		query = &wrapperspb.StringValue{Value: lua.LVAsString(v)}
+	}
+
+	count, err := n.matchRegistry.CountMatches(l.Context(), authoritative, label, query)
 	if err != nil {
-		l.RaiseError("failed to signal match: %s", err.Error())
+		l.RaiseError("failed to count matches: %s", err.Error())
 		return 0
 	}
 
-	l.Push(lua.LString(responseData))
+	l.Push(lua.LNumber(count))
 	return 1
 }
 
@@ -5072,7 +7172,7 @@ func (n *RuntimeLuaNakamaModule) notificationSend(l *lua.LState) int {
 		userID: nots,
 	}
 
-	if err := NotificationSend(l.Context(), n.logger, n.db, n.tracker, n.router, notifications); err != nil {
+	if err := NotificationSend(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, notifications); err != nil {
 		l.RaiseError("failed to send notifications: %s", err.Error())
 	}
 
@@ -5229,7 +7329,7 @@ func (n *RuntimeLuaNakamaModule) notificationsSend(l *lua.LState) int {
 		return 0
 	}
 
-	if err := NotificationSend(l.Context(), n.logger, n.db, n.tracker, n.router, notifications); err != nil {
+	if err := NotificationSend(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, notifications); err != nil {
 		l.RaiseError("failed to send notifications: %s", err.Error())
 	}
 
@@ -5279,7 +7379,116 @@ func (n *RuntimeLuaNakamaModule) notificationSendAll(l *lua.LState) int {
 		CreateTime: createTime,
 	}
 
-	if err := NotificationSendAll(l.Context(), n.logger, n.db, n.tracker, n.router, notification); err != nil {
+	if err := NotificationSendAll(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, notification); err != nil {
+		l.RaiseError("failed to send notification: %s", err.Error())
+	}
+
+	return 0
+}
+
+// @group notifications
+// @summary Send an in-app notification to a filtered set of users. Recipients are resolved and delivered in batches.
+// @param subject(type=string) Notification subject.
+// @param content(type=table) Notification content. Must be set but can be an empty table.
+// @param code(type=number) Notification code to use. Must be greater than or equal to 0.
+// @param filter(type=table) Exactly one of 'user_ids' (a list of user IDs), 'group_id' (all members of a group), or 'index_name' with an optional 'query' (owners of matching storage index entries).
+// @param persistent(type=bool, optional=true, default=false) Whether to record this in the database for later listing.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) notificationSendFiltered(l *lua.LState) int {
+	subject := l.CheckString(1)
+	if subject == "" {
+		l.ArgError(1, "expects subject to be a non-empty string")
+		return 0
+	}
+
+	contentMap := RuntimeLuaConvertLuaTable(l.CheckTable(2))
+	contentBytes, err := json.Marshal(contentMap)
+	if err != nil {
+		l.ArgError(2, fmt.Sprintf("failed to convert content: %s", err.Error()))
+		return 0
+	}
+	content := string(contentBytes)
+
+	code := l.CheckInt(3)
+	if code <= 0 {
+		l.ArgError(3, "expects code number to be a positive integer")
+		return 0
+	}
+
+	filterTable := l.CheckTable(4)
+	filter := &NotificationFilter{}
+
+	if userIDsValue := filterTable.RawGetString("user_ids"); userIDsValue != lua.LNil {
+		userIDsTable, ok := userIDsValue.(*lua.LTable)
+		if !ok {
+			l.ArgError(4, "expects user_ids to be a table")
+			return 0
+		}
+		conversionError := false
+		filter.UserIDs = make([]uuid.UUID, 0, userIDsTable.Len())
+		userIDsTable.ForEach(func(k, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			if v.Type() != lua.LTString {
+				conversionError = true
+				l.ArgError(4, "expects user_ids to be strings")
+				return
+			}
+			userID, err := uuid.FromString(v.String())
+			if err != nil {
+				conversionError = true
+				l.ArgError(4, "expects user_ids to be valid UUIDs")
+				return
+			}
+			filter.UserIDs = append(filter.UserIDs, userID)
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	if groupIDValue := filterTable.RawGetString("group_id"); groupIDValue != lua.LNil {
+		if groupIDValue.Type() != lua.LTString {
+			l.ArgError(4, "expects group_id to be a string")
+			return 0
+		}
+		groupID, err := uuid.FromString(groupIDValue.String())
+		if err != nil || groupID == uuid.Nil {
+			l.ArgError(4, "expects group_id to be a valid UUID")
+			return 0
+		}
+		filter.GroupID = groupID
+	}
+
+	if indexNameValue := filterTable.RawGetString("index_name"); indexNameValue != lua.LNil {
+		if indexNameValue.Type() != lua.LTString || indexNameValue.String() == "" {
+			l.ArgError(4, "expects index_name to be a non-empty string")
+			return 0
+		}
+		filter.IndexName = indexNameValue.String()
+
+		if queryValue := filterTable.RawGetString("query"); queryValue != lua.LNil {
+			if queryValue.Type() != lua.LTString {
+				l.ArgError(4, "expects query to be a string")
+				return 0
+			}
+			filter.IndexQuery = queryValue.String()
+		}
+	}
+
+	persistent := l.OptBool(5, false)
+
+	notification := &api.Notification{
+		Subject:    subject,
+		Content:    content,
+		Code:       int32(code),
+		SenderId:   uuid.Nil.String(),
+		Persistent: persistent,
+		CreateTime: &timestamppb.Timestamp{Seconds: time.Now().UTC().Unix()},
+	}
+
+	if err := NotificationSendFiltered(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, n.storageIndex, notification, filter); err != nil {
 		l.RaiseError("failed to send notification: %s", err.Error())
 	}
 
@@ -5291,7 +7500,8 @@ func (n *RuntimeLuaNakamaModule) notificationSendAll(l *lua.LState) int {
 // @param userID(type=string) Optional userID to scope results to that user only.
 // @param limit(type=int, optiona=true, default=100) Limit number of results. Must be a value between 1 and 1000.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
-// @return notifications(table) A list of notifications.
+// @param read(type=bool, optional=true, default=nil) Only list read notifications if true, or unread notifications if false. Lists all notifications if not set.
+// @return notifications(table) A list of notifications, each with a 'read' field holding its read state.
 // @return cursor(string) A cursor to fetch the next page of results.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) notificationsList(l *lua.LState) int {
@@ -5310,7 +7520,17 @@ func (n *RuntimeLuaNakamaModule) notificationsList(l *lua.LState) int {
 
 	cursor := l.OptString(3, "")
 
-	list, err := NotificationList(l.Context(), n.logger, n.db, userID, limit, cursor, false)
+	var read *bool
+	switch v := l.Get(4).(type) {
+	case *lua.LNilType:
+	case lua.LBool:
+		read = (*bool)(&v)
+	default:
+		l.ArgError(4, "expects read to be a boolean")
+		return 0
+	}
+
+	list, readStates, err := NotificationListFiltered(l.Context(), n.logger, n.db, userID, limit, cursor, false, read)
 	if err != nil {
 		l.RaiseError("failed to list notifications: %s", err.Error())
 		return 0
@@ -5331,6 +7551,7 @@ func (n *RuntimeLuaNakamaModule) notificationsList(l *lua.LState) int {
 		noTable.RawSetString("senderId", lua.LString(no.SenderId))
 		noTable.RawSetString("persistent", lua.LBool(no.Persistent))
 		noTable.RawSetString("createTime", lua.LNumber(no.CreateTime.Seconds))
+		noTable.RawSetString("read", lua.LBool(readStates[no.Id]))
 
 		notifsTable.RawSetInt(i+1, noTable)
 	}
@@ -5346,6 +7567,118 @@ func (n *RuntimeLuaNakamaModule) notificationsList(l *lua.LState) int {
 	return 2
 }
 
+// @group notifications
+// @summary Count the notifications stored for a user. Only persistent notifications are stored, so only they are counted.
+// @param userID(type=string) The user ID to count notifications for.
+// @param read(type=bool, optional=true, default=nil) Only count read notifications if true, or unread notifications if false, for example for a badge count. Counts all notifications if not set.
+// @return count(number) The number of stored notifications, 0 if the user is unknown.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) notificationsCount(l *lua.LState) int {
+	u := l.CheckString(1)
+	userID, err := uuid.FromString(u)
+	if err != nil {
+		l.ArgError(1, "expects user_id to be a valid uuid")
+		return 0
+	}
+
+	var read *bool
+	switch v := l.Get(2).(type) {
+	case *lua.LNilType:
+	case lua.LBool:
+		read = (*bool)(&v)
+	default:
+		l.ArgError(2, "expects read to be a boolean")
+		return 0
+	}
+
+	count, err := NotificationsCount(l.Context(), n.logger, n.db, userID, read)
+	if err != nil {
+		l.RaiseError("failed to count notifications: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
+// @group notifications
+// @summary Mark one or more notifications belonging to a user as read. New notifications are unread until marked.
+// @param userID(type=string) The user ID the notifications belong to.
+// @param ids(type=table) A list of notification IDs to mark as read.
+// @return count(number) The number of notifications that were previously unread.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) notificationsMarkRead(l *lua.LState) int {
+	u := l.CheckString(1)
+	userID, err := uuid.FromString(u)
+	if err != nil {
+		l.ArgError(1, "expects user_id to be a valid uuid")
+		return 0
+	}
+
+	idsTable := l.CheckTable(2)
+	ids := make([]string, 0, idsTable.Len())
+	conversionError := false
+	idsTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+
+		if v.Type() != lua.LTString {
+			conversionError = true
+			l.ArgError(2, "expects ids to be a table of strings")
+			return
+		}
+		if _, err := uuid.FromString(v.String()); err != nil {
+			conversionError = true
+			l.ArgError(2, "expects ids to be valid uuids")
+			return
+		}
+		ids = append(ids, v.String())
+	})
+	if conversionError {
+		return 0
+	}
+
+	count, err := NotificationsMarkRead(l.Context(), n.logger, n.db, userID, ids)
+	if err != nil {
+		l.RaiseError("failed to mark notifications as read: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
+// @group notifications
+// @summary Delete all stored notifications for a user created before the given time. Only persistent notifications are stored, so only they are deleted.
+// @param userID(type=string) The user ID to delete notifications for.
+// @param before(type=number) Time since epoch in seconds. Notifications created before this time are deleted.
+// @return count(number) The number of notifications deleted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) notificationsDeleteOlderThan(l *lua.LState) int {
+	u := l.CheckString(1)
+	userID, err := uuid.FromString(u)
+	if err != nil {
+		l.ArgError(1, "expects user_id to be a valid uuid")
+		return 0
+	}
+
+	before := l.CheckInt64(2)
+	if before <= 0 {
+		l.ArgError(2, "expects before to be a time since epoch in seconds")
+		return 0
+	}
+
+	count, err := NotificationsDeleteOlderThan(l.Context(), n.logger, n.db, userID, time.Unix(before, 0).UTC())
+	if err != nil {
+		l.RaiseError("failed to delete notifications: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
 // @group notifications
 // @summary Delete one or more in-app notifications.
 // @param notifications(type=table) A list of notifications to be deleted.
@@ -5647,7 +7980,9 @@ func (n *RuntimeLuaNakamaModule) notificationsDeleteId(l *lua.LState) int {
 // @param changeset(type=table) The set of wallet operations to apply.
 // @param metadata(type=table, optional=true) Additional metadata to tag the wallet update with.
 // @param updateLedger(type=bool, optional=true, default=false) Whether to record this update in the ledger.
+// @param limits(type=table, optional=true) Per-currency balance limits, for example { coins = { min = 0, max = 1000 } }. A spend that would take a currency below its 'min' fails the whole update, a grant that would take it above its 'max' is capped. Limits are enforced in the same transaction as the update.
 // @return result(table) The changeset after the update and before to the update, respectively.
+// @return clamped(bool) True if any grant was capped by its maximum balance.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) walletUpdate(l *lua.LState) int {
 	// Parse user ID.
@@ -5693,11 +8028,67 @@ func (n *RuntimeLuaNakamaModule) walletUpdate(l *lua.LState) int {
 
 	updateLedger := l.OptBool(4, false)
 
-	results, err := UpdateWallets(l.Context(), n.logger, n.db, []*walletUpdate{{
+	// Parse balance limits, optional.
+	var minBalances, maxBalances map[string]int64
+	limitsTable := l.OptTable(5, nil)
+	if limitsTable != nil {
+		var conversionError bool
+		limitsTable.ForEach(func(k, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			currency, ok := k.(lua.LString)
+			if !ok {
+				conversionError = true
+				l.ArgError(5, "expects limits keys to be currency names")
+				return
+			}
+			limitTable, ok := v.(*lua.LTable)
+			if !ok {
+				conversionError = true
+				l.ArgError(5, "expects limits values to be tables")
+				return
+			}
+			for _, bound := range []string{"min", "max"} {
+				switch b := limitTable.RawGetString(bound).(type) {
+				case *lua.LNilType:
+				case lua.LNumber:
+					if float64(b) != float64(int64(b)) {
+						conversionError = true
+						l.ArgError(5, fmt.Sprintf("expects %s limits to be whole numbers", bound))
+						return
+					}
+					if bound == "min" {
+						if minBalances == nil {
+							minBalances = make(map[string]int64)
+						}
+						minBalances[string(currency)] = int64(b)
+					} else {
+						if maxBalances == nil {
+							maxBalances = make(map[string]int64)
+						}
+						maxBalances[string(currency)] = int64(b)
+					}
+				default:
+					conversionError = true
+					l.ArgError(5, fmt.Sprintf("expects %s limits to be numbers", bound))
+					return
+				}
+			}
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	update := &walletUpdate{
 		UserID:    userID,
 		Changeset: changesetMapInt64,
 		Metadata:  string(metadataBytes),
-	}}, updateLedger)
+		Min:       minBalances,
+		Max:       maxBalances,
+	}
+	results, err := UpdateWallets(l.Context(), n.logger, n.db, []*walletUpdate{update}, updateLedger)
 	if err != nil {
 		l.RaiseError("failed to update user wallet: %s", err.Error())
 		return 0
@@ -5711,7 +8102,8 @@ func (n *RuntimeLuaNakamaModule) walletUpdate(l *lua.LState) int {
 
 	l.Push(RuntimeLuaConvertMapInt64(l, results[0].Updated))
 	l.Push(RuntimeLuaConvertMapInt64(l, results[0].Previous))
-	return 2
+	l.Push(lua.LBool(walletUpdateClamped(update, results[0])))
+	return 3
 }
 
 // @group wallets
@@ -5965,6 +8357,7 @@ func (n *RuntimeLuaNakamaModule) walletLedgerList(l *lua.LState) int {
 // @summary Follow a player's status changes on a given session.
 // @param sessionID(type=string) A valid session identifier.
 // @param userIDs(type=table) A list of userIDs to follow.
+// @return statuses(table) A table of each followed user ID to a list of their current status presences, each with 'session_id', 'username' and 'status'. The list is empty for users with no status set.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) statusFollow(l *lua.LState) int {
 	sid := l.CheckString(1)
@@ -6000,7 +8393,23 @@ func (n *RuntimeLuaNakamaModule) statusFollow(l *lua.LState) int {
 
 	n.statusRegistry.Follow(suid, uids)
 
-	return 0
+	// Read current statuses after following, so any later change is also delivered as a status event.
+	statusesTable := l.CreateTable(0, len(uids))
+	for uid := range uids {
+		presences := n.tracker.ListByStream(PresenceStream{Mode: StreamModeStatus, Subject: uid}, false, true)
+		presencesTable := l.CreateTable(len(presences), 0)
+		for i, p := range presences {
+			presenceTable := l.CreateTable(0, 3)
+			presenceTable.RawSetString("session_id", lua.LString(p.ID.SessionID.String()))
+			presenceTable.RawSetString("username", lua.LString(p.Meta.Username))
+			presenceTable.RawSetString("status", lua.LString(p.Meta.Status))
+			presencesTable.RawSetInt(i+1, presenceTable)
+		}
+		statusesTable.RawSetString(uid.String(), presencesTable)
+	}
+
+	l.Push(statusesTable)
+	return 1
 }
 
 // @group status
@@ -6052,7 +8461,7 @@ func (n *RuntimeLuaNakamaModule) statusUnfollow(l *lua.LState) int {
 // @param limit(type=number, optional=true, default=100) Limit number of records retrieved.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
 // @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permission checks are bypassed.
-// @return objects(table) A list of storage objects.
+// @return objects(table) A list of storage objects in the same shape as returned by storage_read.
 // @return cursor(string) Pagination cursor.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageList(l *lua.LState) int {
@@ -6096,29 +8505,11 @@ func (n *RuntimeLuaNakamaModule) storageList(l *lua.LState) int {
 
 	lv := l.CreateTable(len(objectList.GetObjects()), 0)
 	for i, v := range objectList.GetObjects() {
-		vt := l.CreateTable(0, 9)
-		vt.RawSetString("key", lua.LString(v.Key))
-		vt.RawSetString("collection", lua.LString(v.Collection))
-		if v.UserId != "" {
-			vt.RawSetString("user_id", lua.LString(v.UserId))
-		} else {
-			vt.RawSetString("user_id", lua.LNil)
-		}
-		vt.RawSetString("version", lua.LString(v.Version))
-		vt.RawSetString("permission_read", lua.LNumber(v.PermissionRead))
-		vt.RawSetString("permission_write", lua.LNumber(v.PermissionWrite))
-		vt.RawSetString("create_time", lua.LNumber(v.CreateTime.Seconds))
-		vt.RawSetString("update_time", lua.LNumber(v.UpdateTime.Seconds))
-
-		valueMap := make(map[string]interface{})
-		err = json.Unmarshal([]byte(v.Value), &valueMap)
+		vt, err := storageObjectToLua(l, v)
 		if err != nil {
 			l.RaiseError("failed to convert value to json: %s", err.Error())
 			return 0
 		}
-		valueTable := RuntimeLuaConvertMap(l, valueMap)
-		vt.RawSetString("value", valueTable)
-
 		lv.RawSetInt(i+1, vt)
 	}
 	l.Push(lv)
@@ -6135,7 +8526,7 @@ func (n *RuntimeLuaNakamaModule) storageList(l *lua.LState) int {
 // @group storage
 // @summary Fetch one or more records by their bucket/collection/keyname and optional user.
 // @param objectIds(type=table) A table of object identifiers to be fetched.
-// @return objects(table) A list of storage objects matching the parameters criteria.
+// @return objects(table) A list of storage objects. Each object is a table with 'collection', 'key', 'user_id', 'version', 'permission_read', 'permission_write', 'create_time', 'update_time' and 'value' fields, where 'version' can be passed to a later conditional write and the times are UTC seconds. Objects that do not exist are not included.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageRead(l *lua.LState) int {
 	keysTable := l.CheckTable(1)
@@ -6240,42 +8631,49 @@ func (n *RuntimeLuaNakamaModule) storageRead(l *lua.LState) int {
 	if err != nil {
 		l.RaiseError("failed to read storage objects: %s", err.Error())
 		return 0
-	}
-
-	lv := l.CreateTable(len(objects.GetObjects()), 0)
-	for i, v := range objects.GetObjects() {
-		vt := l.CreateTable(0, 9)
-		vt.RawSetString("key", lua.LString(v.Key))
-		vt.RawSetString("collection", lua.LString(v.Collection))
-		if v.UserId != "" {
-			vt.RawSetString("user_id", lua.LString(v.UserId))
-		} else {
-			vt.RawSetString("user_id", lua.LNil)
-		}
-		vt.RawSetString("version", lua.LString(v.Version))
-		vt.RawSetString("permission_read", lua.LNumber(v.PermissionRead))
-		vt.RawSetString("permission_write", lua.LNumber(v.PermissionWrite))
-		vt.RawSetString("create_time", lua.LNumber(v.CreateTime.Seconds))
-		vt.RawSetString("update_time", lua.LNumber(v.UpdateTime.Seconds))
+	}
 
-		valueMap := make(map[string]interface{})
-		err = json.Unmarshal([]byte(v.Value), &valueMap)
+	lv := l.CreateTable(len(objects.GetObjects()), 0)
+	for i, v := range objects.GetObjects() {
+		vt, err := storageObjectToLua(l, v)
 		if err != nil {
 			l.RaiseError("failed to convert value to json: %s", err.Error())
 			return 0
 		}
-		valueTable := RuntimeLuaConvertMap(l, valueMap)
-		vt.RawSetString("value", valueTable)
-
 		lv.RawSetInt(i+1, vt)
 	}
 	l.Push(lv)
 	return 1
 }
 
+// storageObjectToLua converts a storage object to the table shape shared by all Lua storage read and list functions.
+func storageObjectToLua(l *lua.LState, v *api.StorageObject) (*lua.LTable, error) {
+	valueMap := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(v.Value), &valueMap); err != nil {
+		return nil, err
+	}
+
+	vt := l.CreateTable(0, 9)
+	vt.RawSetString("key", lua.LString(v.Key))
+	vt.RawSetString("collection", lua.LString(v.Collection))
+	if v.UserId != "" {
+		vt.RawSetString("user_id", lua.LString(v.UserId))
+	} else {
+		vt.RawSetString("user_id", lua.LNil)
+	}
+	vt.RawSetString("version", lua.LString(v.Version))
+	vt.RawSetString("permission_read", lua.LNumber(v.PermissionRead))
+	vt.RawSetString("permission_write", lua.LNumber(v.PermissionWrite))
+	vt.RawSetString("create_time", lua.LNumber(v.CreateTime.GetSeconds()))
+	vt.RawSetString("update_time", lua.LNumber(v.UpdateTime.GetSeconds()))
+	vt.RawSetString("value", RuntimeLuaConvertMap(l, valueMap))
+
+	return vt, nil
+}
+
 // @group storage
 // @summary Write one or more objects by their collection/keyname and optional user.
-// @param objectIds(type=table) A table of object identifiers to be written.
+// @param objectIds(type=table) A table of object identifiers to be written. An optional 'ttl_seconds' field on an object makes it expire after that many seconds; every write replaces any previous expiry, so rewriting an object without 'ttl_seconds' makes it permanent.
 // @return acks(table) A list of acks with the version of the written objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageWrite(l *lua.LState) int {
@@ -6333,6 +8731,7 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 		}
 
 		var userID uuid.UUID
+		var ttl int64
 		d := &api.WriteStorageObject{}
 		dataTable.ForEach(func(k, v lua.LValue) {
 			if conversionError {
@@ -6416,6 +8815,18 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 					return
 				}
 				d.PermissionWrite = &wrapperspb.Int32Value{Value: int32(v.(lua.LNumber))}
+			case "ttl_seconds":
+				if v.Type() != lua.LTNumber {
+					conversionError = true
+					l.ArgError(1, "expects ttl_seconds to be number")
+					return
+				}
+				ttl = int64(v.(lua.LNumber))
+				if ttl < 0 {
+					conversionError = true
+					l.ArgError(1, "expects ttl_seconds to be 0 or greater")
+					return
+				}
 			}
 		})
 
@@ -6449,6 +8860,7 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 		ops = append(ops, &StorageOpWrite{
 			OwnerID: userID.String(),
 			Object:  d,
+			TTL:     ttl,
 		})
 	})
 
@@ -6488,7 +8900,7 @@ func storageOpWritesToTable(l *lua.LState, ops StorageOpWrites) (*lua.LTable, er
 
 // @group storage
 // @summary Remove one or more objects by their collection/keyname and optional user.
-// @param objectIds(type=table) A list of object identifiers to be deleted.
+// @param objectIds(type=table) A list of object identifiers to be deleted. Each may include a 'version' to only delete the object if its stored version still matches; otherwise the whole delete fails, naming the mismatched object.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageDelete(l *lua.LState) int {
 	keysTable := l.CheckTable(1)
@@ -6606,7 +9018,7 @@ func (n *RuntimeLuaNakamaModule) storageDelete(l *lua.LState) int {
 }
 
 // @group users
-// @summary Update account, storage, and wallet information simultaneously.
+// @summary Update account, storage, and wallet information simultaneously. All changes are applied in a single transaction, so if any operation fails none of them persist and the error names the failed operation and its index within its group.
 // @param accountUpdates(type=table) List of account information to be updated.
 // @param storageWrites(type=table) List of storage objects to be updated.
 // @param storageDeletes(type=table) A list of storage objects to be deleted.
@@ -7125,10 +9537,11 @@ func (n *RuntimeLuaNakamaModule) multiUpdate(l *lua.LState) int {
 // @param leaderboardID(type=string) The unique identifier for the new leaderboard. This is used by clients to submit scores.
 // @param authoritative(type=bool, default=false) Mark the leaderboard as authoritative which ensures updates can only be made via the Go runtime. No client can submit a score directly.
 // @param sortOrder(type=string, optional=true, default="desc") The sort order for records in the leaderboard. Possible values are "asc" or "desc".
-// @param operator(type=string, optional=true, default="best") The operator that determines how scores behave when submitted; possible values are "best", "set", or "incr".
+// @param operator(type=string, optional=true, default="best") The operator that determines how scores behave when submitted; possible values are "best", "set", "incr" or "decr".
 // @param resetSchedule(type=string, optional=true) The cron format used to define the reset schedule for the leaderboard. This controls when a leaderboard is reset and can be used to power daily/weekly/monthly leaderboards.
 // @param metadata(type=table, optional=true) The metadata you want associated to the leaderboard. Some good examples are weather conditions for a racing game.
 // @param enableRanks(type=bool, optional=true, default=false) Whether to enable rank values for the leaderboard.
+// @param maxNumScore(type=number, optional=true, default=0) Maximum number of score submissions each owner may make per reset period. 0 means unlimited.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) leaderboardCreate(l *lua.LState) int {
 	id := l.CheckString(1)
@@ -7170,7 +9583,7 @@ func (n *RuntimeLuaNakamaModule) leaderboardCreate(l *lua.LState) int {
 	resetSchedule := l.OptString(5, "")
 	if resetSchedule != "" {
 		if _, err := cronexpr.Parse(resetSchedule); err != nil {
-			l.ArgError(5, "expects reset schedule to be a valid CRON expression")
+			l.ArgError(5, fmt.Sprintf("expects reset schedule to be a valid CRON expression: %v", err.Error()))
 			return 0
 		}
 	}
@@ -7189,7 +9602,13 @@ func (n *RuntimeLuaNakamaModule) leaderboardCreate(l *lua.LState) int {
 
 	enableRanks := l.OptBool(7, false)
 
-	_, created, err := n.leaderboardCache.Create(l.Context(), id, authoritative, sortOrderNumber, operatorNumber, resetSchedule, metadataStr, enableRanks)
+	maxNumScore := l.OptInt(8, 0)
+	if maxNumScore < 0 {
+		l.ArgError(8, "expects max num score to be 0 or greater")
+		return 0
+	}
+
+	_, created, err := n.leaderboardCache.CreateWithMaxNumScore(l.Context(), id, authoritative, sortOrderNumber, operatorNumber, resetSchedule, metadataStr, enableRanks, maxNumScore)
 	if err != nil {
 		l.RaiseError("error creating leaderboard: %v", err.Error())
 	}
@@ -7221,6 +9640,56 @@ func (n *RuntimeLuaNakamaModule) leaderboardDelete(l *lua.LState) int {
 	return 0
 }
 
+// @group leaderboards
+// @summary Delete all leaderboards and tournaments, and the scores that belong to them, matching an ID prefix and/or an end time, in one operation.
+// @param idPrefix(type=string, optional=true, default="") Only delete leaderboards whose ID starts with this prefix.
+// @param endedBefore(type=number, optional=true, default=0) Only delete leaderboards with an end time at or before this UTC unix time. Leaderboards without an end time never match.
+// @return count(number) The number of leaderboards deleted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardsDelete(l *lua.LState) int {
+	idPrefix := l.OptString(1, "")
+	endedBefore := l.OptInt64(2, 0)
+	if endedBefore < 0 {
+		l.ArgError(2, "expects end time to be >= 0")
+		return 0
+	}
+	if idPrefix == "" && endedBefore == 0 {
+		l.ArgError(1, "expects an id prefix or an end time")
+		return 0
+	}
+
+	count, err := n.leaderboardCache.DeleteMany(l.Context(), n.rankCache, n.leaderboardScheduler, idPrefix, endedBefore)
+	if err != nil {
+		l.RaiseError("error deleting leaderboards: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
+// @group leaderboards
+// @summary Rebuild the cached ranks for the current period of a leaderboard from the scores stored in the database, for example after scores were imported or edited directly in the database. This reads every score in the current period and is an expensive operation for large leaderboards. Scores written while it runs are kept. Leaderboards with ranks disabled have nothing to rebuild and return 0.
+// @param id(type=string) The unique identifier for the leaderboard to recalculate ranks for.
+// @return count(number) The number of leaderboard records with a cached rank.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRanksRecalculate(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	count, err := LeaderboardRanksRecalculate(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id)
+	if err != nil {
+		l.RaiseError("error recalculating leaderboard ranks: %v", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	return 1
+}
+
 // @group leaderboards
 // @summary Find leaderboards which have been created on the server. Leaderboards can be filtered with categories.
 // @param limit(type=number, optional=true, default=10) Return only the required number of leaderboards denoted by this limit value.
@@ -7367,11 +9836,74 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsList(l *lua.LState) int {
 	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, false)
 }
 
+// @group leaderboards
+// @summary List only the records of a set of owners, such as a user's friends, in leaderboard sort order. Each record carries its rank within the whole leaderboard.
+// @param id(type=string) The unique identifier for the leaderboard to list. Mandatory field.
+// @param owners(type=table) List of owners to list records for.
+// @param limit(type=number, optional=true, default=100) The maximum number of records to return (Max 10,000).
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param overrideExpiry(type=int, optional=true) Records with expiry in the past are not returned unless within this defined limit. Must be equal or greater than 0.
+// @return records(table) A page of the owners' leaderboard records.
+// @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any). Will be set to "" or nil when fetching last available page.
+// @return prevCursor(string) An optional previous page cursor that can be used to retrieve the previous page of records (if any).
+// @return rankCount(number) The total number of ranked records in the leaderboard, or 0 if ranks are disabled.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRecordsListOwners(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	owners := l.CheckTable(2)
+	ownerIds := make([]string, 0, owners.Len())
+	conversionError := false
+	owners.ForEach(func(k, v lua.LValue) {
+		if conversionError {
+			return
+		}
+
+		if v.Type() != lua.LTString {
+			conversionError = true
+			l.ArgError(2, "expects each owner ID to be string")
+			return
+		}
+		s := v.String()
+		if _, err := uuid.FromString(s); err != nil {
+			conversionError = true
+			l.ArgError(2, "expects each owner ID to be a valid identifier")
+			return
+		}
+		ownerIds = append(ownerIds, s)
+	})
+	if conversionError {
+		return 0
+	}
+
+	limit := l.OptInt(3, 100)
+	if limit < 1 || limit > 10000 {
+		l.ArgError(3, "expects limit to be 1-10000")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+	overrideExpiry := l.OptInt64(5, 0)
+
+	records, err := LeaderboardRecordsListOwners(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id, ownerIds, limit, cursor, overrideExpiry)
+	if err != nil {
+		l.RaiseError("error listing leaderboard records: %v", err.Error())
+		return 0
+	}
+
+	return leaderboardRecordsToLua(l, records.Records, nil, records.PrevCursor, records.NextCursor, records.RankCount, true)
+}
+
 // @group leaderboards
 // @summary Build a cursor to be used with leaderboardRecordsList to fetch records starting at a given rank. Only available if rank cache is not disabled for the leaderboard.
 // @param leaderboardID(type=string) The unique identifier of the leaderboard.
 // @param rank(type=number) The rank to start listing leaderboard records from.
 // @param overrideExpiry(type=number, optional=true) Records with expiry in the past are not returned unless within this defined limit. Must be equal or greater than 0.
+// @param reverse(type=bool, optional=true, default=false) Build a cursor that pages toward rank 1 instead, starting with the record ranked just above the given rank. Records are still returned in rank order.
 // @return leaderboardListCursor(string) A string cursor to be used with leaderboardRecordsList.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) leaderboardRecordsListCursorFromRank(l *lua.LState) int {
@@ -7388,6 +9920,7 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsListCursorFromRank(l *lua.LSt
 	}
 
 	expiryOverride := l.OptInt64(3, 0)
+	reverse := l.OptBool(4, false)
 
 	leaderboard := n.leaderboardCache.Get(id)
 	if leaderboard == nil {
@@ -7401,7 +9934,11 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsListCursorFromRank(l *lua.LSt
 		return 0
 	}
 
-	rank--
+	// A forward cursor sits on the record before the given rank, a reverse cursor sits on the record at the given rank
+	// so that listing from it starts with the rank above. Together they split the leaderboard without overlap.
+	if !reverse {
+		rank--
+	}
 
 	if rank == 0 {
 		l.Push(lua.LString(""))
@@ -7415,7 +9952,7 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsListCursorFromRank(l *lua.LSt
 	}
 
 	cursor := &leaderboardRecordListCursor{
-		IsNext:        true,
+		IsNext:        !reverse,
 		LeaderboardId: id,
 		ExpiryTime:    expiryTime,
 		Score:         score,
@@ -7443,7 +9980,10 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsListCursorFromRank(l *lua.LSt
 // @param subscore(type=number, optional=true, default=0) A secondary subscore parameter for the submission.
 // @param metadata(type=table, optional=true) The metadata you want associated to this submission. Some good examples are weather conditions for a racing game.
 // @param overrideOperator(type=number, optional=true) An override operator for the new record. The accepted values include: 0 (no override), 1 (best), 2 (set), 3 (incr), 4 (decr).
-// @return record(table) The newly created leaderboard record.
+// @param onlyIfBetter(type=bool, optional=true, default=false) Only store the submission if it ranks higher than the owner's existing record under the leaderboard sort order. Cannot be combined with an override operator.
+// @param recordExpiry(type=number, optional=true, default=0) Time since epoch in seconds after which this record no longer appears in listings or ranks. The record is kept, and the next write after it expires starts it afresh. Writes without an expiry keep any existing one, pass -1 to clear it. Ranks may still count the record for up to a minute after it expires. A leaderboard reset still expires the record if it comes first. Cannot be combined with onlyIfBetter.
+// @return record(table) The newly created leaderboard record, or the unchanged existing record if the submission was rejected.
+// @return written(bool) Whether the submission was stored. Only returned if onlyIfBetter is set.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) leaderboardRecordWrite(l *lua.LState) int {
 	id := l.CheckString(1)
@@ -7502,7 +10042,30 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordWrite(l *lua.LState) int {
 		}
 	}
 
-	record, err := LeaderboardRecordWrite(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, uuid.Nil, id, ownerID, username, score, subscore, metadataStr, overrideOperator)
+	onlyIfBetter := l.OptBool(8, false)
+	if onlyIfBetter && overrideOperator != api.Operator_NO_OVERRIDE {
+		l.ArgError(8, "expects no override operator when only writing improved records")
+		return 0
+	}
+
+	recordExpiry := l.OptInt64(9, 0)
+	if recordExpiry < 0 && recordExpiry != LeaderboardRecordExpiryClear {
+		l.ArgError(9, "expects record expiry to be >= 0, or -1 to clear it")
+		return 0
+	}
+	if recordExpiry != 0 && onlyIfBetter {
+		l.ArgError(9, "expects no record expiry when only writing improved records")
+		return 0
+	}
+
+	var record *api.LeaderboardRecord
+	written := true
+	var err error
+	if onlyIfBetter {
+		record, written, err = LeaderboardRecordWriteIfBetter(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, uuid.Nil, id, ownerID, username, score, subscore, metadataStr)
+	} else {
+		record, err = LeaderboardRecordWriteWithExpiry(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, uuid.Nil, id, ownerID, username, score, subscore, metadataStr, overrideOperator, recordExpiry)
+	}
 	if err != nil {
 		l.RaiseError("error writing leaderboard record: %v", err.Error())
 		return 0
@@ -7515,6 +10078,10 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordWrite(l *lua.LState) int {
 	}
 
 	l.Push(recordTable)
+	if onlyIfBetter {
+		l.Push(lua.LBool(written))
+		return 2
+	}
 	return 1
 }
 
@@ -7525,6 +10092,8 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordWrite(l *lua.LState) int {
 // @param limit(type=number, optional=true, default=10) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
 // @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be greater than 0.
+// @param expandUp(type=number, optional=true) Number of records ranked above the owner to return, between 0-100. If expandUp or expandDown is set, limit is ignored and no cursor may be given.
+// @param expandDown(type=number, optional=true) Number of records ranked below the owner to return, between 0-100. Fewer records are returned near the leaderboard boundaries, at most expandUp+1+expandDown in total.
 // @return records(table) A list of leaderboard records.
 // @return prevCursor(string) An optional previous page cursor that can be used to retrieve the previous page of records (if any).
 // @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any). Will be set to "" or nil when fetching last available page.
@@ -7556,6 +10125,31 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsHaystack(l *lua.LState) int {
 		return 0
 	}
 
+	if l.Get(6) != lua.LNil || l.Get(7) != lua.LNil {
+		expandUp := l.OptInt(6, 0)
+		if expandUp < 0 || expandUp > 100 {
+			l.ArgError(6, "expandUp must be 0-100")
+			return 0
+		}
+		expandDown := l.OptInt(7, 0)
+		if expandDown < 0 || expandDown > 100 {
+			l.ArgError(7, "expandDown must be 0-100")
+			return 0
+		}
+		if cursor != "" {
+			l.ArgError(4, "cursor cannot be combined with expandUp or expandDown")
+			return 0
+		}
+
+		records, err := LeaderboardRecordsHaystackExpand(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id, userID, expandUp, expandDown, int64(expiry))
+		if err != nil {
+			l.RaiseError("error listing leaderboard records haystack: %v", err.Error())
+			return 0
+		}
+
+		return leaderboardRecordsToLua(l, records.Records, nil, records.PrevCursor, records.NextCursor, records.RankCount, true)
+	}
+
 	records, err := LeaderboardRecordsHaystack(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id, cursor, userID, limit, int64(expiry))
 	if err != nil {
 		l.RaiseError("error listing leaderboard records haystack: %v", err.Error())
@@ -7701,7 +10295,7 @@ func (n *RuntimeLuaNakamaModule) purchaseValidateApple(l *lua.LState) int {
 
 	persist := l.OptBool(3, true)
 
-	validation, err := ValidatePurchasesApple(l.Context(), n.logger, n.db, userID, password, receipt, persist)
+	validation, err := ValidatePurchasesApple(l.Context(), n.logger, n.db, n.purchaseRefundFn, userID, password, receipt, persist)
 	if err != nil {
 		l.RaiseError("error validating Apple receipt: %v", err.Error())
 		return 0
@@ -7753,7 +10347,7 @@ func (n *RuntimeLuaNakamaModule) purchaseValidateGoogle(l *lua.LState) int {
 		PrivateKey:  privateKey,
 	}
 
-	validation, err := ValidatePurchaseGoogle(l.Context(), n.logger, n.db, userID, configOverride, receipt, persist)
+	validation, err := ValidatePurchaseGoogle(l.Context(), n.logger, n.db, n.purchaseRefundFn, userID, configOverride, receipt, persist)
 
 	if err != nil {
 		l.RaiseError("error validating Google receipt: %v", err.Error())
@@ -7764,6 +10358,38 @@ func (n *RuntimeLuaNakamaModule) purchaseValidateGoogle(l *lua.LState) int {
 	return 1
 }
 
+// @group purchases
+// @summary Apply a decoded Google Play Billing Real-Time Developer Notification to the stored purchase or subscription it references.
+// @param notification(type=string) JSON encoded notification payload, as found base64 encoded in the Pub/Sub message data.
+// @return purchase(table) The updated validated purchase, or nil if the notification references a subscription.
+// @return subscription(table) The updated validated subscription, or nil if the notification references a one-time purchase.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) purchaseGoogleNotificationIngest(l *lua.LState) int {
+	payload := l.CheckString(1)
+	if payload == "" {
+		l.ArgError(1, "expects notification payload")
+		return 0
+	}
+
+	purchase, subscription, err := IngestGoogleNotification(l.Context(), n.logger, n.db, n.purchaseRefundFn, payload)
+	if err != nil {
+		l.RaiseError("error ingesting Google notification: %v", err.Error())
+		return 0
+	}
+
+	if purchase != nil {
+		l.Push(purchaseToLuaTable(l, purchase))
+	} else {
+		l.Push(lua.LNil)
+	}
+	if subscription != nil {
+		l.Push(subscriptionToLuaTable(l, subscription))
+	} else {
+		l.Push(lua.LNil)
+	}
+	return 2
+}
+
 // @group purchases
 // @summary Validates and stores a purchase receipt from the Huawei App Gallery.
 // @param userId(type=string) The user ID of the owner of the receipt.
@@ -7805,7 +10431,7 @@ func (n *RuntimeLuaNakamaModule) purchaseValidateHuawei(l *lua.LState) int {
 
 	persist := l.OptBool(4, true)
 
-	validation, err := ValidatePurchaseHuawei(l.Context(), n.logger, n.db, userID, n.config.GetIAP().Huawei, signature, receipt, persist)
+	validation, err := ValidatePurchaseHuawei(l.Context(), n.logger, n.db, n.purchaseRefundFn, userID, n.config.GetIAP().Huawei, signature, receipt, persist)
 	if err != nil {
 		l.RaiseError("error validating Huawei receipt: %v", err.Error())
 		return 0
@@ -7847,7 +10473,7 @@ func (n *RuntimeLuaNakamaModule) purchaseValidateFacebookInstant(l *lua.LState)
 
 	persist := l.OptBool(3, true)
 
-	validation, err := ValidatePurchaseFacebookInstant(l.Context(), n.logger, n.db, userID, n.config.GetIAP().FacebookInstant, signedRequest, persist)
+	validation, err := ValidatePurchaseFacebookInstant(l.Context(), n.logger, n.db, n.purchaseRefundFn, userID, n.config.GetIAP().FacebookInstant, signedRequest, persist)
 	if err != nil {
 		l.RaiseError("error validating Facebook Instant receipt: %v", err.Error())
 		return 0
@@ -7884,6 +10510,9 @@ func (n *RuntimeLuaNakamaModule) purchaseGetByTransactionId(l *lua.LState) int {
 // @param userId(type=string, optional=true) Filter by user ID. Can be an empty string to list purchases for all users.
 // @param limit(type=number, optional=true, default=100) Limit number of records retrieved.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param productId(type=string, optional=true, default="") Filter by product ID.
+// @param createTimeStart(type=number, optional=true, default=0) Only list purchases stored at or after this Unix time in seconds. 0 leaves the window open.
+// @param createTimeEnd(type=number, optional=true, default=0) Only list purchases stored before this Unix time in seconds. 0 leaves the window open.
 // @return listPurchases(table) A page of stored validated purchases and possibly a cursor. If cursor is empty/nil there are no further results.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) purchasesList(l *lua.LState) int {
@@ -7903,7 +10532,24 @@ func (n *RuntimeLuaNakamaModule) purchasesList(l *lua.LState) int {
 
 	cursor := l.OptString(3, "")
 
-	purchases, err := ListPurchases(l.Context(), n.logger, n.db, userID, limit, cursor)
+	productID := l.OptString(4, "")
+
+	createTimeStart := l.OptInt64(5, 0)
+	if createTimeStart < 0 {
+		l.ArgError(5, "expects create time start to be 0 or greater")
+		return 0
+	}
+	createTimeEnd := l.OptInt64(6, 0)
+	if createTimeEnd < 0 {
+		l.ArgError(6, "expects create time end to be 0 or greater")
+		return 0
+	}
+	if createTimeStart != 0 && createTimeEnd != 0 && createTimeEnd <= createTimeStart {
+		l.ArgError(6, "expects create time end to be after create time start")
+		return 0
+	}
+
+	purchases, err := ListPurchasesFiltered(l.Context(), n.logger, n.db, userID, productID, createTimeStart, createTimeEnd, limit, cursor)
 	if err != nil {
 		l.RaiseError("error retrieving purchases: %v", err.Error())
 		return 0
@@ -7937,7 +10583,7 @@ func (n *RuntimeLuaNakamaModule) purchasesList(l *lua.LState) int {
 // @param receipt(type=string) Base-64 encoded receipt data returned by the subscription operation itself.
 // @param persist(type=bool, optional=true, default=true) Persist the subscription.
 // @param passwordOverride(type=string, optional=true) Override the iap.apple.shared_password provided in your configuration.
-// @return validation(table) The resulting successfully validated subscriptions.
+// @return validation(table) The resulting successfully validated subscriptions. The subscription 'status' is one of 'active', 'grace_period', 'on_hold', 'paused', 'expired' or 'refunded', so entitlements can be kept during a billing retry.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) subscriptionValidateApple(l *lua.LState) int {
 	password := l.OptString(4, n.config.GetIAP().Apple.SharedPassword)
@@ -7982,7 +10628,7 @@ func (n *RuntimeLuaNakamaModule) subscriptionValidateApple(l *lua.LState) int {
 // @param persist(type=bool, optional=true, default=true) Persist the subscription.
 // @param clientEmailOverride(type=string, optional=true) Override the iap.google.client_email provided in your configuration.
 // @param privateKeyOverride(type=string, optional=true) Override the iap.google.private_key provided in your configuration.
-// @return validation(table) The resulting successfully validated subscriptions.
+// @return validation(table) The resulting successfully validated subscriptions. The subscription 'status' is one of 'active', 'grace_period', 'on_hold', 'paused', 'expired' or 'refunded', so entitlements can be kept during a billing retry.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) subscriptionValidateGoogle(l *lua.LState) int {
 	clientEmail := l.OptString(4, n.config.GetIAP().Google.ClientEmail)
@@ -8114,6 +10760,54 @@ func (n *RuntimeLuaNakamaModule) subscriptionsList(l *lua.LState) int {
 	return 3
 }
 
+// @group subscriptions
+// @summary List stored validated subscriptions for a product across all users.
+// @param productId(type=string) The product ID to list subscriptions for.
+// @param activeOnly(type=bool, optional=true, default=false) Whether to skip expired and refunded subscriptions.
+// @param limit(type=number, optional=true, default=100) Limit number of records retrieved.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return subscriptions(table) A page of stored validated subscriptions.
+// @return cursor(string) Cursor for the next page of results, if any.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) subscriptionsListByProduct(l *lua.LState) int {
+	productID := l.CheckString(1)
+	if productID == "" {
+		l.ArgError(1, "expects a product ID string")
+		return 0
+	}
+
+	activeOnly := l.OptBool(2, false)
+
+	limit := l.OptInt(3, 100)
+	if limit < 1 || limit > 100 {
+		l.ArgError(3, "expects a limit 1-100")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+
+	subscriptions, err := ListSubscriptionsByProduct(l.Context(), n.logger, n.db, productID, activeOnly, limit, cursor)
+	if err != nil {
+		l.RaiseError("error retrieving subscriptions: %v", err.Error())
+		return 0
+	}
+
+	subscriptionsTable := l.CreateTable(len(subscriptions.ValidatedSubscriptions), 0)
+	for i, s := range subscriptions.ValidatedSubscriptions {
+		subscriptionsTable.RawSetInt(i+1, subscriptionToLuaTable(l, s))
+	}
+
+	l.Push(subscriptionsTable)
+
+	if subscriptions.Cursor != "" {
+		l.Push(lua.LString(subscriptions.Cursor))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
 // @group tournaments
 // @summary Setup a new dynamic tournament with the specified ID and various configuration settings. The underlying leaderboard will be created if it doesn't already exist, otherwise its configuration will not be updated.
 // @param id(type=string) The unique identifier for the new tournament. This is used by clients to submit scores.
@@ -8254,6 +10948,8 @@ func (n *RuntimeLuaNakamaModule) tournamentDelete(l *lua.LState) int {
 // @param id(type=string) The unique identifier for the tournament to update.
 // @param owner(type=string) The owner of the records to increment the count for.
 // @param count(type=number) The number of attempt counts to increment. Can be negative to decrease count.
+// @param absolute(type=bool, optional=true, default=false) Treat count as the exact number of remaining attempts to set on top of the owner's submitted scores, clamped to the tournament's max number of score attempts, instead of a delta.
+// @return remaining(number) The owner's remaining score attempts after the update. Raises an error if the owner has no record in the current tournament period.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) tournamentAddAttempt(l *lua.LState) int {
 	id := l.CheckString(1)
@@ -8272,15 +10968,24 @@ func (n *RuntimeLuaNakamaModule) tournamentAddAttempt(l *lua.LState) int {
 	}
 
 	count := l.CheckInt(3)
-	if count == 0 {
+
+	absolute := l.OptBool(4, false)
+	if absolute && count < 0 {
+		l.ArgError(3, "expects an attempt count number >= 0 when absolute")
+		return 0
+	} else if !absolute && count == 0 {
 		l.ArgError(3, "expects an attempt count number != 0")
 		return 0
 	}
 
-	if err := TournamentAddAttempt(l.Context(), n.logger, n.db, n.leaderboardCache, id, owner, count); err != nil {
+	remaining, err := TournamentAddAttemptWithMode(l.Context(), n.logger, n.db, n.leaderboardCache, id, owner, count, absolute)
+	if err != nil {
 		l.RaiseError("error adding tournament attempts: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	l.Push(lua.LNumber(remaining))
+	return 1
 }
 
 // @group tournaments
@@ -8288,6 +10993,8 @@ func (n *RuntimeLuaNakamaModule) tournamentAddAttempt(l *lua.LState) int {
 // @param id(type=string) The unique identifier for the tournament to join.
 // @param userId(type=string) The owner of the record.
 // @param username(type=string) The username of the record owner.
+// @param entryCost(type=table, optional=true) Wallet currencies and amounts to deduct from the owner when they join, for example { coins = 100 }. The deduction and the join happen in the same transaction, so neither happens if the owner cannot afford the cost or the tournament is full. Owners who have already joined are not charged again. Requires a tournament with join required.
+// @return wallet(table) The owner's wallet after the entry cost was deducted, or nil if nothing was deducted.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) tournamentJoin(l *lua.LState) int {
 	id := l.CheckString(1)
@@ -8313,10 +11020,32 @@ func (n *RuntimeLuaNakamaModule) tournamentJoin(l *lua.LState) int {
 		return 0
 	}
 
-	if err := TournamentJoin(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, uid, username, id); err != nil {
+	var entryCost map[string]int64
+	if entryCostTable := l.OptTable(4, nil); entryCostTable != nil {
+		entryCostMap := RuntimeLuaConvertLuaTable(entryCostTable)
+		entryCost = make(map[string]int64, len(entryCostMap))
+		for k, v := range entryCostMap {
+			vi, ok := v.(int64)
+			if !ok || vi <= 0 {
+				l.ArgError(4, "expects entry cost values to be whole numbers greater than zero")
+				return 0
+			}
+			entryCost[k] = vi
+		}
+	}
+
+	wallet, err := TournamentJoinWithEntryCost(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, uid, username, id, entryCost)
+	if err != nil {
 		l.RaiseError("error joining tournament: %v", err.Error())
+		return 0
 	}
-	return 0
+
+	if wallet == nil {
+		l.Push(lua.LNil)
+	} else {
+		l.Push(RuntimeLuaConvertMapInt64(l, wallet))
+	}
+	return 1
 }
 
 // @group tournaments
@@ -8588,6 +11317,7 @@ func recordToLuaTable(l *lua.LState, record *api.LeaderboardRecord) (*lua.LTable
 // @param endTime(type=number, optional=true) Filter tournament with that end before this time.
 // @param limit(type=number, optional=true, default=10) Return only the required number of tournament denoted by this limit value.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param userId(type=string, optional=true) If set, each tournament's "can_enter" reflects whether this user may submit to the current period, considering schedule, size and max attempts, and "already_joined" reports whether the user has joined or submitted to it.
 // @return tournamentList(table) A list of tournament results and possibly a cursor and possibly a cursor. If cursor is empty/nil there are no further results.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) tournamentList(l *lua.LState) int {
@@ -8656,12 +11386,31 @@ func (n *RuntimeLuaNakamaModule) tournamentList(l *lua.LState) int {
 		}
 	}
 
+	var userID uuid.UUID
+	if userIDStr := l.OptString(7, ""); userIDStr != "" {
+		var err error
+		userID, err = uuid.FromString(userIDStr)
+		if err != nil {
+			l.ArgError(7, "expects user ID to be a valid identifier")
+			return 0
+		}
+	}
+
 	list, err := TournamentList(l.Context(), n.logger, n.db, n.leaderboardCache, categoryStart, categoryEnd, startTime, endTime, limit, cursor)
 	if err != nil {
 		l.RaiseError("error listing tournaments: %v", err.Error())
 		return 0
 	}
 
+	var statuses map[string]*TournamentEntryStatus
+	if userID != uuid.Nil {
+		statuses, err = TournamentsEntryStatus(l.Context(), n.logger, n.db, n.leaderboardCache, userID, list.Tournaments)
+		if err != nil {
+			l.RaiseError("error reading tournament entries: %v", err.Error())
+			return 0
+		}
+	}
+
 	tournaments := l.CreateTable(len(list.Tournaments), 0)
 	for i, t := range list.Tournaments {
 		tt, err := tournamentToLuaTable(l, t)
@@ -8669,6 +11418,14 @@ func (n *RuntimeLuaNakamaModule) tournamentList(l *lua.LState) int {
 			l.RaiseError("error converting tournaments: %s", err.Error())
 			return 0
 		}
+		if userID != uuid.Nil {
+			status, found := statuses[t.Id]
+			if !found {
+				status = &TournamentEntryStatus{}
+			}
+			tt.RawSetString("can_enter", lua.LBool(status.CanEnter))
+			tt.RawSetString("already_joined", lua.LBool(status.AlreadyJoined))
+		}
 
 		tournaments.RawSetInt(i+1, tt)
 	}
@@ -8709,7 +11466,7 @@ func (n *RuntimeLuaNakamaModule) tournamentRanksDisable(l *lua.LState) int {
 // @param score(type=number, optional=true, default=0) The score to submit.
 // @param subscore(type=number, optional=true, default=0) A secondary subscore parameter for the submission.
 // @return metadata(table) The metadata you want associated to this submission. Some good examples are weather conditions for a racing game.
-// @return result(table) The newly created leaderboard record.
+// @return result(table) The newly created leaderboard record, including its "rank" and the tournament "rank_count". Both are 0 if ranks are disabled for the tournament.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) tournamentRecordWrite(l *lua.LState) int {
 	id := l.CheckString(1)
@@ -8760,6 +11517,7 @@ func (n *RuntimeLuaNakamaModule) tournamentRecordWrite(l *lua.LState) int {
 		l.RaiseError("error converting tournament records: %s", err.Error())
 		return 0
 	}
+	recordTable.RawSetString("rank_count", lua.LNumber(TournamentRecordRankCount(n.leaderboardCache, n.rankCache, record)))
 
 	l.Push(recordTable)
 	return 1
@@ -8796,6 +11554,8 @@ func (n *RuntimeLuaNakamaModule) tournamentRecordDelete(l *lua.LState) int {
 // @param limit(type=number, optional=true, default=10) Return only the required number of tournament records denoted by this limit value. Between 1-100.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
 // @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be greater than 0.
+// @param expandUp(type=number, optional=true) Number of records ranked above the owner to return, between 0-100. If expandUp or expandDown is set, limit is ignored and no cursor may be given.
+// @param expandDown(type=number, optional=true) Number of records ranked below the owner to return, between 0-100. Fewer records are returned near the leaderboard boundaries, at most expandUp+1+expandDown in total.
 // @return records(table) A page of tournament records.
 // @return prevCursor(string) An optional previous page cursor that can be used to retrieve the previous page of records (if any).
 // @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any). Will be set to "" or nil when fetching last available page.
@@ -8827,6 +11587,31 @@ func (n *RuntimeLuaNakamaModule) tournamentRecordsHaystack(l *lua.LState) int {
 		return 0
 	}
 
+	if l.Get(6) != lua.LNil || l.Get(7) != lua.LNil {
+		expandUp := l.OptInt(6, 0)
+		if expandUp < 0 || expandUp > 100 {
+			l.ArgError(6, "expandUp must be 0-100")
+			return 0
+		}
+		expandDown := l.OptInt(7, 0)
+		if expandDown < 0 || expandDown > 100 {
+			l.ArgError(7, "expandDown must be 0-100")
+			return 0
+		}
+		if cursor != "" {
+			l.ArgError(4, "cursor cannot be combined with expandUp or expandDown")
+			return 0
+		}
+
+		records, err := TournamentRecordsHaystackExpand(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id, userID, expandUp, expandDown, int64(expiry))
+		if err != nil {
+			l.RaiseError("error listing tournament records haystack: %v", err.Error())
+			return 0
+		}
+
+		return leaderboardRecordsToLua(l, records.Records, nil, records.PrevCursor, records.NextCursor, records.RankCount, true)
+	}
+
 	records, err := TournamentRecordsHaystack(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id, cursor, userID, limit, int64(expiry))
 	if err != nil {
 		l.RaiseError("error listing tournament records haystack: %v", err.Error())
@@ -8925,7 +11710,9 @@ func (n *RuntimeLuaNakamaModule) groupsGetId(l *lua.LState) int {
 // @param open(type=bool, optional=true, default=false) Whether the group is for anyone to join, or members will need to send invitations to join.
 // @param metadata(type=table, optional=true) Custom information to store for this group. Can be left empty as nil/null.
 // @param maxCount(type=number, optional=true, default=100) Maximum number of members to have in the group.
+// @param members(type=table, optional=true) Initial members to add in the same transaction, as a list of tables with a "user_id" and a "role" of "superadmin", "admin", "member" (default) or "join_request". Join requests to open groups are added as members. The group is not created if the members would exceed maxCount.
 // @return createGroup(string) The ID of the newly created group.
+// @return membersAdded(number) The number of initial members added, besides the creator and not counting join requests. Only returned if members is set.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) groupCreate(l *lua.LState) int {
 	userID, err := uuid.FromString(l.CheckString(1))
@@ -8959,15 +11746,73 @@ func (n *RuntimeLuaNakamaModule) groupCreate(l *lua.LState) int {
 			l.RaiseError("error encoding metadata: %v", err.Error())
 			return 0
 		}
-		metadataStr = string(metadataBytes)
-	}
-	maxCount := l.OptInt(9, 100)
-	if maxCount < 1 {
-		l.ArgError(9, "expects max_count to be >= 1")
-		return 0
+		metadataStr = string(metadataBytes)
+	}
+	maxCount := l.OptInt(9, 100)
+	if maxCount < 1 {
+		l.ArgError(9, "expects max_count to be >= 1")
+		return 0
+	}
+
+	membersTable := l.OptTable(10, nil)
+	var members []*GroupCreateMember
+	if membersTable != nil {
+		members = make([]*GroupCreateMember, 0, membersTable.Len())
+		conversionError := false
+		membersTable.ForEach(func(_ lua.LValue, v lua.LValue) {
+			if conversionError {
+				return
+			}
+
+			memberTable, ok := v.(*lua.LTable)
+			if !ok {
+				conversionError = true
+				l.ArgError(10, "expects each member to be a table")
+				return
+			}
+
+			memberID, err := uuid.FromString(lua.LVAsString(memberTable.RawGetString("user_id")))
+			if err != nil {
+				conversionError = true
+				l.ArgError(10, "expects each member user_id to be a valid identifier")
+				return
+			}
+
+			state := 2
+			switch role := memberTable.RawGetString("role"); role.Type() {
+			case lua.LTNil:
+			case lua.LTNumber:
+				state = int(lua.LVAsNumber(role))
+			case lua.LTString:
+				switch role.String() {
+				case "superadmin":
+					state = 0
+				case "admin":
+					state = 1
+				case "member":
+					state = 2
+				case "join_request":
+					state = 3
+				default:
+					state = -1
+				}
+			default:
+				state = -1
+			}
+			if state < 0 || state > 3 {
+				conversionError = true
+				l.ArgError(10, "expects each member role to be superadmin, admin, member, join_request or 0-3")
+				return
+			}
+
+			members = append(members, &GroupCreateMember{UserID: memberID, State: state})
+		})
+		if conversionError {
+			return 0
+		}
 	}
 
-	group, err := CreateGroup(l.Context(), n.logger, n.db, userID, creatorID, name, lang, desc, avatarURL, metadataStr, open, maxCount)
+	group, membersAdded, err := CreateGroupWithMembers(l.Context(), n.logger, n.db, n.groupEventFn, userID, creatorID, name, lang, desc, avatarURL, metadataStr, open, maxCount, members)
 	if err != nil {
 		l.RaiseError("error while trying to create group: %v", err.Error())
 		return 0
@@ -9001,6 +11846,10 @@ func (n *RuntimeLuaNakamaModule) groupCreate(l *lua.LState) int {
 	groupTable.RawSetString("update_time", lua.LNumber(group.UpdateTime.Seconds))
 
 	l.Push(groupTable)
+	if membersTable != nil {
+		l.Push(lua.LNumber(membersAdded))
+		return 2
+	}
 	return 1
 }
 
@@ -9016,6 +11865,8 @@ func (n *RuntimeLuaNakamaModule) groupCreate(l *lua.LState) int {
 // @param open(type=bool, optional=true) Whether the group is for anyone to join or not.
 // @param metadata(type=table, optional=true) Custom information to store for this group. Use nil if field is not being updated.
 // @param maxCount(type=number, optional=true) Maximum number of members to have in the group. Use 0, nil/null if field is not being updated.
+// @param expectedVersion(type=number, optional=true) Only apply the update if the group's current version matches, as returned by group_metadata_get or a previous update. A stale version raises an error that includes the current version. Use nil to skip the check.
+// @return version(number) The group's new version.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) groupUpdate(l *lua.LState) int {
 	groupID, err := uuid.FromString(l.CheckString(1))
@@ -9085,12 +11936,53 @@ func (n *RuntimeLuaNakamaModule) groupUpdate(l *lua.LState) int {
 
 	maxCount := l.OptInt(10, 0)
 
-	if err = UpdateGroup(l.Context(), n.logger, n.db, groupID, userID, creatorID, name, lang, desc, avatarURL, metadata, open, maxCount); err != nil {
+	expectedVersion := int64(-1)
+	if l.Get(11) != lua.LNil {
+		expectedVersion = l.CheckInt64(11)
+		if expectedVersion < 0 {
+			l.ArgError(11, "expects expected version to be 0 or greater")
+			return 0
+		}
+	}
+
+	version, err := UpdateGroupWithVersion(l.Context(), n.logger, n.db, groupID, userID, creatorID, name, lang, desc, avatarURL, metadata, open, maxCount, expectedVersion)
+	if err != nil {
 		l.RaiseError("error while trying to update group: %v", err.Error())
 		return 0
 	}
 
-	return 0
+	l.Push(lua.LNumber(version))
+	return 1
+}
+
+// @group groups
+// @summary Read a group's metadata together with its current version, to pass as the expected version to group_update.
+// @param groupId(type=string) The ID of the group.
+// @return metadata(table) The group metadata.
+// @return version(number) The group's current version.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) groupMetadataGet(l *lua.LState) int {
+	groupID, err := uuid.FromString(l.CheckString(1))
+	if err != nil {
+		l.ArgError(1, "expects group ID to be a valid identifier")
+		return 0
+	}
+
+	metadata, version, err := GetGroupMetadataVersion(l.Context(), n.logger, n.db, groupID)
+	if err != nil {
+		l.RaiseError("error while trying to read group metadata: %v", err.Error())
+		return 0
+	}
+
+	metadataMap := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(metadata), &metadataMap); err != nil {
+		l.RaiseError("failed to convert group metadata to json: %s", err.Error())
+		return 0
+	}
+
+	l.Push(RuntimeLuaConvertMap(l, metadataMap))
+	l.Push(lua.LNumber(version))
+	return 2
 }
 
 // @group groups
@@ -9104,7 +11996,7 @@ func (n *RuntimeLuaNakamaModule) groupDelete(l *lua.LState) int {
 		return 0
 	}
 
-	if err = DeleteGroup(l.Context(), n.logger, n.db, n.tracker, groupID, uuid.Nil); err != nil {
+	if err = DeleteGroup(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, groupID, uuid.Nil); err != nil {
 		l.RaiseError("error while trying to delete group: %v", err.Error())
 		return 0
 	}
@@ -9137,7 +12029,7 @@ func (n *RuntimeLuaNakamaModule) groupUserJoin(l *lua.LState) int {
 		return 0
 	}
 
-	if err := JoinGroup(l.Context(), n.logger, n.db, n.tracker, n.router, groupID, userID, username); err != nil {
+	if err := JoinGroup(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.notificationPushFn, groupID, userID, username); err != nil {
 		l.RaiseError("error while trying to join a group: %v", err.Error())
 		return 0
 	}
@@ -9169,8 +12061,9 @@ func (n *RuntimeLuaNakamaModule) groupUserLeave(l *lua.LState) int {
 		return 0
 	}
 
-	if err := LeaveGroup(l.Context(), n.logger, n.db, n.tracker, n.router, n.streamManager, groupID, userID, username); err != nil {
+	if err := LeaveGroup(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, groupID, userID, username); err != nil {
 		l.RaiseError("error while trying to leave a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9232,8 +12125,9 @@ func (n *RuntimeLuaNakamaModule) groupUsersAdd(l *lua.LState) int {
 		}
 	}
 
-	if err := AddGroupUsers(l.Context(), n.logger, n.db, n.tracker, n.router, callerID, groupID, userIDs); err != nil {
+	if err := AddGroupUsers(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.notificationPushFn, callerID, groupID, userIDs); err != nil {
 		l.RaiseError("error while trying to add users into a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9295,8 +12189,9 @@ func (n *RuntimeLuaNakamaModule) groupUsersBan(l *lua.LState) int {
 		}
 	}
 
-	if err := BanGroupUsers(l.Context(), n.logger, n.db, n.tracker, n.router, n.streamManager, callerID, groupID, userIDs); err != nil {
+	if err := BanGroupUsers(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, callerID, groupID, userIDs); err != nil {
 		l.RaiseError("error while trying to add users into a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9358,8 +12253,9 @@ func (n *RuntimeLuaNakamaModule) groupUsersPromote(l *lua.LState) int {
 		}
 	}
 
-	if err := PromoteGroupUsers(l.Context(), n.logger, n.db, n.router, callerID, groupID, userIDs); err != nil {
+	if err := PromoteGroupUsers(l.Context(), n.logger, n.db, n.groupEventFn, n.router, callerID, groupID, userIDs); err != nil {
 		l.RaiseError("error while trying to promote users in a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9421,8 +12317,9 @@ func (n *RuntimeLuaNakamaModule) groupUsersDemote(l *lua.LState) int {
 		}
 	}
 
-	if err := DemoteGroupUsers(l.Context(), n.logger, n.db, n.router, callerID, groupID, userIDs); err != nil {
+	if err := DemoteGroupUsers(l.Context(), n.logger, n.db, n.groupEventFn, n.router, callerID, groupID, userIDs); err != nil {
 		l.RaiseError("error while trying to demote users in a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9484,8 +12381,9 @@ func (n *RuntimeLuaNakamaModule) groupUsersKick(l *lua.LState) int {
 		}
 	}
 
-	if err := KickGroupUsers(l.Context(), n.logger, n.db, n.tracker, n.router, n.streamManager, callerID, groupID, userIDs, false); err != nil {
+	if err := KickGroupUsers(l.Context(), n.logger, n.db, n.groupEventFn, n.tracker, n.router, n.streamManager, callerID, groupID, userIDs, false); err != nil {
 		l.RaiseError("error while trying to kick users from a group: %v", err.Error())
+		return 0
 	}
 	return 0
 }
@@ -9550,7 +12448,8 @@ func (n *RuntimeLuaNakamaModule) groupsList(l *lua.LState) int {
 // @group groups
 // @summary Fetch one or more groups randomly.
 // @param count(type=int) The number of groups to fetch.
-// @return users(table) A list of group record objects.
+// @param filter(type=table, optional=true) A table with an optional `open` (bool) to only return open or closed groups, and an optional `max_fullness` (number) between 0 and 1 to only return groups whose member count is below that fraction of their max count. Use 1 to exclude full groups.
+// @return users(table) A list of group record objects. Empty if no groups match the filter.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) groupsGetRandom(l *lua.LState) int {
 	count := l.OptInt(1, 0)
@@ -9560,7 +12459,34 @@ func (n *RuntimeLuaNakamaModule) groupsGetRandom(l *lua.LState) int {
 		return 0
 	}
 
-	groups, err := GetRandomGroups(l.Context(), n.logger, n.db, count)
+	var open *bool
+	var maxFullness float64
+	if filterTable := l.OptTable(2, nil); filterTable != nil {
+		switch v := filterTable.RawGetString("open").(type) {
+		case *lua.LNilType:
+		case lua.LBool:
+			openValue := bool(v)
+			open = &openValue
+		default:
+			l.ArgError(2, "expects filter open to be a boolean")
+			return 0
+		}
+
+		switch v := filterTable.RawGetString("max_fullness").(type) {
+		case *lua.LNilType:
+		case lua.LNumber:
+			if v <= 0 || v > 1 {
+				l.ArgError(2, "expects filter max_fullness to be greater than 0 and at most 1")
+				return 0
+			}
+			maxFullness = float64(v)
+		default:
+			l.ArgError(2, "expects filter max_fullness to be a number")
+			return 0
+		}
+	}
+
+	groups, err := GetRandomGroupsFiltered(l.Context(), n.logger, n.db, count, open, maxFullness)
 	if err != nil {
 		l.RaiseError("failed to get groups: %s", err.Error())
 		return 0
@@ -9585,7 +12511,7 @@ func (n *RuntimeLuaNakamaModule) groupsGetRandom(l *lua.LState) int {
 // @summary List all members, admins and superadmins which belong to a group. This also list incoming join requests.
 // @param groupId(type=string) The ID of the group to list members for.
 // @param limit(type=int, optional=true, default=100) The maximum number of entries in the listing.
-// @param state(type=int, optional=true, default=null) The state of the user within the group. If unspecified this returns users in all states.
+// @param state(type=int, optional=true, default=null) The state of the user within the group, or a table of states such as {0, 1} for superadmins and admins. If unspecified this returns users in all states.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
 // @return groupUsers(table) The user information for members, admins and superadmins for the group. Also users who sent a join request.
 // @return error(error) An optional error value if an error occurred.
@@ -9602,19 +12528,48 @@ func (n *RuntimeLuaNakamaModule) groupUsersList(l *lua.LState) int {
 		return 0
 	}
 
-	state := l.OptInt(3, -1)
-	var stateWrapper *wrapperspb.Int32Value
-	if state != -1 {
-		if state < 0 || state > 4 {
-			l.ArgError(3, "expects state to be 0-4")
+	var states []int32
+	switch v := l.Get(3); v.Type() {
+	case lua.LTNil:
+	case lua.LTNumber:
+		state := int(lua.LVAsNumber(v))
+		if state != -1 {
+			if state < 0 || state > 4 {
+				l.ArgError(3, "expects state to be 0-4")
+				return 0
+			}
+			states = []int32{int32(state)}
+		}
+	case lua.LTTable:
+		conversionError := false
+		v.(*lua.LTable).ForEach(func(_ lua.LValue, sv lua.LValue) {
+			if conversionError {
+				return
+			}
+			if sv.Type() != lua.LTNumber {
+				conversionError = true
+				l.ArgError(3, "expects each state to be 0-4")
+				return
+			}
+			state := int(lua.LVAsNumber(sv))
+			if state < 0 || state > 4 {
+				conversionError = true
+				l.ArgError(3, "expects each state to be 0-4")
+				return
+			}
+			states = append(states, int32(state))
+		})
+		if conversionError {
 			return 0
 		}
-		stateWrapper = &wrapperspb.Int32Value{Value: int32(state)}
+	default:
+		l.ArgError(3, "expects state to be a number or a table of numbers")
+		return 0
 	}
 
 	cursor := l.OptString(4, "")
 
-	res, err := ListGroupUsers(l.Context(), n.logger, n.db, n.statusRegistry, groupID, limit, stateWrapper, cursor)
+	res, err := ListGroupUsersByStates(l.Context(), n.logger, n.db, n.statusRegistry, groupID, limit, states, cursor)
 	if err != nil {
 		l.RaiseError("error while trying to list users in a group: %v", err.Error())
 		return 0
@@ -9769,6 +12724,7 @@ func (n *RuntimeLuaNakamaModule) userGroupsList(l *lua.LState) int {
 // @param location(type=string, optional=true) Location to be updated. Use null if it is not being updated.
 // @param language(type=string, optional=true) Lang tag to be updated. Use null if it is not being updated.
 // @param avatarUrl(type=string, optional=true) User's avatar URL. Use null if it is not being updated.
+// @param merge(type=bool, optional=true, default=false) Deep merge the metadata into the existing metadata in a single transaction instead of replacing it. Nested objects are merged key by key, other values replace existing ones (last writer wins).
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) accountUpdateId(l *lua.LState) int {
 	userID, err := uuid.FromString(l.CheckString(1))
@@ -9821,15 +12777,18 @@ func (n *RuntimeLuaNakamaModule) accountUpdateId(l *lua.LState) int {
 		avatar = &wrapperspb.StringValue{Value: l.OptString(8, "")}
 	}
 
+	merge := l.OptBool(9, false)
+
 	if err = UpdateAccounts(l.Context(), n.logger, n.db, []*accountUpdate{{
-		userID:      userID,
-		username:    username,
-		displayName: displayName,
-		timezone:    timezone,
-		location:    location,
-		langTag:     lang,
-		avatarURL:   avatar,
-		metadata:    metadata,
+		userID:        userID,
+		username:      username,
+		displayName:   displayName,
+		timezone:      timezone,
+		location:      location,
+		langTag:       lang,
+		avatarURL:     avatar,
+		metadata:      metadata,
+		mergeMetadata: merge,
 	}}); err != nil {
 		l.RaiseError("error while trying to update user: %v", err.Error())
 	}
@@ -9840,7 +12799,9 @@ func (n *RuntimeLuaNakamaModule) accountUpdateId(l *lua.LState) int {
 // @group accounts
 // @summary Delete an account by user ID.
 // @param userId(type=string) User ID for the account to be deleted. Must be valid UUID.
-// @param recorded(type=bool, optional=true, default=false) Whether to record this deletion in the database. By default this is set to false.
+// @param recorded(type=bool, optional=true, default=false) Whether to record this deletion in the database. By default this is set to false. Ignored when anonymizing.
+// @param anonymize(type=bool, optional=true, default=false) Scrub personal data instead of deleting the account. The username is replaced with a tombstone value and the display name, avatar, location, timezone, email, password, social and custom IDs, device IDs and metadata are cleared, while the wallet, leaderboard records and group memberships are kept.
+// @return result(table) When anonymizing, a table with the tombstone 'username', the list of scrubbed 'fields', and the number of 'devices', 'leaderboard_records' and 'messages' updated. Nothing is returned when deleting.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) accountDeleteId(l *lua.LState) int {
 	userID, err := uuid.FromString(l.CheckString(1))
@@ -9851,6 +12812,28 @@ func (n *RuntimeLuaNakamaModule) accountDeleteId(l *lua.LState) int {
 
 	recorded := l.OptBool(2, false)
 
+	if l.OptBool(3, false) {
+		result, err := AnonymizeAccount(l.Context(), n.logger, n.db, n.config, n.sessionRegistry, n.sessionCache, n.tracker, userID)
+		if err != nil {
+			l.RaiseError("error while trying to anonymize account: %v", err.Error())
+			return 0
+		}
+
+		fieldsTable := l.CreateTable(len(result.Fields), 0)
+		for i, field := range result.Fields {
+			fieldsTable.RawSetInt(i+1, lua.LString(field))
+		}
+
+		resultTable := l.CreateTable(0, 5)
+		resultTable.RawSetString("username", lua.LString(result.Username))
+		resultTable.RawSetString("fields", fieldsTable)
+		resultTable.RawSetString("devices", lua.LNumber(result.Devices))
+		resultTable.RawSetString("leaderboard_records", lua.LNumber(result.LeaderboardRecords))
+		resultTable.RawSetString("messages", lua.LNumber(result.Messages))
+		l.Push(resultTable)
+		return 1
+	}
+
 	if err := DeleteAccount(l.Context(), n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, userID, recorded); err != nil {
 		l.RaiseError("error while trying to delete account: %v", err.Error())
 	}
@@ -9861,6 +12844,7 @@ func (n *RuntimeLuaNakamaModule) accountDeleteId(l *lua.LState) int {
 // @group accounts
 // @summary Export account information for a specified user ID.
 // @param userId(type=string) User ID for the account to be exported. Must be valid UUID.
+// @param options(type=table, optional=true) Set 'wallet', 'ledger', 'storage' or 'messages' to false to leave those sections out of the export, and 'redact' to true to strip the email, custom ID, device IDs and social provider IDs. By default the full export is produced.
 // @return export(string) Account information for the provided user ID, in JSON format.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) accountExportId(l *lua.LState) int {
@@ -9870,7 +12854,41 @@ func (n *RuntimeLuaNakamaModule) accountExportId(l *lua.LState) int {
 		return 0
 	}
 
-	export, err := ExportAccount(l.Context(), n.logger, n.db, userID)
+	var opts ExportAccountOptions
+	if options := l.OptTable(2, nil); options != nil {
+		var conversionError bool
+		options.ForEach(func(k lua.LValue, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			b, ok := v.(lua.LBool)
+			if !ok {
+				conversionError = true
+				l.ArgError(2, fmt.Sprintf("expects %s option to be a boolean", k.String()))
+				return
+			}
+			switch k.String() {
+			case "wallet":
+				opts.ExcludeWallet = !bool(b)
+			case "ledger":
+				opts.ExcludeLedger = !bool(b)
+			case "storage":
+				opts.ExcludeStorage = !bool(b)
+			case "messages":
+				opts.ExcludeMessages = !bool(b)
+			case "redact":
+				opts.Redact = bool(b)
+			default:
+				conversionError = true
+				l.ArgError(2, fmt.Sprintf("unrecognised option %s", k.String()))
+			}
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	export, err := ExportAccountWithOptions(l.Context(), n.logger, n.db, userID, opts)
 	if err != nil {
 		l.RaiseError("error exporting account: %v", err.Error())
 		return 0
@@ -9886,13 +12904,92 @@ func (n *RuntimeLuaNakamaModule) accountExportId(l *lua.LState) int {
 	return 1
 }
 
+// @group accounts
+// @summary Merge a source account into a target account. The wallet, storage objects, friends, group memberships and optionally leaderboard records are moved in a single transaction, then the source account is disabled or deleted. Where the target already has a conflicting storage object, friend relationship, group membership or leaderboard record the target's is kept, though a group role is raised to the source's if higher. Wallet balances are added together.
+// @param sourceUserId(type=string) User ID for the account to merge from. Must be valid UUID.
+// @param targetUserId(type=string) User ID for the account to merge into. Must be valid UUID.
+// @param options(type=table, optional=true) Set 'leaderboard_records' to true to also move leaderboard and tournament records, and 'delete_source' to true to delete the source account instead of disabling it.
+// @return result(table) A table with the target's updated 'wallet', and the number of 'storage_objects', 'friends', 'groups' and 'leaderboard_records' moved alongside the number of each left behind due to conflicts, as 'storage_conflicts', 'friend_conflicts', 'group_conflicts' and 'leaderboard_record_conflicts'. 'groups_skipped' lists the IDs of groups left with the source because they are full, and 'source_deleted' is false if the source could not be deleted and was left disabled.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) accountMerge(l *lua.LState) int {
+	sourceID, err := uuid.FromString(l.CheckString(1))
+	if err != nil {
+		l.ArgError(1, "expects source user ID to be a valid identifier")
+		return 0
+	}
+
+	targetID, err := uuid.FromString(l.CheckString(2))
+	if err != nil {
+		l.ArgError(2, "expects target user ID to be a valid identifier")
+		return 0
+	}
+
+	var opts AccountMergeOptions
+	if options := l.OptTable(3, nil); options != nil {
+		var conversionError bool
+		options.ForEach(func(k lua.LValue, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			b, ok := v.(lua.LBool)
+			if !ok {
+				conversionError = true
+				l.ArgError(3, fmt.Sprintf("expects %s option to be a boolean", k.String()))
+				return
+			}
+			switch k.String() {
+			case "leaderboard_records":
+				opts.LeaderboardRecords = bool(b)
+			case "delete_source":
+				opts.DeleteSource = bool(b)
+			default:
+				conversionError = true
+				l.ArgError(3, fmt.Sprintf("unrecognised option %s", k.String()))
+			}
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	result, err := MergeAccounts(l.Context(), n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, n.storageIndex, sourceID, targetID, opts)
+	if err != nil {
+		l.RaiseError("error while trying to merge accounts: %v", err.Error())
+		return 0
+	}
+
+	resultTable := l.CreateTable(0, 11)
+	if result.Wallet != nil {
+		resultTable.RawSetString("wallet", RuntimeLuaConvertMapInt64(l, result.Wallet))
+	} else {
+		resultTable.RawSetString("wallet", l.CreateTable(0, 0))
+	}
+	resultTable.RawSetString("storage_objects", lua.LNumber(result.StorageObjects))
+	resultTable.RawSetString("storage_conflicts", lua.LNumber(result.StorageConflicts))
+	resultTable.RawSetString("friends", lua.LNumber(result.Friends))
+	resultTable.RawSetString("friend_conflicts", lua.LNumber(result.FriendConflicts))
+	resultTable.RawSetString("groups", lua.LNumber(result.Groups))
+	resultTable.RawSetString("group_conflicts", lua.LNumber(result.GroupConflicts))
+	groupsSkipped := l.CreateTable(len(result.GroupsSkipped), 0)
+	for i, groupID := range result.GroupsSkipped {
+		groupsSkipped.RawSetInt(i+1, lua.LString(groupID))
+	}
+	resultTable.RawSetString("groups_skipped", groupsSkipped)
+	resultTable.RawSetString("leaderboard_records", lua.LNumber(result.LeaderboardRecords))
+	resultTable.RawSetString("leaderboard_record_conflicts", lua.LNumber(result.LeaderboardRecordConflicts))
+	resultTable.RawSetString("source_deleted", lua.LBool(result.SourceDeleted))
+	l.Push(resultTable)
+	return 1
+}
+
 // @group friends
 // @summary List all friends, invites, invited, and blocked which belong to a user.
 // @param userId(type=string) The ID of the user whose friends, invites, invited, and blocked you want to list.
 // @param limit(type=number, optional=true) The number of friends to retrieve in this page of results. No more than 100 limit allowed per result.
 // @param state(type=number, optional=true) The state of the friendship with the user. If unspecified this returns friends in all states for the user.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
-// @return friends(table) The user information for users that are friends of the current user.
+// @param mutualOnly(type=bool, optional=true, default=false) Only return confirmed friends that also have the user as a friend. Implies a state of 0.
+// @return friends(table) The user information for users that are friends of the current user. Each entry's 'metadata' is the metadata on the user's edge. For a pending received invite, any invite metadata the sender attached is included under its 'invite' key.
 // @return cursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any). Will be set to "" or nil when fetching last available page.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) friendsList(l *lua.LState) int {
@@ -9920,7 +13017,13 @@ func (n *RuntimeLuaNakamaModule) friendsList(l *lua.LState) int {
 
 	cursor := l.OptString(4, "")
 
-	friends, err := ListFriends(l.Context(), n.logger, n.db, n.statusRegistry, userID, limit, stateWrapper, cursor)
+	mutualOnly := l.OptBool(5, false)
+	if mutualOnly && stateWrapper != nil && stateWrapper.Value != 0 {
+		l.ArgError(5, "expects state to be unset or 0 when listing mutual friends only")
+		return 0
+	}
+
+	friends, err := ListFriendsFiltered(l.Context(), n.logger, n.db, n.statusRegistry, userID, limit, stateWrapper, mutualOnly, cursor)
 	if err != nil {
 		l.RaiseError("error while trying to list friends for a user: %v", err.Error())
 		return 0
@@ -9967,7 +13070,9 @@ func (n *RuntimeLuaNakamaModule) friendsList(l *lua.LState) int {
 // @param userId(type=string) The ID of the user whose friends, invites, invited, and blocked you want to list.
 // @param limit(type=number, optional=true) The number of friends to retrieve in this page of results. No more than 100 limit allowed per result.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
-// @return friendsOfFriends(table) The user information for users that are friends of friends of the current user.
+// @param sortByMutual(type=bool, optional=true, default=false) Return a single page of users not yet related to the user, ranked by the number of mutual friends, most connected first. Cannot be combined with a cursor.
+// @param traversalBudget(type=number, optional=true, default=10000) When sorting by mutual friends, only the friends of this many of the user's most recently added friends are inspected, between 1-100000.
+// @return friendsOfFriends(table) The user information for users that are friends of friends of the current user, each with a "mutual_count" of friends shared with the user.
 // @return cursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any). Will be set to "" or nil when fetching last available page.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) friendsOfFriendsList(l *lua.LState) int {
@@ -9985,7 +13090,31 @@ func (n *RuntimeLuaNakamaModule) friendsOfFriendsList(l *lua.LState) int {
 
 	cursor := l.OptString(3, "")
 
-	friends, err := ListFriendsOfFriends(l.Context(), n.logger, n.db, n.statusRegistry, userID, limit, cursor)
+	sortByMutual := l.OptBool(4, false)
+	traversalBudget := l.OptInt(5, 10000)
+	if traversalBudget < 1 || traversalBudget > 100000 {
+		l.ArgError(5, "expects traversal budget to be 1-100000")
+		return 0
+	}
+
+	var friends *api.FriendsOfFriendsList
+	var mutualCounts map[string]int
+	if sortByMutual {
+		if cursor != "" {
+			l.ArgError(3, "expects no cursor when sorting by mutual friend count")
+			return 0
+		}
+		friends, mutualCounts, err = ListFriendsOfFriendsByMutualCount(l.Context(), n.logger, n.db, n.statusRegistry, userID, limit, traversalBudget)
+	} else {
+		friends, err = ListFriendsOfFriends(l.Context(), n.logger, n.db, n.statusRegistry, userID, limit, cursor)
+		if err == nil {
+			candidateIDs := make([]string, 0, len(friends.FriendsOfFriends))
+			for _, f := range friends.FriendsOfFriends {
+				candidateIDs = append(candidateIDs, f.User.Id)
+			}
+			mutualCounts, err = FriendsOfFriendsMutualCounts(l.Context(), n.logger, n.db, userID, candidateIDs)
+		}
+	}
 	if err != nil {
 		l.RaiseError("error while trying to list friends of friends for a user: %v", err.Error())
 		return 0
@@ -10001,9 +13130,10 @@ func (n *RuntimeLuaNakamaModule) friendsOfFriendsList(l *lua.LState) int {
 			return 0
 		}
 
-		ft := l.CreateTable(0, 2)
+		ft := l.CreateTable(0, 3)
 		ft.RawSetString("referrer", lua.LString(f.Referrer))
 		ft.RawSetString("user", fut)
+		ft.RawSetString("mutual_count", lua.LNumber(mutualCounts[u.Id]))
 
 		userFriendsOfFriends.RawSetInt(i+1, ft)
 	}
@@ -10024,6 +13154,9 @@ func (n *RuntimeLuaNakamaModule) friendsOfFriendsList(l *lua.LState) int {
 // @param username(type=string) The name of the user to whom you want to add friends.
 // @param ids(type=table) The IDs of the users you want to add as friends.
 // @param usernames(type=table) The usernames of the users you want to add as friends.
+// @param metadata(type=table, optional=true) Metadata to store on the user's own friend edges.
+// @param inviteMetadata(type=table, optional=true) A table of target user IDs to metadata, such as an invite message, stored on each new invite. While the invite is pending the target sees it under the 'invite' key of the friend metadata when listing their friends. It is cleared when the invite is accepted.
+// @return states(table) A table of target user IDs to the resulting friend state: 0 friends, 1 invite sent, or 3 blocked. Targets with no resulting edge, such as users who blocked the caller, are omitted.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) friendsAdd(l *lua.LState) int {
 	userID, err := uuid.FromString(l.CheckString(1))
@@ -10090,7 +13223,8 @@ func (n *RuntimeLuaNakamaModule) friendsAdd(l *lua.LState) int {
 	}
 
 	if len(userIDs) == 0 && len(usernames) == 0 {
-		return 0
+		l.Push(l.CreateTable(0, 0))
+		return 1
 	}
 
 	fetchIDs, err := fetchUserID(l.Context(), n.db, usernames)
@@ -10127,13 +13261,52 @@ func (n *RuntimeLuaNakamaModule) friendsAdd(l *lua.LState) int {
 		metadataStr = string(bytes)
 	}
 
-	err = AddFriends(l.Context(), n.logger, n.db, n.tracker, n.router, userID, username, allIDs, metadataStr)
+	// Parse per-target invite metadata, optional.
+	var inviteMetadata map[string]string
+	if inviteMetadataTable := l.OptTable(6, nil); inviteMetadataTable != nil {
+		inviteMetadata = make(map[string]string, inviteMetadataTable.Len())
+		var conversionError bool
+		inviteMetadataTable.ForEach(func(k, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			if _, err := uuid.FromString(k.String()); k.Type() != lua.LTString || err != nil {
+				conversionError = true
+				l.ArgError(6, "expects invite metadata to be keyed by valid user IDs")
+				return
+			}
+			vt, ok := v.(*lua.LTable)
+			if !ok {
+				conversionError = true
+				l.ArgError(6, "expects invite metadata values to be tables")
+				return
+			}
+			bytes, err := json.Marshal(RuntimeLuaConvertLuaTable(vt))
+			if err != nil {
+				conversionError = true
+				l.RaiseError("error marshalling invite metadata: %s", err.Error())
+				return
+			}
+			inviteMetadata[k.String()] = string(bytes)
+		})
+		if conversionError {
+			return 0
+		}
+	}
+
+	states, err := AddFriendsWithInvites(l.Context(), n.logger, n.db, n.tracker, n.router, n.notificationPushFn, userID, username, allIDs, metadataStr, inviteMetadata)
 	if err != nil {
 		l.RaiseError("error adding friends: %s", err.Error())
 		return 0
 	}
 
-	return 0
+	statesTable := l.CreateTable(0, len(states))
+	for id, state := range states {
+		statesTable.RawSetString(id, lua.LNumber(state))
+	}
+
+	l.Push(statesTable)
+	return 1
 }
 
 // @group friends
@@ -10403,6 +13576,7 @@ func (n *RuntimeLuaNakamaModule) fileRead(l *lua.LState) int {
 // @param senderId(type=string, optional=true) The UUID for the sender of this message. If left empty, it will be assumed that it is a system message.
 // @param senderUsername(type=string, optional=true) The username of the user to send this message as. If left empty, it will be assumed that it is a system message.
 // @param persist(type=bool, optional=true, default=true) Whether to record this message in the channel history.
+// @param dedupeToken(type=string, optional=true) A token identifying this send. Repeated sends with the same token from the same sender to the same channel within a short window return the original message instead of sending again. Requires persist to be true.
 // @return ack(table) Message sent ack containing the following variables: 'channelId', 'messageId', 'code', 'username', 'createTime', 'updateTime', and 'persistent'.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) channelMessageSend(l *lua.LState) int {
@@ -10439,13 +13613,25 @@ func (n *RuntimeLuaNakamaModule) channelMessageSend(l *lua.LState) int {
 
 	persist := l.OptBool(5, false)
 
+	dedupeToken := l.OptString(6, "")
+	if dedupeToken != "" {
+		if !persist {
+			l.ArgError(6, "expects persist to be true when a dedupe token is set")
+			return 0
+		}
+		if len(dedupeToken) > 128 {
+			l.ArgError(6, "expects dedupe token to be at most 128 characters")
+			return 0
+		}
+	}
+
 	channelIdToStreamResult, err := ChannelIdToStream(channelId)
 	if err != nil {
 		l.RaiseError("error converting channel identifier to stream: %s", err.Error())
 		return 0
 	}
 
-	ack, err := ChannelMessageSend(l.Context(), n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, contentStr, senderID, senderUsername, persist)
+	ack, err := ChannelMessageSendDedupe(l.Context(), n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, contentStr, senderID, senderUsername, persist, dedupeToken)
 	if err != nil {
 		l.RaiseError("failed to send channel message: %v", err.Error())
 		return 0
@@ -10603,6 +13789,8 @@ func (n *RuntimeLuaNakamaModule) channelMessageRemove(l *lua.LState) int {
 // @param limit(type=number, optional=true, default=100) The number of messages to return per page.
 // @param forward(type=bool, optional=true, default=true) Whether to list messages from oldest to newest, or newest to oldest.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param since(type=number, optional=true, default=0) Only list messages created at or after this time, in seconds since epoch. 0 means no lower bound.
+// @param until(type=number, optional=true, default=0) Only list messages created before this time, in seconds since epoch. 0 means no upper bound. Cursors are only valid with the same since and until values.
 // @return messages(table) Messages from the specified channel.
 // @return nextCursor(string) Cursor for the next page of messages, if any. Will be set to "" or nil when fetching last available page.
 // @return prevCursor(string) Cursor for the previous page of messages, if any.
@@ -10620,13 +13808,28 @@ func (n *RuntimeLuaNakamaModule) channelMessagesList(l *lua.LState) int {
 
 	cursor := l.OptString(4, "")
 
+	since := l.OptInt64(5, 0)
+	if since < 0 {
+		l.ArgError(5, "since must be >= 0")
+		return 0
+	}
+	until := l.OptInt64(6, 0)
+	if until < 0 {
+		l.ArgError(6, "until must be >= 0")
+		return 0
+	}
+	if since != 0 && until != 0 && until <= since {
+		l.ArgError(6, "until must be greater than since")
+		return 0
+	}
+
 	channelIdToStreamResult, err := ChannelIdToStream(channelId)
 	if err != nil {
 		l.RaiseError("error converting leaderboard records: %s", err.Error())
 		return 0
 	}
 
-	list, err := ChannelMessagesList(l.Context(), n.logger, n.db, uuid.Nil, channelIdToStreamResult.Stream, channelId, limit, forward, cursor)
+	list, err := ChannelMessagesListRange(l.Context(), n.logger, n.db, uuid.Nil, channelIdToStreamResult.Stream, channelId, limit, forward, since, until, cursor)
 	if err != nil {
 		l.RaiseError("failed to list channel messages: %v", err.Error())
 		return 0
@@ -10690,6 +13893,10 @@ func (n *RuntimeLuaNakamaModule) channelIdBuild(l *lua.LState) int {
 	}
 
 	target := l.CheckString(2)
+	if target == "" {
+		l.ArgError(2, "expects target to be a non-empty string")
+		return 0
+	}
 
 	chanType := l.CheckInt(3)
 	if chanType < 1 || chanType > 3 {
@@ -10700,10 +13907,10 @@ func (n *RuntimeLuaNakamaModule) channelIdBuild(l *lua.LState) int {
 	channelId, _, err := BuildChannelId(l.Context(), n.logger, n.db, suid, target, rtapi.ChannelJoin_Type(chanType))
 	if err != nil {
 		if errors.Is(err, runtime.ErrInvalidChannelTarget) {
-			l.ArgError(1, err.Error())
+			l.ArgError(2, err.Error())
 			return 0
 		} else if errors.Is(err, runtime.ErrInvalidChannelType) {
-			l.ArgError(2, err.Error())
+			l.ArgError(3, err.Error())
 			return 0
 		}
 		l.RaiseError("error building channel identifier: %s", err.Error())
@@ -10714,6 +13921,40 @@ func (n *RuntimeLuaNakamaModule) channelIdBuild(l *lua.LState) int {
 	return 1
 }
 
+// @group chat
+// @summary Decode a channel identifier into its channel type and components. The inverse of channel_id_build.
+// @param channelId(type=string) The channel identifier to decode.
+// @return channel(table) A table with 'type', either Room (1), Direct (2), or Group (3), and 'target' for rooms and groups: the room name or group ID. Group channels also set 'group_id', and direct message channels set the two participants as 'user_id_one' and 'user_id_two'.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelIdParse(l *lua.LState) int {
+	channelId := l.CheckString(1)
+
+	result, err := ChannelIdToStream(channelId)
+	if err != nil {
+		l.ArgError(1, err.Error())
+		return 0
+	}
+	stream := result.Stream
+
+	channel := l.CreateTable(0, 5)
+	switch stream.Mode {
+	case StreamModeChannel:
+		channel.RawSetString("type", lua.LNumber(rtapi.ChannelJoin_ROOM))
+		channel.RawSetString("target", lua.LString(stream.Label))
+	case StreamModeGroup:
+		channel.RawSetString("type", lua.LNumber(rtapi.ChannelJoin_GROUP))
+		channel.RawSetString("target", lua.LString(stream.Subject.String()))
+		channel.RawSetString("group_id", lua.LString(stream.Subject.String()))
+	case StreamModeDM:
+		channel.RawSetString("type", lua.LNumber(rtapi.ChannelJoin_DIRECT_MESSAGE))
+		channel.RawSetString("user_id_one", lua.LString(stream.Subject.String()))
+		channel.RawSetString("user_id_two", lua.LString(stream.Subcontext.String()))
+	}
+
+	l.Push(channel)
+	return 1
+}
+
 // @group storage
 // @summary List storage index entries
 // @param indexName(type=string) Name of the index to list entries from.
@@ -10721,7 +13962,8 @@ func (n *RuntimeLuaNakamaModule) channelIdBuild(l *lua.LState) int {
 // @param limit(type=int) Maximum number of results to be returned.
 // @param order(type=[]string, optional=true) The storage object fields to sort the query results by. The prefix '-' before a field name indicates descending order. All specified fields must be indexed and sortable.
 // @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permission checks are bypassed.
-// @return objects(table) A list of storage objects.
+// @param cursor(type=string, optional=true) A cursor to fetch the next page of results.
+// @return objects(table) A list of storage objects in the same shape as returned by storage_read.
 // @return objects(string) A cursor, if there's a next page of results, nil otherwise.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
@@ -10763,29 +14005,11 @@ func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
 
 	lv := l.CreateTable(len(objectList.GetObjects()), 0)
 	for i, v := range objectList.GetObjects() {
-		vt := l.CreateTable(0, 9)
-		vt.RawSetString("key", lua.LString(v.Key))
-		vt.RawSetString("collection", lua.LString(v.Collection))
-		if v.UserId != "" {
-			vt.RawSetString("user_id", lua.LString(v.UserId))
-		} else {
-			vt.RawSetString("user_id", lua.LNil)
-		}
-		vt.RawSetString("version", lua.LString(v.Version))
-		vt.RawSetString("permission_read", lua.LNumber(v.PermissionRead))
-		vt.RawSetString("permission_write", lua.LNumber(v.PermissionWrite))
-		vt.RawSetString("create_time", lua.LNumber(v.CreateTime.Seconds))
-		vt.RawSetString("update_time", lua.LNumber(v.UpdateTime.Seconds))
-
-		valueMap := make(map[string]interface{})
-		err = json.Unmarshal([]byte(v.Value), &valueMap)
+		vt, err := storageObjectToLua(l, v)
 		if err != nil {
 			l.RaiseError("failed to convert value to json: %s", err.Error())
 			return 0
 		}
-		valueTable := RuntimeLuaConvertMap(l, valueMap)
-		vt.RawSetString("value", valueTable)
-
 		lv.RawSetInt(i+1, vt)
 	}
 	l.Push(lv)
@@ -10799,6 +14023,51 @@ func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
 	return 2
 }
 
+// @group storage
+// @summary Count storage index entries matching a query, without reading the objects.
+// @param indexName(type=string) Name of the index to count entries from.
+// @param queryString(type=string) Query to filter index entries.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permission checks are bypassed.
+// @param groupBy(type=string, optional=true) Also count matching entries per distinct value of this field, which must be a sortable field of the index. Numeric values are keyed by their decimal representation, for example "3" or "2.5". Values indexed as dates cannot be grouped.
+// @return count(number) The number of matching entries.
+// @return groups(table) A table of counts keyed by group by field value, or nil if no group by field was given.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) storageIndexCount(l *lua.LState) int {
+	idxName := l.CheckString(1)
+	queryString := l.CheckString(2)
+
+	callerID := uuid.Nil
+	callerIDStr := l.OptString(3, "")
+	if callerIDStr != "" {
+		cid, err := uuid.FromString(callerIDStr)
+		if err != nil {
+			l.ArgError(3, "expects caller ID to be empty or a valid identifier")
+			return 0
+		}
+		callerID = cid
+	}
+
+	groupBy := l.OptString(4, "")
+
+	count, groups, err := n.storageIndex.Count(l.Context(), callerID, idxName, queryString, groupBy)
+	if err != nil {
+		l.RaiseError("error in storage index count: %s", err.Error())
+		return 0
+	}
+
+	l.Push(lua.LNumber(count))
+	if groups != nil {
+		groupsTable := l.CreateTable(0, len(groups))
+		for value, groupCount := range groups {
+			groupsTable.RawSetString(value, lua.LNumber(groupCount))
+		}
+		l.Push(groupsTable)
+	} else {
+		l.Push(lua.LNil)
+	}
+	return 2
+}
+
 // @group configuration
 // @summary Get a subset of the Nakama configuration values.
 // @return config(table) A number of Nakama configuration values.
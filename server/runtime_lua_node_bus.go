@@ -0,0 +1,111 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Messages retained for runtimes that have not yet caught up. A runtime that falls further behind than this misses
+	// the oldest messages.
+	luaNodeBusMaxMessages = 1_000
+	// Maximum time a single subscriber call may run before it is cancelled.
+	luaNodeBusDeliveryTimeout = 5 * time.Second
+)
+
+type luaNodeBusMessage struct {
+	seq     uint64
+	topic   string
+	payload string
+}
+
+// RuntimeLuaNodeBus holds messages published with node_broadcast until every pooled Lua runtime on this node has
+// delivered them to its subscribers. Delivery happens asynchronously on a dedicated goroutine while runtimes are idle
+// in the pool, never on the request path. The bus is node-local, messages are not shared with other nodes in a
+// cluster.
+type RuntimeLuaNodeBus struct {
+	sync.RWMutex
+
+	seq      uint64
+	messages []*luaNodeBusMessage
+	notifyCh chan struct{}
+}
+
+func NewRuntimeLuaNodeBus() *RuntimeLuaNodeBus {
+	return &RuntimeLuaNodeBus{
+		messages: make([]*luaNodeBusMessage, 0),
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+func (b *RuntimeLuaNodeBus) Publish(topic, payload string) {
+	b.Lock()
+	b.seq++
+	b.messages = append(b.messages, &luaNodeBusMessage{seq: b.seq, topic: topic, payload: payload})
+	if len(b.messages) > luaNodeBusMaxMessages {
+		b.messages[0] = nil
+		b.messages = b.messages[1:]
+	}
+	b.Unlock()
+
+	b.Notify()
+}
+
+// Notify wakes the delivery goroutine without blocking. Repeated calls before it wakes are coalesced.
+func (b *RuntimeLuaNodeBus) Notify() {
+	select {
+	case b.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// NotifyCh receives a value whenever there may be messages to deliver.
+func (b *RuntimeLuaNodeBus) NotifyCh() <-chan struct{} {
+	return b.notifyCh
+}
+
+// Seq returns the sequence number of the latest published message.
+func (b *RuntimeLuaNodeBus) Seq() uint64 {
+	b.RLock()
+	seq := b.seq
+	b.RUnlock()
+	return seq
+}
+
+// Since returns the messages published after the given sequence number, the sequence number to resume from next
+// time, and the number of messages that were no longer retained.
+func (b *RuntimeLuaNodeBus) Since(seq uint64) ([]*luaNodeBusMessage, uint64, uint64) {
+	b.RLock()
+	defer b.RUnlock()
+
+	if seq >= b.seq || len(b.messages) == 0 {
+		return nil, b.seq, 0
+	}
+
+	var missed uint64
+	first := b.messages[0].seq
+	if seq+1 < first {
+		missed = first - seq - 1
+		seq = first - 1
+	}
+
+	start := int(seq + 1 - first)
+	messages := make([]*luaNodeBusMessage, len(b.messages)-start)
+	copy(messages, b.messages[start:])
+
+	return messages, b.seq, missed
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeLuaNodeBusSince(t *testing.T) {
+	b := NewRuntimeLuaNodeBus()
+
+	messages, seq, missed := b.Since(0)
+	assert.Empty(t, messages)
+	assert.Zero(t, seq)
+	assert.Zero(t, missed)
+
+	b.Publish("config", "a")
+	b.Publish("config", "b")
+
+	messages, seq, missed = b.Since(0)
+	assert.Len(t, messages, 2)
+	assert.EqualValues(t, 2, seq)
+	assert.Zero(t, missed)
+	assert.Equal(t, "a", messages[0].payload)
+
+	// Runtimes only receive messages newer than the last they saw.
+	messages, _, _ = b.Since(1)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "b", messages[0].payload)
+	messages, _, _ = b.Since(2)
+	assert.Empty(t, messages)
+
+	// Runtimes that fall too far behind skip the messages no longer retained.
+	for i := 0; i < luaNodeBusMaxMessages; i++ {
+		b.Publish("config", "c")
+	}
+	messages, seq, missed = b.Since(0)
+	assert.Len(t, messages, luaNodeBusMaxMessages)
+	assert.EqualValues(t, luaNodeBusMaxMessages+2, seq)
+	assert.EqualValues(t, 2, missed)
+}
@@ -16,20 +16,35 @@ package server
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
 	"github.com/heroiclabs/nakama-common/rtapi"
+	lua "github.com/heroiclabs/nakama/v3/internal/gopher-lua"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -359,6 +374,118 @@ nakama.register_rpc(test.printWorld, "helloworld")`,
 	}
 }
 
+func TestRuntimeRegisterRPCWithMaxPayloadSize(t *testing.T) {
+	modules := map[string]string{
+		"http-invoke": `
+local nakama = require("nakama")
+nakama.register_rpc(function(ctx, payload)
+	return payload
+end, "echo", 5)`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("echo")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "hello")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result != "hello" {
+		t.Fatal("Invocation failed. Return result not expected")
+	}
+
+	_, err, code := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "hello world")
+	if err != ErrRuntimeRPCPayloadTooLarge {
+		t.Fatalf("Expected payload too large error, got: %v", err)
+	}
+	if code != codes.InvalidArgument {
+		t.Fatalf("Expected invalid argument code, got: %v", code)
+	}
+}
+
+func TestRuntimeRegisterRPCWithRequireAuth(t *testing.T) {
+	modules := map[string]string{
+		"http-invoke": `
+local nakama = require("nakama")
+nakama.register_rpc(function(ctx, payload)
+	return ctx.user_id
+end, "whoami", 0, true)`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("whoami")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	_, err, code := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "")
+	if err != ErrRuntimeRPCUnauthenticated {
+		t.Fatalf("Expected unauthenticated error, got: %v", err)
+	}
+	if code != codes.Unauthenticated {
+		t.Fatalf("Expected unauthenticated code, got: %v", code)
+	}
+
+	userID := uuid.Must(uuid.NewV4()).String()
+	result, err, _ := fn(context.Background(), nil, nil, userID, "username", nil, 0, "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result != userID {
+		t.Fatal("Invocation failed. Return result not expected")
+	}
+}
+
+type rpcRecordingMetrics struct {
+	testMetrics
+	ids  []string
+	errs []bool
+}
+
+func (m *rpcRecordingMetrics) RuntimeRpc(id string, elapsed time.Duration, isErr bool) {
+	m.ids = append(m.ids, id)
+	m.errs = append(m.errs, isErr)
+}
+
+func TestRuntimeRpcWithMetrics(t *testing.T) {
+	metrics := &rpcRecordingMetrics{}
+	rpcErr := errors.New("rpc failed")
+	fn := runtimeRpcWithMetrics(metrics, "echo", func(ctx context.Context, headers, queryParams map[string][]string, userID, username string, vars map[string]string, expiry int64, sessionID, clientIP, clientPort, lang, payload string) (string, error, codes.Code) {
+		if payload == "fail" {
+			return "partial", rpcErr, codes.FailedPrecondition
+		}
+		return payload, nil, codes.OK
+	})
+
+	result, err, code := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "hello")
+	if result != "hello" || err != nil || code != codes.OK {
+		t.Fatalf("Expected successful result to pass through unchanged, got: %v, %v, %v", result, err, code)
+	}
+
+	result, err, code = fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "fail")
+	if result != "partial" || err != rpcErr || code != codes.FailedPrecondition {
+		t.Fatalf("Expected failed result to pass through unchanged, got: %v, %v, %v", result, err, code)
+	}
+
+	if len(metrics.ids) != 2 || metrics.ids[0] != "echo" || metrics.ids[1] != "echo" {
+		t.Fatalf("Expected two recorded calls for RPC ID, got: %v", metrics.ids)
+	}
+	if metrics.errs[0] || !metrics.errs[1] {
+		t.Fatalf("Expected only the second call to be recorded as an error, got: %v", metrics.errs)
+	}
+}
+
 func TestRuntimeRegisterRPCWithPayloadEndToEnd(t *testing.T) {
 	modules := map[string]string{
 		"test": `
@@ -445,6 +572,223 @@ nakama.register_rpc(test, "test")`, srv.URL),
 	}
 }
 
+func TestRuntimeHTTPRequestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if calls.Add(1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	defer srv.Close()
+
+	modules := map[string]string{
+		"test": fmt.Sprintf(`
+local nakama = require("nakama")
+function test(ctx, payload)
+	local code, headers, body, attempts = nakama.http_request("%s", payload, {}, nil, 5000, false, 5, 1)
+	return tostring(code) .. " " .. tostring(attempts)
+end
+nakama.register_rpc(test, "test")`, srv.URL),
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "GET")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "200 3" {
+		t.Fatal("Invocation failed. Return result not expected", result)
+	}
+
+	// Non-idempotent methods are not retried by default.
+	calls.Store(0)
+	result, err, _ = fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "POST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "503 1" {
+		t.Fatal("Invocation failed. Return result not expected", result)
+	}
+}
+
+func TestRuntimeHTTPRequestDefaults(t *testing.T) {
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(request.Header.Get("X-Api-Key") + " " + request.Header.Get("X-Env")))
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	otherSrv := httptest.NewServer(handler)
+	defer otherSrv.Close()
+
+	modules := map[string]string{
+		"test": fmt.Sprintf(`
+local nakama = require("nakama")
+nakama.http_request_defaults("%s/api", {timeout = 2000, headers = {["X-Api-Key"] = "module", ["X-Env"] = "prod"}})
+nakama.http_request_defaults("%s/api/v2", {headers = {["X-Api-Key"] = "v2"}})
+function test(ctx, payload)
+	local request = nakama.json_decode(payload)
+	local headers = {}
+	if request.key ~= "" then
+		headers["x-api-key"] = request.key
+	end
+	local code, headers, body = nakama.http_request(request.url, "GET", headers)
+	return body
+end
+nakama.register_rpc(test, "test")
+function test_defaults(ctx, payload)
+	nakama.http_request_defaults("%s", {})
+end
+nakama.register_rpc(test_defaults, "test_defaults")`, srv.URL, srv.URL, srv.URL),
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	for _, tc := range []struct {
+		url      string
+		key      string
+		expected string
+	}{
+		{url: srv.URL + "/api/users", expected: "module prod"},
+		// Headers passed to the call replace defaults of the same name.
+		{url: srv.URL + "/api/users", key: "call", expected: "call prod"},
+		// The longest matching base URL is used.
+		{url: srv.URL + "/api/v2/users", expected: "v2 "},
+		// Defaults are not applied outside the base URL path, or to other hosts.
+		{url: srv.URL + "/apiv2", expected: " "},
+		{url: otherSrv.URL + "/api/users", expected: " "},
+	} {
+		payload := fmt.Sprintf(`{"url":%q,"key":%q}`, tc.url, tc.key)
+		result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != tc.expected {
+			t.Fatalf("Invocation failed for %v. Return result not expected: %q", tc.url, result)
+		}
+	}
+
+	// Defaults can only be set while the module is loading.
+	fn = runtime.Rpc("test_defaults")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+	if _, err, _ = fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", ""); err == nil || !strings.Contains(err.Error(), "can only be called while the module is loading") {
+		t.Fatal("Expected error setting defaults after module load", err)
+	}
+}
+
+func TestRuntimeLuaHTTPClientProfile(t *testing.T) {
+	// Self-signed client certificate, trusted by the server for client authentication.
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientCertDER, err := x509.CreateCertificate(rand.Reader, template, template, &clientKey.PublicKey, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKeyDER, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := x509.ParseCertificate(clientCertDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCertPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCertDER}))
+	clientKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: clientKeyDER}))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte(request.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	serverCAPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	client, err := runtimeLuaHTTPClientProfile(clientCertPEM, clientKeyPEM, serverCAPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "client" {
+		t.Fatal("Expected server to see the client certificate, got:", string(body))
+	}
+
+	// Trusting the server without presenting a client certificate fails the handshake.
+	client, err = runtimeLuaHTTPClientProfile("", "", serverCAPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = client.Get(srv.URL); err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("Expected request without a client certificate to fail")
+	}
+
+	if _, err = runtimeLuaHTTPClientProfile(clientCertPEM, "", ""); err == nil {
+		t.Fatal("Expected a certificate without a key to be rejected")
+	}
+}
+
+func TestRuntimeLuaHTTPClientRegisterInitOnly(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nakama = require("nakama")
+function test(ctx, payload)
+	nakama.http_client_register("partner", {ca = payload})
+end
+nakama.register_rpc(test, "test")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+	if _, err, _ = fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", ""); err == nil || !strings.Contains(err.Error(), "can only be called while the module is loading") {
+		t.Fatal("Expected error registering a client after module load", err)
+	}
+}
+
 func TestRuntimeJson(t *testing.T) {
 	modules := map[string]string{
 		"test": `
@@ -659,6 +1003,157 @@ nakama.register_rpc(test, "test")`,
 	}
 }
 
+func TestRuntimeBcryptCompareMalformedHash(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nakama = require("nakama")
+function test(ctx, payload)
+	local match, err = nakama.bcrypt_compare(payload, "something_to_encrypt")
+	return tostring(match) .. " " .. tostring(err ~= nil)
+end
+nakama.register_rpc(test, "test")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	for payload, expected := range map[string]string{
+		"$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$aGFzaA":               "false true",
+		"not-a-bcrypt-hash-but-long-enough-to-pass-the-length-check-0123": "false true",
+	} {
+		result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Error("Return result not expected", payload, result)
+		}
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("something_else"), bcrypt.MinCost)
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "false false" {
+		t.Error("Return result not expected", result)
+	}
+}
+
+func TestRuntimeHMACHashes(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nakama = require("nakama")
+function test(ctx, payload)
+	local input = "The quick brown fox jumps over the lazy dog"
+	local sha1 = nakama.base16_encode(nakama.hmac_sha1_hash(input, "key"))
+	local sha512 = nakama.base16_encode(nakama.hmac_sha512_hash(input, "key"))
+	return sha1 .. " " .. sha512 .. " " .. tostring(nakama.hmac_equal(sha1, payload)) .. " " .. tostring(nakama.hmac_equal(sha1, "de7c"))
+end
+nakama.register_rpc(test, "test")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	sha1 := "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"
+	sha512 := "b42af09057bac1e2d41708e48a902e09b5ff7f12ab428a4fe86653c73dd248fb82f948a549f7b791a5b41915ee4d1ec3935357e4e2317250d0372afa2ebeeb3a"
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", sha1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sha1 + " " + sha512 + " true false"; result != expected {
+		t.Error("Return result not expected", result)
+	}
+}
+
+func TestRuntimeCompression(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nakama = require("nakama")
+function test(ctx, payload)
+	assert(nakama.gzip_decompress(nakama.gzip_compress(payload)) == payload)
+	assert(nakama.gzip_decompress(nakama.gzip_compress(payload, 9)) == payload)
+	assert(nakama.deflate_decompress(nakama.deflate_compress(payload)) == payload)
+	assert(#nakama.gzip_compress(payload) < #payload)
+	local ok = pcall(nakama.gzip_decompress, "not gzip")
+	assert(not ok)
+	ok = pcall(nakama.deflate_decompress, "not deflate")
+	assert(not ok)
+	assert(nakama.gzip_decompress(nakama.gzip_compress(payload), #payload) == payload)
+	ok = pcall(nakama.gzip_decompress, nakama.gzip_compress(payload), #payload - 1)
+	assert(not ok)
+	ok = pcall(nakama.deflate_decompress, nakama.deflate_compress(payload), #payload - 1)
+	assert(not ok)
+	return "ok"
+end
+nakama.register_rpc(test, "test")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", strings.Repeat(`{"tick":1,"x":0.5,"y":0.25}`, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "ok" {
+		t.Error("Return result not expected", result)
+	}
+}
+
+func TestRuntimeUUIDv5(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nakama = require("nakama")
+function test(ctx, payload)
+	local ok = pcall(nakama.uuid_v5, "not-a-uuid", payload)
+	assert(not ok)
+	return nakama.uuid_v5("6ba7b810-9dad-11d1-80b4-00c04fd430c8", payload)
+end
+nakama.register_rpc(test, "test")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "nakama.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "1e515a47-e3a3-531d-8500-8e74329d3c62" {
+		t.Error("Return result not expected", result)
+	}
+}
+
 func TestRuntimeNotificationsSend(t *testing.T) {
 	modules := map[string]string{
 		"test": `
@@ -704,6 +1199,50 @@ nk.notification_send(user_id, subject, content, code, "", false)`,
 	}
 }
 
+func TestRuntimeNotificationsListRead(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	modules := map[string]string{
+		"test": `
+local nk = require("nakama")
+
+nk.register_rpc(function(ctx, payload)
+	nk.notification_send(payload, "first", {}, 1, "", true)
+	nk.notification_send(payload, "second", {}, 1, "", true)
+	local notifications = nk.notifications_list(payload, 10)
+	nk.notifications_mark_read(payload, {notifications[1].id})
+
+	local states = {}
+	for _, n in ipairs(nk.notifications_list(payload, 10)) do
+		table.insert(states, n.subject .. ":" .. tostring(n.read))
+	end
+	return table.concat(states, ";")
+end, "test_notifications_read")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test_notifications_read")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", userID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "first:true;second:false" {
+		t.Fatalf("Unexpected notification read states: %q", result)
+	}
+}
+
 func TestRuntimeNotificationsDelete(t *testing.T) {
 	modules := map[string]string{
 		"test": `
@@ -1054,3 +1593,240 @@ nk.group_delete(group.id)`,
 		t.Fatal(err.Error())
 	}
 }
+
+func TestRuntimeGroupEventHook(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	superadminID := uuid.Must(uuid.NewV4())
+	memberID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, superadminID)
+	InsertUser(t, db, memberID)
+
+	modules := map[string]string{
+		"test": `
+local nk = require("nakama")
+
+nk.register_group_event(function(ctx, event)
+	nk.localcache_put(event.group_id .. ":" .. event.type, event.user_id)
+end)
+
+nk.register_rpc(function(ctx, payload)
+	local ids = nk.json_decode(payload)
+	local group = nk.group_create(ids.superadmin, nk.uuid_v4(), nil, nil, nil, nil, true)
+	nk.group_user_join(group.id, ids.member, ids.member)
+	-- Adding an existing member changes nothing, so no event is sent.
+	nk.group_users_add(group.id, {ids.member})
+	nk.group_users_promote(group.id, {ids.member})
+	nk.group_user_leave(group.id, ids.member, ids.member)
+	nk.group_delete(group.id)
+	return group.id
+end, "test_group_event")
+
+nk.register_rpc(function(ctx, payload)
+	local events = {}
+	for _, event in ipairs({"create", "join", "add", "promote", "leave", "delete"}) do
+		table.insert(events, event .. ":" .. (nk.localcache_get(payload .. ":" .. event) or ""))
+	end
+	return table.concat(events, ";")
+end, "test_group_event_list")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test_group_event")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+	listFn := runtime.Rpc("test_group_event_list")
+	if listFn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	payload := fmt.Sprintf(`{"superadmin":%q,"member":%q}`, superadminID.String(), memberID.String())
+	groupID, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Events are delivered through the runtime event queue, so they arrive after the RPC returns.
+	superadmin := superadminID.String()
+	member := memberID.String()
+	expected := "create:" + superadmin + ";join:" + member + ";add:;promote:" + member + ";leave:" + member + ";delete:" + superadmin
+	var result string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		result, err, _ = listFn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", groupID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == expected {
+			return
+		}
+	}
+	t.Fatalf("Unexpected group events, expected %q, got %q", expected, result)
+}
+
+func TestRuntimeLuaNodeBroadcast(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nk = require("nakama")
+
+nk.register_node_subscriber("config", function(ctx, payload)
+	nk.localcache_put("node_broadcast", payload)
+end)
+
+nk.register_rpc(function(ctx, payload)
+	nk.node_broadcast("config", payload)
+	return ""
+end, "test_node_broadcast")
+
+nk.register_rpc(function(ctx, payload)
+	return nk.localcache_get("node_broadcast") or ""
+end, "test_node_broadcast_get")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test_node_broadcast")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+	getFn := runtime.Rpc("test_node_broadcast_get")
+	if getFn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	if _, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "reload"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages are delivered to idle runtimes in the background, so they arrive after the RPC returns.
+	var result string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		result, err, _ = getFn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == "reload" {
+			return
+		}
+	}
+	t.Fatalf("Expected node subscriber to receive the message, got %q", result)
+}
+
+func TestRuntimeLuaSqlQueryEach(t *testing.T) {
+	modules := map[string]string{
+		"sql-each": `
+local nakama = require("nakama")
+nakama.register_rpc(function(ctx, payload)
+	local sum = 0
+	local count = nakama.sql_query_each("SELECT generate_series(1, 10) AS n", {}, function(row)
+		sum = sum + row.n
+		if row.n == 5 then
+			return false
+		end
+	end)
+	return count .. ":" .. sum
+end, "sql_each")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("sql_each")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result != "5:15" {
+		t.Fatalf("Expected iteration to stop after the fifth row, got: %v", result)
+	}
+}
+
+func TestRuntimeLuaLoggerWithFields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	vm := lua.NewState()
+	defer vm.Close()
+	vm.SetContext(context.WithValue(context.Background(), ctxLoggerFields{}, map[string]string{"mode": "rpc"}))
+
+	nakamaModule := NewRuntimeLuaNakamaModule(zap.New(core), nil, nil, nil, cfg, "", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	vm.PreloadModule("nakama", nakamaModule.Loader)
+
+	err := vm.DoString(`
+local nk = require("nakama")
+local logger = nk.logger_with_fields({match_id = "match", count = 2, nested = {a = true}})
+assert(logger.info("first") == "first")
+assert(logger:warn("second") == "second")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.TakeAll()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	for i, expected := range []struct {
+		level   zapcore.Level
+		message string
+	}{{zap.InfoLevel, "first"}, {zap.WarnLevel, "second"}} {
+		entry := entries[i]
+		if entry.Level != expected.level || entry.Message != expected.message {
+			t.Fatalf("Unexpected log entry %d, got %v %q", i, entry.Level, entry.Message)
+		}
+		fields := entry.ContextMap()
+		if fields["runtime"] != "lua" || fields["mode"] != "rpc" || fields["match_id"] != "match" {
+			t.Fatalf("Unexpected string fields on log entry %d: %v", i, fields)
+		}
+		if count, ok := fields["count"].(int64); !ok || count != 2 {
+			t.Fatalf("Unexpected count field on log entry %d: %#v", i, fields["count"])
+		}
+		if nested, ok := fields["nested"].(map[string]interface{}); !ok || nested["a"] != true {
+			t.Fatalf("Unexpected nested field on log entry %d: %#v", i, fields["nested"])
+		}
+	}
+}
+
+func TestRuntimeLuaChannelIdParse(t *testing.T) {
+	modules := map[string]string{
+		"test": `
+local nk = require("nakama")
+
+nk.register_rpc(function(ctx, payload)
+	local group_id = nk.uuid_v4()
+	local room = nk.channel_id_parse(nk.channel_id_build("", "lobby", 1))
+	local group = nk.channel_id_parse(nk.channel_id_build("", group_id, 3))
+	local ok = pcall(nk.channel_id_parse, "invalid")
+	return nk.json_encode({room_type = room.type, room_target = room.target, group_type = group.type, group_match = group.group_id == group_id, invalid_ok = ok})
+end, "test_channel_id_parse")`,
+	}
+
+	runtime, _, err := runtimeWithModules(t, modules)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	fn := runtime.Rpc("test_channel_id_parse")
+	if fn == nil {
+		t.Fatal("Expected RPC function to be registered")
+	}
+
+	result, err, _ := fn(context.Background(), nil, nil, "", "", nil, 0, "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"group_match":true,"group_type":3,"invalid_ok":false,"room_target":"lobby","room_type":1}`; result != expected {
+		t.Fatalf("Unexpected parse result, expected %q, got %q", expected, result)
+	}
+}
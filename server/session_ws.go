@@ -42,7 +42,7 @@ type sessionWS struct {
 	format     SessionFormat
 	userID     uuid.UUID
 	username   *atomic.String
-	vars       map[string]string
+	vars       *atomic.Pointer[map[string]string]
 	expiry     int64
 	clientIP   string
 	clientPort string
@@ -94,7 +94,7 @@ func NewSessionWS(logger *zap.Logger, config Config, format SessionFormat, sessi
 		format:     format,
 		userID:     userID,
 		username:   atomic.NewString(username),
-		vars:       vars,
+		vars:       atomic.NewPointer(&vars),
 		expiry:     expiry,
 		clientIP:   clientIP,
 		clientPort: clientPort,
@@ -164,7 +164,11 @@ func (s *sessionWS) SetUsername(username string) {
 }
 
 func (s *sessionWS) Vars() map[string]string {
-	return s.vars
+	return *s.vars.Load()
+}
+
+func (s *sessionWS) SetVars(vars map[string]string) {
+	s.vars.Store(&vars)
 }
 
 func (s *sessionWS) Expiry() int64 {
@@ -174,7 +178,7 @@ func (s *sessionWS) Expiry() int64 {
 func (s *sessionWS) Consume() {
 	// Fire an event for session start.
 	if fn := s.runtime.EventSessionStart(); fn != nil {
-		fn(s.userID.String(), s.username.Load(), s.vars, s.expiry, s.id.String(), s.clientIP, s.clientPort, s.lang, time.Now().UTC().Unix())
+		fn(s.userID.String(), s.username.Load(), s.Vars(), s.expiry, s.id.String(), s.clientIP, s.clientPort, s.lang, time.Now().UTC().Unix())
 	}
 
 	s.conn.SetReadLimit(s.config.GetSocket().MaxMessageSizeBytes)
@@ -522,6 +526,6 @@ func (s *sessionWS) Close(msg string, reason runtime.PresenceReason, envelopes .
 
 	// Fire an event for session end.
 	if fn := s.runtime.EventSessionEnd(); fn != nil {
-		fn(s.userID.String(), s.username.Load(), s.vars, s.expiry, s.id.String(), s.clientIP, s.clientPort, s.lang, time.Now().UTC().Unix(), msg)
+		fn(s.userID.String(), s.username.Load(), s.Vars(), s.expiry, s.id.String(), s.clientIP, s.clientPort, s.lang, time.Now().UTC().Unix(), msg)
 	}
 }
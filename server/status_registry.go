@@ -40,6 +40,7 @@ type StatusRegistry interface {
 	Unfollow(sessionID uuid.UUID, userIDs []uuid.UUID)
 	UnfollowAll(sessionID uuid.UUID)
 	IsOnline(userID uuid.UUID) bool
+	OnlineStatuses(userIDs []uuid.UUID) map[uuid.UUID]bool
 	FillOnlineUsers(users []*api.User)
 	FillOnlineAccounts(accounts []*api.Account)
 	FillOnlineFriends(friends []*api.Friend)
@@ -280,6 +281,21 @@ func (s *LocalStatusRegistry) IsOnline(userID uuid.UUID) bool {
 	return found
 }
 
+// OnlineStatuses reports whether each of the given users is online, checking all of them under a single lock. The
+// registry only tracks current presence, so no last-seen time is known for offline users.
+func (s *LocalStatusRegistry) OnlineStatuses(userIDs []uuid.UUID) map[uuid.UUID]bool {
+	statuses := make(map[uuid.UUID]bool, len(userIDs))
+
+	s.onlineMutex.RLock()
+	for _, userID := range userIDs {
+		_, found := s.onlineCache[userID]
+		statuses[userID] = found
+	}
+	s.onlineMutex.RUnlock()
+
+	return statuses
+}
+
 func (s *LocalStatusRegistry) FillOnlineUsers(users []*api.User) {
 	if len(users) == 0 {
 		return
@@ -0,0 +1,38 @@
+// Copyright 2026 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusRegistryOnlineStatuses(t *testing.T) {
+	statusRegistry := NewLocalStatusRegistry(logger, cfg, NewLocalSessionRegistry(metrics), protojsonMarshaler)
+	defer statusRegistry.Stop()
+
+	online := uuid.Must(uuid.NewV4())
+	offline := uuid.Must(uuid.NewV4())
+	statusRegistry.Queue(online, []*rtapi.UserPresence{{UserId: online.String(), SessionId: uuid.Must(uuid.NewV4()).String()}}, nil)
+
+	assert.Eventually(t, func() bool { return statusRegistry.IsOnline(online) }, time.Second, 10*time.Millisecond)
+
+	statuses := statusRegistry.OnlineStatuses([]uuid.UUID{online, offline})
+	assert.Equal(t, map[uuid.UUID]bool{online: true, offline: false}, statuses)
+}
@@ -24,11 +24,14 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/blugelabs/bluge"
 	"github.com/blugelabs/bluge/index"
+	"github.com/blugelabs/bluge/numeric"
 	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/aggregations"
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
 	"go.uber.org/zap"
@@ -40,6 +43,7 @@ type StorageIndex interface {
 	Write(ctx context.Context, objects []*api.StorageObject) (creates int, deletes int)
 	Delete(ctx context.Context, objects StorageOpDeletes) (deletes int)
 	List(ctx context.Context, callerID uuid.UUID, indexName, query string, limit int, order []string, cursor string) (*api.StorageObjects, string, error)
+	Count(ctx context.Context, callerID uuid.UUID, indexName, query, groupBy string) (int64, map[string]int64, error)
 	Load(ctx context.Context) error
 	CreateIndex(ctx context.Context, name, collection, key string, fields []string, sortFields []string, maxEntries int, indexOnly bool) error
 	RegisterFilters(runtime *Runtime)
@@ -378,6 +382,93 @@ func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, index
 	return objects, newCursor, nil
 }
 
+// Count returns the number of index entries matching the query without reading any objects. If a group by field is
+// given the matches are also counted per distinct value of that field, which must be one of the sortable fields of
+// the index. String values are grouped as is and numeric values by their decimal representation, for example "3" or
+// "2.5". Matches without a value for the field are not included in any group.
+func (si *LocalStorageIndex) Count(ctx context.Context, callerID uuid.UUID, indexName, query, groupBy string) (int64, map[string]int64, error) {
+	idx, found := si.indexByName[indexName]
+	if !found {
+		return 0, nil, fmt.Errorf("index %q not found", indexName)
+	}
+
+	if groupBy != "" && !slices.Contains(idx.SortableFields, groupBy) {
+		return 0, nil, fmt.Errorf("group by field %q is not a sortable field of index %q", groupBy, indexName)
+	}
+
+	if query == "" {
+		query = "*"
+	}
+
+	parsedQuery, err := ParseQueryString(query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if callerID != uuid.Nil {
+		// Only count objects the caller could read, same as List.
+		readQuery := bluge.NewBooleanQuery().
+			AddShould(bluge.NewNumericRangeInclusiveQuery(2, 2, true, true).SetField("read")).
+			AddShould(bluge.NewTermQuery(callerID.String()).SetField("user_id"))
+		parsedQuery = bluge.NewBooleanQuery().AddMust(parsedQuery).AddMust(readQuery)
+	}
+
+	searchReq := bluge.NewTopNSearch(0, parsedQuery)
+	searchReq.AddAggregation("count", aggregations.CountMatches())
+	if groupBy != "" {
+		searchReq.AddAggregation("groups", aggregations.NewTermsAggregation(storageIndexGroupSource("value."+groupBy), idx.MaxEntries))
+	}
+
+	indexReader, err := idx.Index.Reader()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	results, err := indexReader.Search(ctx, searchReq)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var groups map[string]int64
+	if groupBy != "" {
+		buckets := results.Aggregations().Buckets("groups")
+		groups = make(map[string]int64, len(buckets))
+		for _, bucket := range buckets {
+			groups[bucket.Name()] = int64(bucket.Count())
+		}
+	}
+
+	return int64(results.Aggregations().Count()), groups, nil
+}
+
+// storageIndexGroupSource reads the values of a field for a group by count. Numeric values are indexed as several
+// prefix coded terms of decreasing precision, so only the full precision term is kept and decoded to its number, which
+// counts each value once under a readable name.
+type storageIndexGroupSource string
+
+func (s storageIndexGroupSource) Fields() []string {
+	return []string{string(s)}
+}
+
+func (s storageIndexGroupSource) Values(match *search.DocumentMatch) [][]byte {
+	terms := match.DocValues(string(s))
+	values := make([][]byte, 0, len(terms))
+	for _, term := range terms {
+		if valid, shift := numeric.ValidPrefixCodedTermBytes(term); valid {
+			if shift != 0 {
+				continue
+			}
+			value, err := bluge.DecodeNumericFloat64(term)
+			if err != nil {
+				continue
+			}
+			term = []byte(strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		values = append(values, term)
+	}
+	return values
+}
+
 func (si *LocalStorageIndex) Load(ctx context.Context) error {
 	var rangeError error
 	for _, idx := range si.indexByName {
@@ -398,6 +489,7 @@ func (si *LocalStorageIndex) load(ctx context.Context, idx *storageIndex) error
 SELECT user_id, key, version, value, read, write, create_time, update_time
 FROM storage
 WHERE collection = $1
+AND (expire_time IS NULL OR expire_time > now())
 ORDER BY collection, key, user_id
 LIMIT $2`
 	params := []any{idx.Collection, 10_000}
@@ -407,6 +499,7 @@ LIMIT $2`
 SELECT user_id, key, version, value, read, write, create_time, update_time
 FROM storage
 WHERE collection = $1 AND key = $3
+AND (expire_time IS NULL OR expire_time > now())
 ORDER BY collection, key, user_id
 LIMIT $2`
 		params = append(params, idx.Key)
@@ -491,6 +584,7 @@ SELECT user_id, key, version, value, read, write, create_time, update_time
 FROM storage
 WHERE collection = $1
 AND (collection, key, user_id) > ($1, $3, $4)
+AND (expire_time IS NULL OR expire_time > now())
 ORDER BY collection, key, user_id
 LIMIT $2`
 		if idx.Key != "" {
@@ -500,6 +594,7 @@ FROM storage
 WHERE collection = $1
 AND key = $3
 AND user_id > $4
+AND (expire_time IS NULL OR expire_time > now())
 ORDER BY collection, key, user_id
 LIMIT $2`
 		}
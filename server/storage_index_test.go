@@ -17,6 +17,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
@@ -630,6 +631,69 @@ func TestLocalStorageIndex_List(t *testing.T) {
 	})
 }
 
+func TestLocalStorageIndex_Count(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_count"
+	collection := "test_collection"
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"region", "members", "tier"}, []string{"region", "tier"}, 10, false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	u1 := uuid.Must(uuid.NewV4())
+	objects := make([]*api.StorageObject, 0, 4)
+	for i, v := range []string{`{"region":"eu","members":150,"tier":1}`, `{"region":"eu","members":50,"tier":2.5}`, `{"region":"us","members":200,"tier":1}`, `{"region":"us","members":300,"tier":1000000}`} {
+		objects = append(objects, &api.StorageObject{
+			Collection:     collection,
+			Key:            fmt.Sprintf("key%d", i),
+			UserId:         u1.String(),
+			Value:          v,
+			PermissionRead: int32(i % 3),
+			CreateTime:     timestamppb.Now(),
+			UpdateTime:     timestamppb.Now(),
+		})
+	}
+	storageIdx.Write(ctx, objects)
+
+	count, groups, err := storageIdx.Count(ctx, uuid.Nil, indexName, "+value.members:>100", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.EqualValues(t, 3, count)
+	assert.Nil(t, groups)
+
+	count, groups, err = storageIdx.Count(ctx, uuid.Nil, indexName, "*", "region")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.EqualValues(t, 4, count)
+	assert.Equal(t, map[string]int64{"eu": 2, "us": 2}, groups)
+
+	// Numeric values are grouped once per value, by their decimal representation.
+	count, groups, err = storageIdx.Count(ctx, uuid.Nil, indexName, "*", "tier")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.EqualValues(t, 4, count)
+	assert.Equal(t, map[string]int64{"1": 2, "2.5": 1, "1000000": 1}, groups)
+
+	// Only objects with public read permission are counted for other users.
+	count, _, err = storageIdx.Count(ctx, uuid.Must(uuid.NewV4()), indexName, "*", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.EqualValues(t, 1, count)
+
+	_, _, err = storageIdx.Count(ctx, uuid.Nil, indexName, "*", "members")
+	assert.Error(t, err, "group by a non sortable field should fail")
+}
+
 func TestLocalStorageIndex_Delete(t *testing.T) {
 	db := NewDB(t)
 	defer db.Close()
@@ -15,8 +15,14 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	syncAtomic "sync/atomic"
 	"time"
@@ -128,6 +134,7 @@ type Tracker interface {
 	SetMatchLeaveListener(func(id uuid.UUID, leaves []*MatchPresence))
 	SetPartyJoinListener(func(id uuid.UUID, joins []*Presence))
 	SetPartyLeaveListener(func(id uuid.UUID, leaves []*Presence))
+	SetStreamPresenceListeners(map[uint8]func(stream PresenceStream, joins, leaves []*Presence))
 	Stop()
 
 	// Track returns success true/false, and new presence true/false.
@@ -161,6 +168,9 @@ type Tracker interface {
 	GetLocalBySessionIDStreamUserID(sessionID uuid.UUID, stream PresenceStream, userID uuid.UUID) *PresenceMeta
 	// List presences by stream, optionally include hidden ones and not hidden ones.
 	ListByStream(stream PresenceStream, includeHidden bool, includeNotHidden bool) []*Presence
+	// List a page of presences by stream ordered by presence ID, starting after the given presence ID if any.
+	// Returns the presence ID to continue from, or nil if there are no further presences.
+	ListByStreamPage(stream PresenceStream, includeHidden bool, includeNotHidden bool, limit int, after *PresenceID) ([]*Presence, *PresenceID)
 
 	// Fast lookup of local session IDs to use for message delivery.
 	ListLocalSessionIDByStream(stream PresenceStream) []uuid.UUID
@@ -183,15 +193,17 @@ type LocalTracker struct {
 	matchLeaveListener func(id uuid.UUID, leaves []*MatchPresence)
 	partyJoinListener  func(id uuid.UUID, joins []*Presence)
 	partyLeaveListener func(id uuid.UUID, leaves []*Presence)
-	sessionRegistry    SessionRegistry
-	statusRegistry     StatusRegistry
-	metrics            Metrics
-	protojsonMarshaler *protojson.MarshalOptions
-	name               string
-	eventsCh           chan *PresenceEvent
-	presencesByStream  map[uint8]map[PresenceStream]map[presenceCompact]*Presence
-	presencesBySession map[uuid.UUID]map[presenceCompact]*Presence
-	count              *atomic.Int64
+	// Stream presence listeners keyed by stream mode.
+	streamPresenceListeners map[uint8]func(stream PresenceStream, joins, leaves []*Presence)
+	sessionRegistry         SessionRegistry
+	statusRegistry          StatusRegistry
+	metrics                 Metrics
+	protojsonMarshaler      *protojson.MarshalOptions
+	name                    string
+	eventsCh                chan *PresenceEvent
+	presencesByStream       map[uint8]map[PresenceStream]map[presenceCompact]*Presence
+	presencesBySession      map[uuid.UUID]map[presenceCompact]*Presence
+	count                   *atomic.Int64
 
 	ctx         context.Context
 	ctxCancelFn context.CancelFunc
@@ -250,6 +262,10 @@ func (t *LocalTracker) SetPartyLeaveListener(f func(id uuid.UUID, leaves []*Pres
 	t.partyLeaveListener = f
 }
 
+func (t *LocalTracker) SetStreamPresenceListeners(listeners map[uint8]func(stream PresenceStream, joins, leaves []*Presence)) {
+	t.streamPresenceListeners = listeners
+}
+
 func (t *LocalTracker) Stop() {
 	// No need to explicitly clean up the events channel, just let the application exit.
 	t.ctxCancelFn()
@@ -833,6 +849,86 @@ func (t *LocalTracker) ListByStream(stream PresenceStream, includeHidden bool, i
 	return ps
 }
 
+func (t *LocalTracker) ListByStreamPage(stream PresenceStream, includeHidden bool, includeNotHidden bool, limit int, after *PresenceID) ([]*Presence, *PresenceID) {
+	if (!includeHidden && !includeNotHidden) || limit < 1 {
+		return []*Presence{}, nil
+	}
+
+	t.RLock()
+	byStream, anyTracked := t.presencesByStream[stream.Mode][stream]
+	if !anyTracked {
+		t.RUnlock()
+		return []*Presence{}, nil
+	}
+	// Only retain a bounded number of candidates, one more than the limit to detect if there is a further page.
+	bound := limit + 1
+	ps := make([]*Presence, 0, min(len(byStream), 2*bound))
+	for _, p := range byStream {
+		if (p.Meta.Hidden && !includeHidden) || (!p.Meta.Hidden && !includeNotHidden) {
+			continue
+		}
+		if after != nil && comparePresenceID(p.ID, *after) <= 0 {
+			continue
+		}
+		ps = append(ps, p)
+		if len(ps) >= 2*bound {
+			slices.SortFunc(ps, func(a, b *Presence) int { return comparePresenceID(a.ID, b.ID) })
+			ps = ps[:bound]
+		}
+	}
+	t.RUnlock()
+
+	slices.SortFunc(ps, func(a, b *Presence) int { return comparePresenceID(a.ID, b.ID) })
+	if len(ps) <= limit {
+		return ps, nil
+	}
+	ps = ps[:limit]
+	next := ps[limit-1].ID
+	return ps, &next
+}
+
+type presenceListCursor struct {
+	SessionID []byte
+	Node      string
+}
+
+func encodePresenceListCursor(id *PresenceID) (string, error) {
+	if id == nil {
+		return "", nil
+	}
+	cursorBuf := new(bytes.Buffer)
+	if err := gob.NewEncoder(cursorBuf).Encode(&presenceListCursor{SessionID: id.SessionID.Bytes(), Node: id.Node}); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorBuf.Bytes()), nil
+}
+
+func decodePresenceListCursor(cursor string) (*PresenceID, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	cb, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	c := &presenceListCursor{}
+	if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(c); err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	sessionID, err := uuid.FromBytes(c.SessionID)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	return &PresenceID{Node: c.Node, SessionID: sessionID}, nil
+}
+
+func comparePresenceID(a, b PresenceID) int {
+	if c := bytes.Compare(a.SessionID.Bytes(), b.SessionID.Bytes()); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Node, b.Node)
+}
+
 func (t *LocalTracker) ListLocalSessionIDByStream(stream PresenceStream) []uuid.UUID {
 	t.RLock()
 	byStream, anyTracked := t.presencesByStream[stream.Mode][stream]
@@ -924,6 +1020,10 @@ func (t *LocalTracker) processEvent(e *PresenceEvent) {
 	partyJoins := make(map[uuid.UUID][]*Presence, 0)
 	partyLeaves := make(map[uuid.UUID][]*Presence, 0)
 
+	// Track grouped joins and leaves for streams with a mode that has a presence listener.
+	listenerJoins := make(map[PresenceStream][]*Presence, 0)
+	listenerLeaves := make(map[PresenceStream][]*Presence, 0)
+
 	for _, p := range e.Joins {
 		pWire := &rtapi.UserPresence{
 			UserId:      p.UserID.String(),
@@ -966,6 +1066,10 @@ func (t *LocalTracker) processEvent(e *PresenceEvent) {
 				partyJoins[p.Stream.Subject] = []*Presence{c}
 			}
 		}
+
+		if _, ok := t.streamPresenceListeners[p.Stream.Mode]; ok {
+			listenerJoins[p.Stream] = append(listenerJoins[p.Stream], p)
+		}
 	}
 	for _, p := range e.Leaves {
 		pWire := &rtapi.UserPresence{
@@ -1009,6 +1113,10 @@ func (t *LocalTracker) processEvent(e *PresenceEvent) {
 				partyLeaves[p.Stream.Subject] = []*Presence{c}
 			}
 		}
+
+		if _, ok := t.streamPresenceListeners[p.Stream.Mode]; ok {
+			listenerLeaves[p.Stream] = append(listenerLeaves[p.Stream], p)
+		}
 	}
 
 	// Notify locally hosted authoritative matches of join and leave events.
@@ -1027,6 +1135,16 @@ func (t *LocalTracker) processEvent(e *PresenceEvent) {
 		t.partyLeaveListener(partyID, leaves)
 	}
 
+	// Notify stream presence listeners of join and leave events, together for the same stream.
+	for stream, joins := range listenerJoins {
+		leaves := listenerLeaves[stream]
+		delete(listenerLeaves, stream)
+		t.streamPresenceListeners[stream.Mode](stream, joins, leaves)
+	}
+	for stream, leaves := range listenerLeaves {
+		t.streamPresenceListeners[stream.Mode](stream, nil, leaves)
+	}
+
 	// Send joins, together with any leaves for the same stream.
 	for stream, joins := range streamJoins {
 		leaves, ok := streamLeaves[stream]